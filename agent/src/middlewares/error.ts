@@ -1,4 +1,5 @@
 import { Elysia } from 'elysia'
+import { providerStatus } from '../utils/error'
 
 export interface ErrorResponse {
   success: false
@@ -60,6 +61,18 @@ export const errorMiddleware = new Elysia({ name: 'error' })
         if (error instanceof Error) {
           const message = error.message
 
+          // Relay the status of a failed model provider call so the server
+          // retries only failures another model may not hit.
+          const upstream = providerStatus(error)
+          if (upstream) {
+            set.status = upstream
+            return {
+              success: false,
+              error: message,
+              code: 'PROVIDER_ERROR',
+            } satisfies ErrorResponse
+          }
+
           if (
             message.includes('No bearer token') ||
             message.includes('Invalid or expired token')
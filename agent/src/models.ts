@@ -24,6 +24,8 @@ export const ModelConfigModel = z.object({
   apiKey: z.string().min(1, 'API key is required'),
   baseUrl: z.string(),
   reasoning: ReasoningConfigModel,
+  temperature: z.number().min(0).max(2).optional(),
+  topP: z.number().gt(0).max(1).optional(),
 })
 
 export const AllowedActionModel = z.enum(allActions)
@@ -35,6 +37,7 @@ export const IdentityContextModel = z.object({
   displayName: z.string().min(1, 'Display name is required'),
   currentPlatform: z.string().optional(),
   conversationType: z.string().optional(),
+  routeId: z.string().optional(),
   sessionToken: z.string().optional(),
 })
 
@@ -5,13 +5,14 @@ import { createAuthFetcher, getBaseUrl } from '../index'
 import { bearerMiddleware } from '../middlewares/bearer'
 import { AgentSkillModel, AllowedActionModel, AttachmentModel, IdentityContextModel, InboxItemModel, MCPConnectionModel, ModelConfigModel, ScheduleModel } from '../models'
 import { sseChunked } from '../utils/sse'
+import { providerStatus } from '../utils/error'
 
 const AgentModel = z.object({
   model: ModelConfigModel,
   activeContextTime: z.number(),
   channels: z.array(z.string()),
   currentChannel: z.string(),
-  allowedActions: z.array(AllowedActionModel).optional().default(allActions),
+  allowedActions: z.array(AllowedActionModel).nullish().transform((actions) => actions ?? allActions),
   messages: z.array(z.any()),
   usableSkills: z.array(AgentSkillModel).optional().default([]),
   skills: z.array(z.string()),
@@ -19,6 +20,7 @@ const AgentModel = z.object({
   attachments: z.array(AttachmentModel).optional().default([]),
   mcpConnections: z.array(MCPConnectionModel).optional().default([]),
   inbox: z.array(InboxItemModel).optional().default([]),
+  instructions: z.string().optional().default(''),
 })
 
 export const chatModule = new Elysia({ prefix: '/chat' })
@@ -40,6 +42,7 @@ export const chatModule = new Elysia({ prefix: '/chat' })
       skills: body.usableSkills,
       mcpConnections: body.mcpConnections,
       inbox: body.inbox,
+      instructions: body.instructions,
     }, authFetcher)
     return ask({
       query: body.query,
@@ -70,6 +73,7 @@ export const chatModule = new Elysia({ prefix: '/chat' })
         skills: body.usableSkills,
         mcpConnections: body.mcpConnections,
         inbox: body.inbox,
+        instructions: body.instructions,
       }, authFetcher)
       for await (const action of stream({
         query: body.query,
@@ -84,9 +88,12 @@ export const chatModule = new Elysia({ prefix: '/chat' })
       const message = error instanceof Error && error.message.trim()
         ? error.message
         : 'Internal server error'
+      // The stream has answered 200 already; status carries the provider's
+      // answer so the server can tell whether another model may succeed.
       yield sseChunked(JSON.stringify({
         type: 'error',
         message,
+        status: providerStatus(error),
       }))
     }
   }, {
@@ -110,6 +117,7 @@ export const chatModule = new Elysia({ prefix: '/chat' })
       skills: body.usableSkills,
       mcpConnections: body.mcpConnections,
       inbox: body.inbox,
+      instructions: body.instructions,
     }, authFetcher)
     return triggerSchedule({
       schedule: body.schedule,
@@ -0,0 +1,17 @@
+// providerStatus returns the HTTP status a model provider answered with, when
+// error came from a provider call. The AI SDK reports it as statusCode on
+// APICallError, wrapped in RetryError.lastError after retries and in cause
+// when the agent rethrows a stream error.
+export function providerStatus(error: unknown): number | undefined {
+  const seen = new Set<unknown>()
+  let current = error
+  while (current && typeof current === 'object' && !seen.has(current)) {
+    seen.add(current)
+    const candidate = current as { statusCode?: unknown; lastError?: unknown; cause?: unknown }
+    if (typeof candidate.statusCode === 'number' && candidate.statusCode >= 400 && candidate.statusCode < 600) {
+      return candidate.statusCode
+    }
+    current = candidate.lastError ?? candidate.cause
+  }
+  return undefined
+}
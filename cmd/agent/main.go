@@ -20,6 +20,7 @@ import (
 
 	dbembed "github.com/memohai/memoh/db"
 	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/apitokens"
 	"github.com/memohai/memoh/internal/bind"
 	"github.com/memohai/memoh/internal/boot"
 	"github.com/memohai/memoh/internal/bots"
@@ -27,6 +28,7 @@ import (
 	"github.com/memohai/memoh/internal/channel/adapters/discord"
 	"github.com/memohai/memoh/internal/channel/adapters/feishu"
 	"github.com/memohai/memoh/internal/channel/adapters/local"
+	"github.com/memohai/memoh/internal/channel/adapters/remote"
 	"github.com/memohai/memoh/internal/channel/adapters/telegram"
 	"github.com/memohai/memoh/internal/channel/identities"
 	"github.com/memohai/memoh/internal/channel/inbound"
@@ -35,18 +37,23 @@ import (
 	ctr "github.com/memohai/memoh/internal/containerd"
 	"github.com/memohai/memoh/internal/conversation"
 	"github.com/memohai/memoh/internal/conversation/flow"
+	"github.com/memohai/memoh/internal/conversation/gateway"
+	"github.com/memohai/memoh/internal/conversation/recording"
 	"github.com/memohai/memoh/internal/db"
 	dbsqlc "github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/docextract"
 	"github.com/memohai/memoh/internal/embeddings"
 	"github.com/memohai/memoh/internal/handlers"
 	"github.com/memohai/memoh/internal/healthcheck"
 	channelchecker "github.com/memohai/memoh/internal/healthcheck/checkers/channel"
+	gatewaychecker "github.com/memohai/memoh/internal/healthcheck/checkers/gateway"
 	mcpchecker "github.com/memohai/memoh/internal/healthcheck/checkers/mcp"
 	"github.com/memohai/memoh/internal/inbox"
 	"github.com/memohai/memoh/internal/logger"
 	"github.com/memohai/memoh/internal/mcp"
 	mcpcontacts "github.com/memohai/memoh/internal/mcp/providers/contacts"
 	mcpcontainer "github.com/memohai/memoh/internal/mcp/providers/container"
+	mcphistory "github.com/memohai/memoh/internal/mcp/providers/history"
 	mcpinbox "github.com/memohai/memoh/internal/mcp/providers/inbox"
 	mcpmemory "github.com/memohai/memoh/internal/mcp/providers/memory"
 	mcpmessage "github.com/memohai/memoh/internal/mcp/providers/message"
@@ -56,17 +63,26 @@ import (
 	"github.com/memohai/memoh/internal/media"
 	"github.com/memohai/memoh/internal/memory"
 	"github.com/memohai/memoh/internal/message"
+	"github.com/memohai/memoh/internal/message/archive"
 	"github.com/memohai/memoh/internal/message/event"
+	"github.com/memohai/memoh/internal/message/retention"
 	"github.com/memohai/memoh/internal/models"
+	"github.com/memohai/memoh/internal/moderation"
+	"github.com/memohai/memoh/internal/pii"
 	"github.com/memohai/memoh/internal/policy"
 	"github.com/memohai/memoh/internal/preauth"
 	"github.com/memohai/memoh/internal/providers"
+	"github.com/memohai/memoh/internal/quota"
 	"github.com/memohai/memoh/internal/schedule"
 	"github.com/memohai/memoh/internal/searchproviders"
 	"github.com/memohai/memoh/internal/server"
 	"github.com/memohai/memoh/internal/settings"
 	"github.com/memohai/memoh/internal/storage/providers/containerfs"
 	"github.com/memohai/memoh/internal/subagent"
+	"github.com/memohai/memoh/internal/tokenizer"
+	"github.com/memohai/memoh/internal/toolapproval"
+	"github.com/memohai/memoh/internal/tts"
+	"github.com/memohai/memoh/internal/usage"
 	"github.com/memohai/memoh/internal/version"
 )
 
@@ -89,10 +105,12 @@ func main() {
 		runServe()
 	case "migrate":
 		runMigrate(os.Args[2:])
+	case "migrate-memory":
+		runMigrateMemory()
 	case "version":
 		fmt.Printf("memoh-server %s\n", version.GetInfo())
 	default:
-		fmt.Fprintf(os.Stderr, "Usage: memoh-server <command>\n\nCommands:\n  serve     Start the server (default)\n  migrate   Run database migrations (up|down|version|force)\n  version   Print version information\n")
+		fmt.Fprintf(os.Stderr, "Usage: memoh-server <command>\n\nCommands:\n  serve           Start the server (default)\n  migrate         Run database migrations (up|down|version|force)\n  migrate-memory  Copy memories from Qdrant into Postgres (pgvector)\n  version         Print version information\n")
 		os.Exit(1)
 	}
 }
@@ -124,6 +142,47 @@ func runMigrate(args []string) {
 	}
 }
 
+// runMigrateMemory copies the memories in the [qdrant] collection into the
+// pgvector store, for switching [memory] backend to "pgvector".
+func runMigrateMemory() {
+	cfg, err := provideConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Init(cfg.Log.Level, cfg.Log.Format)
+	log := logger.L
+	ctx := context.Background()
+
+	conn, err := db.Open(ctx, cfg.Postgres)
+	if err != nil {
+		log.Error("db connect failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	qcfg := cfg.Qdrant
+	from, err := memory.NewQdrantStore(log, qcfg.BaseURL, qcfg.APIKey, qcfg.Collection, 0, "sparse_hash", time.Duration(qcfg.TimeoutSeconds)*time.Second)
+	if err != nil {
+		log.Error("qdrant init failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	to, err := memory.NewPostgresStore(log, conn, 0, nil, "sparse_hash")
+	if err != nil {
+		log.Error("pgvector memory store init failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+	copied, err := memory.CopyQdrantToPostgres(ctx, from, to, 200, func(copied int) {
+		log.Info("copying memories", slog.Int("copied", copied))
+	})
+	if err != nil {
+		log.Error("memory migration failed", slog.Int("copied", copied), slog.Any("error", err))
+		os.Exit(1)
+	}
+	log.Info("memory migration finished", slog.Int("copied", copied))
+}
+
 func runServe() {
 	fx.New(
 		fx.Provide(
@@ -142,7 +201,7 @@ func runServe() {
 			provideEmbeddingsResolver,
 			provideEmbeddingSetup,
 			provideTextEmbedderForMemory,
-			provideQdrantStore,
+			provideVectorStore,
 			memory.NewBM25Indexer,
 			provideMemoryService,
 
@@ -162,11 +221,20 @@ func runServe() {
 			bind.NewService,
 			event.NewHub,
 			inbox.NewService,
+			apitokens.NewService,
+			toolapproval.NewService,
+			usage.NewService,
+			quota.NewService,
+			recording.NewService,
+			moderation.NewService,
+			pii.NewService,
 
 			// services requiring provide functions
 			provideRouteService,
 			provideMessageService,
 			provideMediaService,
+			provideTTSService,
+			provideRetentionService,
 
 			// channel infrastructure
 			local.NewRouteHub,
@@ -177,6 +245,7 @@ func runServe() {
 			provideChannelLifecycleService,
 
 			// conversation flow
+			provideGatewayPool,
 			provideChatResolver,
 			provideScheduleTriggerer,
 			schedule.NewService,
@@ -205,8 +274,18 @@ func runServe() {
 			provideServerHandler(provideUsersHandler),
 			provideServerHandler(handlers.NewMCPHandler),
 			provideServerHandler(handlers.NewInboxHandler),
+			provideServerHandler(handlers.NewToolApprovalHandler),
+			provideServerHandler(handlers.NewUsageHandler),
+			provideServerHandler(handlers.NewQuotaHandler),
+			provideServerHandler(handlers.NewRetentionHandler),
+			provideServerHandler(provideGatewayRecordingHandler),
+			provideServerHandler(handlers.NewModerationHandler),
+			provideServerHandler(handlers.NewPIIHandler),
 			provideServerHandler(provideCLIHandler),
 			provideServerHandler(provideWebHandler),
+			provideServerHandler(provideTTSHandler),
+			provideServerHandler(handlers.NewAPITokensHandler),
+			provideServerHandler(provideOpenAIHandler),
 
 			provideServer,
 		),
@@ -214,7 +293,12 @@ func runServe() {
 			startMemoryWarmup,
 			startScheduleService,
 			startChannelManager,
+			startRemoteAdapters,
 			startContainerReconciliation,
+			startGatewayHealthChecks,
+			startUsageQuotas,
+			startContentModeration,
+			startMessageRetention,
 			startServer,
 		),
 		fx.WithLogger(func(logger *slog.Logger) fxevent.Logger {
@@ -336,25 +420,51 @@ func provideTextEmbedderForMemory(resolver *embeddings.Resolver, setup embedding
 	return buildTextEmbedder(resolver, setup.TextModel, setup.HasEmbeddingModels, log)
 }
 
-func provideQdrantStore(log *slog.Logger, cfg config.Config, setup embeddingSetup) (*memory.QdrantStore, error) {
-	qcfg := cfg.Qdrant
-	timeout := time.Duration(qcfg.TimeoutSeconds) * time.Second
+func provideVectorStore(log *slog.Logger, cfg config.Config, setup embeddingSetup, conn *pgxpool.Pool) (memory.VectorStore, error) {
+	var vectors map[string]int
 	if setup.HasEmbeddingModels && len(setup.Vectors) > 0 {
-		store, err := memory.NewQdrantStoreWithVectors(log, qcfg.BaseURL, qcfg.APIKey, qcfg.Collection, setup.Vectors, "sparse_hash", timeout)
+		vectors = setup.Vectors
+	}
+	switch backend := strings.TrimSpace(cfg.Memory.Backend); backend {
+	case "", config.MemoryBackendQdrant:
+		return provideQdrantStore(log, cfg.Qdrant, setup.TextModel.Dimensions, vectors)
+	case config.MemoryBackendPgvector:
+		store, err := memory.NewPostgresStore(log, conn, setup.TextModel.Dimensions, vectors, "sparse_hash")
+		if err != nil {
+			return nil, fmt.Errorf("pgvector memory store init: %w", err)
+		}
+		return store, nil
+	case config.MemoryBackendLocal:
+		store, err := memory.NewLocalStore(log, cfg.Memory.Dir, vectors, "sparse_hash")
+		if err != nil {
+			return nil, fmt.Errorf("local memory store init: %w", err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown memory backend %q", backend)
+	}
+}
+
+func provideQdrantStore(log *slog.Logger, qcfg config.QdrantConfig, dimension int, vectors map[string]int) (*memory.QdrantStore, error) {
+	timeout := time.Duration(qcfg.TimeoutSeconds) * time.Second
+	if len(vectors) > 0 {
+		store, err := memory.NewQdrantStoreWithVectors(log, qcfg.BaseURL, qcfg.APIKey, qcfg.Collection, vectors, "sparse_hash", timeout)
 		if err != nil {
 			return nil, fmt.Errorf("qdrant named vectors init: %w", err)
 		}
 		return store, nil
 	}
-	store, err := memory.NewQdrantStore(log, qcfg.BaseURL, qcfg.APIKey, qcfg.Collection, setup.TextModel.Dimensions, "sparse_hash", timeout)
+	store, err := memory.NewQdrantStore(log, qcfg.BaseURL, qcfg.APIKey, qcfg.Collection, dimension, "sparse_hash", timeout)
 	if err != nil {
 		return nil, fmt.Errorf("qdrant init: %w", err)
 	}
 	return store, nil
 }
 
-func provideMemoryService(log *slog.Logger, llm memory.LLM, embedder embeddings.Embedder, store *memory.QdrantStore, resolver *embeddings.Resolver, bm25 *memory.BM25Indexer, setup embeddingSetup) *memory.Service {
-	return memory.NewService(log, llm, embedder, store, resolver, bm25, setup.TextModel.ModelID, setup.MultimodalModel.ModelID)
+func provideMemoryService(log *slog.Logger, llm memory.LLM, embedder embeddings.Embedder, store memory.VectorStore, resolver *embeddings.Resolver, bm25 *memory.BM25Indexer, setup embeddingSetup, piiService *pii.Service) *memory.Service {
+	service := memory.NewService(log, llm, embedder, store, resolver, bm25, setup.TextModel.ModelID, setup.MultimodalModel.ModelID)
+	service.SetRedactor(piiService)
+	return service
 }
 
 // ---------------------------------------------------------------------------
@@ -365,8 +475,12 @@ func provideRouteService(log *slog.Logger, queries *dbsqlc.Queries, chatService
 	return route.NewService(log, queries, chatService)
 }
 
-func provideMessageService(log *slog.Logger, queries *dbsqlc.Queries, hub *event.Hub) *message.DBService {
-	return message.NewService(log, queries, hub)
+func provideTTSService(log *slog.Logger, queries *dbsqlc.Queries, settingsService *settings.Service, routeService *route.DBService, mediaService *media.Service) *tts.Service {
+	return tts.NewService(log, queries, settingsService, routeService, mediaService)
+}
+
+func provideMessageService(log *slog.Logger, conn *pgxpool.Pool, queries *dbsqlc.Queries, hub *event.Hub) *message.DBService {
+	return message.NewService(log, conn, queries, hub)
 }
 
 func provideScheduleTriggerer(resolver *flow.Resolver) schedule.Triggerer {
@@ -377,14 +491,52 @@ func provideScheduleTriggerer(resolver *flow.Resolver) schedule.Triggerer {
 // conversation flow
 // ---------------------------------------------------------------------------
 
-func provideChatResolver(log *slog.Logger, cfg config.Config, modelsService *models.Service, queries *dbsqlc.Queries, memoryService *memory.Service, chatService *conversation.Service, msgService *message.DBService, settingsService *settings.Service, mediaService *media.Service, containerdHandler *handlers.ContainerdHandler, inboxService *inbox.Service) *flow.Resolver {
+func provideGatewayPool(log *slog.Logger, cfg config.Config) (*gateway.Pool, error) {
+	strategy, err := gateway.ParseStrategy(cfg.AgentGateway.Balance)
+	if err != nil {
+		return nil, err
+	}
+	return gateway.NewPool(log, cfg.AgentGateway.EndpointURLs(), gateway.Options{
+		Strategy:      strategy,
+		ProbeInterval: time.Duration(cfg.AgentGateway.HealthCheckIntervalSeconds) * time.Second,
+	}), nil
+}
+
+func provideChatResolver(log *slog.Logger, cfg config.Config, modelsService *models.Service, queries *dbsqlc.Queries, memoryService *memory.Service, chatService *conversation.Service, msgService *message.DBService, settingsService *settings.Service, mediaService *media.Service, containerdHandler *handlers.ContainerdHandler, inboxService *inbox.Service, memoryLLM memory.LLM, quotaService *quota.Service, gatewayPool *gateway.Pool, recordingService *recording.Service, moderationService *moderation.Service, piiService *pii.Service) *flow.Resolver {
 	resolver := flow.NewResolver(log, modelsService, queries, memoryService, chatService, msgService, settingsService, cfg.AgentGateway.BaseURL(), 120*time.Second)
 	resolver.SetSkillLoader(&skillLoaderAdapter{handler: containerdHandler})
 	resolver.SetGatewayAssetLoader(&gatewayAssetLoaderAdapter{media: mediaService})
+	resolver.SetDocumentExtractor(docextract.NewService(log, docextract.Config{}))
 	resolver.SetInboxService(inboxService)
+	resolver.SetHistorySummarizer(memoryLLM)
+	resolver.SetTokenizers(provideTokenizers(log, cfg.Tokenizer))
+	resolver.SetQuotaEnforcer(quotaService)
+	resolver.SetGatewayPool(gatewayPool)
+	resolver.SetGatewayRecorder(recordingService)
+	recordingService.SetReplayer(resolver)
+	resolver.SetModerator(moderationService)
+	resolver.SetRedactor(piiService)
 	return resolver
 }
 
+// provideTokenizers loads the tiktoken encodings found in the configured
+// directory. Without them OpenAI models fall back to approximate counting.
+func provideTokenizers(log *slog.Logger, cfg config.TokenizerConfig) *tokenizer.Registry {
+	registry := tokenizer.NewRegistry()
+	dir := strings.TrimSpace(cfg.EncodingsDir)
+	if dir == "" {
+		return registry
+	}
+	loaded, err := registry.LoadDir(dir)
+	if err != nil {
+		log.Warn("load tokenizer encodings failed", slog.String("dir", dir), slog.Any("error", err))
+	}
+	if len(loaded) > 0 {
+		log.Info("tokenizer encodings loaded", slog.String("dir", dir), slog.Any("encodings", loaded))
+	}
+	return registry
+}
+
 // ---------------------------------------------------------------------------
 // channel providers
 // ---------------------------------------------------------------------------
@@ -417,12 +569,20 @@ func provideChannelRouter(
 	bindService *bind.Service,
 	mediaService *media.Service,
 	inboxService *inbox.Service,
+	ttsService *tts.Service,
+	chatService *conversation.Service,
+	toolApprovals *toolapproval.Service,
+	moderationService *moderation.Service,
 	rc *boot.RuntimeConfig,
 ) *inbound.ChannelInboundProcessor {
 	processor := inbound.NewChannelInboundProcessor(log, registry, routeService, msgService, resolver, identityService, botService, policyService, preauthService, bindService, rc.JwtSecret, 5*time.Minute)
 	processor.SetMediaService(mediaService)
 	processor.SetStreamObserver(local.NewRouteHubBroadcaster(hub))
 	processor.SetInboxService(inboxService)
+	processor.SetVoiceReplySynthesizer(ttsService)
+	processor.SetConversationSettings(chatService)
+	processor.SetToolApprovals(toolApprovals)
+	processor.SetModerator(moderationService)
 	return processor
 }
 
@@ -446,7 +606,7 @@ func provideContainerdHandler(log *slog.Logger, service ctr.Service, manager *mc
 	return handlers.NewContainerdHandler(log, service, manager, cfg.MCP, cfg.Containerd.Namespace, rc.ContainerBackend, botService, accountService, policyService, queries)
 }
 
-func provideToolGatewayService(log *slog.Logger, cfg config.Config, channelManager *channel.Manager, registry *channel.Registry, routeService *route.DBService, scheduleService *schedule.Service, memoryService *memory.Service, chatService *conversation.Service, accountService *accounts.Service, settingsService *settings.Service, searchProviderService *searchproviders.Service, manager *mcp.Manager, containerdHandler *handlers.ContainerdHandler, mcpConnService *mcp.ConnectionService, mediaService *media.Service, inboxService *inbox.Service) *mcp.ToolGatewayService {
+func provideToolGatewayService(log *slog.Logger, cfg config.Config, channelManager *channel.Manager, registry *channel.Registry, routeService *route.DBService, scheduleService *schedule.Service, memoryService *memory.Service, chatService *conversation.Service, accountService *accounts.Service, settingsService *settings.Service, searchProviderService *searchproviders.Service, manager *mcp.Manager, containerdHandler *handlers.ContainerdHandler, mcpConnService *mcp.ConnectionService, mediaService *media.Service, inboxService *inbox.Service, msgService *message.DBService, toolApprovals *toolapproval.Service, piiService *pii.Service, identityService *identities.Service, policyService *policy.Service) *mcp.ToolGatewayService {
 	var assetResolver mcpmessage.AssetResolver
 	if mediaService != nil {
 		assetResolver = &mediaAssetResolverAdapter{media: mediaService}
@@ -454,9 +614,10 @@ func provideToolGatewayService(log *slog.Logger, cfg config.Config, channelManag
 	messageExec := mcpmessage.NewExecutor(log, channelManager, channelManager, registry, assetResolver)
 	contactsExec := mcpcontacts.NewExecutor(log, routeService)
 	scheduleExec := mcpschedule.NewExecutor(log, scheduleService)
-	memoryExec := mcpmemory.NewExecutor(log, memoryService, chatService, accountService)
+	memoryExec := mcpmemory.NewExecutor(log, memoryService, chatService, accountService, routeService, identityService)
 	webExec := mcpweb.NewExecutor(log, settingsService, searchProviderService)
 	inboxExec := mcpinbox.NewExecutor(log, inboxService)
+	historyExec := mcphistory.NewExecutor(log, msgService, routeService, identityService, policyService)
 	execWorkDir := cfg.MCP.DataMount
 	if strings.TrimSpace(execWorkDir) == "" {
 		execWorkDir = config.DefaultDataMount
@@ -468,9 +629,12 @@ func provideToolGatewayService(log *slog.Logger, cfg config.Config, channelManag
 
 	svc := mcp.NewToolGatewayService(
 		log,
-		[]mcp.ToolExecutor{messageExec, contactsExec, scheduleExec, memoryExec, webExec, fsExec, inboxExec},
+		[]mcp.ToolExecutor{messageExec, contactsExec, scheduleExec, memoryExec, webExec, fsExec, inboxExec, historyExec},
 		[]mcp.ToolSource{fedSource},
 	)
+	toolApprovals.SetChannelSender(channelManager, registry)
+	svc.SetReviewer(toolApprovals)
+	svc.SetRedactor(piiService)
 	containerdHandler.SetToolGatewayService(svc)
 	return svc
 }
@@ -495,12 +659,25 @@ func provideAuthHandler(log *slog.Logger, accountService *accounts.Service, rc *
 	return handlers.NewAuthHandler(log, accountService, rc.JwtSecret, rc.JwtExpiresIn)
 }
 
-func provideMessageHandler(log *slog.Logger, chatService *conversation.Service, msgService *message.DBService, mediaService *media.Service, botService *bots.Service, accountService *accounts.Service, hub *event.Hub) *handlers.MessageHandler {
+func provideMessageHandler(log *slog.Logger, chatService *conversation.Service, msgService *message.DBService, mediaService *media.Service, botService *bots.Service, accountService *accounts.Service, routeService *route.DBService, hub *event.Hub, resolver *flow.Resolver, rc *boot.RuntimeConfig) *handlers.MessageHandler {
 	h := handlers.NewMessageHandler(log, chatService, msgService, botService, accountService, hub)
 	h.SetMediaService(mediaService)
+	h.SetBranchRunner(resolver, rc.JwtSecret)
+	archiveService := archive.NewService(log, msgService, mediaService)
+	archiveService.SetRouteLister(routeService)
+	h.SetArchiveService(archiveService)
+	h.SetGenerationCanceller(resolver)
 	return h
 }
 
+func provideRetentionService(log *slog.Logger, queries *dbsqlc.Queries, msgService *message.DBService, mediaService *media.Service) *retention.Service {
+	return retention.NewService(log, queries, archive.NewService(log, msgService, mediaService), mediaService)
+}
+
+func provideGatewayRecordingHandler(log *slog.Logger, service *recording.Service, botService *bots.Service, accountService *accounts.Service, rc *boot.RuntimeConfig) *handlers.GatewayRecordingHandler {
+	return handlers.NewGatewayRecordingHandler(log, service, botService, accountService, rc.JwtSecret)
+}
+
 func provideMediaService(log *slog.Logger, cfg config.Config) (*media.Service, error) {
 	dataRoot := strings.TrimSpace(cfg.MCP.DataRoot)
 	if dataRoot == "" {
@@ -517,6 +694,14 @@ func provideUsersHandler(log *slog.Logger, accountService *accounts.Service, ide
 	return handlers.NewUsersHandler(log, accountService, identityService, botService, routeService, channelStore, channelLifecycle, channelManager, registry)
 }
 
+func provideTTSHandler(log *slog.Logger, ttsService *tts.Service, routeService *route.DBService, botService *bots.Service, accountService *accounts.Service) *handlers.TTSHandler {
+	return handlers.NewTTSHandler(log, ttsService, routeService, botService, accountService)
+}
+
+func provideOpenAIHandler(log *slog.Logger, resolver *flow.Resolver, tokenService *apitokens.Service, botService *bots.Service, accountService *accounts.Service, rc *boot.RuntimeConfig) *handlers.OpenAIHandler {
+	return handlers.NewOpenAIHandler(log, resolver, tokenService, botService, accountService, rc.JwtSecret)
+}
+
 func provideCLIHandler(channelManager *channel.Manager, channelStore *channel.Store, chatService *conversation.Service, hub *local.RouteHub, botService *bots.Service, accountService *accounts.Service) *handlers.LocalChannelHandler {
 	return handlers.NewLocalChannelHandler(local.CLIType, channelManager, channelStore, chatService, hub, botService, accountService)
 }
@@ -585,6 +770,31 @@ func startChannelManager(lc fx.Lifecycle, channelManager *channel.Manager) {
 	})
 }
 
+func startRemoteAdapters(lc fx.Lifecycle, log *slog.Logger, cfg config.Config, channelManager *channel.Manager) {
+	configs := make([]remote.ProcessConfig, 0, len(cfg.ChannelAdapters))
+	for _, item := range cfg.ChannelAdapters {
+		configs = append(configs, remote.ProcessConfig{
+			Name:    item.Name,
+			Command: item.Command,
+			Args:    item.Args,
+			Env:     item.Env,
+			Address: item.Address,
+		})
+	}
+	launcher := remote.NewLauncher(log, channelManager, configs)
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			launcher.Start(ctx)
+			return nil
+		},
+		OnStop: func(stopCtx context.Context) error {
+			cancel()
+			return launcher.Stop(stopCtx)
+		},
+	})
+}
+
 func startContainerReconciliation(lc fx.Lifecycle, containerdHandler *handlers.ContainerdHandler, _ *mcp.ToolGatewayService) {
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
@@ -594,7 +804,62 @@ func startContainerReconciliation(lc fx.Lifecycle, containerdHandler *handlers.C
 	})
 }
 
-func startServer(lc fx.Lifecycle, logger *slog.Logger, srv *server.Server, shutdowner fx.Shutdowner, cfg config.Config, queries *dbsqlc.Queries, botService *bots.Service, containerdHandler *handlers.ContainerdHandler, mcpConnService *mcp.ConnectionService, toolGateway *mcp.ToolGatewayService, channelManager *channel.Manager) {
+// startGatewayHealthChecks probes the agent gateway endpoints in the
+// background.
+func startGatewayHealthChecks(lc fx.Lifecycle, gatewayPool *gateway.Pool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			go gatewayPool.Run(ctx)
+			return nil
+		},
+		OnStop: func(_ context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
+
+// startUsageQuotas lets quota notices reach owners over their channels and
+// prunes counters of past periods in the background.
+func startUsageQuotas(lc fx.Lifecycle, quotaService *quota.Service, channelManager *channel.Manager) {
+	quotaService.SetChannelSender(channelManager)
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			go quotaService.Run(ctx)
+			return nil
+		},
+		OnStop: func(_ context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
+
+// startContentModeration lets moderation notices reach owners over their
+// channels.
+func startContentModeration(moderationService *moderation.Service, channelManager *channel.Manager) {
+	moderationService.SetChannelSender(channelManager)
+}
+
+// startMessageRetention purges bot history past its retention policy in the
+// background.
+func startMessageRetention(lc fx.Lifecycle, retentionService *retention.Service) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			go retentionService.Run(ctx)
+			return nil
+		},
+		OnStop: func(_ context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
+
+func startServer(lc fx.Lifecycle, logger *slog.Logger, srv *server.Server, shutdowner fx.Shutdowner, cfg config.Config, queries *dbsqlc.Queries, botService *bots.Service, containerdHandler *handlers.ContainerdHandler, mcpConnService *mcp.ConnectionService, toolGateway *mcp.ToolGatewayService, channelManager *channel.Manager, gatewayPool *gateway.Pool) {
 	fmt.Printf("Starting Memoh Agent %s\n", version.GetInfo())
 
 	lc.Append(fx.Hook{
@@ -609,6 +874,9 @@ func startServer(lc fx.Lifecycle, logger *slog.Logger, srv *server.Server, shutd
 			botService.AddRuntimeChecker(healthcheck.NewRuntimeCheckerAdapter(
 				channelchecker.NewChecker(logger, channelManager),
 			))
+			botService.AddRuntimeChecker(healthcheck.NewRuntimeCheckerAdapter(
+				gatewaychecker.NewChecker(logger, gatewayPool),
+			))
 
 			go func() {
 				if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -749,6 +1017,14 @@ func (c *lazyLLMClient) DetectLanguage(ctx context.Context, text string) (string
 	return client.DetectLanguage(ctx, text)
 }
 
+func (c *lazyLLMClient) Summarize(ctx context.Context, req memory.SummarizeRequest) (memory.SummarizeResponse, error) {
+	client, err := c.resolve(ctx)
+	if err != nil {
+		return memory.SummarizeResponse{}, err
+	}
+	return client.Summarize(ctx, req)
+}
+
 func (c *lazyLLMClient) resolve(ctx context.Context) (memory.LLM, error) {
 	if c.modelsService == nil || c.queries == nil {
 		return nil, fmt.Errorf("models service not configured")
@@ -1,3 +1,12 @@
+DROP TABLE IF EXISTS pii_vault_entries;
+DROP TABLE IF EXISTS gateway_recordings;
+DROP TABLE IF EXISTS message_purge_runs;
+DROP TABLE IF EXISTS bot_history_message_stars;
+DROP TABLE IF EXISTS usage_quota_counters;
+DROP TABLE IF EXISTS tool_approvals;
+DROP TABLE IF EXISTS bot_history_summaries;
+DROP TABLE IF EXISTS bot_history_message_reactions;
+DROP TABLE IF EXISTS api_tokens;
 DROP TABLE IF EXISTS bot_history_message_assets;
 DROP TABLE IF EXISTS media_assets;
 DROP TABLE IF EXISTS bot_storage_bindings;
@@ -9,6 +18,7 @@ DROP TABLE IF EXISTS container_versions;
 DROP TABLE IF EXISTS snapshots;
 DROP TABLE IF EXISTS containers;
 DROP TABLE IF EXISTS bot_history_messages;
+DROP FUNCTION IF EXISTS bot_history_message_text(JSONB);
 DROP TABLE IF EXISTS bot_channel_routes;
 DROP TABLE IF EXISTS channel_identity_bind_codes;
 DROP TABLE IF EXISTS bot_preauth_keys;
@@ -16,6 +26,7 @@ DROP TABLE IF EXISTS bot_channel_configs;
 DROP TABLE IF EXISTS mcp_connections;
 DROP TABLE IF EXISTS bot_members;
 DROP TABLE IF EXISTS bots;
+DROP TABLE IF EXISTS model_prices;
 DROP TABLE IF EXISTS model_variants;
 DROP TABLE IF EXISTS models;
 DROP TABLE IF EXISTS search_providers;
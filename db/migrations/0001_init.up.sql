@@ -1,4 +1,5 @@
 CREATE EXTENSION IF NOT EXISTS pgcrypto;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
 
 DO $$
 BEGIN
@@ -90,7 +91,7 @@ CREATE TABLE IF NOT EXISTS models (
   created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
   updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
   CONSTRAINT models_provider_model_id_unique UNIQUE (llm_provider_id, model_id),
-  CONSTRAINT models_type_check CHECK (type IN ('chat', 'embedding')),
+  CONSTRAINT models_type_check CHECK (type IN ('chat', 'embedding', 'speech')),
   CONSTRAINT models_dimensions_check CHECK (type != 'embedding' OR dimensions IS NOT NULL),
   CONSTRAINT models_client_type_check CHECK (client_type IS NULL OR client_type IN ('openai-responses', 'openai-completions', 'anthropic-messages', 'google-generative-ai')),
   CONSTRAINT models_chat_client_type_check CHECK (type != 'chat' OR client_type IS NOT NULL)
@@ -109,6 +110,19 @@ CREATE TABLE IF NOT EXISTS model_variants (
 CREATE INDEX IF NOT EXISTS idx_model_variants_model_uuid ON model_variants(model_uuid);
 CREATE INDEX IF NOT EXISTS idx_model_variants_variant_id ON model_variants(variant_id);
 
+-- model_prices: token prices per model for cost reports. NULL cached/reasoning
+-- prices fall back to the input/output price.
+CREATE TABLE IF NOT EXISTS model_prices (
+  model_uuid UUID PRIMARY KEY REFERENCES models(id) ON DELETE CASCADE,
+  currency TEXT NOT NULL DEFAULT 'USD',
+  input_per_million DOUBLE PRECISION NOT NULL DEFAULT 0,
+  output_per_million DOUBLE PRECISION NOT NULL DEFAULT 0,
+  cached_input_per_million DOUBLE PRECISION,
+  reasoning_per_million DOUBLE PRECISION,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
 CREATE TABLE IF NOT EXISTS bots (
   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
   owner_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -128,6 +142,17 @@ CREATE TABLE IF NOT EXISTS bots (
   memory_model_id UUID REFERENCES models(id) ON DELETE SET NULL,
   embedding_model_id UUID REFERENCES models(id) ON DELETE SET NULL,
   search_provider_id UUID REFERENCES search_providers(id) ON DELETE SET NULL,
+  tts_enabled BOOLEAN NOT NULL DEFAULT false,
+  tts_model_id UUID REFERENCES models(id) ON DELETE SET NULL,
+  tts_voice TEXT NOT NULL DEFAULT '',
+  fallback_model_ids UUID[] NOT NULL DEFAULT '{}',
+  chat_overrides JSONB NOT NULL DEFAULT '{}'::jsonb,
+  tool_approval_policy JSONB NOT NULL DEFAULT '{}'::jsonb,
+  quota_policy JSONB NOT NULL DEFAULT '{}'::jsonb,
+  retention_policy JSONB NOT NULL DEFAULT '{}'::jsonb,
+  gateway_recording BOOLEAN NOT NULL DEFAULT false,
+  moderation_policy JSONB NOT NULL DEFAULT '{}'::jsonb,
+  pii_policy JSONB NOT NULL DEFAULT '{}'::jsonb,
   metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
   created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
   updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
@@ -235,6 +260,24 @@ CREATE UNIQUE INDEX IF NOT EXISTS idx_bot_channel_routes_unique
   ON bot_channel_routes (bot_id, channel_type, external_conversation_id, COALESCE(external_thread_id, ''));
 CREATE INDEX IF NOT EXISTS idx_bot_channel_routes_bot ON bot_channel_routes(bot_id);
 
+-- bot_history_message_text extracts the readable text of a stored model
+-- message: string content or text parts, without the user header front-matter.
+CREATE OR REPLACE FUNCTION bot_history_message_text(content JSONB) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+  SELECT regexp_replace(
+    CASE jsonb_typeof(content->'content')
+      WHEN 'string' THEN content->>'content'
+      WHEN 'array' THEN COALESCE((
+        SELECT string_agg(part->>'text', E'\n')
+        FROM jsonb_array_elements(content->'content') AS part
+        WHERE part->>'type' = 'text'
+      ), '')
+      ELSE ''
+    END,
+    '^---\n.*?\n---\n', ''
+  )
+$$;
+
 -- bot_history_messages: unified message history under bot scope.
 CREATE TABLE IF NOT EXISTS bot_history_messages (
   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -249,15 +292,29 @@ CREATE TABLE IF NOT EXISTS bot_history_messages (
   content JSONB NOT NULL,
   metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
   usage JSONB,
-  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+  parent_id UUID REFERENCES bot_history_messages(id) ON DELETE SET NULL,
+  superseded_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  search_text TEXT GENERATED ALWAYS AS (bot_history_message_text(content)) STORED
 );
 
 CREATE INDEX IF NOT EXISTS idx_bot_history_messages_bot_created ON bot_history_messages(bot_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_parent ON bot_history_messages(parent_id);
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_active
+  ON bot_history_messages(bot_id, created_at) WHERE superseded_at IS NULL;
 CREATE INDEX IF NOT EXISTS idx_bot_history_messages_route ON bot_history_messages(route_id);
 CREATE INDEX IF NOT EXISTS idx_bot_history_messages_source_lookup
   ON bot_history_messages(channel_type, source_message_id);
 CREATE INDEX IF NOT EXISTS idx_bot_history_messages_reply_lookup
   ON bot_history_messages(channel_type, source_reply_to_message_id);
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_variant_of
+  ON bot_history_messages((metadata->>'variant_of'), created_at) WHERE metadata->>'variant_of' IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_search_fts
+  ON bot_history_messages USING GIN (to_tsvector('simple', search_text));
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_search_trgm
+  ON bot_history_messages USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_usage_created
+  ON bot_history_messages(created_at) WHERE usage IS NOT NULL;
 
 CREATE TABLE IF NOT EXISTS containers (
   id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -408,3 +465,135 @@ CREATE TABLE IF NOT EXISTS bot_inbox (
 
 CREATE INDEX IF NOT EXISTS idx_bot_inbox_bot_unread ON bot_inbox(bot_id, created_at DESC) WHERE is_read = FALSE;
 CREATE INDEX IF NOT EXISTS idx_bot_inbox_bot_created ON bot_inbox(bot_id, created_at DESC);
+
+-- api_tokens: personal API tokens for the OpenAI-compatible endpoints.
+-- Only the SHA-256 hash is stored; the plaintext is shown once on creation.
+CREATE TABLE IF NOT EXISTS api_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL DEFAULT '',
+  token_hash TEXT NOT NULL,
+  token_prefix TEXT NOT NULL DEFAULT '',
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT api_tokens_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+
+-- bot_history_message_reactions: thumbs up/down from users on assistant replies.
+CREATE TABLE IF NOT EXISTS bot_history_message_reactions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  message_id UUID NOT NULL REFERENCES bot_history_messages(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reaction TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT bot_history_message_reactions_reaction_check CHECK (reaction IN ('up', 'down')),
+  CONSTRAINT bot_history_message_reactions_unique UNIQUE (message_id, user_id)
+);
+
+-- bot_history_summaries: versioned rolling summaries of history trimmed from the
+-- context window. route_id is NULL for bot-level history.
+CREATE TABLE IF NOT EXISTS bot_history_summaries (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  route_id UUID REFERENCES bot_channel_routes(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  content TEXT NOT NULL,
+  covered_until TIMESTAMPTZ NOT NULL,
+  message_count INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bot_history_summaries_version ON bot_history_summaries(bot_id, route_id, version) NULLS NOT DISTINCT;
+
+-- tool_approvals: audit trail of tool calls held for bot owner approval.
+CREATE TABLE IF NOT EXISTS tool_approvals (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  tool_name TEXT NOT NULL,
+  arguments JSONB NOT NULL DEFAULT '{}'::jsonb,
+  rule TEXT NOT NULL DEFAULT '',
+  platform TEXT NOT NULL DEFAULT '',
+  requested_by_channel_identity_id UUID,
+  status TEXT NOT NULL DEFAULT 'pending',
+  decided_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  decision_note TEXT NOT NULL DEFAULT '',
+  expires_at TIMESTAMPTZ NOT NULL,
+  decided_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT tool_approvals_status_check CHECK (status IN ('pending', 'approved', 'denied', 'expired'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_approvals_bot_created ON tool_approvals(bot_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tool_approvals_pending ON tool_approvals(bot_id, created_at) WHERE status = 'pending';
+
+-- usage_quota_counters: usage metered against a bot's quota policy, one row
+-- per scope subject and period.
+CREATE TABLE IF NOT EXISTS usage_quota_counters (
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  scope TEXT NOT NULL,
+  subject TEXT NOT NULL DEFAULT '',
+  period TEXT NOT NULL,
+  period_start TIMESTAMPTZ NOT NULL,
+  requests BIGINT NOT NULL DEFAULT 0,
+  tokens BIGINT NOT NULL DEFAULT 0,
+  cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+  notified JSONB NOT NULL DEFAULT '{}'::jsonb,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (bot_id, scope, subject, period, period_start),
+  CONSTRAINT usage_quota_counters_scope_check CHECK (scope IN ('bot', 'user', 'guest')),
+  CONSTRAINT usage_quota_counters_period_check CHECK (period IN ('day', 'week', 'month'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_quota_counters_period_start ON usage_quota_counters(period_start);
+
+-- bot_history_message_stars: messages a bot's retention policy can be told to keep.
+CREATE TABLE IF NOT EXISTS bot_history_message_stars (
+  message_id UUID PRIMARY KEY REFERENCES bot_history_messages(id) ON DELETE CASCADE,
+  starred_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- message_purge_runs: one row per retention purge that deleted history.
+CREATE TABLE IF NOT EXISTS message_purge_runs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  trigger TEXT NOT NULL,
+  deleted_messages BIGINT NOT NULL DEFAULT 0,
+  deleted_assets BIGINT NOT NULL DEFAULT 0,
+  archive_content_hash TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT message_purge_runs_trigger_check CHECK (trigger IN ('schedule', 'manual'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_purge_runs_bot_created ON message_purge_runs(bot_id, created_at DESC);
+
+-- gateway_recordings: agent gateway requests and responses kept for replay.
+CREATE TABLE IF NOT EXISTS gateway_recordings (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  route_id UUID REFERENCES bot_channel_routes(id) ON DELETE SET NULL,
+  kind TEXT NOT NULL,
+  model_id UUID REFERENCES models(id) ON DELETE SET NULL,
+  request JSONB NOT NULL,
+  response JSONB NOT NULL,
+  latency_ms BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT gateway_recordings_kind_check CHECK (kind IN ('chat', 'stream', 'schedule'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_gateway_recordings_bot_created ON gateway_recordings(bot_id, created_at DESC);
+
+-- pii_vault_entries: personal data a bot redacted, by the placeholder that stands in for it.
+CREATE TABLE IF NOT EXISTS pii_vault_entries (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  ordinal INTEGER NOT NULL,
+  value TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT pii_vault_entries_value_unique UNIQUE (bot_id, value),
+  CONSTRAINT pii_vault_entries_ordinal_unique UNIQUE (bot_id, kind, ordinal)
+);
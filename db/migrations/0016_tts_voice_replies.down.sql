@@ -0,0 +1,10 @@
+-- 0016_tts_voice_replies (rollback)
+-- Remove text-to-speech reply settings from bots and the speech model type.
+
+ALTER TABLE bots DROP COLUMN IF EXISTS tts_voice;
+ALTER TABLE bots DROP COLUMN IF EXISTS tts_model_id;
+ALTER TABLE bots DROP COLUMN IF EXISTS tts_enabled;
+
+DELETE FROM models WHERE type = 'speech';
+ALTER TABLE models DROP CONSTRAINT IF EXISTS models_type_check;
+ALTER TABLE models ADD CONSTRAINT models_type_check CHECK (type IN ('chat', 'embedding'));
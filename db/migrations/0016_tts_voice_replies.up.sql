@@ -0,0 +1,9 @@
+-- 0016_tts_voice_replies
+-- Add the speech model type and per-bot text-to-speech reply settings.
+
+ALTER TABLE models DROP CONSTRAINT IF EXISTS models_type_check;
+ALTER TABLE models ADD CONSTRAINT models_type_check CHECK (type IN ('chat', 'embedding', 'speech'));
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS tts_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS tts_model_id UUID REFERENCES models(id) ON DELETE SET NULL;
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS tts_voice TEXT NOT NULL DEFAULT '';
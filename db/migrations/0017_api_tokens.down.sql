@@ -0,0 +1,4 @@
+-- 0017_api_tokens (rollback)
+-- Remove personal API tokens.
+
+DROP TABLE IF EXISTS api_tokens;
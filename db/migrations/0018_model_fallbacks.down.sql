@@ -0,0 +1,4 @@
+-- 0018_model_fallbacks (rollback)
+-- Remove fallback chat models from bots.
+
+ALTER TABLE bots DROP COLUMN IF EXISTS fallback_model_ids;
@@ -0,0 +1,4 @@
+-- 0018_model_fallbacks
+-- Add an ordered list of fallback chat models tried when the primary model fails.
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS fallback_model_ids UUID[] NOT NULL DEFAULT '{}';
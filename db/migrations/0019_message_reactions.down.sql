@@ -0,0 +1,5 @@
+-- 0019_message_reactions (rollback)
+-- Remove message reactions and the model variant reply index.
+
+DROP INDEX IF EXISTS idx_bot_history_messages_variant_of;
+DROP TABLE IF EXISTS bot_history_message_reactions;
@@ -0,0 +1,14 @@
+-- 0019_message_reactions
+-- Record user reactions on assistant messages and index replies by model variant for A/B reports.
+
+CREATE TABLE IF NOT EXISTS bot_history_message_reactions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  message_id UUID NOT NULL REFERENCES bot_history_messages(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reaction TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT bot_history_message_reactions_reaction_check CHECK (reaction IN ('up', 'down')),
+  CONSTRAINT bot_history_message_reactions_unique UNIQUE (message_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_variant_of ON bot_history_messages ((metadata->>'variant_of'), created_at) WHERE metadata->>'variant_of' IS NOT NULL;
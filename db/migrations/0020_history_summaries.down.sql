@@ -0,0 +1,4 @@
+-- 0020_history_summaries (rollback)
+-- Remove rolling history summaries.
+
+DROP TABLE IF EXISTS bot_history_summaries;
@@ -0,0 +1,15 @@
+-- 0020_history_summaries
+-- Store versioned rolling summaries of history that no longer fits the context window.
+
+CREATE TABLE IF NOT EXISTS bot_history_summaries (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  route_id UUID REFERENCES bot_channel_routes(id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  content TEXT NOT NULL,
+  covered_until TIMESTAMPTZ NOT NULL,
+  message_count INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_history_summaries_scope ON bot_history_summaries(bot_id, route_id, version DESC);
@@ -0,0 +1,4 @@
+-- 0021_chat_overrides (rollback)
+-- Remove per-conversation chat setting overrides.
+
+ALTER TABLE bots DROP COLUMN IF EXISTS chat_overrides;
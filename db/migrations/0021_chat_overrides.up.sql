@@ -0,0 +1,4 @@
+-- 0021_chat_overrides
+-- Store per-conversation overrides of bot chat settings (system prompt, sampling, reasoning, context, skills, tools).
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS chat_overrides JSONB NOT NULL DEFAULT '{}'::jsonb;
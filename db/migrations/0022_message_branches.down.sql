@@ -0,0 +1,8 @@
+-- 0022_message_branches (rollback)
+-- Drop message branches; superseded turns are deleted so history is linear again.
+
+DELETE FROM bot_history_messages WHERE superseded_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_bot_history_messages_active;
+DROP INDEX IF EXISTS idx_bot_history_messages_parent;
+ALTER TABLE bot_history_messages DROP COLUMN IF EXISTS superseded_at;
+ALTER TABLE bot_history_messages DROP COLUMN IF EXISTS parent_id;
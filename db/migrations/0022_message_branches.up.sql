@@ -0,0 +1,20 @@
+-- 0022_message_branches
+-- Link history messages to their parent and keep superseded turns as inactive branches.
+
+ALTER TABLE bot_history_messages ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES bot_history_messages(id) ON DELETE SET NULL;
+ALTER TABLE bot_history_messages ADD COLUMN IF NOT EXISTS superseded_at TIMESTAMPTZ;
+
+-- Existing history is a single linear branch per route.
+UPDATE bot_history_messages m
+SET parent_id = p.prev_id
+FROM (
+  SELECT id, LAG(id) OVER (PARTITION BY bot_id, route_id ORDER BY created_at, id) AS prev_id
+  FROM bot_history_messages
+) p
+WHERE m.id = p.id
+  AND m.parent_id IS NULL
+  AND p.prev_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_parent ON bot_history_messages(parent_id);
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_active
+  ON bot_history_messages(bot_id, created_at) WHERE superseded_at IS NULL;
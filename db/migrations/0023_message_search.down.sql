@@ -0,0 +1,7 @@
+-- 0023_message_search (rollback)
+-- Drop the history search column, its indexes and the text extraction function.
+
+DROP INDEX IF EXISTS idx_bot_history_messages_search_trgm;
+DROP INDEX IF EXISTS idx_bot_history_messages_search_fts;
+ALTER TABLE bot_history_messages DROP COLUMN IF EXISTS search_text;
+DROP FUNCTION IF EXISTS bot_history_message_text(JSONB);
@@ -0,0 +1,30 @@
+-- 0023_message_search
+-- Index the text of history messages for full-text and trigram search.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- bot_history_message_text extracts the readable text of a stored model
+-- message: string content or text parts, without the user header front-matter.
+CREATE OR REPLACE FUNCTION bot_history_message_text(content JSONB) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+  SELECT regexp_replace(
+    CASE jsonb_typeof(content->'content')
+      WHEN 'string' THEN content->>'content'
+      WHEN 'array' THEN COALESCE((
+        SELECT string_agg(part->>'text', E'\n')
+        FROM jsonb_array_elements(content->'content') AS part
+        WHERE part->>'type' = 'text'
+      ), '')
+      ELSE ''
+    END,
+    '^---\n.*?\n---\n', ''
+  )
+$$;
+
+ALTER TABLE bot_history_messages
+  ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (bot_history_message_text(content)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_search_fts
+  ON bot_history_messages USING GIN (to_tsvector('simple', search_text));
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_search_trgm
+  ON bot_history_messages USING GIN (search_text gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- 0024_tool_approvals (rollback)
+-- Drop tool approval policies and their audit trail.
+
+DROP TABLE IF EXISTS tool_approvals;
+ALTER TABLE bots DROP COLUMN IF EXISTS tool_approval_policy;
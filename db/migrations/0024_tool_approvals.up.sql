@@ -0,0 +1,24 @@
+-- 0024_tool_approvals
+-- Per-bot policy of tool calls that need owner approval, and the audit trail of those calls.
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS tool_approval_policy JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS tool_approvals (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  tool_name TEXT NOT NULL,
+  arguments JSONB NOT NULL DEFAULT '{}'::jsonb,
+  rule TEXT NOT NULL DEFAULT '',
+  platform TEXT NOT NULL DEFAULT '',
+  requested_by_channel_identity_id UUID,
+  status TEXT NOT NULL DEFAULT 'pending',
+  decided_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  decision_note TEXT NOT NULL DEFAULT '',
+  expires_at TIMESTAMPTZ NOT NULL,
+  decided_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT tool_approvals_status_check CHECK (status IN ('pending', 'approved', 'denied', 'expired'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_approvals_bot_created ON tool_approvals(bot_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tool_approvals_pending ON tool_approvals(bot_id, created_at) WHERE status = 'pending';
@@ -0,0 +1,5 @@
+-- 0025_model_prices (rollback)
+-- Drop model prices.
+
+DROP INDEX IF EXISTS idx_bot_history_messages_usage_created;
+DROP TABLE IF EXISTS model_prices;
@@ -0,0 +1,16 @@
+-- 0025_model_prices
+-- Per-model token prices used to turn stored message usage into cost reports.
+
+CREATE TABLE IF NOT EXISTS model_prices (
+  model_uuid UUID PRIMARY KEY REFERENCES models(id) ON DELETE CASCADE,
+  currency TEXT NOT NULL DEFAULT 'USD',
+  input_per_million DOUBLE PRECISION NOT NULL DEFAULT 0,
+  output_per_million DOUBLE PRECISION NOT NULL DEFAULT 0,
+  cached_input_per_million DOUBLE PRECISION,
+  reasoning_per_million DOUBLE PRECISION,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_history_messages_usage_created
+  ON bot_history_messages(created_at) WHERE usage IS NOT NULL;
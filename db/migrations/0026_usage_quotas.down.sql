@@ -0,0 +1,5 @@
+-- 0026_usage_quotas (rollback)
+-- Drop usage quota policies and counters.
+
+DROP TABLE IF EXISTS usage_quota_counters;
+ALTER TABLE bots DROP COLUMN IF EXISTS quota_policy;
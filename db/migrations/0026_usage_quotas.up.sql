@@ -0,0 +1,22 @@
+-- 0026_usage_quotas
+-- Per-bot usage quota policy and the per-period counters it is enforced against.
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS quota_policy JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS usage_quota_counters (
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  scope TEXT NOT NULL,
+  subject TEXT NOT NULL DEFAULT '',
+  period TEXT NOT NULL,
+  period_start TIMESTAMPTZ NOT NULL,
+  requests BIGINT NOT NULL DEFAULT 0,
+  tokens BIGINT NOT NULL DEFAULT 0,
+  cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+  notified JSONB NOT NULL DEFAULT '{}'::jsonb,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (bot_id, scope, subject, period, period_start),
+  CONSTRAINT usage_quota_counters_scope_check CHECK (scope IN ('bot', 'user', 'guest')),
+  CONSTRAINT usage_quota_counters_period_check CHECK (period IN ('day', 'week', 'month'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_quota_counters_period_start ON usage_quota_counters(period_start);
@@ -0,0 +1,6 @@
+-- 0027_message_retention (rollback)
+-- Drop message retention policies, stars and purge runs.
+
+DROP TABLE IF EXISTS message_purge_runs;
+DROP TABLE IF EXISTS bot_history_message_stars;
+ALTER TABLE bots DROP COLUMN IF EXISTS retention_policy;
@@ -0,0 +1,23 @@
+-- 0027_message_retention
+-- Per-bot message retention policy, starred messages kept by it, and a log of purge runs.
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS retention_policy JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS bot_history_message_stars (
+  message_id UUID PRIMARY KEY REFERENCES bot_history_messages(id) ON DELETE CASCADE,
+  starred_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS message_purge_runs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  trigger TEXT NOT NULL,
+  deleted_messages BIGINT NOT NULL DEFAULT 0,
+  deleted_assets BIGINT NOT NULL DEFAULT 0,
+  archive_content_hash TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT message_purge_runs_trigger_check CHECK (trigger IN ('schedule', 'manual'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_purge_runs_bot_created ON message_purge_runs(bot_id, created_at DESC);
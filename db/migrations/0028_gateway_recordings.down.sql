@@ -0,0 +1,5 @@
+-- 0028_gateway_recordings (rollback)
+-- Drop gateway recordings and the per-bot recording switch.
+
+DROP TABLE IF EXISTS gateway_recordings;
+ALTER TABLE bots DROP COLUMN IF EXISTS gateway_recording;
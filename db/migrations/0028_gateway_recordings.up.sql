@@ -0,0 +1,19 @@
+-- 0028_gateway_recordings
+-- Per-bot switch to record agent gateway requests and responses for replay.
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS gateway_recording BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS gateway_recordings (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  route_id UUID REFERENCES bot_channel_routes(id) ON DELETE SET NULL,
+  kind TEXT NOT NULL,
+  model_id UUID REFERENCES models(id) ON DELETE SET NULL,
+  request JSONB NOT NULL,
+  response JSONB NOT NULL,
+  latency_ms BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT gateway_recordings_kind_check CHECK (kind IN ('chat', 'stream', 'schedule'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_gateway_recordings_bot_created ON gateway_recordings(bot_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- 0029_moderation (rollback)
+-- Drop content moderation policies.
+
+ALTER TABLE bots DROP COLUMN IF EXISTS moderation_policy;
@@ -0,0 +1,4 @@
+-- 0029_moderation
+-- Per-bot content moderation policy applied to user input and assistant output.
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS moderation_policy JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,5 @@
+-- 0030_pii_vault (rollback)
+-- Drop the PII vault and redaction policies.
+
+DROP TABLE IF EXISTS pii_vault_entries;
+ALTER TABLE bots DROP COLUMN IF EXISTS pii_policy;
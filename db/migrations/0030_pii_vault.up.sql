@@ -0,0 +1,15 @@
+-- 0030_pii_vault
+-- Per-bot PII redaction policy and the vault mapping placeholders back to redacted values.
+
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS pii_policy JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE TABLE IF NOT EXISTS pii_vault_entries (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  bot_id UUID NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  ordinal INTEGER NOT NULL,
+  value TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT pii_vault_entries_value_unique UNIQUE (bot_id, value),
+  CONSTRAINT pii_vault_entries_ordinal_unique UNIQUE (bot_id, kind, ordinal)
+);
@@ -0,0 +1,5 @@
+-- 0031_memory_pgvector (rollback)
+-- Drop the pgvector memory tables. The vector extension is left installed.
+
+DROP TABLE IF EXISTS memory_point_vectors;
+DROP TABLE IF EXISTS memory_points;
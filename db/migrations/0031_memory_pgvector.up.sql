@@ -0,0 +1,33 @@
+-- 0031_memory_pgvector
+-- Tables of the pgvector memory backend. They need the vector extension, which
+-- the plain postgres image lacks, so nothing is created where it is not
+-- available. The HNSW indexes depend on the embedding dimensions and are
+-- created by the server on start.
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+    RAISE NOTICE 'pgvector is not installed, skipping the memory tables';
+    RETURN;
+  END IF;
+
+  CREATE EXTENSION IF NOT EXISTS vector;
+
+  CREATE TABLE IF NOT EXISTS memory_points (
+    id UUID PRIMARY KEY,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    sparse_indices BIGINT[],
+    sparse_values REAL[],
+    search_text TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(payload->>'data', ''))) STORED
+  );
+  CREATE INDEX IF NOT EXISTS memory_points_payload_idx ON memory_points USING gin (payload jsonb_path_ops);
+  CREATE INDEX IF NOT EXISTS memory_points_search_idx ON memory_points USING gin (search_text);
+
+  CREATE TABLE IF NOT EXISTS memory_point_vectors (
+    point_id UUID NOT NULL REFERENCES memory_points(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    embedding VECTOR NOT NULL,
+    PRIMARY KEY (point_id, name)
+  );
+END
+$$;
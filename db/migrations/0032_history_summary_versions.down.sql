@@ -0,0 +1,5 @@
+-- 0032_history_summary_versions (rollback)
+-- Replace the unique version index with the plain lookup index.
+
+DROP INDEX IF EXISTS idx_bot_history_summaries_version;
+CREATE INDEX IF NOT EXISTS idx_bot_history_summaries_scope ON bot_history_summaries(bot_id, route_id, version DESC);
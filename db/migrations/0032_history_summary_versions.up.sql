@@ -0,0 +1,14 @@
+-- 0032_history_summary_versions
+-- Make summary versions unique per bot and route so concurrent refreshes cannot
+-- both store the same version. Existing duplicates are renumbered first.
+
+UPDATE bot_history_summaries s
+SET version = ranked.version
+FROM (
+  SELECT id, ROW_NUMBER() OVER (PARTITION BY bot_id, route_id ORDER BY version, created_at, id)::integer AS version
+  FROM bot_history_summaries
+) ranked
+WHERE s.id = ranked.id AND s.version <> ranked.version;
+
+DROP INDEX IF EXISTS idx_bot_history_summaries_scope;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bot_history_summaries_version ON bot_history_summaries(bot_id, route_id, version) NULLS NOT DISTINCT;
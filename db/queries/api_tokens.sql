@@ -0,0 +1,26 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, token_hash, token_prefix, expires_at, last_used_at, created_at;
+
+-- name: ListAPITokensByUser :many
+SELECT id, user_id, name, token_hash, token_prefix, expires_at, last_used_at, created_at
+FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetAPITokenByHash :one
+SELECT id, user_id, name, token_hash, token_prefix, expires_at, last_used_at, created_at
+FROM api_tokens
+WHERE token_hash = $1
+  AND (expires_at IS NULL OR expires_at > now())
+LIMIT 1;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens
+SET last_used_at = now()
+WHERE id = $1;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE id = $1 AND user_id = $2;
@@ -205,17 +205,27 @@ ON CONFLICT (bot_id, user_id) DO NOTHING;
 -- chat_settings
 
 -- name: UpsertChatSettings :one
+-- chat_overrides is merged into the stored overrides after dropping the keys
+-- in cleared_overrides, so concurrent updates of different fields keep each
+-- other's changes.
 WITH
 updated AS (
   UPDATE bots
   SET chat_model_id = COALESCE(sqlc.narg(chat_model_id)::uuid, bots.chat_model_id),
+      fallback_model_ids = COALESCE(sqlc.narg(fallback_model_ids)::uuid[], bots.fallback_model_ids),
+      chat_overrides = CASE
+        WHEN sqlc.narg(chat_overrides)::jsonb IS NULL THEN bots.chat_overrides
+        ELSE (COALESCE(bots.chat_overrides, '{}'::jsonb) - sqlc.arg(cleared_overrides)::text[]) || sqlc.narg(chat_overrides)::jsonb
+      END,
       updated_at = now()
   WHERE bots.id = sqlc.arg(id)
-  RETURNING bots.id, bots.chat_model_id, bots.updated_at
+  RETURNING bots.id, bots.chat_model_id, bots.fallback_model_ids, bots.chat_overrides, bots.updated_at
 )
 SELECT
   updated.id AS chat_id,
   chat_models.id AS model_id,
+  updated.fallback_model_ids,
+  updated.chat_overrides,
   updated.updated_at
 FROM updated
 LEFT JOIN models chat_models ON chat_models.id = updated.chat_model_id;
@@ -224,6 +234,8 @@ LEFT JOIN models chat_models ON chat_models.id = updated.chat_model_id;
 SELECT
   b.id AS chat_id,
   chat_models.id AS model_id,
+  b.fallback_model_ids,
+  b.chat_overrides,
   b.updated_at
 FROM bots b
 LEFT JOIN models chat_models ON chat_models.id = b.chat_model_id
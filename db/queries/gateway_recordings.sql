@@ -0,0 +1,41 @@
+-- name: GetBotGatewayRecording :one
+SELECT gateway_recording
+FROM bots
+WHERE id = $1;
+
+-- name: SetBotGatewayRecording :one
+UPDATE bots
+SET gateway_recording = sqlc.arg(gateway_recording),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING gateway_recording;
+
+-- name: CreateGatewayRecording :one
+INSERT INTO gateway_recordings (bot_id, route_id, kind, model_id, request, response, latency_ms)
+VALUES (
+  sqlc.arg(bot_id),
+  sqlc.narg(route_id),
+  sqlc.arg(kind),
+  sqlc.narg(model_id),
+  sqlc.arg(request),
+  sqlc.arg(response),
+  sqlc.arg(latency_ms)
+)
+RETURNING *;
+
+-- name: GetGatewayRecording :one
+SELECT * FROM gateway_recordings
+WHERE id = sqlc.arg(id)
+  AND bot_id = sqlc.arg(bot_id);
+
+-- name: ListGatewayRecordings :many
+-- Recordings without their payloads, newest first.
+SELECT id, bot_id, route_id, kind, model_id, latency_ms, created_at
+FROM gateway_recordings
+WHERE bot_id = sqlc.arg(bot_id)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_count);
+
+-- name: DeleteGatewayRecordings :execrows
+DELETE FROM gateway_recordings
+WHERE bot_id = $1;
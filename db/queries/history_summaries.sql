@@ -0,0 +1,24 @@
+-- name: GetLatestHistorySummary :one
+SELECT * FROM bot_history_summaries
+WHERE bot_id = sqlc.arg(bot_id)
+  AND route_id IS NOT DISTINCT FROM sqlc.narg(route_id)::uuid
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: CreateHistorySummary :one
+INSERT INTO bot_history_summaries (bot_id, route_id, version, content, covered_until, message_count)
+SELECT
+  sqlc.arg(bot_id),
+  sqlc.narg(route_id)::uuid,
+  COALESCE(MAX(version), 0) + 1,
+  sqlc.arg(content),
+  sqlc.arg(covered_until),
+  sqlc.arg(message_count)
+FROM bot_history_summaries
+WHERE bot_id = sqlc.arg(bot_id)
+  AND route_id IS NOT DISTINCT FROM sqlc.narg(route_id)::uuid
+RETURNING *;
+
+-- name: DeleteHistorySummariesByBot :exec
+DELETE FROM bot_history_summaries
+WHERE bot_id = sqlc.arg(bot_id);
@@ -10,7 +10,9 @@ INSERT INTO bot_history_messages (
   role,
   content,
   metadata,
-  usage
+  usage,
+  parent_id,
+  created_at
 )
 VALUES (
   sqlc.arg(bot_id),
@@ -23,7 +25,19 @@ VALUES (
   sqlc.arg(role),
   sqlc.arg(content),
   sqlc.arg(metadata),
-  sqlc.arg(usage)
+  sqlc.arg(usage),
+  -- Without an explicit parent the message continues the active branch of
+  -- its route, unless no_parent starts a new thread.
+  COALESCE(sqlc.narg(parent_id)::uuid, (
+    SELECT p.id FROM bot_history_messages p
+    WHERE p.bot_id = sqlc.arg(bot_id)
+      AND p.route_id IS NOT DISTINCT FROM sqlc.narg(route_id)::uuid
+      AND p.superseded_at IS NULL
+      AND NOT sqlc.arg(no_parent)::boolean
+    ORDER BY p.created_at DESC
+    LIMIT 1
+  )),
+  COALESCE(sqlc.narg(created_at)::timestamptz, now())
 )
 RETURNING
   id,
@@ -38,6 +52,7 @@ RETURNING
   content,
   metadata,
   usage,
+  parent_id,
   created_at;
 
 -- name: ListMessages :many
@@ -54,6 +69,7 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
@@ -77,12 +93,14 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
 FROM bot_history_messages m
 LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
 WHERE m.bot_id = sqlc.arg(bot_id)
+  AND m.superseded_at IS NULL
   AND m.created_at >= sqlc.arg(created_at)
 ORDER BY m.created_at ASC;
 
@@ -100,12 +118,14 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
 FROM bot_history_messages m
 LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
 WHERE m.bot_id = sqlc.arg(bot_id)
+  AND m.superseded_at IS NULL
   AND m.created_at >= sqlc.arg(created_at)
   AND (m.metadata->>'trigger_mode' IS NULL OR m.metadata->>'trigger_mode' != 'passive_sync')
 ORDER BY m.created_at ASC;
@@ -124,12 +144,14 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
 FROM bot_history_messages m
 LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
 WHERE m.bot_id = sqlc.arg(bot_id)
+  AND m.superseded_at IS NULL
   AND m.created_at < sqlc.arg(created_at)
 ORDER BY m.created_at DESC
 LIMIT sqlc.arg(max_count);
@@ -148,15 +170,233 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
 FROM bot_history_messages m
 LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
 WHERE m.bot_id = sqlc.arg(bot_id)
+  AND m.superseded_at IS NULL
 ORDER BY m.created_at DESC
 LIMIT sqlc.arg(max_count);
 
 -- name: DeleteMessagesByBot :exec
 DELETE FROM bot_history_messages
 WHERE bot_id = sqlc.arg(bot_id);
+
+-- name: UpsertMessageReaction :execrows
+INSERT INTO bot_history_message_reactions (message_id, user_id, reaction)
+SELECT m.id, sqlc.arg(user_id), sqlc.arg(reaction)
+FROM bot_history_messages m
+WHERE m.id = sqlc.arg(message_id)
+  AND m.bot_id = sqlc.arg(bot_id)
+  AND m.role = 'assistant'
+ON CONFLICT (message_id, user_id) DO UPDATE SET
+  reaction = EXCLUDED.reaction,
+  created_at = now();
+
+-- name: DeleteMessageReaction :execrows
+DELETE FROM bot_history_message_reactions r
+USING bot_history_messages m
+WHERE r.message_id = m.id
+  AND m.id = sqlc.arg(message_id)
+  AND m.bot_id = sqlc.arg(bot_id)
+  AND r.user_id = sqlc.arg(user_id);
+
+-- name: StarMessage :execrows
+INSERT INTO bot_history_message_stars (message_id, starred_by)
+SELECT m.id, sqlc.arg(starred_by)
+FROM bot_history_messages m
+WHERE m.id = sqlc.arg(message_id)
+  AND m.bot_id = sqlc.arg(bot_id)
+ON CONFLICT (message_id) DO UPDATE SET
+  starred_by = EXCLUDED.starred_by,
+  created_at = now();
+
+-- name: UnstarMessage :execrows
+DELETE FROM bot_history_message_stars s
+USING bot_history_messages m
+WHERE s.message_id = m.id
+  AND m.id = sqlc.arg(message_id)
+  AND m.bot_id = sqlc.arg(bot_id);
+
+-- name: GetMessage :one
+SELECT
+  m.id,
+  m.bot_id,
+  m.route_id,
+  m.sender_channel_identity_id,
+  m.sender_account_user_id AS sender_user_id,
+  m.channel_type AS platform,
+  m.source_message_id AS external_message_id,
+  m.source_reply_to_message_id,
+  m.role,
+  m.content,
+  m.metadata,
+  m.usage,
+  m.parent_id,
+  m.superseded_at,
+  m.created_at,
+  ci.display_name AS sender_display_name,
+  ci.avatar_url AS sender_avatar_url
+FROM bot_history_messages m
+LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
+WHERE m.id = sqlc.arg(id)
+  AND m.bot_id = sqlc.arg(bot_id);
+
+-- name: GetLatestActiveUserMessageID :one
+SELECT id
+FROM bot_history_messages
+WHERE bot_id = sqlc.arg(bot_id)
+  AND route_id IS NOT DISTINCT FROM sqlc.narg(route_id)::uuid
+  AND role = 'user'
+  AND superseded_at IS NULL
+  AND (metadata->>'trigger_mode' IS NULL OR metadata->>'trigger_mode' != 'passive_sync')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ListMessageAlternatives :many
+SELECT
+  m.id,
+  m.bot_id,
+  m.route_id,
+  m.sender_channel_identity_id,
+  m.sender_account_user_id AS sender_user_id,
+  m.channel_type AS platform,
+  m.source_message_id AS external_message_id,
+  m.source_reply_to_message_id,
+  m.role,
+  m.content,
+  m.metadata,
+  m.usage,
+  m.parent_id,
+  m.superseded_at,
+  m.created_at,
+  ci.display_name AS sender_display_name,
+  ci.avatar_url AS sender_avatar_url
+FROM bot_history_messages m
+LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
+WHERE m.bot_id = sqlc.arg(bot_id)
+  AND m.parent_id IS NOT DISTINCT FROM sqlc.narg(parent_id)::uuid
+ORDER BY m.created_at ASC;
+
+-- name: ListMessageSenderIDs :many
+-- Lists the channel identities and users that have messages in a bot.
+SELECT sender_channel_identity_id::text AS sender_id
+FROM bot_history_messages
+WHERE bot_id = sqlc.arg(bot_id) AND sender_channel_identity_id IS NOT NULL
+UNION
+SELECT sender_account_user_id::text
+FROM bot_history_messages
+WHERE bot_id = sqlc.arg(bot_id) AND sender_account_user_id IS NOT NULL;
+
+-- name: ListMessageSourceIDs :many
+-- Pairs every message of a bot with the archived message it was imported
+-- from, or with itself when it was not imported.
+SELECT
+  id,
+  COALESCE(metadata->'imported_from'->>'message_id', id::text)::text AS source_id
+FROM bot_history_messages
+WHERE bot_id = sqlc.arg(bot_id);
+
+-- name: ListMessagesByIDs :many
+-- Loads the given messages of a bot, superseded ones included.
+SELECT
+  m.id,
+  m.bot_id,
+  m.route_id,
+  m.sender_channel_identity_id,
+  m.sender_account_user_id AS sender_user_id,
+  m.channel_type AS platform,
+  m.source_message_id AS external_message_id,
+  m.source_reply_to_message_id,
+  m.role,
+  m.content,
+  m.metadata,
+  m.usage,
+  m.parent_id,
+  m.superseded_at,
+  m.created_at,
+  ci.display_name AS sender_display_name,
+  ci.avatar_url AS sender_avatar_url
+FROM bot_history_messages m
+LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
+WHERE m.bot_id = sqlc.arg(bot_id)
+  AND m.id = ANY(sqlc.arg(ids)::uuid[])
+ORDER BY m.created_at ASC;
+
+-- name: SupersedeActiveMessagesAfter :many
+-- Retires the active messages of one route (or of the bot's routeless
+-- history) created after the cutoff; other conversations keep theirs.
+UPDATE bot_history_messages
+SET superseded_at = now()
+WHERE bot_id = sqlc.arg(bot_id)
+  AND route_id IS NOT DISTINCT FROM sqlc.narg(route_id)::uuid
+  AND superseded_at IS NULL
+  AND created_at > sqlc.arg(created_at)
+RETURNING id, created_at;
+
+-- name: RestoreMessageBranch :execrows
+-- Re-activates a superseded message and the rest of the branch that was
+-- superseded together with it.
+UPDATE bot_history_messages m
+SET superseded_at = NULL
+FROM bot_history_messages t
+WHERE t.id = sqlc.arg(id)
+  AND t.bot_id = sqlc.arg(bot_id)
+  AND m.bot_id = t.bot_id
+  AND m.route_id IS NOT DISTINCT FROM t.route_id
+  AND m.superseded_at = t.superseded_at
+  AND m.created_at >= t.created_at;
+
+-- name: RestoreMessages :exec
+UPDATE bot_history_messages
+SET superseded_at = NULL
+WHERE bot_id = sqlc.arg(bot_id)
+  AND id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: SearchMessages :many
+-- Full-text match on the active branch, with a substring fallback for text
+-- the simple parser does not split into words (CJK). pattern and sender_name
+-- have their LIKE wildcards escaped with a backslash. route_scoped limits the
+-- search to route_id, or to the routeless history when it is null.
+SELECT
+  m.id,
+  m.bot_id,
+  m.route_id,
+  m.sender_channel_identity_id,
+  m.sender_account_user_id AS sender_user_id,
+  m.channel_type AS platform,
+  m.source_message_id AS external_message_id,
+  m.source_reply_to_message_id,
+  m.role,
+  m.content,
+  m.metadata,
+  m.usage,
+  m.parent_id,
+  m.created_at,
+  m.search_text,
+  ci.display_name AS sender_display_name,
+  ci.avatar_url AS sender_avatar_url
+FROM bot_history_messages m
+LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
+WHERE m.bot_id = sqlc.arg(bot_id)
+  AND m.superseded_at IS NULL
+  AND (
+    to_tsvector('simple', m.search_text) @@ websearch_to_tsquery('simple', sqlc.arg(query)::text)
+    OR m.search_text ILIKE '%' || sqlc.arg(pattern)::text || '%' ESCAPE '\'
+  )
+  AND (sqlc.narg(platform)::text IS NULL OR m.channel_type = sqlc.narg(platform)::text)
+  AND (sqlc.narg(role)::text IS NULL OR m.role = sqlc.narg(role)::text)
+  AND (
+    sqlc.narg(sender_id)::uuid IS NULL
+    OR m.sender_channel_identity_id = sqlc.narg(sender_id)::uuid
+    OR m.sender_account_user_id = sqlc.narg(sender_id)::uuid
+  )
+  AND (sqlc.narg(sender_name)::text IS NULL OR ci.display_name ILIKE '%' || sqlc.narg(sender_name)::text || '%' ESCAPE '\')
+  AND (sqlc.narg(start_time)::timestamptz IS NULL OR m.created_at >= sqlc.narg(start_time)::timestamptz)
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR m.created_at <= sqlc.narg(end_time)::timestamptz)
+  AND (NOT sqlc.arg(route_scoped)::boolean OR m.route_id IS NOT DISTINCT FROM sqlc.narg(route_id)::uuid)
+ORDER BY ts_rank(to_tsvector('simple', m.search_text), websearch_to_tsquery('simple', sqlc.arg(query)::text)) DESC, m.created_at DESC
+LIMIT sqlc.arg(max_count);
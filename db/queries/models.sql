@@ -142,3 +142,48 @@ RETURNING *;
 SELECT * FROM model_variants
 WHERE model_uuid = sqlc.arg(model_uuid)
 ORDER BY weight DESC, created_at DESC;
+
+-- name: GetModelVariant :one
+SELECT * FROM model_variants
+WHERE id = sqlc.arg(id) AND model_uuid = sqlc.arg(model_uuid);
+
+-- name: UpdateModelVariant :one
+UPDATE model_variants
+SET
+  variant_id = sqlc.arg(variant_id),
+  weight = sqlc.arg(weight),
+  metadata = sqlc.arg(metadata),
+  updated_at = now()
+WHERE id = sqlc.arg(id) AND model_uuid = sqlc.arg(model_uuid)
+RETURNING *;
+
+-- name: DeleteModelVariant :execrows
+DELETE FROM model_variants
+WHERE id = sqlc.arg(id) AND model_uuid = sqlc.arg(model_uuid);
+
+-- name: GetModelVariantReport :many
+-- Aggregates assistant replies routed through a model's variants. Latency is
+-- recorded on the last message of each round, so it also counts rounds.
+SELECT
+  COALESCE(m.metadata->>'variant_id', '')::text AS variant_id,
+  COUNT(m.metadata->>'latency_ms')::bigint AS replies,
+  COUNT(DISTINCT COALESCE(m.route_id, m.bot_id))::bigint AS conversations,
+  COALESCE(AVG((m.metadata->>'latency_ms')::bigint), 0)::float8 AS avg_latency_ms,
+  COALESCE(SUM((m.usage->>'inputTokens')::bigint), 0)::bigint AS input_tokens,
+  COALESCE(SUM((m.usage->>'outputTokens')::bigint), 0)::bigint AS output_tokens,
+  COALESCE(SUM(r.up), 0)::bigint AS up_reactions,
+  COALESCE(SUM(r.down), 0)::bigint AS down_reactions
+FROM bot_history_messages m
+LEFT JOIN LATERAL (
+  SELECT
+    COUNT(*) FILTER (WHERE reaction = 'up') AS up,
+    COUNT(*) FILTER (WHERE reaction = 'down') AS down
+  FROM bot_history_message_reactions
+  WHERE message_id = m.id
+) r ON TRUE
+WHERE m.metadata->>'variant_of' = sqlc.arg(model_uuid)::text
+  AND m.role = 'assistant'
+  AND m.created_at >= sqlc.arg(since)
+  AND m.created_at < sqlc.arg(until)
+GROUP BY m.metadata->>'variant_id'
+ORDER BY replies DESC;
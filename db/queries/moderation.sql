@@ -0,0 +1,11 @@
+-- name: GetModerationPolicy :one
+SELECT id, owner_user_id, moderation_policy
+FROM bots
+WHERE id = $1;
+
+-- name: UpdateModerationPolicy :one
+UPDATE bots
+SET moderation_policy = sqlc.arg(moderation_policy),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING moderation_policy;
@@ -0,0 +1,28 @@
+-- name: GetPIIPolicy :one
+SELECT pii_policy
+FROM bots
+WHERE id = $1;
+
+-- name: UpdatePIIPolicy :one
+UPDATE bots
+SET pii_policy = sqlc.arg(pii_policy),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING pii_policy;
+
+-- name: ListPIIVaultEntries :many
+SELECT id, bot_id, kind, ordinal, value, created_at
+FROM pii_vault_entries
+WHERE bot_id = $1
+ORDER BY kind, ordinal;
+
+-- name: CreatePIIVaultEntry :one
+-- Takes the next ordinal of the kind. A value already in the vault keeps
+-- its entry, so the same value always gets the same placeholder.
+INSERT INTO pii_vault_entries (bot_id, kind, ordinal, value)
+SELECT sqlc.arg(bot_id)::uuid, sqlc.arg(kind)::text, COALESCE(MAX(ordinal), 0) + 1, sqlc.arg(value)::text
+FROM pii_vault_entries
+WHERE bot_id = sqlc.arg(bot_id)::uuid
+  AND kind = sqlc.arg(kind)::text
+ON CONFLICT (bot_id, value) DO UPDATE SET value = EXCLUDED.value
+RETURNING id, bot_id, kind, ordinal, value, created_at;
@@ -0,0 +1,67 @@
+-- name: GetRetentionPolicy :one
+SELECT id, owner_user_id, retention_policy
+FROM bots
+WHERE id = $1;
+
+-- name: UpdateRetentionPolicy :one
+UPDATE bots
+SET retention_policy = sqlc.arg(retention_policy),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING retention_policy;
+
+-- name: ListRetentionPolicies :many
+SELECT id, retention_policy
+FROM bots
+WHERE retention_policy <> '{}'::jsonb
+ORDER BY id;
+
+-- name: ListRetentionCandidates :many
+-- Messages a retention policy removes, oldest first: those created before the
+-- cutoff and those past the newest keep_latest. Starred messages are skipped
+-- when keep_starred is set.
+WITH ranked AS (
+  SELECT
+    m.id,
+    m.created_at,
+    row_number() OVER (ORDER BY m.created_at DESC, m.id DESC) AS recency
+  FROM bot_history_messages m
+  WHERE m.bot_id = sqlc.arg(bot_id)
+)
+SELECT
+  r.id,
+  r.created_at,
+  (SELECT count(*) FROM bot_history_message_assets a WHERE a.message_id = r.id)::bigint AS asset_count
+FROM ranked r
+WHERE (
+    r.created_at < sqlc.narg(before)::timestamptz
+    OR r.recency > sqlc.narg(keep_latest)::bigint
+  )
+  AND NOT (
+    sqlc.arg(keep_starred)::boolean
+    AND EXISTS (SELECT 1 FROM bot_history_message_stars s WHERE s.message_id = r.id)
+  )
+ORDER BY r.created_at, r.id;
+
+-- name: DeleteMessagesByIDs :execrows
+-- Asset links, reactions and stars go with the messages through ON DELETE CASCADE.
+DELETE FROM bot_history_messages
+WHERE bot_id = sqlc.arg(bot_id)
+  AND id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: CreatePurgeRun :one
+INSERT INTO message_purge_runs (bot_id, trigger, deleted_messages, deleted_assets, archive_content_hash)
+VALUES (
+  sqlc.arg(bot_id),
+  sqlc.arg(trigger),
+  sqlc.arg(deleted_messages),
+  sqlc.arg(deleted_assets),
+  sqlc.arg(archive_content_hash)
+)
+RETURNING *;
+
+-- name: ListPurgeRuns :many
+SELECT * FROM message_purge_runs
+WHERE bot_id = sqlc.arg(bot_id)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_count);
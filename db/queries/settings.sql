@@ -8,15 +8,20 @@ SELECT
   bots.allow_guest,
   bots.reasoning_enabled,
   bots.reasoning_effort,
+  bots.tts_enabled,
+  bots.tts_voice,
+  bots.fallback_model_ids,
   chat_models.id AS chat_model_id,
   memory_models.id AS memory_model_id,
   embedding_models.id AS embedding_model_id,
-  search_providers.id AS search_provider_id
+  search_providers.id AS search_provider_id,
+  tts_models.id AS tts_model_id
 FROM bots
 LEFT JOIN models AS chat_models ON chat_models.id = bots.chat_model_id
 LEFT JOIN models AS memory_models ON memory_models.id = bots.memory_model_id
 LEFT JOIN models AS embedding_models ON embedding_models.id = bots.embedding_model_id
 LEFT JOIN search_providers ON search_providers.id = bots.search_provider_id
+LEFT JOIN models AS tts_models ON tts_models.id = bots.tts_model_id
 WHERE bots.id = $1;
 
 -- name: UpsertBotSettings :one
@@ -29,13 +34,17 @@ WITH updated AS (
       allow_guest = sqlc.arg(allow_guest),
       reasoning_enabled = sqlc.arg(reasoning_enabled),
       reasoning_effort = sqlc.arg(reasoning_effort),
+      tts_enabled = sqlc.arg(tts_enabled),
+      tts_voice = sqlc.arg(tts_voice),
+      fallback_model_ids = COALESCE(sqlc.narg(fallback_model_ids)::uuid[], bots.fallback_model_ids),
       chat_model_id = COALESCE(sqlc.narg(chat_model_id)::uuid, bots.chat_model_id),
       memory_model_id = COALESCE(sqlc.narg(memory_model_id)::uuid, bots.memory_model_id),
       embedding_model_id = COALESCE(sqlc.narg(embedding_model_id)::uuid, bots.embedding_model_id),
       search_provider_id = COALESCE(sqlc.narg(search_provider_id)::uuid, bots.search_provider_id),
+      tts_model_id = COALESCE(sqlc.narg(tts_model_id)::uuid, bots.tts_model_id),
       updated_at = now()
   WHERE bots.id = sqlc.arg(id)
-  RETURNING bots.id, bots.max_context_load_time, bots.max_context_tokens, bots.max_inbox_items, bots.language, bots.allow_guest, bots.reasoning_enabled, bots.reasoning_effort, bots.chat_model_id, bots.memory_model_id, bots.embedding_model_id, bots.search_provider_id
+  RETURNING bots.id, bots.max_context_load_time, bots.max_context_tokens, bots.max_inbox_items, bots.language, bots.allow_guest, bots.reasoning_enabled, bots.reasoning_effort, bots.tts_enabled, bots.tts_voice, bots.fallback_model_ids, bots.chat_model_id, bots.memory_model_id, bots.embedding_model_id, bots.search_provider_id, bots.tts_model_id
 )
 SELECT
   updated.id AS bot_id,
@@ -46,15 +55,20 @@ SELECT
   updated.allow_guest,
   updated.reasoning_enabled,
   updated.reasoning_effort,
+  updated.tts_enabled,
+  updated.tts_voice,
+  updated.fallback_model_ids,
   chat_models.id AS chat_model_id,
   memory_models.id AS memory_model_id,
   embedding_models.id AS embedding_model_id,
-  search_providers.id AS search_provider_id
+  search_providers.id AS search_provider_id,
+  tts_models.id AS tts_model_id
 FROM updated
 LEFT JOIN models AS chat_models ON chat_models.id = updated.chat_model_id
 LEFT JOIN models AS memory_models ON memory_models.id = updated.memory_model_id
 LEFT JOIN models AS embedding_models ON embedding_models.id = updated.embedding_model_id
-LEFT JOIN search_providers ON search_providers.id = updated.search_provider_id;
+LEFT JOIN search_providers ON search_providers.id = updated.search_provider_id
+LEFT JOIN models AS tts_models ON tts_models.id = updated.tts_model_id;
 
 -- name: DeleteSettingsByBotID :exec
 UPDATE bots
@@ -65,9 +79,13 @@ SET max_context_load_time = 1440,
     allow_guest = false,
     reasoning_enabled = false,
     reasoning_effort = 'medium',
+    tts_enabled = false,
+    tts_voice = '',
+    fallback_model_ids = '{}',
     chat_model_id = NULL,
     memory_model_id = NULL,
     embedding_model_id = NULL,
     search_provider_id = NULL,
+    tts_model_id = NULL,
     updated_at = now()
 WHERE id = $1;
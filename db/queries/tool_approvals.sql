@@ -0,0 +1,56 @@
+-- name: GetToolApprovalPolicy :one
+SELECT id, owner_user_id, tool_approval_policy
+FROM bots
+WHERE id = $1;
+
+-- name: UpdateToolApprovalPolicy :one
+UPDATE bots
+SET tool_approval_policy = sqlc.arg(tool_approval_policy),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING tool_approval_policy;
+
+-- name: CreateToolApproval :one
+INSERT INTO tool_approvals (bot_id, tool_name, arguments, rule, platform, requested_by_channel_identity_id, expires_at)
+VALUES (
+  sqlc.arg(bot_id),
+  sqlc.arg(tool_name),
+  sqlc.arg(arguments),
+  sqlc.arg(rule),
+  sqlc.arg(platform),
+  sqlc.narg(requested_by_channel_identity_id)::uuid,
+  sqlc.arg(expires_at)
+)
+RETURNING *;
+
+-- name: GetToolApproval :one
+SELECT * FROM tool_approvals
+WHERE id = sqlc.arg(id)
+  AND bot_id = sqlc.arg(bot_id);
+
+-- name: ListToolApprovals :many
+SELECT * FROM tool_approvals
+WHERE bot_id = sqlc.arg(bot_id)
+  AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_count);
+
+-- name: DecideToolApproval :one
+-- Records the outcome of a pending approval; matches nothing once the
+-- approval has been decided or has expired.
+UPDATE tool_approvals
+SET status = sqlc.arg(status),
+    decided_by_user_id = sqlc.narg(decided_by_user_id)::uuid,
+    decision_note = sqlc.arg(decision_note),
+    decided_at = now()
+WHERE id = sqlc.arg(id)
+  AND bot_id = sqlc.arg(bot_id)
+  AND status = 'pending'
+RETURNING *;
+
+-- name: ExpireToolApprovals :exec
+UPDATE tool_approvals
+SET status = 'expired',
+    decided_at = now()
+WHERE status = 'pending'
+  AND expires_at < now();
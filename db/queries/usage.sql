@@ -0,0 +1,73 @@
+-- name: UpsertModelPrice :one
+INSERT INTO model_prices (model_uuid, currency, input_per_million, output_per_million, cached_input_per_million, reasoning_per_million)
+VALUES (
+  sqlc.arg(model_uuid),
+  sqlc.arg(currency),
+  sqlc.arg(input_per_million),
+  sqlc.arg(output_per_million),
+  sqlc.narg(cached_input_per_million),
+  sqlc.narg(reasoning_per_million)
+)
+ON CONFLICT (model_uuid) DO UPDATE SET
+  currency = EXCLUDED.currency,
+  input_per_million = EXCLUDED.input_per_million,
+  output_per_million = EXCLUDED.output_per_million,
+  cached_input_per_million = EXCLUDED.cached_input_per_million,
+  reasoning_per_million = EXCLUDED.reasoning_per_million,
+  updated_at = now()
+RETURNING *;
+
+-- name: GetModelPrice :one
+SELECT * FROM model_prices WHERE model_uuid = $1;
+
+-- name: DeleteModelPrice :execrows
+DELETE FROM model_prices WHERE model_uuid = $1;
+
+-- name: ListModelPrices :many
+SELECT
+  p.model_uuid,
+  m.model_id,
+  m.name AS model_name,
+  p.currency,
+  p.input_per_million,
+  p.output_per_million,
+  p.cached_input_per_million,
+  p.reasoning_per_million,
+  p.updated_at
+FROM model_prices p
+JOIN models m ON m.id = p.model_uuid
+ORDER BY m.model_id, m.created_at;
+
+-- name: ListUsageBuckets :many
+-- Sums stored token usage per UTC day, bot, user, model and channel. Replies
+-- carry no sender, so they are attributed to the user message that preceded
+-- them in the same route.
+SELECT
+  to_char(m.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')::text AS day,
+  m.bot_id,
+  COALESCE(u.sender_account_user_id::text, u.sender_channel_identity_id::text, '')::text AS user_id,
+  COALESCE(m.metadata->>'model_id', '')::text AS model_id,
+  COALESCE(m.metadata->>'model_uuid', '')::text AS model_uuid,
+  COALESCE(m.channel_type, '')::text AS channel,
+  COUNT(*)::bigint AS messages,
+  COALESCE(SUM((m.usage->>'inputTokens')::bigint), 0)::bigint AS input_tokens,
+  COALESCE(SUM((m.usage->>'outputTokens')::bigint), 0)::bigint AS output_tokens,
+  COALESCE(SUM(COALESCE(m.usage->'inputTokenDetails'->>'cacheReadTokens', m.usage->>'cachedInputTokens')::bigint), 0)::bigint AS cached_input_tokens,
+  COALESCE(SUM(COALESCE(m.usage->'outputTokenDetails'->>'reasoningTokens', m.usage->>'reasoningTokens')::bigint), 0)::bigint AS reasoning_tokens
+FROM bot_history_messages m
+LEFT JOIN LATERAL (
+  SELECT p.sender_account_user_id, p.sender_channel_identity_id
+  FROM bot_history_messages p
+  WHERE p.bot_id = m.bot_id
+    AND p.route_id IS NOT DISTINCT FROM m.route_id
+    AND p.role = 'user'
+    AND p.created_at <= m.created_at
+  ORDER BY p.created_at DESC
+  LIMIT 1
+) u ON TRUE
+WHERE m.usage IS NOT NULL
+  AND m.created_at >= sqlc.arg(since)
+  AND m.created_at < sqlc.arg(until)
+  AND (sqlc.narg(bot_id)::uuid IS NULL OR m.bot_id = sqlc.narg(bot_id)::uuid)
+GROUP BY 1, 2, 3, 4, 5, 6
+ORDER BY 1, 2;
@@ -0,0 +1,67 @@
+-- name: GetQuotaPolicy :one
+SELECT id, owner_user_id, quota_policy
+FROM bots
+WHERE id = $1;
+
+-- name: UpdateQuotaPolicy :one
+UPDATE bots
+SET quota_policy = sqlc.arg(quota_policy),
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING quota_policy;
+
+-- name: GetQuotaCounter :one
+SELECT * FROM usage_quota_counters
+WHERE bot_id = sqlc.arg(bot_id)
+  AND scope = sqlc.arg(scope)
+  AND subject = sqlc.arg(subject)
+  AND period = sqlc.arg(period)
+  AND period_start = sqlc.arg(period_start);
+
+-- name: AddQuotaUsage :one
+INSERT INTO usage_quota_counters (bot_id, scope, subject, period, period_start, requests, tokens, cost)
+VALUES (
+  sqlc.arg(bot_id),
+  sqlc.arg(scope),
+  sqlc.arg(subject),
+  sqlc.arg(period),
+  sqlc.arg(period_start),
+  sqlc.arg(requests),
+  sqlc.arg(tokens),
+  sqlc.arg(cost)
+)
+ON CONFLICT (bot_id, scope, subject, period, period_start) DO UPDATE SET
+  requests = usage_quota_counters.requests + EXCLUDED.requests,
+  tokens = usage_quota_counters.tokens + EXCLUDED.tokens,
+  cost = usage_quota_counters.cost + EXCLUDED.cost,
+  updated_at = now()
+RETURNING *;
+
+-- name: MarkQuotaNotified :execrows
+-- Records that the owner was told a metric reached percent of its limit.
+-- Affects no row when an equal or higher threshold was already recorded, so
+-- each threshold is announced once per period.
+UPDATE usage_quota_counters
+SET notified = notified || jsonb_build_object(sqlc.arg(metric)::text, sqlc.arg(percent)::int)
+WHERE bot_id = sqlc.arg(bot_id)
+  AND scope = sqlc.arg(scope)
+  AND subject = sqlc.arg(subject)
+  AND period = sqlc.arg(period)
+  AND period_start = sqlc.arg(period_start)
+  AND COALESCE((notified ->> sqlc.arg(metric)::text)::int, 0) < sqlc.arg(percent)::int;
+
+-- name: ListQuotaCounters :many
+SELECT * FROM usage_quota_counters
+WHERE bot_id = sqlc.arg(bot_id)
+  AND period_start >= sqlc.arg(since)
+ORDER BY scope, subject, period, period_start DESC;
+
+-- name: DeleteQuotaCounters :execrows
+DELETE FROM usage_quota_counters
+WHERE bot_id = sqlc.arg(bot_id)
+  AND (sqlc.narg(scope)::text IS NULL OR scope = sqlc.narg(scope)::text)
+  AND (sqlc.narg(subject)::text IS NULL OR subject = sqlc.narg(subject)::text);
+
+-- name: DeleteExpiredQuotaCounters :execrows
+DELETE FROM usage_quota_counters
+WHERE period_start < sqlc.arg(before);
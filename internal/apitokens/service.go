@@ -0,0 +1,186 @@
+package apitokens
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+// TokenPrefix marks Memoh API tokens so they are recognizable in configs and
+// secret scanners.
+const TokenPrefix = "mh-"
+
+const (
+	tokenRandomBytes  = 32
+	displayPrefixSize = len(TokenPrefix) + 6
+)
+
+var (
+	ErrTokenNotFound = errors.New("api token not found")
+	ErrInvalidToken  = errors.New("invalid api token")
+)
+
+type Service struct {
+	queries *sqlc.Queries
+	logger  *slog.Logger
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Service{queries: queries, logger: log.With(slog.String("service", "apitokens"))}
+}
+
+// Create issues a new token for the user. The plaintext is returned once and
+// only its hash is stored.
+func (s *Service) Create(ctx context.Context, userID string, req CreateRequest) (Token, error) {
+	if s.queries == nil {
+		return Token{}, fmt.Errorf("api token queries not configured")
+	}
+	pgUserID, err := db.ParseUUID(userID)
+	if err != nil {
+		return Token{}, err
+	}
+	if req.TTLSeconds < 0 {
+		return Token{}, fmt.Errorf("ttl_seconds must not be negative")
+	}
+	plaintext, err := generateToken()
+	if err != nil {
+		return Token{}, err
+	}
+	expiresAt := pgtype.Timestamptz{}
+	if req.TTLSeconds > 0 {
+		expiresAt = pgtype.Timestamptz{Time: time.Now().UTC().Add(time.Duration(req.TTLSeconds) * time.Second), Valid: true}
+	}
+	row, err := s.queries.CreateAPIToken(ctx, sqlc.CreateAPITokenParams{
+		UserID:      pgUserID,
+		Name:        strings.TrimSpace(req.Name),
+		TokenHash:   HashToken(plaintext),
+		TokenPrefix: plaintext[:displayPrefixSize],
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		return Token{}, err
+	}
+	token := normalizeToken(row)
+	token.Token = plaintext
+	return token, nil
+}
+
+// List returns the user's tokens without their plaintext.
+func (s *Service) List(ctx context.Context, userID string) ([]Token, error) {
+	if s.queries == nil {
+		return nil, fmt.Errorf("api token queries not configured")
+	}
+	pgUserID, err := db.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListAPITokensByUser(ctx, pgUserID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Token, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, normalizeToken(row))
+	}
+	return items, nil
+}
+
+// Delete revokes one of the user's tokens.
+func (s *Service) Delete(ctx context.Context, userID, tokenID string) error {
+	if s.queries == nil {
+		return fmt.Errorf("api token queries not configured")
+	}
+	pgUserID, err := db.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+	pgTokenID, err := db.ParseUUID(tokenID)
+	if err != nil {
+		return err
+	}
+	affected, err := s.queries.DeleteAPIToken(ctx, sqlc.DeleteAPITokenParams{ID: pgTokenID, UserID: pgUserID})
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a plaintext token to its owner and records its use.
+func (s *Service) Authenticate(ctx context.Context, plaintext string) (Token, error) {
+	if s.queries == nil {
+		return Token{}, fmt.Errorf("api token queries not configured")
+	}
+	plaintext = strings.TrimSpace(plaintext)
+	if !LooksLikeToken(plaintext) {
+		return Token{}, ErrInvalidToken
+	}
+	row, err := s.queries.GetAPITokenByHash(ctx, HashToken(plaintext))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Token{}, ErrInvalidToken
+		}
+		return Token{}, err
+	}
+	if err := s.queries.TouchAPIToken(ctx, row.ID); err != nil {
+		s.logger.Warn("touch api token failed", slog.String("token_id", row.ID.String()), slog.Any("error", err))
+	}
+	return normalizeToken(row), nil
+}
+
+// LooksLikeToken reports whether value has the shape of a Memoh API token.
+func LooksLikeToken(value string) bool {
+	return strings.HasPrefix(value, TokenPrefix) && len(value) > displayPrefixSize
+}
+
+// HashToken returns the hex SHA-256 digest stored for a plaintext token.
+func HashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, tokenRandomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate api token: %w", err)
+	}
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func normalizeToken(row sqlc.ApiToken) Token {
+	return Token{
+		ID:         row.ID.String(),
+		UserID:     row.UserID.String(),
+		Name:       row.Name,
+		Prefix:     row.TokenPrefix,
+		ExpiresAt:  optionalTimeFromPg(row.ExpiresAt),
+		LastUsedAt: optionalTimeFromPg(row.LastUsedAt),
+		CreatedAt:  row.CreatedAt.Time,
+	}
+}
+
+func optionalTimeFromPg(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := value.Time
+	return &t
+}
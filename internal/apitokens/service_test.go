@@ -0,0 +1,50 @@
+package apitokens
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateToken(t *testing.T) {
+	first, err := generateToken()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	second, err := generateToken()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if first == second {
+		t.Fatal("expected distinct tokens")
+	}
+	if !strings.HasPrefix(first, TokenPrefix) || !LooksLikeToken(first) {
+		t.Fatalf("unexpected token shape: %q", first)
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken("mh-example")
+	if len(hash) != 64 {
+		t.Fatalf("expected hex sha256, got %q", hash)
+	}
+	if hash != HashToken("mh-example") {
+		t.Fatal("hash must be deterministic")
+	}
+	if hash == HashToken("mh-example2") {
+		t.Fatal("different tokens must hash differently")
+	}
+}
+
+func TestLooksLikeToken(t *testing.T) {
+	cases := map[string]bool{
+		"":                 false,
+		"mh-":              false,
+		"eyJhbGciOiJIUzI1": false,
+		"mh-abcdefghijkl":  true,
+	}
+	for value, want := range cases {
+		if got := LooksLikeToken(value); got != want {
+			t.Fatalf("LooksLikeToken(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package apitokens
+
+import "time"
+
+// Token is a personal API token. The plaintext Token is only populated in the
+// response to Create; afterwards only Prefix identifies it.
+type Token struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Token      string     `json:"token,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateRequest is the input for creating an API token.
+type CreateRequest struct {
+	Name       string `json:"name"`
+	TTLSeconds int    `json:"ttl_seconds,omitempty"`
+}
+
+// ListResponse wraps a list of API tokens.
+type ListResponse struct {
+	Items []Token `json:"items"`
+}
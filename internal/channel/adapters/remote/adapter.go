@@ -0,0 +1,246 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/memohai/memoh/internal/channel"
+)
+
+// shutdownTimeout bounds the shutdown call sent when a session is closed.
+const shutdownTimeout = 5 * time.Second
+
+// Adapter proxies channel.Adapter, Receiver, Sender and StreamSender to an
+// external adapter process. Its channel type and descriptor come from the
+// adapter's initialize response.
+type Adapter struct {
+	logger     *slog.Logger
+	peer       *peer
+	descriptor channel.Descriptor
+
+	nextID      atomic.Uint64
+	mu          sync.Mutex
+	connections map[string]*remoteConnection
+}
+
+type remoteConnection struct {
+	conn    *channel.BaseConnection
+	cfg     channel.ChannelConfig
+	handler channel.InboundHandler
+}
+
+// NewAdapter performs the protocol handshake over rwc and returns the proxy.
+// The session is owned by the adapter; Close ends it.
+func NewAdapter(ctx context.Context, log *slog.Logger, rwc io.ReadWriteCloser) (*Adapter, error) {
+	if log == nil {
+		log = slog.Default()
+	}
+	a := &Adapter{
+		logger:      log.With(slog.String("adapter", "remote")),
+		connections: map[string]*remoteConnection{},
+	}
+	a.peer = newPeer(a.logger, rwc, map[string]rpcHandler{
+		MethodInbound:          a.handleInbound,
+		MethodConnectionClosed: a.handleConnectionClosed,
+		MethodLog:              a.handleLog,
+	})
+	a.peer.start()
+	var result InitializeResult
+	if err := a.peer.Call(ctx, MethodInitialize, InitializeParams{ProtocolVersion: ProtocolVersion}, &result); err != nil {
+		_ = a.peer.Close()
+		return nil, fmt.Errorf("initialize remote adapter: %w", err)
+	}
+	if result.ProtocolVersion != ProtocolVersion {
+		_ = a.peer.Close()
+		return nil, fmt.Errorf("remote adapter speaks protocol version %d, want %d", result.ProtocolVersion, ProtocolVersion)
+	}
+	a.descriptor = result.Descriptor.Descriptor()
+	if a.descriptor.Type == "" {
+		_ = a.peer.Close()
+		return nil, fmt.Errorf("remote adapter descriptor has no channel type")
+	}
+	return a, nil
+}
+
+// Type returns the channel type announced by the remote adapter.
+func (a *Adapter) Type() channel.ChannelType {
+	return a.descriptor.Type
+}
+
+// Descriptor returns the descriptor announced by the remote adapter.
+func (a *Adapter) Descriptor() channel.Descriptor {
+	return a.descriptor
+}
+
+// Done is closed when the session with the remote adapter ends.
+func (a *Adapter) Done() <-chan struct{} {
+	return a.peer.Done()
+}
+
+// Err reports why the session ended.
+func (a *Adapter) Err() error {
+	return a.peer.Err()
+}
+
+// Close asks the remote adapter to shut down and ends the session.
+func (a *Adapter) Close() error {
+	select {
+	case <-a.peer.Done():
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := a.peer.Call(ctx, MethodShutdown, Empty{}, nil); err != nil && !errors.Is(err, ErrClosed) {
+			a.logger.Debug("remote adapter shutdown failed", slog.Any("error", err))
+		}
+		cancel()
+	}
+	a.mu.Lock()
+	connections := a.connections
+	a.connections = map[string]*remoteConnection{}
+	a.mu.Unlock()
+	for _, rc := range connections {
+		_ = rc.conn.Stop(context.Background())
+	}
+	return a.peer.Close()
+}
+
+// Connect asks the remote adapter to start receiving messages for cfg.
+func (a *Adapter) Connect(ctx context.Context, cfg channel.ChannelConfig, handler channel.InboundHandler) (channel.Connection, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("inbound handler is required")
+	}
+	connectionID := cfg.ID + "#" + strconv.FormatUint(a.nextID.Add(1), 10)
+	rc := &remoteConnection{cfg: cfg, handler: handler}
+	rc.conn = channel.NewConnection(cfg, func(stopCtx context.Context) error {
+		if !a.dropConnection(connectionID) {
+			return nil
+		}
+		err := a.peer.Call(stopCtx, MethodDisconnect, DisconnectParams{ConnectionID: connectionID}, nil)
+		if errors.Is(err, ErrClosed) {
+			return nil
+		}
+		return err
+	})
+	// Register before calling connect: the adapter may deliver messages
+	// before its connect response arrives.
+	a.mu.Lock()
+	a.connections[connectionID] = rc
+	a.mu.Unlock()
+	if err := a.peer.Call(ctx, MethodConnect, ConnectParams{ConnectionID: connectionID, Config: cfg}, nil); err != nil {
+		a.dropConnection(connectionID)
+		return nil, err
+	}
+	return rc.conn, nil
+}
+
+// Send delivers one outbound message through the remote adapter.
+func (a *Adapter) Send(ctx context.Context, cfg channel.ChannelConfig, msg channel.OutboundMessage) error {
+	return a.peer.Call(ctx, MethodSend, SendParams{Config: cfg, Message: msg}, nil)
+}
+
+// OpenStream opens an outbound stream on the remote adapter.
+func (a *Adapter) OpenStream(ctx context.Context, cfg channel.ChannelConfig, target string, opts channel.StreamOptions) (channel.OutboundStream, error) {
+	streamID := "stream-" + strconv.FormatUint(a.nextID.Add(1), 10)
+	if err := a.peer.Call(ctx, MethodStreamOpen, StreamOpenParams{StreamID: streamID, Config: cfg, Target: target, Options: opts}, nil); err != nil {
+		return nil, err
+	}
+	return &remoteStream{peer: a.peer, id: streamID}, nil
+}
+
+func (a *Adapter) dropConnection(connectionID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.connections[connectionID]; !ok {
+		return false
+	}
+	delete(a.connections, connectionID)
+	return true
+}
+
+func (a *Adapter) handleInbound(ctx context.Context, raw json.RawMessage) (any, error) {
+	var params InboundParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	rc, ok := a.connections[params.ConnectionID]
+	a.mu.Unlock()
+	if !ok {
+		return nil, &Error{Code: CodeInvalidParams, Message: "unknown connection: " + params.ConnectionID}
+	}
+	msg := params.Message.InboundMessage(a.descriptor.Type, rc.cfg)
+	if strings.TrimSpace(msg.ReplyTarget) == "" {
+		return nil, &Error{Code: CodeInvalidParams, Message: "reply_target is required"}
+	}
+	if err := rc.handler(ctx, rc.cfg, msg); err != nil {
+		return nil, err
+	}
+	return Empty{}, nil
+}
+
+func (a *Adapter) handleConnectionClosed(_ context.Context, raw json.RawMessage) (any, error) {
+	var params ConnectionClosedParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	rc, ok := a.connections[params.ConnectionID]
+	delete(a.connections, params.ConnectionID)
+	a.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	// Mark the connection stopped so the manager's refresh reconnects it.
+	_ = rc.conn.Stop(context.Background())
+	a.logger.Warn("remote adapter connection closed",
+		slog.String("config_id", rc.cfg.ID),
+		slog.String("error", params.Error),
+	)
+	return nil, nil
+}
+
+func (a *Adapter) handleLog(_ context.Context, raw json.RawMessage) (any, error) {
+	var params LogParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(params.Level))); err != nil {
+		level = slog.LevelInfo
+	}
+	attrs := make([]any, 0, len(params.Attrs))
+	for key, value := range params.Attrs {
+		attrs = append(attrs, slog.Any(key, value))
+	}
+	a.logger.Log(context.Background(), level, params.Message, attrs...)
+	return nil, nil
+}
+
+// remoteStream forwards stream events to the remote adapter.
+type remoteStream struct {
+	peer   *peer
+	id     string
+	closed atomic.Bool
+}
+
+func (s *remoteStream) Push(ctx context.Context, event channel.StreamEvent) error {
+	if s.closed.Load() {
+		return fmt.Errorf("stream is closed")
+	}
+	return s.peer.Call(ctx, MethodStreamPush, StreamPushParams{StreamID: s.id, Event: event}, nil)
+}
+
+func (s *remoteStream) Close(ctx context.Context) error {
+	if s.closed.Swap(true) {
+		return nil
+	}
+	return s.peer.Call(ctx, MethodStreamClose, StreamCloseParams{StreamID: s.id}, nil)
+}
@@ -0,0 +1,280 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/memohai/memoh/internal/channel"
+)
+
+const (
+	handshakeTimeout  = 15 * time.Second
+	minRestartBackoff = time.Second
+	maxRestartBackoff = time.Minute
+	// stableRunTime resets the restart backoff after a healthy session.
+	stableRunTime = time.Minute
+)
+
+// ProcessConfig describes one external adapter. Either Command is started
+// with the protocol on its stdin/stdout, or Address is dialed when the
+// adapter runs as its own service ("unix:///path.sock" or "tcp://host:port").
+type ProcessConfig struct {
+	Name    string
+	Command string
+	Args    []string
+	Env     []string
+	Address string
+}
+
+// AdapterHost registers and removes adapters at runtime; *channel.Manager
+// implements it.
+type AdapterHost interface {
+	Registry() *channel.Registry
+	AddAdapter(ctx context.Context, adapter channel.Adapter)
+	RemoveAdapter(ctx context.Context, channelType channel.ChannelType)
+}
+
+// Launcher keeps configured external adapters running and registered with the
+// channel manager. A crashed or disconnected adapter is removed and restarted
+// with backoff.
+type Launcher struct {
+	logger  *slog.Logger
+	host    AdapterHost
+	configs []ProcessConfig
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewLauncher creates a Launcher for the given adapters.
+func NewLauncher(log *slog.Logger, host AdapterHost, configs []ProcessConfig) *Launcher {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Launcher{
+		logger:  log.With(slog.String("component", "remote_adapters")),
+		host:    host,
+		configs: configs,
+	}
+}
+
+// Start launches every configured adapter in the background.
+func (l *Launcher) Start(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cancel != nil || len(l.configs) == 0 {
+		return
+	}
+	ctx, l.cancel = context.WithCancel(ctx)
+	for _, cfg := range l.configs {
+		l.wg.Add(1)
+		go func(cfg ProcessConfig) {
+			defer l.wg.Done()
+			l.supervise(ctx, cfg)
+		}(cfg)
+	}
+}
+
+// Stop shuts down every adapter and waits for them to exit.
+func (l *Launcher) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	cancel := l.cancel
+	l.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Launcher) supervise(ctx context.Context, cfg ProcessConfig) {
+	log := l.logger.With(slog.String("name", processName(cfg)))
+	backoff := minRestartBackoff
+	for {
+		started := time.Now()
+		if err := l.runOnce(ctx, log, cfg); err != nil && ctx.Err() == nil {
+			log.Warn("remote adapter stopped", slog.Any("error", err))
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > stableRunTime {
+			backoff = minRestartBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRestartBackoff)
+	}
+}
+
+// runOnce starts one adapter session and blocks until it ends.
+func (l *Launcher) runOnce(ctx context.Context, log *slog.Logger, cfg ProcessConfig) error {
+	transport, wait, err := openTransport(ctx, log, cfg)
+	if err != nil {
+		return err
+	}
+	handshakeCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	adapter, err := NewAdapter(handshakeCtx, log, transport)
+	cancel()
+	if err != nil {
+		_ = transport.Close()
+		_ = wait()
+		return err
+	}
+	channelType := adapter.Type()
+	if _, exists := l.host.Registry().Get(channelType); exists {
+		_ = adapter.Close()
+		_ = wait()
+		return fmt.Errorf("channel type %s is already registered", channelType)
+	}
+	l.host.AddAdapter(ctx, adapter)
+	log.Info("remote adapter registered", slog.String("channel", channelType.String()))
+
+	select {
+	case <-ctx.Done():
+	case <-adapter.Done():
+	}
+	l.host.RemoveAdapter(context.WithoutCancel(ctx), channelType)
+	_ = adapter.Close()
+	waitErr := wait()
+	if err := adapter.Err(); err != nil && !errors.Is(err, ErrClosed) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return waitErr
+}
+
+// openTransport dials the adapter or starts its process. wait reaps the
+// process, if any, after the transport is closed.
+func openTransport(ctx context.Context, log *slog.Logger, cfg ProcessConfig) (io.ReadWriteCloser, func() error, error) {
+	noWait := func() error { return nil }
+	if address := strings.TrimSpace(cfg.Address); address != "" {
+		network, target, err := parseAddress(address)
+		if err != nil {
+			return nil, nil, err
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, network, target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("dial remote adapter: %w", err)
+		}
+		return conn, noWait, nil
+	}
+	if strings.TrimSpace(cfg.Command) == "" {
+		return nil, nil, fmt.Errorf("remote adapter needs a command or an address")
+	}
+	// The process is not bound to ctx: it is asked to shut down over the
+	// protocol and its stdin is closed afterwards.
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("start remote adapter: %w", err)
+	}
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Info("remote adapter output", slog.String("line", scanner.Text()))
+		}
+	}()
+	wait := func() error {
+		exited := make(chan error, 1)
+		go func() {
+			<-stderrDone
+			exited <- cmd.Wait()
+		}()
+		select {
+		case err := <-exited:
+			return err
+		case <-time.After(shutdownTimeout):
+			_ = cmd.Process.Kill()
+			return <-exited
+		}
+	}
+	return &processTransport{stdin: stdin, stdout: stdout}, wait, nil
+}
+
+func parseAddress(address string) (string, string, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid remote adapter address %q: %w", address, err)
+	}
+	switch parsed.Scheme {
+	case "unix":
+		path := parsed.Path
+		if path == "" {
+			path = parsed.Opaque
+		}
+		if path == "" {
+			return "", "", fmt.Errorf("invalid remote adapter address %q: missing socket path", address)
+		}
+		return "unix", path, nil
+	case "tcp":
+		if parsed.Host == "" {
+			return "", "", fmt.Errorf("invalid remote adapter address %q: missing host", address)
+		}
+		return "tcp", parsed.Host, nil
+	default:
+		return "", "", fmt.Errorf("invalid remote adapter address %q: scheme must be unix or tcp", address)
+	}
+}
+
+func processName(cfg ProcessConfig) string {
+	if name := strings.TrimSpace(cfg.Name); name != "" {
+		return name
+	}
+	if address := strings.TrimSpace(cfg.Address); address != "" {
+		return address
+	}
+	return cfg.Command
+}
+
+// processTransport joins a child process's stdout and stdin.
+type processTransport struct {
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+}
+
+func (t *processTransport) Read(p []byte) (int, error)  { return t.stdout.Read(p) }
+func (t *processTransport) Write(p []byte) (int, error) { return t.stdin.Write(p) }
+
+func (t *processTransport) Close() error {
+	return t.stdin.Close()
+}
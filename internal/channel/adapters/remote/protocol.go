@@ -0,0 +1,260 @@
+// Package remote implements the out-of-process channel adapter protocol.
+//
+// An external adapter is a separate program that speaks JSON-RPC 2.0 with the
+// server, one JSON message per line, over its stdin/stdout or a socket. The
+// server side is the proxy Adapter, which is registered with the channel
+// manager like a built-in adapter. Adapters written in Go can use Serve to
+// expose a channel.Adapter implementation without dealing with the wire format.
+//
+// Methods called by the server on the adapter:
+//
+//	initialize    {protocol_version}                          -> {protocol_version, descriptor}
+//	connect       {connection_id, config}                     -> {}   (Receiver)
+//	disconnect    {connection_id}                             -> {}
+//	send          {config, message}                           -> {}   (Sender)
+//	stream.open   {stream_id, config, target, options}        -> {}   (StreamSender)
+//	stream.push   {stream_id, event}                          -> {}
+//	stream.close  {stream_id}                                 -> {}
+//	shutdown      {}                                          -> {}
+//
+// Methods called by the adapter on the server:
+//
+//	inbound             {connection_id, message}              -> {}
+//	connection.closed   {connection_id, error}                   (notification)
+//	log                 {level, message, attrs}                  (notification)
+package remote
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/memohai/memoh/internal/channel"
+)
+
+// ProtocolVersion is the adapter protocol version spoken by this server.
+const ProtocolVersion = 1
+
+// Method names of the adapter protocol.
+const (
+	MethodInitialize       = "initialize"
+	MethodConnect          = "connect"
+	MethodDisconnect       = "disconnect"
+	MethodSend             = "send"
+	MethodStreamOpen       = "stream.open"
+	MethodStreamPush       = "stream.push"
+	MethodStreamClose      = "stream.close"
+	MethodShutdown         = "shutdown"
+	MethodInbound          = "inbound"
+	MethodConnectionClosed = "connection.closed"
+	MethodLog              = "log"
+)
+
+// InitializeParams opens a session with an adapter.
+type InitializeParams struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// InitializeResult describes the adapter behind the session.
+type InitializeResult struct {
+	ProtocolVersion int               `json:"protocol_version"`
+	Descriptor      DescriptorPayload `json:"descriptor"`
+}
+
+// DescriptorPayload is the wire form of channel.Descriptor.
+type DescriptorPayload struct {
+	Type             string                      `json:"type"`
+	DisplayName      string                      `json:"display_name"`
+	Configless       bool                        `json:"configless,omitempty"`
+	Capabilities     channel.ChannelCapabilities `json:"capabilities"`
+	OutboundPolicy   channel.OutboundPolicy      `json:"outbound_policy"`
+	ConfigSchema     channel.ConfigSchema        `json:"config_schema"`
+	UserConfigSchema channel.ConfigSchema        `json:"user_config_schema"`
+	TargetSpec       channel.TargetSpec          `json:"target_spec"`
+}
+
+// ConnectParams asks the adapter to start receiving messages for a config.
+type ConnectParams struct {
+	ConnectionID string                `json:"connection_id"`
+	Config       channel.ChannelConfig `json:"config"`
+}
+
+// DisconnectParams stops a connection started with connect.
+type DisconnectParams struct {
+	ConnectionID string `json:"connection_id"`
+}
+
+// SendParams delivers one outbound message.
+type SendParams struct {
+	Config  channel.ChannelConfig   `json:"config"`
+	Message channel.OutboundMessage `json:"message"`
+}
+
+// StreamOpenParams opens an outbound stream identified by StreamID.
+type StreamOpenParams struct {
+	StreamID string                `json:"stream_id"`
+	Config   channel.ChannelConfig `json:"config"`
+	Target   string                `json:"target"`
+	Options  channel.StreamOptions `json:"options"`
+}
+
+// StreamPushParams pushes one event to an open stream.
+type StreamPushParams struct {
+	StreamID string              `json:"stream_id"`
+	Event    channel.StreamEvent `json:"event"`
+}
+
+// StreamCloseParams closes an open stream.
+type StreamCloseParams struct {
+	StreamID string `json:"stream_id"`
+}
+
+// InboundParams carries a message received by an adapter connection.
+type InboundParams struct {
+	ConnectionID string         `json:"connection_id"`
+	Message      InboundPayload `json:"message"`
+}
+
+// ConnectionClosedParams reports that a connection ended on the adapter side.
+type ConnectionClosedParams struct {
+	ConnectionID string `json:"connection_id"`
+	Error        string `json:"error,omitempty"`
+}
+
+// LogParams forwards an adapter log line to the server log.
+type LogParams struct {
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+}
+
+// InboundPayload is the wire form of channel.InboundMessage.
+type InboundPayload struct {
+	Message      channel.Message     `json:"message"`
+	BotID        string              `json:"bot_id,omitempty"`
+	ReplyTarget  string              `json:"reply_target"`
+	RouteKey     string              `json:"route_key,omitempty"`
+	Sender       IdentityPayload     `json:"sender"`
+	Conversation ConversationPayload `json:"conversation"`
+	ReceivedAt   time.Time           `json:"received_at"`
+	Source       string              `json:"source,omitempty"`
+	Metadata     map[string]any      `json:"metadata,omitempty"`
+}
+
+// IdentityPayload is the wire form of channel.Identity.
+type IdentityPayload struct {
+	SubjectID   string            `json:"subject_id"`
+	DisplayName string            `json:"display_name,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+}
+
+// ConversationPayload is the wire form of channel.Conversation.
+type ConversationPayload struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type,omitempty"`
+	Name     string         `json:"name,omitempty"`
+	ThreadID string         `json:"thread_id,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// Empty is the result of methods that return nothing.
+type Empty struct{}
+
+// DescriptorToPayload converts a descriptor to its wire form.
+func DescriptorToPayload(desc channel.Descriptor) DescriptorPayload {
+	return DescriptorPayload{
+		Type:             desc.Type.String(),
+		DisplayName:      desc.DisplayName,
+		Configless:       desc.Configless,
+		Capabilities:     desc.Capabilities,
+		OutboundPolicy:   desc.OutboundPolicy,
+		ConfigSchema:     desc.ConfigSchema,
+		UserConfigSchema: desc.UserConfigSchema,
+		TargetSpec:       desc.TargetSpec,
+	}
+}
+
+// Descriptor converts the wire form back to a channel.Descriptor.
+func (p DescriptorPayload) Descriptor() channel.Descriptor {
+	return channel.Descriptor{
+		Type:             channel.ChannelType(strings.ToLower(strings.TrimSpace(p.Type))),
+		DisplayName:      p.DisplayName,
+		Configless:       p.Configless,
+		Capabilities:     p.Capabilities,
+		OutboundPolicy:   p.OutboundPolicy,
+		ConfigSchema:     p.ConfigSchema,
+		UserConfigSchema: p.UserConfigSchema,
+		TargetSpec:       p.TargetSpec,
+	}
+}
+
+// InboundToPayload converts an inbound message to its wire form.
+func InboundToPayload(msg channel.InboundMessage) InboundPayload {
+	return InboundPayload{
+		Message:     msg.Message,
+		BotID:       msg.BotID,
+		ReplyTarget: msg.ReplyTarget,
+		RouteKey:    msg.RouteKey,
+		Sender: IdentityPayload{
+			SubjectID:   msg.Sender.SubjectID,
+			DisplayName: msg.Sender.DisplayName,
+			Attributes:  msg.Sender.Attributes,
+		},
+		Conversation: ConversationPayload{
+			ID:       msg.Conversation.ID,
+			Type:     msg.Conversation.Type,
+			Name:     msg.Conversation.Name,
+			ThreadID: msg.Conversation.ThreadID,
+			Metadata: msg.Conversation.Metadata,
+		},
+		ReceivedAt: msg.ReceivedAt,
+		Source:     msg.Source,
+		Metadata:   msg.Metadata,
+	}
+}
+
+// InboundMessage converts the wire form to a channel.InboundMessage for the
+// given channel config. Adapters may omit the bot ID; it defaults to the
+// config's bot.
+func (p InboundPayload) InboundMessage(channelType channel.ChannelType, cfg channel.ChannelConfig) channel.InboundMessage {
+	botID := strings.TrimSpace(p.BotID)
+	if botID == "" {
+		botID = cfg.BotID
+	}
+	receivedAt := p.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now().UTC()
+	}
+	return channel.InboundMessage{
+		Channel:     channelType,
+		Message:     p.Message,
+		BotID:       botID,
+		ReplyTarget: p.ReplyTarget,
+		RouteKey:    p.RouteKey,
+		Sender: channel.Identity{
+			SubjectID:   p.Sender.SubjectID,
+			DisplayName: p.Sender.DisplayName,
+			Attributes:  p.Sender.Attributes,
+		},
+		Conversation: channel.Conversation{
+			ID:       p.Conversation.ID,
+			Type:     p.Conversation.Type,
+			Name:     p.Conversation.Name,
+			ThreadID: p.Conversation.ThreadID,
+			Metadata: p.Conversation.Metadata,
+		},
+		ReceivedAt: receivedAt,
+		Source:     p.Source,
+		Metadata:   p.Metadata,
+	}
+}
+
+func decodeParams(raw json.RawMessage, out any) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
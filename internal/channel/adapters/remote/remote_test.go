@@ -0,0 +1,303 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/memohai/memoh/internal/channel"
+)
+
+const testChannelType = channel.ChannelType("matrix")
+
+// fakeAdapter is an in-process adapter served through the protocol.
+type fakeAdapter struct {
+	mu       sync.Mutex
+	sent     []channel.OutboundMessage
+	events   []channel.StreamEvent
+	closed   int
+	stopped  int
+	handler  channel.InboundHandler
+	cfg      channel.ChannelConfig
+	received chan struct{}
+}
+
+func newFakeAdapter() *fakeAdapter {
+	return &fakeAdapter{received: make(chan struct{}, 1)}
+}
+
+func (f *fakeAdapter) Type() channel.ChannelType { return testChannelType }
+
+func (f *fakeAdapter) Descriptor() channel.Descriptor {
+	return channel.Descriptor{
+		Type:         testChannelType,
+		DisplayName:  "Matrix",
+		Capabilities: channel.ChannelCapabilities{Text: true, Streaming: true},
+		ConfigSchema: channel.ConfigSchema{
+			Version: 1,
+			Fields:  map[string]channel.FieldSchema{"homeserver": {Type: channel.FieldString, Required: true}},
+		},
+		TargetSpec: channel.TargetSpec{Format: "room_id"},
+	}
+}
+
+func (f *fakeAdapter) Connect(ctx context.Context, cfg channel.ChannelConfig, handler channel.InboundHandler) (channel.Connection, error) {
+	f.mu.Lock()
+	f.handler = handler
+	f.cfg = cfg
+	f.mu.Unlock()
+	f.received <- struct{}{}
+	return channel.NewConnection(cfg, func(context.Context) error {
+		f.mu.Lock()
+		f.stopped++
+		f.mu.Unlock()
+		return nil
+	}), nil
+}
+
+func (f *fakeAdapter) Send(ctx context.Context, cfg channel.ChannelConfig, msg channel.OutboundMessage) error {
+	if msg.Target == "forbidden" {
+		return errors.New("room is read-only")
+	}
+	f.mu.Lock()
+	f.sent = append(f.sent, msg)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeAdapter) OpenStream(ctx context.Context, cfg channel.ChannelConfig, target string, opts channel.StreamOptions) (channel.OutboundStream, error) {
+	return &fakeStream{adapter: f}, nil
+}
+
+type fakeStream struct {
+	adapter *fakeAdapter
+}
+
+func (s *fakeStream) Push(ctx context.Context, event channel.StreamEvent) error {
+	s.adapter.mu.Lock()
+	s.adapter.events = append(s.adapter.events, event)
+	s.adapter.mu.Unlock()
+	return nil
+}
+
+func (s *fakeStream) Close(ctx context.Context) error {
+	s.adapter.mu.Lock()
+	s.adapter.closed++
+	s.adapter.mu.Unlock()
+	return nil
+}
+
+func startSession(t *testing.T, impl channel.Adapter) (*Adapter, <-chan error) {
+	t.Helper()
+	hostSide, adapterSide := net.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(context.Background(), slog.Default(), impl, adapterSide)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	proxy, err := NewAdapter(ctx, slog.Default(), hostSide)
+	if err != nil {
+		t.Fatalf("new adapter: %v", err)
+	}
+	return proxy, served
+}
+
+func TestAdapterRoundTrip(t *testing.T) {
+	impl := newFakeAdapter()
+	proxy, served := startSession(t, impl)
+	ctx := context.Background()
+
+	if proxy.Type() != testChannelType {
+		t.Fatalf("unexpected type: %s", proxy.Type())
+	}
+	desc := proxy.Descriptor()
+	if desc.DisplayName != "Matrix" || !desc.Capabilities.Streaming || !desc.ConfigSchema.Fields["homeserver"].Required || desc.TargetSpec.Format != "room_id" {
+		t.Fatalf("descriptor not carried over: %#v", desc)
+	}
+
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: testChannelType}
+	if err := proxy.Send(ctx, cfg, channel.OutboundMessage{Target: "!room", Message: channel.Message{Text: "hi"}}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	err := proxy.Send(ctx, cfg, channel.OutboundMessage{Target: "forbidden", Message: channel.Message{Text: "hi"}})
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Message != "room is read-only" {
+		t.Fatalf("expected remote error, got %v", err)
+	}
+
+	stream, err := proxy.OpenStream(ctx, cfg, "!room", channel.StreamOptions{})
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	if err := stream.Push(ctx, channel.StreamEvent{Type: channel.StreamEventDelta, Delta: "he"}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if err := stream.Close(ctx); err != nil {
+		t.Fatalf("close stream: %v", err)
+	}
+
+	inbound := make(chan channel.InboundMessage, 1)
+	conn, err := proxy.Connect(ctx, cfg, func(ctx context.Context, got channel.ChannelConfig, msg channel.InboundMessage) error {
+		inbound <- msg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	<-impl.received
+	impl.mu.Lock()
+	handler := impl.handler
+	impl.mu.Unlock()
+	err = handler(ctx, cfg, channel.InboundMessage{
+		Message:      channel.Message{Text: "hello bot"},
+		ReplyTarget:  "!room",
+		Sender:       channel.Identity{SubjectID: "@alice:example.org", DisplayName: "Alice"},
+		Conversation: channel.Conversation{ID: "!room", Type: "group"},
+	})
+	if err != nil {
+		t.Fatalf("deliver inbound: %v", err)
+	}
+	msg := <-inbound
+	if msg.Channel != testChannelType || msg.BotID != "bot-1" || msg.Message.Text != "hello bot" || msg.Sender.DisplayName != "Alice" || msg.Conversation.Type != "group" || msg.ReceivedAt.IsZero() {
+		t.Fatalf("unexpected inbound message: %#v", msg)
+	}
+
+	if err := conn.Stop(ctx); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	impl.mu.Lock()
+	if len(impl.sent) != 1 || len(impl.events) != 1 || impl.closed != 1 || impl.stopped != 1 || impl.cfg.ID != "cfg-1" {
+		t.Fatalf("unexpected adapter state: sent=%d events=%d closed=%d stopped=%d", len(impl.sent), len(impl.events), impl.closed, impl.stopped)
+	}
+	impl.mu.Unlock()
+
+	if err := proxy.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("close: %v", err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("serve returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after shutdown")
+	}
+}
+
+// sendOnlyAdapter implements neither Receiver nor StreamSender.
+type sendOnlyAdapter struct{ fakeAdapter }
+
+func (a *sendOnlyAdapter) Type() channel.ChannelType { return testChannelType }
+func (a *sendOnlyAdapter) Descriptor() channel.Descriptor {
+	return channel.Descriptor{Type: testChannelType}
+}
+
+func TestAdapterUnsupportedMethod(t *testing.T) {
+	impl := struct{ channel.Adapter }{&sendOnlyAdapter{}}
+	proxy, _ := startSession(t, impl)
+	defer proxy.Close()
+
+	_, err := proxy.Connect(context.Background(), channel.ChannelConfig{ID: "cfg-1"}, func(context.Context, channel.ChannelConfig, channel.InboundMessage) error { return nil })
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeUnsupported {
+		t.Fatalf("expected unsupported error, got %v", err)
+	}
+}
+
+type fakeHost struct {
+	registry *channel.Registry
+	added    chan channel.ChannelType
+	removed  chan channel.ChannelType
+}
+
+func (h *fakeHost) Registry() *channel.Registry { return h.registry }
+
+func (h *fakeHost) AddAdapter(ctx context.Context, adapter channel.Adapter) {
+	_ = h.registry.Register(adapter)
+	h.added <- adapter.Type()
+}
+
+func (h *fakeHost) RemoveAdapter(ctx context.Context, channelType channel.ChannelType) {
+	h.registry.Unregister(channelType)
+	h.removed <- channelType
+}
+
+func TestLauncherRegistersAndRemovesAdapter(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+		_ = Serve(context.Background(), slog.Default(), newFakeAdapter(), conn)
+	}()
+
+	host := &fakeHost{registry: channel.NewRegistry(), added: make(chan channel.ChannelType, 1), removed: make(chan channel.ChannelType, 1)}
+	launcher := NewLauncher(slog.Default(), host, []ProcessConfig{{Name: "matrix", Address: "tcp://" + listener.Addr().String()}})
+	launcher.Start(context.Background())
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = launcher.Stop(ctx)
+	}()
+
+	select {
+	case ct := <-host.added:
+		if ct != testChannelType {
+			t.Fatalf("unexpected channel type: %s", ct)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("adapter was not registered")
+	}
+	if _, ok := host.registry.GetSender(testChannelType); !ok {
+		t.Fatal("expected proxy to be usable as a sender")
+	}
+
+	// Dropping the connection must unregister the adapter.
+	(<-accepted).Close()
+	select {
+	case ct := <-host.removed:
+		if ct != testChannelType {
+			t.Fatalf("unexpected removed type: %s", ct)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("adapter was not removed")
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	cases := []struct {
+		address string
+		network string
+		target  string
+		wantErr bool
+	}{
+		{address: "unix:///run/memoh/matrix.sock", network: "unix", target: "/run/memoh/matrix.sock"},
+		{address: "tcp://127.0.0.1:7070", network: "tcp", target: "127.0.0.1:7070"},
+		{address: "http://example.com", wantErr: true},
+		{address: "tcp://", wantErr: true},
+	}
+	for _, tc := range cases {
+		network, target, err := parseAddress(tc.address)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("expected error for %q", tc.address)
+			}
+			continue
+		}
+		if err != nil || network != tc.network || target != tc.target {
+			t.Fatalf("parseAddress(%q) = %q %q %v", tc.address, network, target, err)
+		}
+	}
+}
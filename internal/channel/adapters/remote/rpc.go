@@ -0,0 +1,286 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+// JSON-RPC error codes used by the protocol.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeUnsupported is returned when the adapter does not implement the
+	// interface backing a method, for example send on a receive-only adapter.
+	CodeUnsupported = -32001
+)
+
+// maxMessageSize bounds a single protocol line.
+const maxMessageSize = 16 * 1024 * 1024
+
+// ErrClosed is returned for calls on a closed session.
+var ErrClosed = errors.New("remote adapter session closed")
+
+// Error is a JSON-RPC error returned by the peer.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("remote adapter error %d: %s", e.Code, e.Message)
+}
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// rpcHandler serves one incoming method.
+type rpcHandler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// peer is one end of a bidirectional JSON-RPC session. Both sides may issue
+// requests; incoming requests are served concurrently so a slow handler does
+// not block responses to outstanding calls.
+type peer struct {
+	logger   *slog.Logger
+	rwc      io.ReadWriteCloser
+	handlers map[string]rpcHandler
+
+	writeMu sync.Mutex
+	writer  *bufio.Writer
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan rpcMessage
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newPeer(log *slog.Logger, rwc io.ReadWriteCloser, handlers map[string]rpcHandler) *peer {
+	if log == nil {
+		log = slog.Default()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &peer{
+		logger:   log,
+		rwc:      rwc,
+		handlers: handlers,
+		writer:   bufio.NewWriter(rwc),
+		pending:  map[uint64]chan rpcMessage{},
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	return p
+}
+
+// start begins reading from the transport. It is separate from newPeer so
+// owners can finish wiring the handlers' receiver before any request is served.
+func (p *peer) start() {
+	go p.readLoop()
+}
+
+// Done is closed when the session ends.
+func (p *peer) Done() <-chan struct{} {
+	return p.ctx.Done()
+}
+
+// Err returns the reason the session ended, if it has.
+func (p *peer) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closeErr
+}
+
+// Close ends the session and fails outstanding calls.
+func (p *peer) Close() error {
+	return p.closeWithError(ErrClosed)
+}
+
+func (p *peer) closeWithError(cause error) error {
+	var err error
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closeErr = cause
+		pending := p.pending
+		p.pending = map[uint64]chan rpcMessage{}
+		p.cancel()
+		p.mu.Unlock()
+		err = p.rwc.Close()
+		for _, ch := range pending {
+			close(ch)
+		}
+	})
+	return err
+}
+
+// Call sends a request and decodes the result into result when non-nil.
+func (p *peer) Call(ctx context.Context, method string, params any, result any) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	ch := make(chan rpcMessage, 1)
+	p.mu.Lock()
+	if p.ctx.Err() != nil {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.nextID++
+	id := p.nextID
+	p.pending[id] = ch
+	p.mu.Unlock()
+
+	if err := p.write(rpcMessage{JSONRPC: "2.0", ID: json.RawMessage(strconv.FormatUint(id, 10)), Method: method, Params: rawParams}); err != nil {
+		p.forget(id)
+		return err
+	}
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return ErrClosed
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	case <-ctx.Done():
+		p.forget(id)
+		return ctx.Err()
+	}
+}
+
+// Notify sends a request without an ID; the peer does not answer.
+func (p *peer) Notify(method string, params any) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return p.write(rpcMessage{JSONRPC: "2.0", Method: method, Params: rawParams})
+}
+
+func (p *peer) forget(id uint64) {
+	p.mu.Lock()
+	delete(p.pending, id)
+	p.mu.Unlock()
+}
+
+func (p *peer) write(msg rpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	if p.ctx.Err() != nil {
+		return ErrClosed
+	}
+	if _, err := p.writer.Write(data); err != nil {
+		return err
+	}
+	if err := p.writer.WriteByte('\n'); err != nil {
+		return err
+	}
+	return p.writer.Flush()
+}
+
+func (p *peer) readLoop() {
+	scanner := bufio.NewScanner(p.rwc)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			p.logger.Warn("remote adapter sent invalid message", slog.Any("error", err))
+			_ = p.write(rpcMessage{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}})
+			continue
+		}
+		if msg.Method != "" {
+			go p.serve(msg)
+			continue
+		}
+		p.deliver(msg)
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	_ = p.closeWithError(err)
+}
+
+func (p *peer) deliver(msg rpcMessage) {
+	id, err := strconv.ParseUint(string(msg.ID), 10, 64)
+	if err != nil {
+		p.logger.Warn("remote adapter response with unknown id", slog.String("id", string(msg.ID)))
+		return
+	}
+	p.mu.Lock()
+	ch, ok := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (p *peer) serve(msg rpcMessage) {
+	handler, ok := p.handlers[msg.Method]
+	var (
+		result any
+		err    error
+	)
+	if !ok {
+		err = &Error{Code: CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	} else {
+		result, err = handler(p.ctx, msg.Params)
+	}
+	if len(msg.ID) == 0 {
+		if err != nil {
+			p.logger.Warn("remote adapter notification failed", slog.String("method", msg.Method), slog.Any("error", err))
+		}
+		return
+	}
+	resp := rpcMessage{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+	} else {
+		if result == nil {
+			result = Empty{}
+		}
+		data, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			resp.Error = &Error{Code: CodeInternalError, Message: marshalErr.Error()}
+		} else {
+			resp.Result = data
+		}
+	}
+	if err := p.write(resp); err != nil && !errors.Is(err, ErrClosed) {
+		p.logger.Warn("remote adapter response write failed", slog.String("method", msg.Method), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,213 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/memohai/memoh/internal/channel"
+)
+
+// Serve exposes a Go channel.Adapter over the adapter protocol and blocks until
+// the server ends the session, sends shutdown, or ctx is cancelled. It is the
+// entry point for adapters shipped as separate binaries:
+//
+//	func main() {
+//		_ = remote.Serve(context.Background(), nil, myadapter.New(), remote.Stdio())
+//	}
+func Serve(ctx context.Context, log *slog.Logger, adapter channel.Adapter, rwc io.ReadWriteCloser) error {
+	if adapter == nil {
+		return fmt.Errorf("adapter is nil")
+	}
+	s := &server{
+		adapter:     adapter,
+		connections: map[string]channel.Connection{},
+		streams:     map[string]channel.OutboundStream{},
+		shutdown:    make(chan struct{}),
+	}
+	s.peer = newPeer(log, rwc, map[string]rpcHandler{
+		MethodInitialize:  s.initialize,
+		MethodConnect:     s.connect,
+		MethodDisconnect:  s.disconnect,
+		MethodSend:        s.send,
+		MethodStreamOpen:  s.streamOpen,
+		MethodStreamPush:  s.streamPush,
+		MethodStreamClose: s.streamClose,
+		MethodShutdown:    s.handleShutdown,
+	})
+	s.peer.start()
+	select {
+	case <-ctx.Done():
+	case <-s.shutdown:
+	case <-s.peer.Done():
+	}
+	s.stopAll()
+	_ = s.peer.Close()
+	return ctx.Err()
+}
+
+// Stdio returns the process stdin/stdout as a protocol transport. Adapters
+// serving over stdio must log to stderr.
+func Stdio() io.ReadWriteCloser {
+	return stdio{}
+}
+
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdio) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdio) Close() error                { return os.Stdin.Close() }
+
+type server struct {
+	adapter channel.Adapter
+	peer    *peer
+
+	mu          sync.Mutex
+	connections map[string]channel.Connection
+	streams     map[string]channel.OutboundStream
+
+	shutdownOnce sync.Once
+	shutdown     chan struct{}
+}
+
+func unsupported(method string) error {
+	return &Error{Code: CodeUnsupported, Message: method + " is not supported by this adapter"}
+}
+
+func (s *server) initialize(_ context.Context, raw json.RawMessage) (any, error) {
+	var params InitializeParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	return InitializeResult{
+		ProtocolVersion: ProtocolVersion,
+		Descriptor:      DescriptorToPayload(s.adapter.Descriptor()),
+	}, nil
+}
+
+func (s *server) connect(ctx context.Context, raw json.RawMessage) (any, error) {
+	receiver, ok := s.adapter.(channel.Receiver)
+	if !ok {
+		return nil, unsupported(MethodConnect)
+	}
+	var params ConnectParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	connectionID := params.ConnectionID
+	handler := func(handlerCtx context.Context, _ channel.ChannelConfig, msg channel.InboundMessage) error {
+		return s.peer.Call(handlerCtx, MethodInbound, InboundParams{ConnectionID: connectionID, Message: InboundToPayload(msg)}, nil)
+	}
+	conn, err := receiver.Connect(ctx, params.Config, handler)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.connections[connectionID] = conn
+	s.mu.Unlock()
+	return Empty{}, nil
+}
+
+func (s *server) disconnect(ctx context.Context, raw json.RawMessage) (any, error) {
+	var params DisconnectParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	conn, ok := s.connections[params.ConnectionID]
+	delete(s.connections, params.ConnectionID)
+	s.mu.Unlock()
+	if !ok {
+		return Empty{}, nil
+	}
+	if err := conn.Stop(ctx); err != nil && !errors.Is(err, channel.ErrStopNotSupported) {
+		return nil, err
+	}
+	return Empty{}, nil
+}
+
+func (s *server) send(ctx context.Context, raw json.RawMessage) (any, error) {
+	sender, ok := s.adapter.(channel.Sender)
+	if !ok {
+		return nil, unsupported(MethodSend)
+	}
+	var params SendParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	return Empty{}, sender.Send(ctx, params.Config, params.Message)
+}
+
+func (s *server) streamOpen(ctx context.Context, raw json.RawMessage) (any, error) {
+	streamSender, ok := s.adapter.(channel.StreamSender)
+	if !ok {
+		return nil, unsupported(MethodStreamOpen)
+	}
+	var params StreamOpenParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	stream, err := streamSender.OpenStream(ctx, params.Config, params.Target, params.Options)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.streams[params.StreamID] = stream
+	s.mu.Unlock()
+	return Empty{}, nil
+}
+
+func (s *server) streamPush(ctx context.Context, raw json.RawMessage) (any, error) {
+	var params StreamPushParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	stream, ok := s.streams[params.StreamID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, &Error{Code: CodeInvalidParams, Message: "unknown stream: " + params.StreamID}
+	}
+	return Empty{}, stream.Push(ctx, params.Event)
+}
+
+func (s *server) streamClose(ctx context.Context, raw json.RawMessage) (any, error) {
+	var params StreamCloseParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	stream, ok := s.streams[params.StreamID]
+	delete(s.streams, params.StreamID)
+	s.mu.Unlock()
+	if !ok {
+		return Empty{}, nil
+	}
+	return Empty{}, stream.Close(ctx)
+}
+
+func (s *server) handleShutdown(context.Context, json.RawMessage) (any, error) {
+	s.shutdownOnce.Do(func() { close(s.shutdown) })
+	return Empty{}, nil
+}
+
+func (s *server) stopAll() {
+	s.mu.Lock()
+	connections := s.connections
+	streams := s.streams
+	s.connections = map[string]channel.Connection{}
+	s.streams = map[string]channel.OutboundStream{}
+	s.mu.Unlock()
+	ctx := context.Background()
+	for _, stream := range streams {
+		_ = stream.Close(ctx)
+	}
+	for _, conn := range connections {
+		_ = conn.Stop(ctx)
+	}
+}
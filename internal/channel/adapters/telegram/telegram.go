@@ -105,6 +105,7 @@ func (a *TelegramAdapter) Descriptor() channel.Descriptor {
 			Reply:          true,
 			Attachments:    true,
 			Media:          true,
+			VoiceNotes:     true,
 			Streaming:      true,
 			BlockStreaming: true,
 		},
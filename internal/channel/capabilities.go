@@ -8,6 +8,7 @@ type ChannelCapabilities struct {
 	RichText        bool     `json:"rich_text"`
 	Attachments     bool     `json:"attachments"`
 	Media           bool     `json:"media"`
+	VoiceNotes      bool     `json:"voice_notes"`
 	Reactions       bool     `json:"reactions"`
 	Buttons         bool     `json:"buttons"`
 	Reply           bool     `json:"reply"`
@@ -10,25 +10,42 @@ type inboundTask struct {
 	ctx context.Context
 	cfg ChannelConfig
 	msg InboundMessage
+	// release, when set, is called once processing returns.
+	release context.CancelFunc
 }
 
 // HandleInbound enqueues an inbound message for asynchronous processing by the worker pool.
 func (m *Manager) HandleInbound(ctx context.Context, cfg ChannelConfig, msg InboundMessage) error {
-	if m.processor == nil {
-		return fmt.Errorf("inbound processor not configured")
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	return m.enqueueInbound(ctx, inboundTask{ctx: context.WithoutCancel(ctx), cfg: cfg, msg: msg})
+}
+
+// HandleInboundWithCancel enqueues an inbound message like HandleInbound and
+// returns a function that aborts its processing, plus a context that is done
+// once processing has finished or was cancelled. Long-lived transports such as
+// WebSocket sessions use it to cancel a reply after the send call returned.
+func (m *Manager) HandleInboundWithCancel(ctx context.Context, cfg ChannelConfig, msg InboundMessage) (context.Context, context.CancelFunc, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	taskCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if err := m.enqueueInbound(ctx, inboundTask{ctx: taskCtx, cfg: cfg, msg: msg, release: cancel}); err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return taskCtx, cancel, nil
+}
+
+func (m *Manager) enqueueInbound(ctx context.Context, task inboundTask) error {
+	if m.processor == nil {
+		return fmt.Errorf("inbound processor not configured")
+	}
 	m.startInboundWorkers(ctx)
 	if m.inboundCtx != nil && m.inboundCtx.Err() != nil {
 		return fmt.Errorf("inbound dispatcher stopped")
 	}
-	task := inboundTask{
-		ctx: context.WithoutCancel(ctx),
-		cfg: cfg,
-		msg: msg,
-	}
 	select {
 	case m.inboundQueue <- task:
 		return nil
@@ -75,6 +92,9 @@ func (m *Manager) runInboundWorker(ctx context.Context) {
 					m.logger.Error("inbound processing failed", slog.String("channel", task.msg.Channel.String()), slog.Any("error", err))
 				}
 			}
+			if task.release != nil {
+				task.release()
+			}
 		}
 	}
 }
@@ -0,0 +1,74 @@
+package inbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/memohai/memoh/internal/toolapproval"
+)
+
+const (
+	approveCommand = "/approve"
+	denyCommand    = "/deny"
+)
+
+// toolApprovalDecider decides tool calls held for the bot owner, such as
+// toolapproval.Service.
+type toolApprovalDecider interface {
+	FindPending(ctx context.Context, botID, ref string) (toolapproval.Approval, error)
+	Decide(ctx context.Context, botID, approvalID string, approve bool, userID, note string) (toolapproval.Approval, error)
+}
+
+// SetToolApprovals enables the /approve and /deny commands.
+func (p *ChannelInboundProcessor) SetToolApprovals(decider toolApprovalDecider) {
+	if p == nil {
+		return
+	}
+	p.approvals = decider
+}
+
+// parseApprovalCommand reports whether text is /approve or /deny and
+// returns its arguments.
+func parseApprovalCommand(text string) (approve bool, args string, ok bool) {
+	if args, ok := parseCommand(text, approveCommand); ok {
+		return true, args, true
+	}
+	if args, ok := parseCommand(text, denyCommand); ok {
+		return false, args, true
+	}
+	return false, "", false
+}
+
+// handleApprovalCommand answers /approve [<id>] [note] and /deny [<id>]
+// [reason]. Only the bot owner may decide; the ID may be left out when a
+// single call is waiting.
+func (p *ChannelInboundProcessor) handleApprovalCommand(ctx context.Context, identity InboundIdentity, approve bool, args string) (string, error) {
+	if !p.isBotOwner(ctx, identity) {
+		return "Only the bot owner can approve or deny tool calls.", nil
+	}
+	ref, note := splitCommandWord(args)
+	pending, err := p.approvals.FindPending(ctx, identity.BotID, ref)
+	switch {
+	case errors.Is(err, toolapproval.ErrNotFound):
+		if ref == "" {
+			return "No tool calls are waiting for approval.", nil
+		}
+		return fmt.Sprintf("No waiting tool call matches %q.", ref), nil
+	case errors.Is(err, toolapproval.ErrAmbiguous):
+		return "More than one tool call is waiting; add the ID from the request, e.g. /approve 1a2b3c4d.", nil
+	case err != nil:
+		return "", fmt.Errorf("find pending tool approval: %w", err)
+	}
+	decided, err := p.approvals.Decide(ctx, identity.BotID, pending.ID, approve, identity.UserID, note)
+	if errors.Is(err, toolapproval.ErrAlreadyDecided) {
+		return fmt.Sprintf("That %s call was already %s.", decided.ToolName, decided.Status), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("decide tool approval: %w", err)
+	}
+	if approve {
+		return fmt.Sprintf("Approved %s.", decided.ToolName), nil
+	}
+	return fmt.Sprintf("Denied %s.", decided.ToolName), nil
+}
@@ -0,0 +1,92 @@
+package inbound
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/toolapproval"
+)
+
+type fakeToolApprovals struct {
+	pending []toolapproval.Approval
+	decided []string
+}
+
+func (f *fakeToolApprovals) FindPending(ctx context.Context, botID, ref string) (toolapproval.Approval, error) {
+	var matched []toolapproval.Approval
+	for _, item := range f.pending {
+		if strings.HasPrefix(item.ID, ref) {
+			matched = append(matched, item)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return toolapproval.Approval{}, toolapproval.ErrNotFound
+	case 1:
+		return matched[0], nil
+	default:
+		return toolapproval.Approval{}, toolapproval.ErrAmbiguous
+	}
+}
+
+func (f *fakeToolApprovals) Decide(ctx context.Context, botID, approvalID string, approve bool, userID, note string) (toolapproval.Approval, error) {
+	status := toolapproval.StatusDenied
+	if approve {
+		status = toolapproval.StatusApproved
+	}
+	f.decided = append(f.decided, approvalID+" "+status+" "+note)
+	return toolapproval.Approval{ID: approvalID, ToolName: "exec", Status: status}, nil
+}
+
+func TestApprovalCommandOwnerDenies(t *testing.T) {
+	approvals := &fakeToolApprovals{pending: []toolapproval.Approval{
+		{ID: "1a2b3c4d-0000", ToolName: "exec"},
+		{ID: "9f8e7d6c-0000", ToolName: "exec"},
+	}}
+	gateway := &fakeChatGateway{}
+	processor := newSettingsCommandProcessor("channelIdentity-1", &fakeSettingsManager{}, gateway)
+	processor.SetToolApprovals(approvals)
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("telegram")}
+
+	sender := &fakeReplySender{}
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("/deny 9f8e too risky"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(approvals.decided) != 1 || approvals.decided[0] != "9f8e7d6c-0000 denied too risky" {
+		t.Fatalf("expected the matching call denied, got %v", approvals.decided)
+	}
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Message.PlainText(), "Denied exec") {
+		t.Fatalf("expected a denial reply, got %+v", sender.sent)
+	}
+
+	sender = &fakeReplySender{}
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("/approve"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(approvals.decided) != 1 || !strings.Contains(sender.sent[0].Message.PlainText(), "More than one") {
+		t.Fatalf("expected an ambiguity reply, got %+v", sender.sent)
+	}
+	if gateway.gotReq.Query != "" {
+		t.Fatalf("command must not reach the chat model, got query %q", gateway.gotReq.Query)
+	}
+}
+
+func TestApprovalCommandRequiresOwner(t *testing.T) {
+	approvals := &fakeToolApprovals{pending: []toolapproval.Approval{{ID: "1a2b3c4d-0000", ToolName: "exec"}}}
+	processor := newSettingsCommandProcessor("someone-else", &fakeSettingsManager{}, &fakeChatGateway{})
+	processor.SetToolApprovals(approvals)
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("telegram")}
+
+	sender := &fakeReplySender{}
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("/approve 1a2b"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(approvals.decided) != 0 {
+		t.Fatalf("expected no decision from a non-owner, got %v", approvals.decided)
+	}
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Message.PlainText(), "Only the bot owner") {
+		t.Fatalf("expected an owner-only reply, got %+v", sender.sent)
+	}
+}
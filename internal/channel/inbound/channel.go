@@ -3,6 +3,7 @@ package inbound
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -22,6 +23,7 @@ import (
 	"github.com/memohai/memoh/internal/inbox"
 	"github.com/memohai/memoh/internal/media"
 	messagepkg "github.com/memohai/memoh/internal/message"
+	"github.com/memohai/memoh/internal/quota"
 )
 
 const (
@@ -49,6 +51,12 @@ type mediaIngestor interface {
 	IngestContainerFile(ctx context.Context, botID, containerPath string) (media.Asset, error)
 }
 
+// voiceReplySynthesizer turns the final assistant reply into a voice note.
+type voiceReplySynthesizer interface {
+	VoiceReplyEnabled(ctx context.Context, botID, routeID string) bool
+	Synthesize(ctx context.Context, botID, text string) (media.Asset, error)
+}
+
 // ChannelInboundProcessor routes channel inbound messages to the chat gateway.
 type ChannelInboundProcessor struct {
 	runner        flow.Runner
@@ -56,12 +64,16 @@ type ChannelInboundProcessor struct {
 	message       messagepkg.Writer
 	mediaService  mediaIngestor
 	inboxService  *inbox.Service
+	voiceReplies  voiceReplySynthesizer
+	settings      conversation.SettingsManager
 	registry      *channel.Registry
 	logger        *slog.Logger
 	jwtSecret     string
 	tokenTTL      time.Duration
 	identity      *IdentityResolver
 	observer      channel.StreamObserver
+	approvals     toolApprovalDecider
+	moderator     inputModerator
 }
 
 // NewChannelInboundProcessor creates a processor with channel identity-based resolution.
@@ -133,6 +145,15 @@ func (p *ChannelInboundProcessor) SetInboxService(service *inbox.Service) {
 	p.inboxService = service
 }
 
+// SetVoiceReplySynthesizer configures text-to-speech for final replies on
+// channels that support voice notes.
+func (p *ChannelInboundProcessor) SetVoiceReplySynthesizer(synthesizer voiceReplySynthesizer) {
+	if p == nil {
+		return
+	}
+	p.voiceReplies = synthesizer
+}
+
 // HandleInbound processes an inbound channel message through identity resolution and chat gateway.
 func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel.ChannelConfig, msg channel.InboundMessage, sender channel.StreamReplySender) error {
 	if p.runner == nil {
@@ -182,6 +203,24 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 	}
 
 	identity := state.Identity
+	if p.settings != nil {
+		if args, ok := parseSettingsCommand(msg.Message.PlainText()); ok {
+			reply, err := p.handleSettingsCommand(ctx, identity, args)
+			if err != nil {
+				return err
+			}
+			return replyCommand(ctx, sender, msg, reply)
+		}
+	}
+	if p.approvals != nil {
+		if approve, args, ok := parseApprovalCommand(msg.Message.PlainText()); ok {
+			reply, err := p.handleApprovalCommand(ctx, identity, approve, args)
+			if err != nil {
+				return err
+			}
+			return replyCommand(ctx, sender, msg, reply)
+		}
+	}
 	resolvedAttachments := p.ingestInboundAttachments(ctx, cfg, msg, strings.TrimSpace(identity.BotID), msg.Message.Attachments)
 	attachments := mapChannelToChatAttachments(resolvedAttachments)
 
@@ -204,13 +243,27 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 	if err != nil {
 		return fmt.Errorf("resolve route conversation: %w", err)
 	}
+	if _, ok := parseCommand(msg.Message.PlainText(), stopCommand); ok {
+		if handled, err := p.handleStopCommand(ctx, sender, msg, identity.BotID, resolved.RouteID); handled || err != nil {
+			return err
+		}
+	}
 	// Bot-centric history container:
 	// always persist channel traffic under bot_id so WebUI can view unified cross-platform history.
 	activeChatID := strings.TrimSpace(identity.BotID)
 	if activeChatID == "" {
 		activeChatID = strings.TrimSpace(resolved.ChatID)
 	}
-	if !shouldTriggerAssistantResponse(msg) && !identity.ForceReply {
+	triggered := shouldTriggerAssistantResponse(msg) || identity.ForceReply
+	moderated, err := p.moderateInbound(ctx, identity, msg, resolved.RouteID, text)
+	if err != nil {
+		return fmt.Errorf("moderate inbound message: %w", err)
+	}
+	if moderated.Blocked {
+		return p.handleBlockedInbound(ctx, sender, identity, msg, resolved.RouteID, triggered, moderated)
+	}
+	text = moderated.Text
+	if !triggered {
 		if p.logger != nil {
 			p.logger.Info(
 				"inbound not triggering assistant (group trigger condition not met)",
@@ -227,7 +280,7 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 		p.createInboxItem(ctx, identity, msg, text, attachments, resolved.RouteID)
 		return nil
 	}
-	userMessagePersisted := p.persistInboundUser(ctx, resolved.RouteID, identity, msg, text, attachments, "active_chat")
+	userMessagePersisted := p.persistInboundUser(ctx, resolved.RouteID, identity, msg, text, attachments, "active_chat", moderated.Metadata)
 
 	// Issue chat token for reply routing.
 	chatToken := ""
@@ -372,6 +425,8 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 		CurrentChannel:          msg.Channel.String(),
 		Channels:                []string{msg.Channel.String()},
 		UserMessagePersisted:    userMessagePersisted,
+		InputModerated:          p.moderator != nil,
+		InputModeration:         moderated.Metadata,
 		Attachments:             attachments,
 		OutboundAssetCollector:  assetCollector,
 	})
@@ -450,6 +505,32 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 		}
 	}
 
+	var exceeded *quota.ExceededError
+	if errors.As(streamErr, &exceeded) {
+		// Running out of quota is an answer for the sender, not a failure.
+		notice := channel.Message{Text: exceeded.Message}
+		if sourceMessageID != "" {
+			notice.Reply = &channel.ReplyRef{Target: target, MessageID: sourceMessageID}
+		}
+		if err := stream.Push(ctx, channel.StreamEvent{
+			Type:  channel.StreamEventFinal,
+			Final: &channel.StreamFinalizePayload{Message: notice},
+		}); err != nil {
+			return err
+		}
+		if err := stream.Push(ctx, channel.StreamEvent{
+			Type:   channel.StreamEventStatus,
+			Status: channel.StreamStatusCompleted,
+		}); err != nil {
+			return err
+		}
+		if statusNotifier != nil {
+			if notifyErr := p.notifyProcessingCompleted(ctx, statusNotifier, cfg, msg, statusInfo, statusHandle); notifyErr != nil {
+				p.logProcessingStatusError("processing_completed", msg, identity, notifyErr)
+			}
+		}
+		return nil
+	}
 	if streamErr != nil {
 		if p.logger != nil {
 			p.logger.Error(
@@ -490,6 +571,7 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 
 	outputs := flow.ExtractAssistantOutputs(finalMessages)
 	attachmentsApplied := false
+	spokenTexts := make([]string, 0, len(outputs))
 	for _, output := range outputs {
 		outMessage := buildChannelMessage(output, desc.Capabilities)
 		if outMessage.IsEmpty() && !(len(outboundAttachments) > 0 && !attachmentsApplied) {
@@ -520,6 +602,9 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 		}); err != nil {
 			return err
 		}
+		if plainText != "" {
+			spokenTexts = append(spokenTexts, plainText)
+		}
 	}
 	if !attachmentsApplied && len(outboundAttachments) > 0 {
 		attachMsg := channel.Message{Attachments: outboundAttachments}
@@ -533,6 +618,18 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 			return err
 		}
 	}
+	if voice, ok := p.synthesizeVoiceReply(ctx, desc.Capabilities, identity, resolved.RouteID, spokenTexts); ok {
+		voiceMsg := channel.Message{Attachments: []channel.Attachment{voice}}
+		if sourceMessageID != "" {
+			voiceMsg.Reply = &channel.ReplyRef{Target: target, MessageID: sourceMessageID}
+		}
+		if err := stream.Push(ctx, channel.StreamEvent{
+			Type:  channel.StreamEventFinal,
+			Final: &channel.StreamFinalizePayload{Message: voiceMsg},
+		}); err != nil {
+			return err
+		}
+	}
 	if err := stream.Push(ctx, channel.StreamEvent{
 		Type:   channel.StreamEventStatus,
 		Status: channel.StreamStatusCompleted,
@@ -547,6 +644,33 @@ func (p *ChannelInboundProcessor) HandleInbound(ctx context.Context, cfg channel
 	return nil
 }
 
+// synthesizeVoiceReply speaks the delivered reply text as a voice note. The
+// text reply has already been sent, so channels without voice note support
+// (or any synthesis failure) simply keep the text-only reply.
+func (p *ChannelInboundProcessor) synthesizeVoiceReply(ctx context.Context, capabilities channel.ChannelCapabilities, identity InboundIdentity, routeID string, texts []string) (channel.Attachment, bool) {
+	if p.voiceReplies == nil || !capabilities.VoiceNotes || len(texts) == 0 {
+		return channel.Attachment{}, false
+	}
+	botID := strings.TrimSpace(identity.BotID)
+	if !p.voiceReplies.VoiceReplyEnabled(ctx, botID, routeID) {
+		return channel.Attachment{}, false
+	}
+	asset, err := p.voiceReplies.Synthesize(ctx, botID, strings.Join(texts, "\n\n"))
+	if err != nil {
+		if p.logger != nil {
+			p.logger.Warn("voice reply synthesis failed, keeping text reply",
+				slog.String("bot_id", botID),
+				slog.String("route_id", routeID),
+				slog.Any("error", err),
+			)
+		}
+		return channel.Attachment{}, false
+	}
+	voice := channel.Attachment{Type: channel.AttachmentVoice}
+	applyAssetToAttachment(asset, botID, &voice)
+	return voice, true
+}
+
 func shouldTriggerAssistantResponse(msg channel.InboundMessage) bool {
 	if isDirectConversationType(msg.Conversation.Type) {
 		return true
@@ -649,6 +773,7 @@ func (p *ChannelInboundProcessor) persistInboundUser(
 	query string,
 	attachments []conversation.ChatAttachment,
 	triggerMode string,
+	moderation map[string]any,
 ) bool {
 	if p.message == nil {
 		return false
@@ -688,6 +813,9 @@ func (p *ChannelInboundProcessor) persistInboundUser(
 		"platform":     msg.Channel.String(),
 		"trigger_mode": strings.TrimSpace(triggerMode),
 	}
+	if moderation != nil {
+		meta["moderation"] = moderation
+	}
 	if _, err := p.message.Persist(ctx, messagepkg.PersistInput{
 		BotID:                   botID,
 		RouteID:                 strings.TrimSpace(routeID),
@@ -872,6 +1000,11 @@ type gatewayStreamEnvelope struct {
 	Input       json.RawMessage `json:"input"`
 	Result      json.RawMessage `json:"result"`
 	Attachments json.RawMessage `json:"attachments"`
+
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	Cancelled bool `json:"cancelled"`
 }
 
 type gatewayStreamDoneData struct {
@@ -976,12 +1109,28 @@ func mapStreamChunkToChannelEvents(chunk conversation.StreamChunk) ([]channel.St
 			},
 		}, finalMessages, nil
 	case "agent_end":
+		metadata := map[string]any{
+			"result": parseRawJSON(envelope.Result),
+			"data":   parseRawJSON(envelope.Data),
+		}
+		if envelope.Cancelled {
+			metadata["cancelled"] = true
+		}
+		return []channel.StreamEvent{
+			{
+				Type:     channel.StreamEventAgentEnd,
+				Metadata: metadata,
+			},
+		}, finalMessages, nil
+	case "model_fallback":
 		return []channel.StreamEvent{
 			{
-				Type: channel.StreamEventAgentEnd,
+				Type:   channel.StreamEventStatus,
+				Status: channel.StreamStatusFallback,
+				Error:  strings.TrimSpace(envelope.Error),
 				Metadata: map[string]any{
-					"result": parseRawJSON(envelope.Result),
-					"data":   parseRawJSON(envelope.Data),
+					"from_model": strings.TrimSpace(envelope.From),
+					"to_model":   strings.TrimSpace(envelope.To),
 				},
 			},
 		}, finalMessages, nil
@@ -19,6 +19,7 @@ import (
 	"github.com/memohai/memoh/internal/conversation"
 	"github.com/memohai/memoh/internal/media"
 	messagepkg "github.com/memohai/memoh/internal/message"
+	"github.com/memohai/memoh/internal/quota"
 	"github.com/memohai/memoh/internal/schedule"
 )
 
@@ -956,6 +957,44 @@ func TestChannelInboundProcessorProcessingStatusFailureLifecycle(t *testing.T) {
 	}
 }
 
+func TestChannelInboundProcessorRepliesWhenQuotaExceeded(t *testing.T) {
+	notifier := &fakeProcessingStatusNotifier{
+		startedHandle: channel.ProcessingStatusHandle{Token: "reaction-q"},
+	}
+	registry := channel.NewRegistry()
+	registry.MustRegister(&fakeProcessingStatusAdapter{notifier: notifier})
+	channelIdentitySvc := &fakeChannelIdentityService{channelIdentity: identities.ChannelIdentity{ID: "channelIdentity-q"}}
+	memberSvc := &fakeMemberService{isMember: true}
+	chatSvc := &fakeChatService{resolveResult: route.ResolveConversationResult{ChatID: "chat-q", RouteID: "route-q"}}
+	gateway := &fakeChatGateway{err: fmt.Errorf("resolve: %w", &quota.ExceededError{Message: "You have reached your daily usage limit for this bot."})}
+	processor := NewChannelInboundProcessor(slog.Default(), registry, chatSvc, chatSvc, gateway, channelIdentitySvc, memberSvc, nil, nil, nil, "", 0)
+	sender := &fakeReplySender{}
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("feishu")}
+	msg := channel.InboundMessage{
+		BotID:        "bot-1",
+		Channel:      channel.ChannelType("feishu"),
+		Message:      channel.Message{ID: "om_q", Text: "hello"},
+		ReplyTarget:  "chat_id:oc_q",
+		Sender:       channel.Identity{SubjectID: "ext-q"},
+		Conversation: channel.Conversation{ID: "oc_q", Type: "p2p"},
+	}
+
+	if err := processor.HandleInbound(context.Background(), cfg, msg, sender); err != nil {
+		t.Fatalf("quota refusal should not fail the inbound message: %v", err)
+	}
+	if len(notifier.events) != 2 || notifier.events[1] != "completed" {
+		t.Fatalf("unexpected processing status lifecycle: %+v", notifier.events)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Message.PlainText() != "You have reached your daily usage limit for this bot." {
+		t.Fatalf("expected the quota notice as reply, got: %+v", sender.sent)
+	}
+	for _, event := range sender.events {
+		if event.Type == channel.StreamEventError {
+			t.Fatalf("unexpected error event: %+v", event)
+		}
+	}
+}
+
 func TestChannelInboundProcessorProcessingStatusErrorsAreBestEffort(t *testing.T) {
 	notifier := &fakeProcessingStatusNotifier{
 		startedErr:   errors.New("start notify failed"),
@@ -1165,6 +1204,12 @@ func TestMapStreamChunkToChannelEvents(t *testing.T) {
 			chunk:    `{"type":"agent_end","result":{"ok":true}}`,
 			wantType: channel.StreamEventAgentEnd,
 		},
+		{
+			name:      "model_fallback",
+			chunk:     `{"type":"model_fallback","from":"gpt-4o","to":"claude-sonnet","error":"rate limited"}`,
+			wantType:  channel.StreamEventStatus,
+			wantError: "rate limited",
+		},
 		{
 			name:     "processing_started",
 			chunk:    `{"type":"processing_started"}`,
@@ -1468,3 +1513,104 @@ func TestMapChannelToChatAttachments(t *testing.T) {
 		t.Fatalf("expected non-asset attachment URL, got %q", mapped[1].URL)
 	}
 }
+
+type fakeVoiceAdapter struct {
+	voiceNotes bool
+}
+
+func (a *fakeVoiceAdapter) Type() channel.ChannelType {
+	return channel.ChannelType("voicechat")
+}
+
+func (a *fakeVoiceAdapter) Descriptor() channel.Descriptor {
+	return channel.Descriptor{
+		Type: channel.ChannelType("voicechat"),
+		Capabilities: channel.ChannelCapabilities{
+			Text:        true,
+			Attachments: true,
+			VoiceNotes:  a.voiceNotes,
+		},
+	}
+}
+
+type fakeVoiceReplySynthesizer struct {
+	enabled bool
+	text    string
+	calls   int
+}
+
+func (f *fakeVoiceReplySynthesizer) VoiceReplyEnabled(_ context.Context, _, _ string) bool {
+	return f.enabled
+}
+
+func (f *fakeVoiceReplySynthesizer) Synthesize(_ context.Context, botID, text string) (media.Asset, error) {
+	f.calls++
+	f.text = text
+	return media.Asset{ContentHash: "voice-hash", BotID: botID, Mime: "audio/ogg", SizeBytes: 4, StorageKey: "bot-1/voice-hash.ogg"}, nil
+}
+
+func TestChannelInboundProcessorVoiceReply(t *testing.T) {
+	tests := []struct {
+		name       string
+		voiceNotes bool
+		enabled    bool
+		wantSent   int
+		wantCalls  int
+	}{
+		{name: "voice notes supported", voiceNotes: true, enabled: true, wantSent: 2, wantCalls: 1},
+		{name: "text fallback without capability", voiceNotes: false, enabled: true, wantSent: 1, wantCalls: 0},
+		{name: "disabled for route", voiceNotes: true, enabled: false, wantSent: 1, wantCalls: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := channel.NewRegistry()
+			registry.MustRegister(&fakeVoiceAdapter{voiceNotes: tt.voiceNotes})
+			channelIdentitySvc := &fakeChannelIdentityService{channelIdentity: identities.ChannelIdentity{ID: "channelIdentity-1"}}
+			memberSvc := &fakeMemberService{isMember: true}
+			chatSvc := &fakeChatService{resolveResult: route.ResolveConversationResult{ChatID: "chat-1", RouteID: "route-1"}}
+			gateway := &fakeChatGateway{
+				resp: conversation.ChatResponse{
+					Messages: []conversation.ModelMessage{
+						{Role: "assistant", Content: conversation.NewTextContent("Good morning")},
+					},
+				},
+			}
+			processor := NewChannelInboundProcessor(slog.Default(), registry, chatSvc, chatSvc, gateway, channelIdentitySvc, memberSvc, nil, nil, nil, "", 0)
+			synth := &fakeVoiceReplySynthesizer{enabled: tt.enabled}
+			processor.SetVoiceReplySynthesizer(synth)
+			sender := &fakeReplySender{}
+			cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("voicechat")}
+			msg := channel.InboundMessage{
+				BotID:        "bot-1",
+				Channel:      channel.ChannelType("voicechat"),
+				Message:      channel.Message{ID: "m-1", Text: "hello"},
+				ReplyTarget:  "target-id",
+				Sender:       channel.Identity{SubjectID: "ext-1"},
+				Conversation: channel.Conversation{ID: "c-1", Type: "p2p"},
+			}
+
+			if err := processor.HandleInbound(context.Background(), cfg, msg, sender); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if synth.calls != tt.wantCalls {
+				t.Fatalf("expected %d synthesize calls, got %d", tt.wantCalls, synth.calls)
+			}
+			if len(sender.sent) != tt.wantSent {
+				t.Fatalf("expected %d outbound messages, got %d", tt.wantSent, len(sender.sent))
+			}
+			if sender.sent[0].Message.PlainText() != "Good morning" {
+				t.Fatalf("expected text reply first, got %+v", sender.sent[0].Message)
+			}
+			if tt.wantCalls == 0 {
+				return
+			}
+			if synth.text != "Good morning" {
+				t.Fatalf("unexpected spoken text %q", synth.text)
+			}
+			voice := sender.sent[1].Message.Attachments
+			if len(voice) != 1 || voice[0].Type != channel.AttachmentVoice || voice[0].ContentHash != "voice-hash" {
+				t.Fatalf("expected voice attachment, got %+v", voice)
+			}
+		})
+	}
+}
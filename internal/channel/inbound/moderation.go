@@ -0,0 +1,73 @@
+package inbound
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/flow"
+)
+
+// withheldInputText is stored in place of a blocked inbound message, so the
+// history never carries it to the model.
+const withheldInputText = "[withheld by content moderation]"
+
+// inputModerator screens inbound text before it is stored.
+type inputModerator interface {
+	ModerateInput(ctx context.Context, req conversation.ChatRequest, text string) (flow.ModeratedInput, error)
+}
+
+// SetModerator enables content moderation of inbound messages. Messages are
+// screened before they are stored, so blocked text never enters the history
+// or the inbox.
+func (p *ChannelInboundProcessor) SetModerator(moderator inputModerator) {
+	if p == nil {
+		return
+	}
+	p.moderator = moderator
+}
+
+// moderateInbound screens the text of msg. Without a moderator the text
+// passes as it is.
+func (p *ChannelInboundProcessor) moderateInbound(ctx context.Context, identity InboundIdentity, msg channel.InboundMessage, routeID, text string) (flow.ModeratedInput, error) {
+	if p.moderator == nil || strings.TrimSpace(text) == "" {
+		return flow.ModeratedInput{Text: text}, nil
+	}
+	return p.moderator.ModerateInput(ctx, conversation.ChatRequest{
+		BotID:                   identity.BotID,
+		UserID:                  identity.UserID,
+		SourceChannelIdentityID: identity.ChannelIdentityID,
+		DisplayName:             identity.DisplayName,
+		RouteID:                 routeID,
+		CurrentChannel:          msg.Channel.String(),
+	}, text)
+}
+
+// handleBlockedInbound stores a blocked message withheld, with the verdict
+// in its metadata, and tells the sender when the bot was addressed.
+// Messages the bot was not addressed by are dropped.
+func (p *ChannelInboundProcessor) handleBlockedInbound(
+	ctx context.Context,
+	sender channel.StreamReplySender,
+	identity InboundIdentity,
+	msg channel.InboundMessage,
+	routeID string,
+	triggered bool,
+	moderated flow.ModeratedInput,
+) error {
+	if p.logger != nil {
+		p.logger.Info("inbound message blocked by moderation",
+			slog.String("channel", msg.Channel.String()),
+			slog.String("bot_id", strings.TrimSpace(identity.BotID)),
+			slog.String("route_id", strings.TrimSpace(routeID)),
+			slog.Bool("triggered", triggered),
+		)
+	}
+	if !triggered {
+		return nil
+	}
+	p.persistInboundUser(ctx, routeID, identity, msg, withheldInputText, nil, "active_chat", moderated.Metadata)
+	return replyCommand(ctx, sender, msg, moderated.Message)
+}
@@ -0,0 +1,93 @@
+package inbound
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/channel/identities"
+	"github.com/memohai/memoh/internal/channel/route"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/flow"
+)
+
+type fakeInputModerator struct {
+	result flow.ModeratedInput
+	texts  []string
+}
+
+func (f *fakeInputModerator) ModerateInput(ctx context.Context, req conversation.ChatRequest, text string) (flow.ModeratedInput, error) {
+	f.texts = append(f.texts, text)
+	return f.result, nil
+}
+
+func newModeratedProcessor(moderator *fakeInputModerator, gateway *fakeChatGateway) (*ChannelInboundProcessor, *fakeChatService) {
+	channelIdentitySvc := &fakeChannelIdentityService{channelIdentity: identities.ChannelIdentity{ID: "channelIdentity-1"}}
+	memberSvc := &fakeMemberService{isMember: true}
+	policySvc := &fakePolicyService{ownerUserID: "owner-1"}
+	chatSvc := &fakeChatService{resolveResult: route.ResolveConversationResult{ChatID: "chat-1", RouteID: "route-1"}}
+	processor := NewChannelInboundProcessor(slog.Default(), nil, chatSvc, chatSvc, gateway, channelIdentitySvc, memberSvc, policySvc, nil, nil, "", 0)
+	processor.SetModerator(moderator)
+	return processor, chatSvc
+}
+
+func TestBlockedInboundMessageIsWithheld(t *testing.T) {
+	verdict := map[string]any{"stage": "input", "action": "block", "rules": []string{"slurs"}}
+	moderator := &fakeInputModerator{result: flow.ModeratedInput{Blocked: true, Message: "Not allowed here.", Metadata: verdict}}
+	gateway := &fakeChatGateway{}
+	processor, chatSvc := newModeratedProcessor(moderator, gateway)
+	sender := &fakeReplySender{}
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("telegram")}
+
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("something offensive"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if gateway.gotReq.BotID != "" {
+		t.Fatalf("blocked message must not reach the gateway, got %+v", gateway.gotReq)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].Message.PlainText() != "Not allowed here." {
+		t.Fatalf("expected the block message sent, got %+v", sender.sent)
+	}
+	if len(chatSvc.persistedIn) != 1 {
+		t.Fatalf("expected the withheld message persisted, got %d", len(chatSvc.persistedIn))
+	}
+	stored := chatSvc.persistedIn[0]
+	var content conversation.ModelMessage
+	if err := json.Unmarshal(stored.Content, &content); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(content.TextContent(), "offensive") || !strings.Contains(content.TextContent(), withheldInputText) {
+		t.Fatalf("expected withheld content, got %q", content.TextContent())
+	}
+	if stored.Metadata["moderation"] == nil {
+		t.Fatalf("expected the verdict in metadata, got %+v", stored.Metadata)
+	}
+}
+
+func TestRedactedInboundMessageReachesGatewayRedacted(t *testing.T) {
+	verdict := map[string]any{"stage": "input", "action": "redact", "rules": []string{"phone"}}
+	moderator := &fakeInputModerator{result: flow.ModeratedInput{Text: "call me at [redacted]", Metadata: verdict}}
+	gateway := &fakeChatGateway{}
+	processor, chatSvc := newModeratedProcessor(moderator, gateway)
+	sender := &fakeReplySender{}
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("telegram")}
+
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("call me at 555-0100"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(moderator.texts) != 1 || moderator.texts[0] != "call me at 555-0100" {
+		t.Fatalf("expected the raw text screened once, got %v", moderator.texts)
+	}
+	if gateway.gotReq.Query != "call me at [redacted]" || !gateway.gotReq.InputModerated || gateway.gotReq.InputModeration == nil {
+		t.Fatalf("unexpected gateway request %+v", gateway.gotReq)
+	}
+	if len(chatSvc.persistedIn) == 0 || chatSvc.persistedIn[0].Metadata["moderation"] == nil {
+		t.Fatal("expected the verdict in the user message metadata")
+	}
+	if strings.Contains(string(chatSvc.persistedIn[0].Content), "555-0100") {
+		t.Fatalf("stored user message kept redacted text: %s", chatSvc.persistedIn[0].Content)
+	}
+}
@@ -0,0 +1,269 @@
+package inbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+const (
+	settingsCommand       = "/settings"
+	settingsPromptPreview = 200
+)
+
+const settingsCommandUsage = `Usage:
+/settings — show this conversation's settings
+/settings set <name> <value> — override a bot setting
+/settings reset [<name>|all] — fall back to the bot setting
+
+Names: prompt, temperature, top_p, reasoning (on/off), effort (low/medium/high), max_tokens, context_minutes, skills, tools`
+
+// settingsCommandFields maps command names to override names.
+var settingsCommandFields = map[string]string{
+	"prompt":                conversation.OverrideSystemPrompt,
+	"system_prompt":         conversation.OverrideSystemPrompt,
+	"temperature":           conversation.OverrideTemperature,
+	"top_p":                 conversation.OverrideTopP,
+	"reasoning":             conversation.OverrideReasoningEnabled,
+	"effort":                conversation.OverrideReasoningEffort,
+	"reasoning_effort":      conversation.OverrideReasoningEffort,
+	"max_tokens":            conversation.OverrideMaxContextTokens,
+	"max_context_tokens":    conversation.OverrideMaxContextTokens,
+	"context_minutes":       conversation.OverrideMaxContextLoadTime,
+	"max_context_load_time": conversation.OverrideMaxContextLoadTime,
+	"skills":                conversation.OverrideEnabledSkills,
+	"enabled_skills":        conversation.OverrideEnabledSkills,
+	"tools":                 conversation.OverrideAllowedTools,
+	"allowed_tools":         conversation.OverrideAllowedTools,
+	"all":                   conversation.OverrideAll,
+}
+
+// SetConversationSettings enables the /settings command.
+func (p *ChannelInboundProcessor) SetConversationSettings(service conversation.SettingsManager) {
+	if p == nil {
+		return
+	}
+	p.settings = service
+}
+
+// parseSettingsCommand returns the arguments of a /settings command. It
+// accepts the Telegram form /settings@botname.
+func parseSettingsCommand(text string) (string, bool) {
+	return parseCommand(text, settingsCommand)
+}
+
+// parseCommand returns the arguments of a slash command, accepting the
+// Telegram form /command@botname.
+func parseCommand(text, command string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(strings.ToLower(text), command) {
+		return "", false
+	}
+	rest := text[len(command):]
+	if strings.HasPrefix(rest, "@") {
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			return "", true
+		}
+		rest = rest[end:]
+	} else if r, _ := utf8.DecodeRuneInString(rest); rest != "" && !unicode.IsSpace(r) {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// handleSettingsCommand answers a /settings command. Anyone who may talk to
+// the bot can read the settings, except the prompt text; only the bot owner
+// can see the prompt and change settings.
+func (p *ChannelInboundProcessor) handleSettingsCommand(ctx context.Context, identity InboundIdentity, args string) (string, error) {
+	chatID := strings.TrimSpace(identity.BotID)
+	verb, rest := splitCommandWord(args)
+	switch strings.ToLower(verb) {
+	case "":
+		current, err := p.settings.GetSettings(ctx, chatID)
+		if err != nil {
+			return "", fmt.Errorf("get conversation settings: %w", err)
+		}
+		return formatConversationSettings(current, p.isBotOwner(ctx, identity)), nil
+	case "set", "reset":
+	default:
+		return settingsCommandUsage, nil
+	}
+
+	if !p.isBotOwner(ctx, identity) {
+		return "Only the bot owner can change conversation settings.", nil
+	}
+	var req conversation.UpdateSettingsRequest
+	if strings.EqualFold(verb, "reset") {
+		name := strings.ToLower(strings.TrimSpace(rest))
+		if name == "" {
+			name = conversation.OverrideAll
+		}
+		field, ok := settingsCommandFields[name]
+		if !ok {
+			return settingsCommandUsage, nil
+		}
+		req.Reset = []string{field}
+	} else {
+		name, value := splitCommandWord(rest)
+		field, ok := settingsCommandFields[strings.ToLower(name)]
+		if !ok || field == conversation.OverrideAll || value == "" {
+			return settingsCommandUsage, nil
+		}
+		if err := setSettingsField(&req, field, value); err != nil {
+			return err.Error(), nil
+		}
+	}
+	updated, err := p.settings.UpdateSettings(ctx, chatID, req)
+	if err != nil {
+		if errors.Is(err, conversation.ErrInvalidSettings) {
+			return err.Error(), nil
+		}
+		return "", fmt.Errorf("update conversation settings: %w", err)
+	}
+	return formatConversationSettings(updated, true), nil
+}
+
+func (p *ChannelInboundProcessor) isBotOwner(ctx context.Context, identity InboundIdentity) bool {
+	if p.identity == nil || p.identity.policy == nil {
+		return false
+	}
+	userID := strings.TrimSpace(identity.UserID)
+	if userID == "" {
+		return false
+	}
+	ownerUserID, err := p.identity.policy.BotOwnerUserID(ctx, identity.BotID)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(ownerUserID) == userID
+}
+
+func setSettingsField(req *conversation.UpdateSettingsRequest, field, value string) error {
+	switch field {
+	case conversation.OverrideSystemPrompt:
+		req.SystemPrompt = &value
+	case conversation.OverrideTemperature, conversation.OverrideTopP:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", field)
+		}
+		if field == conversation.OverrideTemperature {
+			req.Temperature = &f
+		} else {
+			req.TopP = &f
+		}
+	case conversation.OverrideReasoningEnabled:
+		var enabled bool
+		switch strings.ToLower(value) {
+		case "on", "true", "yes", "1":
+			enabled = true
+		case "off", "false", "no", "0":
+		default:
+			return fmt.Errorf("reasoning must be on or off")
+		}
+		req.ReasoningEnabled = &enabled
+	case conversation.OverrideReasoningEffort:
+		req.ReasoningEffort = &value
+	case conversation.OverrideMaxContextTokens, conversation.OverrideMaxContextLoadTime:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number", field)
+		}
+		if field == conversation.OverrideMaxContextTokens {
+			req.MaxContextTokens = &n
+		} else {
+			req.MaxContextLoadTime = &n
+		}
+	case conversation.OverrideEnabledSkills:
+		req.EnabledSkills = splitList(value)
+	case conversation.OverrideAllowedTools:
+		req.AllowedTools = splitList(value)
+	}
+	return nil
+}
+
+// formatConversationSettings lists the settings. The prompt text is only
+// shown with showPrompt, since it may hold instructions meant for the bot
+// alone.
+func formatConversationSettings(s conversation.Settings, showPrompt bool) string {
+	const inherited = "(bot default)"
+	value := func(set bool, v string) string {
+		if !set {
+			return inherited
+		}
+		return v
+	}
+	prompt := s.SystemPrompt
+	if utf8.RuneCountInString(prompt) > settingsPromptPreview {
+		prompt = string([]rune(prompt)[:settingsPromptPreview]) + "…"
+	}
+	if prompt != "" && !showPrompt {
+		prompt = "(set)"
+	}
+	var b strings.Builder
+	b.WriteString("Conversation settings:\n")
+	fmt.Fprintf(&b, "model: %s\n", value(s.ModelID != "", s.ModelID))
+	fmt.Fprintf(&b, "prompt: %s\n", value(prompt != "", prompt))
+	fmt.Fprintf(&b, "temperature: %s\n", value(s.Temperature != nil, formatFloatPtr(s.Temperature)))
+	fmt.Fprintf(&b, "top_p: %s\n", value(s.TopP != nil, formatFloatPtr(s.TopP)))
+	reasoning := ""
+	if s.ReasoningEnabled != nil {
+		reasoning = "off"
+		if *s.ReasoningEnabled {
+			reasoning = "on"
+		}
+	}
+	fmt.Fprintf(&b, "reasoning: %s\n", value(reasoning != "", reasoning))
+	fmt.Fprintf(&b, "effort: %s\n", value(s.ReasoningEffort != "", s.ReasoningEffort))
+	fmt.Fprintf(&b, "max_tokens: %s\n", value(s.MaxContextTokens != nil, formatIntPtr(s.MaxContextTokens)))
+	fmt.Fprintf(&b, "context_minutes: %s\n", value(s.MaxContextLoadTime != nil, formatIntPtr(s.MaxContextLoadTime)))
+	fmt.Fprintf(&b, "skills: %s\n", value(len(s.EnabledSkills) > 0, strings.Join(s.EnabledSkills, ", ")))
+	fmt.Fprintf(&b, "tools: %s", value(len(s.AllowedTools) > 0, strings.Join(s.AllowedTools, ", ")))
+	return b.String()
+}
+
+func formatFloatPtr(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
+
+func formatIntPtr(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// splitCommandWord splits off the first whitespace-separated word.
+func splitCommandWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, unicode.IsSpace)
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], strings.TrimSpace(s[end:])
+}
+
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// replyCommand sends the text reply to a native command.
+func replyCommand(ctx context.Context, sender channel.StreamReplySender, msg channel.InboundMessage, text string) error {
+	return sender.Send(ctx, channel.OutboundMessage{
+		Target:  strings.TrimSpace(msg.ReplyTarget),
+		Message: channel.Message{Text: text},
+	})
+}
@@ -0,0 +1,135 @@
+package inbound
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/channel/identities"
+	"github.com/memohai/memoh/internal/channel/route"
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+type fakeSettingsManager struct {
+	current conversation.Settings
+	updates []conversation.UpdateSettingsRequest
+}
+
+func (f *fakeSettingsManager) GetSettings(ctx context.Context, conversationID string) (conversation.Settings, error) {
+	return f.current, nil
+}
+
+func (f *fakeSettingsManager) UpdateSettings(ctx context.Context, conversationID string, req conversation.UpdateSettingsRequest) (conversation.Settings, error) {
+	f.updates = append(f.updates, req)
+	if req.Temperature != nil {
+		f.current.Temperature = req.Temperature
+	}
+	return f.current, nil
+}
+
+func TestParseSettingsCommand(t *testing.T) {
+	cases := []struct {
+		text string
+		args string
+		ok   bool
+	}{
+		{"/settings", "", true},
+		{"  /settings set temperature 0.3 ", "set temperature 0.3", true},
+		{"/settings@memoh_bot reset all", "reset all", true},
+		{"/Settings", "", true},
+		{"/settingsx", "", false},
+		{"show /settings", "", false},
+	}
+	for _, tc := range cases {
+		args, ok := parseSettingsCommand(tc.text)
+		if args != tc.args || ok != tc.ok {
+			t.Errorf("parseSettingsCommand(%q) = %q, %v; want %q, %v", tc.text, args, ok, tc.args, tc.ok)
+		}
+	}
+}
+
+func newSettingsCommandProcessor(ownerID string, manager *fakeSettingsManager, gateway *fakeChatGateway) *ChannelInboundProcessor {
+	channelIdentitySvc := &fakeChannelIdentityService{channelIdentity: identities.ChannelIdentity{ID: "channelIdentity-1"}}
+	memberSvc := &fakeMemberService{isMember: true}
+	policySvc := &fakePolicyService{ownerUserID: ownerID}
+	chatSvc := &fakeChatService{resolveResult: route.ResolveConversationResult{ChatID: "chat-1", RouteID: "route-1"}}
+	processor := NewChannelInboundProcessor(slog.Default(), nil, chatSvc, chatSvc, gateway, channelIdentitySvc, memberSvc, policySvc, nil, nil, "", 0)
+	processor.SetConversationSettings(manager)
+	return processor
+}
+
+func settingsCommandMessage(text string) channel.InboundMessage {
+	return channel.InboundMessage{
+		BotID:        "bot-1",
+		Channel:      channel.ChannelType("telegram"),
+		Message:      channel.Message{Text: text},
+		ReplyTarget:  "target-id",
+		Sender:       channel.Identity{SubjectID: "ext-1", DisplayName: "User1"},
+		Conversation: channel.Conversation{ID: "chat-1", Type: "private"},
+	}
+}
+
+func TestSettingsCommandOwnerCanSet(t *testing.T) {
+	manager := &fakeSettingsManager{}
+	gateway := &fakeChatGateway{}
+	processor := newSettingsCommandProcessor("channelIdentity-1", manager, gateway)
+	sender := &fakeReplySender{}
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("telegram")}
+
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("/settings set temperature 0.3"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(manager.updates) != 1 || manager.updates[0].Temperature == nil || *manager.updates[0].Temperature != 0.3 {
+		t.Fatalf("expected a temperature update, got %+v", manager.updates)
+	}
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Message.PlainText(), "temperature: 0.3") {
+		t.Fatalf("expected settings reply, got %+v", sender.sent)
+	}
+	if gateway.gotReq.Query != "" {
+		t.Fatalf("command must not reach the chat model, got query %q", gateway.gotReq.Query)
+	}
+}
+
+func TestSettingsCommandNonOwnerCanOnlyRead(t *testing.T) {
+	manager := &fakeSettingsManager{current: conversation.Settings{SystemPrompt: "answer in French"}}
+	processor := newSettingsCommandProcessor("someone-else", manager, &fakeChatGateway{})
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("telegram")}
+
+	sender := &fakeReplySender{}
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("/settings reset"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(manager.updates) != 0 {
+		t.Fatalf("non-owner must not update settings, got %+v", manager.updates)
+	}
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Message.PlainText(), "Only the bot owner") {
+		t.Fatalf("expected a permission reply, got %+v", sender.sent)
+	}
+
+	sender = &fakeReplySender{}
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("/settings"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Message.PlainText(), "prompt: (set)") {
+		t.Fatalf("expected the current settings, got %+v", sender.sent)
+	}
+	if strings.Contains(sender.sent[0].Message.PlainText(), "French") {
+		t.Fatalf("non-owner must not see the prompt text, got %q", sender.sent[0].Message.PlainText())
+	}
+}
+
+func TestSettingsCommandOwnerSeesPrompt(t *testing.T) {
+	manager := &fakeSettingsManager{current: conversation.Settings{SystemPrompt: "answer in French"}}
+	processor := newSettingsCommandProcessor("channelIdentity-1", manager, &fakeChatGateway{})
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("telegram")}
+
+	sender := &fakeReplySender{}
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("/settings"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Message.PlainText(), "prompt: answer in French") {
+		t.Fatalf("expected the owner to see the prompt, got %+v", sender.sent)
+	}
+}
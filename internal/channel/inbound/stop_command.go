@@ -0,0 +1,42 @@
+package inbound
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/conversation/flow"
+)
+
+const stopCommand = "/stop"
+
+// generationCanceller is implemented by runners that can stop a streaming
+// reply, such as flow.Resolver.
+type generationCanceller interface {
+	CancelGenerations(botID, routeID, requestID string) []flow.Generation
+}
+
+// handleStopCommand stops the replies being generated in the route a /stop
+// command was sent from. Anyone allowed to talk to the bot there may stop
+// them. It reports false when the runner cannot cancel, so the text is
+// handled as a normal message.
+func (p *ChannelInboundProcessor) handleStopCommand(ctx context.Context, sender channel.StreamReplySender, msg channel.InboundMessage, botID, routeID string) (bool, error) {
+	canceller, ok := p.runner.(generationCanceller)
+	if !ok {
+		return false, nil
+	}
+	stopped := canceller.CancelGenerations(botID, routeID, "")
+	reply := "Nothing to stop."
+	switch len(stopped) {
+	case 0:
+	case 1:
+		reply = "Stopped."
+	default:
+		reply = fmt.Sprintf("Stopped %d replies.", len(stopped))
+	}
+	return true, sender.Send(ctx, channel.OutboundMessage{
+		Target:  strings.TrimSpace(msg.ReplyTarget),
+		Message: channel.Message{Text: reply},
+	})
+}
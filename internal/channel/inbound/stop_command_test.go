@@ -0,0 +1,41 @@
+package inbound
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/conversation/flow"
+)
+
+type cancellingChatGateway struct {
+	fakeChatGateway
+	cancelled []string
+}
+
+func (f *cancellingChatGateway) CancelGenerations(botID, routeID, requestID string) []flow.Generation {
+	f.cancelled = append(f.cancelled, botID+"/"+routeID)
+	return []flow.Generation{{RequestID: "req-1", BotID: botID, RouteID: routeID}}
+}
+
+func TestStopCommandCancelsRouteGeneration(t *testing.T) {
+	gateway := &cancellingChatGateway{}
+	processor := newSettingsCommandProcessor("channelIdentity-1", &fakeSettingsManager{}, &gateway.fakeChatGateway)
+	processor.runner = gateway
+	cfg := channel.ChannelConfig{ID: "cfg-1", BotID: "bot-1", ChannelType: channel.ChannelType("telegram")}
+
+	sender := &fakeReplySender{}
+	if err := processor.HandleInbound(context.Background(), cfg, settingsCommandMessage("/stop@memoh_bot"), sender); err != nil {
+		t.Fatal(err)
+	}
+	if len(gateway.cancelled) != 1 || gateway.cancelled[0] != "bot-1/route-1" {
+		t.Fatalf("expected the route generation cancelled, got %v", gateway.cancelled)
+	}
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Message.PlainText(), "Stopped") {
+		t.Fatalf("expected a stop reply, got %+v", sender.sent)
+	}
+	if gateway.gotReq.Query != "" {
+		t.Fatalf("command must not reach the chat model, got query %q", gateway.gotReq.Query)
+	}
+}
@@ -2,9 +2,11 @@ package channel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"testing"
+	"time"
 )
 
 // mockAdapter is used for inbound handleInbound tests.
@@ -215,3 +217,74 @@ func TestManager_handleInbound(t *testing.T) {
 		}
 	})
 }
+
+type blockingInboundProcessor struct {
+	started chan struct{}
+	done    chan error
+}
+
+func (f *blockingInboundProcessor) HandleInbound(ctx context.Context, cfg ChannelConfig, msg InboundMessage, sender StreamReplySender) error {
+	close(f.started)
+	<-ctx.Done()
+	f.done <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestManager_HandleInboundWithCancel(t *testing.T) {
+	processor := &blockingInboundProcessor{started: make(chan struct{}), done: make(chan error, 1)}
+	m := NewManager(slog.Default(), NewRegistry(), &fakeConfigStore{}, processor)
+	m.startInboundWorkers(context.Background())
+	defer func() {
+		_ = m.Shutdown(context.Background())
+	}()
+
+	reqCtx, reqCancel := context.WithCancel(context.Background())
+	done, cancel, err := m.HandleInboundWithCancel(reqCtx, ChannelConfig{BotID: "bot-1"}, InboundMessage{Message: Message{Text: "hi"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Ending the caller context must not abort processing on its own.
+	reqCancel()
+	select {
+	case <-processor.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("processor did not start")
+	}
+	select {
+	case err := <-processor.done:
+		t.Fatalf("processing ended before cancel: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case err := <-processor.done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("processing was not cancelled")
+	}
+	select {
+	case <-done.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected returned context to be done")
+	}
+}
+
+func TestManager_HandleInboundWithCancelReleasesOnCompletion(t *testing.T) {
+	m := NewManager(slog.Default(), NewRegistry(), &fakeConfigStore{}, &fakeInboundProcessor{})
+	m.startInboundWorkers(context.Background())
+	defer func() {
+		_ = m.Shutdown(context.Background())
+	}()
+
+	done, _, err := m.HandleInboundWithCancel(context.Background(), ChannelConfig{BotID: "bot-1"}, InboundMessage{Message: Message{Text: "hi"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-done.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected context to be released after processing")
+	}
+}
@@ -113,6 +113,7 @@ const (
 	StreamStatusStarted   StreamStatus = "started"
 	StreamStatusCompleted StreamStatus = "completed"
 	StreamStatusFailed    StreamStatus = "failed"
+	StreamStatusFallback  StreamStatus = "fallback"
 )
 
 // StreamFinalizePayload carries the final reply message emitted by a stream.
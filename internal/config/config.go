@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
@@ -25,6 +26,17 @@ const (
 	DefaultPGSSLMode        = "disable"
 	DefaultQdrantURL        = "http://127.0.0.1:6334"
 	DefaultQdrantCollection = "memory"
+	DefaultMemoryBackend    = MemoryBackendQdrant
+	DefaultMemoryDir        = "data/memory"
+)
+
+// Memory backends.
+const (
+	MemoryBackendQdrant = "qdrant"
+	MemoryBackendLocal  = "local"
+	// MemoryBackendPgvector keeps memories in the [postgres] database, which
+	// needs the pgvector extension.
+	MemoryBackendPgvector = "pgvector"
 )
 
 type Config struct {
@@ -36,7 +48,11 @@ type Config struct {
 	MCP          MCPConfig          `toml:"mcp"`
 	Postgres     PostgresConfig     `toml:"postgres"`
 	Qdrant       QdrantConfig       `toml:"qdrant"`
+	Memory       MemoryConfig       `toml:"memory"`
 	AgentGateway AgentGatewayConfig `toml:"agent_gateway"`
+	Tokenizer    TokenizerConfig    `toml:"tokenizer"`
+
+	ChannelAdapters []ChannelAdapterConfig `toml:"channel_adapters"`
 }
 
 type LogConfig struct {
@@ -95,9 +111,44 @@ type QdrantConfig struct {
 	TimeoutSeconds int    `toml:"timeout_seconds"`
 }
 
+// MemoryConfig selects where memories and their vectors are kept.
+type MemoryConfig struct {
+	// Backend is "qdrant", the server in the [qdrant] section, "pgvector",
+	// the [postgres] database, or "local", an embedded store persisted under
+	// Dir for small deployments.
+	Backend string `toml:"backend"`
+	Dir     string `toml:"dir"`
+}
+
 type AgentGatewayConfig struct {
 	Host string `toml:"host"`
 	Port int    `toml:"port"`
+	// Endpoints lists the base URLs of gateway processes to spread chat
+	// requests over. When empty, the gateway at Host and Port is the only one.
+	Endpoints []string `toml:"endpoints"`
+	// Balance is "round_robin" (default) or "least_inflight".
+	Balance string `toml:"balance"`
+	// HealthCheckIntervalSeconds is how often endpoints are probed. Zero
+	// uses 10 seconds.
+	HealthCheckIntervalSeconds int `toml:"health_check_interval_seconds"`
+}
+
+// TokenizerConfig points at a directory holding tiktoken rank files
+// (cl100k_base.tiktoken, o200k_base.tiktoken) used to count tokens for OpenAI
+// models. Other providers are always approximated.
+type TokenizerConfig struct {
+	EncodingsDir string `toml:"encodings_dir"`
+}
+
+// ChannelAdapterConfig declares an out-of-process channel adapter. Set either
+// Command, started with the adapter protocol on its stdin/stdout, or Address
+// ("unix:///path.sock" or "tcp://host:port") for an adapter running on its own.
+type ChannelAdapterConfig struct {
+	Name    string   `toml:"name"`
+	Command string   `toml:"command"`
+	Args    []string `toml:"args"`
+	Env     []string `toml:"env"`
+	Address string   `toml:"address"`
 }
 
 func (c AgentGatewayConfig) BaseURL() string {
@@ -112,6 +163,20 @@ func (c AgentGatewayConfig) BaseURL() string {
 	return "http://" + host + ":" + fmt.Sprint(port)
 }
 
+// EndpointURLs returns the configured endpoints, or BaseURL alone.
+func (c AgentGatewayConfig) EndpointURLs() []string {
+	urls := make([]string, 0, len(c.Endpoints))
+	for _, endpoint := range c.Endpoints {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			urls = append(urls, endpoint)
+		}
+	}
+	if len(urls) == 0 {
+		return []string{c.BaseURL()}
+	}
+	return urls
+}
+
 func Load(path string) (Config, error) {
 	cfg := Config{
 		Log: LogConfig{
@@ -151,6 +216,10 @@ func Load(path string) (Config, error) {
 			BaseURL:    DefaultQdrantURL,
 			Collection: DefaultQdrantCollection,
 		},
+		Memory: MemoryConfig{
+			Backend: DefaultMemoryBackend,
+			Dir:     DefaultMemoryDir,
+		},
 		AgentGateway: AgentGatewayConfig{
 			Host: "127.0.0.1",
 			Port: 8081,
@@ -0,0 +1,177 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/memohai/memoh/internal/conversation"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+// ErrBranchingUnsupported is returned when the message service cannot
+// rewrite history.
+var ErrBranchingUnsupported = errors.New("message service does not support branching")
+
+// Regenerate answers the latest user message on the active branch of the
+// request's route again.
+// The previous reply is superseded, not deleted, so it stays available as
+// an alternative.
+func (r *Resolver) Regenerate(ctx context.Context, req conversation.ChatRequest) (conversation.ChatResponse, error) {
+	brancher, err := r.brancher()
+	if err != nil {
+		return conversation.ChatResponse{}, err
+	}
+	user, err := brancher.LatestUserMessage(ctx, req.BotID, req.RouteID)
+	if err != nil {
+		return conversation.ChatResponse{}, err
+	}
+	restoreID, err := brancher.SupersedeAfter(ctx, req.BotID, user.ID)
+	if err != nil {
+		return conversation.ChatResponse{}, err
+	}
+	rerun := rerunRequest(req, user, storedUserQuery(user))
+	rerun.RerunMessageID = user.ID
+	rerun.UserMessagePersisted = true
+	resp, err := r.Chat(ctx, rerun)
+	if err != nil {
+		r.restoreBranch(ctx, brancher, req.BotID, restoreID)
+		return conversation.ChatResponse{}, err
+	}
+	return resp, nil
+}
+
+// EditAndResend replaces a user message on the active branch with text and
+// runs the conversation again from there. The original message and
+// everything after it become an alternative branch.
+func (r *Resolver) EditAndResend(ctx context.Context, req conversation.ChatRequest, messageID, text string) (conversation.ChatResponse, error) {
+	if strings.TrimSpace(text) == "" {
+		return conversation.ChatResponse{}, fmt.Errorf("text is required")
+	}
+	brancher, err := r.brancher()
+	if err != nil {
+		return conversation.ChatResponse{}, err
+	}
+	original, err := brancher.Get(ctx, req.BotID, messageID)
+	if err != nil {
+		return conversation.ChatResponse{}, err
+	}
+	if original.Role != "user" {
+		return conversation.ChatResponse{}, messagepkg.ErrNotUserMessage
+	}
+	if err := brancher.SupersedeFrom(ctx, req.BotID, original.ID); err != nil {
+		return conversation.ChatResponse{}, err
+	}
+	resp, err := r.Chat(ctx, rerunRequest(req, original, text))
+	if err != nil {
+		r.restoreBranch(ctx, brancher, req.BotID, original.ID)
+		return conversation.ChatResponse{}, err
+	}
+	return resp, nil
+}
+
+func (r *Resolver) brancher() (messagepkg.Brancher, error) {
+	brancher, ok := r.messageService.(messagepkg.Brancher)
+	if !ok {
+		return nil, ErrBranchingUnsupported
+	}
+	return brancher, nil
+}
+
+// restoreBranch puts the superseded branch back after a failed re-run.
+func (r *Resolver) restoreBranch(ctx context.Context, brancher messagepkg.Brancher, botID, messageID string) {
+	if messageID == "" {
+		return
+	}
+	if err := brancher.Activate(context.WithoutCancel(ctx), botID, messageID); err != nil {
+		r.logger.Warn("restore branch failed",
+			slog.String("bot_id", botID),
+			slog.String("message_id", messageID),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// rerunRequest builds a chat request that answers query as if it were sent
+// where original was: same route, channel, sender and attachments.
+func rerunRequest(req conversation.ChatRequest, original messagepkg.Message, query string) conversation.ChatRequest {
+	header, _ := SplitUserHeader(userMessageText(original))
+	req.Query = query
+	if len(req.Attachments) == 0 {
+		req.Attachments = storedAttachments(original.Assets)
+	}
+	req.RouteID = original.RouteID
+	req.CurrentChannel = original.Platform
+	req.ExternalMessageID = original.ExternalMessageID
+	req.SourceChannelIdentityID = original.SenderChannelIdentityID
+	if original.SenderUserID != "" {
+		req.UserID = original.SenderUserID
+	}
+	if req.ConversationType == "" {
+		req.ConversationType = header["conversation-type"]
+	}
+	if req.ConversationName == "" {
+		req.ConversationName = header["conversation-name"]
+	}
+	if len(req.Channels) == 0 && req.CurrentChannel != "" {
+		req.Channels = []string{req.CurrentChannel}
+	}
+	return req
+}
+
+// storedAttachments turns the assets of a stored message back into chat
+// attachments. Images are inlined from the media store by content hash.
+func storedAttachments(assets []messagepkg.MessageAsset) []conversation.ChatAttachment {
+	if len(assets) == 0 {
+		return nil
+	}
+	attachments := make([]conversation.ChatAttachment, 0, len(assets))
+	for _, asset := range assets {
+		if strings.TrimSpace(asset.ContentHash) == "" {
+			continue
+		}
+		att := conversation.ChatAttachment{
+			Type:        attachmentTypeFromMime(asset.Mime),
+			ContentHash: asset.ContentHash,
+			Mime:        asset.Mime,
+			Size:        asset.SizeBytes,
+		}
+		if asset.StorageKey != "" {
+			att.Metadata = map[string]any{"storage_key": asset.StorageKey}
+		}
+		attachments = append(attachments, att)
+	}
+	return attachments
+}
+
+func attachmentTypeFromMime(mime string) string {
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	switch {
+	case strings.HasPrefix(mime, "image/"):
+		return "image"
+	case strings.HasPrefix(mime, "audio/"):
+		return "audio"
+	case strings.HasPrefix(mime, "video/"):
+		return "video"
+	default:
+		return "file"
+	}
+}
+
+// storedUserQuery returns the text of a stored user message without the
+// header FormatUserHeader put in front of it.
+func storedUserQuery(msg messagepkg.Message) string {
+	_, query := SplitUserHeader(userMessageText(msg))
+	return query
+}
+
+func userMessageText(msg messagepkg.Message) string {
+	var mm conversation.ModelMessage
+	if err := json.Unmarshal(msg.Content, &mm); err != nil {
+		return string(msg.Content)
+	}
+	return mm.TextContent()
+}
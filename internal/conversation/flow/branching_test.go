@@ -0,0 +1,176 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/memohai/memoh/internal/conversation"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+type fakeBranchService struct {
+	messagepkg.Service
+	messages   map[string]messagepkg.Message
+	latestUser string
+	// latestRoute is the route whose latest user message is latestUser.
+	latestRoute string
+	next        string
+	superseded  []string
+	activated   []string
+}
+
+func (f *fakeBranchService) Get(ctx context.Context, botID, messageID string) (messagepkg.Message, error) {
+	msg, ok := f.messages[messageID]
+	if !ok {
+		return messagepkg.Message{}, messagepkg.ErrMessageNotFound
+	}
+	return msg, nil
+}
+
+func (f *fakeBranchService) LatestUserMessage(ctx context.Context, botID, routeID string) (messagepkg.Message, error) {
+	if f.latestUser == "" || routeID != f.latestRoute {
+		return messagepkg.Message{}, messagepkg.ErrNothingToRegenerate
+	}
+	return f.messages[f.latestUser], nil
+}
+
+func (f *fakeBranchService) SupersedeAfter(ctx context.Context, botID, messageID string) (string, error) {
+	f.superseded = append(f.superseded, "after:"+messageID)
+	return f.next, nil
+}
+
+func (f *fakeBranchService) SupersedeFrom(ctx context.Context, botID, messageID string) error {
+	f.superseded = append(f.superseded, "from:"+messageID)
+	return nil
+}
+
+func (f *fakeBranchService) Alternatives(ctx context.Context, botID, messageID string) ([]messagepkg.Message, error) {
+	return nil, nil
+}
+
+func (f *fakeBranchService) Activate(ctx context.Context, botID, messageID string) error {
+	f.activated = append(f.activated, messageID)
+	return nil
+}
+
+func storedUserMessage(t *testing.T, id, text string) messagepkg.Message {
+	t.Helper()
+	content, err := json.Marshal(conversation.ModelMessage{Role: "user", Content: conversation.NewTextContent(text)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messagepkg.Message{ID: id, Role: "user", Content: content, Platform: "telegram"}
+}
+
+func TestSplitUserHeader(t *testing.T) {
+	stored := FormatUserHeader("42", "ci-1", "Ann: admin", "telegram", "group", "Team", []string{"/data/a.png"}, "hello\n---\nworld")
+	header, query := SplitUserHeader(stored)
+	if query != "hello\n---\nworld" {
+		t.Fatalf("unexpected query %q", query)
+	}
+	if header["display-name"] != "Ann: admin" || header["conversation-type"] != "group" || header["conversation-name"] != "Team" {
+		t.Fatalf("unexpected header %v", header)
+	}
+
+	if _, query := SplitUserHeader("plain text"); query != "plain text" {
+		t.Fatalf("expected text without header unchanged, got %q", query)
+	}
+}
+
+func TestRegenerate_RestoresBranchWhenChatFails(t *testing.T) {
+	svc := &fakeBranchService{
+		messages: map[string]messagepkg.Message{
+			"u1": storedUserMessage(t, "u1", FormatUserHeader("", "", "Ann", "telegram", "private", "", nil, "hi")),
+		},
+		latestUser: "u1",
+		next:       "a1",
+	}
+	resolver := &Resolver{messageService: svc, logger: slog.Default()}
+
+	// Without a chat id resolve fails before reaching the gateway.
+	_, err := resolver.Regenerate(context.Background(), conversation.ChatRequest{BotID: "bot-1"})
+	if err == nil {
+		t.Fatal("expected chat error")
+	}
+	if len(svc.superseded) != 1 || svc.superseded[0] != "after:u1" {
+		t.Fatalf("expected reply after u1 superseded, got %v", svc.superseded)
+	}
+	if len(svc.activated) != 1 || svc.activated[0] != "a1" {
+		t.Fatalf("expected previous reply restored, got %v", svc.activated)
+	}
+}
+
+func TestRegenerate_OnlyRerunsTheRequestRoute(t *testing.T) {
+	dm := storedUserMessage(t, "u1", "my secret")
+	dm.RouteID = "route-dm"
+	svc := &fakeBranchService{
+		messages:    map[string]messagepkg.Message{"u1": dm},
+		latestUser:  "u1",
+		latestRoute: "route-dm",
+	}
+	resolver := &Resolver{messageService: svc, logger: slog.Default()}
+
+	_, err := resolver.Regenerate(context.Background(), conversation.ChatRequest{BotID: "bot-1", RouteID: "route-group"})
+	if !errors.Is(err, messagepkg.ErrNothingToRegenerate) {
+		t.Fatalf("expected nothing to regenerate in another route, got %v", err)
+	}
+	if len(svc.superseded) != 0 {
+		t.Fatalf("expected no history rewritten, got %v", svc.superseded)
+	}
+}
+
+func TestEditAndResend_RejectsAssistantMessage(t *testing.T) {
+	svc := &fakeBranchService{messages: map[string]messagepkg.Message{
+		"a1": {ID: "a1", Role: "assistant"},
+	}}
+	resolver := &Resolver{messageService: svc, logger: slog.Default()}
+
+	_, err := resolver.EditAndResend(context.Background(), conversation.ChatRequest{BotID: "bot-1", ChatID: "bot-1"}, "a1", "new text")
+	if !errors.Is(err, messagepkg.ErrNotUserMessage) {
+		t.Fatalf("expected ErrNotUserMessage, got %v", err)
+	}
+	if len(svc.superseded) != 0 {
+		t.Fatalf("expected history untouched, got %v", svc.superseded)
+	}
+}
+
+func TestRerunRequest_UsesOriginalRoute(t *testing.T) {
+	original := storedUserMessage(t, "u1", FormatUserHeader("", "ci-1", "Ann", "telegram", "supergroup", "Team", nil, "hi"))
+	original.RouteID = "route-1"
+	original.SenderChannelIdentityID = "ci-1"
+	original.ExternalMessageID = "m-7"
+
+	req := rerunRequest(conversation.ChatRequest{BotID: "bot-1", UserID: "caller"}, original, "hi again")
+	if req.Query != "hi again" || req.RouteID != "route-1" || req.CurrentChannel != "telegram" || req.ExternalMessageID != "m-7" {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	if req.ConversationType != "supergroup" || req.ConversationName != "Team" {
+		t.Fatalf("expected conversation from header, got %q %q", req.ConversationType, req.ConversationName)
+	}
+	if len(req.Channels) != 1 || req.Channels[0] != "telegram" {
+		t.Fatalf("expected current channel allowed, got %v", req.Channels)
+	}
+}
+
+func TestRerunRequest_CarriesOriginalAttachments(t *testing.T) {
+	original := storedUserMessage(t, "u1", "what is this?")
+	original.Assets = []messagepkg.MessageAsset{
+		{ContentHash: "h-img", Role: "attachment", Mime: "image/png", SizeBytes: 10, StorageKey: "ab/h-img.png"},
+		{ContentHash: "h-doc", Role: "attachment", Mime: "application/pdf", SizeBytes: 20},
+	}
+
+	req := rerunRequest(conversation.ChatRequest{BotID: "bot-1"}, original, "what is this?")
+	if len(req.Attachments) != 2 {
+		t.Fatalf("expected both attachments carried over, got %+v", req.Attachments)
+	}
+	img, doc := req.Attachments[0], req.Attachments[1]
+	if img.Type != "image" || img.ContentHash != "h-img" || img.Metadata["storage_key"] != "ab/h-img.png" {
+		t.Fatalf("unexpected image attachment %+v", img)
+	}
+	if doc.Type != "file" || doc.ContentHash != "h-doc" || doc.Mime != "application/pdf" {
+		t.Fatalf("unexpected file attachment %+v", doc)
+	}
+}
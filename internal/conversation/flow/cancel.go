@@ -0,0 +1,225 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/models"
+	"github.com/memohai/memoh/internal/tokenizer"
+)
+
+// ErrGenerationCancelled is the cancel cause of a stream stopped through
+// CancelGenerations.
+var ErrGenerationCancelled = errors.New("generation cancelled")
+
+// Generation describes a streaming reply in progress.
+type Generation struct {
+	RequestID string    `json:"request_id"`
+	BotID     string    `json:"bot_id"`
+	RouteID   string    `json:"route_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type generationEntry struct {
+	Generation
+	cancel context.CancelCauseFunc
+}
+
+// generationRegistry tracks in-flight streams by request ID so they can be
+// stopped from another request.
+type generationRegistry struct {
+	mu      sync.Mutex
+	entries map[string]generationEntry
+}
+
+// start registers req and returns a context cancelled by CancelGenerations.
+// The returned func unregisters it and must be called when the stream ends.
+func (g *generationRegistry) start(ctx context.Context, req *conversation.ChatRequest) (context.Context, func()) {
+	if strings.TrimSpace(req.RequestID) == "" {
+		req.RequestID = uuid.NewString()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	entry := generationEntry{
+		Generation: Generation{
+			RequestID: req.RequestID,
+			BotID:     strings.TrimSpace(req.BotID),
+			RouteID:   strings.TrimSpace(req.RouteID),
+			StartedAt: time.Now().UTC(),
+		},
+		cancel: cancel,
+	}
+	g.mu.Lock()
+	if g.entries == nil {
+		g.entries = map[string]generationEntry{}
+	}
+	g.entries[entry.RequestID] = entry
+	g.mu.Unlock()
+	return ctx, func() {
+		g.mu.Lock()
+		delete(g.entries, entry.RequestID)
+		g.mu.Unlock()
+		cancel(context.Canceled)
+	}
+}
+
+// match returns the generations of botID, narrowed to routeID and
+// requestID when they are set, oldest first.
+func (g *generationRegistry) match(botID, routeID, requestID string) []generationEntry {
+	botID = strings.TrimSpace(botID)
+	routeID = strings.TrimSpace(routeID)
+	requestID = strings.TrimSpace(requestID)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var matched []generationEntry
+	for _, entry := range g.entries {
+		if entry.BotID != botID {
+			continue
+		}
+		if routeID != "" && entry.RouteID != routeID {
+			continue
+		}
+		if requestID != "" && entry.RequestID != requestID {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].StartedAt.Before(matched[j].StartedAt)
+	})
+	return matched
+}
+
+// ActiveGenerations lists the streaming replies of a bot that are in
+// progress.
+func (r *Resolver) ActiveGenerations(botID string) []Generation {
+	entries := r.generations.match(botID, "", "")
+	out := make([]Generation, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, entry.Generation)
+	}
+	return out
+}
+
+// CancelGenerations stops the streaming replies of a bot, narrowed to one
+// route or one request when routeID or requestID is set. Output streamed so
+// far is stored and sent as the final reply. It returns what was stopped.
+func (r *Resolver) CancelGenerations(botID, routeID, requestID string) []Generation {
+	entries := r.generations.match(botID, routeID, requestID)
+	out := make([]Generation, 0, len(entries))
+	for _, entry := range entries {
+		entry.cancel(ErrGenerationCancelled)
+		out = append(out, entry.Generation)
+	}
+	if len(out) > 0 {
+		r.logger.Info("generation cancelled",
+			slog.String("bot_id", botID),
+			slog.String("route_id", routeID),
+			slog.Int("count", len(out)),
+		)
+	}
+	return out
+}
+
+// partialReply records what a stream produced before it was stopped.
+type partialReply struct {
+	model  gatewayModelConfig
+	text   strings.Builder
+	stored bool
+	// counter and inputTokens estimate the usage of the round, since the
+	// gateway only reports it once the reply is complete.
+	counter     tokenCounter
+	inputTokens int
+}
+
+// reset starts recording an attempt sending payload.
+func (p *partialReply) reset(payload gatewayRequest, registry *tokenizer.Registry) {
+	p.model = payload.Model
+	p.text.Reset()
+	p.stored = false
+	p.counter = newTokenCounter(registry, models.GetResponse{
+		ModelID: payload.Model.ModelID,
+		Model:   models.Model{ClientType: models.ClientType(payload.Model.ClientType)},
+	})
+	p.inputTokens = messageTokenOverhead + p.counter.text(payload.Query) + p.counter.attachments(payload.Attachments)
+	for _, msg := range payload.Messages {
+		p.inputTokens += p.counter.message(msg)
+	}
+	p.inputTokens += p.counter.systemPromptReserve(payload.UsableSkills, payload.Skills, payload.Inbox) + p.counter.text(payload.Instructions)
+}
+
+// usage estimates what the stopped round consumed: the whole prompt and the
+// text streamed so far.
+func (p *partialReply) usage() conversation.Usage {
+	return conversation.Usage{
+		InputTokens:  p.inputTokens,
+		OutputTokens: p.counter.text(p.text.String()),
+	}
+}
+
+func (p *partialReply) observe(data []byte) {
+	var event struct {
+		Type  string `json:"type"`
+		Delta string `json:"delta"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return
+	}
+	if event.Type == "text_delta" {
+		p.text.WriteString(event.Delta)
+	}
+}
+
+// finishCancelled stores the partial reply of a stopped stream and sends a
+// final agent_end event carrying it, so the channel closes the reply instead
+// of reporting an error. Unless the round was stored complete, its
+// estimated usage counts against the quotas.
+func (r *Resolver) finishCancelled(ctx context.Context, req conversation.ChatRequest, partial *partialReply, latency time.Duration, chunkCh chan<- conversation.StreamChunk) {
+	if r.quotas != nil && partial.model.ModelID != "" && !partial.stored {
+		r.quotas.RecordUsage(context.WithoutCancel(ctx), req, partial.model.id, partial.usage())
+	}
+	messages := []conversation.ModelMessage{}
+	if text := strings.TrimSpace(partial.text.String()); text != "" && !partial.stored {
+		messages = append(messages, conversation.ModelMessage{
+			Role:    "assistant",
+			Content: conversation.NewTextContent(text),
+		})
+		reply := replyInfo{model: partial.model, latency: latency, cancelled: true}
+		moderated := r.moderateOutput(context.WithoutCancel(ctx), req, messages)
+		if moderated.Messages != nil {
+			messages = moderated.Messages
+		}
+		reply.moderation = moderated.Metadata
+		r.storeMessages(context.WithoutCancel(ctx), req, reply, messages, nil, nil)
+	}
+	data, err := json.Marshal(map[string]any{
+		"type":      "agent_end",
+		"cancelled": true,
+		"messages":  messages,
+	})
+	if err != nil {
+		return
+	}
+	select {
+	case chunkCh <- conversation.StreamChunk(data):
+	case <-ctx.Done():
+	}
+}
+
+// withCancelledMetadata returns a copy of meta marking a stopped reply.
+func withCancelledMetadata(meta map[string]any) map[string]any {
+	out := make(map[string]any, len(meta)+1)
+	for k, v := range meta {
+		out[k] = v
+	}
+	out["cancelled"] = true
+	return out
+}
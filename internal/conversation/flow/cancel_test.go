@@ -0,0 +1,161 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+type recordingMessageService struct {
+	messagepkg.Service
+	persisted []messagepkg.PersistInput
+}
+
+func (s *recordingMessageService) Persist(ctx context.Context, input messagepkg.PersistInput) (messagepkg.Message, error) {
+	s.persisted = append(s.persisted, input)
+	return messagepkg.Message{}, nil
+}
+
+type recordingQuotas struct {
+	models []string
+	usages []conversation.Usage
+}
+
+func (q *recordingQuotas) CheckQuota(ctx context.Context, req conversation.ChatRequest) error {
+	return nil
+}
+
+func (q *recordingQuotas) RecordUsage(ctx context.Context, req conversation.ChatRequest, modelID string, usage conversation.Usage) {
+	q.models = append(q.models, modelID)
+	q.usages = append(q.usages, usage)
+}
+
+func TestCancelGenerations_NarrowsByRoute(t *testing.T) {
+	r := &Resolver{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	req1 := conversation.ChatRequest{BotID: "bot-1", RouteID: "route-1"}
+	req2 := conversation.ChatRequest{BotID: "bot-1", RouteID: "route-2"}
+	ctx1, done1 := r.generations.start(context.Background(), &req1)
+	defer done1()
+	ctx2, done2 := r.generations.start(context.Background(), &req2)
+	defer done2()
+
+	if req1.RequestID == "" || req1.RequestID == req2.RequestID {
+		t.Fatalf("expected distinct request ids, got %q %q", req1.RequestID, req2.RequestID)
+	}
+	if got := r.ActiveGenerations("bot-1"); len(got) != 2 {
+		t.Fatalf("expected 2 active generations, got %v", got)
+	}
+
+	stopped := r.CancelGenerations("bot-1", "route-1", "")
+	if len(stopped) != 1 || stopped[0].RequestID != req1.RequestID {
+		t.Fatalf("expected route-1 stopped, got %v", stopped)
+	}
+	if !errors.Is(context.Cause(ctx1), ErrGenerationCancelled) {
+		t.Fatalf("expected route-1 context cancelled, got %v", context.Cause(ctx1))
+	}
+	if ctx2.Err() != nil {
+		t.Fatal("expected route-2 to keep running")
+	}
+
+	done1()
+	if got := r.ActiveGenerations("bot-1"); len(got) != 1 || got[0].RequestID != req2.RequestID {
+		t.Fatalf("expected finished generation unregistered, got %v", got)
+	}
+}
+
+func TestStreamChat_CancelStoresPartialReply(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("data: {\"type\":\"text_delta\",\"delta\":\"Hello, \"}\n\n"))
+		_, _ = w.Write([]byte("data: {\"type\":\"text_delta\",\"delta\":\"world\"}\n\n"))
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	msgSvc := &recordingMessageService{}
+	quotas := &recordingQuotas{}
+	r := &Resolver{
+		messageService:  msgSvc,
+		quotas:          quotas,
+		gatewayBaseURL:  srv.URL,
+		logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		streamingClient: srv.Client(),
+		httpClient:      srv.Client(),
+	}
+	req := conversation.ChatRequest{BotID: "bot-1", RouteID: "route-1"}
+	ctx, done := r.generations.start(context.Background(), &req)
+	defer done()
+
+	chunkCh := make(chan conversation.StreamChunk, 10)
+	payload := gatewayRequest{
+		Model: gatewayModelConfig{ModelID: "gpt-4o", ClientType: "openai", id: "model-uuid-1"},
+		Query: "Say hello to the world",
+	}
+	partial := &partialReply{}
+	partial.reset(payload, nil)
+	streamDone := make(chan error, 1)
+	go func() {
+		streamDone <- r.streamChatRecording(ctx, payload, req, chunkCh, partial)
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-chunkCh:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for deltas")
+		}
+	}
+
+	r.CancelGenerations("bot-1", "", req.RequestID)
+	select {
+	case err := <-streamDone:
+		if err == nil {
+			t.Fatal("expected cancelled stream to fail")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for stream to stop")
+	}
+
+	r.finishCancelled(context.Background(), req, partial, time.Second, chunkCh)
+	if len(msgSvc.persisted) != 1 {
+		t.Fatalf("expected partial reply stored, got %d messages", len(msgSvc.persisted))
+	}
+	stored := msgSvc.persisted[0]
+	var content conversation.ModelMessage
+	if err := json.Unmarshal(stored.Content, &content); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Role != "assistant" || content.TextContent() != "Hello, world" || stored.Metadata["cancelled"] != true {
+		t.Fatalf("unexpected stored reply %+v", stored)
+	}
+	if len(quotas.usages) != 1 || quotas.models[0] != "model-uuid-1" {
+		t.Fatalf("expected the cancelled round metered once, got %v", quotas.models)
+	}
+	if usage := quotas.usages[0]; usage.InputTokens <= systemPromptBaseReserve || usage.OutputTokens == 0 {
+		t.Fatalf("expected the prompt and streamed text estimated, got %+v", usage)
+	}
+
+	var final struct {
+		Type      string                      `json:"type"`
+		Cancelled bool                        `json:"cancelled"`
+		Messages  []conversation.ModelMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(<-chunkCh, &final); err != nil {
+		t.Fatal(err)
+	}
+	if final.Type != "agent_end" || !final.Cancelled || len(final.Messages) != 1 {
+		t.Fatalf("unexpected final event %+v", final)
+	}
+}
@@ -0,0 +1,46 @@
+package flow
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/settings"
+)
+
+// withChatOverrides returns the bot settings with the conversation's
+// reasoning and context overrides applied.
+func withChatOverrides(botSettings settings.Settings, cs conversation.Settings) settings.Settings {
+	merged := botSettings
+	if cs.ReasoningEnabled != nil {
+		merged.ReasoningEnabled = *cs.ReasoningEnabled
+	}
+	if effort := strings.TrimSpace(cs.ReasoningEffort); effort != "" {
+		merged.ReasoningEffort = effort
+	}
+	if cs.MaxContextTokens != nil && *cs.MaxContextTokens > 0 {
+		merged.MaxContextTokens = *cs.MaxContextTokens
+	}
+	if cs.MaxContextLoadTime != nil && *cs.MaxContextLoadTime > 0 {
+		merged.MaxContextLoadTime = *cs.MaxContextLoadTime
+	}
+	return merged
+}
+
+// mergeAllowedActions narrows the tool sets a request allows to the ones the
+// conversation allows. Either side being empty means no restriction from it.
+func mergeAllowedActions(requested, allowed []string) []string {
+	if len(allowed) == 0 {
+		return requested
+	}
+	if len(requested) == 0 {
+		return slices.Clone(allowed)
+	}
+	merged := make([]string, 0, len(requested))
+	for _, action := range requested {
+		if slices.Contains(allowed, action) {
+			merged = append(merged, action)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,50 @@
+package flow
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/settings"
+)
+
+func TestWithChatOverrides(t *testing.T) {
+	enabled := false
+	tokens := 8000
+	bot := settings.Settings{ReasoningEnabled: true, ReasoningEffort: "low", MaxContextTokens: 32000, MaxContextLoadTime: 60}
+	merged := withChatOverrides(bot, conversation.Settings{
+		ReasoningEnabled: &enabled,
+		ReasoningEffort:  "high",
+		MaxContextTokens: &tokens,
+	})
+	if merged.ReasoningEnabled || merged.ReasoningEffort != "high" || merged.MaxContextTokens != 8000 || merged.MaxContextLoadTime != 60 {
+		t.Fatalf("unexpected merged settings: %+v", merged)
+	}
+	if got := withChatOverrides(bot, conversation.Settings{}); !reflect.DeepEqual(got, bot) {
+		t.Fatalf("expected bot settings without overrides, got %+v", got)
+	}
+}
+
+func TestMergeAllowedActions(t *testing.T) {
+	if got := mergeAllowedActions(nil, nil); got != nil {
+		t.Fatalf("expected no restriction, got %v", got)
+	}
+	if got := mergeAllowedActions(nil, []string{"web"}); !reflect.DeepEqual(got, []string{"web"}) {
+		t.Fatalf("expected conversation tools, got %v", got)
+	}
+	if got := mergeAllowedActions([]string{"web", "memory"}, []string{"memory", "skill"}); !reflect.DeepEqual(got, []string{"memory"}) {
+		t.Fatalf("expected intersection, got %v", got)
+	}
+	// No overlap must still reach the gateway as an empty list, not as
+	// "unrestricted".
+	got := mergeAllowedActions([]string{"web"}, []string{"skill"})
+	data, err := json.Marshal(gatewayRequest{AllowedActions: got})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"allowedActions":[]`) {
+		t.Fatalf("expected an empty action list, got %s", data)
+	}
+}
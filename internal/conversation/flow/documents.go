@@ -0,0 +1,131 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	attachmentpkg "github.com/memohai/memoh/internal/attachment"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/docextract"
+	"github.com/memohai/memoh/internal/models"
+)
+
+// documentExtractor converts document attachments into bounded plain text.
+type documentExtractor interface {
+	Extract(ctx context.Context, contentHash, mime, name string, r io.Reader) (docextract.Result, error)
+}
+
+// extractedDocument is the text of one attachment injected into the query.
+type extractedDocument struct {
+	Name      string
+	Format    string
+	Text      string
+	Truncated bool
+}
+
+// extractDocumentTexts extracts text from document attachments when the chat
+// model cannot take files natively. The attachments still go through the
+// capability fallback, so the agent keeps its container file reference too.
+func (r *Resolver) extractDocumentTexts(ctx context.Context, model models.GetResponse, req conversation.ChatRequest) []extractedDocument {
+	if r == nil || r.docExtractor == nil || len(req.Attachments) == 0 {
+		return nil
+	}
+	if model.HasInputModality(models.ModelInputFile) {
+		return nil
+	}
+	botID := strings.TrimSpace(req.BotID)
+	var docs []extractedDocument
+	for _, att := range req.Attachments {
+		switch strings.ToLower(strings.TrimSpace(att.Type)) {
+		case "image", "audio", "video", "voice", "gif":
+			continue
+		}
+		name := strings.TrimSpace(att.Name)
+		mime := strings.TrimSpace(att.Mime)
+		if !docextract.Supported(mime, name) && !strings.EqualFold(strings.TrimSpace(att.Type), "file") {
+			continue
+		}
+		reader, err := r.openDocumentAttachment(ctx, botID, att)
+		if err != nil {
+			r.logger.Debug("skip document extraction", slog.String("name", name), slog.Any("error", err))
+			continue
+		}
+		result, err := r.docExtractor.Extract(ctx, strings.TrimSpace(att.ContentHash), mime, name, reader)
+		_ = reader.Close()
+		if err != nil {
+			level := slog.LevelWarn
+			if errors.Is(err, docextract.ErrUnsupported) || errors.Is(err, docextract.ErrNoText) {
+				level = slog.LevelDebug
+			}
+			r.logger.Log(ctx, level, "document extraction failed",
+				slog.String("bot_id", botID),
+				slog.String("name", name),
+				slog.String("content_hash", strings.TrimSpace(att.ContentHash)),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		docs = append(docs, extractedDocument{
+			Name:      name,
+			Format:    result.Format,
+			Text:      result.Text,
+			Truncated: result.Truncated,
+		})
+	}
+	return docs
+}
+
+// openDocumentAttachment resolves attachment bytes from the media store or an
+// inline base64 payload.
+func (r *Resolver) openDocumentAttachment(ctx context.Context, botID string, att conversation.ChatAttachment) (io.ReadCloser, error) {
+	if contentHash := strings.TrimSpace(att.ContentHash); contentHash != "" && r.assetLoader != nil {
+		reader, _, err := r.assetLoader.OpenForGateway(ctx, botID, contentHash)
+		if err != nil {
+			return nil, fmt.Errorf("open asset: %w", err)
+		}
+		return reader, nil
+	}
+	payload := strings.TrimSpace(att.Base64)
+	if payload == "" && isDataURL(att.URL) {
+		payload = strings.TrimSpace(att.URL)
+	}
+	if payload == "" {
+		return nil, fmt.Errorf("attachment has no readable content")
+	}
+	reader, err := attachmentpkg.DecodeBase64(payload, gatewayInlineAttachmentMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(reader), nil
+}
+
+// appendDocumentTexts appends extracted documents to the user query as
+// delimited blocks so the model can tell them apart from the message itself.
+func appendDocumentTexts(query string, docs []extractedDocument) string {
+	if len(docs) == 0 {
+		return query
+	}
+	var sb strings.Builder
+	sb.WriteString(query)
+	for _, doc := range docs {
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		name := doc.Name
+		if name == "" {
+			name = "attachment"
+		}
+		fmt.Fprintf(&sb, "<document name=%q format=%q", name, doc.Format)
+		if doc.Truncated {
+			sb.WriteString(` truncated="true"`)
+		}
+		sb.WriteString(">\n")
+		sb.WriteString(doc.Text)
+		sb.WriteString("\n</document>")
+	}
+	return sb.String()
+}
@@ -0,0 +1,230 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/models"
+	"github.com/memohai/memoh/internal/settings"
+)
+
+// modelFallbackEventType is the stream event emitted when the resolver
+// switches to the next model in the fallback chain.
+const modelFallbackEventType = "model_fallback"
+
+// gatewayStatusError is a failed agent gateway call: a non-2xx response,
+// or an error event before a stream produced any content. The gateway
+// answers a failed provider call with the provider's status code, and puts
+// it in the status field of a stream's error event.
+type gatewayStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *gatewayStatusError) Error() string {
+	return "agent gateway error: " + e.Body
+}
+
+// isRetryableGatewayError reports whether the next model in the fallback
+// chain may succeed where the current one failed: rate limits and server
+// errors. Errors after a stream produced content are forwarded in-band and
+// never become status errors, so partial replies are not retried.
+func isRetryableGatewayError(err error) bool {
+	var statusErr *gatewayStatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+}
+
+// gatewayOpeningEventTypes are the stream events that open a reply without
+// content. They are held back until content follows.
+var gatewayOpeningEventTypes = []string{"agent_start", "text_start", "reasoning_start"}
+
+func gatewayEventType(event []byte) string {
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(event, &envelope); err != nil {
+		return ""
+	}
+	return envelope.Type
+}
+
+// gatewayStreamError converts a stream error event. An event without a
+// status did not come from a provider response, such as a connection
+// failure, and is treated as a bad gateway so the fallback chain goes on.
+func gatewayStreamError(event []byte) *gatewayStatusError {
+	var envelope struct {
+		Message string `json:"message"`
+		Status  int    `json:"status"`
+	}
+	_ = json.Unmarshal(event, &envelope)
+	status := envelope.Status
+	if status < http.StatusBadRequest {
+		status = http.StatusBadGateway
+	}
+	message := strings.TrimSpace(envelope.Message)
+	if message == "" {
+		message = strings.TrimSpace(string(event))
+	}
+	return &gatewayStatusError{StatusCode: status, Body: message}
+}
+
+// chatModelCandidate is one entry of a fallback chain.
+type chatModelCandidate struct {
+	model    models.GetResponse
+	provider sqlc.LlmProvider
+}
+
+// selectFallbackModels resolves the ordered fallback chain for a request.
+// Conversation settings take precedence over bot settings. A request that
+// pins its own model gets no fallbacks. Unknown or non-chat models are
+// skipped so a stale entry does not break the chain.
+func (r *Resolver) selectFallbackModels(ctx context.Context, req conversation.ChatRequest, botSettings settings.Settings, cs conversation.Settings, primary models.GetResponse) []chatModelCandidate {
+	if strings.TrimSpace(req.Model) != "" || strings.TrimSpace(req.Provider) != "" {
+		return nil
+	}
+	refs := cs.FallbackModelIDs
+	if len(refs) == 0 {
+		refs = botSettings.FallbackModelIDs
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+	seen := map[string]struct{}{primary.ID: {}}
+	candidates := make([]chatModelCandidate, 0, len(refs))
+	for _, ref := range refs {
+		model, provider, err := r.fetchChatModel(ctx, ref)
+		if err != nil {
+			r.logger.Warn("skip fallback model", slog.String("bot_id", req.BotID), slog.String("model", ref), slog.Any("error", err))
+			continue
+		}
+		if _, ok := seen[model.ID]; ok {
+			continue
+		}
+		seen[model.ID] = struct{}{}
+		candidates = append(candidates, chatModelCandidate{model: model, provider: provider})
+	}
+	return candidates
+}
+
+// gatewayModelFor builds the gateway model config for a chat model.
+func gatewayModelFor(model models.GetResponse, provider sqlc.LlmProvider, botSettings settings.Settings) gatewayModelConfig {
+	var reasoning *gatewayReasoningConfig
+	if model.SupportsReasoning && botSettings.ReasoningEnabled {
+		reasoning = &gatewayReasoningConfig{
+			Enabled: true,
+			Effort:  botSettings.ReasoningEffort,
+		}
+	}
+	return gatewayModelConfig{
+		ModelID:    model.ModelID,
+		ClientType: string(model.ClientType),
+		Input:      model.InputModalities,
+		APIKey:     provider.ApiKey,
+		BaseURL:    provider.BaseUrl,
+		Reasoning:  reasoning,
+		id:         model.ID,
+	}
+}
+
+// withChatModel returns rc with its payload retargeted at candidate.
+// Attachments are re-routed for the new model's input modalities. The query,
+// including any extracted document text, is kept so the persisted user turn
+// does not change between attempts.
+func (r *Resolver) withChatModel(ctx context.Context, rc resolvedContext, candidate chatModelCandidate) resolvedContext {
+	next := rc
+	next.model = candidate.model
+	next.provider = candidate.provider
+	next.payload.Model = gatewayModelFor(candidate.model, candidate.provider, rc.botSettings)
+	next.payload.Model.Temperature = rc.payload.Model.Temperature
+	next.payload.Model.TopP = rc.payload.Model.TopP
+	next.payload.Model.variant = rc.payload.Model.variant
+	next.payload.Attachments = r.routeAndMergeAttachments(ctx, candidate.model, rc.req)
+	return next
+}
+
+// callWithFallback runs call against the resolved model and then each
+// fallback in order while the failure is retryable. onSwitch, when set, is
+// told about every switch before the next attempt. It returns the context of
+// the model that answered, or of the last one tried.
+func (r *Resolver) callWithFallback(ctx context.Context, rc resolvedContext, onSwitch func(from, to models.GetResponse, cause error), call func(resolvedContext) error) (resolvedContext, error) {
+	current := rc
+	for i := 0; ; i++ {
+		err := call(current)
+		if err == nil {
+			return current, nil
+		}
+		if i >= len(rc.fallbacks) || ctx.Err() != nil || !isRetryableGatewayError(err) {
+			return current, err
+		}
+		next := r.withChatModel(ctx, rc, rc.fallbacks[i])
+		r.logger.Warn("chat model failed, trying fallback",
+			slog.String("bot_id", rc.req.BotID),
+			slog.String("model", current.model.ModelID),
+			slog.String("fallback", next.model.ModelID),
+			slog.Any("error", err),
+		)
+		if onSwitch != nil {
+			onSwitch(current.model, next.model, err)
+		}
+		current = next
+	}
+}
+
+// modelFallbackChunk is the stream chunk announcing a fallback switch.
+func modelFallbackChunk(from, to models.GetResponse, cause error) (conversation.StreamChunk, error) {
+	data, err := json.Marshal(map[string]string{
+		"type":  modelFallbackEventType,
+		"from":  from.ModelID,
+		"to":    to.ModelID,
+		"error": cause.Error(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal fallback event: %w", err)
+	}
+	return conversation.StreamChunk(data), nil
+}
+
+// answeredByMetadata records which model produced a stored message and, when
+// the request was routed through an A/B variant, which variant it belongs to.
+// A fallback keeps the variant so the report counts the reply against the
+// variant that was assigned.
+func answeredByMetadata(model gatewayModelConfig) map[string]any {
+	if strings.TrimSpace(model.ModelID) == "" {
+		return nil
+	}
+	meta := map[string]any{
+		"model_id":    model.ModelID,
+		"client_type": model.ClientType,
+	}
+	// model_uuid identifies the model row, which model_id alone does not
+	// when several providers serve the same model.
+	if model.id != "" {
+		meta["model_uuid"] = model.id
+	}
+	if model.variant != nil {
+		meta["variant_id"] = model.variant.ID
+		meta["variant_of"] = model.variant.BaseModelID
+	}
+	return meta
+}
+
+// withLatencyMetadata returns a copy of meta with the round latency.
+func withLatencyMetadata(meta map[string]any, latency time.Duration) map[string]any {
+	out := make(map[string]any, len(meta)+1)
+	for k, v := range meta {
+		out[k] = v
+	}
+	out["latency_ms"] = latency.Milliseconds()
+	return out
+}
@@ -0,0 +1,309 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/models"
+)
+
+func TestIsRetryableGatewayError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{err: &gatewayStatusError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{err: &gatewayStatusError{StatusCode: http.StatusBadGateway}, want: true},
+		{err: &gatewayStatusError{StatusCode: http.StatusInternalServerError}, want: true},
+		{err: &gatewayStatusError{StatusCode: http.StatusBadRequest}, want: false},
+		{err: &gatewayStatusError{StatusCode: http.StatusUnauthorized}, want: false},
+		{err: errors.New("sse line too long"), want: false},
+		{err: context.Canceled, want: false},
+	}
+	for _, tc := range cases {
+		if got := isRetryableGatewayError(tc.err); got != tc.want {
+			t.Fatalf("isRetryableGatewayError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+// fallbackGateway fails every model in failing the way the agent gateway
+// reports a provider answering status: with that status and an error body
+// on /chat/, and with an in-band error event after a 200 on /chat/stream.
+// It answers the other models.
+func fallbackGateway(t *testing.T, status int, failing ...string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var tried []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload gatewayRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		tried = append(tried, payload.Model.ModelID)
+		stream := r.URL.Path == "/chat/stream"
+		if stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(w, `data:{"type":"agent_start","input":{}}`+"\n\n")
+		}
+		for _, model := range failing {
+			if payload.Model.ModelID != model {
+				continue
+			}
+			if stream {
+				_, _ = fmt.Fprintf(w, `data:{"type":"error","message":"provider unavailable","status":%d}`+"\n\n", status)
+				return
+			}
+			w.WriteHeader(status)
+			_, _ = io.WriteString(w, `{"success":false,"error":"provider unavailable","code":"PROVIDER_ERROR"}`)
+			return
+		}
+		if stream {
+			_, _ = io.WriteString(w, `data:{"type":"text_delta","delta":"hi"}`+"\n\n")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(gatewayResponse{Messages: []conversation.ModelMessage{
+			{Role: "assistant", Content: conversation.NewTextContent("answered by " + payload.Model.ModelID)},
+		}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &tried
+}
+
+func fallbackContext(primary string, fallbacks ...string) resolvedContext {
+	rc := resolvedContext{
+		model:   models.GetResponse{ID: primary + "-id", ModelID: primary},
+		payload: gatewayRequest{Model: gatewayModelConfig{ModelID: primary}},
+	}
+	for _, id := range fallbacks {
+		rc.fallbacks = append(rc.fallbacks, chatModelCandidate{
+			model:    models.GetResponse{ID: id + "-id", ModelID: id},
+			provider: sqlc.LlmProvider{BaseUrl: "https://" + id + ".example"},
+		})
+	}
+	return rc
+}
+
+func TestCallWithFallback_SwitchesOnRetryableError(t *testing.T) {
+	srv, tried := fallbackGateway(t, http.StatusTooManyRequests, "primary", "second")
+	resolver := &Resolver{
+		gatewayBaseURL: srv.URL,
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+		logger:         slog.Default(),
+	}
+
+	var switches []string
+	var resp gatewayResponse
+	rc, err := resolver.callWithFallback(context.Background(), fallbackContext("primary", "second", "third"),
+		func(from, to models.GetResponse, cause error) {
+			switches = append(switches, from.ModelID+"->"+to.ModelID)
+		},
+		func(attempt resolvedContext) error {
+			var postErr error
+			resp, postErr = resolver.postChat(context.Background(), attempt.payload, "")
+			return postErr
+		},
+	)
+	if err != nil {
+		t.Fatalf("expected fallback to succeed: %v", err)
+	}
+	if rc.model.ModelID != "third" || rc.payload.Model.ModelID != "third" || rc.payload.Model.BaseURL != "https://third.example" {
+		t.Fatalf("expected answering model third, got %#v", rc.payload.Model)
+	}
+	if strings.Join(*tried, ",") != "primary,second,third" {
+		t.Fatalf("unexpected attempts: %v", *tried)
+	}
+	if strings.Join(switches, ",") != "primary->second,second->third" {
+		t.Fatalf("unexpected switches: %v", switches)
+	}
+	if len(resp.Messages) != 1 || resp.Messages[0].TextContent() != "answered by third" {
+		t.Fatalf("unexpected response: %#v", resp.Messages)
+	}
+}
+
+func TestCallWithFallback_DoesNotRetryClientErrors(t *testing.T) {
+	srv, tried := fallbackGateway(t, http.StatusBadRequest, "primary")
+	resolver := &Resolver{
+		gatewayBaseURL: srv.URL,
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+		logger:         slog.Default(),
+	}
+
+	_, err := resolver.callWithFallback(context.Background(), fallbackContext("primary", "second"), nil, func(attempt resolvedContext) error {
+		_, postErr := resolver.postChat(context.Background(), attempt.payload, "")
+		return postErr
+	})
+	var statusErr *gatewayStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 gateway error, got %v", err)
+	}
+	if len(*tried) != 1 {
+		t.Fatalf("expected a single attempt, got %v", *tried)
+	}
+}
+
+func TestCallWithFallback_ReturnsLastErrorWhenChainExhausted(t *testing.T) {
+	srv, tried := fallbackGateway(t, http.StatusServiceUnavailable, "primary", "second")
+	resolver := &Resolver{
+		gatewayBaseURL: srv.URL,
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+		logger:         slog.Default(),
+	}
+
+	rc, err := resolver.callWithFallback(context.Background(), fallbackContext("primary", "second"), nil, func(attempt resolvedContext) error {
+		_, postErr := resolver.postChat(context.Background(), attempt.payload, "")
+		return postErr
+	})
+	if !isRetryableGatewayError(err) {
+		t.Fatalf("expected the last gateway error, got %v", err)
+	}
+	if rc.model.ModelID != "second" || len(*tried) != 2 {
+		t.Fatalf("expected both models tried, got %v (last %s)", *tried, rc.model.ModelID)
+	}
+}
+
+func TestCallWithFallback_StreamAnnouncesSwitch(t *testing.T) {
+	srv, _ := fallbackGateway(t, http.StatusBadGateway, "primary")
+	resolver := &Resolver{
+		gatewayBaseURL:  srv.URL,
+		streamingClient: srv.Client(),
+		logger:          slog.Default(),
+	}
+
+	chunkCh := make(chan conversation.StreamChunk, 4)
+	announce := func(from, to models.GetResponse, cause error) {
+		chunk, err := modelFallbackChunk(from, to, cause)
+		if err != nil {
+			t.Fatalf("fallback chunk: %v", err)
+		}
+		chunkCh <- chunk
+	}
+	_, err := resolver.callWithFallback(context.Background(), fallbackContext("primary", "second"), announce, func(attempt resolvedContext) error {
+		return resolver.streamChat(context.Background(), attempt.payload, conversation.ChatRequest{}, chunkCh)
+	})
+	if err != nil {
+		t.Fatalf("stream with fallback: %v", err)
+	}
+	close(chunkCh)
+
+	var events []map[string]any
+	for chunk := range chunkCh {
+		var event map[string]any
+		if err := json.Unmarshal(chunk, &event); err != nil {
+			t.Fatalf("unmarshal chunk: %v", err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected fallback event, one start and one delta, got %v", events)
+	}
+	if events[0]["type"] != modelFallbackEventType || events[0]["from"] != "primary" || events[0]["to"] != "second" || !strings.Contains(events[0]["error"].(string), "provider unavailable") {
+		t.Fatalf("unexpected fallback event: %v", events[0])
+	}
+	if events[1]["type"] != "agent_start" || events[2]["type"] != "text_delta" {
+		t.Fatalf("expected the answering model's start and delta after fallback, got %v", events[1:])
+	}
+}
+
+func TestCallWithFallback_StreamDoesNotRetryClientErrors(t *testing.T) {
+	srv, tried := fallbackGateway(t, http.StatusBadRequest, "primary")
+	resolver := &Resolver{
+		gatewayBaseURL:  srv.URL,
+		streamingClient: srv.Client(),
+		logger:          slog.Default(),
+	}
+
+	chunkCh := make(chan conversation.StreamChunk, 4)
+	_, err := resolver.callWithFallback(context.Background(), fallbackContext("primary", "second"), nil, func(attempt resolvedContext) error {
+		return resolver.streamChat(context.Background(), attempt.payload, conversation.ChatRequest{}, chunkCh)
+	})
+	var statusErr *gatewayStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest || statusErr.Body != "provider unavailable" {
+		t.Fatalf("expected the in-band 400 as a gateway error, got %v", err)
+	}
+	if len(*tried) != 1 || len(chunkCh) != 0 {
+		t.Fatalf("expected a single attempt and nothing forwarded, got %v and %d chunks", *tried, len(chunkCh))
+	}
+}
+
+func TestStreamChat_ErrorAfterContentIsForwarded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, `data:{"type":"agent_start","input":{}}`+"\n\n")
+		_, _ = io.WriteString(w, `data:{"type":"text_delta","delta":"hel"}`+"\n\n")
+		_, _ = io.WriteString(w, `data:{"type":"error","message":"overloaded","status":529}`+"\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	resolver := &Resolver{gatewayBaseURL: srv.URL, streamingClient: srv.Client(), logger: slog.Default()}
+
+	chunkCh := make(chan conversation.StreamChunk, 4)
+	if err := resolver.streamChat(context.Background(), gatewayRequest{}, conversation.ChatRequest{}, chunkCh); err != nil {
+		t.Fatalf("expected a partial reply to end in-band, got %v", err)
+	}
+	close(chunkCh)
+	var types []string
+	for chunk := range chunkCh {
+		types = append(types, gatewayEventType(chunk))
+	}
+	if strings.Join(types, ",") != "agent_start,text_delta,error" {
+		t.Fatalf("unexpected events %v", types)
+	}
+}
+
+func TestGatewayStreamError(t *testing.T) {
+	err := gatewayStreamError([]byte(`{"type":"error","message":"context too long","status":400}`))
+	if err.StatusCode != http.StatusBadRequest || err.Body != "context too long" || isRetryableGatewayError(err) {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	// Failures without a provider answer, such as a refused connection.
+	err = gatewayStreamError([]byte(`{"type":"error","message":"fetch failed"}`))
+	if err.StatusCode != http.StatusBadGateway || !isRetryableGatewayError(err) {
+		t.Fatalf("expected a missing status to be retryable, got %#v", err)
+	}
+}
+
+func TestAnsweredByMetadata(t *testing.T) {
+	meta := answeredByMetadata(gatewayModelConfig{ModelID: "claude-sonnet", ClientType: "anthropic-messages", id: "model-uuid-1"})
+	if meta["model_id"] != "claude-sonnet" || meta["client_type"] != "anthropic-messages" || meta["model_uuid"] != "model-uuid-1" {
+		t.Fatalf("unexpected metadata: %v", meta)
+	}
+	if _, ok := meta["variant_id"]; ok {
+		t.Fatalf("unexpected variant metadata: %v", meta)
+	}
+	if answeredByMetadata(gatewayModelConfig{}) != nil {
+		t.Fatal("expected no metadata without a model")
+	}
+
+	variant := &modelVariant{ID: "variant-1", BaseModelID: "base-model"}
+	meta = answeredByMetadata(gatewayModelConfig{ModelID: "gpt-5", ClientType: "openai-responses", variant: variant})
+	if meta["variant_id"] != "variant-1" || meta["variant_of"] != "base-model" {
+		t.Fatalf("expected variant metadata, got %v", meta)
+	}
+	withLatency := withLatencyMetadata(meta, 1500*time.Millisecond)
+	if withLatency["latency_ms"] != int64(1500) || withLatency["variant_id"] != "variant-1" {
+		t.Fatalf("unexpected latency metadata: %v", withLatency)
+	}
+	if _, ok := meta["latency_ms"]; ok {
+		t.Fatal("latency must not leak into the shared metadata map")
+	}
+}
+
+func TestWithChatModelKeepsVariant(t *testing.T) {
+	resolver := &Resolver{logger: slog.Default()}
+	rc := fallbackContext("primary", "second")
+	rc.payload.Model.variant = &modelVariant{ID: "variant-1", BaseModelID: "primary-id"}
+	next := resolver.withChatModel(context.Background(), rc, rc.fallbacks[0])
+	if next.payload.Model.ModelID != "second" || next.payload.Model.variant != rc.payload.Model.variant {
+		t.Fatalf("expected fallback to keep the assigned variant, got %#v", next.payload.Model)
+	}
+}
@@ -0,0 +1,44 @@
+package flow
+
+import (
+	"context"
+	"strings"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/memory"
+)
+
+// memoryStore is the part of memory.Service the resolver uses.
+type memoryStore interface {
+	Search(ctx context.Context, req memory.SearchRequest) (memory.SearchResponse, error)
+	Add(ctx context.Context, req memory.AddRequest) (memory.SearchResponse, error)
+}
+
+// memoryReadScopes returns the scopes searched for a request, most specific
+// first. The search_memory tool resolves the same scopes for its session.
+func (r *Resolver) memoryReadScopes(ctx context.Context, req conversation.ChatRequest) []memory.Scope {
+	return memory.ReadScopes(req.BotID, req.ConversationType, req.RouteID, r.memoryUserScopeID(ctx, req))
+}
+
+// memoryWriteScope returns the scope a conversation round is remembered in:
+// the group route for group chats, the sender for direct chats, and the bot
+// when neither is known. Bot-wide memory is otherwise added through the
+// memory API. Memories stored before scopes existed stay in the bot scope,
+// which every conversation still reads, so they need no migration.
+func (r *Resolver) memoryWriteScope(ctx context.Context, req conversation.ChatRequest) memory.Scope {
+	return r.memoryReadScopes(ctx, req)[0]
+}
+
+func (r *Resolver) memoryUserScopeID(ctx context.Context, req conversation.ChatRequest) string {
+	if userID := strings.TrimSpace(req.UserID); userID != "" {
+		return userID
+	}
+	channelIdentityID := strings.TrimSpace(req.SourceChannelIdentityID)
+	if channelIdentityID == "" {
+		return ""
+	}
+	if linked := r.linkedUserIDFromChannelIdentity(ctx, channelIdentityID); linked != "" {
+		return linked
+	}
+	return channelIdentityID
+}
@@ -0,0 +1,219 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/memohai/memoh/internal/channel/route"
+	"github.com/memohai/memoh/internal/conversation"
+	mcpgw "github.com/memohai/memoh/internal/mcp"
+	mcpmemory "github.com/memohai/memoh/internal/mcp/providers/memory"
+	"github.com/memohai/memoh/internal/memory"
+)
+
+type fakeMemoryStore struct {
+	mu       sync.Mutex
+	byScope  map[string][]memory.MemoryItem
+	searched []string
+	added    []map[string]any
+}
+
+func (f *fakeMemoryStore) Search(ctx context.Context, req memory.SearchRequest) (memory.SearchResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := req.Filters["namespace"].(string) + ":" + req.Filters["scopeId"].(string)
+	f.searched = append(f.searched, key)
+	return memory.SearchResponse{Results: f.byScope[key]}, nil
+}
+
+func (f *fakeMemoryStore) Add(ctx context.Context, req memory.AddRequest) (memory.SearchResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.added = append(f.added, req.Filters)
+	key := req.Filters["namespace"].(string) + ":" + req.Filters["scopeId"].(string)
+	if f.byScope == nil {
+		f.byScope = map[string][]memory.MemoryItem{}
+	}
+	for _, msg := range req.Messages {
+		f.byScope[key] = append(f.byScope[key], memory.MemoryItem{ID: key + "/" + msg.Content, Memory: msg.Content, Score: 1})
+	}
+	return memory.SearchResponse{}, nil
+}
+
+// botChatAccessor serves tool sessions without a conversation, which skip
+// the participant check.
+type botChatAccessor struct{}
+
+func (botChatAccessor) Get(ctx context.Context, conversationID string) (conversation.Conversation, error) {
+	return conversation.Conversation{}, errors.New("conversation not found")
+}
+
+func (botChatAccessor) IsParticipant(ctx context.Context, conversationID, channelIdentityID string) (bool, error) {
+	return false, nil
+}
+
+func (botChatAccessor) GetReadAccess(ctx context.Context, conversationID, channelIdentityID string) (conversation.ConversationReadAccess, error) {
+	return conversation.ConversationReadAccess{}, nil
+}
+
+type fakeRouteReader map[string]route.Route
+
+func (f fakeRouteReader) GetByID(ctx context.Context, routeID string) (route.Route, error) {
+	rt, ok := f[routeID]
+	if !ok {
+		return route.Route{}, errors.New("route not found")
+	}
+	return rt, nil
+}
+
+func TestLoadMemoryContextMessage_DirectChatPrefersUserScope(t *testing.T) {
+	store := &fakeMemoryStore{byScope: map[string][]memory.MemoryItem{
+		"user:user-1": {{ID: "u1", Memory: "prefers tea", Score: 0.4}},
+		"bot:bot-1":   {{ID: "b1", Memory: "team uses Go", Score: 0.9}},
+	}}
+	resolver := &Resolver{memoryService: store, logger: slog.Default()}
+
+	msg := resolver.loadMemoryContextMessage(context.Background(), conversation.ChatRequest{
+		Query:            "what do I like?",
+		BotID:            "bot-1",
+		ChatID:           "bot-1",
+		UserID:           "user-1",
+		ConversationType: "private",
+	})
+	if msg == nil {
+		t.Fatal("expected memory context")
+	}
+	text := msg.TextContent()
+	if !strings.Contains(text, "[user] prefers tea") || !strings.Contains(text, "[bot] team uses Go") {
+		t.Fatalf("expected both scopes in context, got %q", text)
+	}
+	if strings.Index(text, "prefers tea") > strings.Index(text, "team uses Go") {
+		t.Fatalf("expected user scope to rank before bot scope, got %q", text)
+	}
+}
+
+func TestLoadMemoryContextMessage_GroupChatNeverReadsUserScope(t *testing.T) {
+	store := &fakeMemoryStore{byScope: map[string][]memory.MemoryItem{
+		"user:user-1":   {{ID: "u1", Memory: "private address", Score: 0.99}},
+		"group:route-1": {{ID: "g1", Memory: "standup at 10", Score: 0.5}},
+	}}
+	resolver := &Resolver{memoryService: store, logger: slog.Default()}
+
+	msg := resolver.loadMemoryContextMessage(context.Background(), conversation.ChatRequest{
+		Query:            "when is standup?",
+		BotID:            "bot-1",
+		ChatID:           "bot-1",
+		UserID:           "user-1",
+		RouteID:          "route-1",
+		ConversationType: "group",
+	})
+	if msg == nil || !strings.Contains(msg.TextContent(), "[group] standup at 10") {
+		t.Fatalf("expected group memory, got %v", msg)
+	}
+	if strings.Contains(msg.TextContent(), "private address") {
+		t.Fatalf("user memory leaked into group chat: %q", msg.TextContent())
+	}
+	for _, key := range store.searched {
+		if strings.HasPrefix(key, memory.NamespaceUser+":") {
+			t.Fatalf("group chat searched user scope %q", key)
+		}
+	}
+}
+
+func TestStoreMemory_WritesToConversationScope(t *testing.T) {
+	cases := []struct {
+		name string
+		req  conversation.ChatRequest
+		want string
+	}{
+		{"direct", conversation.ChatRequest{BotID: "bot-1", UserID: "user-1", ConversationType: "p2p"}, "user:user-1"},
+		{"unlinked sender", conversation.ChatRequest{BotID: "bot-1", SourceChannelIdentityID: "ci-1"}, "user:ci-1"},
+		{"group", conversation.ChatRequest{BotID: "bot-1", UserID: "user-1", RouteID: "route-1", ConversationType: "supergroup"}, "group:route-1"},
+		{"group without route", conversation.ChatRequest{BotID: "bot-1", UserID: "user-1", ConversationType: "group"}, "bot:bot-1"},
+		{"anonymous", conversation.ChatRequest{BotID: "bot-1"}, "bot:bot-1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeMemoryStore{}
+			resolver := &Resolver{memoryService: store, logger: slog.Default()}
+			resolver.storeMemory(context.Background(), tc.req, []conversation.ModelMessage{
+				{Role: "user", Content: conversation.NewTextContent("remember this")},
+			})
+			if len(store.added) != 1 {
+				t.Fatalf("expected one add, got %d", len(store.added))
+			}
+			filters := store.added[0]
+			if got := filters["namespace"].(string) + ":" + filters["scopeId"].(string); got != tc.want {
+				t.Fatalf("expected scope %s, got %s", tc.want, got)
+			}
+			if filters["bot_id"] != "bot-1" {
+				t.Fatalf("expected bot_id filter, got %v", filters["bot_id"])
+			}
+		})
+	}
+}
+
+func TestStoreMemory_SearchMemoryToolFindsWrite(t *testing.T) {
+	routes := fakeRouteReader{
+		"route-1": {ID: "route-1", BotID: "bot-1", ConversationType: "group"},
+		"route-2": {ID: "route-2", BotID: "bot-1", ConversationType: "private"},
+		"route-x": {ID: "route-x", BotID: "bot-2", ConversationType: "group"},
+	}
+	cases := []struct {
+		name    string
+		req     conversation.ChatRequest
+		session mcpgw.ToolSessionContext
+		found   bool
+	}{
+		{
+			"group",
+			conversation.ChatRequest{BotID: "bot-1", UserID: "user-1", RouteID: "route-1", ConversationType: "group"},
+			mcpgw.ToolSessionContext{BotID: "bot-1", ChannelIdentityID: "user-1", RouteID: "route-1"},
+			true,
+		},
+		{
+			"direct",
+			conversation.ChatRequest{BotID: "bot-1", SourceChannelIdentityID: "ci-1", RouteID: "route-2", ConversationType: "private"},
+			mcpgw.ToolSessionContext{BotID: "bot-1", ChannelIdentityID: "ci-1", RouteID: "route-2"},
+			true,
+		},
+		{
+			"direct memory stays out of group chats",
+			conversation.ChatRequest{BotID: "bot-1", SourceChannelIdentityID: "ci-1", RouteID: "route-2", ConversationType: "private"},
+			mcpgw.ToolSessionContext{BotID: "bot-1", ChannelIdentityID: "ci-1", RouteID: "route-1"},
+			false,
+		},
+		{
+			"route of another bot",
+			conversation.ChatRequest{BotID: "bot-1", UserID: "user-1", RouteID: "route-1", ConversationType: "group"},
+			mcpgw.ToolSessionContext{BotID: "bot-1", ChannelIdentityID: "user-1", RouteID: "route-x"},
+			false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeMemoryStore{}
+			resolver := &Resolver{memoryService: store, logger: slog.Default()}
+			resolver.storeMemory(context.Background(), tc.req, []conversation.ModelMessage{
+				{Role: "user", Content: conversation.NewTextContent("standup at 10")},
+			})
+
+			exec := mcpmemory.NewExecutor(nil, store, botChatAccessor{}, nil, routes, nil)
+			result, err := exec.CallTool(context.Background(), tc.session, "search_memory", map[string]any{"query": "standup"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := mcpgw.PayloadError(result); err != nil {
+				t.Fatal(err)
+			}
+			content, _ := result["structuredContent"].(map[string]any)
+			if got := content["total"] == 1; got != tc.found {
+				t.Fatalf("expected found=%v, got %v (searched %v)", tc.found, content["total"], store.searched)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+// ErrBlocked matches every *BlockedError.
+var ErrBlocked = errors.New("blocked by content moderation")
+
+// BlockedError refuses a query the bot's moderation policy blocks. Its
+// message is meant for the sender.
+type BlockedError struct {
+	Message string
+}
+
+func (e *BlockedError) Error() string {
+	return e.Message
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// ModeratedInput is the outcome of screening a query.
+type ModeratedInput struct {
+	// Text is the query to carry on with, redacted where a rule asked for it.
+	Text string
+	// Blocked reports that the query must not reach the model. Message is
+	// the reply to the sender instead.
+	Blocked bool
+	Message string
+	// Metadata describes the verdict for the stored message. It is nil when
+	// no rule matched.
+	Metadata map[string]any
+}
+
+// ModeratedOutput is the outcome of screening the messages of a round.
+type ModeratedOutput struct {
+	// Messages is the round with blocked or redacted assistant text
+	// replaced. It is nil when nothing was replaced.
+	Messages []conversation.ModelMessage
+	// Metadata holds the verdict of each flagged message by index.
+	Metadata map[int]map[string]any
+}
+
+// Moderator screens chat input before it reaches the model and assistant
+// output before it is stored and delivered.
+type Moderator interface {
+	// ModerateInput returns an error only when the policy of the bot cannot
+	// be applied.
+	ModerateInput(ctx context.Context, req conversation.ChatRequest, text string) (ModeratedInput, error)
+	ModerateOutput(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) ModeratedOutput
+	// ScreensOutput reports whether the policy of the bot screens assistant
+	// output, so only those streams are held back.
+	ScreensOutput(ctx context.Context, botID string) bool
+	// ModerateStreamText screens one streamed text segment and returns the
+	// text to deliver in its place.
+	ModerateStreamText(ctx context.Context, req conversation.ChatRequest, text string) string
+}
+
+// SetModerator enables content moderation. Blocked queries are refused
+// with a *BlockedError before the gateway is called. When the bot screens
+// output, streamed text is held back segment by segment and delivered once
+// screened, and the final messages of a stream carry the moderated text.
+func (r *Resolver) SetModerator(moderator Moderator) {
+	r.moderator = moderator
+}
+
+// streamModeration holds back the text deltas of a stream until the
+// segment they belong to ends, at a text_end or any other event, and
+// releases the screened segment as one delta.
+type streamModeration struct {
+	moderator Moderator
+	req       conversation.ChatRequest
+	held      strings.Builder
+}
+
+// newStreamModeration returns nil when the bot does not screen output, so
+// its deltas pass through as they come.
+func (r *Resolver) newStreamModeration(ctx context.Context, req conversation.ChatRequest) *streamModeration {
+	if r.moderator == nil || !r.moderator.ScreensOutput(ctx, req.BotID) {
+		return nil
+	}
+	return &streamModeration{moderator: r.moderator, req: req}
+}
+
+// events returns the events to forward in place of event.
+func (m *streamModeration) events(ctx context.Context, event []byte) [][]byte {
+	if m == nil {
+		return [][]byte{event}
+	}
+	var envelope struct {
+		Type  string `json:"type"`
+		Delta string `json:"delta"`
+	}
+	if err := json.Unmarshal(event, &envelope); err == nil && envelope.Type == "text_delta" {
+		m.held.WriteString(envelope.Delta)
+		return nil
+	}
+	return append(m.flush(ctx), event)
+}
+
+// flush returns the held segment, screened, as a delta event.
+func (m *streamModeration) flush(ctx context.Context) [][]byte {
+	if m == nil || m.held.Len() == 0 {
+		return nil
+	}
+	text := m.moderator.ModerateStreamText(ctx, m.req, m.held.String())
+	m.held.Reset()
+	if text == "" {
+		return nil
+	}
+	data, err := json.Marshal(map[string]string{"type": "text_delta", "delta": text})
+	if err != nil {
+		return nil
+	}
+	return [][]byte{data}
+}
+
+// moderateInput screens req.Query unless the caller already did.
+func (r *Resolver) moderateInput(ctx context.Context, req *conversation.ChatRequest) error {
+	if r.moderator == nil || req.InputModerated || strings.TrimSpace(req.Query) == "" {
+		return nil
+	}
+	result, err := r.moderator.ModerateInput(ctx, *req, req.Query)
+	if err != nil {
+		return err
+	}
+	if result.Blocked {
+		return &BlockedError{Message: result.Message}
+	}
+	req.Query = result.Text
+	req.InputModerated = true
+	req.InputModeration = result.Metadata
+	return nil
+}
+
+func (r *Resolver) moderateOutput(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) ModeratedOutput {
+	if r.moderator == nil || len(messages) == 0 {
+		return ModeratedOutput{}
+	}
+	return r.moderator.ModerateOutput(ctx, req, messages)
+}
+
+func withModerationMetadata(meta map[string]any, verdict map[string]any) map[string]any {
+	out := make(map[string]any, len(meta)+1)
+	for k, v := range meta {
+		out[k] = v
+	}
+	out["moderation"] = verdict
+	return out
+}
+
+// withStreamMessages rewrites the messages of a final stream event, at the
+// top level or under data, so the client receives what was stored.
+func withStreamMessages(event []byte, messages []conversation.ModelMessage, underData bool) []byte {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(event, &envelope); err != nil {
+		return event
+	}
+	encoded, err := json.Marshal(messages)
+	if err != nil {
+		return event
+	}
+	if underData {
+		var data map[string]json.RawMessage
+		if err := json.Unmarshal(envelope["data"], &data); err != nil {
+			return event
+		}
+		data["messages"] = encoded
+		if envelope["data"], err = json.Marshal(data); err != nil {
+			return event
+		}
+	} else {
+		envelope["messages"] = encoded
+	}
+	out, err := json.Marshal(envelope)
+	if err != nil {
+		return event
+	}
+	return out
+}
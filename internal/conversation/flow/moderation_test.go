@@ -0,0 +1,147 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+type fakeModerator struct {
+	input    ModeratedInput
+	output   ModeratedOutput
+	screens  bool
+	segments []string
+}
+
+func (f *fakeModerator) ModerateInput(ctx context.Context, req conversation.ChatRequest, text string) (ModeratedInput, error) {
+	return f.input, nil
+}
+
+func (f *fakeModerator) ModerateOutput(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) ModeratedOutput {
+	return f.output
+}
+
+func (f *fakeModerator) ScreensOutput(ctx context.Context, botID string) bool {
+	return f.screens
+}
+
+// ModerateStreamText withholds every segment mentioning a secret.
+func (f *fakeModerator) ModerateStreamText(ctx context.Context, req conversation.ChatRequest, text string) string {
+	f.segments = append(f.segments, text)
+	if strings.Contains(text, "secret") {
+		return "[withheld]"
+	}
+	return text
+}
+
+func TestChatRefusesBlockedQuery(t *testing.T) {
+	r := &Resolver{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	r.SetModerator(&fakeModerator{input: ModeratedInput{Blocked: true, Message: "Not here."}})
+
+	_, err := r.Chat(context.Background(), conversation.ChatRequest{BotID: "bot-1", ChatID: "bot-1", Query: "bad words"})
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || !errors.Is(err, ErrBlocked) || blocked.Message != "Not here." {
+		t.Fatalf("expected a BlockedError, got %v", err)
+	}
+}
+
+func TestStreamFinalEventCarriesModeratedReply(t *testing.T) {
+	messages := []conversation.ModelMessage{
+		{Role: "user", Content: conversation.NewTextContent("hi")},
+		{Role: "assistant", Content: conversation.NewTextContent("my number is 555-0100")},
+	}
+	redacted := []conversation.ModelMessage{
+		messages[0],
+		{Role: "assistant", Content: conversation.NewTextContent("my number is [redacted]")},
+	}
+	verdict := map[string]any{"stage": "output", "action": "redact", "rules": []string{"phone"}}
+	store := &recordingMessageService{}
+	r := &Resolver{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), messageService: store}
+	r.SetModerator(&fakeModerator{output: ModeratedOutput{Messages: redacted, Metadata: map[int]map[string]any{1: verdict}}})
+
+	event, err := json.Marshal(map[string]any{"type": "agent_end", "messages": messages, "extra": "kept"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := conversation.ChatRequest{BotID: "bot-1", ChatID: "bot-1", Query: "hi", UserMessagePersisted: true}
+	handled, forwarded, err := r.tryStoreStream(context.Background(), req, replyInfo{}, event)
+	if err != nil || !handled {
+		t.Fatalf("expected the final event stored, got %v %v", handled, err)
+	}
+
+	var out struct {
+		Type     string                      `json:"type"`
+		Extra    string                      `json:"extra"`
+		Messages []conversation.ModelMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(forwarded, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Type != "agent_end" || out.Extra != "kept" || len(out.Messages) != 2 || out.Messages[1].TextContent() != "my number is [redacted]" {
+		t.Fatalf("unexpected forwarded event %s", forwarded)
+	}
+	// The persisted user message is skipped, so the reply is stored alone
+	// with its verdict.
+	if len(store.persisted) != 1 || store.persisted[0].Metadata["moderation"] == nil {
+		t.Fatalf("expected the reply stored with its verdict, got %+v", store.persisted)
+	}
+	var stored conversation.ModelMessage
+	if err := json.Unmarshal(store.persisted[0].Content, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.TextContent() != "my number is [redacted]" {
+		t.Fatalf("expected the redacted reply stored, got %q", stored.TextContent())
+	}
+}
+
+func TestStreamHoldsTextUntilScreened(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"text_start"}`,
+			`{"type":"text_delta","delta":"the "}`,
+			`{"type":"text_delta","delta":"secret is 42"}`,
+			`{"type":"text_end"}`,
+			`{"type":"tool_call_start","toolName":"search"}`,
+			`{"type":"text_delta","delta":"all good"}`,
+		} {
+			_, _ = io.WriteString(w, "data:"+event+"\n\n")
+		}
+	}))
+	t.Cleanup(srv.Close)
+	moderator := &fakeModerator{screens: true}
+	r := &Resolver{gatewayBaseURL: srv.URL, streamingClient: srv.Client(), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	r.SetModerator(moderator)
+
+	chunkCh := make(chan conversation.StreamChunk, 16)
+	if err := r.streamChat(context.Background(), gatewayRequest{}, conversation.ChatRequest{BotID: "bot-1"}, chunkCh); err != nil {
+		t.Fatal(err)
+	}
+	close(chunkCh)
+	var got []string
+	for chunk := range chunkCh {
+		var event struct {
+			Type  string `json:"type"`
+			Delta string `json:"delta"`
+		}
+		if err := json.Unmarshal(chunk, &event); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, event.Type+":"+event.Delta)
+	}
+	want := "text_start:,text_delta:[withheld],text_end:,tool_call_start:,text_delta:all good"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("unexpected events\n got: %s\nwant: %s", strings.Join(got, ","), want)
+	}
+	if len(moderator.segments) != 2 || moderator.segments[0] != "the secret is 42" {
+		t.Fatalf("expected each segment screened whole, got %q", moderator.segments)
+	}
+}
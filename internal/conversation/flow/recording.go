@@ -0,0 +1,180 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+// Gateway round kinds, by the resolver entry point that sent the request.
+const (
+	GatewayKindChat     = "chat"
+	GatewayKindStream   = "stream"
+	GatewayKindSchedule = "schedule"
+)
+
+// GatewayRound is one agent gateway request and the response it got. The
+// provider API key and the session token are removed from Request.
+type GatewayRound struct {
+	BotID   string
+	RouteID string
+	Kind    string
+	// ModelID is the models table ID of the model that answered.
+	ModelID  string
+	Request  json.RawMessage
+	Response json.RawMessage
+	Latency  time.Duration
+}
+
+// GatewayRecorder keeps the gateway rounds of bots that have recording
+// enabled.
+type GatewayRecorder interface {
+	RecordsGateway(ctx context.Context, botID string) bool
+	RecordGatewayRound(ctx context.Context, round GatewayRound)
+}
+
+// recordRound hands a stored round to the gateway recorder.
+func (r *Resolver) recordRound(ctx context.Context, req conversation.ChatRequest, reply replyInfo, resp gatewayResponse) {
+	if r.recorder == nil || reply.request == nil || !r.recorder.RecordsGateway(ctx, req.BotID) {
+		return
+	}
+	request, err := redactGatewayRequest(reply.request)
+	if err != nil {
+		r.logger.Warn("gateway recording skipped", slog.String("bot_id", req.BotID), slog.Any("error", err))
+		return
+	}
+	response, err := json.Marshal(resp)
+	if err != nil {
+		r.logger.Warn("gateway recording skipped", slog.String("bot_id", req.BotID), slog.Any("error", err))
+		return
+	}
+	r.recorder.RecordGatewayRound(context.WithoutCancel(ctx), GatewayRound{
+		BotID:    req.BotID,
+		RouteID:  req.RouteID,
+		Kind:     reply.kind,
+		ModelID:  reply.model.id,
+		Request:  request,
+		Response: response,
+		Latency:  reply.latency,
+	})
+}
+
+// redactGatewayRequest marshals a gateway request without its credentials.
+func redactGatewayRequest(request any) (json.RawMessage, error) {
+	switch v := request.(type) {
+	case gatewayRequest:
+		v.Model.APIKey = ""
+		v.Identity.SessionToken = ""
+		return json.Marshal(v)
+	case triggerScheduleRequest:
+		v.Model.APIKey = ""
+		v.Identity.SessionToken = ""
+		return json.Marshal(v)
+	default:
+		return nil, fmt.Errorf("unsupported gateway request %T", request)
+	}
+}
+
+// GatewayReplay asks for a recorded gateway request to be sent again.
+type GatewayReplay struct {
+	BotID   string
+	Kind    string
+	Request json.RawMessage
+	// ModelID selects the chat model to answer, by models table ID or model
+	// slug. The recorded sampling options are kept.
+	ModelID string
+	// GatewayURL sends the request to another agent gateway instead of the
+	// configured ones.
+	GatewayURL string
+	// AllowTools keeps the recorded tool list. Otherwise the replay runs
+	// without tools, since they act on the bot for real.
+	AllowTools bool
+	// Token authorizes the replay on the gateway. The recorded session token
+	// is not kept, so replayed tools cannot reply on a channel.
+	Token string
+}
+
+// GatewayReplayResult is the answer to a replayed request.
+type GatewayReplayResult struct {
+	// Model is the gateway model ID that answered.
+	Model    string
+	Response json.RawMessage
+	Latency  time.Duration
+}
+
+// ReplayGatewayRound sends a recorded request to the agent gateway again and
+// returns the response. Nothing is stored and no usage is metered.
+func (r *Resolver) ReplayGatewayRound(ctx context.Context, replay GatewayReplay) (GatewayReplayResult, error) {
+	model, provider, err := r.fetchChatModel(ctx, replay.ModelID)
+	if err != nil {
+		return GatewayReplayResult{}, err
+	}
+	botSettings, err := r.loadBotSettings(ctx, replay.BotID)
+	if err != nil {
+		return GatewayReplayResult{}, err
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(replay.Request, &body); err != nil {
+		return GatewayReplayResult{}, fmt.Errorf("invalid recorded request: %w", err)
+	}
+
+	var recorded gatewayModelConfig
+	if raw, ok := body["model"]; ok {
+		_ = json.Unmarshal(raw, &recorded)
+	}
+	modelConfig := gatewayModelFor(model, provider, botSettings)
+	modelConfig.Temperature = recorded.Temperature
+	modelConfig.TopP = recorded.TopP
+	if body["model"], err = json.Marshal(modelConfig); err != nil {
+		return GatewayReplayResult{}, err
+	}
+
+	if !replay.AllowTools {
+		body["allowedActions"] = json.RawMessage("[]")
+	}
+	header := gatewayHeader(replay.Token)
+
+	path := "/chat/"
+	if replay.Kind == GatewayKindSchedule {
+		path = "/chat/trigger-schedule"
+	}
+	started := time.Now()
+	var resp *http.Response
+	if gatewayURL := strings.TrimRight(strings.TrimSpace(replay.GatewayURL), "/"); gatewayURL != "" {
+		httpReq, reqErr := newJSONRequestWithContext(ctx, http.MethodPost, gatewayURL+path, body)
+		if reqErr != nil {
+			return GatewayReplayResult{}, reqErr
+		}
+		for key, values := range header {
+			httpReq.Header[key] = values
+		}
+		resp, err = r.httpClient.Do(httpReq)
+	} else {
+		resp, err = r.sendGateway(ctx, r.httpClient, path, body, header)
+	}
+	if err != nil {
+		return GatewayReplayResult{}, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return GatewayReplayResult{}, err
+	}
+	latency := time.Since(started)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return GatewayReplayResult{}, &gatewayStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+	var parsed gatewayResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return GatewayReplayResult{}, fmt.Errorf("failed to parse gateway response: %w", err)
+	}
+	return GatewayReplayResult{Model: model.ModelID, Response: respBody, Latency: latency}, nil
+}
@@ -0,0 +1,80 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+type fakeGatewayRecorder struct {
+	enabled bool
+	rounds  []GatewayRound
+}
+
+func (f *fakeGatewayRecorder) RecordsGateway(ctx context.Context, botID string) bool {
+	return f.enabled
+}
+
+func (f *fakeGatewayRecorder) RecordGatewayRound(ctx context.Context, round GatewayRound) {
+	f.rounds = append(f.rounds, round)
+}
+
+func TestRecordRoundRedactsCredentials(t *testing.T) {
+	recorder := &fakeGatewayRecorder{enabled: true}
+	resolver := &Resolver{logger: slog.Default()}
+	resolver.SetGatewayRecorder(recorder)
+
+	payload := triggerScheduleRequest{
+		gatewayRequest: gatewayRequest{
+			Model:    gatewayModelConfig{ModelID: "gpt-4o", APIKey: "sk-secret", id: "model-uuid"},
+			Query:    "ignored",
+			Identity: gatewayIdentity{BotID: "bot-1", SessionToken: "session-secret"},
+		},
+		Schedule: gatewaySchedule{ID: "s1", Command: "report"},
+	}
+	reply := replyInfo{model: payload.Model, latency: 1500 * time.Millisecond, kind: GatewayKindSchedule, request: payload}
+	messages := []conversation.ModelMessage{{Role: "assistant", Content: conversation.NewTextContent("done")}}
+	resolver.recordRound(context.Background(), conversation.ChatRequest{BotID: "bot-1", RouteID: "route-1"}, reply, gatewayResponse{Messages: messages})
+
+	if len(recorder.rounds) != 1 {
+		t.Fatalf("expected one recorded round, got %d", len(recorder.rounds))
+	}
+	round := recorder.rounds[0]
+	if round.Kind != GatewayKindSchedule || round.ModelID != "model-uuid" || round.RouteID != "route-1" || round.Latency != 1500*time.Millisecond {
+		t.Fatalf("unexpected round %+v", round)
+	}
+	if strings.Contains(string(round.Request), "secret") {
+		t.Fatalf("credentials leaked into recording: %s", round.Request)
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(round.Request, &body); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["schedule"]; !ok {
+		t.Fatalf("expected schedule payload recorded as sent, got %s", round.Request)
+	}
+	if _, ok := body["query"]; ok {
+		t.Fatalf("expected trigger-schedule request without query, got %s", round.Request)
+	}
+	// The caller's payload keeps its credentials.
+	if payload.Model.APIKey != "sk-secret" {
+		t.Fatal("redaction must not modify the sent payload")
+	}
+}
+
+func TestRecordRoundSkipsBotsWithoutRecording(t *testing.T) {
+	recorder := &fakeGatewayRecorder{}
+	resolver := &Resolver{logger: slog.Default()}
+	resolver.SetGatewayRecorder(recorder)
+
+	reply := replyInfo{kind: GatewayKindChat, request: gatewayRequest{}}
+	resolver.recordRound(context.Background(), conversation.ChatRequest{BotID: "bot-1"}, reply, gatewayResponse{})
+	if len(recorder.rounds) != 0 {
+		t.Fatalf("expected nothing recorded, got %d", len(recorder.rounds))
+	}
+}
@@ -0,0 +1,218 @@
+package flow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/memory"
+)
+
+// Redactor keeps personal data on the server. Text bound for the agent
+// gateway has it replaced with placeholders, and placeholders in what comes
+// back are swapped for the values before it is stored and delivered.
+type Redactor interface {
+	// Redact returns value, a string or a raw or decoded JSON value, with
+	// personal data replaced. An error means value must not be sent.
+	Redact(ctx context.Context, botID string, value any) (any, error)
+	Rehydrate(ctx context.Context, botID string, value any) any
+	// SplitPartial splits streamed text before a trailing placeholder that
+	// may have been cut off.
+	SplitPartial(text string) (complete, partial string)
+}
+
+// SetRedactor enables PII redaction of gateway payloads and history
+// summaries. Recordings keep the redacted payload and the raw response.
+func (r *Resolver) SetRedactor(redactor Redactor) {
+	r.redactor = redactor
+}
+
+// redactPayload redacts the query, display name, messages and inbox items
+// of payload.
+func (r *Resolver) redactPayload(ctx context.Context, payload gatewayRequest) (gatewayRequest, error) {
+	if r.redactor == nil {
+		return payload, nil
+	}
+	values := make([]any, 0, 2+len(payload.Messages)+len(payload.Inbox))
+	values = append(values, payload.Query, payload.Identity.DisplayName)
+	for _, m := range payload.Messages {
+		values = append(values, m.Content)
+	}
+	for _, item := range payload.Inbox {
+		values = append(values, item.Content)
+	}
+	redacted, err := r.redactor.Redact(ctx, payload.Identity.BotID, values)
+	if err != nil {
+		return gatewayRequest{}, fmt.Errorf("redact personal data: %w", err)
+	}
+	out, ok := redacted.([]any)
+	if !ok || len(out) != len(values) {
+		return gatewayRequest{}, fmt.Errorf("redact personal data: unexpected result %T", redacted)
+	}
+	payload.Query, _ = out[0].(string)
+	payload.Identity.DisplayName, _ = out[1].(string)
+	out = out[2:]
+	messages := slices.Clone(payload.Messages)
+	for i := range messages {
+		messages[i].Content, _ = out[i].(json.RawMessage)
+	}
+	payload.Messages = messages
+	out = out[len(messages):]
+	inbox := slices.Clone(payload.Inbox)
+	for i := range inbox {
+		inbox[i].Content, _ = out[i].(map[string]any)
+	}
+	payload.Inbox = inbox
+	return payload, nil
+}
+
+// redactSummaryInput redacts the history handed to the summarizer, which
+// calls the memory model directly.
+func (r *Resolver) redactSummaryInput(ctx context.Context, botID, previous string, messages []memory.Message) (string, []memory.Message, error) {
+	if r.redactor == nil {
+		return previous, messages, nil
+	}
+	texts := make([]string, 0, 1+len(messages))
+	texts = append(texts, previous)
+	for _, m := range messages {
+		texts = append(texts, m.Content)
+	}
+	redacted, err := r.redactor.Redact(ctx, botID, texts)
+	if err != nil {
+		return "", nil, fmt.Errorf("redact personal data: %w", err)
+	}
+	out, ok := redacted.([]string)
+	if !ok || len(out) != len(texts) {
+		return "", nil, fmt.Errorf("redact personal data: unexpected result %T", redacted)
+	}
+	messages = slices.Clone(messages)
+	for i := range messages {
+		messages[i].Content = out[1+i]
+	}
+	return out[0], messages, nil
+}
+
+// rehydrateMessages returns messages with placeholders swapped back, or nil
+// when none had any.
+func (r *Resolver) rehydrateMessages(ctx context.Context, botID string, messages []conversation.ModelMessage) []conversation.ModelMessage {
+	if r.redactor == nil || len(messages) == 0 {
+		return nil
+	}
+	values := make([]any, len(messages))
+	for i, m := range messages {
+		values[i] = m.Content
+	}
+	out, ok := r.redactor.Rehydrate(ctx, botID, values).([]any)
+	if !ok || len(out) != len(values) {
+		return nil
+	}
+	var rewritten []conversation.ModelMessage
+	for i := range messages {
+		content, _ := out[i].(json.RawMessage)
+		if bytes.Equal(content, messages[i].Content) {
+			continue
+		}
+		if rewritten == nil {
+			rewritten = slices.Clone(messages)
+		}
+		rewritten[i].Content = content
+	}
+	return rewritten
+}
+
+// streamRehydrator re-hydrates the events of one stream. A delta ending in
+// what may be the start of a placeholder is held back until the next event.
+type streamRehydrator struct {
+	redactor Redactor
+	botID    string
+	logger   *slog.Logger
+	held     map[string]string
+}
+
+func (r *Resolver) newStreamRehydrator(botID string) *streamRehydrator {
+	if r.redactor == nil {
+		return nil
+	}
+	return &streamRehydrator{redactor: r.redactor, botID: botID, logger: r.logger, held: map[string]string{}}
+}
+
+// streamDeltaTypes are the events whose deltas are held back, flushed in
+// this order.
+var streamDeltaTypes = []string{"reasoning_delta", "text_delta"}
+
+// events returns the events to forward in place of event.
+func (s *streamRehydrator) events(ctx context.Context, event []byte) [][]byte {
+	if s == nil {
+		return [][]byte{event}
+	}
+	var envelope struct {
+		Type  string `json:"type"`
+		Delta string `json:"delta"`
+	}
+	if err := json.Unmarshal(event, &envelope); err != nil {
+		return [][]byte{event}
+	}
+	if slices.Contains(streamDeltaTypes, envelope.Type) {
+		complete, partial := s.redactor.SplitPartial(s.held[envelope.Type] + envelope.Delta)
+		s.held[envelope.Type] = partial
+		if complete == "" {
+			return nil
+		}
+		return [][]byte{withStreamDelta(event, s.text(ctx, complete))}
+	}
+	out := s.flush(ctx)
+	rehydrated, ok := s.redactor.Rehydrate(ctx, s.botID, json.RawMessage(event)).(json.RawMessage)
+	if !ok {
+		rehydrated = event
+	}
+	return append(out, rehydrated)
+}
+
+// flush returns delta events carrying the text held back.
+func (s *streamRehydrator) flush(ctx context.Context) [][]byte {
+	if s == nil {
+		return nil
+	}
+	var out [][]byte
+	for _, eventType := range streamDeltaTypes {
+		text := s.held[eventType]
+		if text == "" {
+			continue
+		}
+		delete(s.held, eventType)
+		data, err := json.Marshal(map[string]string{"type": eventType, "delta": s.text(ctx, text)})
+		if err != nil {
+			s.logger.Warn("flush held stream text failed", slog.String("bot_id", s.botID), slog.Any("error", err))
+			continue
+		}
+		out = append(out, data)
+	}
+	return out
+}
+
+func (s *streamRehydrator) text(ctx context.Context, text string) string {
+	out, _ := s.redactor.Rehydrate(ctx, s.botID, text).(string)
+	return out
+}
+
+// withStreamDelta replaces the delta of a stream event.
+func withStreamDelta(event []byte, delta string) []byte {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(event, &envelope); err != nil {
+		return event
+	}
+	encoded, err := json.Marshal(delta)
+	if err != nil {
+		return event
+	}
+	envelope["delta"] = encoded
+	out, err := json.Marshal(envelope)
+	if err != nil {
+		return event
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package flow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+// fakeRedactor swaps one value for one placeholder.
+type fakeRedactor struct {
+	value       string
+	placeholder string
+}
+
+func (f fakeRedactor) Redact(ctx context.Context, botID string, value any) (any, error) {
+	return swapValue(value, f.value, f.placeholder), nil
+}
+
+func (f fakeRedactor) Rehydrate(ctx context.Context, botID string, value any) any {
+	return swapValue(value, f.placeholder, f.value)
+}
+
+func (f fakeRedactor) SplitPartial(text string) (string, string) {
+	i := strings.LastIndex(text, "{{")
+	if i < 0 || strings.Contains(text[i:], "}}") {
+		return text, ""
+	}
+	return text[:i], text[i:]
+}
+
+func swapValue(value any, from, to string) any {
+	switch v := value.(type) {
+	case string:
+		return strings.ReplaceAll(v, from, to)
+	case json.RawMessage:
+		return json.RawMessage(bytes.ReplaceAll(v, []byte(from), []byte(to)))
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = swapValue(item, from, to)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = swapValue(item, from, to)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+func TestRedactPayloadLeavesOriginalUntouched(t *testing.T) {
+	r := &Resolver{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	r.SetRedactor(fakeRedactor{value: "555-0100", placeholder: "{{PHONE_1}}"})
+
+	history := []conversation.ModelMessage{{Role: "user", Content: conversation.NewTextContent("my number is 555-0100")}}
+	payload := gatewayRequest{
+		Query:    "call 555-0100",
+		Messages: history,
+		Inbox:    []gatewayInboxItem{{ID: "i1", Content: map[string]any{"text": "missed call from 555-0100"}}},
+		Identity: gatewayIdentity{BotID: "bot-1"},
+	}
+	redacted, err := r.redactPayload(context.Background(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redacted.Query != "call {{PHONE_1}}" || redacted.Messages[0].TextContent() != "my number is {{PHONE_1}}" || redacted.Inbox[0].Content["text"] != "missed call from {{PHONE_1}}" {
+		t.Fatalf("unexpected redacted payload %+v", redacted)
+	}
+	if history[0].TextContent() != "my number is 555-0100" || payload.Inbox[0].Content["text"] != "missed call from 555-0100" {
+		t.Fatal("redaction must not modify the caller's messages")
+	}
+}
+
+func TestStoredRoundIsRehydrated(t *testing.T) {
+	store := &recordingMessageService{}
+	r := &Resolver{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), messageService: store}
+	r.SetRedactor(fakeRedactor{value: "555-0100", placeholder: "{{PHONE_1}}"})
+
+	req := conversation.ChatRequest{BotID: "bot-1", ChatID: "bot-1", Query: "call 555-0100", UserMessagePersisted: true}
+	messages := []conversation.ModelMessage{
+		{Role: "user", Content: conversation.NewTextContent("call {{PHONE_1}}")},
+		{Role: "assistant", Content: conversation.NewTextContent("Calling {{PHONE_1}} now.")},
+	}
+	rewritten, err := r.storeRound(context.Background(), req, replyInfo{}, messages, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rewritten) != 2 || rewritten[1].TextContent() != "Calling 555-0100 now." {
+		t.Fatalf("expected re-hydrated messages, got %+v", rewritten)
+	}
+	// The re-hydrated user message matches the persisted query and is skipped.
+	if len(store.persisted) != 1 {
+		t.Fatalf("expected only the reply stored, got %d", len(store.persisted))
+	}
+	var stored conversation.ModelMessage
+	if err := json.Unmarshal(store.persisted[0].Content, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.TextContent() != "Calling 555-0100 now." {
+		t.Fatalf("expected the re-hydrated reply stored, got %q", stored.TextContent())
+	}
+}
+
+func TestStreamRehydratorHoldsSplitPlaceholders(t *testing.T) {
+	r := &Resolver{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	r.SetRedactor(fakeRedactor{value: "555-0100", placeholder: "{{PHONE_1}}"})
+	s := r.newStreamRehydrator("bot-1")
+	ctx := context.Background()
+
+	deltaOf := func(events [][]byte) []string {
+		var out []string
+		for _, event := range events {
+			var envelope struct {
+				Type  string `json:"type"`
+				Delta string `json:"delta"`
+			}
+			if err := json.Unmarshal(event, &envelope); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, envelope.Type+":"+envelope.Delta)
+		}
+		return out
+	}
+
+	if got := deltaOf(s.events(ctx, []byte(`{"type":"text_delta","delta":"Call {{PHO"}`))); len(got) != 1 || got[0] != "text_delta:Call " {
+		t.Fatalf("expected the cut placeholder held back, got %v", got)
+	}
+	if got := deltaOf(s.events(ctx, []byte(`{"type":"text_delta","delta":"NE_1}} now {{"}`))); len(got) != 1 || got[0] != "text_delta:555-0100 now " {
+		t.Fatalf("expected the completed placeholder re-hydrated, got %v", got)
+	}
+	got := deltaOf(s.events(ctx, []byte(`{"type":"tool_call_start","input":{"to":"{{PHONE_1}}"}}`)))
+	if len(got) != 2 || got[0] != "text_delta:{{" || got[1] != "tool_call_start:" {
+		t.Fatalf("expected held text flushed before the next event, got %v", got)
+	}
+	tool := s.events(ctx, []byte(`{"type":"tool_call_end","input":{"to":"{{PHONE_1}}"}}`))
+	if len(tool) != 1 || !strings.Contains(string(tool[0]), `"to":"555-0100"`) {
+		t.Fatalf("expected tool input re-hydrated, got %s", tool)
+	}
+
+	var none *streamRehydrator
+	if out := none.events(ctx, []byte(`{"type":"text_delta","delta":"{{PHO"}`)); len(out) != 1 {
+		t.Fatal("without a redactor events pass as they are")
+	}
+}
@@ -11,8 +11,10 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -20,6 +22,7 @@ import (
 
 	attachmentpkg "github.com/memohai/memoh/internal/attachment"
 	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/gateway"
 	"github.com/memohai/memoh/internal/db"
 	"github.com/memohai/memoh/internal/db/sqlc"
 	"github.com/memohai/memoh/internal/inbox"
@@ -28,6 +31,7 @@ import (
 	"github.com/memohai/memoh/internal/models"
 	"github.com/memohai/memoh/internal/schedule"
 	"github.com/memohai/memoh/internal/settings"
+	"github.com/memohai/memoh/internal/tokenizer"
 )
 
 const (
@@ -35,7 +39,6 @@ const (
 	memoryContextLimitPerScope = 4
 	memoryContextMaxItems      = 8
 	memoryContextItemMaxChars  = 220
-	sharedMemoryNamespace      = "bot"
 	// Keep gateway payload bounded when inlining binary attachments as data URLs.
 	gatewayInlineAttachmentMaxBytes int64 = 20 * 1024 * 1024
 	// SSE payloads (especially attachment/tool results) can be very large.
@@ -69,22 +72,47 @@ type gatewayAssetLoader interface {
 	OpenForGateway(ctx context.Context, botID, contentHash string) (reader io.ReadCloser, mime string, err error)
 }
 
+// QuotaEnforcer gates chat rounds on usage quotas and meters the usage they
+// consume.
+type QuotaEnforcer interface {
+	// CheckQuota returns an error, shown to the sender, when req may not run.
+	CheckQuota(ctx context.Context, req conversation.ChatRequest) error
+	// RecordUsage meters a round answered by the model with the models
+	// table ID modelUUID.
+	RecordUsage(ctx context.Context, req conversation.ChatRequest, modelUUID string, usage conversation.Usage)
+}
+
 // Resolver orchestrates chat with the agent gateway.
 type Resolver struct {
 	modelsService   *models.Service
 	queries         *sqlc.Queries
-	memoryService   *memory.Service
+	memoryService   memoryStore
 	conversationSvc ConversationSettingsReader
 	messageService  messagepkg.Service
 	settingsService *settings.Service
 	inboxService    *inbox.Service
 	skillLoader     SkillLoader
 	assetLoader     gatewayAssetLoader
+	docExtractor    documentExtractor
+	summarizer      historySummarizer
+	summaries       historySummaryStore
+	tokenizers      *tokenizer.Registry
+	quotas          QuotaEnforcer
+	recorder        GatewayRecorder
+	moderator       Moderator
+	redactor        Redactor
+	gateways        *gateway.Pool
 	gatewayBaseURL  string
 	timeout         time.Duration
 	logger          *slog.Logger
 	httpClient      *http.Client
 	streamingClient *http.Client
+
+	// summaryRefreshes tracks background summary regenerations per route so
+	// a burst of turns does not summarize the same span twice.
+	summaryRefreshes sync.Map
+	// generations tracks streaming replies for CancelGenerations.
+	generations generationRegistry
 }
 
 // NewResolver creates a Resolver that communicates with the agent gateway.
@@ -106,19 +134,26 @@ func NewResolver(
 	if timeout <= 0 {
 		timeout = 60 * time.Second
 	}
-	return &Resolver{
+	r := &Resolver{
 		modelsService:   modelsService,
 		queries:         queries,
-		memoryService:   memoryService,
 		conversationSvc: conversationSvc,
 		messageService:  messageService,
 		settingsService: settingsService,
+		tokenizers:      tokenizer.NewRegistry(),
 		gatewayBaseURL:  gatewayBaseURL,
 		timeout:         timeout,
 		logger:          log.With(slog.String("service", "conversation_resolver")),
 		httpClient:      &http.Client{Timeout: timeout},
 		streamingClient: &http.Client{},
 	}
+	if memoryService != nil {
+		r.memoryService = memoryService
+	}
+	if queries != nil {
+		r.summaries = queries
+	}
+	return r
 }
 
 // SetSkillLoader sets the skill loader used to populate usable skills in gateway requests.
@@ -132,6 +167,42 @@ func (r *Resolver) SetGatewayAssetLoader(loader gatewayAssetLoader) {
 	r.assetLoader = loader
 }
 
+// SetDocumentExtractor configures text extraction for document attachments
+// sent to models without file input support.
+func (r *Resolver) SetDocumentExtractor(extractor documentExtractor) {
+	r.docExtractor = extractor
+}
+
+// SetHistorySummarizer enables rolling summaries of history trimmed from the
+// context window. Summaries are generated with the bot's memory model.
+func (r *Resolver) SetHistorySummarizer(summarizer historySummarizer) {
+	r.summarizer = summarizer
+}
+
+// SetTokenizers replaces the tokenizers used to budget the context window.
+func (r *Resolver) SetTokenizers(registry *tokenizer.Registry) {
+	if registry != nil {
+		r.tokenizers = registry
+	}
+}
+
+// SetQuotaEnforcer enables usage quotas. Rounds over quota are refused
+// before the gateway is called.
+func (r *Resolver) SetQuotaEnforcer(enforcer QuotaEnforcer) {
+	r.quotas = enforcer
+}
+
+// SetGatewayRecorder enables recording of gateway rounds for replay.
+func (r *Resolver) SetGatewayRecorder(recorder GatewayRecorder) {
+	r.recorder = recorder
+}
+
+// SetGatewayPool spreads gateway requests over the endpoints of pool instead
+// of the single base URL the resolver was created with.
+func (r *Resolver) SetGatewayPool(pool *gateway.Pool) {
+	r.gateways = pool
+}
+
 // SetInboxService configures inbox support for injecting unread items into the
 // system prompt and marking them as read after a response.
 func (r *Resolver) SetInboxService(service *inbox.Service) {
@@ -146,12 +217,18 @@ type gatewayReasoningConfig struct {
 }
 
 type gatewayModelConfig struct {
-	ModelID    string                  `json:"modelId"`
-	ClientType string                  `json:"clientType"`
-	Input      []string                `json:"input"`
-	APIKey     string                  `json:"apiKey"`
-	BaseURL    string                  `json:"baseUrl"`
-	Reasoning  *gatewayReasoningConfig `json:"reasoning,omitempty"`
+	ModelID     string                  `json:"modelId"`
+	ClientType  string                  `json:"clientType"`
+	Input       []string                `json:"input"`
+	APIKey      string                  `json:"apiKey"`
+	BaseURL     string                  `json:"baseUrl"`
+	Reasoning   *gatewayReasoningConfig `json:"reasoning,omitempty"`
+	Temperature *float64                `json:"temperature,omitempty"`
+	TopP        *float64                `json:"topP,omitempty"`
+
+	// id is the models table ID, kept for gateway recordings.
+	id      string
+	variant *modelVariant
 }
 
 type gatewayIdentity struct {
@@ -161,6 +238,7 @@ type gatewayIdentity struct {
 	DisplayName       string `json:"displayName"`
 	CurrentPlatform   string `json:"currentPlatform,omitempty"`
 	ConversationType  string `json:"conversationType,omitempty"`
+	RouteID           string `json:"routeId,omitempty"`
 	SessionToken      string `json:"sessionToken,omitempty"`
 }
 
@@ -183,7 +261,7 @@ type gatewayRequest struct {
 	ActiveContextTime int                         `json:"activeContextTime"`
 	Channels          []string                    `json:"channels"`
 	CurrentChannel    string                      `json:"currentChannel"`
-	AllowedActions    []string                    `json:"allowedActions,omitempty"`
+	AllowedActions    []string                    `json:"allowedActions"`
 	Messages          []conversation.ModelMessage `json:"messages"`
 	Skills            []string                    `json:"skills"`
 	UsableSkills      []gatewaySkill              `json:"usableSkills"`
@@ -191,6 +269,7 @@ type gatewayRequest struct {
 	Identity          gatewayIdentity             `json:"identity"`
 	Attachments       []any                       `json:"attachments"`
 	Inbox             []gatewayInboxItem          `json:"inbox,omitempty"`
+	Instructions      string                      `json:"instructions,omitempty"`
 }
 
 type gatewayResponse struct {
@@ -246,7 +325,15 @@ type resolvedContext struct {
 	payload      gatewayRequest
 	model        models.GetResponse
 	provider     sqlc.LlmProvider
+	fallbacks    []chatModelCandidate
 	inboxItemIDs []string
+	// query is the headerified query before redaction, for the stored user
+	// message.
+	query string
+
+	// Kept to retarget the payload at a fallback model.
+	req         conversation.ChatRequest
+	botSettings settings.Settings
 }
 
 func (r *Resolver) resolve(ctx context.Context, req conversation.ChatRequest) (resolvedContext, error) {
@@ -259,6 +346,11 @@ func (r *Resolver) resolve(ctx context.Context, req conversation.ChatRequest) (r
 	if strings.TrimSpace(req.ChatID) == "" {
 		return resolvedContext{}, fmt.Errorf("chat id is required")
 	}
+	if r.quotas != nil {
+		if err := r.quotas.CheckQuota(ctx, req); err != nil {
+			return resolvedContext{}, err
+		}
+	}
 
 	skipHistory := req.MaxContextLoadTime < 0
 
@@ -276,11 +368,14 @@ func (r *Resolver) resolve(ctx context.Context, req conversation.ChatRequest) (r
 		}
 	}
 
+	botSettings = withChatOverrides(botSettings, chatSettings)
+
 	chatModel, provider, err := r.selectChatModel(ctx, req, botSettings, chatSettings)
 	if err != nil {
 		return resolvedContext{}, err
 	}
-	clientType := string(chatModel.ClientType)
+	chatModel, provider, variant := r.selectModelVariant(ctx, req, chatModel, provider)
+	fallbacks := r.selectFallbackModels(ctx, req, botSettings, chatSettings, chatModel)
 
 	maxCtx := coalescePositiveInt(req.MaxContextLoadTime, botSettings.MaxContextLoadTime, defaultMaxContextMinutes)
 	maxTokens := botSettings.MaxContextTokens
@@ -293,51 +388,8 @@ func (r *Resolver) resolve(ctx context.Context, req conversation.ChatRequest) (r
 		pruned, _ := pruneMessageForGateway(*memoryMsg)
 		memoryMsg = &pruned
 	}
-	var overhead int
-	if memoryMsg != nil {
-		overhead += estimateMessageTokens(*memoryMsg)
-	}
-	for _, m := range reqMessages {
-		overhead += estimateMessageTokens(m)
-	}
-	// Reserve space for the system prompt built by the agent gateway
-	// (IDENTITY.md, SOUL.md, TOOLS.md, skills, boilerplate, user prompt, etc.).
-	const systemPromptReserve = 4096
-	overhead += systemPromptReserve
 
-	historyBudget := maxTokens - overhead
-	if historyBudget < 0 {
-		historyBudget = 0
-	}
-
-	r.logger.Debug("context token budget",
-		slog.Int("max_tokens", maxTokens),
-		slog.Int("overhead", overhead),
-		slog.Int("system_prompt_reserve", systemPromptReserve),
-		slog.Int("history_budget", historyBudget),
-	)
-
-	var messages []conversation.ModelMessage
-	if !skipHistory && r.conversationSvc != nil {
-		loaded, loadErr := r.loadMessages(ctx, req.ChatID, maxCtx)
-		if loadErr != nil {
-			return resolvedContext{}, loadErr
-		}
-		loaded = pruneHistoryForGateway(loaded)
-		messages = trimMessagesByTokens(loaded, historyBudget)
-		r.logger.Debug("context trim result",
-			slog.Int("loaded_messages", len(loaded)),
-			slog.Int("kept_messages", len(messages)),
-			slog.Int("trimmed_messages", len(loaded)-len(messages)),
-			slog.Int("history_budget", historyBudget),
-		)
-	}
-	if memoryMsg != nil {
-		messages = append(messages, *memoryMsg)
-	}
-	messages = append(messages, reqMessages...)
-	messages = sanitizeMessages(messages)
-	skills := dedup(req.Skills)
+	skills := dedup(append(slices.Clone(req.Skills), chatSettings.EnabledSkills...))
 	containerID := r.resolveContainerID(ctx, req.BotID, req.ContainerID)
 
 	var usableSkills []gatewaySkill
@@ -387,6 +439,7 @@ func (r *Resolver) resolve(ctx context.Context, req conversation.ChatRequest) (r
 
 	attachments := r.routeAndMergeAttachments(ctx, chatModel, req)
 	displayName := r.resolveDisplayName(ctx, req)
+	query := appendDocumentTexts(req.Query, r.extractDocumentTexts(ctx, chatModel, req))
 
 	headerifiedQuery := FormatUserHeader(
 		strings.TrimSpace(req.ExternalMessageID),
@@ -396,30 +449,69 @@ func (r *Resolver) resolve(ctx context.Context, req conversation.ChatRequest) (r
 		strings.TrimSpace(req.ConversationType),
 		strings.TrimSpace(req.ConversationName),
 		extractFileRefPaths(attachments),
-		req.Query,
+		query,
+	)
+
+	counter := newTokenCounter(r.tokenizers, chatModel)
+	var overhead int
+	if memoryMsg != nil {
+		overhead += counter.message(*memoryMsg)
+	}
+	for _, m := range reqMessages {
+		overhead += counter.message(m)
+	}
+	overhead += messageTokenOverhead + counter.text(headerifiedQuery) + counter.attachments(attachments)
+	// Reserve space for the system prompt built by the agent gateway.
+	systemPromptReserve := counter.systemPromptReserve(usableSkills, skills, inboxGatewayItems) + counter.text(chatSettings.SystemPrompt)
+	overhead += systemPromptReserve
+
+	historyBudget := maxTokens - overhead
+	if historyBudget < 0 {
+		historyBudget = 0
+	}
+
+	r.logger.Debug("context token budget",
+		slog.Int("max_tokens", maxTokens),
+		slog.Int("overhead", overhead),
+		slog.Int("system_prompt_reserve", systemPromptReserve),
+		slog.Int("history_budget", historyBudget),
 	)
 
-	var reasoning *gatewayReasoningConfig
-	if chatModel.SupportsReasoning && botSettings.ReasoningEnabled {
-		reasoning = &gatewayReasoningConfig{
-			Enabled: true,
-			Effort:  botSettings.ReasoningEffort,
+	var messages []conversation.ModelMessage
+	if !skipHistory && r.conversationSvc != nil {
+		loaded, loadErr := r.loadMessages(ctx, req.ChatID, maxCtx, req.RerunMessageID)
+		if loadErr != nil {
+			return resolvedContext{}, loadErr
+		}
+		loaded = pruneHistoryForGateway(loaded)
+		cutoff := historyCutoff(counter, loaded, historyBudget)
+		if cutoff > 0 && r.summarizer != nil && historyBudget > 2*historySummaryReserve {
+			// Make room for the summary that replaces the dropped span.
+			cutoff = historyCutoff(counter, loaded, historyBudget-historySummaryReserve)
 		}
+		if summaryMsg := r.historySummaryMessage(ctx, req, loaded[:cutoff]); summaryMsg != nil {
+			messages = append(messages, *summaryMsg)
+		}
+		messages = append(messages, modelMessagesOf(loaded[cutoff:])...)
+		r.logger.Debug("context trim result",
+			slog.Int("loaded_messages", len(loaded)),
+			slog.Int("kept_messages", len(loaded)-cutoff),
+			slog.Int("trimmed_messages", cutoff),
+			slog.Int("history_budget", historyBudget),
+		)
+	}
+	if memoryMsg != nil {
+		messages = append(messages, *memoryMsg)
 	}
+	messages = append(messages, reqMessages...)
+	messages = sanitizeMessages(messages)
 
 	payload := gatewayRequest{
-		Model: gatewayModelConfig{
-			ModelID:    chatModel.ModelID,
-			ClientType: clientType,
-			Input:      chatModel.InputModalities,
-			APIKey:     provider.ApiKey,
-			BaseURL:    provider.BaseUrl,
-			Reasoning:  reasoning,
-		},
+		Model:             gatewayModelFor(chatModel, provider, botSettings),
 		ActiveContextTime: maxCtx,
 		Channels:          nonNilStrings(req.Channels),
 		CurrentChannel:    req.CurrentChannel,
-		AllowedActions:    req.AllowedActions,
+		AllowedActions:    mergeAllowedActions(req.AllowedActions, chatSettings.AllowedTools),
 		Messages:          nonNilModelMessages(messages),
 		Skills:            nonNilStrings(skills),
 		UsableSkills:      usableSkills,
@@ -431,38 +523,76 @@ func (r *Resolver) resolve(ctx context.Context, req conversation.ChatRequest) (r
 			DisplayName:       displayName,
 			CurrentPlatform:   req.CurrentChannel,
 			ConversationType:  strings.TrimSpace(req.ConversationType),
+			RouteID:           strings.TrimSpace(req.RouteID),
 			SessionToken:      req.ChatToken,
 		},
-		Attachments: attachments,
-		Inbox:       inboxGatewayItems,
+		Attachments:  attachments,
+		Inbox:        inboxGatewayItems,
+		Instructions: chatSettings.SystemPrompt,
+	}
+	payload.Model.Temperature = chatSettings.Temperature
+	payload.Model.TopP = chatSettings.TopP
+	payload.Model.variant = variant
+	payload, err = r.redactPayload(ctx, payload)
+	if err != nil {
+		return resolvedContext{}, err
 	}
 
-	return resolvedContext{payload: payload, model: chatModel, provider: provider, inboxItemIDs: inboxItemIDs}, nil
+	return resolvedContext{
+		payload:      payload,
+		model:        chatModel,
+		provider:     provider,
+		fallbacks:    fallbacks,
+		inboxItemIDs: inboxItemIDs,
+		query:        headerifiedQuery,
+		req:          req,
+		botSettings:  botSettings,
+	}, nil
 }
 
 // --- Chat ---
 
 // Chat sends a synchronous chat request to the agent gateway and stores the result.
 func (r *Resolver) Chat(ctx context.Context, req conversation.ChatRequest) (conversation.ChatResponse, error) {
+	if err := r.moderateInput(ctx, &req); err != nil {
+		return conversation.ChatResponse{}, err
+	}
 	rc, err := r.resolve(ctx, req)
 	if err != nil {
 		return conversation.ChatResponse{}, err
 	}
-	req.Query = rc.payload.Query
-	resp, err := r.postChat(ctx, rc.payload, req.Token)
+	req.Query = rc.query
+	var resp gatewayResponse
+	var latency time.Duration
+	rc, err = r.callWithFallback(ctx, rc, nil, func(attempt resolvedContext) error {
+		started := time.Now()
+		var postErr error
+		resp, postErr = r.postChat(ctx, attempt.payload, req.Token)
+		latency = time.Since(started)
+		return postErr
+	})
 	if err != nil {
 		return conversation.ChatResponse{}, err
 	}
-	if err := r.storeRound(ctx, req, resp.Messages, resp.Usage, resp.Usages); err != nil {
+	reply := replyInfo{model: rc.payload.Model, latency: latency, kind: GatewayKindChat, request: rc.payload}
+	moderated, err := r.storeRound(ctx, req, reply, resp.Messages, resp.Usage, resp.Usages)
+	if err != nil {
 		return conversation.ChatResponse{}, err
 	}
+	if moderated != nil {
+		resp.Messages = moderated
+	}
 	r.markInboxRead(ctx, req.BotID, rc.inboxItemIDs)
-	return conversation.ChatResponse{
+	result := conversation.ChatResponse{
 		Messages: resp.Messages,
 		Skills:   resp.Skills,
 		Model:    rc.model.ModelID,
 		Provider: string(rc.model.ClientType),
-	}, nil
+	}
+	if usage, ok := conversation.ParseUsage(resp.Usage, resp.Usages); ok {
+		result.Usage = &usage
+	}
+	return result, nil
 }
 
 // --- TriggerSchedule ---
@@ -488,27 +618,38 @@ func (r *Resolver) TriggerSchedule(ctx context.Context, botID string, payload sc
 		return err
 	}
 
-	schedulePayload := rc.payload
-	schedulePayload.Identity.ChannelIdentityID = strings.TrimSpace(payload.OwnerUserID)
-	schedulePayload.Identity.DisplayName = "Scheduler"
-
-	triggerReq := triggerScheduleRequest{
-		gatewayRequest: schedulePayload,
-		Schedule: gatewaySchedule{
-			ID:          payload.ID,
-			Name:        payload.Name,
-			Description: payload.Description,
-			Pattern:     payload.Pattern,
-			MaxCalls:    payload.MaxCalls,
-			Command:     payload.Command,
-		},
-	}
-
-	resp, err := r.postTriggerSchedule(ctx, triggerReq, token)
+	var resp gatewayResponse
+	var sent triggerScheduleRequest
+	var latency time.Duration
+	rc, err = r.callWithFallback(ctx, rc, nil, func(attempt resolvedContext) error {
+		started := time.Now()
+		schedulePayload := attempt.payload
+		schedulePayload.Identity.ChannelIdentityID = strings.TrimSpace(payload.OwnerUserID)
+		schedulePayload.Identity.DisplayName = "Scheduler"
+
+		triggerReq := triggerScheduleRequest{
+			gatewayRequest: schedulePayload,
+			Schedule: gatewaySchedule{
+				ID:          payload.ID,
+				Name:        payload.Name,
+				Description: payload.Description,
+				Pattern:     payload.Pattern,
+				MaxCalls:    payload.MaxCalls,
+				Command:     payload.Command,
+			},
+		}
+		sent = triggerReq
+		var postErr error
+		resp, postErr = r.postTriggerSchedule(ctx, triggerReq, token)
+		latency = time.Since(started)
+		return postErr
+	})
 	if err != nil {
 		return err
 	}
-	return r.storeRound(ctx, req, resp.Messages, resp.Usage, resp.Usages)
+	reply := replyInfo{model: rc.payload.Model, latency: latency, kind: GatewayKindSchedule, request: sent}
+	_, err = r.storeRound(ctx, req, reply, resp.Messages, resp.Usage, resp.Usages)
+	return err
 }
 
 // --- StreamChat ---
@@ -527,7 +668,21 @@ func (r *Resolver) StreamChat(ctx context.Context, req conversation.ChatRequest)
 		defer close(errCh)
 
 		streamReq := req
+		parentCtx := ctx
+		ctx, done := r.generations.start(ctx, &streamReq)
+		defer done()
+		started := time.Now()
+
+		partial := &partialReply{}
+		if err := r.moderateInput(ctx, &streamReq); err != nil {
+			errCh <- err
+			return
+		}
 		rc, err := r.resolve(ctx, streamReq)
+		if err != nil && errors.Is(context.Cause(ctx), ErrGenerationCancelled) {
+			r.finishCancelled(parentCtx, streamReq, partial, time.Since(started), chunkCh)
+			return
+		}
 		if err != nil {
 			r.logger.Error("gateway stream resolve failed",
 				slog.String("bot_id", streamReq.BotID),
@@ -537,7 +692,7 @@ func (r *Resolver) StreamChat(ctx context.Context, req conversation.ChatRequest)
 			errCh <- err
 			return
 		}
-		streamReq.Query = rc.payload.Query
+		streamReq.Query = rc.query
 		if !streamReq.UserMessagePersisted {
 			if err := r.persistUserMessage(ctx, streamReq); err != nil {
 				r.logger.Error("gateway stream persist user message failed",
@@ -550,7 +705,26 @@ func (r *Resolver) StreamChat(ctx context.Context, req conversation.ChatRequest)
 			}
 			streamReq.UserMessagePersisted = true
 		}
-		if err := r.streamChat(ctx, rc.payload, streamReq, chunkCh); err != nil {
+		announceFallback := func(from, to models.GetResponse, cause error) {
+			chunk, err := modelFallbackChunk(from, to, cause)
+			if err != nil {
+				return
+			}
+			select {
+			case chunkCh <- chunk:
+			case <-ctx.Done():
+			}
+		}
+		_, err = r.callWithFallback(ctx, rc, announceFallback, func(attempt resolvedContext) error {
+			partial.reset(attempt.payload, r.tokenizers)
+			return r.streamChatRecording(ctx, attempt.payload, streamReq, chunkCh, partial)
+		})
+		if err != nil && errors.Is(context.Cause(ctx), ErrGenerationCancelled) {
+			r.finishCancelled(parentCtx, streamReq, partial, time.Since(started), chunkCh)
+			r.markInboxRead(parentCtx, streamReq.BotID, rc.inboxItemIDs)
+			return
+		}
+		if err != nil {
 			r.logger.Error("gateway stream request failed",
 				slog.String("bot_id", streamReq.BotID),
 				slog.String("chat_id", streamReq.ChatID),
@@ -567,27 +741,20 @@ func (r *Resolver) StreamChat(ctx context.Context, req conversation.ChatRequest)
 // --- HTTP helpers ---
 
 func (r *Resolver) postChat(ctx context.Context, payload gatewayRequest, token string) (gatewayResponse, error) {
-	url := r.gatewayBaseURL + "/chat/"
+	const path = "/chat/"
 	r.logger.Info(
 		"gateway request",
-		slog.String("url", url),
+		slog.String("path", path),
 		slog.Int("messages", len(payload.Messages)),
 		slog.Int("attachments", len(payload.Attachments)),
 	)
 
-	httpReq, err := newJSONRequestWithContext(ctx, http.MethodPost, url, payload)
-	if err != nil {
-		return gatewayResponse{}, err
-	}
-	if strings.TrimSpace(token) != "" {
-		httpReq.Header.Set("Authorization", token)
-	}
-
-	resp, err := r.httpClient.Do(httpReq)
+	resp, err := r.sendGateway(ctx, r.httpClient, path, payload, gatewayHeader(token))
 	if err != nil {
 		return gatewayResponse{}, err
 	}
 	defer resp.Body.Close()
+	url := resp.Request.URL.String()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -595,7 +762,7 @@ func (r *Resolver) postChat(ctx context.Context, payload gatewayRequest, token s
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		r.logger.Error("gateway error", slog.String("url", url), slog.Int("status", resp.StatusCode), slog.String("body_prefix", truncate(string(respBody), 300)))
-		return gatewayResponse{}, fmt.Errorf("agent gateway error: %s", strings.TrimSpace(string(respBody)))
+		return gatewayResponse{}, &gatewayStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
 	}
 
 	var parsed gatewayResponse
@@ -608,22 +775,15 @@ func (r *Resolver) postChat(ctx context.Context, payload gatewayRequest, token s
 
 // postTriggerSchedule sends a trigger-schedule request to the agent gateway.
 func (r *Resolver) postTriggerSchedule(ctx context.Context, payload triggerScheduleRequest, token string) (gatewayResponse, error) {
-	url := r.gatewayBaseURL + "/chat/trigger-schedule"
-	r.logger.Info("gateway trigger-schedule request", slog.String("url", url), slog.String("schedule_id", payload.Schedule.ID))
-
-	httpReq, err := newJSONRequestWithContext(ctx, http.MethodPost, url, payload)
-	if err != nil {
-		return gatewayResponse{}, err
-	}
-	if strings.TrimSpace(token) != "" {
-		httpReq.Header.Set("Authorization", token)
-	}
+	const path = "/chat/trigger-schedule"
+	r.logger.Info("gateway trigger-schedule request", slog.String("path", path), slog.String("schedule_id", payload.Schedule.ID))
 
-	resp, err := r.httpClient.Do(httpReq)
+	resp, err := r.sendGateway(ctx, r.httpClient, path, payload, gatewayHeader(token))
 	if err != nil {
 		return gatewayResponse{}, err
 	}
 	defer resp.Body.Close()
+	url := resp.Request.URL.String()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -631,7 +791,7 @@ func (r *Resolver) postTriggerSchedule(ctx context.Context, payload triggerSched
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		r.logger.Error("gateway trigger-schedule error", slog.String("url", url), slog.Int("status", resp.StatusCode), slog.String("body_prefix", truncate(string(respBody), 300)))
-		return gatewayResponse{}, fmt.Errorf("agent gateway error: %s", strings.TrimSpace(string(respBody)))
+		return gatewayResponse{}, &gatewayStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
 	}
 
 	var parsed gatewayResponse
@@ -643,37 +803,92 @@ func (r *Resolver) postTriggerSchedule(ctx context.Context, payload triggerSched
 }
 
 func (r *Resolver) streamChat(ctx context.Context, payload gatewayRequest, req conversation.ChatRequest, chunkCh chan<- conversation.StreamChunk) error {
-	url := r.gatewayBaseURL + "/chat/stream"
+	return r.streamChatRecording(ctx, payload, req, chunkCh, nil)
+}
+
+// streamChatRecording is streamChat that also records the streamed text in
+// partial, when set, for storing it if the stream is cancelled.
+func (r *Resolver) streamChatRecording(ctx context.Context, payload gatewayRequest, req conversation.ChatRequest, chunkCh chan<- conversation.StreamChunk, partial *partialReply) error {
+	started := time.Now()
+	const path = "/chat/stream"
 	r.logger.Info(
 		"gateway stream request",
-		slog.String("url", url),
+		slog.String("path", path),
 		slog.Int("messages", len(payload.Messages)),
 		slog.Int("attachments", len(payload.Attachments)),
 	)
-	httpReq, err := newJSONRequestWithContext(ctx, http.MethodPost, url, payload)
-	if err != nil {
-		return err
-	}
-	httpReq.Header.Set("Accept", "text/event-stream")
-	if strings.TrimSpace(req.Token) != "" {
-		httpReq.Header.Set("Authorization", req.Token)
-	}
+	header := gatewayHeader(req.Token)
+	header.Set("Accept", "text/event-stream")
 
-	resp, err := r.streamingClient.Do(httpReq)
+	resp, err := r.sendGateway(ctx, r.streamingClient, path, payload, header)
 	if err != nil {
-		r.logger.Error("gateway stream connect failed", slog.String("url", url), slog.Any("error", err))
+		r.logger.Error("gateway stream connect failed", slog.String("path", path), slog.Any("error", err))
 		return err
 	}
 	defer resp.Body.Close()
+	url := resp.Request.URL.String()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		errBody, _ := io.ReadAll(resp.Body)
 		r.logger.Error("gateway stream error", slog.String("url", url), slog.Int("status", resp.StatusCode), slog.String("body_prefix", truncate(string(errBody), 300)))
-		return fmt.Errorf("agent gateway error: %s", strings.TrimSpace(string(errBody)))
+		return &gatewayStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(errBody))}
 	}
 
 	stored := false
+	rehydrator := r.newStreamRehydrator(req.BotID)
+	moderation := r.newStreamModeration(ctx, req)
 	var dataBuf bytes.Buffer
+	// Until the reply carries content, the opening events are held back so
+	// a provider failure reported in-band can still go to a fallback model.
+	var opening [][]byte
+	replying := false
+
+	forward := func(out []byte) error {
+		// Persist final messages before forwarding the "done"/"agent_end" event so the
+		// next user turn can immediately see the assistant output in history.
+		handled := false
+		if !stored {
+			reply := replyInfo{model: payload.Model, latency: time.Since(started), kind: GatewayKindStream, request: payload}
+			var event []byte
+			var storeErr error
+			handled, event, storeErr = r.tryStoreStream(ctx, req, reply, out)
+			if storeErr != nil {
+				return storeErr
+			}
+			if handled {
+				stored = true
+				out = event
+			}
+		}
+		// Stored messages are re-hydrated already; only held text is left.
+		var events [][]byte
+		if handled {
+			events = append(rehydrator.flush(ctx), out)
+		} else {
+			events = rehydrator.events(ctx, out)
+		}
+		for _, event := range events {
+			// The partial reply keeps the text as produced; a cancelled
+			// reply is moderated when it is stored.
+			if partial != nil {
+				partial.observe(event)
+				partial.stored = stored
+			}
+			for _, screened := range moderation.events(ctx, event) {
+				chunkCh <- conversation.StreamChunk(screened)
+			}
+		}
+		return nil
+	}
+	forwardOpening := func() error {
+		for _, event := range opening {
+			if err := forward(event); err != nil {
+				return err
+			}
+		}
+		opening = nil
+		return nil
+	}
 
 	flushEvent := func() error {
 		if dataBuf.Len() == 0 {
@@ -684,17 +899,23 @@ func (r *Resolver) streamChat(ctx context.Context, payload gatewayRequest, req c
 		if len(out) == 0 || bytes.Equal(bytes.TrimSpace(out), []byte("[DONE]")) {
 			return nil
 		}
-		// Persist final messages before forwarding the "done"/"agent_end" event so the
-		// next user turn can immediately see the assistant output in history.
-		if !stored {
-			if handled, storeErr := r.tryStoreStream(ctx, req, out); storeErr != nil {
-				return storeErr
-			} else if handled {
-				stored = true
+		if !replying {
+			eventType := gatewayEventType(out)
+			if eventType == "error" {
+				streamErr := gatewayStreamError(out)
+				r.logger.Error("gateway stream error event", slog.String("url", url), slog.Int("status", streamErr.StatusCode), slog.String("body_prefix", truncate(streamErr.Body, 300)))
+				return streamErr
+			}
+			if slices.Contains(gatewayOpeningEventTypes, eventType) {
+				opening = append(opening, out)
+				return nil
+			}
+			replying = true
+			if err := forwardOpening(); err != nil {
+				return err
 			}
 		}
-		chunkCh <- conversation.StreamChunk(out)
-		return nil
+		return forward(out)
 	}
 
 	scanner := bufio.NewScanner(resp.Body)
@@ -731,7 +952,48 @@ func (r *Resolver) streamChat(ctx context.Context, payload gatewayRequest, req c
 		}
 		return err
 	}
-	return flushEvent()
+	if err := flushEvent(); err != nil {
+		return err
+	}
+	if err := forwardOpening(); err != nil {
+		return err
+	}
+	for _, event := range moderation.flush(ctx) {
+		chunkCh <- conversation.StreamChunk(event)
+	}
+	return nil
+}
+
+// sendGateway posts payload to the agent gateway, through the endpoint pool
+// when one is set.
+func (r *Resolver) sendGateway(ctx context.Context, client *http.Client, path string, payload any, header http.Header) (*http.Response, error) {
+	if r.gateways == nil {
+		httpReq, err := newJSONRequestWithContext(ctx, http.MethodPost, r.gatewayBaseURL+path, payload)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			httpReq.Header[key] = values
+		}
+		return client.Do(httpReq)
+	}
+	// The pool may replay the request on another endpoint, so the body is
+	// buffered rather than streamed.
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	header = header.Clone()
+	header.Set("Content-Type", "application/json")
+	return r.gateways.Do(ctx, client, http.MethodPost, path, body, header)
+}
+
+func gatewayHeader(token string) http.Header {
+	header := http.Header{}
+	if strings.TrimSpace(token) != "" {
+		header.Set("Authorization", token)
+	}
+	return header
 }
 
 func newJSONRequestWithContext(ctx context.Context, method, url string, payload any) (*http.Request, error) {
@@ -749,8 +1011,10 @@ func newJSONRequestWithContext(ctx context.Context, method, url string, payload
 	return req, nil
 }
 
-// tryStoreStream attempts to extract final messages from a stream event and persist them.
-func (r *Resolver) tryStoreStream(ctx context.Context, req conversation.ChatRequest, data []byte) (bool, error) {
+// tryStoreStream attempts to extract final messages from a stream event and
+// persist them. It returns the event to forward, rewritten when moderation
+// replaced any of its messages.
+func (r *Resolver) tryStoreStream(ctx context.Context, req conversation.ChatRequest, reply replyInfo, data []byte) (bool, []byte, error) {
 	// data: {"type":"text_delta"|"agent_end"|"done", ...}
 	var envelope struct {
 		Type     string                      `json:"type"`
@@ -761,12 +1025,14 @@ func (r *Resolver) tryStoreStream(ctx context.Context, req conversation.ChatRequ
 	}
 	if err := json.Unmarshal(data, &envelope); err == nil {
 		if (envelope.Type == "agent_end" || envelope.Type == "done") && len(envelope.Messages) > 0 {
-			return true, r.storeRound(ctx, req, envelope.Messages, envelope.Usage, envelope.Usages)
+			moderated, err := r.storeRound(ctx, req, reply, envelope.Messages, envelope.Usage, envelope.Usages)
+			return true, streamEventAfterStore(data, moderated, false), err
 		}
 		if envelope.Type == "done" && len(envelope.Data) > 0 {
 			var resp gatewayResponse
 			if err := json.Unmarshal(envelope.Data, &resp); err == nil && len(resp.Messages) > 0 {
-				return true, r.storeRound(ctx, req, resp.Messages, resp.Usage, resp.Usages)
+				moderated, err := r.storeRound(ctx, req, reply, resp.Messages, resp.Usage, resp.Usages)
+				return true, streamEventAfterStore(data, moderated, true), err
 			}
 		}
 	}
@@ -774,9 +1040,17 @@ func (r *Resolver) tryStoreStream(ctx context.Context, req conversation.ChatRequ
 	// fallback: data: {messages: [...]}
 	var resp gatewayResponse
 	if err := json.Unmarshal(data, &resp); err == nil && len(resp.Messages) > 0 {
-		return true, r.storeRound(ctx, req, resp.Messages, resp.Usage, resp.Usages)
+		moderated, err := r.storeRound(ctx, req, reply, resp.Messages, resp.Usage, resp.Usages)
+		return true, streamEventAfterStore(data, moderated, false), err
+	}
+	return false, data, nil
+}
+
+func streamEventAfterStore(event []byte, moderated []conversation.ModelMessage, underData bool) []byte {
+	if moderated == nil {
+		return event
 	}
-	return false, nil
+	return withStreamMessages(event, moderated, underData)
 }
 
 // routeAndMergeAttachments applies CapabilityFallbackPolicy to split
@@ -1041,9 +1315,12 @@ type messageWithUsage struct {
 	Message           conversation.ModelMessage
 	UsageInputTokens  *int
 	UsageOutputTokens *int
+	CreatedAt         time.Time
 }
 
-func (r *Resolver) loadMessages(ctx context.Context, chatID string, maxContextMinutes int) ([]messageWithUsage, error) {
+// loadMessages returns the active branch of the history. excludeID drops the
+// user message being re-run, which is sent again as the query.
+func (r *Resolver) loadMessages(ctx context.Context, chatID string, maxContextMinutes int, excludeID string) ([]messageWithUsage, error) {
 	if r.messageService == nil {
 		return nil, nil
 	}
@@ -1054,6 +1331,9 @@ func (r *Resolver) loadMessages(ctx context.Context, chatID string, maxContextMi
 	}
 	var result []messageWithUsage
 	for _, m := range msgs {
+		if excludeID != "" && m.ID == excludeID {
+			continue
+		}
 		var mm conversation.ModelMessage
 		if err := json.Unmarshal(m.Content, &mm); err != nil {
 			r.logger.Warn("loadMessages: content unmarshal failed, treating as raw text",
@@ -1071,41 +1351,28 @@ func (r *Resolver) loadMessages(ctx context.Context, chatID string, maxContextMi
 				outputTokens = u.OutputTokens
 			}
 		}
-		result = append(result, messageWithUsage{Message: mm, UsageInputTokens: inputTokens, UsageOutputTokens: outputTokens})
+		result = append(result, messageWithUsage{Message: mm, UsageInputTokens: inputTokens, UsageOutputTokens: outputTokens, CreatedAt: m.CreatedAt})
 	}
 	return result, nil
 }
 
-func estimateMessageTokens(msg conversation.ModelMessage) int {
-	text := msg.TextContent()
-	if len(text) == 0 {
-		data, _ := json.Marshal(msg.Content)
-		return len(data) / 4
-	}
-	return len(text) / 4
+func trimMessagesByTokens(counter tokenCounter, messages []messageWithUsage, maxTokens int) []conversation.ModelMessage {
+	return modelMessagesOf(messages[historyCutoff(counter, messages, maxTokens):])
 }
 
-func trimMessagesByTokens(messages []messageWithUsage, maxTokens int) []conversation.ModelMessage {
+// historyCutoff returns the index of the first message that fits maxTokens.
+// Messages before it are dropped from the context window.
+func historyCutoff(counter tokenCounter, messages []messageWithUsage, maxTokens int) int {
 	if maxTokens <= 0 || len(messages) == 0 {
-		result := make([]conversation.ModelMessage, len(messages))
-		for i, m := range messages {
-			result[i] = m.Message
-		}
-		return result
+		return 0
 	}
 
-	// Scan from newest to oldest, accumulating per-message outputTokens from
-	// stored usage data. Messages without usage (user / tool) are included for
-	// free — the outputTokens of surrounding assistant turns already account
-	// for the context they consumed.
+	// Scan from newest to oldest, counting every message with the chat
+	// model's tokenizer.
 	totalTokens := 0
 	cutoff := 0
-	messagesWithUsage := 0
 	for i := len(messages) - 1; i >= 0; i-- {
-		if messages[i].UsageOutputTokens != nil {
-			totalTokens += *messages[i].UsageOutputTokens
-			messagesWithUsage++
-		}
+		totalTokens += counter.message(messages[i].Message)
 		if totalTokens > maxTokens {
 			cutoff = i + 1
 			break
@@ -1121,16 +1388,18 @@ func trimMessagesByTokens(messages []messageWithUsage, maxTokens int) []conversa
 
 	slog.Debug("trimMessagesByTokens",
 		slog.Int("total_messages", len(messages)),
-		slog.Int("messages_with_usage", messagesWithUsage),
-		slog.Int("accumulated_output_tokens", totalTokens),
+		slog.Int("counted_tokens", totalTokens),
 		slog.Int("max_tokens", maxTokens),
 		slog.Int("cutoff_index", cutoff),
 		slog.Int("kept_messages", len(messages)-cutoff),
 	)
+	return cutoff
+}
 
-	result := make([]conversation.ModelMessage, 0, len(messages)-cutoff)
-	for _, m := range messages[cutoff:] {
-		result = append(result, m.Message)
+func modelMessagesOf(messages []messageWithUsage) []conversation.ModelMessage {
+	result := make([]conversation.ModelMessage, len(messages))
+	for i, m := range messages {
+		result[i] = m.Message
 	}
 	return result
 }
@@ -1148,47 +1417,45 @@ func (r *Resolver) loadMemoryContextMessage(ctx context.Context, req conversatio
 		return nil
 	}
 
-	results := make([]memoryContextItem, 0, memoryContextLimitPerScope)
+	// Search every readable scope; more specific scopes rank first, scores
+	// order results within a scope.
+	scopes := r.memoryReadScopes(ctx, req)
+	results := make([]memoryContextItem, 0, memoryContextLimitPerScope*len(scopes))
 	seen := map[string]struct{}{}
-	resp, err := r.memoryService.Search(ctx, memory.SearchRequest{
-		Query: req.Query,
-		BotID: req.BotID,
-		Limit: memoryContextLimitPerScope,
-		Filters: map[string]any{
-			"namespace": sharedMemoryNamespace,
-			"scopeId":   req.BotID,
-			"bot_id":    req.BotID,
-		},
-		NoStats: true,
-	})
-	if err != nil {
-		r.logger.Warn("memory search for context failed",
-			slog.String("namespace", sharedMemoryNamespace),
-			slog.Any("error", err),
-		)
-		return nil
-	}
-	for _, item := range resp.Results {
-		key := strings.TrimSpace(item.ID)
-		if key == "" {
-			key = sharedMemoryNamespace + ":" + strings.TrimSpace(item.Memory)
-		}
-		if key == "" {
+	for _, scope := range scopes {
+		resp, err := r.memoryService.Search(ctx, memory.SearchRequest{
+			Query:   req.Query,
+			BotID:   req.BotID,
+			Limit:   memoryContextLimitPerScope,
+			Filters: scope.Filters(req.BotID),
+			NoStats: true,
+		})
+		if err != nil {
+			r.logger.Warn("memory search for context failed",
+				slog.String("namespace", scope.Namespace),
+				slog.Any("error", err),
+			)
 			continue
 		}
-		if _, ok := seen[key]; ok {
-			continue
+		items := resp.Results
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Score > items[j].Score
+		})
+		for _, item := range items {
+			key := strings.TrimSpace(item.ID)
+			if key == "" {
+				key = scope.Namespace + ":" + strings.TrimSpace(item.Memory)
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			results = append(results, memoryContextItem{Namespace: scope.Namespace, Item: item})
 		}
-		seen[key] = struct{}{}
-		results = append(results, memoryContextItem{Namespace: sharedMemoryNamespace, Item: item})
 	}
 	if len(results) == 0 {
 		return nil
 	}
-
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Item.Score > results[j].Item.Score
-	})
 	if len(results) > memoryContextMaxItems {
 		results = results[:memoryContextMaxItems]
 	}
@@ -1249,34 +1516,59 @@ func (r *Resolver) persistUserMessage(ctx context.Context, req conversation.Chat
 		ExternalMessageID:       req.ExternalMessageID,
 		Role:                    "user",
 		Content:                 content,
-		Metadata:                buildRouteMetadata(req),
+		Metadata:                userMessageMetadata(req),
 		Assets:                  chatAttachmentsToAssetRefs(req.Attachments),
 	})
 	return err
 }
 
-func (r *Resolver) storeRound(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage, usage json.RawMessage, usages []json.RawMessage) error {
+// storeRound stores a finished round. It returns the messages rewritten by
+// re-hydration or moderation, or nil when neither changed anything.
+func (r *Resolver) storeRound(ctx context.Context, req conversation.ChatRequest, reply replyInfo, messages []conversation.ModelMessage, usage json.RawMessage, usages []json.RawMessage) ([]conversation.ModelMessage, error) {
+	if r.quotas != nil {
+		// Rounds without reported usage still count as a request.
+		total, _ := conversation.ParseUsage(usage, usages)
+		r.quotas.RecordUsage(context.WithoutCancel(ctx), req, reply.model.id, total)
+	}
+	r.recordRound(ctx, req, reply, gatewayResponse{Messages: messages, Usage: usage, Usages: usages})
+	rewritten := r.rehydrateMessages(ctx, req.BotID, messages)
+	if rewritten != nil {
+		messages = rewritten
+	}
+	moderated := r.moderateOutput(ctx, req, messages)
+	if moderated.Messages != nil {
+		messages = moderated.Messages
+		rewritten = moderated.Messages
+	}
 	fullRound := make([]conversation.ModelMessage, 0, len(messages))
 	roundUsages := make([]json.RawMessage, 0, len(usages))
+	var roundModeration map[int]map[string]any
 	for i, m := range messages {
 		if req.UserMessagePersisted && m.Role == "user" && strings.TrimSpace(m.TextContent()) == strings.TrimSpace(req.Query) {
 			continue
 		}
+		if verdict := moderated.Metadata[i]; verdict != nil {
+			if roundModeration == nil {
+				roundModeration = map[int]map[string]any{}
+			}
+			roundModeration[len(fullRound)] = verdict
+		}
 		fullRound = append(fullRound, m)
 		if i < len(usages) {
 			roundUsages = append(roundUsages, usages[i])
 		}
 	}
 	if len(fullRound) == 0 {
-		return nil
+		return rewritten, nil
 	}
 
-	r.storeMessages(ctx, req, fullRound, usage, roundUsages)
-	go r.storeMemory(context.WithoutCancel(ctx), req.BotID, fullRound)
-	return nil
+	reply.moderation = roundModeration
+	r.storeMessages(ctx, req, reply, fullRound, usage, roundUsages)
+	go r.storeMemory(context.WithoutCancel(ctx), req, fullRound)
+	return rewritten, nil
 }
 
-func (r *Resolver) storeMessages(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage, usage json.RawMessage, usages []json.RawMessage) {
+func (r *Resolver) storeMessages(ctx context.Context, req conversation.ChatRequest, reply replyInfo, messages []conversation.ModelMessage, usage json.RawMessage, usages []json.RawMessage) {
 	if r.messageService == nil {
 		return
 	}
@@ -1284,20 +1576,29 @@ func (r *Resolver) storeMessages(ctx context.Context, req conversation.ChatReque
 		return
 	}
 	meta := buildRouteMetadata(req)
+	replyMeta := meta
+	if answeredBy := answeredByMetadata(reply.model); answeredBy != nil {
+		replyMeta = make(map[string]any, len(meta)+len(answeredBy))
+		for k, v := range meta {
+			replyMeta[k] = v
+		}
+		for k, v := range answeredBy {
+			replyMeta[k] = v
+		}
+	}
 	senderChannelIdentityID, senderUserID := r.resolvePersistSenderIDs(ctx, req)
 
-	// Determine the last assistant message index for outbound asset attachment.
+	// Determine the last assistant message index for outbound asset attachment
+	// and the round latency.
 	lastAssistantIdx := -1
-	if req.OutboundAssetCollector != nil {
-		for i := len(messages) - 1; i >= 0; i-- {
-			if messages[i].Role == "assistant" {
-				lastAssistantIdx = i
-				break
-			}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" {
+			lastAssistantIdx = i
+			break
 		}
 	}
 	var outboundAssets []messagepkg.AssetRef
-	if lastAssistantIdx >= 0 {
+	if lastAssistantIdx >= 0 && req.OutboundAssetCollector != nil {
 		outboundAssets = outboundAssetRefsToMessageRefs(req.OutboundAssetCollector())
 	}
 
@@ -1312,12 +1613,15 @@ func (r *Resolver) storeMessages(ctx context.Context, req conversation.ChatReque
 		externalMessageID := ""
 		sourceReplyToMessageID := ""
 		assets := []messagepkg.AssetRef(nil)
+		messageMeta := replyMeta
 		if msg.Role == "user" {
+			messageMeta = meta
 			messageSenderChannelIdentityID = senderChannelIdentityID
 			messageSenderUserID = senderUserID
 			externalMessageID = req.ExternalMessageID
 			if strings.TrimSpace(msg.TextContent()) == strings.TrimSpace(req.Query) {
 				assets = chatAttachmentsToAssetRefs(req.Attachments)
+				messageMeta = userMessageMetadata(req)
 			}
 		} else if strings.TrimSpace(req.ExternalMessageID) != "" {
 			sourceReplyToMessageID = req.ExternalMessageID
@@ -1325,6 +1629,15 @@ func (r *Resolver) storeMessages(ctx context.Context, req conversation.ChatReque
 		if i == lastAssistantIdx && len(outboundAssets) > 0 {
 			assets = append(assets, outboundAssets...)
 		}
+		if i == lastAssistantIdx && reply.latency > 0 {
+			messageMeta = withLatencyMetadata(messageMeta, reply.latency)
+		}
+		if i == lastAssistantIdx && reply.cancelled {
+			messageMeta = withCancelledMetadata(messageMeta)
+		}
+		if verdict := reply.moderation[i]; verdict != nil {
+			messageMeta = withModerationMetadata(messageMeta, verdict)
+		}
 		var msgUsage json.RawMessage
 		if i < len(usages) && len(usages[i]) > 0 && !isJSONNull(usages[i]) {
 			msgUsage = usages[i]
@@ -1341,7 +1654,7 @@ func (r *Resolver) storeMessages(ctx context.Context, req conversation.ChatReque
 			SourceReplyToMessageID:  sourceReplyToMessageID,
 			Role:                    msg.Role,
 			Content:                 content,
-			Metadata:                meta,
+			Metadata:                messageMeta,
 			Usage:                   msgUsage,
 			Assets:                  assets,
 		}); err != nil {
@@ -1425,6 +1738,16 @@ func buildRouteMetadata(req conversation.ChatRequest) map[string]any {
 	return meta
 }
 
+// userMessageMetadata is the route metadata of the user message of req, with
+// the moderation verdict on its query.
+func userMessageMetadata(req conversation.ChatRequest) map[string]any {
+	meta := buildRouteMetadata(req)
+	if req.InputModeration != nil {
+		meta = withModerationMetadata(meta, req.InputModeration)
+	}
+	return meta
+}
+
 func (r *Resolver) resolvePersistSenderIDs(ctx context.Context, req conversation.ChatRequest) (string, string) {
 	channelIdentityID := strings.TrimSpace(req.SourceChannelIdentityID)
 	userID := strings.TrimSpace(req.UserID)
@@ -1526,11 +1849,12 @@ func (r *Resolver) resolveDisplayName(ctx context.Context, req conversation.Chat
 	return "User"
 }
 
-func (r *Resolver) storeMemory(ctx context.Context, botID string, messages []conversation.ModelMessage) {
+func (r *Resolver) storeMemory(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) {
 	if r.memoryService == nil {
 		return
 	}
-	if strings.TrimSpace(botID) == "" {
+	botID := strings.TrimSpace(req.BotID)
+	if botID == "" {
 		return
 	}
 	memMsgs := make([]memory.Message, 0, len(messages))
@@ -1548,23 +1872,18 @@ func (r *Resolver) storeMemory(ctx context.Context, botID string, messages []con
 	if len(memMsgs) == 0 {
 		return
 	}
-	r.addMemory(ctx, botID, memMsgs, sharedMemoryNamespace, botID)
+	r.addMemory(ctx, botID, memMsgs, r.memoryWriteScope(ctx, req))
 }
 
-func (r *Resolver) addMemory(ctx context.Context, botID string, msgs []memory.Message, namespace, scopeID string) {
-	filters := map[string]any{
-		"namespace": namespace,
-		"scopeId":   scopeID,
-		"bot_id":    botID,
-	}
+func (r *Resolver) addMemory(ctx context.Context, botID string, msgs []memory.Message, scope memory.Scope) {
 	if _, err := r.memoryService.Add(ctx, memory.AddRequest{
 		Messages: msgs,
 		BotID:    botID,
-		Filters:  filters,
+		Filters:  scope.Filters(botID),
 	}); err != nil {
 		r.logger.Warn("store memory failed",
-			slog.String("namespace", namespace),
-			slog.String("scope_id", scopeID),
+			slog.String("namespace", scope.Namespace),
+			slog.String("scope_id", scope.ScopeID),
 			slog.Any("error", err),
 		)
 	}
@@ -1905,6 +2224,34 @@ func FormatUserHeaderFromMeta(meta UserMessageMeta, query string) string {
 	return sb.String()
 }
 
+// SplitUserHeader splits the YAML front-matter written by
+// FormatUserHeaderFromMeta from the query of a stored user message. Only the
+// flat key: value lines are returned; list items are skipped.
+func SplitUserHeader(text string) (map[string]string, string) {
+	const fence = "---\n"
+	if !strings.HasPrefix(text, fence) {
+		return nil, text
+	}
+	end := strings.Index(text[len(fence):], "\n"+fence)
+	if end < 0 {
+		return nil, text
+	}
+	block := text[len(fence) : len(fence)+end]
+	query := text[len(fence)+end+1+len(fence):]
+	fields := map[string]string{}
+	for _, line := range strings.Split(block, "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || strings.HasPrefix(key, " ") {
+			continue
+		}
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+		}
+		fields[key] = value
+	}
+	return fields, query
+}
+
 func writeYAMLString(sb *strings.Builder, key, value string) {
 	sb.WriteString(key)
 	sb.WriteString(": ")
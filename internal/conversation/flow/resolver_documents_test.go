@@ -0,0 +1,69 @@
+package flow
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/docextract"
+	"github.com/memohai/memoh/internal/models"
+)
+
+func TestExtractDocumentTexts_TextOnlyModel(t *testing.T) {
+	resolver := &Resolver{
+		logger: slog.Default(),
+		assetLoader: &fakeGatewayAssetLoader{
+			openFn: func(ctx context.Context, botID, contentHash string) (io.ReadCloser, string, error) {
+				if botID != "bot-1" || contentHash != "hash-1" {
+					t.Fatalf("unexpected asset request: %s/%s", botID, contentHash)
+				}
+				return io.NopCloser(strings.NewReader("<p>Quarterly <b>report</b></p>")), "text/html", nil
+			},
+		},
+		docExtractor: docextract.NewService(nil, docextract.Config{}),
+	}
+	model := models.GetResponse{Model: models.Model{InputModalities: []string{models.ModelInputText}}}
+	req := conversation.ChatRequest{
+		BotID: "bot-1",
+		Attachments: []conversation.ChatAttachment{
+			{Type: "file", ContentHash: "hash-1", Name: "report.html", Mime: "text/html"},
+			{Type: "file", Base64: base64.StdEncoding.EncodeToString([]byte("plain notes")), Name: "notes.txt"},
+			{Type: "image", ContentHash: "img-1", Mime: "image/png"},
+		},
+	}
+
+	docs := resolver.extractDocumentTexts(context.Background(), model, req)
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d: %#v", len(docs), docs)
+	}
+	query := appendDocumentTexts("summarize these", docs)
+	want := "summarize these\n\n" +
+		"<document name=\"report.html\" format=\"html\">\nQuarterly report\n</document>\n\n" +
+		"<document name=\"notes.txt\" format=\"text\">\nplain notes\n</document>"
+	if query != want {
+		t.Fatalf("query = %q, want %q", query, want)
+	}
+}
+
+func TestExtractDocumentTexts_SkipsFileCapableModel(t *testing.T) {
+	resolver := &Resolver{
+		logger:       slog.Default(),
+		docExtractor: docextract.NewService(nil, docextract.Config{}),
+	}
+	model := models.GetResponse{Model: models.Model{InputModalities: []string{models.ModelInputText, models.ModelInputFile}}}
+	req := conversation.ChatRequest{
+		Attachments: []conversation.ChatAttachment{
+			{Type: "file", Base64: base64.StdEncoding.EncodeToString([]byte("plain notes")), Name: "notes.txt"},
+		},
+	}
+	if docs := resolver.extractDocumentTexts(context.Background(), model, req); len(docs) != 0 {
+		t.Fatalf("expected no extraction for file-capable model, got %#v", docs)
+	}
+	if got := appendDocumentTexts("hi", nil); got != "hi" {
+		t.Fatalf("expected query unchanged, got %q", got)
+	}
+}
@@ -49,6 +49,18 @@ func (s *blockingMessageService) ListBefore(ctx context.Context, botID string, b
 	return nil, nil
 }
 
+func (s *blockingMessageService) ListByIDs(ctx context.Context, botID string, ids []string) ([]messagepkg.Message, error) {
+	return nil, nil
+}
+
+func (s *blockingMessageService) ListSourceIDs(ctx context.Context, botID string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (s *blockingMessageService) ListSenderIDs(ctx context.Context, botID string) (map[string]bool, error) {
+	return nil, nil
+}
+
 func (s *blockingMessageService) DeleteByBot(ctx context.Context, botID string) error {
 	return nil
 }
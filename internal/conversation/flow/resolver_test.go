@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/gateway"
 	"github.com/memohai/memoh/internal/models"
 )
 
@@ -105,6 +106,39 @@ func TestPostTriggerSchedule_Endpoint(t *testing.T) {
 	}
 }
 
+func TestPostChat_UsesGatewayPool(t *testing.T) {
+	var capturedAuth, capturedType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedAuth = r.Header.Get("Authorization")
+		capturedType = r.Header.Get("Content-Type")
+		json.NewEncoder(w).Encode(gatewayResponse{
+			Messages: []conversation.ModelMessage{{Role: "assistant", Content: conversation.NewTextContent("ok")}},
+		})
+	}))
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	resolver := &Resolver{
+		gatewayBaseURL: "http://127.0.0.1:1",
+		httpClient:     &http.Client{Timeout: 5 * time.Second},
+		logger:         slog.Default(),
+	}
+	resolver.SetGatewayPool(gateway.NewPool(slog.Default(), []string{downURL, srv.URL}, gateway.Options{}))
+
+	resp, err := resolver.postChat(context.Background(), gatewayRequest{}, "Bearer test-token")
+	if err != nil {
+		t.Fatalf("postChat returned error: %v", err)
+	}
+	if len(resp.Messages) != 1 {
+		t.Fatalf("expected reply from the live endpoint, got %+v", resp)
+	}
+	if capturedAuth != "Bearer test-token" || capturedType != "application/json" {
+		t.Fatalf("unexpected headers %q %q", capturedAuth, capturedType)
+	}
+}
+
 func TestPostTriggerSchedule_NoAuth(t *testing.T) {
 	var capturedAuth string
 
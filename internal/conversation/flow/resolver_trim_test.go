@@ -1,12 +1,24 @@
 package flow
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/models"
+	"github.com/memohai/memoh/internal/tokenizer"
 )
 
-func intPtr(v int) *int { return &v }
+// runeTokenizer costs one token per character, which keeps budgets in tests
+// easy to follow.
+type runeTokenizer struct{}
+
+func (runeTokenizer) Count(text string) int { return utf8.RuneCountInString(text) }
+
+func runeCounter() tokenCounter {
+	return tokenCounter{tok: runeTokenizer{}, imageTokens: 100}
+}
 
 func TestTrimMessagesByTokens_DropsLeadingOrphanTool(t *testing.T) {
 	t.Parallel()
@@ -32,7 +44,6 @@ func TestTrimMessagesByTokens_DropsLeadingOrphanTool(t *testing.T) {
 					},
 				},
 			},
-			UsageOutputTokens: intPtr(50),
 		},
 		{
 			Message: conversation.ModelMessage{
@@ -46,15 +57,14 @@ func TestTrimMessagesByTokens_DropsLeadingOrphanTool(t *testing.T) {
 				Role:    "assistant",
 				Content: conversation.NewTextContent("done"),
 			},
-			UsageOutputTokens: intPtr(60),
 		},
 	}
 
-	// Budget 70: assistant(60) fits, adding assistant-tool-call(50) exceeds →
-	// cutoff lands on the tool message which must be skipped.
-	trimmed := trimMessagesByTokens(messages, 70)
-	if len(trimmed) == 0 {
-		t.Fatal("expected non-empty trimmed messages")
+	// Budget 20: assistant(8) and tool(5) fit, adding the tool call (23)
+	// exceeds → cutoff lands on the tool message which must be skipped.
+	trimmed := trimMessagesByTokens(runeCounter(), messages, 20)
+	if len(trimmed) != 1 {
+		t.Fatalf("expected only the last message to be kept, got %d", len(trimmed))
 	}
 	if trimmed[0].Role == "tool" {
 		t.Fatal("expected first trimmed message not to be tool")
@@ -79,7 +89,6 @@ func TestTrimMessagesByTokens_KeepsToolWhenPaired(t *testing.T) {
 					},
 				},
 			},
-			UsageOutputTokens: intPtr(10),
 		},
 		{
 			Message: conversation.ModelMessage{
@@ -90,7 +99,7 @@ func TestTrimMessagesByTokens_KeepsToolWhenPaired(t *testing.T) {
 		},
 	}
 
-	trimmed := trimMessagesByTokens(messages, 100)
+	trimmed := trimMessagesByTokens(runeCounter(), messages, 100)
 	if len(trimmed) != 2 {
 		t.Fatalf("expected 2 messages, got %d", len(trimmed))
 	}
@@ -99,16 +108,128 @@ func TestTrimMessagesByTokens_KeepsToolWhenPaired(t *testing.T) {
 	}
 }
 
-func TestTrimMessagesByTokens_NoUsage_KeepsAll(t *testing.T) {
+func TestTrimMessagesByTokens_CountsMessagesWithoutUsage(t *testing.T) {
 	t.Parallel()
 
 	messages := []messageWithUsage{
+		{Message: conversation.ModelMessage{Role: "user", Content: conversation.NewTextContent(strings.Repeat("a", 40))}},
 		{Message: conversation.ModelMessage{Role: "user", Content: conversation.NewTextContent("hello")}},
 		{Message: conversation.ModelMessage{Role: "assistant", Content: conversation.NewTextContent("hi")}},
 	}
 
-	trimmed := trimMessagesByTokens(messages, 10)
-	if len(trimmed) != 2 {
-		t.Fatalf("messages without outputTokens should all be kept, got %d", len(trimmed))
+	trimmed := trimMessagesByTokens(runeCounter(), messages, 20)
+	if len(trimmed) != 2 || trimmed[0].TextContent() != "hello" {
+		t.Fatalf("expected the long user message to be dropped, got %d messages", len(trimmed))
+	}
+}
+
+func TestTrimMessagesByTokens_BudgetsCJKHistory(t *testing.T) {
+	t.Parallel()
+
+	// 400 Han characters are 1200 bytes; the old len/4 estimate put them at
+	// 300 tokens and kept far more history than fits.
+	long := strings.Repeat("这是一段很长的中文对话内容", 30)
+	messages := []messageWithUsage{
+		{Message: conversation.ModelMessage{Role: "user", Content: conversation.NewTextContent(long)}},
+		{Message: conversation.ModelMessage{Role: "assistant", Content: conversation.NewTextContent(long)}},
+		{Message: conversation.ModelMessage{Role: "user", Content: conversation.NewTextContent("好的")}},
+	}
+	counter := newTokenCounter(tokenizer.NewRegistry(), models.GetResponse{
+		ModelID: "claude-sonnet",
+		Model:   models.Model{ClientType: models.ClientTypeAnthropicMessages},
+	})
+
+	trimmed := trimMessagesByTokens(counter, messages, 400)
+	if len(trimmed) != 1 {
+		t.Fatalf("expected only the short message to fit, got %d", len(trimmed))
+	}
+}
+
+func TestTokenCounter_MessageParts(t *testing.T) {
+	t.Parallel()
+
+	counter := runeCounter()
+	parts := conversation.ModelMessage{
+		Role:    "user",
+		Content: []byte(`[{"type":"text","text":"look"},{"type":"image","url":"https://example.com/a.png"}]`),
+	}
+	if got, want := counter.message(parts), messageTokenOverhead+4+100; got != want {
+		t.Fatalf("expected text plus image cost %d, got %d", want, got)
+	}
+
+	atts := []any{
+		gatewayAttachment{Type: "image", Transport: gatewayTransportInlineDataURL, Payload: "data:image/png;base64,AAAA"},
+		gatewayAttachment{Type: "file", Transport: gatewayTransportToolFileRef, Payload: "/data/a.pdf"},
+	}
+	if got := counter.attachments(atts); got != 100 {
+		t.Fatalf("expected only the native image to cost tokens, got %d", got)
+	}
+}
+
+func TestTokenCounter_GatewayToolMessages(t *testing.T) {
+	t.Parallel()
+
+	counter := runeCounter()
+	input := `{"query":"weather in Paris"}`
+	call := conversation.ModelMessage{
+		Role:    "assistant",
+		Content: []byte(`[{"type":"text","text":"ok"},{"type":"tool-call","toolCallId":"call_1","toolName":"web_search","input":` + input + `}]`),
+	}
+	if got, want := counter.message(call), messageTokenOverhead+2+toolCallTokenOverhead+len("web_search")+len(input); got != want {
+		t.Fatalf("expected tool call cost %d, got %d", want, got)
+	}
+
+	output := `{"type":"json","value":{"results":["sunny, 21C"]}}`
+	result := conversation.ModelMessage{
+		Role:    "tool",
+		Content: []byte(`[{"type":"tool-result","toolCallId":"call_1","toolName":"web_search","output":` + output + `}]`),
+	}
+	if got, want := counter.message(result), messageTokenOverhead+len("web_search")+len(output); got != want {
+		t.Fatalf("expected tool result cost %d, got %d", want, got)
+	}
+
+	unknown := `{"type":"source","sourceType":"url","url":"https://example.com"}`
+	other := conversation.ModelMessage{Role: "assistant", Content: []byte(`[` + unknown + `]`)}
+	if got, want := counter.message(other), messageTokenOverhead+len(unknown); got != want {
+		t.Fatalf("expected unknown part cost %d, got %d", want, got)
+	}
+}
+
+func TestTrimMessagesByTokens_CountsGatewayToolResults(t *testing.T) {
+	t.Parallel()
+
+	big := strings.Repeat("x", 500)
+	messages := []messageWithUsage{
+		{Message: conversation.ModelMessage{Role: "user", Content: conversation.NewTextContent("search")}},
+		{Message: conversation.ModelMessage{
+			Role:    "assistant",
+			Content: []byte(`[{"type":"tool-call","toolCallId":"call_1","toolName":"read","input":{"path":"/data/a.txt"}}]`),
+		}},
+		{Message: conversation.ModelMessage{
+			Role:    "tool",
+			Content: []byte(`[{"type":"tool-result","toolCallId":"call_1","toolName":"read","output":{"type":"text","value":"` + big + `"}}]`),
+		}},
+		{Message: conversation.ModelMessage{Role: "assistant", Content: conversation.NewTextContent("done")}},
+	}
+
+	// The 500 character tool result does not fit a budget of 200, so the
+	// history starts after it rather than with an orphaned tool message.
+	trimmed := trimMessagesByTokens(runeCounter(), messages, 200)
+	if len(trimmed) != 1 || trimmed[0].TextContent() != "done" {
+		t.Fatalf("expected only the final reply to fit, got %d messages", len(trimmed))
+	}
+}
+
+func TestTokenCounter_SystemPromptReserveCountsEnabledSkills(t *testing.T) {
+	t.Parallel()
+
+	counter := runeCounter()
+	skills := []gatewaySkill{
+		{Name: "a", Description: "bb", Content: strings.Repeat("c", 100)},
+		{Name: "d", Description: "ee", Content: strings.Repeat("f", 100)},
+	}
+	got := counter.systemPromptReserve(skills, []string{"a"}, nil)
+	if want := systemPromptBaseReserve + 3 + 3 + 100; got != want {
+		t.Fatalf("expected %d, got %d", want, got)
 	}
 }
@@ -0,0 +1,175 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/memory"
+)
+
+const (
+	// historySummaryReserve is the history budget set aside for the summary
+	// once trimming starts dropping messages.
+	historySummaryReserve = 1024
+	// historySummaryMinBatch is how many newly dropped messages it takes to
+	// regenerate an existing summary. Smaller spans wait for the next turn.
+	historySummaryMinBatch = 6
+	historySummaryTimeout  = 60 * time.Second
+	// historySummaryInsertAttempts bounds how often a new version is retried
+	// when a concurrent refresh took the same version number.
+	historySummaryInsertAttempts = 3
+	historySummaryHeader         = "Summary of the earlier conversation (older messages were condensed to fit the context window):\n"
+)
+
+// historySummarizer condenses dropped history. memory.LLM satisfies it.
+type historySummarizer interface {
+	Summarize(ctx context.Context, req memory.SummarizeRequest) (memory.SummarizeResponse, error)
+}
+
+// historySummaryStore persists versioned summaries. *sqlc.Queries satisfies it.
+type historySummaryStore interface {
+	GetLatestHistorySummary(ctx context.Context, arg sqlc.GetLatestHistorySummaryParams) (sqlc.BotHistorySummary, error)
+	CreateHistorySummary(ctx context.Context, arg sqlc.CreateHistorySummaryParams) (sqlc.BotHistorySummary, error)
+}
+
+// historySummaryMessage returns the rolling summary to inject ahead of the
+// kept history, given the messages trimming dropped. Summaries are kept per
+// route. The first summary is generated inline so no context is lost; later
+// ones are regenerated in the background once enough new messages slid out
+// of the window, and the latest stored version is used meanwhile.
+func (r *Resolver) historySummaryMessage(ctx context.Context, req conversation.ChatRequest, dropped []messageWithUsage) *conversation.ModelMessage {
+	if r.summarizer == nil || r.summaries == nil || len(dropped) == 0 {
+		return nil
+	}
+	botID, err := db.ParseUUID(req.BotID)
+	if err != nil {
+		return nil
+	}
+	var routeID pgtype.UUID
+	if strings.TrimSpace(req.RouteID) != "" {
+		if routeID, err = db.ParseUUID(req.RouteID); err != nil {
+			return nil
+		}
+	}
+
+	latest, err := r.summaries.GetLatestHistorySummary(ctx, sqlc.GetLatestHistorySummaryParams{BotID: botID, RouteID: routeID})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		r.logger.Warn("load history summary failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+		return nil
+	}
+	hasLatest := err == nil
+
+	var coveredUntil time.Time
+	if hasLatest && latest.CoveredUntil.Valid {
+		coveredUntil = latest.CoveredUntil.Time
+	}
+	pending, pendingUntil := pendingSummaryMessages(dropped, coveredUntil)
+
+	if !hasLatest {
+		if len(pending) == 0 {
+			return nil
+		}
+		inlineCtx, cancel := context.WithTimeout(ctx, historySummaryTimeout)
+		created, err := r.refreshHistorySummary(inlineCtx, req.BotID, botID, routeID, "", pending, pendingUntil)
+		cancel()
+		if err != nil {
+			r.logger.Warn("summarize history failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+			return nil
+		}
+		return historySummaryModelMessage(created.Content)
+	}
+
+	if len(pending) >= historySummaryMinBatch {
+		key := req.BotID + ":" + strings.TrimSpace(req.RouteID)
+		if _, running := r.summaryRefreshes.LoadOrStore(key, struct{}{}); !running {
+			go func() {
+				defer r.summaryRefreshes.Delete(key)
+				bgCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), historySummaryTimeout)
+				defer cancel()
+				if _, err := r.refreshHistorySummary(bgCtx, req.BotID, botID, routeID, latest.Content, pending, pendingUntil); err != nil {
+					r.logger.Warn("refresh history summary failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+				}
+			}()
+		}
+	}
+	return historySummaryModelMessage(latest.Content)
+}
+
+// refreshHistorySummary folds pending into previous and stores the result as
+// the next version. Versions are unique per route; when a concurrent refresh
+// stored the same version first, the insert is retried with the next one.
+func (r *Resolver) refreshHistorySummary(ctx context.Context, botIDText string, botID, routeID pgtype.UUID, previous string, pending []memory.Message, coveredUntil time.Time) (sqlc.BotHistorySummary, error) {
+	// The summary is stored as the model wrote it, placeholders included.
+	previous, redacted, err := r.redactSummaryInput(ctx, botIDText, previous, pending)
+	if err != nil {
+		return sqlc.BotHistorySummary{}, err
+	}
+	resp, err := r.summarizer.Summarize(memory.WithBotID(ctx, botIDText), memory.SummarizeRequest{
+		PreviousSummary: previous,
+		Messages:        redacted,
+	})
+	if err != nil {
+		return sqlc.BotHistorySummary{}, err
+	}
+	params := sqlc.CreateHistorySummaryParams{
+		BotID:        botID,
+		RouteID:      routeID,
+		Content:      resp.Summary,
+		CoveredUntil: pgtype.Timestamptz{Time: coveredUntil, Valid: true},
+		MessageCount: int32(len(pending)),
+	}
+	var created sqlc.BotHistorySummary
+	for attempt := 0; attempt < historySummaryInsertAttempts; attempt++ {
+		created, err = r.summaries.CreateHistorySummary(ctx, params)
+		if !db.IsUniqueViolation(err) {
+			break
+		}
+	}
+	return created, err
+}
+
+// pendingSummaryMessages returns the text of dropped messages newer than
+// coveredUntil and the timestamp of the last dropped message.
+func pendingSummaryMessages(dropped []messageWithUsage, coveredUntil time.Time) ([]memory.Message, time.Time) {
+	var pending []memory.Message
+	var until time.Time
+	for _, m := range dropped {
+		if !coveredUntil.IsZero() && !m.CreatedAt.After(coveredUntil) {
+			continue
+		}
+		if m.CreatedAt.After(until) {
+			until = m.CreatedAt
+		}
+		text := strings.TrimSpace(m.Message.TextContent())
+		if text == "" {
+			continue
+		}
+		role := strings.TrimSpace(m.Message.Role)
+		if role == "" {
+			role = "assistant"
+		}
+		pending = append(pending, memory.Message{Role: role, Content: text})
+	}
+	return pending, until
+}
+
+func historySummaryModelMessage(summary string) *conversation.ModelMessage {
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return nil
+	}
+	msg := conversation.ModelMessage{
+		Role:    "user",
+		Content: conversation.NewTextContent(historySummaryHeader + summary),
+	}
+	return &msg
+}
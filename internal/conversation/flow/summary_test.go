@@ -0,0 +1,181 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/memory"
+)
+
+type fakeSummaryStore struct {
+	mu      sync.Mutex
+	latest  *sqlc.BotHistorySummary
+	created chan sqlc.CreateHistorySummaryParams
+	// conflicts is how many inserts fail as if a concurrent refresh took
+	// the version first.
+	conflicts   int
+	inserts     int
+	hadDeadline bool
+}
+
+func (s *fakeSummaryStore) GetLatestHistorySummary(ctx context.Context, arg sqlc.GetLatestHistorySummaryParams) (sqlc.BotHistorySummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.latest == nil {
+		return sqlc.BotHistorySummary{}, pgx.ErrNoRows
+	}
+	return *s.latest, nil
+}
+
+func (s *fakeSummaryStore) CreateHistorySummary(ctx context.Context, arg sqlc.CreateHistorySummaryParams) (sqlc.BotHistorySummary, error) {
+	s.mu.Lock()
+	s.inserts++
+	_, s.hadDeadline = ctx.Deadline()
+	if s.conflicts > 0 {
+		s.conflicts--
+		s.mu.Unlock()
+		return sqlc.BotHistorySummary{}, &pgconn.PgError{Code: "23505"}
+	}
+	version := int32(1)
+	if s.latest != nil {
+		version = s.latest.Version + 1
+	}
+	s.latest = &sqlc.BotHistorySummary{Version: version, Content: arg.Content, CoveredUntil: arg.CoveredUntil}
+	row := *s.latest
+	s.mu.Unlock()
+	if s.created != nil {
+		s.created <- arg
+	}
+	return row, nil
+}
+
+type fakeSummarizer struct {
+	mu       sync.Mutex
+	requests []memory.SummarizeRequest
+}
+
+func (f *fakeSummarizer) Summarize(ctx context.Context, req memory.SummarizeRequest) (memory.SummarizeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	return memory.SummarizeResponse{Summary: fmt.Sprintf("summary v%d of %d messages", len(f.requests), len(req.Messages))}, nil
+}
+
+func droppedHistory(start time.Time, n int) []messageWithUsage {
+	out := make([]messageWithUsage, 0, n)
+	for i := 0; i < n; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		out = append(out, messageWithUsage{
+			Message:   conversation.ModelMessage{Role: role, Content: conversation.NewTextContent(fmt.Sprintf("message %d", i))},
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return out
+}
+
+const summaryTestBotID = "11111111-1111-1111-1111-111111111111"
+
+func TestHistorySummaryMessage_SummarizesFirstSpanInline(t *testing.T) {
+	store := &fakeSummaryStore{}
+	summarizer := &fakeSummarizer{}
+	resolver := &Resolver{logger: slog.Default(), summarizer: summarizer, summaries: store}
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	dropped := droppedHistory(start, 3)
+
+	msg := resolver.historySummaryMessage(context.Background(), conversation.ChatRequest{BotID: summaryTestBotID}, dropped)
+	if msg == nil {
+		t.Fatal("expected a summary message")
+	}
+	if msg.Role != "user" || !strings.HasPrefix(msg.TextContent(), historySummaryHeader) || !strings.HasSuffix(msg.TextContent(), "summary v1 of 3 messages") {
+		t.Fatalf("unexpected summary message: %q", msg.TextContent())
+	}
+	if store.latest == nil || !store.latest.CoveredUntil.Time.Equal(dropped[2].CreatedAt) {
+		t.Fatalf("expected summary to cover the dropped span, got %#v", store.latest)
+	}
+	if !store.hadDeadline {
+		t.Fatal("expected the inline summary to run with a deadline")
+	}
+}
+
+func TestHistorySummaryMessage_RetriesVersionConflict(t *testing.T) {
+	store := &fakeSummaryStore{conflicts: 1}
+	resolver := &Resolver{logger: slog.Default(), summarizer: &fakeSummarizer{}, summaries: store}
+
+	msg := resolver.historySummaryMessage(context.Background(), conversation.ChatRequest{BotID: summaryTestBotID}, droppedHistory(time.Now(), 3))
+	if msg == nil {
+		t.Fatal("expected a summary after retrying the conflicting version")
+	}
+	if store.inserts != 2 || store.latest == nil {
+		t.Fatalf("expected one retry after the conflict, got %d inserts", store.inserts)
+	}
+}
+
+func TestHistorySummaryMessage_ReusesLatestForSmallSlides(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	dropped := droppedHistory(start, 5)
+	store := &fakeSummaryStore{}
+	store.latest = &sqlc.BotHistorySummary{Version: 3, Content: "stored summary"}
+	store.latest.CoveredUntil.Time = dropped[2].CreatedAt
+	store.latest.CoveredUntil.Valid = true
+	summarizer := &fakeSummarizer{}
+	resolver := &Resolver{logger: slog.Default(), summarizer: summarizer, summaries: store}
+
+	msg := resolver.historySummaryMessage(context.Background(), conversation.ChatRequest{BotID: summaryTestBotID}, dropped)
+	if msg == nil || !strings.HasSuffix(msg.TextContent(), "stored summary") {
+		t.Fatalf("expected the stored summary, got %v", msg)
+	}
+	if len(summarizer.requests) != 0 {
+		t.Fatalf("expected no regeneration for two new messages, got %d", len(summarizer.requests))
+	}
+}
+
+func TestHistorySummaryMessage_RegeneratesIncrementally(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	dropped := droppedHistory(start, 2+historySummaryMinBatch)
+	store := &fakeSummaryStore{created: make(chan sqlc.CreateHistorySummaryParams, 1)}
+	store.latest = &sqlc.BotHistorySummary{Version: 1, Content: "stored summary"}
+	store.latest.CoveredUntil.Time = dropped[1].CreatedAt
+	store.latest.CoveredUntil.Valid = true
+	summarizer := &fakeSummarizer{}
+	resolver := &Resolver{logger: slog.Default(), summarizer: summarizer, summaries: store}
+
+	msg := resolver.historySummaryMessage(context.Background(), conversation.ChatRequest{BotID: summaryTestBotID}, dropped)
+	if msg == nil || !strings.HasSuffix(msg.TextContent(), "stored summary") {
+		t.Fatalf("expected the stored summary while regenerating, got %v", msg)
+	}
+
+	select {
+	case created := <-store.created:
+		if !created.CoveredUntil.Time.Equal(dropped[len(dropped)-1].CreatedAt) || created.MessageCount != historySummaryMinBatch {
+			t.Fatalf("unexpected new version: %#v", created)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("summary was not regenerated")
+	}
+	summarizer.mu.Lock()
+	defer summarizer.mu.Unlock()
+	req := summarizer.requests[0]
+	if req.PreviousSummary != "stored summary" || len(req.Messages) != historySummaryMinBatch || req.Messages[0].Content != "message 2" {
+		t.Fatalf("expected only the new span folded into the stored summary, got %#v", req)
+	}
+}
+
+func TestHistorySummaryMessage_DisabledWithoutSummarizer(t *testing.T) {
+	resolver := &Resolver{logger: slog.Default(), summaries: &fakeSummaryStore{}}
+	if msg := resolver.historySummaryMessage(context.Background(), conversation.ChatRequest{BotID: summaryTestBotID}, droppedHistory(time.Now(), 3)); msg != nil {
+		t.Fatalf("expected no summary without a summarizer, got %v", msg)
+	}
+}
@@ -0,0 +1,140 @@
+package flow
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/models"
+	"github.com/memohai/memoh/internal/tokenizer"
+)
+
+const (
+	// messageTokenOverhead covers role markers and separators around each
+	// message in the provider's chat template.
+	messageTokenOverhead = 4
+	// toolCallTokenOverhead covers the id and wrapping of one tool call.
+	toolCallTokenOverhead = 8
+	// systemPromptBaseReserve covers the parts of the system prompt the agent
+	// gateway adds on its own (IDENTITY.md, SOUL.md, TOOLS.md, boilerplate).
+	systemPromptBaseReserve = 3072
+)
+
+// tokenCounter estimates what request parts cost the chat model.
+type tokenCounter struct {
+	tok         tokenizer.Tokenizer
+	imageTokens int
+}
+
+func newTokenCounter(registry *tokenizer.Registry, model models.GetResponse) tokenCounter {
+	if registry == nil {
+		registry = tokenizer.NewRegistry()
+	}
+	return tokenCounter{
+		tok:         registry.For(model.ClientType, model.ModelID),
+		imageTokens: tokenizer.ImageTokens(model.ClientType),
+	}
+}
+
+func (c tokenCounter) text(s string) int {
+	if s == "" {
+		return 0
+	}
+	return c.tok.Count(s)
+}
+
+// message counts text and image parts, tool calls and the per-message
+// framing. Tool call inputs, tool result outputs and content in an unknown
+// shape are counted as raw JSON.
+func (c tokenCounter) message(msg conversation.ModelMessage) int {
+	total := messageTokenOverhead
+	var text string
+	var parts []json.RawMessage
+	if len(msg.Content) > 0 && json.Unmarshal(msg.Content, &text) == nil {
+		total += c.text(text)
+	} else if len(msg.Content) > 0 && json.Unmarshal(msg.Content, &parts) == nil {
+		for _, raw := range parts {
+			total += c.part(raw)
+		}
+	} else if len(msg.Content) > 0 {
+		total += c.text(string(msg.Content))
+	}
+	for _, call := range msg.ToolCalls {
+		total += toolCallTokenOverhead + c.text(call.Function.Name) + c.text(call.Function.Arguments)
+	}
+	return total
+}
+
+// messagePart is the part of an AI SDK content part the counter reads.
+type messagePart struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	MediaType string          `json:"mediaType"`
+	ToolName  string          `json:"toolName"`
+	Input     json.RawMessage `json:"input"`
+	Output    json.RawMessage `json:"output"`
+}
+
+// part counts one content part. Files other than images are not counted,
+// as for attachments.
+func (c tokenCounter) part(raw json.RawMessage) int {
+	var p messagePart
+	if json.Unmarshal(raw, &p) != nil {
+		return c.text(string(raw))
+	}
+	switch strings.ToLower(strings.TrimSpace(p.Type)) {
+	case "text", "reasoning":
+		return c.text(p.Text)
+	case "image", "image_url":
+		return c.imageTokens
+	case "file":
+		if strings.HasPrefix(strings.ToLower(p.MediaType), "image/") {
+			return c.imageTokens
+		}
+		return 0
+	case "tool-call":
+		return toolCallTokenOverhead + c.text(p.ToolName) + c.text(string(p.Input))
+	case "tool-result":
+		return c.text(p.ToolName) + c.text(string(p.Output))
+	default:
+		return c.text(string(raw))
+	}
+}
+
+// attachments counts attachments sent natively to the model. Tool file
+// references only cost their path, which is part of the query header.
+func (c tokenCounter) attachments(atts []any) int {
+	total := 0
+	for _, a := range atts {
+		att, ok := a.(gatewayAttachment)
+		if !ok || att.Transport == gatewayTransportToolFileRef {
+			continue
+		}
+		if att.Type == "image" {
+			total += c.imageTokens
+		}
+	}
+	return total
+}
+
+// systemPromptReserve estimates the system prompt the agent gateway builds:
+// a fixed base, the skill index, the full text of enabled skills and the
+// unread inbox items.
+func (c tokenCounter) systemPromptReserve(skills []gatewaySkill, enabled []string, inbox []gatewayInboxItem) int {
+	total := systemPromptBaseReserve
+	enabledSet := make(map[string]struct{}, len(enabled))
+	for _, name := range enabled {
+		enabledSet[name] = struct{}{}
+	}
+	for _, s := range skills {
+		total += c.text(s.Name) + c.text(s.Description)
+		if _, ok := enabledSet[s.Name]; ok {
+			total += c.text(s.Content)
+		}
+	}
+	for _, item := range inbox {
+		content, _ := json.Marshal(item.Content)
+		total += messageTokenOverhead + c.text(item.Source) + c.text(string(content))
+	}
+	return total
+}
@@ -0,0 +1,71 @@
+package flow
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/models"
+)
+
+// modelVariant identifies the A/B variant a request was routed through.
+type modelVariant struct {
+	ID          string
+	BaseModelID string
+}
+
+// selectModelVariant routes a request to one of the weighted variants of its
+// chat model. The pick is keyed by conversation, so every turn of a
+// conversation talks to the same variant. Requests that pin a model are not
+// routed, and a variant whose model cannot be resolved falls back to the base
+// model.
+func (r *Resolver) selectModelVariant(ctx context.Context, req conversation.ChatRequest, base models.GetResponse, baseProvider sqlc.LlmProvider) (models.GetResponse, sqlc.LlmProvider, *modelVariant) {
+	if strings.TrimSpace(req.Model) != "" || strings.TrimSpace(req.Provider) != "" {
+		return base, baseProvider, nil
+	}
+	variants, err := r.modelsService.ListVariants(ctx, base.ID)
+	if err != nil {
+		r.logger.Warn("list model variants failed", slog.String("model", base.ModelID), slog.Any("error", err))
+		return base, baseProvider, nil
+	}
+	picked, ok := models.PickVariant(variants, variantStickyKey(req)+":"+base.ID)
+	if !ok {
+		return base, baseProvider, nil
+	}
+	variant := &modelVariant{ID: picked.ID, BaseModelID: base.ID}
+	if matchesModelReference(base, picked.VariantID) {
+		return base, baseProvider, variant
+	}
+	model, provider, err := r.fetchChatModel(ctx, picked.VariantID)
+	if err != nil {
+		r.logger.Warn("skip model variant",
+			slog.String("model", base.ModelID),
+			slog.String("variant", picked.VariantID),
+			slog.Any("error", err),
+		)
+		return base, baseProvider, nil
+	}
+	return model, provider, variant
+}
+
+func variantStickyKey(req conversation.ChatRequest) string {
+	if routeID := strings.TrimSpace(req.RouteID); routeID != "" {
+		return routeID
+	}
+	return strings.TrimSpace(req.ChatID)
+}
+
+// replyInfo describes the gateway call that produced a stored round.
+type replyInfo struct {
+	model     gatewayModelConfig
+	latency   time.Duration
+	cancelled bool
+	// kind and request are the gateway call as sent, for the recorder.
+	kind    string
+	request any
+	// moderation holds the moderation verdict of stored messages by index.
+	moderation map[int]map[string]any
+}
@@ -0,0 +1,356 @@
+// Package gateway spreads requests over agent gateway endpoints and probes
+// their health, so one gateway process restarting does not fail every bot.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultProbeInterval = 10 * time.Second
+	defaultProbeTimeout  = 3 * time.Second
+	healthPath           = "/health"
+)
+
+// ErrNoEndpoints is returned when a pool has no endpoint to send to.
+var ErrNoEndpoints = errors.New("no agent gateway endpoints configured")
+
+// Strategy picks the endpoint of each request among the healthy ones.
+type Strategy string
+
+const (
+	// StrategyRoundRobin takes healthy endpoints in turn.
+	StrategyRoundRobin Strategy = "round_robin"
+	// StrategyLeastInflight takes the healthy endpoint with the fewest
+	// requests in progress, which suits long streaming replies.
+	StrategyLeastInflight Strategy = "least_inflight"
+)
+
+// ParseStrategy validates a configured strategy. Empty means round robin.
+func ParseStrategy(raw string) (Strategy, error) {
+	switch Strategy(strings.ToLower(strings.TrimSpace(raw))) {
+	case "", StrategyRoundRobin:
+		return StrategyRoundRobin, nil
+	case StrategyLeastInflight:
+		return StrategyLeastInflight, nil
+	default:
+		return "", fmt.Errorf("unknown agent gateway balance strategy %q", raw)
+	}
+}
+
+// Options tunes a Pool. Zero values use the defaults.
+type Options struct {
+	Strategy      Strategy
+	ProbeInterval time.Duration
+	ProbeTimeout  time.Duration
+}
+
+// EndpointStatus is the last known state of one endpoint.
+type EndpointStatus struct {
+	URL      string `json:"url"`
+	Healthy  bool   `json:"healthy"`
+	Inflight int64  `json:"inflight"`
+	// Probed is false until the first health probe finishes.
+	Probed      bool      `json:"probed"`
+	LastProbeAt time.Time `json:"last_probe_at,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+type endpoint struct {
+	url      string
+	inflight atomic.Int64
+
+	mu        sync.Mutex
+	healthy   bool
+	lastProbe time.Time
+	lastError string
+}
+
+func (e *endpoint) isHealthy() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.healthy
+}
+
+// Pool sends agent gateway requests to healthy endpoints. Endpoints start out
+// healthy; a failed health probe or an unreachable endpoint takes one out of
+// rotation until a probe succeeds again.
+type Pool struct {
+	endpoints     []*endpoint
+	strategy      Strategy
+	next          atomic.Uint64
+	probeClient   *http.Client
+	probeInterval time.Duration
+	probeTimeout  time.Duration
+	logger        *slog.Logger
+}
+
+// NewPool creates a pool over the given base URLs. Blank and repeated URLs
+// are dropped.
+func NewPool(log *slog.Logger, urls []string, opts Options) *Pool {
+	if log == nil {
+		log = slog.Default()
+	}
+	p := &Pool{
+		strategy:      opts.Strategy,
+		probeClient:   &http.Client{},
+		probeInterval: opts.ProbeInterval,
+		probeTimeout:  opts.ProbeTimeout,
+		logger:        log.With(slog.String("service", "agent_gateway_pool")),
+	}
+	if p.strategy == "" {
+		p.strategy = StrategyRoundRobin
+	}
+	if p.probeInterval <= 0 {
+		p.probeInterval = defaultProbeInterval
+	}
+	if p.probeTimeout <= 0 {
+		p.probeTimeout = defaultProbeTimeout
+	}
+	seen := map[string]bool{}
+	for _, raw := range urls {
+		url := strings.TrimRight(strings.TrimSpace(raw), "/")
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		p.endpoints = append(p.endpoints, &endpoint{url: url, healthy: true})
+	}
+	return p
+}
+
+// Do sends a request to an endpoint chosen by the pool's strategy and
+// replays it on another endpoint when the chosen one cannot be reached.
+// Idempotent requests, by method or by an Idempotency-Key header, also move
+// on after any transport error or a 502, 503 or 504 response; other requests
+// may already have run tools on the gateway, so they do not. Closing the
+// response body ends the request's in-flight count.
+func (p *Pool) Do(ctx context.Context, client *http.Client, method, path string, body []byte, header http.Header) (*http.Response, error) {
+	if len(p.endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	idempotent := isIdempotent(method, header)
+	tried := make(map[*endpoint]bool, len(p.endpoints))
+	var lastErr error
+	for len(tried) < len(p.endpoints) {
+		ep := p.pick(tried)
+		tried[ep] = true
+		req, err := http.NewRequestWithContext(ctx, method, ep.url+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if header != nil {
+			req.Header = header.Clone()
+		}
+
+		ep.inflight.Add(1)
+		resp, err := client.Do(req)
+		if err != nil {
+			ep.inflight.Add(-1)
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			unreachable := isDialError(err)
+			if unreachable {
+				p.markDown(ep, err)
+			}
+			if !unreachable && !idempotent {
+				return nil, err
+			}
+			p.logger.Warn("agent gateway request failed, trying next endpoint",
+				slog.String("endpoint", ep.url),
+				slog.String("path", path),
+				slog.Any("error", err),
+			)
+			lastErr = err
+			continue
+		}
+		if idempotent && isUnavailableStatus(resp.StatusCode) && len(tried) < len(p.endpoints) {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			ep.inflight.Add(-1)
+			lastErr = fmt.Errorf("agent gateway %s returned status %d", ep.url, resp.StatusCode)
+			continue
+		}
+		resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() { ep.inflight.Add(-1) }}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// Statuses reports every endpoint in configuration order.
+func (p *Pool) Statuses() []EndpointStatus {
+	out := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		ep.mu.Lock()
+		status := EndpointStatus{
+			URL:         ep.url,
+			Healthy:     ep.healthy,
+			Probed:      !ep.lastProbe.IsZero(),
+			LastProbeAt: ep.lastProbe,
+			LastError:   ep.lastError,
+		}
+		ep.mu.Unlock()
+		status.Inflight = ep.inflight.Load()
+		out = append(out, status)
+	}
+	return out
+}
+
+// Run probes every endpoint until ctx is cancelled.
+func (p *Pool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.probeInterval)
+	defer ticker.Stop()
+	for {
+		p.probeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) probeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ep := range p.endpoints {
+		wg.Add(1)
+		go func(ep *endpoint) {
+			defer wg.Done()
+			p.probe(ctx, ep)
+		}(ep)
+	}
+	wg.Wait()
+}
+
+func (p *Pool) probe(ctx context.Context, ep *endpoint) {
+	probeCtx, cancel := context.WithTimeout(ctx, p.probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(probeCtx, http.MethodGet, ep.url+healthPath, nil)
+	if err != nil {
+		p.record(ep, err)
+		return
+	}
+	resp, err := p.probeClient.Do(req)
+	if err == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			err = fmt.Errorf("health check returned status %d", resp.StatusCode)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	p.record(ep, err)
+}
+
+// record stores a probe result and logs when the endpoint changes state.
+func (p *Pool) record(ep *endpoint, err error) {
+	ep.mu.Lock()
+	wasHealthy := ep.healthy
+	ep.healthy = err == nil
+	ep.lastProbe = time.Now().UTC()
+	ep.lastError = ""
+	if err != nil {
+		ep.lastError = err.Error()
+	}
+	ep.mu.Unlock()
+
+	switch {
+	case wasHealthy && err != nil:
+		p.logger.Warn("agent gateway endpoint is down", slog.String("endpoint", ep.url), slog.Any("error", err))
+	case !wasHealthy && err == nil:
+		p.logger.Info("agent gateway endpoint is back", slog.String("endpoint", ep.url))
+	}
+}
+
+// markDown takes an unreachable endpoint out of rotation until the next
+// successful probe.
+func (p *Pool) markDown(ep *endpoint, err error) {
+	ep.mu.Lock()
+	wasHealthy := ep.healthy
+	ep.healthy = false
+	ep.lastError = err.Error()
+	ep.mu.Unlock()
+	if wasHealthy {
+		p.logger.Warn("agent gateway endpoint is unreachable", slog.String("endpoint", ep.url), slog.Any("error", err))
+	}
+}
+
+// pick chooses among the untried healthy endpoints, or among all untried ones
+// when none is healthy, since probes may lag behind a recovery.
+func (p *Pool) pick(tried map[*endpoint]bool) *endpoint {
+	candidates := make([]*endpoint, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		if !tried[ep] && ep.isHealthy() {
+			candidates = append(candidates, ep)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, ep := range p.endpoints {
+			if !tried[ep] {
+				candidates = append(candidates, ep)
+			}
+		}
+	}
+	start := int(p.next.Add(1)-1) % len(candidates)
+	if p.strategy != StrategyLeastInflight {
+		return candidates[start]
+	}
+	// Scan from the round-robin position so ties still rotate.
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		ep := candidates[(start+i)%len(candidates)]
+		if ep.inflight.Load() < best.inflight.Load() {
+			best = ep
+		}
+	}
+	return best
+}
+
+func isIdempotent(method string, header http.Header) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return header.Get("Idempotency-Key") != ""
+}
+
+func isUnavailableStatus(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// isDialError reports whether err happened before the request reached the
+// endpoint.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// releasingBody runs release once when the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
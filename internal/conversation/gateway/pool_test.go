@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func countingServer(t *testing.T, status int, hits *atomic.Int64) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == healthPath {
+			w.WriteHeader(status)
+			return
+		}
+		hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// closedURL returns the URL of a port nothing listens on.
+func closedURL(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + ln.Addr().String()
+	_ = ln.Close()
+	return url
+}
+
+func send(t *testing.T, p *Pool, method string, header http.Header) *http.Response {
+	t.Helper()
+	resp, err := p.Do(context.Background(), http.DefaultClient, method, "/chat/", []byte(`{"x":1}`), header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	var a, b atomic.Int64
+	srvA := countingServer(t, http.StatusOK, &a)
+	srvB := countingServer(t, http.StatusOK, &b)
+	p := NewPool(slog.Default(), []string{srvA.URL, srvB.URL + "/", srvA.URL}, Options{})
+
+	if len(p.Statuses()) != 2 {
+		t.Fatalf("expected duplicate endpoint dropped, got %+v", p.Statuses())
+	}
+	for range 4 {
+		send(t, p, http.MethodPost, nil)
+	}
+	if a.Load() != 2 || b.Load() != 2 {
+		t.Fatalf("expected requests split evenly, got %d and %d", a.Load(), b.Load())
+	}
+}
+
+func TestPoolReplaysOnUnreachableEndpoint(t *testing.T) {
+	var hits atomic.Int64
+	srv := countingServer(t, http.StatusOK, &hits)
+	down := closedURL(t)
+	p := NewPool(slog.Default(), []string{down, srv.URL}, Options{})
+
+	for range 3 {
+		resp := send(t, p, http.MethodPost, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+	}
+	if hits.Load() != 3 {
+		t.Fatalf("expected all requests on the live endpoint, got %d", hits.Load())
+	}
+	statuses := p.Statuses()
+	if statuses[0].Healthy || statuses[0].LastError == "" || !statuses[1].Healthy {
+		t.Fatalf("expected unreachable endpoint marked down, got %+v", statuses)
+	}
+}
+
+func TestPoolRetriesUnavailableOnlyWhenIdempotent(t *testing.T) {
+	var failing, ok atomic.Int64
+	srvFail := countingServer(t, http.StatusServiceUnavailable, &failing)
+	srvOK := countingServer(t, http.StatusOK, &ok)
+	p := NewPool(slog.Default(), []string{srvFail.URL, srvOK.URL}, Options{})
+
+	// A chat request may have run tools before failing, so it is returned as is.
+	if resp := send(t, p, http.MethodPost, nil); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 passed through, got %d", resp.StatusCode)
+	}
+	header := http.Header{}
+	header.Set("Idempotency-Key", "k1")
+	p.next.Store(0)
+	if resp := send(t, p, http.MethodPost, header); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected idempotent request replayed, got %d", resp.StatusCode)
+	}
+	if failing.Load() != 2 || ok.Load() != 1 {
+		t.Fatalf("unexpected hits %d / %d", failing.Load(), ok.Load())
+	}
+}
+
+func TestPoolLeastInflight(t *testing.T) {
+	var a, b atomic.Int64
+	srvA := countingServer(t, http.StatusOK, &a)
+	srvB := countingServer(t, http.StatusOK, &b)
+	p := NewPool(slog.Default(), []string{srvA.URL, srvB.URL}, Options{Strategy: StrategyLeastInflight})
+
+	open, err := p.Do(context.Background(), http.DefaultClient, http.MethodPost, "/chat/stream", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Statuses()[0].Inflight; got != 1 {
+		t.Fatalf("expected open response counted in flight, got %d", got)
+	}
+	for range 3 {
+		send(t, p, http.MethodPost, nil)
+	}
+	if a.Load() != 1 || b.Load() != 3 {
+		t.Fatalf("expected new requests to avoid the busy endpoint, got %d and %d", a.Load(), b.Load())
+	}
+	_ = open.Body.Close()
+	_ = open.Body.Close()
+	if got := p.Statuses()[0].Inflight; got != 0 {
+		t.Fatalf("expected in-flight count released once, got %d", got)
+	}
+}
+
+func TestPoolProbeTracksHealth(t *testing.T) {
+	var hits atomic.Int64
+	healthy := countingServer(t, http.StatusOK, &hits)
+	sick := countingServer(t, http.StatusInternalServerError, &hits)
+	p := NewPool(slog.Default(), []string{healthy.URL, sick.URL}, Options{})
+
+	p.probeAll(context.Background())
+	statuses := p.Statuses()
+	if !statuses[0].Healthy || !statuses[0].Probed {
+		t.Fatalf("expected healthy endpoint, got %+v", statuses[0])
+	}
+	if statuses[1].Healthy || statuses[1].LastError == "" {
+		t.Fatalf("expected failing probe to mark endpoint down, got %+v", statuses[1])
+	}
+	for range 2 {
+		send(t, p, http.MethodPost, nil)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("expected requests routed to healthy endpoint, got %d", hits.Load())
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	if s, err := ParseStrategy(""); err != nil || s != StrategyRoundRobin {
+		t.Fatalf("unexpected default %q %v", s, err)
+	}
+	if s, err := ParseStrategy("Least_Inflight"); err != nil || s != StrategyLeastInflight {
+		t.Fatalf("unexpected strategy %q %v", s, err)
+	}
+	if _, err := ParseStrategy("random"); err == nil {
+		t.Fatal("expected error for unknown strategy")
+	}
+}
@@ -18,3 +18,9 @@ type Accessor interface {
 	ParticipantChecker
 	GetReadAccess(ctx context.Context, conversationID, channelIdentityID string) (ConversationReadAccess, error)
 }
+
+// SettingsManager reads and updates per-conversation settings.
+type SettingsManager interface {
+	GetSettings(ctx context.Context, conversationID string) (Settings, error)
+	UpdateSettings(ctx context.Context, conversationID string, req UpdateSettingsRequest) (Settings, error)
+}
@@ -0,0 +1,238 @@
+package conversation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidSettings is returned by UpdateSettings for out-of-range values.
+var ErrInvalidSettings = errors.New("invalid conversation settings")
+
+const (
+	// MaxSystemPromptLength caps the extra instructions, in characters.
+	MaxSystemPromptLength = 8000
+	maxEnabledSkills      = 32
+)
+
+// Override field names accepted by UpdateSettingsRequest.Reset.
+const (
+	OverrideSystemPrompt       = "system_prompt"
+	OverrideTemperature        = "temperature"
+	OverrideTopP               = "top_p"
+	OverrideReasoningEnabled   = "reasoning_enabled"
+	OverrideReasoningEffort    = "reasoning_effort"
+	OverrideMaxContextTokens   = "max_context_tokens"
+	OverrideMaxContextLoadTime = "max_context_load_time"
+	OverrideEnabledSkills      = "enabled_skills"
+	OverrideAllowedTools       = "allowed_tools"
+	// OverrideAll resets every override at once.
+	OverrideAll = "all"
+)
+
+var allOverrides = []string{
+	OverrideSystemPrompt,
+	OverrideTemperature,
+	OverrideTopP,
+	OverrideReasoningEnabled,
+	OverrideReasoningEffort,
+	OverrideMaxContextTokens,
+	OverrideMaxContextLoadTime,
+	OverrideEnabledSkills,
+	OverrideAllowedTools,
+}
+
+// chatOverrides is the JSON stored in bots.chat_overrides.
+type chatOverrides struct {
+	SystemPrompt       string   `json:"system_prompt,omitempty"`
+	Temperature        *float64 `json:"temperature,omitempty"`
+	TopP               *float64 `json:"top_p,omitempty"`
+	ReasoningEnabled   *bool    `json:"reasoning_enabled,omitempty"`
+	ReasoningEffort    string   `json:"reasoning_effort,omitempty"`
+	MaxContextTokens   *int     `json:"max_context_tokens,omitempty"`
+	MaxContextLoadTime *int     `json:"max_context_load_time,omitempty"`
+	EnabledSkills      []string `json:"enabled_skills,omitempty"`
+	AllowedTools       []string `json:"allowed_tools,omitempty"`
+}
+
+func parseChatOverrides(raw []byte) chatOverrides {
+	var o chatOverrides
+	if len(raw) == 0 {
+		return o
+	}
+	_ = json.Unmarshal(raw, &o)
+	return o
+}
+
+func (o chatOverrides) applyTo(s *Settings) {
+	s.SystemPrompt = o.SystemPrompt
+	s.Temperature = o.Temperature
+	s.TopP = o.TopP
+	s.ReasoningEnabled = o.ReasoningEnabled
+	s.ReasoningEffort = o.ReasoningEffort
+	s.MaxContextTokens = o.MaxContextTokens
+	s.MaxContextLoadTime = o.MaxContextLoadTime
+	s.EnabledSkills = o.EnabledSkills
+	s.AllowedTools = o.AllowedTools
+}
+
+// hasOverrides reports whether req touches any field stored in chat_overrides.
+func (req UpdateSettingsRequest) hasOverrides() bool {
+	return req.SystemPrompt != nil || req.Temperature != nil || req.TopP != nil ||
+		req.ReasoningEnabled != nil || req.ReasoningEffort != nil ||
+		req.MaxContextTokens != nil || req.MaxContextLoadTime != nil ||
+		req.EnabledSkills != nil || req.AllowedTools != nil || len(req.Reset) > 0
+}
+
+// touchedOverrides lists the stored override keys req resets or sets. They
+// are dropped before the new values are merged in, so a cleared or emptied
+// field falls back to the bot setting.
+func (req UpdateSettingsRequest) touchedOverrides() []string {
+	var keys []string
+	for _, field := range req.Reset {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == OverrideAll {
+			return allOverrides
+		}
+		keys = append(keys, field)
+	}
+	set := []struct {
+		key     string
+		present bool
+	}{
+		{OverrideSystemPrompt, req.SystemPrompt != nil},
+		{OverrideTemperature, req.Temperature != nil},
+		{OverrideTopP, req.TopP != nil},
+		{OverrideReasoningEnabled, req.ReasoningEnabled != nil},
+		{OverrideReasoningEffort, req.ReasoningEffort != nil},
+		{OverrideMaxContextTokens, req.MaxContextTokens != nil},
+		{OverrideMaxContextLoadTime, req.MaxContextLoadTime != nil},
+		{OverrideEnabledSkills, req.EnabledSkills != nil},
+		{OverrideAllowedTools, req.AllowedTools != nil},
+	}
+	for _, field := range set {
+		if field.present && !slices.Contains(keys, field.key) {
+			keys = append(keys, field.key)
+		}
+	}
+	return keys
+}
+
+// applyOverrides validates req and applies it to current. Resets run first,
+// so a request can clear everything and set new values in one call.
+func applyOverrides(current chatOverrides, req UpdateSettingsRequest) (chatOverrides, error) {
+	next := current
+	for _, field := range req.Reset {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case OverrideAll:
+			next = chatOverrides{}
+		case OverrideSystemPrompt:
+			next.SystemPrompt = ""
+		case OverrideTemperature:
+			next.Temperature = nil
+		case OverrideTopP:
+			next.TopP = nil
+		case OverrideReasoningEnabled:
+			next.ReasoningEnabled = nil
+		case OverrideReasoningEffort:
+			next.ReasoningEffort = ""
+		case OverrideMaxContextTokens:
+			next.MaxContextTokens = nil
+		case OverrideMaxContextLoadTime:
+			next.MaxContextLoadTime = nil
+		case OverrideEnabledSkills:
+			next.EnabledSkills = nil
+		case OverrideAllowedTools:
+			next.AllowedTools = nil
+		default:
+			return chatOverrides{}, fmt.Errorf("%w: unknown setting %q", ErrInvalidSettings, field)
+		}
+	}
+
+	if req.SystemPrompt != nil {
+		prompt := strings.TrimSpace(*req.SystemPrompt)
+		if utf8.RuneCountInString(prompt) > MaxSystemPromptLength {
+			return chatOverrides{}, fmt.Errorf("%w: system_prompt exceeds %d characters", ErrInvalidSettings, MaxSystemPromptLength)
+		}
+		next.SystemPrompt = prompt
+	}
+	if req.Temperature != nil {
+		if t := *req.Temperature; t < 0 || t > 2 {
+			return chatOverrides{}, fmt.Errorf("%w: temperature must be between 0 and 2", ErrInvalidSettings)
+		}
+		next.Temperature = req.Temperature
+	}
+	if req.TopP != nil {
+		if p := *req.TopP; p <= 0 || p > 1 {
+			return chatOverrides{}, fmt.Errorf("%w: top_p must be greater than 0 and at most 1", ErrInvalidSettings)
+		}
+		next.TopP = req.TopP
+	}
+	if req.ReasoningEnabled != nil {
+		next.ReasoningEnabled = req.ReasoningEnabled
+	}
+	if req.ReasoningEffort != nil {
+		effort := strings.ToLower(strings.TrimSpace(*req.ReasoningEffort))
+		switch effort {
+		case "low", "medium", "high":
+		default:
+			return chatOverrides{}, fmt.Errorf("%w: reasoning_effort must be low, medium or high", ErrInvalidSettings)
+		}
+		next.ReasoningEffort = effort
+	}
+	if req.MaxContextTokens != nil {
+		if *req.MaxContextTokens <= 0 {
+			return chatOverrides{}, fmt.Errorf("%w: max_context_tokens must be positive", ErrInvalidSettings)
+		}
+		next.MaxContextTokens = req.MaxContextTokens
+	}
+	if req.MaxContextLoadTime != nil {
+		if *req.MaxContextLoadTime <= 0 {
+			return chatOverrides{}, fmt.Errorf("%w: max_context_load_time must be positive", ErrInvalidSettings)
+		}
+		next.MaxContextLoadTime = req.MaxContextLoadTime
+	}
+	if req.EnabledSkills != nil {
+		skills := normalizeNames(req.EnabledSkills)
+		if len(skills) > maxEnabledSkills {
+			return chatOverrides{}, fmt.Errorf("%w: at most %d enabled skills", ErrInvalidSettings, maxEnabledSkills)
+		}
+		next.EnabledSkills = skills
+	}
+	if req.AllowedTools != nil {
+		lowered := make([]string, len(req.AllowedTools))
+		for i, tool := range req.AllowedTools {
+			lowered[i] = strings.ToLower(tool)
+		}
+		tools := normalizeNames(lowered)
+		for _, tool := range tools {
+			if !slices.Contains(ToolSets, tool) {
+				return chatOverrides{}, fmt.Errorf("%w: unknown tool set %q (want one of %s)", ErrInvalidSettings, tool, strings.Join(ToolSets, ", "))
+			}
+		}
+		next.AllowedTools = tools
+	}
+	return next, nil
+}
+
+// normalizeNames trims names and drops blanks and duplicates, keeping order.
+// An empty result is nil so the override falls back to the bot setting.
+func normalizeNames(names []string) []string {
+	var out []string
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package conversation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestApplyOverridesValidates(t *testing.T) {
+	cases := map[string]UpdateSettingsRequest{
+		"temperature":  {Temperature: ptr(2.5)},
+		"top_p zero":   {TopP: ptr(0.0)},
+		"effort":       {ReasoningEffort: ptr("max")},
+		"tokens":       {MaxContextTokens: ptr(0)},
+		"context time": {MaxContextLoadTime: ptr(-5)},
+		"tool set":     {AllowedTools: []string{"web", "shell"}},
+		"prompt":       {SystemPrompt: ptr(strings.Repeat("x", MaxSystemPromptLength+1))},
+		"reset field":  {Reset: []string{"color"}},
+	}
+	for name, req := range cases {
+		if _, err := applyOverrides(chatOverrides{}, req); !errors.Is(err, ErrInvalidSettings) {
+			t.Errorf("%s: expected ErrInvalidSettings, got %v", name, err)
+		}
+	}
+}
+
+func TestApplyOverridesSetsAndResets(t *testing.T) {
+	current := chatOverrides{
+		SystemPrompt: "be brief",
+		Temperature:  ptr(0.2),
+		AllowedTools: []string{"web"},
+	}
+	next, err := applyOverrides(current, UpdateSettingsRequest{
+		TopP:            ptr(0.9),
+		ReasoningEffort: ptr(" HIGH "),
+		EnabledSkills:   []string{" notes ", "", "notes", "search"},
+		AllowedTools:    []string{"Memory", "memory", "web"},
+		Reset:           []string{OverrideTemperature},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := chatOverrides{
+		SystemPrompt:    "be brief",
+		TopP:            ptr(0.9),
+		ReasoningEffort: "high",
+		EnabledSkills:   []string{"notes", "search"},
+		AllowedTools:    []string{"memory", "web"},
+	}
+	if !reflect.DeepEqual(next, want) {
+		t.Fatalf("unexpected overrides\n got: %+v\nwant: %+v", next, want)
+	}
+
+	cleared, err := applyOverrides(next, UpdateSettingsRequest{Reset: []string{OverrideAll}, Temperature: ptr(1.0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cleared, chatOverrides{Temperature: ptr(1.0)}) {
+		t.Fatalf("expected reset before set, got %+v", cleared)
+	}
+}
+
+func TestTouchedOverrides(t *testing.T) {
+	req := UpdateSettingsRequest{
+		SystemPrompt: ptr(""),
+		TopP:         ptr(0.9),
+		Reset:        []string{" Temperature ", OverrideTopP},
+	}
+	want := []string{OverrideTemperature, OverrideTopP, OverrideSystemPrompt}
+	if got := req.touchedOverrides(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys %v, want %v", got, want)
+	}
+	if got := (UpdateSettingsRequest{Reset: []string{OverrideAll}}).touchedOverrides(); !reflect.DeepEqual(got, allOverrides) {
+		t.Fatalf("expected every key for a full reset, got %v", got)
+	}
+}
+
+func TestChatOverridesRoundTripIntoSettings(t *testing.T) {
+	var s Settings
+	parseChatOverrides([]byte(`{"system_prompt":"hi","reasoning_enabled":false,"max_context_tokens":4000}`)).applyTo(&s)
+	if s.SystemPrompt != "hi" || s.ReasoningEnabled == nil || *s.ReasoningEnabled || s.MaxContextTokens == nil || *s.MaxContextTokens != 4000 {
+		t.Fatalf("unexpected settings: %+v", s)
+	}
+	parseChatOverrides(nil).applyTo(&s)
+	if s.SystemPrompt != "" || s.ReasoningEnabled != nil {
+		t.Fatalf("expected empty overrides to clear settings, got %+v", s)
+	}
+}
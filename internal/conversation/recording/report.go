@@ -0,0 +1,128 @@
+package recording
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+// Output is what one side of a comparison answered.
+type Output struct {
+	// Model is the gateway model ID that answered.
+	Model string `json:"model,omitempty"`
+	// Text joins the text of the assistant messages.
+	Text string `json:"text"`
+	// ToolCalls names the tools called, in order.
+	ToolCalls []string           `json:"tool_calls"`
+	Usage     conversation.Usage `json:"usage"`
+	LatencyMs int64              `json:"latency_ms"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// Comparison sets a recorded answer beside its replay.
+type Comparison struct {
+	RecordingID string    `json:"recording_id"`
+	Kind        string    `json:"kind"`
+	CreatedAt   time.Time `json:"created_at"`
+	Original    Output    `json:"original"`
+	Replay      Output    `json:"replay"`
+	// The fields below are only set when the replay succeeded.
+	TextChanged       bool `json:"text_changed"`
+	ToolCallsChanged  bool `json:"tool_calls_changed"`
+	InputTokensDelta  int  `json:"input_tokens_delta"`
+	OutputTokensDelta int  `json:"output_tokens_delta"`
+}
+
+func (c *Comparison) compare() {
+	c.TextChanged = strings.TrimSpace(c.Original.Text) != strings.TrimSpace(c.Replay.Text)
+	c.ToolCallsChanged = !slices.Equal(c.Original.ToolCalls, c.Replay.ToolCalls)
+	c.InputTokensDelta = c.Replay.Usage.InputTokens - c.Original.Usage.InputTokens
+	c.OutputTokensDelta = c.Replay.Usage.OutputTokens - c.Original.Usage.OutputTokens
+}
+
+// Report is the result of a replay: every comparison and their totals.
+type Report struct {
+	BotID      string `json:"bot_id"`
+	ModelID    string `json:"model_id,omitempty"`
+	GatewayURL string `json:"gateway_url,omitempty"`
+	AllowTools bool   `json:"allow_tools"`
+
+	Replayed         int `json:"replayed"`
+	Failed           int `json:"failed"`
+	TextChanged      int `json:"text_changed"`
+	ToolCallsChanged int `json:"tool_calls_changed"`
+	// OriginalUsage and ReplayUsage total the successful replays only, so
+	// they compare like with like.
+	OriginalUsage conversation.Usage `json:"original_usage"`
+	ReplayUsage   conversation.Usage `json:"replay_usage"`
+
+	Comparisons []Comparison `json:"comparisons"`
+}
+
+func (r *Report) add(c Comparison) {
+	r.Comparisons = append(r.Comparisons, c)
+	if c.Replay.Error != "" {
+		r.Failed++
+		return
+	}
+	r.Replayed++
+	if c.TextChanged {
+		r.TextChanged++
+	}
+	if c.ToolCallsChanged {
+		r.ToolCallsChanged++
+	}
+	r.OriginalUsage.InputTokens += c.Original.Usage.InputTokens
+	r.OriginalUsage.OutputTokens += c.Original.Usage.OutputTokens
+	r.ReplayUsage.InputTokens += c.Replay.Usage.InputTokens
+	r.ReplayUsage.OutputTokens += c.Replay.Usage.OutputTokens
+}
+
+// Markdown renders the report with each recording's answers side by side.
+func (r Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Gateway replay report\n\n")
+	fmt.Fprintf(&b, "- Bot: `%s`\n", r.BotID)
+	if r.ModelID != "" {
+		fmt.Fprintf(&b, "- Model: `%s`\n", r.ModelID)
+	}
+	if r.GatewayURL != "" {
+		fmt.Fprintf(&b, "- Gateway: `%s`\n", r.GatewayURL)
+	}
+	fmt.Fprintf(&b, "- Tools allowed: %t\n", r.AllowTools)
+	fmt.Fprintf(&b, "- Replayed: %d, failed: %d\n", r.Replayed, r.Failed)
+	fmt.Fprintf(&b, "- Text changed: %d, tool calls changed: %d\n", r.TextChanged, r.ToolCallsChanged)
+	fmt.Fprintf(&b, "- Input tokens: %d → %d\n", r.OriginalUsage.InputTokens, r.ReplayUsage.InputTokens)
+	fmt.Fprintf(&b, "- Output tokens: %d → %d\n", r.OriginalUsage.OutputTokens, r.ReplayUsage.OutputTokens)
+
+	for _, c := range r.Comparisons {
+		fmt.Fprintf(&b, "\n## %s (%s, %s)\n\n", c.RecordingID, c.Kind, c.CreatedAt.UTC().Format(time.RFC3339))
+		b.WriteString("| | Original | Replay |\n|---|---|---|\n")
+		row := func(label, original, replay string) {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", label, markdownCell(original), markdownCell(replay))
+		}
+		row("Model", c.Original.Model, c.Replay.Model)
+		if c.Replay.Error != "" {
+			row("Error", c.Original.Error, c.Replay.Error)
+			row("Text", c.Original.Text, "")
+			continue
+		}
+		row("Text", c.Original.Text, c.Replay.Text)
+		row("Tool calls", strings.Join(c.Original.ToolCalls, ", "), strings.Join(c.Replay.ToolCalls, ", "))
+		row("Input tokens", fmt.Sprint(c.Original.Usage.InputTokens), fmt.Sprint(c.Replay.Usage.InputTokens))
+		row("Output tokens", fmt.Sprint(c.Original.Usage.OutputTokens), fmt.Sprint(c.Replay.Usage.OutputTokens))
+		row("Latency (ms)", fmt.Sprint(c.Original.LatencyMs), fmt.Sprint(c.Replay.LatencyMs))
+	}
+	return b.String()
+}
+
+// markdownCell keeps text inside one table cell.
+func markdownCell(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
@@ -0,0 +1,420 @@
+// Package recording keeps agent gateway requests of bots that opt in and
+// replays them against another model or gateway to compare the answers.
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/flow"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+const (
+	defaultListLimit   = 50
+	defaultReplayLimit = 10
+	// maxReplayBatch caps the recordings replayed by one request, since they
+	// are sent one after another.
+	maxReplayBatch = 50
+)
+
+var (
+	// ErrReplayUnavailable is returned when no replayer is configured.
+	ErrReplayUnavailable = errors.New("gateway replay is not configured")
+	// ErrTooManyRecordings is returned when a replay asks for more recordings
+	// than one request may replay.
+	ErrTooManyRecordings = fmt.Errorf("at most %d recordings can be replayed at once", maxReplayBatch)
+)
+
+// Settings is the recording switch of a bot.
+type Settings struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Recording is one recorded gateway round. Request and Response are only
+// filled when a single recording is fetched.
+type Recording struct {
+	ID        string          `json:"id"`
+	BotID     string          `json:"bot_id"`
+	RouteID   string          `json:"route_id,omitempty"`
+	Kind      string          `json:"kind"`
+	ModelID   string          `json:"model_id,omitempty"`
+	LatencyMs int64           `json:"latency_ms"`
+	CreatedAt time.Time       `json:"created_at"`
+	Request   json.RawMessage `json:"request,omitempty"`
+	Response  json.RawMessage `json:"response,omitempty"`
+}
+
+type store interface {
+	GetBotGatewayRecording(ctx context.Context, id pgtype.UUID) (bool, error)
+	SetBotGatewayRecording(ctx context.Context, arg sqlc.SetBotGatewayRecordingParams) (bool, error)
+	CreateGatewayRecording(ctx context.Context, arg sqlc.CreateGatewayRecordingParams) (sqlc.GatewayRecording, error)
+	GetGatewayRecording(ctx context.Context, arg sqlc.GetGatewayRecordingParams) (sqlc.GatewayRecording, error)
+	ListGatewayRecordings(ctx context.Context, arg sqlc.ListGatewayRecordingsParams) ([]sqlc.ListGatewayRecordingsRow, error)
+	DeleteGatewayRecordings(ctx context.Context, botID pgtype.UUID) (int64, error)
+}
+
+// Replayer sends a recorded request to the agent gateway again.
+type Replayer interface {
+	ReplayGatewayRound(ctx context.Context, replay flow.GatewayReplay) (flow.GatewayReplayResult, error)
+}
+
+// Service records gateway rounds and replays them.
+type Service struct {
+	queries  store
+	replayer Replayer
+	logger   *slog.Logger
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Service{
+		queries: queries,
+		logger:  log.With(slog.String("service", "gateway_recording")),
+	}
+}
+
+// SetReplayer enables replays.
+func (s *Service) SetReplayer(replayer Replayer) {
+	s.replayer = replayer
+}
+
+// GetSettings returns the recording switch of a bot.
+func (s *Service) GetSettings(ctx context.Context, botID string) (Settings, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Settings{}, err
+	}
+	enabled, err := s.queries.GetBotGatewayRecording(ctx, pgBotID)
+	if err != nil {
+		return Settings{}, err
+	}
+	return Settings{Enabled: enabled}, nil
+}
+
+// UpdateSettings turns recording of a bot on or off. Existing recordings are
+// kept either way.
+func (s *Service) UpdateSettings(ctx context.Context, botID string, settings Settings) (Settings, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Settings{}, err
+	}
+	enabled, err := s.queries.SetBotGatewayRecording(ctx, sqlc.SetBotGatewayRecordingParams{
+		GatewayRecording: settings.Enabled,
+		ID:               pgBotID,
+	})
+	if err != nil {
+		return Settings{}, err
+	}
+	return Settings{Enabled: enabled}, nil
+}
+
+// RecordsGateway implements flow.GatewayRecorder.
+func (s *Service) RecordsGateway(ctx context.Context, botID string) bool {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return false
+	}
+	enabled, err := s.queries.GetBotGatewayRecording(ctx, pgBotID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.logger.Warn("read gateway recording switch failed", slog.String("bot_id", botID), slog.Any("error", err))
+		}
+		return false
+	}
+	return enabled
+}
+
+// RecordGatewayRound implements flow.GatewayRecorder. Failures are logged so
+// recording never fails a chat round.
+func (s *Service) RecordGatewayRound(ctx context.Context, round flow.GatewayRound) {
+	pgBotID, err := db.ParseUUID(round.BotID)
+	if err != nil {
+		return
+	}
+	arg := sqlc.CreateGatewayRecordingParams{
+		BotID:     pgBotID,
+		Kind:      round.Kind,
+		Request:   round.Request,
+		Response:  round.Response,
+		LatencyMs: round.Latency.Milliseconds(),
+	}
+	if routeID, err := db.ParseUUID(round.RouteID); err == nil {
+		arg.RouteID = routeID
+	}
+	if modelID, err := db.ParseUUID(round.ModelID); err == nil {
+		arg.ModelID = modelID
+	}
+	if _, err := s.queries.CreateGatewayRecording(ctx, arg); err != nil {
+		s.logger.Warn("store gateway recording failed", slog.String("bot_id", round.BotID), slog.Any("error", err))
+	}
+}
+
+// List returns the latest recordings of a bot, newest first, without their
+// payloads.
+func (s *Service) List(ctx context.Context, botID string, limit int32) ([]Recording, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	rows, err := s.queries.ListGatewayRecordings(ctx, sqlc.ListGatewayRecordingsParams{BotID: pgBotID, MaxCount: limit})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Recording, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, Recording{
+			ID:        row.ID.String(),
+			BotID:     row.BotID.String(),
+			RouteID:   uuidString(row.RouteID),
+			Kind:      row.Kind,
+			ModelID:   uuidString(row.ModelID),
+			LatencyMs: row.LatencyMs,
+			CreatedAt: db.TimeFromPg(row.CreatedAt),
+		})
+	}
+	return out, nil
+}
+
+// Get returns one recording with its request and response.
+func (s *Service) Get(ctx context.Context, botID, recordingID string) (Recording, error) {
+	row, err := s.get(ctx, botID, recordingID)
+	if err != nil {
+		return Recording{}, err
+	}
+	return toRecording(row), nil
+}
+
+// Clear deletes every recording of a bot.
+func (s *Service) Clear(ctx context.Context, botID string) (int64, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return 0, err
+	}
+	return s.queries.DeleteGatewayRecordings(ctx, pgBotID)
+}
+
+// ReplayRequest selects recordings to replay and where to send them.
+type ReplayRequest struct {
+	// RecordingIDs lists the recordings to replay. When empty, the latest
+	// Limit recordings are replayed.
+	RecordingIDs []string `json:"recording_ids,omitempty"`
+	Limit        int32    `json:"limit,omitempty"`
+	// ModelID answers with another chat model, by ID or model slug. Empty
+	// keeps the recorded model.
+	ModelID string `json:"model_id,omitempty"`
+	// GatewayURL sends the requests to another agent gateway.
+	GatewayURL string `json:"gateway_url,omitempty"`
+	// AllowTools lets the replayed requests call tools, which act on the bot
+	// for real. Off by default.
+	AllowTools bool `json:"allow_tools,omitempty"`
+	// Token authorizes the replayed requests on the gateway.
+	Token string `json:"-"`
+}
+
+// Replay sends the selected recordings to the gateway again and compares the
+// answers with the recorded ones. A recording that fails to replay is
+// reported with its error; the others still run.
+func (s *Service) Replay(ctx context.Context, botID string, req ReplayRequest) (Report, error) {
+	if s.replayer == nil {
+		return Report{}, ErrReplayUnavailable
+	}
+	rows, err := s.selectRecordings(ctx, botID, req)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{
+		BotID:       botID,
+		ModelID:     strings.TrimSpace(req.ModelID),
+		GatewayURL:  strings.TrimSpace(req.GatewayURL),
+		AllowTools:  req.AllowTools,
+		Comparisons: make([]Comparison, 0, len(rows)),
+	}
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return Report{}, err
+		}
+		report.add(s.replayOne(ctx, row, req))
+	}
+	return report, nil
+}
+
+func (s *Service) replayOne(ctx context.Context, row sqlc.GatewayRecording, req ReplayRequest) Comparison {
+	comparison := Comparison{
+		RecordingID: row.ID.String(),
+		Kind:        row.Kind,
+		CreatedAt:   db.TimeFromPg(row.CreatedAt),
+		Original:    summarize(row.Response),
+	}
+	comparison.Original.Model = recordedModel(row.Request)
+	comparison.Original.LatencyMs = row.LatencyMs
+
+	modelID := strings.TrimSpace(req.ModelID)
+	if modelID == "" {
+		modelID = uuidString(row.ModelID)
+	}
+	if modelID == "" {
+		comparison.Replay.Error = "the recorded model no longer exists; choose a model to replay with"
+		return comparison
+	}
+	result, err := s.replayer.ReplayGatewayRound(ctx, flow.GatewayReplay{
+		BotID:      row.BotID.String(),
+		Kind:       row.Kind,
+		Request:    row.Request,
+		ModelID:    modelID,
+		GatewayURL: req.GatewayURL,
+		AllowTools: req.AllowTools,
+		Token:      req.Token,
+	})
+	if err != nil {
+		s.logger.Warn("gateway replay failed", slog.String("recording_id", comparison.RecordingID), slog.Any("error", err))
+		comparison.Replay.Error = err.Error()
+		return comparison
+	}
+	comparison.Replay = summarize(result.Response)
+	comparison.Replay.Model = result.Model
+	comparison.Replay.LatencyMs = result.Latency.Milliseconds()
+	comparison.compare()
+	return comparison
+}
+
+func (s *Service) selectRecordings(ctx context.Context, botID string, req ReplayRequest) ([]sqlc.GatewayRecording, error) {
+	ids := req.RecordingIDs
+	if len(ids) == 0 {
+		limit := req.Limit
+		if limit <= 0 {
+			limit = defaultReplayLimit
+		}
+		if limit > maxReplayBatch {
+			return nil, ErrTooManyRecordings
+		}
+		latest, err := s.List(ctx, botID, limit)
+		if err != nil {
+			return nil, err
+		}
+		// Replay in the order the rounds happened.
+		ids = make([]string, 0, len(latest))
+		for i := len(latest) - 1; i >= 0; i-- {
+			ids = append(ids, latest[i].ID)
+		}
+	}
+	if len(ids) > maxReplayBatch {
+		return nil, ErrTooManyRecordings
+	}
+	rows := make([]sqlc.GatewayRecording, 0, len(ids))
+	for _, id := range ids {
+		row, err := s.get(ctx, botID, id)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (s *Service) get(ctx context.Context, botID, recordingID string) (sqlc.GatewayRecording, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return sqlc.GatewayRecording{}, err
+	}
+	pgID, err := db.ParseUUID(recordingID)
+	if err != nil {
+		return sqlc.GatewayRecording{}, err
+	}
+	return s.queries.GetGatewayRecording(ctx, sqlc.GetGatewayRecordingParams{ID: pgID, BotID: pgBotID})
+}
+
+func toRecording(row sqlc.GatewayRecording) Recording {
+	return Recording{
+		ID:        row.ID.String(),
+		BotID:     row.BotID.String(),
+		RouteID:   uuidString(row.RouteID),
+		Kind:      row.Kind,
+		ModelID:   uuidString(row.ModelID),
+		LatencyMs: row.LatencyMs,
+		CreatedAt: db.TimeFromPg(row.CreatedAt),
+		Request:   row.Request,
+		Response:  row.Response,
+	}
+}
+
+func uuidString(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.String()
+}
+
+// recordedModel reads the gateway model ID from a recorded request.
+func recordedModel(request json.RawMessage) string {
+	var body struct {
+		Model struct {
+			ModelID string `json:"modelId"`
+		} `json:"model"`
+	}
+	_ = json.Unmarshal(request, &body)
+	return body.Model.ModelID
+}
+
+// summarize extracts what a report compares from a gateway response.
+func summarize(response json.RawMessage) Output {
+	var resp struct {
+		Messages []conversation.ModelMessage `json:"messages"`
+		Usage    json.RawMessage             `json:"usage,omitempty"`
+		Usages   []json.RawMessage           `json:"usages,omitempty"`
+	}
+	out := Output{ToolCalls: []string{}}
+	if err := json.Unmarshal(response, &resp); err != nil {
+		out.Error = "unreadable response: " + err.Error()
+		return out
+	}
+	texts := make([]string, 0, len(resp.Messages))
+	for _, m := range resp.Messages {
+		if m.Role != "assistant" {
+			continue
+		}
+		if text := strings.TrimSpace(m.TextContent()); text != "" {
+			texts = append(texts, text)
+		}
+		out.ToolCalls = append(out.ToolCalls, toolCallNames(m)...)
+	}
+	out.Text = strings.Join(texts, "\n")
+	out.Usage, _ = conversation.ParseUsage(resp.Usage, resp.Usages)
+	return out
+}
+
+// toolCallNames lists the tools an assistant message calls, from OpenAI style
+// tool_calls or AI SDK tool-call content parts.
+func toolCallNames(m conversation.ModelMessage) []string {
+	names := make([]string, 0, len(m.ToolCalls))
+	for _, call := range m.ToolCalls {
+		names = append(names, call.Function.Name)
+	}
+	var parts []struct {
+		Type     string `json:"type"`
+		ToolName string `json:"toolName"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err == nil {
+		for _, part := range parts {
+			if part.Type == "tool-call" && part.ToolName != "" {
+				names = append(names, part.ToolName)
+			}
+		}
+	}
+	return names
+}
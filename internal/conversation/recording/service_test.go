@@ -0,0 +1,244 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/conversation/flow"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+const (
+	testBotID   = "00000000-0000-0000-0000-0000000000b1"
+	testModelID = "00000000-0000-0000-0000-0000000000d1"
+)
+
+type fakeStore struct {
+	enabled    bool
+	recordings []sqlc.GatewayRecording
+	created    []sqlc.CreateGatewayRecordingParams
+}
+
+func (f *fakeStore) GetBotGatewayRecording(ctx context.Context, id pgtype.UUID) (bool, error) {
+	return f.enabled, nil
+}
+
+func (f *fakeStore) SetBotGatewayRecording(ctx context.Context, arg sqlc.SetBotGatewayRecordingParams) (bool, error) {
+	f.enabled = arg.GatewayRecording
+	return f.enabled, nil
+}
+
+func (f *fakeStore) CreateGatewayRecording(ctx context.Context, arg sqlc.CreateGatewayRecordingParams) (sqlc.GatewayRecording, error) {
+	f.created = append(f.created, arg)
+	return sqlc.GatewayRecording{}, nil
+}
+
+func (f *fakeStore) GetGatewayRecording(ctx context.Context, arg sqlc.GetGatewayRecordingParams) (sqlc.GatewayRecording, error) {
+	for _, rec := range f.recordings {
+		if rec.ID == arg.ID && rec.BotID == arg.BotID {
+			return rec, nil
+		}
+	}
+	return sqlc.GatewayRecording{}, pgx.ErrNoRows
+}
+
+// ListGatewayRecordings returns the recordings newest first, as stored in
+// insertion order.
+func (f *fakeStore) ListGatewayRecordings(ctx context.Context, arg sqlc.ListGatewayRecordingsParams) ([]sqlc.ListGatewayRecordingsRow, error) {
+	rows := []sqlc.ListGatewayRecordingsRow{}
+	for i := len(f.recordings) - 1; i >= 0 && len(rows) < int(arg.MaxCount); i-- {
+		rec := f.recordings[i]
+		rows = append(rows, sqlc.ListGatewayRecordingsRow{ID: rec.ID, BotID: rec.BotID, Kind: rec.Kind, ModelID: rec.ModelID, CreatedAt: rec.CreatedAt})
+	}
+	return rows, nil
+}
+
+func (f *fakeStore) DeleteGatewayRecordings(ctx context.Context, botID pgtype.UUID) (int64, error) {
+	n := int64(len(f.recordings))
+	f.recordings = nil
+	return n, nil
+}
+
+type fakeReplayer struct {
+	replays  []flow.GatewayReplay
+	response string
+	err      error
+}
+
+func (f *fakeReplayer) ReplayGatewayRound(ctx context.Context, replay flow.GatewayReplay) (flow.GatewayReplayResult, error) {
+	f.replays = append(f.replays, replay)
+	if f.err != nil {
+		return flow.GatewayReplayResult{}, f.err
+	}
+	return flow.GatewayReplayResult{Model: "replay-model", Response: json.RawMessage(f.response), Latency: 2 * time.Second}, nil
+}
+
+func mustUUID(t *testing.T, id string) pgtype.UUID {
+	t.Helper()
+	parsed, err := db.ParseUUID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func recordingRow(t *testing.T, n byte, modelID string, response string) sqlc.GatewayRecording {
+	t.Helper()
+	var id pgtype.UUID
+	id.Bytes[15] = n
+	id.Valid = true
+	row := sqlc.GatewayRecording{
+		ID:        id,
+		BotID:     mustUUID(t, testBotID),
+		Kind:      flow.GatewayKindChat,
+		Request:   []byte(`{"model":{"modelId":"recorded-model"},"query":"hi"}`),
+		Response:  []byte(response),
+		LatencyMs: 900,
+		CreatedAt: pgtype.Timestamptz{Time: time.Date(2025, 7, 1, 10, int(n), 0, 0, time.UTC), Valid: true},
+	}
+	if modelID != "" {
+		row.ModelID = mustUUID(t, modelID)
+	}
+	return row
+}
+
+func newTestService(store *fakeStore, replayer Replayer) *Service {
+	return &Service{queries: store, replayer: replayer, logger: slog.Default()}
+}
+
+const recordedResponse = `{
+	"messages": [
+		{"role": "assistant", "content": [{"type": "text", "text": "Let me check."}, {"type": "tool-call", "toolCallId": "c1", "toolName": "web_search", "input": {}}]},
+		{"role": "tool", "content": [{"type": "tool-result", "toolCallId": "c1"}]},
+		{"role": "assistant", "content": "It is sunny."}
+	],
+	"usage": {"inputTokens": 120, "outputTokens": 30}
+}`
+
+func TestRecordGatewayRoundStoresRound(t *testing.T) {
+	store := &fakeStore{}
+	svc := newTestService(store, nil)
+
+	svc.RecordGatewayRound(context.Background(), flow.GatewayRound{
+		BotID:    testBotID,
+		Kind:     flow.GatewayKindStream,
+		ModelID:  testModelID,
+		Request:  json.RawMessage(`{}`),
+		Response: json.RawMessage(`{}`),
+		Latency:  1250 * time.Millisecond,
+	})
+	if len(store.created) != 1 {
+		t.Fatalf("expected one recording, got %d", len(store.created))
+	}
+	arg := store.created[0]
+	if arg.Kind != flow.GatewayKindStream || arg.LatencyMs != 1250 || !arg.ModelID.Valid || arg.RouteID.Valid {
+		t.Fatalf("unexpected recording %+v", arg)
+	}
+}
+
+func TestReplayComparesWithRecordedAnswer(t *testing.T) {
+	store := &fakeStore{recordings: []sqlc.GatewayRecording{recordingRow(t, 1, testModelID, recordedResponse)}}
+	replayer := &fakeReplayer{response: `{
+		"messages": [{"role": "assistant", "content": "It is sunny."}],
+		"usage": {"inputTokens": 100, "outputTokens": 10}
+	}`}
+	svc := newTestService(store, replayer)
+
+	report, err := svc.Replay(context.Background(), testBotID, ReplayRequest{Token: "Bearer t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayer.replays) != 1 {
+		t.Fatalf("expected one replay, got %d", len(replayer.replays))
+	}
+	sent := replayer.replays[0]
+	if sent.ModelID != testModelID || sent.AllowTools || sent.Token != "Bearer t" {
+		t.Fatalf("expected the recorded model without tools, got %+v", sent)
+	}
+	c := report.Comparisons[0]
+	if c.Original.Model != "recorded-model" || c.Replay.Model != "replay-model" {
+		t.Fatalf("unexpected models %q / %q", c.Original.Model, c.Replay.Model)
+	}
+	if c.Original.Text != "Let me check.\nIt is sunny." || len(c.Original.ToolCalls) != 1 || c.Original.ToolCalls[0] != "web_search" {
+		t.Fatalf("unexpected original %+v", c.Original)
+	}
+	if !c.TextChanged || !c.ToolCallsChanged || c.InputTokensDelta != -20 || c.OutputTokensDelta != -20 {
+		t.Fatalf("unexpected comparison %+v", c)
+	}
+	if report.Replayed != 1 || report.Failed != 0 || report.OriginalUsage.InputTokens != 120 || report.ReplayUsage.OutputTokens != 10 {
+		t.Fatalf("unexpected totals %+v", report)
+	}
+
+	md := report.Markdown()
+	for _, want := range []string{"| Text | Let me check.<br>It is sunny. | It is sunny. |", "| Tool calls | web_search |  |", "Input tokens: 120 → 100"} {
+		if !strings.Contains(md, want) {
+			t.Fatalf("markdown report missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestReplayReportsFailuresPerRecording(t *testing.T) {
+	store := &fakeStore{recordings: []sqlc.GatewayRecording{
+		recordingRow(t, 1, "", recordedResponse),
+		recordingRow(t, 2, testModelID, recordedResponse),
+	}}
+	replayer := &fakeReplayer{err: errors.New("gateway down")}
+	svc := newTestService(store, replayer)
+
+	report, err := svc.Replay(context.Background(), testBotID, ReplayRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 2 || report.Replayed != 0 {
+		t.Fatalf("unexpected totals %+v", report)
+	}
+	// The oldest recording comes first; its model is gone, so it is not sent.
+	if !strings.Contains(report.Comparisons[0].Replay.Error, "no longer exists") {
+		t.Fatalf("unexpected first comparison %+v", report.Comparisons[0])
+	}
+	if report.Comparisons[1].Replay.Error != "gateway down" || len(replayer.replays) != 1 {
+		t.Fatalf("unexpected second comparison %+v", report.Comparisons[1])
+	}
+}
+
+func TestReplayTargetsRequestedModel(t *testing.T) {
+	store := &fakeStore{recordings: []sqlc.GatewayRecording{recordingRow(t, 1, "", recordedResponse)}}
+	replayer := &fakeReplayer{response: recordedResponse}
+	svc := newTestService(store, replayer)
+
+	report, err := svc.Replay(context.Background(), testBotID, ReplayRequest{
+		RecordingIDs: []string{store.recordings[0].ID.String()},
+		ModelID:      "claude-sonnet",
+		GatewayURL:   "http://gateway-canary:8081",
+		AllowTools:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := replayer.replays[0]
+	if sent.ModelID != "claude-sonnet" || sent.GatewayURL != "http://gateway-canary:8081" || !sent.AllowTools {
+		t.Fatalf("unexpected replay target %+v", sent)
+	}
+	if c := report.Comparisons[0]; c.TextChanged || c.ToolCallsChanged || c.InputTokensDelta != 0 {
+		t.Fatalf("expected identical answers, got %+v", c)
+	}
+}
+
+func TestReplayRejectsLargeBatches(t *testing.T) {
+	svc := newTestService(&fakeStore{}, &fakeReplayer{})
+	if _, err := svc.Replay(context.Background(), testBotID, ReplayRequest{Limit: maxReplayBatch + 1}); !errors.Is(err, ErrTooManyRecordings) {
+		t.Fatalf("expected ErrTooManyRecordings, got %v", err)
+	}
+	if _, err := newTestService(&fakeStore{}, nil).Replay(context.Background(), testBotID, ReplayRequest{}); !errors.Is(err, ErrReplayUnavailable) {
+		t.Fatalf("expected ErrReplayUnavailable, got %v", err)
+	}
+}
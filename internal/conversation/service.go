@@ -317,6 +317,22 @@ func (s *Service) UpdateSettings(ctx context.Context, conversationID string, req
 		return Settings{}, err
 	}
 
+	// Only the touched fields are written; the merge happens in the update
+	// so concurrent changes to other fields are kept.
+	var overridesJSON []byte
+	var cleared []string
+	if req.hasOverrides() {
+		patch, err := applyOverrides(chatOverrides{}, req)
+		if err != nil {
+			return Settings{}, err
+		}
+		overridesJSON, err = json.Marshal(patch)
+		if err != nil {
+			return Settings{}, fmt.Errorf("marshal chat overrides: %w", err)
+		}
+		cleared = req.touchedOverrides()
+	}
+
 	chatModelUUID := pgtype.UUID{}
 	if req.ModelID != nil {
 		modelRef := strings.TrimSpace(*req.ModelID)
@@ -329,11 +345,34 @@ func (s *Service) UpdateSettings(ctx context.Context, conversationID string, req
 		}
 	}
 
+	var fallbackModelUUIDs []pgtype.UUID
+	if req.FallbackModelIDs != nil {
+		fallbackModelUUIDs = make([]pgtype.UUID, 0, len(req.FallbackModelIDs))
+		seen := map[pgtype.UUID]struct{}{}
+		for _, ref := range req.FallbackModelIDs {
+			resolved, err := s.resolveModelUUID(ctx, ref)
+			if err != nil {
+				return Settings{}, err
+			}
+			if _, ok := seen[resolved]; ok {
+				continue
+			}
+			seen[resolved] = struct{}{}
+			fallbackModelUUIDs = append(fallbackModelUUIDs, resolved)
+		}
+	}
+
 	row, err := s.queries.UpsertChatSettings(ctx, sqlc.UpsertChatSettingsParams{
-		ID:          pgID,
-		ChatModelID: chatModelUUID,
+		ID:               pgID,
+		ChatModelID:      chatModelUUID,
+		FallbackModelIds: fallbackModelUUIDs,
+		ChatOverrides:    overridesJSON,
+		ClearedOverrides: cleared,
 	})
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Settings{}, ErrChatNotFound
+		}
 		return Settings{}, err
 	}
 	return toSettingsFromUpsert(row), nil
@@ -434,22 +473,26 @@ func toParticipantFields(conversationID, userID pgtype.UUID, role string, joined
 }
 
 func toSettingsFromRead(row sqlc.GetChatSettingsRow) Settings {
-	settings := Settings{
-		ChatID: row.ChatID.String(),
-	}
-	if row.ModelID.Valid {
-		settings.ModelID = uuid.UUID(row.ModelID.Bytes).String()
-	}
-	return settings
+	return toSettingsFields(row.ChatID, row.ModelID, row.FallbackModelIds, row.ChatOverrides)
 }
 
 func toSettingsFromUpsert(row sqlc.UpsertChatSettingsRow) Settings {
+	return toSettingsFields(row.ChatID, row.ModelID, row.FallbackModelIds, row.ChatOverrides)
+}
+
+func toSettingsFields(chatID, modelID pgtype.UUID, fallbackModelIDs []pgtype.UUID, overrides []byte) Settings {
 	settings := Settings{
-		ChatID: row.ChatID.String(),
+		ChatID: chatID.String(),
 	}
-	if row.ModelID.Valid {
-		settings.ModelID = uuid.UUID(row.ModelID.Bytes).String()
+	if modelID.Valid {
+		settings.ModelID = uuid.UUID(modelID.Bytes).String()
+	}
+	for _, id := range fallbackModelIDs {
+		if id.Valid {
+			settings.FallbackModelIDs = append(settings.FallbackModelIDs, uuid.UUID(id.Bytes).String())
+		}
 	}
+	parseChatOverrides(overrides).applyTo(&settings)
 	return settings
 }
 
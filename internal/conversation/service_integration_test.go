@@ -51,7 +51,7 @@ func setupChatPresenceIntegrationTest(t *testing.T) chatPresenceFixture {
 
 	return chatPresenceFixture{
 		chatSvc:            conversation.NewService(logger, queries),
-		messageSvc:         message.NewService(logger, queries),
+		messageSvc:         message.NewService(logger, pool, queries),
 		channelIdentitySvc: identities.NewService(logger, queries),
 		queries:            queries,
 		cleanup:            func() { pool.Close() },
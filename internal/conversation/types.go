@@ -72,12 +72,41 @@ type Participant struct {
 	JoinedAt time.Time `json:"joined_at"`
 }
 
-// Settings holds per-chat configuration.
+// Settings holds per-chat configuration. Unset overrides fall back to the
+// bot settings.
 type Settings struct {
-	ChatID  string `json:"chat_id"`
-	ModelID string `json:"model_id,omitempty"`
+	ChatID           string   `json:"chat_id"`
+	ModelID          string   `json:"model_id,omitempty"`
+	FallbackModelIDs []string `json:"fallback_model_ids,omitempty"`
+	// SystemPrompt holds extra instructions appended to the bot's system prompt.
+	SystemPrompt       string   `json:"system_prompt,omitempty"`
+	Temperature        *float64 `json:"temperature,omitempty"`
+	TopP               *float64 `json:"top_p,omitempty"`
+	ReasoningEnabled   *bool    `json:"reasoning_enabled,omitempty"`
+	ReasoningEffort    string   `json:"reasoning_effort,omitempty"`
+	MaxContextTokens   *int     `json:"max_context_tokens,omitempty"`
+	MaxContextLoadTime *int     `json:"max_context_load_time,omitempty"`
+	// EnabledSkills are loaded into every round of the conversation.
+	EnabledSkills []string `json:"enabled_skills,omitempty"`
+	// AllowedTools limits the agent tool sets (see ToolSets) when non-empty.
+	AllowedTools []string `json:"allowed_tools,omitempty"`
 }
 
+// Agent tool sets that can be allowed per conversation. They match the
+// actions of the agent gateway.
+const (
+	ToolSetWeb      = "web"
+	ToolSetMessage  = "message"
+	ToolSetContact  = "contact"
+	ToolSetSubagent = "subagent"
+	ToolSetSchedule = "schedule"
+	ToolSetSkill    = "skill"
+	ToolSetMemory   = "memory"
+)
+
+// ToolSets lists every agent tool set.
+var ToolSets = []string{ToolSetWeb, ToolSetMessage, ToolSetContact, ToolSetSubagent, ToolSetSchedule, ToolSetSkill, ToolSetMemory}
+
 // CreateRequest is the input for creating a bot-scoped conversation container.
 type CreateRequest struct {
 	Kind         string         `json:"kind"`
@@ -86,9 +115,24 @@ type CreateRequest struct {
 	Metadata     map[string]any `json:"metadata,omitempty"`
 }
 
-// UpdateSettingsRequest is the input for updating chat settings.
+// UpdateSettingsRequest is the input for updating chat settings. Nil fields
+// are left unchanged; Reset clears overrides by their JSON name so the bot
+// setting applies again.
 type UpdateSettingsRequest struct {
 	ModelID *string `json:"model_id,omitempty"`
+	// FallbackModelIDs replaces the ordered fallback chain when non-nil.
+	FallbackModelIDs   []string `json:"fallback_model_ids,omitempty"`
+	SystemPrompt       *string  `json:"system_prompt,omitempty"`
+	Temperature        *float64 `json:"temperature,omitempty"`
+	TopP               *float64 `json:"top_p,omitempty"`
+	ReasoningEnabled   *bool    `json:"reasoning_enabled,omitempty"`
+	ReasoningEffort    *string  `json:"reasoning_effort,omitempty"`
+	MaxContextTokens   *int     `json:"max_context_tokens,omitempty"`
+	MaxContextLoadTime *int     `json:"max_context_load_time,omitempty"`
+	// EnabledSkills and AllowedTools replace the current lists when non-nil.
+	EnabledSkills []string `json:"enabled_skills,omitempty"`
+	AllowedTools  []string `json:"allowed_tools,omitempty"`
+	Reset         []string `json:"reset,omitempty"`
 }
 
 // ModelMessage is the canonical message format exchanged with the agent gateway.
@@ -230,6 +274,18 @@ type ChatRequest struct {
 	ConversationType        string `json:"-"`
 	ConversationName        string `json:"-"`
 	UserMessagePersisted    bool   `json:"-"`
+	// RerunMessageID is a stored user message answered again. It is left out
+	// of the loaded history because Query carries it.
+	RerunMessageID string `json:"-"`
+	// RequestID names a streaming reply so it can be cancelled. StreamChat
+	// assigns one when empty.
+	RequestID string `json:"-"`
+	// InputModerated marks Query as screened already, as the inbound
+	// processor does before it persists the user message.
+	InputModerated bool `json:"-"`
+	// InputModeration is the moderation verdict on Query, stored in the
+	// metadata of the user message.
+	InputModeration map[string]any `json:"-"`
 
 	// OutboundAssetCollector returns asset refs accumulated during outbound streaming.
 	// Set by the inbound channel processor; called by the resolver at persist time.
@@ -253,6 +309,82 @@ type ChatResponse struct {
 	Skills   []string       `json:"skills,omitempty"`
 	Model    string         `json:"model,omitempty"`
 	Provider string         `json:"provider,omitempty"`
+	Usage    *Usage         `json:"usage,omitempty"`
+}
+
+// Usage is the token accounting of one chat round. Input tokens include the
+// cached input and output tokens include reasoning.
+type Usage struct {
+	InputTokens       int `json:"input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+	CachedInputTokens int `json:"cached_input_tokens,omitempty"`
+	ReasoningTokens   int `json:"reasoning_tokens,omitempty"`
+}
+
+// TotalTokens returns the sum of input and output tokens.
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// ParseUsage reads the usage reported by the agent gateway for a round. The
+// round total is preferred; without it the per-message usages are summed.
+func ParseUsage(total json.RawMessage, perMessage []json.RawMessage) (Usage, bool) {
+	type gatewayUsage struct {
+		InputTokens       *int `json:"inputTokens"`
+		OutputTokens      *int `json:"outputTokens"`
+		CachedInputTokens *int `json:"cachedInputTokens"`
+		ReasoningTokens   *int `json:"reasoningTokens"`
+		InputTokenDetails struct {
+			CacheReadTokens *int `json:"cacheReadTokens"`
+		} `json:"inputTokenDetails"`
+		OutputTokenDetails struct {
+			ReasoningTokens *int `json:"reasoningTokens"`
+		} `json:"outputTokenDetails"`
+	}
+	// first returns the first reported count, the detailed field winning
+	// over the older flat one.
+	first := func(values ...*int) int {
+		for _, v := range values {
+			if v != nil {
+				return *v
+			}
+		}
+		return 0
+	}
+	parse := func(raw json.RawMessage) (Usage, bool) {
+		if len(raw) == 0 {
+			return Usage{}, false
+		}
+		var u gatewayUsage
+		if err := json.Unmarshal(raw, &u); err != nil || (u.InputTokens == nil && u.OutputTokens == nil) {
+			return Usage{}, false
+		}
+		var out Usage
+		if u.InputTokens != nil {
+			out.InputTokens = *u.InputTokens
+		}
+		if u.OutputTokens != nil {
+			out.OutputTokens = *u.OutputTokens
+		}
+		out.CachedInputTokens = first(u.InputTokenDetails.CacheReadTokens, u.CachedInputTokens)
+		out.ReasoningTokens = first(u.OutputTokenDetails.ReasoningTokens, u.ReasoningTokens)
+		return out, true
+	}
+	if usage, ok := parse(total); ok {
+		return usage, true
+	}
+	var sum Usage
+	found := false
+	for _, raw := range perMessage {
+		if usage, ok := parse(raw); ok {
+			sum.InputTokens += usage.InputTokens
+			sum.OutputTokens += usage.OutputTokens
+			sum.CachedInputTokens += usage.CachedInputTokens
+			sum.ReasoningTokens += usage.ReasoningTokens
+			found = true
+		}
+	}
+	return sum, found
 }
 
 // StreamChunk is a raw JSON chunk from the streaming response.
@@ -0,0 +1,31 @@
+package conversation
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseUsage(t *testing.T) {
+	usage, ok := ParseUsage(json.RawMessage(`{"inputTokens":10,"outputTokens":4}`), nil)
+	if !ok || usage.InputTokens != 10 || usage.OutputTokens != 4 || usage.TotalTokens() != 14 {
+		t.Fatalf("unexpected total usage: %#v %v", usage, ok)
+	}
+
+	usage, ok = ParseUsage(json.RawMessage(`null`), []json.RawMessage{
+		json.RawMessage(`{"inputTokens":3,"outputTokens":1}`),
+		json.RawMessage(`null`),
+		json.RawMessage(`{"inputTokens":5,"outputTokens":2}`),
+	})
+	if !ok || usage.InputTokens != 8 || usage.OutputTokens != 3 {
+		t.Fatalf("unexpected summed usage: %#v %v", usage, ok)
+	}
+
+	usage, _ = ParseUsage(json.RawMessage(`{"inputTokens":100,"outputTokens":40,"cachedInputTokens":1,"inputTokenDetails":{"cacheReadTokens":60},"reasoningTokens":30}`), nil)
+	if usage.CachedInputTokens != 60 || usage.ReasoningTokens != 30 {
+		t.Fatalf("unexpected token details: %#v", usage)
+	}
+
+	if _, ok := ParseUsage(nil, nil); ok {
+		t.Fatal("expected no usage")
+	}
+}
@@ -272,6 +272,8 @@ const getChatSettings = `-- name: GetChatSettings :one
 SELECT
   b.id AS chat_id,
   chat_models.id AS model_id,
+  b.fallback_model_ids,
+  b.chat_overrides,
   b.updated_at
 FROM bots b
 LEFT JOIN models chat_models ON chat_models.id = b.chat_model_id
@@ -279,15 +281,23 @@ WHERE b.id = $1
 `
 
 type GetChatSettingsRow struct {
-	ChatID    pgtype.UUID        `json:"chat_id"`
-	ModelID   pgtype.UUID        `json:"model_id"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ChatID           pgtype.UUID        `json:"chat_id"`
+	ModelID          pgtype.UUID        `json:"model_id"`
+	FallbackModelIds []pgtype.UUID      `json:"fallback_model_ids"`
+	ChatOverrides    []byte             `json:"chat_overrides"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
 }
 
 func (q *Queries) GetChatSettings(ctx context.Context, id pgtype.UUID) (GetChatSettingsRow, error) {
 	row := q.db.QueryRow(ctx, getChatSettings, id)
 	var i GetChatSettingsRow
-	err := row.Scan(&i.ChatID, &i.ModelID, &i.UpdatedAt)
+	err := row.Scan(
+		&i.ChatID,
+		&i.ModelID,
+		&i.FallbackModelIds,
+		&i.ChatOverrides,
+		&i.UpdatedAt,
+	)
 	return i, err
 }
 
@@ -590,7 +600,7 @@ WITH updated AS (
   SET display_name = $1,
       updated_at = now()
   WHERE bots.id = $2
-  RETURNING id, owner_user_id, type, display_name, avatar_url, is_active, status, max_context_load_time, max_context_tokens, language, allow_guest, reasoning_enabled, reasoning_effort, max_inbox_items, chat_model_id, memory_model_id, embedding_model_id, search_provider_id, metadata, created_at, updated_at
+  RETURNING id, owner_user_id, type, display_name, avatar_url, is_active, status, max_context_load_time, max_context_tokens, language, allow_guest, reasoning_enabled, reasoning_effort, max_inbox_items, chat_model_id, memory_model_id, embedding_model_id, search_provider_id, tts_enabled, tts_model_id, tts_voice, fallback_model_ids, metadata, created_at, updated_at
 )
 SELECT
   updated.id AS id,
@@ -649,33 +659,59 @@ WITH
 updated AS (
   UPDATE bots
   SET chat_model_id = COALESCE($1::uuid, bots.chat_model_id),
+      fallback_model_ids = COALESCE($2::uuid[], bots.fallback_model_ids),
+      chat_overrides = CASE
+        WHEN $3::jsonb IS NULL THEN bots.chat_overrides
+        ELSE (COALESCE(bots.chat_overrides, '{}'::jsonb) - $4::text[]) || $3::jsonb
+      END,
       updated_at = now()
-  WHERE bots.id = $2
-  RETURNING bots.id, bots.chat_model_id, bots.updated_at
+  WHERE bots.id = $5
+  RETURNING bots.id, bots.chat_model_id, bots.fallback_model_ids, bots.chat_overrides, bots.updated_at
 )
 SELECT
   updated.id AS chat_id,
   chat_models.id AS model_id,
+  updated.fallback_model_ids,
+  updated.chat_overrides,
   updated.updated_at
 FROM updated
 LEFT JOIN models chat_models ON chat_models.id = updated.chat_model_id
 `
 
 type UpsertChatSettingsParams struct {
-	ChatModelID pgtype.UUID `json:"chat_model_id"`
-	ID          pgtype.UUID `json:"id"`
+	ChatModelID      pgtype.UUID   `json:"chat_model_id"`
+	FallbackModelIds []pgtype.UUID `json:"fallback_model_ids"`
+	ChatOverrides    []byte        `json:"chat_overrides"`
+	ClearedOverrides []string      `json:"cleared_overrides"`
+	ID               pgtype.UUID   `json:"id"`
 }
 
 type UpsertChatSettingsRow struct {
-	ChatID    pgtype.UUID        `json:"chat_id"`
-	ModelID   pgtype.UUID        `json:"model_id"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ChatID           pgtype.UUID        `json:"chat_id"`
+	ModelID          pgtype.UUID        `json:"model_id"`
+	FallbackModelIds []pgtype.UUID      `json:"fallback_model_ids"`
+	ChatOverrides    []byte             `json:"chat_overrides"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
 }
 
-// chat_settings
+// chat_overrides is merged into the stored overrides after dropping the keys
+// in cleared_overrides, so concurrent updates of different fields keep each
+// other's changes.
 func (q *Queries) UpsertChatSettings(ctx context.Context, arg UpsertChatSettingsParams) (UpsertChatSettingsRow, error) {
-	row := q.db.QueryRow(ctx, upsertChatSettings, arg.ChatModelID, arg.ID)
+	row := q.db.QueryRow(ctx, upsertChatSettings,
+		arg.ChatModelID,
+		arg.FallbackModelIds,
+		arg.ChatOverrides,
+		arg.ClearedOverrides,
+		arg.ID,
+	)
 	var i UpsertChatSettingsRow
-	err := row.Scan(&i.ChatID, &i.ModelID, &i.UpdatedAt)
+	err := row.Scan(
+		&i.ChatID,
+		&i.ModelID,
+		&i.FallbackModelIds,
+		&i.ChatOverrides,
+		&i.UpdatedAt,
+	)
 	return i, err
 }
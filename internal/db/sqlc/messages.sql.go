@@ -23,7 +23,9 @@ INSERT INTO bot_history_messages (
   role,
   content,
   metadata,
-  usage
+  usage,
+  parent_id,
+  created_at
 )
 VALUES (
   $1,
@@ -36,7 +38,19 @@ VALUES (
   $8,
   $9,
   $10,
-  $11
+  $11,
+  -- Without an explicit parent the message continues the active branch of
+  -- its route, unless no_parent starts a new thread.
+  COALESCE($12::uuid, (
+    SELECT p.id FROM bot_history_messages p
+    WHERE p.bot_id = $1
+      AND p.route_id IS NOT DISTINCT FROM $2::uuid
+      AND p.superseded_at IS NULL
+      AND NOT $13::boolean
+    ORDER BY p.created_at DESC
+    LIMIT 1
+  )),
+  COALESCE($14::timestamptz, now())
 )
 RETURNING
   id,
@@ -51,21 +65,25 @@ RETURNING
   content,
   metadata,
   usage,
+  parent_id,
   created_at
 `
 
 type CreateMessageParams struct {
-	BotID                   pgtype.UUID `json:"bot_id"`
-	RouteID                 pgtype.UUID `json:"route_id"`
-	SenderChannelIdentityID pgtype.UUID `json:"sender_channel_identity_id"`
-	SenderUserID            pgtype.UUID `json:"sender_user_id"`
-	Platform                pgtype.Text `json:"platform"`
-	ExternalMessageID       pgtype.Text `json:"external_message_id"`
-	SourceReplyToMessageID  pgtype.Text `json:"source_reply_to_message_id"`
-	Role                    string      `json:"role"`
-	Content                 []byte      `json:"content"`
-	Metadata                []byte      `json:"metadata"`
-	Usage                   []byte      `json:"usage"`
+	BotID                   pgtype.UUID        `json:"bot_id"`
+	RouteID                 pgtype.UUID        `json:"route_id"`
+	SenderChannelIdentityID pgtype.UUID        `json:"sender_channel_identity_id"`
+	SenderUserID            pgtype.UUID        `json:"sender_user_id"`
+	Platform                pgtype.Text        `json:"platform"`
+	ExternalMessageID       pgtype.Text        `json:"external_message_id"`
+	SourceReplyToMessageID  pgtype.Text        `json:"source_reply_to_message_id"`
+	Role                    string             `json:"role"`
+	Content                 []byte             `json:"content"`
+	Metadata                []byte             `json:"metadata"`
+	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
+	NoParent                bool               `json:"no_parent"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
 }
 
 type CreateMessageRow struct {
@@ -81,6 +99,7 @@ type CreateMessageRow struct {
 	Content                 []byte             `json:"content"`
 	Metadata                []byte             `json:"metadata"`
 	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
 	CreatedAt               pgtype.Timestamptz `json:"created_at"`
 }
 
@@ -97,6 +116,9 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (C
 		arg.Content,
 		arg.Metadata,
 		arg.Usage,
+		arg.ParentID,
+		arg.NoParent,
+		arg.CreatedAt,
 	)
 	var i CreateMessageRow
 	err := row.Scan(
@@ -112,11 +134,35 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (C
 		&i.Content,
 		&i.Metadata,
 		&i.Usage,
+		&i.ParentID,
 		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const deleteMessageReaction = `-- name: DeleteMessageReaction :execrows
+DELETE FROM bot_history_message_reactions r
+USING bot_history_messages m
+WHERE r.message_id = m.id
+  AND m.id = $1
+  AND m.bot_id = $2
+  AND r.user_id = $3
+`
+
+type DeleteMessageReactionParams struct {
+	MessageID pgtype.UUID `json:"message_id"`
+	BotID     pgtype.UUID `json:"bot_id"`
+	UserID    pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteMessageReaction(ctx context.Context, arg DeleteMessageReactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteMessageReaction, arg.MessageID, arg.BotID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteMessagesByBot = `-- name: DeleteMessagesByBot :exec
 DELETE FROM bot_history_messages
 WHERE bot_id = $1
@@ -127,6 +173,105 @@ func (q *Queries) DeleteMessagesByBot(ctx context.Context, botID pgtype.UUID) er
 	return err
 }
 
+const getLatestActiveUserMessageID = `-- name: GetLatestActiveUserMessageID :one
+SELECT id
+FROM bot_history_messages
+WHERE bot_id = $1
+  AND route_id IS NOT DISTINCT FROM $2::uuid
+  AND role = 'user'
+  AND superseded_at IS NULL
+  AND (metadata->>'trigger_mode' IS NULL OR metadata->>'trigger_mode' != 'passive_sync')
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetLatestActiveUserMessageIDParams struct {
+	BotID   pgtype.UUID `json:"bot_id"`
+	RouteID pgtype.UUID `json:"route_id"`
+}
+
+func (q *Queries) GetLatestActiveUserMessageID(ctx context.Context, arg GetLatestActiveUserMessageIDParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, getLatestActiveUserMessageID, arg.BotID, arg.RouteID)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getMessage = `-- name: GetMessage :one
+SELECT
+  m.id,
+  m.bot_id,
+  m.route_id,
+  m.sender_channel_identity_id,
+  m.sender_account_user_id AS sender_user_id,
+  m.channel_type AS platform,
+  m.source_message_id AS external_message_id,
+  m.source_reply_to_message_id,
+  m.role,
+  m.content,
+  m.metadata,
+  m.usage,
+  m.parent_id,
+  m.superseded_at,
+  m.created_at,
+  ci.display_name AS sender_display_name,
+  ci.avatar_url AS sender_avatar_url
+FROM bot_history_messages m
+LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
+WHERE m.id = $1
+  AND m.bot_id = $2
+`
+
+type GetMessageParams struct {
+	ID    pgtype.UUID `json:"id"`
+	BotID pgtype.UUID `json:"bot_id"`
+}
+
+type GetMessageRow struct {
+	ID                      pgtype.UUID        `json:"id"`
+	BotID                   pgtype.UUID        `json:"bot_id"`
+	RouteID                 pgtype.UUID        `json:"route_id"`
+	SenderChannelIdentityID pgtype.UUID        `json:"sender_channel_identity_id"`
+	SenderUserID            pgtype.UUID        `json:"sender_user_id"`
+	Platform                pgtype.Text        `json:"platform"`
+	ExternalMessageID       pgtype.Text        `json:"external_message_id"`
+	SourceReplyToMessageID  pgtype.Text        `json:"source_reply_to_message_id"`
+	Role                    string             `json:"role"`
+	Content                 []byte             `json:"content"`
+	Metadata                []byte             `json:"metadata"`
+	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
+	SupersededAt            pgtype.Timestamptz `json:"superseded_at"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
+	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
+}
+
+func (q *Queries) GetMessage(ctx context.Context, arg GetMessageParams) (GetMessageRow, error) {
+	row := q.db.QueryRow(ctx, getMessage, arg.ID, arg.BotID)
+	var i GetMessageRow
+	err := row.Scan(
+		&i.ID,
+		&i.BotID,
+		&i.RouteID,
+		&i.SenderChannelIdentityID,
+		&i.SenderUserID,
+		&i.Platform,
+		&i.ExternalMessageID,
+		&i.SourceReplyToMessageID,
+		&i.Role,
+		&i.Content,
+		&i.Metadata,
+		&i.Usage,
+		&i.ParentID,
+		&i.SupersededAt,
+		&i.CreatedAt,
+		&i.SenderDisplayName,
+		&i.SenderAvatarUrl,
+	)
+	return i, err
+}
+
 const listActiveMessagesSince = `-- name: ListActiveMessagesSince :many
 SELECT
   m.id,
@@ -141,12 +286,14 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
 FROM bot_history_messages m
 LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
 WHERE m.bot_id = $1
+  AND m.superseded_at IS NULL
   AND m.created_at >= $2
   AND (m.metadata->>'trigger_mode' IS NULL OR m.metadata->>'trigger_mode' != 'passive_sync')
 ORDER BY m.created_at ASC
@@ -170,6 +317,7 @@ type ListActiveMessagesSinceRow struct {
 	Content                 []byte             `json:"content"`
 	Metadata                []byte             `json:"metadata"`
 	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
 	CreatedAt               pgtype.Timestamptz `json:"created_at"`
 	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
 	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
@@ -197,6 +345,7 @@ func (q *Queries) ListActiveMessagesSince(ctx context.Context, arg ListActiveMes
 			&i.Content,
 			&i.Metadata,
 			&i.Usage,
+			&i.ParentID,
 			&i.CreatedAt,
 			&i.SenderDisplayName,
 			&i.SenderAvatarUrl,
@@ -211,6 +360,161 @@ func (q *Queries) ListActiveMessagesSince(ctx context.Context, arg ListActiveMes
 	return items, nil
 }
 
+const listMessageAlternatives = `-- name: ListMessageAlternatives :many
+SELECT
+  m.id,
+  m.bot_id,
+  m.route_id,
+  m.sender_channel_identity_id,
+  m.sender_account_user_id AS sender_user_id,
+  m.channel_type AS platform,
+  m.source_message_id AS external_message_id,
+  m.source_reply_to_message_id,
+  m.role,
+  m.content,
+  m.metadata,
+  m.usage,
+  m.parent_id,
+  m.superseded_at,
+  m.created_at,
+  ci.display_name AS sender_display_name,
+  ci.avatar_url AS sender_avatar_url
+FROM bot_history_messages m
+LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
+WHERE m.bot_id = $1
+  AND m.parent_id IS NOT DISTINCT FROM $2::uuid
+ORDER BY m.created_at ASC
+`
+
+type ListMessageAlternativesParams struct {
+	BotID    pgtype.UUID `json:"bot_id"`
+	ParentID pgtype.UUID `json:"parent_id"`
+}
+
+type ListMessageAlternativesRow struct {
+	ID                      pgtype.UUID        `json:"id"`
+	BotID                   pgtype.UUID        `json:"bot_id"`
+	RouteID                 pgtype.UUID        `json:"route_id"`
+	SenderChannelIdentityID pgtype.UUID        `json:"sender_channel_identity_id"`
+	SenderUserID            pgtype.UUID        `json:"sender_user_id"`
+	Platform                pgtype.Text        `json:"platform"`
+	ExternalMessageID       pgtype.Text        `json:"external_message_id"`
+	SourceReplyToMessageID  pgtype.Text        `json:"source_reply_to_message_id"`
+	Role                    string             `json:"role"`
+	Content                 []byte             `json:"content"`
+	Metadata                []byte             `json:"metadata"`
+	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
+	SupersededAt            pgtype.Timestamptz `json:"superseded_at"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
+	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
+}
+
+func (q *Queries) ListMessageAlternatives(ctx context.Context, arg ListMessageAlternativesParams) ([]ListMessageAlternativesRow, error) {
+	rows, err := q.db.Query(ctx, listMessageAlternatives, arg.BotID, arg.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMessageAlternativesRow
+	for rows.Next() {
+		var i ListMessageAlternativesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BotID,
+			&i.RouteID,
+			&i.SenderChannelIdentityID,
+			&i.SenderUserID,
+			&i.Platform,
+			&i.ExternalMessageID,
+			&i.SourceReplyToMessageID,
+			&i.Role,
+			&i.Content,
+			&i.Metadata,
+			&i.Usage,
+			&i.ParentID,
+			&i.SupersededAt,
+			&i.CreatedAt,
+			&i.SenderDisplayName,
+			&i.SenderAvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessageSenderIDs = `-- name: ListMessageSenderIDs :many
+SELECT sender_channel_identity_id::text AS sender_id
+FROM bot_history_messages
+WHERE bot_id = $1 AND sender_channel_identity_id IS NOT NULL
+UNION
+SELECT sender_account_user_id::text
+FROM bot_history_messages
+WHERE bot_id = $1 AND sender_account_user_id IS NOT NULL
+`
+
+// Lists the channel identities and users that have messages in a bot.
+func (q *Queries) ListMessageSenderIDs(ctx context.Context, botID pgtype.UUID) ([]string, error) {
+	rows, err := q.db.Query(ctx, listMessageSenderIDs, botID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var sender_id string
+		if err := rows.Scan(&sender_id); err != nil {
+			return nil, err
+		}
+		items = append(items, sender_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessageSourceIDs = `-- name: ListMessageSourceIDs :many
+SELECT
+  id,
+  COALESCE(metadata->'imported_from'->>'message_id', id::text)::text AS source_id
+FROM bot_history_messages
+WHERE bot_id = $1
+`
+
+type ListMessageSourceIDsRow struct {
+	ID       pgtype.UUID `json:"id"`
+	SourceID string      `json:"source_id"`
+}
+
+// Pairs every message of a bot with the archived message it was imported
+// from, or with itself when it was not imported.
+func (q *Queries) ListMessageSourceIDs(ctx context.Context, botID pgtype.UUID) ([]ListMessageSourceIDsRow, error) {
+	rows, err := q.db.Query(ctx, listMessageSourceIDs, botID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMessageSourceIDsRow
+	for rows.Next() {
+		var i ListMessageSourceIDsRow
+		if err := rows.Scan(&i.ID, &i.SourceID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMessages = `-- name: ListMessages :many
 SELECT
   m.id,
@@ -225,6 +529,7 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
@@ -248,6 +553,7 @@ type ListMessagesRow struct {
 	Content                 []byte             `json:"content"`
 	Metadata                []byte             `json:"metadata"`
 	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
 	CreatedAt               pgtype.Timestamptz `json:"created_at"`
 	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
 	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
@@ -275,6 +581,7 @@ func (q *Queries) ListMessages(ctx context.Context, botID pgtype.UUID) ([]ListMe
 			&i.Content,
 			&i.Metadata,
 			&i.Usage,
+			&i.ParentID,
 			&i.CreatedAt,
 			&i.SenderDisplayName,
 			&i.SenderAvatarUrl,
@@ -303,12 +610,14 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
 FROM bot_history_messages m
 LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
 WHERE m.bot_id = $1
+  AND m.superseded_at IS NULL
   AND m.created_at < $2
 ORDER BY m.created_at DESC
 LIMIT $3
@@ -333,6 +642,7 @@ type ListMessagesBeforeRow struct {
 	Content                 []byte             `json:"content"`
 	Metadata                []byte             `json:"metadata"`
 	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
 	CreatedAt               pgtype.Timestamptz `json:"created_at"`
 	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
 	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
@@ -360,6 +670,97 @@ func (q *Queries) ListMessagesBefore(ctx context.Context, arg ListMessagesBefore
 			&i.Content,
 			&i.Metadata,
 			&i.Usage,
+			&i.ParentID,
+			&i.CreatedAt,
+			&i.SenderDisplayName,
+			&i.SenderAvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessagesByIDs = `-- name: ListMessagesByIDs :many
+SELECT
+  m.id,
+  m.bot_id,
+  m.route_id,
+  m.sender_channel_identity_id,
+  m.sender_account_user_id AS sender_user_id,
+  m.channel_type AS platform,
+  m.source_message_id AS external_message_id,
+  m.source_reply_to_message_id,
+  m.role,
+  m.content,
+  m.metadata,
+  m.usage,
+  m.parent_id,
+  m.superseded_at,
+  m.created_at,
+  ci.display_name AS sender_display_name,
+  ci.avatar_url AS sender_avatar_url
+FROM bot_history_messages m
+LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
+WHERE m.bot_id = $1
+  AND m.id = ANY($2::uuid[])
+ORDER BY m.created_at ASC
+`
+
+type ListMessagesByIDsParams struct {
+	BotID pgtype.UUID   `json:"bot_id"`
+	Ids   []pgtype.UUID `json:"ids"`
+}
+
+type ListMessagesByIDsRow struct {
+	ID                      pgtype.UUID        `json:"id"`
+	BotID                   pgtype.UUID        `json:"bot_id"`
+	RouteID                 pgtype.UUID        `json:"route_id"`
+	SenderChannelIdentityID pgtype.UUID        `json:"sender_channel_identity_id"`
+	SenderUserID            pgtype.UUID        `json:"sender_user_id"`
+	Platform                pgtype.Text        `json:"platform"`
+	ExternalMessageID       pgtype.Text        `json:"external_message_id"`
+	SourceReplyToMessageID  pgtype.Text        `json:"source_reply_to_message_id"`
+	Role                    string             `json:"role"`
+	Content                 []byte             `json:"content"`
+	Metadata                []byte             `json:"metadata"`
+	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
+	SupersededAt            pgtype.Timestamptz `json:"superseded_at"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
+	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
+}
+
+// Loads the given messages of a bot, superseded ones included.
+func (q *Queries) ListMessagesByIDs(ctx context.Context, arg ListMessagesByIDsParams) ([]ListMessagesByIDsRow, error) {
+	rows, err := q.db.Query(ctx, listMessagesByIDs, arg.BotID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMessagesByIDsRow
+	for rows.Next() {
+		var i ListMessagesByIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BotID,
+			&i.RouteID,
+			&i.SenderChannelIdentityID,
+			&i.SenderUserID,
+			&i.Platform,
+			&i.ExternalMessageID,
+			&i.SourceReplyToMessageID,
+			&i.Role,
+			&i.Content,
+			&i.Metadata,
+			&i.Usage,
+			&i.ParentID,
+			&i.SupersededAt,
 			&i.CreatedAt,
 			&i.SenderDisplayName,
 			&i.SenderAvatarUrl,
@@ -388,12 +789,14 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
 FROM bot_history_messages m
 LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
 WHERE m.bot_id = $1
+  AND m.superseded_at IS NULL
 ORDER BY m.created_at DESC
 LIMIT $2
 `
@@ -416,6 +819,7 @@ type ListMessagesLatestRow struct {
 	Content                 []byte             `json:"content"`
 	Metadata                []byte             `json:"metadata"`
 	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
 	CreatedAt               pgtype.Timestamptz `json:"created_at"`
 	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
 	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
@@ -443,6 +847,7 @@ func (q *Queries) ListMessagesLatest(ctx context.Context, arg ListMessagesLatest
 			&i.Content,
 			&i.Metadata,
 			&i.Usage,
+			&i.ParentID,
 			&i.CreatedAt,
 			&i.SenderDisplayName,
 			&i.SenderAvatarUrl,
@@ -471,12 +876,14 @@ SELECT
   m.content,
   m.metadata,
   m.usage,
+  m.parent_id,
   m.created_at,
   ci.display_name AS sender_display_name,
   ci.avatar_url AS sender_avatar_url
 FROM bot_history_messages m
 LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
 WHERE m.bot_id = $1
+  AND m.superseded_at IS NULL
   AND m.created_at >= $2
 ORDER BY m.created_at ASC
 `
@@ -499,6 +906,7 @@ type ListMessagesSinceRow struct {
 	Content                 []byte             `json:"content"`
 	Metadata                []byte             `json:"metadata"`
 	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
 	CreatedAt               pgtype.Timestamptz `json:"created_at"`
 	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
 	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
@@ -526,7 +934,184 @@ func (q *Queries) ListMessagesSince(ctx context.Context, arg ListMessagesSincePa
 			&i.Content,
 			&i.Metadata,
 			&i.Usage,
+			&i.ParentID,
+			&i.CreatedAt,
+			&i.SenderDisplayName,
+			&i.SenderAvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreMessageBranch = `-- name: RestoreMessageBranch :execrows
+UPDATE bot_history_messages m
+SET superseded_at = NULL
+FROM bot_history_messages t
+WHERE t.id = $1
+  AND t.bot_id = $2
+  AND m.bot_id = t.bot_id
+  AND m.route_id IS NOT DISTINCT FROM t.route_id
+  AND m.superseded_at = t.superseded_at
+  AND m.created_at >= t.created_at
+`
+
+type RestoreMessageBranchParams struct {
+	ID    pgtype.UUID `json:"id"`
+	BotID pgtype.UUID `json:"bot_id"`
+}
+
+// Re-activates a superseded message and the rest of the branch that was
+// superseded together with it.
+func (q *Queries) RestoreMessageBranch(ctx context.Context, arg RestoreMessageBranchParams) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreMessageBranch, arg.ID, arg.BotID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreMessages = `-- name: RestoreMessages :exec
+UPDATE bot_history_messages
+SET superseded_at = NULL
+WHERE bot_id = $1
+  AND id = ANY($2::uuid[])
+`
+
+type RestoreMessagesParams struct {
+	BotID pgtype.UUID   `json:"bot_id"`
+	Ids   []pgtype.UUID `json:"ids"`
+}
+
+func (q *Queries) RestoreMessages(ctx context.Context, arg RestoreMessagesParams) error {
+	_, err := q.db.Exec(ctx, restoreMessages, arg.BotID, arg.Ids)
+	return err
+}
+
+const searchMessages = `-- name: SearchMessages :many
+SELECT
+  m.id,
+  m.bot_id,
+  m.route_id,
+  m.sender_channel_identity_id,
+  m.sender_account_user_id AS sender_user_id,
+  m.channel_type AS platform,
+  m.source_message_id AS external_message_id,
+  m.source_reply_to_message_id,
+  m.role,
+  m.content,
+  m.metadata,
+  m.usage,
+  m.parent_id,
+  m.created_at,
+  m.search_text,
+  ci.display_name AS sender_display_name,
+  ci.avatar_url AS sender_avatar_url
+FROM bot_history_messages m
+LEFT JOIN channel_identities ci ON ci.id = m.sender_channel_identity_id
+WHERE m.bot_id = $1
+  AND m.superseded_at IS NULL
+  AND (
+    to_tsvector('simple', m.search_text) @@ websearch_to_tsquery('simple', $2::text)
+    OR m.search_text ILIKE '%' || $3::text || '%' ESCAPE '\'
+  )
+  AND ($4::text IS NULL OR m.channel_type = $4::text)
+  AND ($5::text IS NULL OR m.role = $5::text)
+  AND (
+    $6::uuid IS NULL
+    OR m.sender_channel_identity_id = $6::uuid
+    OR m.sender_account_user_id = $6::uuid
+  )
+  AND ($7::text IS NULL OR ci.display_name ILIKE '%' || $7::text || '%' ESCAPE '\')
+  AND ($8::timestamptz IS NULL OR m.created_at >= $8::timestamptz)
+  AND ($9::timestamptz IS NULL OR m.created_at <= $9::timestamptz)
+  AND (NOT $10::boolean OR m.route_id IS NOT DISTINCT FROM $11::uuid)
+ORDER BY ts_rank(to_tsvector('simple', m.search_text), websearch_to_tsquery('simple', $2::text)) DESC, m.created_at DESC
+LIMIT $12
+`
+
+type SearchMessagesParams struct {
+	BotID       pgtype.UUID        `json:"bot_id"`
+	Query       string             `json:"query"`
+	Pattern     string             `json:"pattern"`
+	Platform    pgtype.Text        `json:"platform"`
+	Role        pgtype.Text        `json:"role"`
+	SenderID    pgtype.UUID        `json:"sender_id"`
+	SenderName  pgtype.Text        `json:"sender_name"`
+	StartTime   pgtype.Timestamptz `json:"start_time"`
+	EndTime     pgtype.Timestamptz `json:"end_time"`
+	RouteScoped bool               `json:"route_scoped"`
+	RouteID     pgtype.UUID        `json:"route_id"`
+	MaxCount    int32              `json:"max_count"`
+}
+
+type SearchMessagesRow struct {
+	ID                      pgtype.UUID        `json:"id"`
+	BotID                   pgtype.UUID        `json:"bot_id"`
+	RouteID                 pgtype.UUID        `json:"route_id"`
+	SenderChannelIdentityID pgtype.UUID        `json:"sender_channel_identity_id"`
+	SenderUserID            pgtype.UUID        `json:"sender_user_id"`
+	Platform                pgtype.Text        `json:"platform"`
+	ExternalMessageID       pgtype.Text        `json:"external_message_id"`
+	SourceReplyToMessageID  pgtype.Text        `json:"source_reply_to_message_id"`
+	Role                    string             `json:"role"`
+	Content                 []byte             `json:"content"`
+	Metadata                []byte             `json:"metadata"`
+	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
+	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	SearchText              pgtype.Text        `json:"search_text"`
+	SenderDisplayName       pgtype.Text        `json:"sender_display_name"`
+	SenderAvatarUrl         pgtype.Text        `json:"sender_avatar_url"`
+}
+
+// Full-text match on the active branch, with a substring fallback for text
+// the simple parser does not split into words (CJK). pattern and sender_name
+// have their LIKE wildcards escaped with a backslash. route_scoped limits the
+// search to route_id, or to the routeless history when it is null.
+func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error) {
+	rows, err := q.db.Query(ctx, searchMessages,
+		arg.BotID,
+		arg.Query,
+		arg.Pattern,
+		arg.Platform,
+		arg.Role,
+		arg.SenderID,
+		arg.SenderName,
+		arg.StartTime,
+		arg.EndTime,
+		arg.RouteScoped,
+		arg.RouteID,
+		arg.MaxCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchMessagesRow
+	for rows.Next() {
+		var i SearchMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BotID,
+			&i.RouteID,
+			&i.SenderChannelIdentityID,
+			&i.SenderUserID,
+			&i.Platform,
+			&i.ExternalMessageID,
+			&i.SourceReplyToMessageID,
+			&i.Role,
+			&i.Content,
+			&i.Metadata,
+			&i.Usage,
+			&i.ParentID,
 			&i.CreatedAt,
+			&i.SearchText,
 			&i.SenderDisplayName,
 			&i.SenderAvatarUrl,
 		); err != nil {
@@ -539,3 +1124,124 @@ func (q *Queries) ListMessagesSince(ctx context.Context, arg ListMessagesSincePa
 	}
 	return items, nil
 }
+
+const starMessage = `-- name: StarMessage :execrows
+INSERT INTO bot_history_message_stars (message_id, starred_by)
+SELECT m.id, $1
+FROM bot_history_messages m
+WHERE m.id = $2
+  AND m.bot_id = $3
+ON CONFLICT (message_id) DO UPDATE SET
+  starred_by = EXCLUDED.starred_by,
+  created_at = now()
+`
+
+type StarMessageParams struct {
+	StarredBy pgtype.UUID `json:"starred_by"`
+	MessageID pgtype.UUID `json:"message_id"`
+	BotID     pgtype.UUID `json:"bot_id"`
+}
+
+func (q *Queries) StarMessage(ctx context.Context, arg StarMessageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, starMessage, arg.StarredBy, arg.MessageID, arg.BotID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const supersedeActiveMessagesAfter = `-- name: SupersedeActiveMessagesAfter :many
+UPDATE bot_history_messages
+SET superseded_at = now()
+WHERE bot_id = $1
+  AND route_id IS NOT DISTINCT FROM $2::uuid
+  AND superseded_at IS NULL
+  AND created_at > $3
+RETURNING id, created_at
+`
+
+type SupersedeActiveMessagesAfterParams struct {
+	BotID     pgtype.UUID        `json:"bot_id"`
+	RouteID   pgtype.UUID        `json:"route_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type SupersedeActiveMessagesAfterRow struct {
+	ID        pgtype.UUID        `json:"id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+// Retires the active messages of one route (or of the bot's routeless
+// history) created after the cutoff; other conversations keep theirs.
+func (q *Queries) SupersedeActiveMessagesAfter(ctx context.Context, arg SupersedeActiveMessagesAfterParams) ([]SupersedeActiveMessagesAfterRow, error) {
+	rows, err := q.db.Query(ctx, supersedeActiveMessagesAfter, arg.BotID, arg.RouteID, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SupersedeActiveMessagesAfterRow
+	for rows.Next() {
+		var i SupersedeActiveMessagesAfterRow
+		if err := rows.Scan(&i.ID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unstarMessage = `-- name: UnstarMessage :execrows
+DELETE FROM bot_history_message_stars s
+USING bot_history_messages m
+WHERE s.message_id = m.id
+  AND m.id = $1
+  AND m.bot_id = $2
+`
+
+type UnstarMessageParams struct {
+	MessageID pgtype.UUID `json:"message_id"`
+	BotID     pgtype.UUID `json:"bot_id"`
+}
+
+func (q *Queries) UnstarMessage(ctx context.Context, arg UnstarMessageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, unstarMessage, arg.MessageID, arg.BotID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertMessageReaction = `-- name: UpsertMessageReaction :execrows
+INSERT INTO bot_history_message_reactions (message_id, user_id, reaction)
+SELECT m.id, $1, $2
+FROM bot_history_messages m
+WHERE m.id = $3
+  AND m.bot_id = $4
+  AND m.role = 'assistant'
+ON CONFLICT (message_id, user_id) DO UPDATE SET
+  reaction = EXCLUDED.reaction,
+  created_at = now()
+`
+
+type UpsertMessageReactionParams struct {
+	UserID    pgtype.UUID `json:"user_id"`
+	Reaction  string      `json:"reaction"`
+	MessageID pgtype.UUID `json:"message_id"`
+	BotID     pgtype.UUID `json:"bot_id"`
+}
+
+func (q *Queries) UpsertMessageReaction(ctx context.Context, arg UpsertMessageReactionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, upsertMessageReaction,
+		arg.UserID,
+		arg.Reaction,
+		arg.MessageID,
+		arg.BotID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
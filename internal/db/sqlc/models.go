@@ -8,6 +8,17 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ApiToken struct {
+	ID          pgtype.UUID        `json:"id"`
+	UserID      pgtype.UUID        `json:"user_id"`
+	Name        string             `json:"name"`
+	TokenHash   string             `json:"token_hash"`
+	TokenPrefix string             `json:"token_prefix"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt  pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
 type Bot struct {
 	ID                 pgtype.UUID        `json:"id"`
 	OwnerUserID        pgtype.UUID        `json:"owner_user_id"`
@@ -27,6 +38,17 @@ type Bot struct {
 	MemoryModelID      pgtype.UUID        `json:"memory_model_id"`
 	EmbeddingModelID   pgtype.UUID        `json:"embedding_model_id"`
 	SearchProviderID   pgtype.UUID        `json:"search_provider_id"`
+	TtsEnabled         bool               `json:"tts_enabled"`
+	TtsModelID         pgtype.UUID        `json:"tts_model_id"`
+	TtsVoice           string             `json:"tts_voice"`
+	FallbackModelIds   []pgtype.UUID      `json:"fallback_model_ids"`
+	ChatOverrides      []byte             `json:"chat_overrides"`
+	ToolApprovalPolicy []byte             `json:"tool_approval_policy"`
+	QuotaPolicy        []byte             `json:"quota_policy"`
+	RetentionPolicy    []byte             `json:"retention_policy"`
+	GatewayRecording   bool               `json:"gateway_recording"`
+	ModerationPolicy   []byte             `json:"moderation_policy"`
+	PiiPolicy          []byte             `json:"pii_policy"`
 	Metadata           []byte             `json:"metadata"`
 	CreatedAt          pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt          pgtype.Timestamptz `json:"updated_at"`
@@ -74,7 +96,10 @@ type BotHistoryMessage struct {
 	Content                 []byte             `json:"content"`
 	Metadata                []byte             `json:"metadata"`
 	Usage                   []byte             `json:"usage"`
+	ParentID                pgtype.UUID        `json:"parent_id"`
+	SupersededAt            pgtype.Timestamptz `json:"superseded_at"`
 	CreatedAt               pgtype.Timestamptz `json:"created_at"`
+	SearchText              pgtype.Text        `json:"search_text"`
 }
 
 type BotHistoryMessageAsset struct {
@@ -86,6 +111,31 @@ type BotHistoryMessageAsset struct {
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 }
 
+type BotHistoryMessageReaction struct {
+	ID        pgtype.UUID        `json:"id"`
+	MessageID pgtype.UUID        `json:"message_id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Reaction  string             `json:"reaction"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type BotHistoryMessageStar struct {
+	MessageID pgtype.UUID        `json:"message_id"`
+	StarredBy pgtype.UUID        `json:"starred_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type BotHistorySummary struct {
+	ID           pgtype.UUID        `json:"id"`
+	BotID        pgtype.UUID        `json:"bot_id"`
+	RouteID      pgtype.UUID        `json:"route_id"`
+	Version      int32              `json:"version"`
+	Content      string             `json:"content"`
+	CoveredUntil pgtype.Timestamptz `json:"covered_until"`
+	MessageCount int32              `json:"message_count"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
 type BotInbox struct {
 	ID        pgtype.UUID        `json:"id"`
 	BotID     pgtype.UUID        `json:"bot_id"`
@@ -170,6 +220,18 @@ type ContainerVersion struct {
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 }
 
+type GatewayRecording struct {
+	ID        pgtype.UUID        `json:"id"`
+	BotID     pgtype.UUID        `json:"bot_id"`
+	RouteID   pgtype.UUID        `json:"route_id"`
+	Kind      string             `json:"kind"`
+	ModelID   pgtype.UUID        `json:"model_id"`
+	Request   []byte             `json:"request"`
+	Response  []byte             `json:"response"`
+	LatencyMs int64              `json:"latency_ms"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type LifecycleEvent struct {
 	ID          string             `json:"id"`
 	ContainerID string             `json:"container_id"`
@@ -216,6 +278,16 @@ type MediaAsset struct {
 	CreatedAt         pgtype.Timestamptz `json:"created_at"`
 }
 
+type MessagePurgeRun struct {
+	ID                 pgtype.UUID        `json:"id"`
+	BotID              pgtype.UUID        `json:"bot_id"`
+	Trigger            string             `json:"trigger"`
+	DeletedMessages    int64              `json:"deleted_messages"`
+	DeletedAssets      int64              `json:"deleted_assets"`
+	ArchiveContentHash string             `json:"archive_content_hash"`
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+}
+
 type Model struct {
 	ID                pgtype.UUID        `json:"id"`
 	ModelID           string             `json:"model_id"`
@@ -230,6 +302,17 @@ type Model struct {
 	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
 }
 
+type ModelPrice struct {
+	ModelUuid             pgtype.UUID        `json:"model_uuid"`
+	Currency              string             `json:"currency"`
+	InputPerMillion       float64            `json:"input_per_million"`
+	OutputPerMillion      float64            `json:"output_per_million"`
+	CachedInputPerMillion pgtype.Float8      `json:"cached_input_per_million"`
+	ReasoningPerMillion   pgtype.Float8      `json:"reasoning_per_million"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+}
+
 type ModelVariant struct {
 	ID        pgtype.UUID        `json:"id"`
 	ModelUuid pgtype.UUID        `json:"model_uuid"`
@@ -240,6 +323,15 @@ type ModelVariant struct {
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
+type PiiVaultEntry struct {
+	ID        pgtype.UUID        `json:"id"`
+	BotID     pgtype.UUID        `json:"bot_id"`
+	Kind      string             `json:"kind"`
+	Ordinal   int32              `json:"ordinal"`
+	Value     string             `json:"value"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
 type Schedule struct {
 	ID           pgtype.UUID        `json:"id"`
 	Name         string             `json:"name"`
@@ -297,6 +389,35 @@ type Subagent struct {
 	Usage       []byte             `json:"usage"`
 }
 
+type ToolApproval struct {
+	ID                           pgtype.UUID        `json:"id"`
+	BotID                        pgtype.UUID        `json:"bot_id"`
+	ToolName                     string             `json:"tool_name"`
+	Arguments                    []byte             `json:"arguments"`
+	Rule                         string             `json:"rule"`
+	Platform                     string             `json:"platform"`
+	RequestedByChannelIdentityID pgtype.UUID        `json:"requested_by_channel_identity_id"`
+	Status                       string             `json:"status"`
+	DecidedByUserID              pgtype.UUID        `json:"decided_by_user_id"`
+	DecisionNote                 string             `json:"decision_note"`
+	ExpiresAt                    pgtype.Timestamptz `json:"expires_at"`
+	DecidedAt                    pgtype.Timestamptz `json:"decided_at"`
+	CreatedAt                    pgtype.Timestamptz `json:"created_at"`
+}
+
+type UsageQuotaCounter struct {
+	BotID       pgtype.UUID        `json:"bot_id"`
+	Scope       string             `json:"scope"`
+	Subject     string             `json:"subject"`
+	Period      string             `json:"period"`
+	PeriodStart pgtype.Timestamptz `json:"period_start"`
+	Requests    int64              `json:"requests"`
+	Tokens      int64              `json:"tokens"`
+	Cost        float64            `json:"cost"`
+	Notified    []byte             `json:"notified"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
 type User struct {
 	ID           pgtype.UUID        `json:"id"`
 	Username     pgtype.Text        `json:"username"`
@@ -201,6 +201,24 @@ func (q *Queries) DeleteModelByModelID(ctx context.Context, modelID string) erro
 	return err
 }
 
+const deleteModelVariant = `-- name: DeleteModelVariant :execrows
+DELETE FROM model_variants
+WHERE id = $1 AND model_uuid = $2
+`
+
+type DeleteModelVariantParams struct {
+	ID        pgtype.UUID `json:"id"`
+	ModelUuid pgtype.UUID `json:"model_uuid"`
+}
+
+func (q *Queries) DeleteModelVariant(ctx context.Context, arg DeleteModelVariantParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteModelVariant, arg.ID, arg.ModelUuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getLlmProviderByID = `-- name: GetLlmProviderByID :one
 SELECT id, name, base_url, api_key, metadata, created_at, updated_at FROM llm_providers WHERE id = $1
 `
@@ -285,6 +303,105 @@ func (q *Queries) GetModelByModelID(ctx context.Context, modelID string) (Model,
 	return i, err
 }
 
+const getModelVariant = `-- name: GetModelVariant :one
+SELECT id, model_uuid, variant_id, weight, metadata, created_at, updated_at FROM model_variants
+WHERE id = $1 AND model_uuid = $2
+`
+
+type GetModelVariantParams struct {
+	ID        pgtype.UUID `json:"id"`
+	ModelUuid pgtype.UUID `json:"model_uuid"`
+}
+
+func (q *Queries) GetModelVariant(ctx context.Context, arg GetModelVariantParams) (ModelVariant, error) {
+	row := q.db.QueryRow(ctx, getModelVariant, arg.ID, arg.ModelUuid)
+	var i ModelVariant
+	err := row.Scan(
+		&i.ID,
+		&i.ModelUuid,
+		&i.VariantID,
+		&i.Weight,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getModelVariantReport = `-- name: GetModelVariantReport :many
+SELECT
+  COALESCE(m.metadata->>'variant_id', '')::text AS variant_id,
+  COUNT(m.metadata->>'latency_ms')::bigint AS replies,
+  COUNT(DISTINCT COALESCE(m.route_id, m.bot_id))::bigint AS conversations,
+  COALESCE(AVG((m.metadata->>'latency_ms')::bigint), 0)::float8 AS avg_latency_ms,
+  COALESCE(SUM((m.usage->>'inputTokens')::bigint), 0)::bigint AS input_tokens,
+  COALESCE(SUM((m.usage->>'outputTokens')::bigint), 0)::bigint AS output_tokens,
+  COALESCE(SUM(r.up), 0)::bigint AS up_reactions,
+  COALESCE(SUM(r.down), 0)::bigint AS down_reactions
+FROM bot_history_messages m
+LEFT JOIN LATERAL (
+  SELECT
+    COUNT(*) FILTER (WHERE reaction = 'up') AS up,
+    COUNT(*) FILTER (WHERE reaction = 'down') AS down
+  FROM bot_history_message_reactions
+  WHERE message_id = m.id
+) r ON TRUE
+WHERE m.metadata->>'variant_of' = $1::text
+  AND m.role = 'assistant'
+  AND m.created_at >= $2
+  AND m.created_at < $3
+GROUP BY m.metadata->>'variant_id'
+ORDER BY replies DESC
+`
+
+type GetModelVariantReportParams struct {
+	ModelUuid string             `json:"model_uuid"`
+	Since     pgtype.Timestamptz `json:"since"`
+	Until     pgtype.Timestamptz `json:"until"`
+}
+
+type GetModelVariantReportRow struct {
+	VariantID     string  `json:"variant_id"`
+	Replies       int64   `json:"replies"`
+	Conversations int64   `json:"conversations"`
+	AvgLatencyMs  float64 `json:"avg_latency_ms"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	UpReactions   int64   `json:"up_reactions"`
+	DownReactions int64   `json:"down_reactions"`
+}
+
+// Aggregates assistant replies routed through a model's variants. Latency is
+// recorded on the last message of each round, so it also counts rounds.
+func (q *Queries) GetModelVariantReport(ctx context.Context, arg GetModelVariantReportParams) ([]GetModelVariantReportRow, error) {
+	rows, err := q.db.Query(ctx, getModelVariantReport, arg.ModelUuid, arg.Since, arg.Until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetModelVariantReportRow
+	for rows.Next() {
+		var i GetModelVariantReportRow
+		if err := rows.Scan(
+			&i.VariantID,
+			&i.Replies,
+			&i.Conversations,
+			&i.AvgLatencyMs,
+			&i.InputTokens,
+			&i.OutputTokens,
+			&i.UpReactions,
+			&i.DownReactions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listLlmProviders = `-- name: ListLlmProviders :many
 SELECT id, name, base_url, api_key, metadata, created_at, updated_at FROM llm_providers
 ORDER BY created_at DESC
@@ -739,3 +856,43 @@ func (q *Queries) UpdateModelByModelID(ctx context.Context, arg UpdateModelByMod
 	)
 	return i, err
 }
+
+const updateModelVariant = `-- name: UpdateModelVariant :one
+UPDATE model_variants
+SET
+  variant_id = $1,
+  weight = $2,
+  metadata = $3,
+  updated_at = now()
+WHERE id = $4 AND model_uuid = $5
+RETURNING id, model_uuid, variant_id, weight, metadata, created_at, updated_at
+`
+
+type UpdateModelVariantParams struct {
+	VariantID string      `json:"variant_id"`
+	Weight    int32       `json:"weight"`
+	Metadata  []byte      `json:"metadata"`
+	ID        pgtype.UUID `json:"id"`
+	ModelUuid pgtype.UUID `json:"model_uuid"`
+}
+
+func (q *Queries) UpdateModelVariant(ctx context.Context, arg UpdateModelVariantParams) (ModelVariant, error) {
+	row := q.db.QueryRow(ctx, updateModelVariant,
+		arg.VariantID,
+		arg.Weight,
+		arg.Metadata,
+		arg.ID,
+		arg.ModelUuid,
+	)
+	var i ModelVariant
+	err := row.Scan(
+		&i.ID,
+		&i.ModelUuid,
+		&i.VariantID,
+		&i.Weight,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
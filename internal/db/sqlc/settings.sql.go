@@ -20,10 +20,14 @@ SET max_context_load_time = 1440,
     allow_guest = false,
     reasoning_enabled = false,
     reasoning_effort = 'medium',
+    tts_enabled = false,
+    tts_voice = '',
+    fallback_model_ids = '{}',
     chat_model_id = NULL,
     memory_model_id = NULL,
     embedding_model_id = NULL,
     search_provider_id = NULL,
+    tts_model_id = NULL,
     updated_at = now()
 WHERE id = $1
 `
@@ -43,31 +47,40 @@ SELECT
   bots.allow_guest,
   bots.reasoning_enabled,
   bots.reasoning_effort,
+  bots.tts_enabled,
+  bots.tts_voice,
+  bots.fallback_model_ids,
   chat_models.id AS chat_model_id,
   memory_models.id AS memory_model_id,
   embedding_models.id AS embedding_model_id,
-  search_providers.id AS search_provider_id
+  search_providers.id AS search_provider_id,
+  tts_models.id AS tts_model_id
 FROM bots
 LEFT JOIN models AS chat_models ON chat_models.id = bots.chat_model_id
 LEFT JOIN models AS memory_models ON memory_models.id = bots.memory_model_id
 LEFT JOIN models AS embedding_models ON embedding_models.id = bots.embedding_model_id
 LEFT JOIN search_providers ON search_providers.id = bots.search_provider_id
+LEFT JOIN models AS tts_models ON tts_models.id = bots.tts_model_id
 WHERE bots.id = $1
 `
 
 type GetSettingsByBotIDRow struct {
-	BotID              pgtype.UUID `json:"bot_id"`
-	MaxContextLoadTime int32       `json:"max_context_load_time"`
-	MaxContextTokens   int32       `json:"max_context_tokens"`
-	MaxInboxItems      int32       `json:"max_inbox_items"`
-	Language           string      `json:"language"`
-	AllowGuest         bool        `json:"allow_guest"`
-	ReasoningEnabled   bool        `json:"reasoning_enabled"`
-	ReasoningEffort    string      `json:"reasoning_effort"`
-	ChatModelID        pgtype.UUID `json:"chat_model_id"`
-	MemoryModelID      pgtype.UUID `json:"memory_model_id"`
-	EmbeddingModelID   pgtype.UUID `json:"embedding_model_id"`
-	SearchProviderID   pgtype.UUID `json:"search_provider_id"`
+	BotID              pgtype.UUID   `json:"bot_id"`
+	MaxContextLoadTime int32         `json:"max_context_load_time"`
+	MaxContextTokens   int32         `json:"max_context_tokens"`
+	MaxInboxItems      int32         `json:"max_inbox_items"`
+	Language           string        `json:"language"`
+	AllowGuest         bool          `json:"allow_guest"`
+	ReasoningEnabled   bool          `json:"reasoning_enabled"`
+	ReasoningEffort    string        `json:"reasoning_effort"`
+	TtsEnabled         bool          `json:"tts_enabled"`
+	TtsVoice           string        `json:"tts_voice"`
+	FallbackModelIds   []pgtype.UUID `json:"fallback_model_ids"`
+	ChatModelID        pgtype.UUID   `json:"chat_model_id"`
+	MemoryModelID      pgtype.UUID   `json:"memory_model_id"`
+	EmbeddingModelID   pgtype.UUID   `json:"embedding_model_id"`
+	SearchProviderID   pgtype.UUID   `json:"search_provider_id"`
+	TtsModelID         pgtype.UUID   `json:"tts_model_id"`
 }
 
 func (q *Queries) GetSettingsByBotID(ctx context.Context, id pgtype.UUID) (GetSettingsByBotIDRow, error) {
@@ -82,10 +95,14 @@ func (q *Queries) GetSettingsByBotID(ctx context.Context, id pgtype.UUID) (GetSe
 		&i.AllowGuest,
 		&i.ReasoningEnabled,
 		&i.ReasoningEffort,
+		&i.TtsEnabled,
+		&i.TtsVoice,
+		&i.FallbackModelIds,
 		&i.ChatModelID,
 		&i.MemoryModelID,
 		&i.EmbeddingModelID,
 		&i.SearchProviderID,
+		&i.TtsModelID,
 	)
 	return i, err
 }
@@ -100,13 +117,17 @@ WITH updated AS (
       allow_guest = $5,
       reasoning_enabled = $6,
       reasoning_effort = $7,
-      chat_model_id = COALESCE($8::uuid, bots.chat_model_id),
-      memory_model_id = COALESCE($9::uuid, bots.memory_model_id),
-      embedding_model_id = COALESCE($10::uuid, bots.embedding_model_id),
-      search_provider_id = COALESCE($11::uuid, bots.search_provider_id),
+      tts_enabled = $8,
+      tts_voice = $9,
+      fallback_model_ids = COALESCE($10::uuid[], bots.fallback_model_ids),
+      chat_model_id = COALESCE($11::uuid, bots.chat_model_id),
+      memory_model_id = COALESCE($12::uuid, bots.memory_model_id),
+      embedding_model_id = COALESCE($13::uuid, bots.embedding_model_id),
+      search_provider_id = COALESCE($14::uuid, bots.search_provider_id),
+      tts_model_id = COALESCE($15::uuid, bots.tts_model_id),
       updated_at = now()
-  WHERE bots.id = $12
-  RETURNING bots.id, bots.max_context_load_time, bots.max_context_tokens, bots.max_inbox_items, bots.language, bots.allow_guest, bots.reasoning_enabled, bots.reasoning_effort, bots.chat_model_id, bots.memory_model_id, bots.embedding_model_id, bots.search_provider_id
+  WHERE bots.id = $16
+  RETURNING bots.id, bots.max_context_load_time, bots.max_context_tokens, bots.max_inbox_items, bots.language, bots.allow_guest, bots.reasoning_enabled, bots.reasoning_effort, bots.tts_enabled, bots.tts_voice, bots.fallback_model_ids, bots.chat_model_id, bots.memory_model_id, bots.embedding_model_id, bots.search_provider_id, bots.tts_model_id
 )
 SELECT
   updated.id AS bot_id,
@@ -117,45 +138,58 @@ SELECT
   updated.allow_guest,
   updated.reasoning_enabled,
   updated.reasoning_effort,
+  updated.tts_enabled,
+  updated.tts_voice,
+  updated.fallback_model_ids,
   chat_models.id AS chat_model_id,
   memory_models.id AS memory_model_id,
   embedding_models.id AS embedding_model_id,
-  search_providers.id AS search_provider_id
+  search_providers.id AS search_provider_id,
+  tts_models.id AS tts_model_id
 FROM updated
 LEFT JOIN models AS chat_models ON chat_models.id = updated.chat_model_id
 LEFT JOIN models AS memory_models ON memory_models.id = updated.memory_model_id
 LEFT JOIN models AS embedding_models ON embedding_models.id = updated.embedding_model_id
 LEFT JOIN search_providers ON search_providers.id = updated.search_provider_id
+LEFT JOIN models AS tts_models ON tts_models.id = updated.tts_model_id
 `
 
 type UpsertBotSettingsParams struct {
-	MaxContextLoadTime int32       `json:"max_context_load_time"`
-	MaxContextTokens   int32       `json:"max_context_tokens"`
-	MaxInboxItems      int32       `json:"max_inbox_items"`
-	Language           string      `json:"language"`
-	AllowGuest         bool        `json:"allow_guest"`
-	ReasoningEnabled   bool        `json:"reasoning_enabled"`
-	ReasoningEffort    string      `json:"reasoning_effort"`
-	ChatModelID        pgtype.UUID `json:"chat_model_id"`
-	MemoryModelID      pgtype.UUID `json:"memory_model_id"`
-	EmbeddingModelID   pgtype.UUID `json:"embedding_model_id"`
-	SearchProviderID   pgtype.UUID `json:"search_provider_id"`
-	ID                 pgtype.UUID `json:"id"`
+	MaxContextLoadTime int32         `json:"max_context_load_time"`
+	MaxContextTokens   int32         `json:"max_context_tokens"`
+	MaxInboxItems      int32         `json:"max_inbox_items"`
+	Language           string        `json:"language"`
+	AllowGuest         bool          `json:"allow_guest"`
+	ReasoningEnabled   bool          `json:"reasoning_enabled"`
+	ReasoningEffort    string        `json:"reasoning_effort"`
+	TtsEnabled         bool          `json:"tts_enabled"`
+	TtsVoice           string        `json:"tts_voice"`
+	FallbackModelIds   []pgtype.UUID `json:"fallback_model_ids"`
+	ChatModelID        pgtype.UUID   `json:"chat_model_id"`
+	MemoryModelID      pgtype.UUID   `json:"memory_model_id"`
+	EmbeddingModelID   pgtype.UUID   `json:"embedding_model_id"`
+	SearchProviderID   pgtype.UUID   `json:"search_provider_id"`
+	TtsModelID         pgtype.UUID   `json:"tts_model_id"`
+	ID                 pgtype.UUID   `json:"id"`
 }
 
 type UpsertBotSettingsRow struct {
-	BotID              pgtype.UUID `json:"bot_id"`
-	MaxContextLoadTime int32       `json:"max_context_load_time"`
-	MaxContextTokens   int32       `json:"max_context_tokens"`
-	MaxInboxItems      int32       `json:"max_inbox_items"`
-	Language           string      `json:"language"`
-	AllowGuest         bool        `json:"allow_guest"`
-	ReasoningEnabled   bool        `json:"reasoning_enabled"`
-	ReasoningEffort    string      `json:"reasoning_effort"`
-	ChatModelID        pgtype.UUID `json:"chat_model_id"`
-	MemoryModelID      pgtype.UUID `json:"memory_model_id"`
-	EmbeddingModelID   pgtype.UUID `json:"embedding_model_id"`
-	SearchProviderID   pgtype.UUID `json:"search_provider_id"`
+	BotID              pgtype.UUID   `json:"bot_id"`
+	MaxContextLoadTime int32         `json:"max_context_load_time"`
+	MaxContextTokens   int32         `json:"max_context_tokens"`
+	MaxInboxItems      int32         `json:"max_inbox_items"`
+	Language           string        `json:"language"`
+	AllowGuest         bool          `json:"allow_guest"`
+	ReasoningEnabled   bool          `json:"reasoning_enabled"`
+	ReasoningEffort    string        `json:"reasoning_effort"`
+	TtsEnabled         bool          `json:"tts_enabled"`
+	TtsVoice           string        `json:"tts_voice"`
+	FallbackModelIds   []pgtype.UUID `json:"fallback_model_ids"`
+	ChatModelID        pgtype.UUID   `json:"chat_model_id"`
+	MemoryModelID      pgtype.UUID   `json:"memory_model_id"`
+	EmbeddingModelID   pgtype.UUID   `json:"embedding_model_id"`
+	SearchProviderID   pgtype.UUID   `json:"search_provider_id"`
+	TtsModelID         pgtype.UUID   `json:"tts_model_id"`
 }
 
 func (q *Queries) UpsertBotSettings(ctx context.Context, arg UpsertBotSettingsParams) (UpsertBotSettingsRow, error) {
@@ -167,10 +201,14 @@ func (q *Queries) UpsertBotSettings(ctx context.Context, arg UpsertBotSettingsPa
 		arg.AllowGuest,
 		arg.ReasoningEnabled,
 		arg.ReasoningEffort,
+		arg.TtsEnabled,
+		arg.TtsVoice,
+		arg.FallbackModelIds,
 		arg.ChatModelID,
 		arg.MemoryModelID,
 		arg.EmbeddingModelID,
 		arg.SearchProviderID,
+		arg.TtsModelID,
 		arg.ID,
 	)
 	var i UpsertBotSettingsRow
@@ -183,10 +221,14 @@ func (q *Queries) UpsertBotSettings(ctx context.Context, arg UpsertBotSettingsPa
 		&i.AllowGuest,
 		&i.ReasoningEnabled,
 		&i.ReasoningEffort,
+		&i.TtsEnabled,
+		&i.TtsVoice,
+		&i.FallbackModelIds,
 		&i.ChatModelID,
 		&i.MemoryModelID,
 		&i.EmbeddingModelID,
 		&i.SearchProviderID,
+		&i.TtsModelID,
 	)
 	return i, err
 }
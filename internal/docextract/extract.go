@@ -0,0 +1,310 @@
+// Package docextract converts document attachments (PDF, DOCX, XLSX, HTML and
+// plain text) into bounded plain text for models without file input support.
+package docextract
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	textprune "github.com/memohai/memoh/internal/prune"
+)
+
+const (
+	FormatPDF  = "pdf"
+	FormatDOCX = "docx"
+	FormatXLSX = "xlsx"
+	FormatHTML = "html"
+	FormatText = "text"
+
+	// DefaultMaxInputBytes caps how much of the source document is read.
+	DefaultMaxInputBytes int64 = 20 * 1024 * 1024
+	// DefaultMaxBytes caps the extracted text injected into a request.
+	DefaultMaxBytes = 32 * 1024
+	// DefaultMaxLines caps the extracted text line count.
+	DefaultMaxLines = 800
+	// DefaultCacheEntries bounds the in-memory extraction cache.
+	DefaultCacheEntries = 256
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported document type")
+	ErrNoText      = errors.New("document contains no extractable text")
+)
+
+// Config controls extraction limits.
+type Config struct {
+	MaxInputBytes int64
+	MaxBytes      int
+	MaxLines      int
+	CacheEntries  int
+}
+
+// Result is the bounded text extracted from a document.
+type Result struct {
+	Format    string `json:"format"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"truncated"`
+}
+
+// Service extracts document text and caches results by content hash.
+type Service struct {
+	cfg    Config
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	order *list.List
+	cache map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key    string
+	result Result
+}
+
+func NewService(log *slog.Logger, cfg Config) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	if cfg.MaxInputBytes <= 0 {
+		cfg.MaxInputBytes = DefaultMaxInputBytes
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.MaxLines <= 0 {
+		cfg.MaxLines = DefaultMaxLines
+	}
+	if cfg.CacheEntries <= 0 {
+		cfg.CacheEntries = DefaultCacheEntries
+	}
+	return &Service{
+		cfg:    cfg,
+		logger: log.With(slog.String("service", "docextract")),
+		order:  list.New(),
+		cache:  make(map[string]*list.Element),
+	}
+}
+
+// Supported reports whether a document with the given mime type or file name
+// can be extracted without reading its content.
+func Supported(mime, name string) bool {
+	return detectFormat(mime, name, nil) != ""
+}
+
+// Extract reads the document and returns bounded text. When contentHash is
+// set the result is cached so repeated turns do not re-parse the file.
+func (s *Service) Extract(ctx context.Context, contentHash, mime, name string, r io.Reader) (Result, error) {
+	contentHash = strings.TrimSpace(contentHash)
+	if cached, ok := s.lookup(contentHash); ok {
+		return cached, nil
+	}
+	if r == nil {
+		return Result{}, fmt.Errorf("document reader is required")
+	}
+	data, err := io.ReadAll(io.LimitReader(r, s.cfg.MaxInputBytes+1))
+	if err != nil {
+		return Result{}, fmt.Errorf("read document: %w", err)
+	}
+	if int64(len(data)) > s.cfg.MaxInputBytes {
+		return Result{}, fmt.Errorf("document exceeds %d bytes", s.cfg.MaxInputBytes)
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	format := detectFormat(mime, name, data)
+	if format == "" {
+		return Result{}, ErrUnsupported
+	}
+	text, err := extractByFormat(format, data)
+	if err != nil {
+		return Result{}, fmt.Errorf("extract %s: %w", format, err)
+	}
+	text = normalizeText(text)
+	if text == "" {
+		return Result{}, ErrNoText
+	}
+	label := strings.TrimSpace(name)
+	if label == "" {
+		label = "document"
+	}
+	bounded := textprune.PruneWithEdges(text, label, s.pruneConfig(label))
+	result := Result{Format: format, Text: bounded, Truncated: bounded != text}
+	s.store(contentHash, result)
+	return result, nil
+}
+
+// pruneConfig splits the byte and line budgets between head and tail after
+// reserving room for the prune header, so the tail survives the final fit.
+func (s *Service) pruneConfig(label string) textprune.Config {
+	overheadBytes := len(textprune.DefaultMarker) + len(label) + 96
+	availBytes := s.cfg.MaxBytes - overheadBytes
+	if availBytes < 0 {
+		availBytes = 0
+	}
+	availLines := s.cfg.MaxLines - 6
+	if availLines < 0 {
+		availLines = 0
+	}
+	return textprune.Config{
+		MaxBytes:  s.cfg.MaxBytes,
+		MaxLines:  s.cfg.MaxLines,
+		HeadBytes: availBytes * 3 / 4,
+		TailBytes: availBytes / 4,
+		HeadLines: availLines * 3 / 4,
+		TailLines: availLines / 4,
+		Marker:    textprune.DefaultMarker,
+	}
+}
+
+func (s *Service) lookup(key string) (Result, bool) {
+	if key == "" {
+		return Result{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.cache[key]
+	if !ok {
+		return Result{}, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).result, true
+}
+
+func (s *Service) store(key string, result Result) {
+	if key == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.cache[key]; ok {
+		elem.Value.(*cacheEntry).result = result
+		s.order.MoveToFront(elem)
+		return
+	}
+	s.cache[key] = s.order.PushFront(&cacheEntry{key: key, result: result})
+	for s.order.Len() > s.cfg.CacheEntries {
+		oldest := s.order.Back()
+		if oldest == nil {
+			break
+		}
+		s.order.Remove(oldest)
+		delete(s.cache, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func extractByFormat(format string, data []byte) (string, error) {
+	switch format {
+	case FormatPDF:
+		return extractPDF(data)
+	case FormatDOCX:
+		return extractDOCX(data)
+	case FormatXLSX:
+		return extractXLSX(data)
+	case FormatHTML:
+		return extractHTML(data)
+	case FormatText:
+		return string(data), nil
+	default:
+		return "", ErrUnsupported
+	}
+}
+
+var textExtensions = map[string]struct{}{
+	".txt": {}, ".md": {}, ".markdown": {}, ".csv": {}, ".tsv": {}, ".json": {},
+	".jsonl": {}, ".yaml": {}, ".yml": {}, ".toml": {}, ".xml": {}, ".log": {},
+	".ini": {}, ".rst": {}, ".srt": {}, ".vtt": {},
+}
+
+// detectFormat resolves the document format from mime, file extension and,
+// when data is available, magic bytes.
+func detectFormat(mime, name string, data []byte) string {
+	mime = strings.ToLower(strings.TrimSpace(mime))
+	if idx := strings.Index(mime, ";"); idx >= 0 {
+		mime = strings.TrimSpace(mime[:idx])
+	}
+	ext := strings.ToLower(filepath.Ext(strings.TrimSpace(name)))
+	switch {
+	case mime == "application/pdf" || ext == ".pdf":
+		return FormatPDF
+	case mime == "application/vnd.openxmlformats-officedocument.wordprocessingml.document" || ext == ".docx":
+		return FormatDOCX
+	case mime == "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" || ext == ".xlsx":
+		return FormatXLSX
+	case mime == "text/html" || mime == "application/xhtml+xml" || ext == ".html" || ext == ".htm" || ext == ".xhtml":
+		return FormatHTML
+	case strings.HasPrefix(mime, "text/"),
+		mime == "application/json",
+		mime == "application/xml",
+		mime == "application/x-yaml",
+		mime == "application/yaml",
+		mime == "application/toml":
+		return FormatText
+	}
+	if _, ok := textExtensions[ext]; ok {
+		return FormatText
+	}
+	if len(data) == 0 {
+		return ""
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return detectOfficeFormat(data)
+	case looksLikeText(data):
+		return FormatText
+	}
+	return ""
+}
+
+// looksLikeText treats valid UTF-8 without NUL bytes in the first 8 KiB as text.
+func looksLikeText(data []byte) bool {
+	sample := data
+	if len(sample) > 8192 {
+		sample = sample[:8192]
+		for len(sample) > 0 && !utf8.RuneStart(sample[len(sample)-1]) {
+			sample = sample[:len(sample)-1]
+		}
+		if len(sample) > 0 {
+			sample = sample[:len(sample)-1]
+		}
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return false
+	}
+	return utf8.Valid(sample)
+}
+
+var lineEndingReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\u00a0", " ")
+
+// normalizeText fixes encoding, line endings and runs of blank lines.
+func normalizeText(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = lineEndingReplacer.Replace(text)
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+			line = ""
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
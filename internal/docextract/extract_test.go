@@ -0,0 +1,217 @@
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	textprune "github.com/memohai/memoh/internal/prune"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func buildPDF(t *testing.T, content string) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("compress close: %v", err)
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /XObject /Subtype /Image /Length 4 >>\nstream\nBT (image) Tj ET\nendstream\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+	content := "BT /F1 12 Tf 72 720 Td (Hello \\(PDF\\) world) Tj 0 -14 Td [(Split)-300(words)] TJ T* <FEFF004F006B> Tj ET"
+	svc := NewService(nil, Config{})
+	res, err := svc.Extract(context.Background(), "", "application/pdf", "report.pdf", bytes.NewReader(buildPDF(t, content)))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if res.Format != FormatPDF {
+		t.Fatalf("format = %q", res.Format)
+	}
+	want := "Hello (PDF) world\nSplit words\nOk"
+	if res.Text != want {
+		t.Fatalf("text = %q, want %q", res.Text, want)
+	}
+}
+
+func TestExtractPDFStopsAtDocumentInflateLimit(t *testing.T) {
+	compress := func(content []byte) []byte {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		if _, err := zw.Write(content); err != nil {
+			t.Fatalf("compress: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("compress close: %v", err)
+		}
+		return buf.Bytes()
+	}
+	var pdf bytes.Buffer
+	writeStream := func(compressed []byte) {
+		fmt.Fprintf(&pdf, "1 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(compressed))
+		pdf.Write(compressed)
+		pdf.WriteString("\nendstream\nendobj\n")
+	}
+	writeStream(compress([]byte("BT (head) Tj ET")))
+	bomb := compress(make([]byte, maxInflatedStreamBytes))
+	for i := 0; i < maxInflatedDocumentBytes/maxInflatedStreamBytes; i++ {
+		writeStream(bomb)
+	}
+	writeStream(compress([]byte("BT (tail) Tj ET")))
+
+	text, err := extractPDF(pdf.Bytes())
+	if err != nil {
+		t.Fatalf("extractPDF() error = %v", err)
+	}
+	if !strings.Contains(text, "head") || strings.Contains(text, "tail") {
+		t.Fatalf("expected extraction to stop once the document limit was spent, got %q", text)
+	}
+}
+
+func TestExtractDOCX(t *testing.T) {
+	doc := `<?xml version="1.0"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		`<w:p><w:r><w:t>First</w:t></w:r><w:r><w:t xml:space="preserve"> paragraph</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>A</w:t><w:tab/><w:t>B</w:t><w:br/><w:t>C &amp; D</w:t></w:r></w:p>` +
+		`</w:body></w:document>`
+	data := buildZip(t, map[string]string{"word/document.xml": doc, "[Content_Types].xml": "<Types/>"})
+	// Detection by magic bytes, without mime or file name hints.
+	res, err := NewService(nil, Config{}).Extract(context.Background(), "", "", "", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if res.Format != FormatDOCX {
+		t.Fatalf("format = %q", res.Format)
+	}
+	if want := "First paragraph\nA\tB\nC & D"; res.Text != want {
+		t.Fatalf("text = %q, want %q", res.Text, want)
+	}
+}
+
+func TestExtractXLSX(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+			`<sheet name="Summary" sheetId="1" r:id="rId2"/><sheet name="Empty" sheetId="2" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":     `<sst><si><t>Name</t></si><si><t>Total</t></si><si><r><t>Al</t></r><r><t>ice</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" t="b"><v>1</v></c><c r="C2"><v>42.5</v></c></row>` +
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>Bob</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+	})
+	res, err := NewService(nil, Config{}).Extract(context.Background(), "", "", "book.xlsx", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := "## Summary\nName\t\tTotal\nAlice\tTRUE\t42.5\nBob"
+	if res.Text != want {
+		t.Fatalf("text = %q, want %q", res.Text, want)
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	page := `<html><head><title>Doc</title><style>p{}</style><script>alert(1)</script></head>` +
+		`<body><h2>Intro</h2><p>Hello <b>bold</b>   text.</p><ul><li>one</li><li>two</li></ul>` +
+		`<table><tr><td>a</td><td>b</td></tr></table><pre>x  y</pre></body></html>`
+	res, err := NewService(nil, Config{}).Extract(context.Background(), "", "text/html; charset=utf-8", "", strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := "Doc\n\n## Intro\n\nHello bold text.\n\n- one\n\n- two\n\na\tb\n\nx  y"
+	if res.Text != want {
+		t.Fatalf("text = %q, want %q", res.Text, want)
+	}
+}
+
+func TestExtractTruncatesAndCaches(t *testing.T) {
+	svc := NewService(nil, Config{MaxBytes: 256, MaxLines: 20})
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sb, "line %d\r\n", i)
+	}
+	res, err := svc.Extract(context.Background(), "hash-1", "text/plain", "notes.txt", strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !res.Truncated || len(res.Text) > 512 {
+		t.Fatalf("expected bounded output, got truncated=%v len=%d", res.Truncated, len(res.Text))
+	}
+	if !strings.HasPrefix(res.Text, textprune.DefaultMarker) || !strings.Contains(res.Text, "\nline 0\nline 1\n") || !strings.Contains(res.Text, "line 199") {
+		t.Fatalf("expected head and tail to be kept, got %q", res.Text)
+	}
+	cached, err := svc.Extract(context.Background(), "hash-1", "text/plain", "notes.txt", nil)
+	if err != nil {
+		t.Fatalf("cached Extract() error = %v", err)
+	}
+	if cached != res {
+		t.Fatal("expected cached result")
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	svc := NewService(nil, Config{})
+	if Supported("image/png", "photo.png") {
+		t.Fatal("images should not be supported")
+	}
+	if !Supported("", "README.md") || !Supported("application/pdf", "") {
+		t.Fatal("expected markdown and pdf to be supported")
+	}
+	_, err := svc.Extract(context.Background(), "", "application/octet-stream", "blob.bin", bytes.NewReader([]byte{0x00, 0x01, 0x02}))
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	_, err = svc.Extract(context.Background(), "", "text/plain", "", strings.NewReader(" \n\n "))
+	if !errors.Is(err, ErrNoText) {
+		t.Fatalf("expected ErrNoText, got %v", err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	svc := NewService(nil, Config{CacheEntries: 2})
+	for _, key := range []string{"a", "b", "c"} {
+		if _, err := svc.Extract(context.Background(), key, "text/plain", "", strings.NewReader("text "+key)); err != nil {
+			t.Fatalf("Extract(%s) error = %v", key, err)
+		}
+	}
+	if _, ok := svc.lookup("a"); ok {
+		t.Fatal("expected oldest entry to be evicted")
+	}
+	if res, ok := svc.lookup("c"); !ok || res.Text != "text c" {
+		t.Fatalf("expected newest entry cached, got %#v %v", res, ok)
+	}
+}
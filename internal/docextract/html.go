@@ -0,0 +1,105 @@
+package docextract
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// extractHTML renders visible text, keeping block structure as line breaks.
+func extractHTML(data []byte) (string, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	renderHTMLNode(&out, root, false)
+	return out.String(), nil
+}
+
+func renderHTMLNode(out *strings.Builder, n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			out.WriteString(n.Data)
+			return
+		}
+		text := strings.Join(strings.Fields(n.Data), " ")
+		if text == "" {
+			return
+		}
+		if startsWithSpace(n.Data) && !endsWithBreak(out) {
+			out.WriteString(" ")
+		}
+		out.WriteString(text)
+		if endsWithSpace(n.Data) {
+			out.WriteString(" ")
+		}
+		return
+	case html.ElementNode:
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Svg:
+			return
+		case atom.Head:
+			// Keep the document title, skip everything else in <head>.
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && c.DataAtom == atom.Title {
+					renderHTMLNode(out, c, false)
+					out.WriteString("\n\n")
+				}
+			}
+			return
+		case atom.Br:
+			out.WriteString("\n")
+			return
+		case atom.Pre:
+			pre = true
+		}
+	}
+	block := n.Type == html.ElementNode && isHTMLBlock(n.DataAtom)
+	if block {
+		out.WriteString("\n")
+		switch n.DataAtom {
+		case atom.Li:
+			out.WriteString("- ")
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			out.WriteString(strings.Repeat("#", int(n.Data[1]-'0')))
+			out.WriteString(" ")
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderHTMLNode(out, c, pre)
+	}
+	if n.Type == html.ElementNode && (n.DataAtom == atom.Td || n.DataAtom == atom.Th) {
+		out.WriteString("\t")
+	}
+	if block {
+		out.WriteString("\n")
+	}
+}
+
+func isHTMLBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer,
+		atom.Nav, atom.Aside, atom.Main, atom.Blockquote, atom.Pre, atom.Ul, atom.Ol,
+		atom.Li, atom.Dl, atom.Dt, atom.Dd, atom.Table, atom.Tr, atom.Hr,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Figure, atom.Figcaption:
+		return true
+	}
+	return false
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s[:1], " \t\r\n") == ""
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s[len(s)-1:], " \t\r\n") == ""
+}
+
+func endsWithBreak(out *strings.Builder) bool {
+	s := out.String()
+	return s == "" || strings.HasSuffix(s, "\n") || strings.HasSuffix(s, " ") || strings.HasSuffix(s, "\t")
+}
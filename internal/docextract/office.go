@@ -0,0 +1,383 @@
+package docextract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxOfficePartBytes bounds a single decompressed XML part so a crafted
+// archive cannot expand without limit.
+const maxOfficePartBytes = 64 * 1024 * 1024
+
+var errPartNotFound = errors.New("archive part not found")
+
+// detectOfficeFormat tells DOCX and XLSX apart by their main part.
+func detectOfficeFormat(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			return FormatDOCX
+		case "xl/workbook.xml":
+			return FormatXLSX
+		}
+	}
+	return ""
+}
+
+func openZipPart(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = rc.Close()
+		}()
+		data, err := io.ReadAll(io.LimitReader(rc, maxOfficePartBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxOfficePartBytes {
+			return nil, fmt.Errorf("%s exceeds %d bytes", name, maxOfficePartBytes)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s: %w", name, errPartNotFound)
+}
+
+// extractDOCX reads paragraphs, tabs and line breaks from word/document.xml.
+func extractDOCX(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	part, err := openZipPart(zr, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	dec := xml.NewDecoder(bytes.NewReader(part))
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				out.WriteString("\t")
+			case "br", "cr":
+				out.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				out.WriteString("\n")
+			case "tc":
+				out.WriteString("\t")
+			}
+		case xml.CharData:
+			if inText {
+				out.Write(t)
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+type xlsxSheet struct {
+	name string
+	path string
+}
+
+// extractXLSX renders every worksheet as tab-separated rows under a heading
+// with the sheet name.
+func extractXLSX(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	shared, err := xlsxSharedStrings(zr)
+	if err != nil {
+		return "", err
+	}
+	sheets, err := xlsxSheets(zr)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, sheet := range sheets {
+		part, err := openZipPart(zr, sheet.path)
+		if err != nil {
+			if errors.Is(err, errPartNotFound) {
+				continue
+			}
+			return "", err
+		}
+		rows, err := xlsxRows(part, shared)
+		if err != nil {
+			return "", fmt.Errorf("sheet %s: %w", sheet.name, err)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		out.WriteString("## ")
+		out.WriteString(sheet.name)
+		out.WriteString("\n")
+		for _, row := range rows {
+			out.WriteString(row)
+			out.WriteString("\n")
+		}
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
+
+func xlsxSharedStrings(zr *zip.Reader) ([]string, error) {
+	part, err := openZipPart(zr, "xl/sharedStrings.xml")
+	if errors.Is(err, errPartNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var (
+		values  []string
+		current strings.Builder
+		inItem  bool
+		inText  bool
+	)
+	dec := xml.NewDecoder(bytes.NewReader(part))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				inItem = true
+				current.Reset()
+			case "t":
+				inText = inItem
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				values = append(values, current.String())
+				inItem = false
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+	return values, nil
+}
+
+// xlsxSheets lists worksheets in workbook order, resolving their part paths
+// through the workbook relationships.
+func xlsxSheets(zr *zip.Reader) ([]xlsxSheet, error) {
+	workbook, err := openZipPart(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	var wb struct {
+		Sheets []struct {
+			Name string     `xml:"name,attr"`
+			Attr []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(workbook, &wb); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	if rels, err := openZipPart(zr, "xl/_rels/workbook.xml.rels"); err == nil {
+		var parsed struct {
+			Items []struct {
+				ID     string `xml:"Id,attr"`
+				Target string `xml:"Target,attr"`
+			} `xml:"Relationship"`
+		}
+		if err := xml.Unmarshal(rels, &parsed); err == nil {
+			for _, item := range parsed.Items {
+				target := strings.TrimPrefix(item.Target, "/")
+				if !strings.HasPrefix(target, "xl/") {
+					target = path.Join("xl", target)
+				}
+				targets[item.ID] = target
+			}
+		}
+	}
+	sheets := make([]xlsxSheet, 0, len(wb.Sheets))
+	for i, s := range wb.Sheets {
+		sheet := xlsxSheet{name: s.Name, path: fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1)}
+		for _, attr := range s.Attr {
+			if attr.Name.Local == "id" {
+				if target, ok := targets[attr.Value]; ok {
+					sheet.path = target
+				}
+			}
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sheets, nil
+}
+
+type xlsxCell struct {
+	col   int
+	value string
+}
+
+func xlsxRows(part []byte, shared []string) ([]string, error) {
+	var (
+		rows    []string
+		cells   []xlsxCell
+		cell    xlsxCell
+		kind    string
+		value   strings.Builder
+		inValue bool
+		nextCol int
+	)
+	dec := xml.NewDecoder(bytes.NewReader(part))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				cells = cells[:0]
+				nextCol = 0
+			case "c":
+				cell = xlsxCell{col: nextCol}
+				kind = ""
+				value.Reset()
+				for _, attr := range t.Attr {
+					switch attr.Name.Local {
+					case "r":
+						if col, ok := xlsxColumnIndex(attr.Value); ok {
+							cell.col = col
+						}
+					case "t":
+						kind = attr.Value
+					}
+				}
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				cell.value = xlsxCellValue(kind, value.String(), shared)
+				if cell.value != "" {
+					cells = append(cells, cell)
+				}
+				nextCol = cell.col + 1
+			case "row":
+				if line := joinXLSXRow(cells); line != "" {
+					rows = append(rows, line)
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+	return rows, nil
+}
+
+func xlsxCellValue(kind, raw string, shared []string) string {
+	raw = strings.TrimSpace(raw)
+	switch kind {
+	case "s":
+		idx, err := strconv.Atoi(raw)
+		if err != nil || idx < 0 || idx >= len(shared) {
+			return ""
+		}
+		return shared[idx]
+	case "b":
+		if raw == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return raw
+	}
+}
+
+// xlsxColumnIndex converts the letters of an A1-style reference to a
+// zero-based column index.
+func xlsxColumnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+func joinXLSXRow(cells []xlsxCell) string {
+	if len(cells) == 0 {
+		return ""
+	}
+	sort.SliceStable(cells, func(i, j int) bool { return cells[i].col < cells[j].col })
+	var sb strings.Builder
+	col := cells[0].col
+	for i, c := range cells {
+		if i > 0 {
+			for ; col < c.col; col++ {
+				sb.WriteString("\t")
+			}
+		}
+		col = c.col
+		sb.WriteString(strings.ReplaceAll(c.value, "\n", " "))
+	}
+	return sb.String()
+}
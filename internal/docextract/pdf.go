@@ -0,0 +1,437 @@
+package docextract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// maxInflatedStreamBytes bounds a single decompressed PDF content stream.
+	maxInflatedStreamBytes = 16 * 1024 * 1024
+	// maxInflatedDocumentBytes bounds what all streams of one document may
+	// decompress to, so many small bombs cannot add up.
+	maxInflatedDocumentBytes = 64 * 1024 * 1024
+)
+
+// extractPDF is a best-effort text extractor for PDF content streams. It
+// handles uncompressed and FlateDecode streams and the common text showing
+// operators; text drawn with custom font encodings and no Unicode mapping
+// comes out garbled or not at all, and scanned PDFs have no text to extract.
+func extractPDF(data []byte) (string, error) {
+	var out strings.Builder
+	rest := data
+	budget := maxInflatedDocumentBytes
+	for budget > 0 {
+		idx := indexStreamKeyword(rest)
+		if idx < 0 {
+			break
+		}
+		dict := streamDictionary(rest[:idx])
+		bodyStart := idx + len("stream")
+		if bodyStart < len(rest) && rest[bodyStart] == '\r' {
+			bodyStart++
+		}
+		if bodyStart < len(rest) && rest[bodyStart] == '\n' {
+			bodyStart++
+		}
+		end := bytes.Index(rest[bodyStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		body := rest[bodyStart : bodyStart+end]
+		rest = rest[bodyStart+end+len("endstream"):]
+
+		content, ok := decodePDFStream(dict, body, &budget)
+		if !ok {
+			continue
+		}
+		if text := pdfContentText(content); strings.TrimSpace(text) != "" {
+			out.WriteString(text)
+			out.WriteString("\n")
+		}
+	}
+	if out.Len() == 0 {
+		return "", ErrNoText
+	}
+	return out.String(), nil
+}
+
+// indexStreamKeyword finds the next "stream" keyword that is not part of
+// "endstream".
+func indexStreamKeyword(data []byte) int {
+	offset := 0
+	for {
+		idx := bytes.Index(data[offset:], []byte("stream"))
+		if idx < 0 {
+			return -1
+		}
+		pos := offset + idx
+		if pos >= 3 && string(data[pos-3:pos]) == "end" {
+			offset = pos + len("stream")
+			continue
+		}
+		return pos
+	}
+}
+
+// streamDictionary returns the dictionary text preceding a stream keyword.
+func streamDictionary(prefix []byte) string {
+	start := bytes.LastIndex(prefix, []byte("obj"))
+	if start < 0 || len(prefix)-start > 4096 {
+		start = len(prefix) - 1024
+		if start < 0 {
+			start = 0
+		}
+	}
+	return string(prefix[start:])
+}
+
+// decodePDFStream returns the content of a stream that may carry page text.
+// Inflated bytes are charged to budget, which caps the stream's output.
+func decodePDFStream(dict string, body []byte, budget *int) ([]byte, bool) {
+	// Images, fonts, xref and object streams never carry page text.
+	for _, marker := range []string{"/Image", "/XRef", "/ObjStm", "/Length1", "/Length2", "/Length3", "/FontFile", "/Type1C", "/CIDFontType0C", "/OpenType", "/Metadata"} {
+		if strings.Contains(dict, marker) {
+			return nil, false
+		}
+	}
+	if !strings.Contains(dict, "/Filter") {
+		return body, true
+	}
+	if !strings.Contains(dict, "/FlateDecode") {
+		return nil, false
+	}
+	for _, filter := range []string{"/DCTDecode", "/JPXDecode", "/LZWDecode", "/ASCII85Decode", "/ASCIIHexDecode", "/RunLengthDecode", "/CCITTFaxDecode", "/JBIG2Decode"} {
+		if strings.Contains(dict, filter) {
+			return nil, false
+		}
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, false
+	}
+	defer func() {
+		_ = zr.Close()
+	}()
+	inflated, err := io.ReadAll(io.LimitReader(zr, int64(min(maxInflatedStreamBytes, *budget))))
+	*budget -= len(inflated)
+	if err != nil && len(inflated) == 0 {
+		return nil, false
+	}
+	return inflated, true
+}
+
+// pdfContentText interprets text operators inside BT/ET blocks.
+func pdfContentText(content []byte) string {
+	var (
+		out      strings.Builder
+		operands []any
+		inText   bool
+	)
+	lex := pdfLexer{data: content}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		switch v := tok.(type) {
+		case pdfOperator:
+			switch string(v) {
+			case "BT":
+				inText = true
+			case "ET":
+				if inText {
+					out.WriteString("\n")
+				}
+				inText = false
+			case "Tj":
+				if inText {
+					writePDFStrings(&out, operands)
+				}
+			case "'", "\"":
+				if inText {
+					out.WriteString("\n")
+					writePDFStrings(&out, operands)
+				}
+			case "TJ":
+				if inText {
+					writePDFArray(&out, operands)
+				}
+			case "T*":
+				if inText {
+					out.WriteString("\n")
+				}
+			case "Td", "TD":
+				if inText {
+					if len(operands) >= 2 && pdfNumber(operands[len(operands)-1]) != 0 {
+						out.WriteString("\n")
+					} else {
+						out.WriteString(" ")
+					}
+				}
+			case "Tm":
+				if inText {
+					out.WriteString("\n")
+				}
+			}
+			operands = operands[:0]
+		default:
+			operands = append(operands, tok)
+		}
+	}
+	return out.String()
+}
+
+func writePDFStrings(out *strings.Builder, operands []any) {
+	for _, op := range operands {
+		if s, ok := op.(pdfString); ok {
+			out.WriteString(decodePDFText(s))
+		}
+	}
+}
+
+func writePDFArray(out *strings.Builder, operands []any) {
+	for _, op := range operands {
+		arr, ok := op.(pdfArray)
+		if !ok {
+			continue
+		}
+		for _, item := range arr {
+			switch v := item.(type) {
+			case pdfString:
+				out.WriteString(decodePDFText(v))
+			case float64:
+				// Large negative kerning is how most producers encode word gaps.
+				if v < -200 {
+					out.WriteString(" ")
+				}
+			}
+		}
+	}
+}
+
+func pdfNumber(v any) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	return 0
+}
+
+// decodePDFText decodes UTF-16BE strings (with BOM) and treats everything
+// else as Latin-1, which matches PDFDocEncoding for printable ASCII.
+func decodePDFText(s pdfString) string {
+	b := []byte(s)
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, (len(b)-2)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, 0, len(b))
+	for _, c := range b {
+		if c < 0x20 && c != '\t' && c != '\n' {
+			continue
+		}
+		runes = append(runes, rune(c))
+	}
+	return string(runes)
+}
+
+type (
+	pdfOperator string
+	pdfString   string
+	pdfName     string
+	pdfArray    []any
+)
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *pdfLexer) next() (any, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, false
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		return l.literalString(), true
+	case c == '<' && l.peek(1) == '<':
+		l.pos += 2
+		return pdfOperator("<<"), true
+	case c == '>' && l.peek(1) == '>':
+		l.pos += 2
+		return pdfOperator(">>"), true
+	case c == '<':
+		return l.hexString(), true
+	case c == '[':
+		l.pos++
+		var arr pdfArray
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return arr, true
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return arr, true
+			}
+			item, ok := l.next()
+			if !ok {
+				return arr, true
+			}
+			arr = append(arr, item)
+		}
+	case c == '/':
+		l.pos++
+		return pdfName(l.word()), true
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		word := l.word()
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f, true
+		}
+		return pdfOperator(word), true
+	default:
+		word := l.word()
+		if word == "" {
+			// Unbalanced delimiters: skip a byte so the lexer always advances.
+			l.pos++
+			return pdfOperator(string(c)), true
+		}
+		return pdfOperator(word), true
+	}
+}
+
+func (l *pdfLexer) peek(offset int) byte {
+	if l.pos+offset < len(l.data) {
+		return l.data[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) || isPDFDelimiter(c) {
+			break
+		}
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // opening paren
+	var buf []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return pdfString(buf)
+			}
+			esc := l.data[l.pos]
+			l.pos++
+			switch esc {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b', 'f':
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if esc >= '0' && esc <= '7' {
+					val := int(esc - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						val = val*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					buf = append(buf, byte(val))
+				} else {
+					buf = append(buf, esc)
+				}
+			}
+		case '(':
+			depth++
+			buf = append(buf, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(buf)
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return pdfString(buf)
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // opening angle bracket
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; isHexDigit(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // closing angle bracket
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i+1 < len(digits); i += 2 {
+		v, _ := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		out = append(out, byte(v))
+	}
+	return pdfString(out)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/apitokens"
+)
+
+type APITokensHandler struct {
+	service *apitokens.Service
+	logger  *slog.Logger
+}
+
+func NewAPITokensHandler(log *slog.Logger, service *apitokens.Service) *APITokensHandler {
+	return &APITokensHandler{
+		service: service,
+		logger:  log.With(slog.String("handler", "api_tokens")),
+	}
+}
+
+func (h *APITokensHandler) Register(e *echo.Echo) {
+	group := e.Group("/api-tokens")
+	group.GET("", h.List)
+	group.POST("", h.Create)
+	group.DELETE("/:id", h.Delete)
+}
+
+// List godoc
+// @Summary List API tokens
+// @Description List the current user's API tokens. Plaintext tokens are never returned here.
+// @Tags api-tokens
+// @Success 200 {object} apitokens.ListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api-tokens [get]
+func (h *APITokensHandler) List(c echo.Context) error {
+	userID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	items, err := h.service.List(c.Request().Context(), userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, apitokens.ListResponse{Items: items})
+}
+
+// Create godoc
+// @Summary Create API token
+// @Description Create an API token for the OpenAI-compatible endpoints. The plaintext token is only returned in this response.
+// @Tags api-tokens
+// @Param payload body apitokens.CreateRequest true "Token payload"
+// @Success 201 {object} apitokens.Token
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api-tokens [post]
+func (h *APITokensHandler) Create(c echo.Context) error {
+	userID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	var req apitokens.CreateRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "name is required")
+	}
+	if req.TTLSeconds < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "ttl_seconds must not be negative")
+	}
+	token, err := h.service.Create(c.Request().Context(), userID, req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, token)
+}
+
+// Delete godoc
+// @Summary Revoke API token
+// @Description Revoke one of the current user's API tokens
+// @Tags api-tokens
+// @Param id path string true "Token ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api-tokens/{id} [delete]
+func (h *APITokensHandler) Delete(c echo.Context) error {
+	userID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	tokenID := strings.TrimSpace(c.Param("id"))
+	if tokenID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "token id is required")
+	}
+	if err := h.service.Delete(c.Request().Context(), userID, tokenID); err != nil {
+		if errors.Is(err, apitokens.ErrTokenNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "api token not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
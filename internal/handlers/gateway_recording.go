@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/auth"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/conversation/recording"
+)
+
+// replayGatewayTokenTTL bounds the JWT minted for tool calls of a replay.
+const replayGatewayTokenTTL = 30 * time.Minute
+
+type GatewayRecordingHandler struct {
+	service        *recording.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	jwtSecret      string
+	logger         *slog.Logger
+}
+
+func NewGatewayRecordingHandler(log *slog.Logger, service *recording.Service, botService *bots.Service, accountService *accounts.Service, jwtSecret string) *GatewayRecordingHandler {
+	return &GatewayRecordingHandler{
+		service:        service,
+		botService:     botService,
+		accountService: accountService,
+		jwtSecret:      jwtSecret,
+		logger:         log.With(slog.String("handler", "gateway_recording")),
+	}
+}
+
+func (h *GatewayRecordingHandler) Register(e *echo.Echo) {
+	group := e.Group("/bots/:bot_id/gateway-recordings")
+	group.GET("/settings", h.GetSettings)
+	group.PUT("/settings", h.UpdateSettings)
+	group.GET("", h.List)
+	group.DELETE("", h.Clear)
+	group.POST("/replay", h.Replay)
+	group.GET("/:recording_id", h.Get)
+}
+
+// GetSettings godoc
+// @Summary Get gateway recording settings
+// @Description Report whether the agent gateway requests and responses of a bot are recorded for replay
+// @Tags gateway-recordings
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} recording.Settings
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/gateway-recordings/settings [get]
+func (h *GatewayRecordingHandler) GetSettings(c echo.Context) error {
+	botID, _, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	settings, err := h.service.GetSettings(c.Request().Context(), botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings godoc
+// @Summary Update gateway recording settings
+// @Description Turn recording of a bot's agent gateway requests on or off. Recordings hold the full prompt and history of each turn, without provider keys or session tokens.
+// @Tags gateway-recordings
+// @Param bot_id path string true "Bot ID"
+// @Param payload body recording.Settings true "Recording settings"
+// @Success 200 {object} recording.Settings
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/gateway-recordings/settings [put]
+func (h *GatewayRecordingHandler) UpdateSettings(c echo.Context) error {
+	botID, _, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	var req recording.Settings
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	settings, err := h.service.UpdateSettings(c.Request().Context(), botID, req)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+// List godoc
+// @Summary List gateway recordings
+// @Description List the latest recorded gateway rounds of a bot, newest first, without their payloads
+// @Tags gateway-recordings
+// @Param bot_id path string true "Bot ID"
+// @Param limit query int false "Limit" default(50)
+// @Success 200 {array} recording.Recording
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/gateway-recordings [get]
+func (h *GatewayRecordingHandler) List(c echo.Context) error {
+	botID, _, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	var limit int32
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || parsed <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		limit = int32(parsed)
+	}
+	items, err := h.service.List(c.Request().Context(), botID, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// Get godoc
+// @Summary Get a gateway recording
+// @Description Get one recorded gateway round with the request as sent and the response received
+// @Tags gateway-recordings
+// @Param bot_id path string true "Bot ID"
+// @Param recording_id path string true "Recording ID"
+// @Success 200 {object} recording.Recording
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/gateway-recordings/{recording_id} [get]
+func (h *GatewayRecordingHandler) Get(c echo.Context) error {
+	botID, _, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	item, err := h.service.Get(c.Request().Context(), botID, strings.TrimSpace(c.Param("recording_id")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "recording not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, item)
+}
+
+// Clear godoc
+// @Summary Delete gateway recordings
+// @Description Delete every recorded gateway round of a bot. Recording stays on if it was enabled.
+// @Tags gateway-recordings
+// @Param bot_id path string true "Bot ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/gateway-recordings [delete]
+func (h *GatewayRecordingHandler) Clear(c echo.Context) error {
+	botID, _, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	if _, err := h.service.Clear(c.Request().Context(), botID); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Replay godoc
+// @Summary Replay gateway recordings
+// @Description Send recorded gateway requests again, optionally to another model or gateway, and compare the answers with the recorded ones: text, tool calls and token usage. Replays are not stored and run without tools unless allow_tools is set. Only admins may choose a gateway_url, since the provider key is sent with each request.
+// @Tags gateway-recordings
+// @Accept json
+// @Produce json
+// @Produce text/markdown
+// @Param bot_id path string true "Bot ID"
+// @Param format query string false "json (default) or markdown"
+// @Param payload body recording.ReplayRequest true "Recordings and replay target"
+// @Success 200 {object} recording.Report
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/gateway-recordings/replay [post]
+func (h *GatewayRecordingHandler) Replay(c echo.Context) error {
+	botID, channelIdentityID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	format := strings.ToLower(strings.TrimSpace(c.QueryParam("format")))
+	switch format {
+	case "", "json", "markdown", "md":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be json or markdown")
+	}
+	var req recording.ReplayRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	ctx := c.Request().Context()
+	if strings.TrimSpace(req.GatewayURL) != "" {
+		isAdmin, err := h.accountService.IsAdmin(ctx, channelIdentityID)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		if !isAdmin {
+			return echo.NewHTTPError(http.StatusForbidden, "only admins can replay against another gateway")
+		}
+	}
+	token, _, err := auth.GenerateToken(channelIdentityID, h.jwtSecret, replayGatewayTokenTTL)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	req.Token = "Bearer " + token
+
+	report, err := h.service.Replay(ctx, botID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, recording.ErrTooManyRecordings):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, pgx.ErrNoRows):
+			return echo.NewHTTPError(http.StatusNotFound, "recording not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if format == "markdown" || format == "md" {
+		return c.Blob(http.StatusOK, "text/markdown; charset=utf-8", []byte(report.Markdown()))
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+func (h *GatewayRecordingHandler) requireBot(c echo.Context) (string, string, error) {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return "", "", err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	if _, err := h.authorizeBotAccess(c.Request().Context(), channelIdentityID, botID); err != nil {
+		return "", "", err
+	}
+	return botID, channelIdentityID, nil
+}
+
+func (h *GatewayRecordingHandler) authorizeBotAccess(ctx context.Context, channelIdentityID, botID string) (bots.Bot, error) {
+	return AuthorizeBotAccess(ctx, h.botService, h.accountService, channelIdentityID, botID, bots.AccessPolicy{AllowPublicMember: false})
+}
@@ -48,6 +48,7 @@ func (h *LocalChannelHandler) Register(e *echo.Echo) {
 	group := e.Group(prefix)
 	group.GET("/stream", h.StreamMessages)
 	group.POST("/messages", h.PostMessage)
+	group.GET("/ws", h.Connect)
 }
 
 // StreamMessages godoc
@@ -168,10 +169,20 @@ func (h *LocalChannelHandler) PostMessage(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	msg := h.newInboundMessage(botID, channelIdentityID, req.Message)
+	if err := h.channelManager.HandleInbound(c.Request().Context(), cfg, msg); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// newInboundMessage wraps a local user message in the inbound envelope shared
+// by the HTTP and WebSocket transports. Local routes are keyed by bot ID.
+func (h *LocalChannelHandler) newInboundMessage(botID, channelIdentityID string, message channel.Message) channel.InboundMessage {
 	routeKey := botID
-	msg := channel.InboundMessage{
+	return channel.InboundMessage{
 		Channel:     h.channelType,
-		Message:     req.Message,
+		Message:     message,
 		BotID:       botID,
 		ReplyTarget: routeKey,
 		RouteKey:    routeKey,
@@ -188,10 +199,6 @@ func (h *LocalChannelHandler) PostMessage(c echo.Context) error {
 		ReceivedAt: time.Now().UTC(),
 		Source:     "local",
 	}
-	if err := h.channelManager.HandleInbound(c.Request().Context(), cfg, msg); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-	}
-	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
 
 func (h *LocalChannelHandler) ensureBotParticipant(ctx context.Context, botID, channelIdentityID string) error {
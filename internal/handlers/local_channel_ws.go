@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/channel/adapters/local"
+)
+
+const (
+	localWSWriteTimeout = 10 * time.Second
+	localWSPongTimeout  = 60 * time.Second
+	localWSPingInterval = 25 * time.Second
+	localWSMaxFrameSize = 8 * 1024 * 1024
+)
+
+// Local WebSocket frame types. Clients send message, cancel and ping frames;
+// the server answers with ack, event, done, cancelled, error and pong frames.
+const (
+	LocalWSFrameMessage   = "message"
+	LocalWSFrameCancel    = "cancel"
+	LocalWSFramePing      = "ping"
+	LocalWSFrameAck       = "ack"
+	LocalWSFrameEvent     = "event"
+	LocalWSFrameDone      = "done"
+	LocalWSFrameCancelled = "cancelled"
+	LocalWSFrameError     = "error"
+	LocalWSFramePong      = "pong"
+)
+
+// LocalWSClientFrame is a frame sent by a local channel WebSocket client.
+// ID is chosen by the client to correlate acks and cancellation; a cancel
+// frame without ID cancels every reply started on the connection.
+type LocalWSClientFrame struct {
+	Type    string           `json:"type"`
+	ID      string           `json:"id,omitempty"`
+	Message *channel.Message `json:"message,omitempty"`
+}
+
+// LocalWSServerFrame is a frame sent to a local channel WebSocket client.
+type LocalWSServerFrame struct {
+	Type  string               `json:"type"`
+	ID    string               `json:"id,omitempty"`
+	Event *channel.StreamEvent `json:"event,omitempty"`
+	Error string               `json:"error,omitempty"`
+}
+
+// Tokens travel in the query string and are validated by the JWT middleware,
+// so cross-origin pages cannot ride on ambient credentials.
+var localWSUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(*http.Request) bool { return true },
+}
+
+// Connect godoc
+// @Summary Open a bidirectional local channel WebSocket
+// @Description Upgrade to a WebSocket that multiplexes sending messages, stream events (including processing status) and cancellation for the given bot. Authenticate with the token query parameter.
+// @Tags local-channel
+// @Param bot_id path string true "Bot ID"
+// @Param token query string false "Access token"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/web/ws [get]
+// @Router /bots/{bot_id}/cli/ws [get]
+func (h *LocalChannelHandler) Connect(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	if _, err := h.authorizeBotAccess(c.Request().Context(), channelIdentityID, botID); err != nil {
+		return err
+	}
+	if err := h.ensureBotParticipant(c.Request().Context(), botID, channelIdentityID); err != nil {
+		return err
+	}
+	if h.routeHub == nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "route hub not configured")
+	}
+	if h.channelManager == nil || h.channelStore == nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "channel manager not configured")
+	}
+
+	conn, err := localWSUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// The upgrader has already written an HTTP error response.
+		return nil
+	}
+	session := &localWSSession{
+		handler:           h,
+		conn:              conn,
+		botID:             botID,
+		channelIdentityID: channelIdentityID,
+		inflight:          map[string]*localWSReply{},
+	}
+	session.run(c.Request().Context())
+	return nil
+}
+
+// localWSReply tracks one reply started from the connection.
+type localWSReply struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+type localWSSession struct {
+	handler           *LocalChannelHandler
+	conn              *websocket.Conn
+	botID             string
+	channelIdentityID string
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	inflight map[string]*localWSReply
+	nextID   int
+}
+
+func (s *localWSSession) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer func() {
+		_ = s.conn.Close()
+	}()
+
+	_, stream, unsubscribe := s.handler.routeHub.Subscribe(s.botID)
+	defer unsubscribe()
+
+	go s.forwardEvents(ctx, cancel, stream)
+	go s.keepAlive(ctx, cancel)
+
+	s.conn.SetReadLimit(localWSMaxFrameSize)
+	_ = s.conn.SetReadDeadline(time.Now().Add(localWSPongTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(localWSPongTimeout))
+	})
+	for {
+		var frame LocalWSClientFrame
+		if err := s.conn.ReadJSON(&frame); err != nil {
+			// Closing the socket leaves in-flight replies running, as with SSE;
+			// clients resubscribe and receive the remaining events.
+			return
+		}
+		_ = s.conn.SetReadDeadline(time.Now().Add(localWSPongTimeout))
+		switch strings.ToLower(strings.TrimSpace(frame.Type)) {
+		case LocalWSFrameMessage:
+			s.handleMessage(ctx, frame)
+		case LocalWSFrameCancel:
+			s.handleCancel(strings.TrimSpace(frame.ID))
+		case LocalWSFramePing:
+			s.write(LocalWSServerFrame{Type: LocalWSFramePong, ID: frame.ID})
+		default:
+			s.write(LocalWSServerFrame{Type: LocalWSFrameError, ID: frame.ID, Error: "unsupported frame type"})
+		}
+	}
+}
+
+func (s *localWSSession) handleMessage(ctx context.Context, frame LocalWSClientFrame) {
+	id := strings.TrimSpace(frame.ID)
+	if frame.Message == nil || frame.Message.IsEmpty() {
+		s.write(LocalWSServerFrame{Type: LocalWSFrameError, ID: id, Error: "message is required"})
+		return
+	}
+	s.mu.Lock()
+	if id == "" {
+		s.nextID++
+		id = "msg-" + strconv.Itoa(s.nextID)
+	}
+	_, duplicate := s.inflight[id]
+	s.mu.Unlock()
+	if duplicate {
+		s.write(LocalWSServerFrame{Type: LocalWSFrameError, ID: id, Error: "message id is already in flight"})
+		return
+	}
+
+	h := s.handler
+	cfg, err := h.channelStore.ResolveEffectiveConfig(ctx, s.botID, h.channelType)
+	if err != nil {
+		s.write(LocalWSServerFrame{Type: LocalWSFrameError, ID: id, Error: err.Error()})
+		return
+	}
+	msg := h.newInboundMessage(s.botID, s.channelIdentityID, *frame.Message)
+	done, cancel, err := h.channelManager.HandleInboundWithCancel(ctx, cfg, msg)
+	if err != nil {
+		s.write(LocalWSServerFrame{Type: LocalWSFrameError, ID: id, Error: err.Error()})
+		return
+	}
+	reply := &localWSReply{cancel: cancel}
+	s.mu.Lock()
+	s.inflight[id] = reply
+	s.mu.Unlock()
+	s.write(LocalWSServerFrame{Type: LocalWSFrameAck, ID: id})
+
+	go func() {
+		select {
+		case <-done.Done():
+		case <-ctx.Done():
+			// Connection closed: stop tracking but let the reply finish.
+			s.mu.Lock()
+			delete(s.inflight, id)
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Lock()
+		delete(s.inflight, id)
+		cancelled := reply.cancelled
+		s.mu.Unlock()
+		if cancelled {
+			s.write(LocalWSServerFrame{Type: LocalWSFrameCancelled, ID: id})
+		} else {
+			s.write(LocalWSServerFrame{Type: LocalWSFrameDone, ID: id})
+		}
+	}()
+}
+
+func (s *localWSSession) handleCancel(id string) {
+	s.mu.Lock()
+	var targets []*localWSReply
+	if id == "" {
+		for _, reply := range s.inflight {
+			targets = append(targets, reply)
+		}
+	} else if reply, ok := s.inflight[id]; ok {
+		targets = append(targets, reply)
+	}
+	for _, reply := range targets {
+		reply.cancelled = true
+	}
+	s.mu.Unlock()
+	if id != "" && len(targets) == 0 {
+		s.write(LocalWSServerFrame{Type: LocalWSFrameError, ID: id, Error: "no reply in flight for id"})
+		return
+	}
+	for _, reply := range targets {
+		reply.cancel()
+	}
+}
+
+func (s *localWSSession) forwardEvents(ctx context.Context, cancel context.CancelFunc, stream <-chan local.RouteHubEvent) {
+	defer func() {
+		// Unblock the read loop when event delivery stops.
+		cancel()
+		_ = s.conn.Close()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-stream:
+			if !ok {
+				return
+			}
+			event := msg.Event
+			if err := s.write(LocalWSServerFrame{Type: LocalWSFrameEvent, Event: &event}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *localWSSession) keepAlive(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(localWSPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.writeMu.Lock()
+			err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(localWSWriteTimeout))
+			s.writeMu.Unlock()
+			if err != nil {
+				cancel()
+				_ = s.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// write serializes frames; gorilla/websocket allows one concurrent writer.
+func (s *localWSSession) write(frame LocalWSServerFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = s.conn.SetWriteDeadline(time.Now().Add(localWSWriteTimeout))
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
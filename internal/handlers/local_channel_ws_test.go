@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/channel/adapters/local"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+// wsEchoProcessor publishes the inbound text as a delta and then waits until
+// its reply is cancelled.
+type wsEchoProcessor struct {
+	hub *local.RouteHub
+}
+
+func (p *wsEchoProcessor) HandleInbound(ctx context.Context, cfg channel.ChannelConfig, msg channel.InboundMessage, sender channel.StreamReplySender) error {
+	p.hub.PublishEvent(msg.RouteKey, channel.StreamEvent{Type: channel.StreamEventDelta, Delta: "echo:" + msg.Message.Text})
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestLocalWSSession_SendStreamAndCancel(t *testing.T) {
+	hub := local.NewRouteHub()
+	registry := channel.NewRegistry()
+	if err := registry.Register(local.NewWebAdapter(hub)); err != nil {
+		t.Fatalf("register adapter: %v", err)
+	}
+	store := channel.NewStore(sqlc.New(nil), registry)
+	manager := channel.NewManager(slog.Default(), registry, nil, &wsEchoProcessor{hub: hub})
+	managerCtx, stopManager := context.WithCancel(context.Background())
+	defer stopManager()
+	manager.Start(managerCtx)
+
+	h := &LocalChannelHandler{
+		channelType:    local.WebType,
+		channelManager: manager,
+		channelStore:   store,
+		routeHub:       hub,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := localWSUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		session := &localWSSession{handler: h, conn: conn, botID: "bot-1", channelIdentityID: "user-1", inflight: map[string]*localWSReply{}}
+		session.run(r.Context())
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	readUntil := func(frameType string) LocalWSServerFrame {
+		t.Helper()
+		for {
+			var frame LocalWSServerFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				t.Fatalf("read %s frame: %v", frameType, err)
+			}
+			if frame.Type == frameType {
+				return frame
+			}
+		}
+	}
+
+	if err := conn.WriteJSON(LocalWSClientFrame{Type: LocalWSFramePing, ID: "p1"}); err != nil {
+		t.Fatalf("write ping: %v", err)
+	}
+	if frame := readUntil(LocalWSFramePong); frame.ID != "p1" {
+		t.Fatalf("unexpected pong: %#v", frame)
+	}
+
+	if err := conn.WriteJSON(LocalWSClientFrame{Type: LocalWSFrameMessage, Message: &channel.Message{}}); err != nil {
+		t.Fatalf("write empty message: %v", err)
+	}
+	if frame := readUntil(LocalWSFrameError); frame.Error != "message is required" {
+		t.Fatalf("unexpected error frame: %#v", frame)
+	}
+
+	if err := conn.WriteJSON(LocalWSClientFrame{Type: LocalWSFrameMessage, ID: "m1", Message: &channel.Message{Text: "hello"}}); err != nil {
+		t.Fatalf("write message: %v", err)
+	}
+	if frame := readUntil(LocalWSFrameAck); frame.ID != "m1" {
+		t.Fatalf("unexpected ack: %#v", frame)
+	}
+	event := readUntil(LocalWSFrameEvent)
+	if event.Event == nil || event.Event.Delta != "echo:hello" {
+		t.Fatalf("unexpected event: %#v", event.Event)
+	}
+
+	if err := conn.WriteJSON(LocalWSClientFrame{Type: LocalWSFrameCancel, ID: "m1"}); err != nil {
+		t.Fatalf("write cancel: %v", err)
+	}
+	if frame := readUntil(LocalWSFrameCancelled); frame.ID != "m1" {
+		t.Fatalf("unexpected cancelled frame: %#v", frame)
+	}
+}
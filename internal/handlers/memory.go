@@ -54,13 +54,19 @@ type memoryCompactPayload struct {
 	DecayDays *int    `json:"decay_days,omitempty"`
 }
 
-// namespaceScope holds namespace + scopeId for a single memory scope.
+// namespaceScope holds namespace + scopeId for a single memory scope. An
+// empty ScopeID covers every scope of the namespace within BotID.
 type namespaceScope struct {
 	Namespace string
 	ScopeID   string
+	BotID     string
 }
 
-const sharedMemoryNamespace = "bot"
+const (
+	sharedMemoryNamespace = "bot"
+	userMemoryNamespace   = "user"
+	groupMemoryNamespace  = "group"
+)
 
 // NewMemoryHandler creates a MemoryHandler.
 func NewMemoryHandler(log *slog.Logger, service *memory.Service, chatService *conversation.Service, accountService *accounts.Service) *MemoryHandler {
@@ -178,7 +184,7 @@ func (h *MemoryHandler) ChatAdd(c echo.Context) error {
 
 // ChatSearch godoc
 // @Summary Search memory
-// @Description Search memory in the bot-shared namespace
+// @Description Search memory across the bot-shared, per-user and per-group scopes
 // @Tags memory
 // @Accept json
 // @Produce json
@@ -225,7 +231,7 @@ func (h *MemoryHandler) ChatSearch(c echo.Context) error {
 	// Search shared namespace and merge results.
 	var allResults []memory.MemoryItem
 	for _, scope := range scopes {
-		filters := buildNamespaceFilters(scope.Namespace, scope.ScopeID, payload.Filters)
+		filters := scope.filters(payload.Filters)
 		if botID != "" {
 			filters["bot_id"] = botID
 		}
@@ -261,7 +267,7 @@ func (h *MemoryHandler) ChatSearch(c echo.Context) error {
 
 // ChatGetAll godoc
 // @Summary Get all memories
-// @Description List all memories in the bot-shared namespace
+// @Description List all memories of the bot across the bot-shared, per-user and per-group scopes
 // @Tags memory
 // @Produce json
 // @Param bot_id path string true "Bot ID"
@@ -297,7 +303,7 @@ func (h *MemoryHandler) ChatGetAll(c echo.Context) error {
 	var allResults []memory.MemoryItem
 	for _, scope := range scopes {
 		req := memory.GetAllRequest{
-			Filters: buildNamespaceFilters(scope.Namespace, scope.ScopeID, nil),
+			Filters: scope.filters(nil),
 			NoStats: noStats,
 		}
 		resp, err := h.service.GetAll(c.Request().Context(), req)
@@ -361,14 +367,14 @@ func (h *MemoryHandler) ChatDelete(c echo.Context) error {
 		return c.JSON(http.StatusOK, resp)
 	}
 
-	// Otherwise delete all memories in the bot-shared namespace.
+	// Otherwise delete all memories of the bot.
 	scopes, err := h.resolveEnabledScopes(c.Request().Context(), containerID)
 	if err != nil {
 		return err
 	}
 	for _, scope := range scopes {
 		req := memory.DeleteAllRequest{
-			Filters: buildNamespaceFilters(scope.Namespace, scope.ScopeID, nil),
+			Filters: scope.filters(nil),
 		}
 		if _, err := h.service.DeleteAll(c.Request().Context(), req); err != nil {
 			h.logger.Warn("deleteall namespace failed", slog.String("namespace", scope.Namespace), slog.Any("error", err))
@@ -489,7 +495,7 @@ func (h *MemoryHandler) ChatCompact(c echo.Context) error {
 
 	// Compact the first (primary) scope.
 	scope := scopes[0]
-	filters := buildNamespaceFilters(scope.Namespace, scope.ScopeID, nil)
+	filters := scope.filters(nil)
 	result, err := h.service.Compact(c.Request().Context(), filters, ratio, decayDays)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
@@ -540,7 +546,7 @@ func (h *MemoryHandler) ChatUsage(c echo.Context) error {
 
 	var totalUsage memory.UsageResponse
 	for _, scope := range scopes {
-		filters := buildNamespaceFilters(scope.Namespace, scope.ScopeID, nil)
+		filters := scope.filters(nil)
 		usage, err := h.service.Usage(c.Request().Context(), filters)
 		if err != nil {
 			h.logger.Warn("usage namespace failed", slog.String("namespace", scope.Namespace), slog.Any("error", err))
@@ -605,7 +611,7 @@ func (h *MemoryHandler) ChatRebuild(c echo.Context) error {
 	existingIDs := map[string]struct{}{}
 	for _, scope := range scopes {
 		req := memory.GetAllRequest{
-			Filters: buildNamespaceFilters(scope.Namespace, scope.ScopeID, nil),
+			Filters: scope.filters(nil),
 		}
 		resp, err := h.service.GetAll(c.Request().Context(), req)
 		if err != nil {
@@ -631,7 +637,7 @@ func (h *MemoryHandler) ChatRebuild(c echo.Context) error {
 			}
 		}
 		if len(filters) == 0 && len(scopes) > 0 {
-			filters = buildNamespaceFilters(scopes[0].Namespace, scopes[0].ScopeID, nil)
+			filters = scopes[0].filters(nil)
 		}
 
 		if _, err := h.service.RebuildAdd(c.Request().Context(), fsItem.ID, fsItem.Memory, filters); err != nil {
@@ -651,7 +657,9 @@ func (h *MemoryHandler) ChatRebuild(c echo.Context) error {
 
 // --- helpers ---
 
-// resolveEnabledScopes returns the bot-shared namespace scope for the conversation.
+// resolveEnabledScopes returns the memory scopes of the bot: the bot-shared
+// scope first, then all per-user and per-group scopes the conversation flow
+// writes.
 func (h *MemoryHandler) resolveEnabledScopes(ctx context.Context, chatID string) ([]namespaceScope, error) {
 	if h.chatService == nil {
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, "chat service not configured")
@@ -664,10 +672,11 @@ func (h *MemoryHandler) resolveEnabledScopes(ctx context.Context, chatID string)
 	if botID == "" {
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, "chat bot id is empty")
 	}
-	return []namespaceScope{{
-		Namespace: sharedMemoryNamespace,
-		ScopeID:   botID,
-	}}, nil
+	return []namespaceScope{
+		{Namespace: sharedMemoryNamespace, ScopeID: botID, BotID: botID},
+		{Namespace: userMemoryNamespace, BotID: botID},
+		{Namespace: groupMemoryNamespace, BotID: botID},
+	}, nil
 }
 
 // resolveWriteScope returns (scopeID, botID) for shared bot memory.
@@ -716,6 +725,15 @@ func buildNamespaceFilters(namespace, scopeID string, extra map[string]any) map[
 	return filters
 }
 
+func (s namespaceScope) filters(extra map[string]any) map[string]any {
+	filters := buildNamespaceFilters(s.Namespace, s.ScopeID, extra)
+	if s.ScopeID == "" {
+		delete(filters, "scopeId")
+		filters["bot_id"] = s.BotID
+	}
+	return filters
+}
+
 func deduplicateMemoryItems(items []memory.MemoryItem) []memory.MemoryItem {
 	if len(items) == 0 {
 		return items
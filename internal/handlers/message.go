@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
 	"github.com/memohai/memoh/internal/accounts"
@@ -20,6 +21,7 @@ import (
 	"github.com/memohai/memoh/internal/conversation"
 	"github.com/memohai/memoh/internal/media"
 	messagepkg "github.com/memohai/memoh/internal/message"
+	"github.com/memohai/memoh/internal/message/archive"
 	messageevent "github.com/memohai/memoh/internal/message/event"
 )
 
@@ -31,6 +33,10 @@ type MessageHandler struct {
 	mediaService        *media.Service
 	botService          *bots.Service
 	accountService      *accounts.Service
+	branchRunner        messageBranchRunner
+	archiveService      *archive.Service
+	generations         generationCanceller
+	jwtSecret           string
 	logger              *slog.Logger
 }
 
@@ -62,6 +68,21 @@ func (h *MessageHandler) Register(e *echo.Echo) {
 	botGroup.GET("/messages", h.ListMessages)
 	botGroup.GET("/messages/events", h.StreamMessageEvents)
 	botGroup.DELETE("/messages", h.DeleteMessages)
+	botGroup.GET("/messages/search", h.SearchMessages)
+	botGroup.GET("/messages/export", h.ExportMessages)
+	botGroup.POST("/messages/import", h.ImportMessages)
+	botGroup.PUT("/messages/:message_id/reaction", h.SetMessageReaction)
+	botGroup.DELETE("/messages/:message_id/reaction", h.ClearMessageReaction)
+	botGroup.PUT("/messages/:message_id/star", h.StarMessage)
+	botGroup.DELETE("/messages/:message_id/star", h.UnstarMessage)
+	botGroup.POST("/messages/regenerate", h.RegenerateMessage)
+	botGroup.POST("/messages/:message_id/edit", h.EditMessage)
+	botGroup.GET("/messages/:message_id/alternatives", h.ListMessageAlternatives)
+	botGroup.POST("/messages/:message_id/activate", h.ActivateMessage)
+	botGroup.GET("/generations", h.ListGenerations)
+	botGroup.POST("/generations/cancel", h.CancelGenerations)
+	botGroup.GET("/conversation/settings", h.GetConversationSettings)
+	botGroup.PUT("/conversation/settings", h.UpdateConversationSettings)
 	botGroup.GET("/media/:content_hash", h.ServeMedia)
 }
 
@@ -347,6 +368,243 @@ func (h *MessageHandler) DeleteMessages(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// MessageReactionRequest is the body of SetMessageReaction.
+type MessageReactionRequest struct {
+	Reaction string `json:"reaction"`
+}
+
+// SetMessageReaction godoc
+// @Summary React to an assistant message
+// @Description Record a thumbs up or down on an assistant reply. Reactions are counted in the model variant report.
+// @Tags messages
+// @Accept json
+// @Param bot_id path string true "Bot ID"
+// @Param message_id path string true "Message ID"
+// @Param payload body MessageReactionRequest true "Reaction (up or down)"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/{message_id}/reaction [put]
+func (h *MessageHandler) SetMessageReaction(c echo.Context) error {
+	var req MessageReactionRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	reaction := strings.ToLower(strings.TrimSpace(req.Reaction))
+	if reaction != messagepkg.ReactionUp && reaction != messagepkg.ReactionDown {
+		return echo.NewHTTPError(http.StatusBadRequest, "reaction must be up or down")
+	}
+	return h.updateMessageReaction(c, func(ctx context.Context, reactor messagepkg.Reactor, botID, messageID, userID string) error {
+		return reactor.SetReaction(ctx, botID, messageID, userID, reaction)
+	})
+}
+
+// ClearMessageReaction godoc
+// @Summary Remove a reaction from a message
+// @Description Remove the caller's reaction from an assistant reply
+// @Tags messages
+// @Param bot_id path string true "Bot ID"
+// @Param message_id path string true "Message ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/{message_id}/reaction [delete]
+func (h *MessageHandler) ClearMessageReaction(c echo.Context) error {
+	return h.updateMessageReaction(c, func(ctx context.Context, reactor messagepkg.Reactor, botID, messageID, userID string) error {
+		return reactor.ClearReaction(ctx, botID, messageID, userID)
+	})
+}
+
+func (h *MessageHandler) updateMessageReaction(c echo.Context, apply func(ctx context.Context, reactor messagepkg.Reactor, botID, messageID, userID string) error) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	messageID := strings.TrimSpace(c.Param("message_id"))
+	if _, err := uuid.Parse(messageID); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid message id")
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotAccess(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	if err := h.requireReadable(ctx, botID, channelIdentityID); err != nil {
+		return err
+	}
+	reactor, ok := h.messageService.(messagepkg.Reactor)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "message reactions not configured")
+	}
+	if err := apply(ctx, reactor, botID, messageID, channelIdentityID); err != nil {
+		if errors.Is(err, messagepkg.ErrMessageNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// StarMessage godoc
+// @Summary Star a message
+// @Description Star a message so retention policies with keep_starred never purge it
+// @Tags messages
+// @Param bot_id path string true "Bot ID"
+// @Param message_id path string true "Message ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/{message_id}/star [put]
+func (h *MessageHandler) StarMessage(c echo.Context) error {
+	return h.updateMessageStar(c, func(ctx context.Context, starrer messagepkg.Starrer, botID, messageID, userID string) error {
+		return starrer.Star(ctx, botID, messageID, userID)
+	})
+}
+
+// UnstarMessage godoc
+// @Summary Unstar a message
+// @Description Remove the star from a message
+// @Tags messages
+// @Param bot_id path string true "Bot ID"
+// @Param message_id path string true "Message ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/{message_id}/star [delete]
+func (h *MessageHandler) UnstarMessage(c echo.Context) error {
+	return h.updateMessageStar(c, func(ctx context.Context, starrer messagepkg.Starrer, botID, messageID, _ string) error {
+		return starrer.Unstar(ctx, botID, messageID)
+	})
+}
+
+func (h *MessageHandler) updateMessageStar(c echo.Context, apply func(ctx context.Context, starrer messagepkg.Starrer, botID, messageID, userID string) error) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	messageID := strings.TrimSpace(c.Param("message_id"))
+	if _, err := uuid.Parse(messageID); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid message id")
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotAccess(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	if err := h.requireReadable(ctx, botID, channelIdentityID); err != nil {
+		return err
+	}
+	starrer, ok := h.messageService.(messagepkg.Starrer)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "message stars not configured")
+	}
+	if err := apply(ctx, starrer, botID, messageID, channelIdentityID); err != nil {
+		if errors.Is(err, messagepkg.ErrMessageNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetConversationSettings godoc
+// @Summary Get conversation settings
+// @Description Get the conversation's model and its overrides of the bot chat settings (system prompt, sampling, reasoning, context, skills and tools)
+// @Tags messages
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} conversation.Settings
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/conversation/settings [get]
+func (h *MessageHandler) GetConversationSettings(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotAccess(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	if err := h.requireReadable(ctx, botID, channelIdentityID); err != nil {
+		return err
+	}
+	manager, ok := h.conversationService.(conversation.SettingsManager)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "conversation settings not configured")
+	}
+	settings, err := manager.GetSettings(ctx, botID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
+// UpdateConversationSettings godoc
+// @Summary Update conversation settings
+// @Description Set or reset per-conversation overrides. Omitted fields are unchanged; names listed in reset fall back to the bot settings ("all" resets every override).
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Param payload body conversation.UpdateSettingsRequest true "Settings"
+// @Success 200 {object} conversation.Settings
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/conversation/settings [put]
+func (h *MessageHandler) UpdateConversationSettings(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	var req conversation.UpdateSettingsRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotManage(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	manager, ok := h.conversationService.(conversation.SettingsManager)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "conversation settings not configured")
+	}
+	settings, err := manager.UpdateSettings(ctx, botID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, conversation.ErrInvalidSettings), errors.Is(err, conversation.ErrModelIDAmbiguous):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		case errors.Is(err, conversation.ErrChatNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, settings)
+}
+
 // --- helpers ---
 
 func (h *MessageHandler) requireChannelIdentityID(c echo.Context) (string, error) {
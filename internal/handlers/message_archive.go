@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/message/archive"
+)
+
+// ImportMessagesRequest is the body of ImportMessages.
+type ImportMessagesRequest struct {
+	Archive archive.Archive `json:"archive"`
+	// SenderMap maps archived channel identity and user IDs to IDs on this
+	// instance. An empty value drops the sender, as do unmapped senders
+	// without messages in this bot.
+	SenderMap map[string]string `json:"sender_map,omitempty"`
+	// RouteMap maps archived route IDs to routes of this bot.
+	RouteMap map[string]string `json:"route_map,omitempty"`
+}
+
+// SetArchiveService enables history export and import.
+func (h *MessageHandler) SetArchiveService(svc *archive.Service) {
+	h.archiveService = svc
+}
+
+// ExportMessages godoc
+// @Summary Export bot history
+// @Description Download the active history of a bot, or of one route, as a JSON archive or a Markdown transcript. JSON archives can bundle attachment bytes and be imported again.
+// @Tags messages
+// @Produce json
+// @Produce text/markdown
+// @Param bot_id path string true "Bot ID"
+// @Param format query string false "json (default) or markdown"
+// @Param route_id query string false "Only export this route"
+// @Param include_media query bool false "Bundle attachment bytes (JSON only)"
+// @Success 200 {object} archive.Archive
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/export [get]
+func (h *MessageHandler) ExportMessages(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	format := strings.ToLower(strings.TrimSpace(c.QueryParam("format")))
+	switch format {
+	case "":
+		format = "json"
+	case "json", "markdown", "md":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be json or markdown")
+	}
+	includeMedia := false
+	if raw := strings.TrimSpace(c.QueryParam("include_media")); raw != "" {
+		includeMedia, err = strconv.ParseBool(raw)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid include_media")
+		}
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotAccess(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	if err := h.requireReadable(ctx, botID, channelIdentityID); err != nil {
+		return err
+	}
+	if h.archiveService == nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "history export not configured")
+	}
+	exported, err := h.archiveService.Export(ctx, archive.ExportOptions{
+		BotID:        botID,
+		RouteID:      strings.TrimSpace(c.QueryParam("route_id")),
+		IncludeMedia: includeMedia && format == "json",
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	ext := "json"
+	if format != "json" {
+		ext = "md"
+	}
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, archive.Filename(botID, ext, exported.ExportedAt)))
+	if format == "json" {
+		return c.JSON(http.StatusOK, exported)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "text/markdown; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+	return archive.WriteMarkdown(c.Response(), exported)
+}
+
+// ImportMessages godoc
+// @Summary Import bot history
+// @Description Recreate the messages of a JSON archive in this bot with their original timestamps. Bundled media is stored first. Senders and routes can be remapped to IDs on this instance; unmapped ones are kept only when this bot already knows them.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Param payload body ImportMessagesRequest true "Archive and ID mappings"
+// @Success 200 {object} archive.ImportResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/import [post]
+func (h *MessageHandler) ImportMessages(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	var req ImportMessagesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if req.Archive.Version == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "archive is required")
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotManage(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	if h.archiveService == nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "history import not configured")
+	}
+	result, err := h.archiveService.Import(ctx, req.Archive, archive.ImportOptions{
+		BotID:     botID,
+		SenderMap: req.SenderMap,
+		RouteMap:  req.RouteMap,
+	})
+	if err != nil {
+		if errors.Is(err, archive.ErrUnsupportedVersion) || errors.Is(err, archive.ErrForeignRoute) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/auth"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/flow"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+// branchGatewayTokenTTL bounds the JWT minted for gateway callbacks of a
+// regenerated turn.
+const branchGatewayTokenTTL = 5 * time.Minute
+
+// messageBranchRunner is the part of flow.Resolver that re-runs turns.
+type messageBranchRunner interface {
+	Regenerate(ctx context.Context, req conversation.ChatRequest) (conversation.ChatResponse, error)
+	EditAndResend(ctx context.Context, req conversation.ChatRequest, messageID, text string) (conversation.ChatResponse, error)
+}
+
+// EditMessageRequest is the body of EditMessage.
+type EditMessageRequest struct {
+	Text string `json:"text"`
+}
+
+// SetBranchRunner enables regenerate and edit-and-resend.
+func (h *MessageHandler) SetBranchRunner(runner *flow.Resolver, jwtSecret string) {
+	if runner == nil {
+		return
+	}
+	h.branchRunner = runner
+	h.jwtSecret = jwtSecret
+}
+
+// RegenerateMessage godoc
+// @Summary Regenerate the last reply
+// @Description Answer the latest user message of a route again. The previous reply is kept as an alternative branch.
+// @Tags messages
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Param route_id query string false "Route whose last turn is regenerated; the bot's routeless history when empty"
+// @Success 200 {object} conversation.ChatResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/regenerate [post]
+func (h *MessageHandler) RegenerateMessage(c echo.Context) error {
+	routeID := strings.TrimSpace(c.QueryParam("route_id"))
+	if routeID != "" {
+		if _, err := uuid.Parse(routeID); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid route id")
+		}
+	}
+	return h.rerun(c, "", routeID, func(ctx context.Context, runner messageBranchRunner, req conversation.ChatRequest) (conversation.ChatResponse, error) {
+		return runner.Regenerate(ctx, req)
+	})
+}
+
+// EditMessage godoc
+// @Summary Edit a user message and resend
+// @Description Replace a user message on the active branch and run the conversation again from it. The original message and its replies are kept as an alternative branch.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Param message_id path string true "Message ID"
+// @Param payload body EditMessageRequest true "New text"
+// @Success 200 {object} conversation.ChatResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/{message_id}/edit [post]
+func (h *MessageHandler) EditMessage(c echo.Context) error {
+	var body EditMessageRequest
+	if err := c.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	text := strings.TrimSpace(body.Text)
+	if text == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "text is required")
+	}
+	messageID := strings.TrimSpace(c.Param("message_id"))
+	if _, err := uuid.Parse(messageID); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid message id")
+	}
+	return h.rerun(c, messageID, "", func(ctx context.Context, runner messageBranchRunner, req conversation.ChatRequest) (conversation.ChatResponse, error) {
+		return runner.EditAndResend(ctx, req, messageID, text)
+	})
+}
+
+// ListMessageAlternatives godoc
+// @Summary List alternatives of a message
+// @Description List the message and the regenerated or edited versions that share its parent, oldest first. The active one has superseded unset.
+// @Tags messages
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Param message_id path string true "Message ID"
+// @Success 200 {object} map[string][]messagepkg.Message
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/{message_id}/alternatives [get]
+func (h *MessageHandler) ListMessageAlternatives(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID, messageID, err := branchParams(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotAccess(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	if err := h.requireReadable(ctx, botID, channelIdentityID); err != nil {
+		return err
+	}
+	brancher, ok := h.messageService.(messagepkg.Brancher)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "message branches not configured")
+	}
+	messages, err := brancher.Alternatives(ctx, botID, messageID)
+	if err != nil {
+		return branchError(err)
+	}
+	h.fillAssetMimeFromStorage(ctx, botID, messages)
+	return c.JSON(http.StatusOK, map[string]any{"items": messages})
+}
+
+// ActivateMessage godoc
+// @Summary Switch to a message's branch
+// @Description Make the branch through the message the active history. The branch it replaces is kept as an alternative.
+// @Tags messages
+// @Param bot_id path string true "Bot ID"
+// @Param message_id path string true "Message ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/{message_id}/activate [post]
+func (h *MessageHandler) ActivateMessage(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID, messageID, err := branchParams(c)
+	if err != nil {
+		return err
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotManage(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	brancher, ok := h.messageService.(messagepkg.Brancher)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "message branches not configured")
+	}
+	if err := brancher.Activate(ctx, botID, messageID); err != nil {
+		return branchError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// rerun authorizes a history rewrite and runs it with a gateway token for
+// the caller. Rewriting the shared history requires manage access.
+func (h *MessageHandler) rerun(c echo.Context, messageID, routeID string, run func(ctx context.Context, runner messageBranchRunner, req conversation.ChatRequest) (conversation.ChatResponse, error)) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotManage(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	if h.branchRunner == nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "chat runner not configured")
+	}
+	token, _, err := auth.GenerateToken(channelIdentityID, h.jwtSecret, branchGatewayTokenTTL)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	resp, err := run(ctx, h.branchRunner, conversation.ChatRequest{
+		BotID:   botID,
+		ChatID:  botID,
+		RouteID: routeID,
+		Token:   "Bearer " + token,
+		UserID:  channelIdentityID,
+	})
+	if err != nil {
+		h.logger.Error("rerun failed",
+			slog.String("bot_id", botID),
+			slog.String("message_id", messageID),
+			slog.Any("error", err),
+		)
+		return branchError(err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+func branchParams(c echo.Context) (string, string, error) {
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	messageID := strings.TrimSpace(c.Param("message_id"))
+	if _, err := uuid.Parse(messageID); err != nil {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid message id")
+	}
+	return botID, messageID, nil
+}
+
+func branchError(err error) error {
+	switch {
+	case errors.Is(err, messagepkg.ErrMessageNotFound), errors.Is(err, messagepkg.ErrNothingToRegenerate):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, messagepkg.ErrNotUserMessage), errors.Is(err, flow.ErrBlocked):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
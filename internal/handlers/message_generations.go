@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/conversation/flow"
+)
+
+// generationCanceller is the part of flow.Resolver that stops streaming
+// replies.
+type generationCanceller interface {
+	ActiveGenerations(botID string) []flow.Generation
+	CancelGenerations(botID, routeID, requestID string) []flow.Generation
+}
+
+// CancelGenerationRequest is the body of CancelGenerations. Both fields are
+// optional; without them every reply of the bot is stopped.
+type CancelGenerationRequest struct {
+	RouteID   string `json:"route_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// SetGenerationCanceller enables listing and stopping streaming replies.
+func (h *MessageHandler) SetGenerationCanceller(canceller *flow.Resolver) {
+	if canceller == nil {
+		return
+	}
+	h.generations = canceller
+}
+
+// ListGenerations godoc
+// @Summary List replies in progress
+// @Description List the streaming replies of a bot that are being generated.
+// @Tags messages
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} map[string][]flow.Generation
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/generations [get]
+func (h *MessageHandler) ListGenerations(c echo.Context) error {
+	botID, err := h.generationAccess(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": h.generations.ActiveGenerations(botID)})
+}
+
+// CancelGenerations godoc
+// @Summary Stop replies in progress
+// @Description Stop streaming replies of a bot, optionally only those of one route or one request. Text streamed so far is stored, marked as cancelled, and sent as the final reply.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Param payload body CancelGenerationRequest false "Route or request to stop"
+// @Success 200 {object} map[string][]flow.Generation
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/generations/cancel [post]
+func (h *MessageHandler) CancelGenerations(c echo.Context) error {
+	botID, err := h.generationAccess(c)
+	if err != nil {
+		return err
+	}
+	var req CancelGenerationRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	stopped := h.generations.CancelGenerations(botID, strings.TrimSpace(req.RouteID), strings.TrimSpace(req.RequestID))
+	return c.JSON(http.StatusOK, map[string]any{"cancelled": stopped})
+}
+
+// generationAccess checks that the caller may use the bot and returns its ID.
+func (h *MessageHandler) generationAccess(c echo.Context) (string, error) {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return "", err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotAccess(ctx, channelIdentityID, botID); err != nil {
+		return "", err
+	}
+	if err := h.requireReadable(ctx, botID, channelIdentityID); err != nil {
+		return "", err
+	}
+	if h.generations == nil {
+		return "", echo.NewHTTPError(http.StatusInternalServerError, "generation cancelling not configured")
+	}
+	return botID, nil
+}
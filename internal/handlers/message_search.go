@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+// SearchMessages godoc
+// @Summary Search bot history
+// @Description Full-text search over the active history of a bot, best match first. Substring matching covers languages without word breaks.
+// @Tags messages
+// @Produce json
+// @Param bot_id path string true "Bot ID"
+// @Param q query string true "Search text"
+// @Param platform query string false "Channel platform, e.g. telegram"
+// @Param sender query string false "Channel identity ID, user ID or part of a display name"
+// @Param role query string false "user, assistant, system or tool"
+// @Param start_time query string false "RFC3339 lower bound"
+// @Param end_time query string false "RFC3339 upper bound"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Success 200 {object} map[string][]messagepkg.SearchResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/messages/search [get]
+func (h *MessageHandler) SearchMessages(c echo.Context) error {
+	channelIdentityID, err := h.requireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	query := messagepkg.SearchQuery{
+		Query:    strings.TrimSpace(c.QueryParam("q")),
+		Platform: strings.TrimSpace(c.QueryParam("platform")),
+		Sender:   strings.TrimSpace(c.QueryParam("sender")),
+		Role:     strings.TrimSpace(c.QueryParam("role")),
+	}
+	if query.Query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is required")
+	}
+	if s := strings.TrimSpace(c.QueryParam("limit")); s != "" {
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		query.Limit = int32(n)
+	}
+	if query.StartTime, err = parseTimeParam(c.QueryParam("start_time")); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid start_time")
+	}
+	if query.EndTime, err = parseTimeParam(c.QueryParam("end_time")); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid end_time")
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.authorizeBotAccess(ctx, channelIdentityID, botID); err != nil {
+		return err
+	}
+	if err := h.requireReadable(ctx, botID, channelIdentityID); err != nil {
+		return err
+	}
+	searcher, ok := h.messageService.(messagepkg.Searcher)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "history search not configured")
+	}
+	results, err := searcher.Search(ctx, botID, query)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": results})
+}
+
+func parseTimeParam(raw string) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
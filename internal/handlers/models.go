@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/labstack/echo/v4"
@@ -35,6 +36,15 @@ func (h *ModelsHandler) Register(e *echo.Echo) {
 	group.DELETE("/:id", h.DeleteByID)
 	group.DELETE("/model/:modelId", h.DeleteByModelID)
 	group.GET("/count", h.Count)
+	group.GET("/:id/variants", h.ListVariants)
+	group.POST("/:id/variants", h.CreateVariant)
+	group.PUT("/:id/variants/:variantId", h.UpdateVariant)
+	group.DELETE("/:id/variants/:variantId", h.DeleteVariant)
+	group.GET("/:id/variants/report", h.VariantReport)
+	group.GET("/prices", h.ListPrices)
+	group.GET("/:id/price", h.GetPrice)
+	group.PUT("/:id/price", h.SetPrice)
+	group.DELETE("/:id/price", h.DeletePrice)
 }
 
 // Create godoc
@@ -306,3 +316,241 @@ func (h *ModelsHandler) Count(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, models.CountResponse{Count: count})
 }
+
+// defaultVariantReportWindow is the report range when the request gives no start.
+const defaultVariantReportWindow = 7 * 24 * time.Hour
+
+// ListVariants godoc
+// @Summary List model variants
+// @Description List the weighted A/B variants of a chat model
+// @Tags models
+// @Param id path string true "Model internal ID (UUID)"
+// @Success 200 {array} models.Variant
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /models/{id}/variants [get]
+func (h *ModelsHandler) ListVariants(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required")
+	}
+	resp, err := h.service.ListVariants(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// CreateVariant godoc
+// @Summary Create a model variant
+// @Description Route a weighted share of a chat model's conversations to another model. Point a variant at the model itself to keep a control group.
+// @Tags models
+// @Param id path string true "Model internal ID (UUID)"
+// @Param payload body models.VariantRequest true "Variant configuration"
+// @Success 201 {object} models.Variant
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /models/{id}/variants [post]
+func (h *ModelsHandler) CreateVariant(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required")
+	}
+	var req models.VariantRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	resp, err := h.service.CreateVariant(c.Request().Context(), id, req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// UpdateVariant godoc
+// @Summary Update a model variant
+// @Description Change the target model, weight or metadata of a variant. Changing weights reshuffles conversation assignments.
+// @Tags models
+// @Param id path string true "Model internal ID (UUID)"
+// @Param variantId path string true "Variant ID (UUID)"
+// @Param payload body models.VariantRequest true "Variant configuration"
+// @Success 200 {object} models.Variant
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /models/{id}/variants/{variantId} [put]
+func (h *ModelsHandler) UpdateVariant(c echo.Context) error {
+	id := c.Param("id")
+	variantID := c.Param("variantId")
+	if id == "" || variantID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "id and variantId are required")
+	}
+	var req models.VariantRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	resp, err := h.service.UpdateVariant(c.Request().Context(), id, variantID, req)
+	if err != nil {
+		if errors.Is(err, models.ErrVariantNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeleteVariant godoc
+// @Summary Delete a model variant
+// @Description Stop routing traffic to a variant. Replies it already produced stay in the report.
+// @Tags models
+// @Param id path string true "Model internal ID (UUID)"
+// @Param variantId path string true "Variant ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /models/{id}/variants/{variantId} [delete]
+func (h *ModelsHandler) DeleteVariant(c echo.Context) error {
+	id := c.Param("id")
+	variantID := c.Param("variantId")
+	if id == "" || variantID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "id and variantId are required")
+	}
+	if err := h.service.DeleteVariant(c.Request().Context(), id, variantID); err != nil {
+		if errors.Is(err, models.ErrVariantNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// VariantReport godoc
+// @Summary Compare model variants
+// @Description Compare the variants of a chat model by reply count, latency, token usage and user reactions. Defaults to the last 7 days.
+// @Tags models
+// @Param id path string true "Model internal ID (UUID)"
+// @Param from query string false "Range start (RFC3339 or unix milliseconds)"
+// @Param to query string false "Range end (RFC3339 or unix milliseconds)"
+// @Success 200 {object} models.VariantReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /models/{id}/variants/report [get]
+func (h *ModelsHandler) VariantReport(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required")
+	}
+	to, hasTo, err := parseSinceParam(c.QueryParam("to"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid to parameter")
+	}
+	if !hasTo {
+		to = time.Now().UTC()
+	}
+	from, hasFrom, err := parseSinceParam(c.QueryParam("from"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid from parameter")
+	}
+	if !hasFrom {
+		from = to.Add(-defaultVariantReportWindow)
+	}
+	if !to.After(from) {
+		return echo.NewHTTPError(http.StatusBadRequest, "to must be after from")
+	}
+	resp, err := h.service.VariantReport(c.Request().Context(), id, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ListPrices godoc
+// @Summary List model prices
+// @Description List the token prices of all priced models
+// @Tags models
+// @Success 200 {array} models.Price
+// @Failure 500 {object} ErrorResponse
+// @Router /models/prices [get]
+func (h *ModelsHandler) ListPrices(c echo.Context) error {
+	resp, err := h.service.ListPrices(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetPrice godoc
+// @Summary Get a model price
+// @Description Get the token prices of a model
+// @Tags models
+// @Param id path string true "Model internal ID (UUID)"
+// @Success 200 {object} models.Price
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /models/{id}/price [get]
+func (h *ModelsHandler) GetPrice(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required")
+	}
+	resp, err := h.service.GetPrice(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, models.ErrPriceNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// SetPrice godoc
+// @Summary Set a model price
+// @Description Set the per-million-token prices of a model used in cost reports. Cached input and reasoning prices default to the input and output price.
+// @Tags models
+// @Param id path string true "Model internal ID (UUID)"
+// @Param payload body models.PriceRequest true "Token prices"
+// @Success 200 {object} models.Price
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /models/{id}/price [put]
+func (h *ModelsHandler) SetPrice(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required")
+	}
+	var req models.PriceRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	resp, err := h.service.SetPrice(c.Request().Context(), id, req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// DeletePrice godoc
+// @Summary Delete a model price
+// @Description Remove the prices of a model; its usage is reported as unpriced
+// @Tags models
+// @Param id path string true "Model internal ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /models/{id}/price [delete]
+func (h *ModelsHandler) DeletePrice(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "id is required")
+	}
+	if err := h.service.DeletePrice(c.Request().Context(), id); err != nil {
+		if errors.Is(err, models.ErrPriceNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.NoContent(http.StatusNoContent)
+}
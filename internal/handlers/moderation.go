@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/moderation"
+)
+
+type ModerationHandler struct {
+	service        *moderation.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	logger         *slog.Logger
+}
+
+func NewModerationHandler(log *slog.Logger, service *moderation.Service, botService *bots.Service, accountService *accounts.Service) *ModerationHandler {
+	return &ModerationHandler{
+		service:        service,
+		botService:     botService,
+		accountService: accountService,
+		logger:         log.With(slog.String("handler", "moderation")),
+	}
+}
+
+func (h *ModerationHandler) Register(e *echo.Echo) {
+	group := e.Group("/bots/:bot_id/moderation")
+	group.GET("/policy", h.GetPolicy)
+	group.PUT("/policy", h.UpdatePolicy)
+}
+
+// GetPolicy godoc
+// @Summary Get content moderation policy
+// @Description Get the rules that screen the input and output of a bot. The endpoint API key is never returned; has_api_key tells whether one is set.
+// @Tags moderation
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} moderation.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/moderation/policy [get]
+func (h *ModerationHandler) GetPolicy(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	policy, err := h.service.GetPolicy(c.Request().Context(), botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update content moderation policy
+// @Description Replace the keyword, regex and endpoint rules of a bot, with their action (block, redact or warn) and owner notices. An endpoint without api_key keeps the stored key.
+// @Tags moderation
+// @Param bot_id path string true "Bot ID"
+// @Param payload body moderation.Policy true "Moderation policy"
+// @Success 200 {object} moderation.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/moderation/policy [put]
+func (h *ModerationHandler) UpdatePolicy(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	var req moderation.Policy
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	policy, err := h.service.UpdatePolicy(c.Request().Context(), botID, req)
+	if err != nil {
+		if errors.Is(err, moderation.ErrInvalidPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+func (h *ModerationHandler) requireBot(c echo.Context) (string, error) {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return "", err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	if _, err := h.authorizeBotAccess(c.Request().Context(), channelIdentityID, botID); err != nil {
+		return "", err
+	}
+	return botID, nil
+}
+
+func (h *ModerationHandler) authorizeBotAccess(ctx context.Context, channelIdentityID, botID string) (bots.Bot, error) {
+	return AuthorizeBotAccess(ctx, h.botService, h.accountService, channelIdentityID, botID, bots.AccessPolicy{AllowPublicMember: false})
+}
@@ -0,0 +1,619 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/apitokens"
+	"github.com/memohai/memoh/internal/auth"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/flow"
+	"github.com/memohai/memoh/internal/quota"
+)
+
+// OpenAIChannel is the platform recorded on messages sent through the
+// OpenAI-compatible API.
+const OpenAIChannel = "openai"
+
+// openAIGatewayTokenTTL bounds the JWT minted for agent gateway callbacks.
+const openAIGatewayTokenTTL = 5 * time.Minute
+
+// openAIChatRunner is the subset of flow.Resolver used by the OpenAI API.
+type openAIChatRunner interface {
+	Chat(ctx context.Context, req conversation.ChatRequest) (conversation.ChatResponse, error)
+	StreamChat(ctx context.Context, req conversation.ChatRequest) (<-chan conversation.StreamChunk, <-chan error)
+}
+
+// OpenAIHandler exposes bots through the OpenAI chat completions API. The
+// "model" of a request is a bot ID and callers authenticate with an API token.
+type OpenAIHandler struct {
+	runner         openAIChatRunner
+	tokenService   *apitokens.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	jwtSecret      string
+	logger         *slog.Logger
+}
+
+func NewOpenAIHandler(log *slog.Logger, runner *flow.Resolver, tokenService *apitokens.Service, botService *bots.Service, accountService *accounts.Service, jwtSecret string) *OpenAIHandler {
+	h := &OpenAIHandler{
+		tokenService:   tokenService,
+		botService:     botService,
+		accountService: accountService,
+		jwtSecret:      jwtSecret,
+		logger:         log.With(slog.String("handler", "openai")),
+	}
+	if runner != nil {
+		h.runner = runner
+	}
+	return h
+}
+
+func (h *OpenAIHandler) Register(e *echo.Echo) {
+	group := e.Group("/v1", openAIErrors)
+	group.GET("/models", h.ListModels)
+	group.POST("/chat/completions", h.ChatCompletions)
+}
+
+// OpenAIModel is one entry of the /v1/models list.
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+	Name    string `json:"name,omitempty"`
+}
+
+// OpenAIModelList is the /v1/models response.
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+}
+
+// OpenAIChatRequest is the subset of the chat completions request Memoh
+// understands. Only the last user message is forwarded; the bot keeps its own
+// history, memory and system prompt.
+type OpenAIChatRequest struct {
+	Model         string               `json:"model"`
+	Messages      []OpenAIChatMessage  `json:"messages"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *OpenAIStreamOptions `json:"stream_options,omitempty"`
+	User          string               `json:"user,omitempty"`
+}
+
+// OpenAIStreamOptions controls optional stream behaviour.
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenAIChatMessage is a chat message whose content is either a string or an
+// array of content parts.
+type OpenAIChatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content,omitempty"`
+	Name    string          `json:"name,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+// OpenAIChatCompletion is the non-streaming chat completions response.
+type OpenAIChatCompletion struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []OpenAIChatChoice `json:"choices"`
+	Usage   *OpenAIUsage       `json:"usage,omitempty"`
+}
+
+// OpenAIChatChoice is one choice of a chat completion.
+type OpenAIChatChoice struct {
+	Index        int                   `json:"index"`
+	Message      OpenAIResponseMessage `json:"message"`
+	FinishReason string                `json:"finish_reason"`
+}
+
+// OpenAIResponseMessage is the assistant message of a completion.
+type OpenAIResponseMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// OpenAIChatChunk is one SSE chunk of a streamed completion.
+type OpenAIChatChunk struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"`
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Choices []OpenAIChunkChoice `json:"choices"`
+	Usage   *OpenAIUsage        `json:"usage,omitempty"`
+}
+
+// OpenAIChunkChoice is one choice of a streamed chunk.
+type OpenAIChunkChoice struct {
+	Index        int              `json:"index"`
+	Delta        OpenAIChunkDelta `json:"delta"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+// OpenAIChunkDelta carries the incremental assistant output.
+type OpenAIChunkDelta struct {
+	Role             string `json:"role,omitempty"`
+	Content          string `json:"content,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// OpenAIUsage is the token usage in OpenAI format.
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// OpenAIErrorResponse is the OpenAI error envelope.
+type OpenAIErrorResponse struct {
+	Error OpenAIError `json:"error"`
+}
+
+// OpenAIError describes a failed request.
+type OpenAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code,omitempty"`
+}
+
+// ListModels godoc
+// @Summary List bots as OpenAI models
+// @Description List the bots accessible with the API token. Each model ID is a bot ID.
+// @Tags openai
+// @Success 200 {object} OpenAIModelList
+// @Failure 401 {object} OpenAIErrorResponse
+// @Failure 500 {object} OpenAIErrorResponse
+// @Router /v1/models [get]
+func (h *OpenAIHandler) ListModels(c echo.Context) error {
+	userID, err := h.authenticate(c)
+	if err != nil {
+		return err
+	}
+	items, err := h.botService.ListAccessible(c.Request().Context(), userID)
+	if err != nil {
+		return newOpenAIError(http.StatusInternalServerError, "server_error", "", err.Error())
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	resp := OpenAIModelList{Object: "list", Data: make([]OpenAIModel, 0, len(items))}
+	for _, bot := range items {
+		if !bot.IsActive {
+			continue
+		}
+		resp.Data = append(resp.Data, OpenAIModel{
+			ID:      bot.ID,
+			Object:  "model",
+			Created: bot.CreatedAt.Unix(),
+			OwnedBy: "memoh",
+			Name:    bot.DisplayName,
+		})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ChatCompletions godoc
+// @Summary Chat with a bot through the OpenAI API
+// @Description Send the last user message to the bot named by model. Set stream to receive chat.completion.chunk SSE events terminated by [DONE].
+// @Tags openai
+// @Param payload body OpenAIChatRequest true "Chat completion request"
+// @Success 200 {object} OpenAIChatCompletion
+// @Failure 400 {object} OpenAIErrorResponse
+// @Failure 401 {object} OpenAIErrorResponse
+// @Failure 404 {object} OpenAIErrorResponse
+// @Failure 500 {object} OpenAIErrorResponse
+// @Router /v1/chat/completions [post]
+func (h *OpenAIHandler) ChatCompletions(c echo.Context) error {
+	userID, err := h.authenticate(c)
+	if err != nil {
+		return err
+	}
+	if h.runner == nil {
+		return newOpenAIError(http.StatusInternalServerError, "server_error", "", "chat runner not configured")
+	}
+	var req OpenAIChatRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return newOpenAIError(http.StatusBadRequest, "invalid_request_error", "", "invalid JSON body: "+err.Error())
+	}
+	botID := strings.TrimSpace(req.Model)
+	if botID == "" {
+		return newOpenAIError(http.StatusBadRequest, "invalid_request_error", "model_not_found", "model is required and must be a bot ID")
+	}
+	if _, err := AuthorizeBotAccess(c.Request().Context(), h.botService, h.accountService, userID, botID, bots.AccessPolicy{AllowPublicMember: true}); err != nil {
+		return err
+	}
+	query, attachments, err := openAIUserInput(req.Messages)
+	if err != nil {
+		return newOpenAIError(http.StatusBadRequest, "invalid_request_error", "", err.Error())
+	}
+	gatewayToken, _, err := auth.GenerateToken(userID, h.jwtSecret, openAIGatewayTokenTTL)
+	if err != nil {
+		return newOpenAIError(http.StatusInternalServerError, "server_error", "", err.Error())
+	}
+	chatReq := conversation.ChatRequest{
+		BotID:                   botID,
+		ChatID:                  botID,
+		Token:                   "Bearer " + gatewayToken,
+		UserID:                  userID,
+		SourceChannelIdentityID: userID,
+		Query:                   query,
+		CurrentChannel:          OpenAIChannel,
+		Channels:                []string{OpenAIChannel},
+		Attachments:             attachments,
+	}
+	completionID := "chatcmpl-" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	created := time.Now().Unix()
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		return h.streamCompletion(c, chatReq, completionID, created, includeUsage)
+	}
+
+	resp, err := h.runner.Chat(c.Request().Context(), chatReq)
+	if errors.Is(err, quota.ErrExceeded) {
+		return newOpenAIError(http.StatusTooManyRequests, "insufficient_quota", "insufficient_quota", err.Error())
+	}
+	if errors.Is(err, flow.ErrBlocked) {
+		return newOpenAIError(http.StatusBadRequest, "invalid_request_error", "content_policy_violation", err.Error())
+	}
+	if err != nil {
+		h.logger.Error("openai chat failed", slog.String("bot_id", botID), slog.Any("error", err))
+		return newOpenAIError(http.StatusBadGateway, "server_error", "", err.Error())
+	}
+	completion := OpenAIChatCompletion{
+		ID:      completionID,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   botID,
+		Choices: []OpenAIChatChoice{{
+			Index:        0,
+			Message:      OpenAIResponseMessage{Role: "assistant", Content: openAIAssistantText(resp.Messages)},
+			FinishReason: "stop",
+		}},
+		Usage: toOpenAIUsage(resp.Usage),
+	}
+	return c.JSON(http.StatusOK, completion)
+}
+
+func (h *OpenAIHandler) streamCompletion(c echo.Context, req conversation.ChatRequest, completionID string, created int64, includeUsage bool) error {
+	ctx := c.Request().Context()
+	chunkCh, errCh := h.runner.StreamChat(ctx, req)
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/event-stream")
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	c.Response().Header().Set(echo.HeaderConnection, "keep-alive")
+	c.Response().WriteHeader(http.StatusOK)
+	flusher, ok := c.Response().Writer.(http.Flusher)
+	if !ok {
+		return echo.NewHTTPError(http.StatusInternalServerError, "streaming not supported")
+	}
+	writer := bufio.NewWriter(c.Response().Writer)
+
+	stream := newOpenAIStream(completionID, req.BotID, created)
+	if err := writeSSEJSON(writer, flusher, stream.roleChunk()); err != nil {
+		return nil
+	}
+	var streamErr error
+	for chunkCh != nil || errCh != nil {
+		select {
+		case chunk, ok := <-chunkCh:
+			if !ok {
+				chunkCh = nil
+				continue
+			}
+			out, err := stream.translate(chunk)
+			if err != nil {
+				h.logger.Warn("openai stream chunk parse failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+				continue
+			}
+			if out == nil {
+				continue
+			}
+			if err := writeSSEJSON(writer, flusher, out); err != nil {
+				// Client went away; the resolver stops with the request context.
+				return nil
+			}
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			if err != nil {
+				streamErr = err
+			}
+		}
+	}
+	if streamErr == nil && stream.failure != "" {
+		streamErr = errors.New(stream.failure)
+	}
+	if streamErr != nil {
+		h.logger.Error("openai stream failed", slog.String("bot_id", req.BotID), slog.Any("error", streamErr))
+		errType := "server_error"
+		switch {
+		case errors.Is(streamErr, quota.ErrExceeded):
+			errType = "insufficient_quota"
+		case errors.Is(streamErr, flow.ErrBlocked):
+			errType = "invalid_request_error"
+		}
+		_ = writeSSEJSON(writer, flusher, OpenAIErrorResponse{Error: OpenAIError{Message: streamErr.Error(), Type: errType}})
+		return nil
+	}
+	if err := writeSSEJSON(writer, flusher, stream.finishChunk()); err != nil {
+		return nil
+	}
+	if includeUsage {
+		if err := writeSSEJSON(writer, flusher, stream.usageChunk()); err != nil {
+			return nil
+		}
+	}
+	_ = writeSSEData(writer, flusher, "[DONE]")
+	return nil
+}
+
+// authenticate resolves the API token in the Authorization header to a user.
+func (h *OpenAIHandler) authenticate(c echo.Context) (string, error) {
+	header := strings.TrimSpace(c.Request().Header.Get(echo.HeaderAuthorization))
+	token := ""
+	if len(header) > len("Bearer ") && strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		token = strings.TrimSpace(header[len("Bearer "):])
+	}
+	if token == "" {
+		return "", newOpenAIError(http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "missing API token")
+	}
+	if h.tokenService == nil {
+		return "", newOpenAIError(http.StatusInternalServerError, "server_error", "", "api token service not configured")
+	}
+	record, err := h.tokenService.Authenticate(c.Request().Context(), token)
+	if err != nil {
+		if errors.Is(err, apitokens.ErrInvalidToken) {
+			return "", newOpenAIError(http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "invalid API token")
+		}
+		return "", newOpenAIError(http.StatusInternalServerError, "server_error", "", err.Error())
+	}
+	return record.UserID, nil
+}
+
+// openAIAPIError is an error rendered in the OpenAI error format.
+type openAIAPIError struct {
+	status int
+	body   OpenAIError
+}
+
+func newOpenAIError(status int, errType, code, message string) *openAIAPIError {
+	return &openAIAPIError{status: status, body: OpenAIError{Message: message, Type: errType, Code: code}}
+}
+
+func (e *openAIAPIError) Error() string {
+	return e.body.Message
+}
+
+// openAIErrors renders handler errors in the OpenAI error envelope so SDK
+// clients surface them instead of failing to decode the response.
+func openAIErrors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		if err == nil || c.Response().Committed {
+			return err
+		}
+		var apiErr *openAIAPIError
+		if errors.As(err, &apiErr) {
+			return c.JSON(apiErr.status, OpenAIErrorResponse{Error: apiErr.body})
+		}
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			errType, code := "invalid_request_error", ""
+			switch {
+			case httpErr.Code == http.StatusNotFound:
+				code = "model_not_found"
+			case httpErr.Code >= http.StatusInternalServerError:
+				errType = "server_error"
+			}
+			return c.JSON(httpErr.Code, OpenAIErrorResponse{Error: OpenAIError{Message: fmt.Sprint(httpErr.Message), Type: errType, Code: code}})
+		}
+		return err
+	}
+}
+
+// openAIUserInput extracts the query and image attachments from the last user
+// message.
+func openAIUserInput(messages []OpenAIChatMessage) (string, []conversation.ChatAttachment, error) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if !strings.EqualFold(strings.TrimSpace(msg.Role), "user") {
+			continue
+		}
+		text, attachments, err := parseOpenAIContent(msg.Content)
+		if err != nil {
+			return "", nil, err
+		}
+		if strings.TrimSpace(text) == "" && len(attachments) == 0 {
+			return "", nil, fmt.Errorf("last user message is empty")
+		}
+		return text, attachments, nil
+	}
+	return "", nil, fmt.Errorf("messages must contain a user message")
+}
+
+func parseOpenAIContent(raw json.RawMessage) (string, []conversation.ChatAttachment, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil, nil
+	}
+	var parts []openAIContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", nil, fmt.Errorf("content must be a string or an array of content parts")
+	}
+	texts := make([]string, 0, len(parts))
+	var attachments []conversation.ChatAttachment
+	for _, part := range parts {
+		switch strings.ToLower(strings.TrimSpace(part.Type)) {
+		case "text":
+			if strings.TrimSpace(part.Text) != "" {
+				texts = append(texts, part.Text)
+			}
+		case "image_url":
+			if part.ImageURL == nil || strings.TrimSpace(part.ImageURL.URL) == "" {
+				return "", nil, fmt.Errorf("image_url part requires a url")
+			}
+			attachments = append(attachments, conversation.ChatAttachment{
+				Type: "image",
+				URL:  strings.TrimSpace(part.ImageURL.URL),
+			})
+		default:
+			return "", nil, fmt.Errorf("unsupported content part type %q", part.Type)
+		}
+	}
+	return strings.Join(texts, "\n"), attachments, nil
+}
+
+func openAIAssistantText(messages []conversation.ModelMessage) string {
+	outputs := flow.ExtractAssistantOutputs(messages)
+	texts := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		if strings.TrimSpace(output.Content) != "" {
+			texts = append(texts, output.Content)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+func toOpenAIUsage(usage *conversation.Usage) *OpenAIUsage {
+	if usage == nil {
+		return nil
+	}
+	return &OpenAIUsage{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      usage.TotalTokens(),
+	}
+}
+
+// openAIStream translates agent gateway stream chunks into chat.completion.chunk
+// payloads and collects the round usage.
+type openAIStream struct {
+	id      string
+	model   string
+	created int64
+	usage   *conversation.Usage
+	failure string
+}
+
+func newOpenAIStream(id, model string, created int64) *openAIStream {
+	return &openAIStream{id: id, model: model, created: created}
+}
+
+type openAIGatewayEnvelope struct {
+	Type    string            `json:"type"`
+	Delta   string            `json:"delta"`
+	Error   string            `json:"error"`
+	Message string            `json:"message"`
+	Usage   json.RawMessage   `json:"usage,omitempty"`
+	Usages  []json.RawMessage `json:"usages,omitempty"`
+	Data    json.RawMessage   `json:"data,omitempty"`
+}
+
+func (s *openAIStream) translate(chunk conversation.StreamChunk) (*OpenAIChatChunk, error) {
+	if len(chunk) == 0 {
+		return nil, nil
+	}
+	var envelope openAIGatewayEnvelope
+	if err := json.Unmarshal(chunk, &envelope); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(strings.TrimSpace(envelope.Type)) {
+	case "text_delta":
+		if envelope.Delta == "" {
+			return nil, nil
+		}
+		return s.deltaChunk(OpenAIChunkDelta{Content: envelope.Delta}), nil
+	case "reasoning_delta":
+		if envelope.Delta == "" {
+			return nil, nil
+		}
+		return s.deltaChunk(OpenAIChunkDelta{ReasoningContent: envelope.Delta}), nil
+	case "agent_end", "done":
+		if usage, ok := conversation.ParseUsage(envelope.Usage, envelope.Usages); ok {
+			s.usage = &usage
+		} else if len(envelope.Data) > 0 {
+			var data struct {
+				Usage  json.RawMessage   `json:"usage,omitempty"`
+				Usages []json.RawMessage `json:"usages,omitempty"`
+			}
+			if err := json.Unmarshal(envelope.Data, &data); err == nil {
+				if usage, ok := conversation.ParseUsage(data.Usage, data.Usages); ok {
+					s.usage = &usage
+				}
+			}
+		}
+	case "error":
+		s.failure = strings.TrimSpace(envelope.Error)
+		if s.failure == "" {
+			s.failure = strings.TrimSpace(envelope.Message)
+		}
+		if s.failure == "" {
+			s.failure = "agent gateway stream error"
+		}
+	}
+	return nil, nil
+}
+
+func (s *openAIStream) chunk(choices []OpenAIChunkChoice) *OpenAIChatChunk {
+	return &OpenAIChatChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: s.created,
+		Model:   s.model,
+		Choices: choices,
+	}
+}
+
+func (s *openAIStream) deltaChunk(delta OpenAIChunkDelta) *OpenAIChatChunk {
+	return s.chunk([]OpenAIChunkChoice{{Index: 0, Delta: delta}})
+}
+
+func (s *openAIStream) roleChunk() *OpenAIChatChunk {
+	return s.deltaChunk(OpenAIChunkDelta{Role: "assistant"})
+}
+
+func (s *openAIStream) finishChunk() *OpenAIChatChunk {
+	reason := "stop"
+	return s.chunk([]OpenAIChunkChoice{{Index: 0, FinishReason: &reason}})
+}
+
+// usageChunk follows the include_usage contract: empty choices and the usage
+// of the whole request.
+func (s *openAIStream) usageChunk() *OpenAIChatChunk {
+	out := s.chunk([]OpenAIChunkChoice{})
+	out.Usage = toOpenAIUsage(s.usage)
+	if out.Usage == nil {
+		out.Usage = &OpenAIUsage{}
+	}
+	return out
+}
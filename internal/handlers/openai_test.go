@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/conversation"
+)
+
+type fakeOpenAIRunner struct {
+	chunks []string
+	err    error
+}
+
+func (f *fakeOpenAIRunner) Chat(ctx context.Context, req conversation.ChatRequest) (conversation.ChatResponse, error) {
+	return conversation.ChatResponse{}, f.err
+}
+
+func (f *fakeOpenAIRunner) StreamChat(ctx context.Context, req conversation.ChatRequest) (<-chan conversation.StreamChunk, <-chan error) {
+	chunkCh := make(chan conversation.StreamChunk, len(f.chunks))
+	errCh := make(chan error, 1)
+	for _, chunk := range f.chunks {
+		chunkCh <- conversation.StreamChunk(chunk)
+	}
+	if f.err != nil {
+		errCh <- f.err
+	}
+	close(chunkCh)
+	close(errCh)
+	return chunkCh, errCh
+}
+
+func TestOpenAIUserInput(t *testing.T) {
+	messages := []OpenAIChatMessage{
+		{Role: "system", Content: json.RawMessage(`"be terse"`)},
+		{Role: "user", Content: json.RawMessage(`"first"`)},
+		{Role: "assistant", Content: json.RawMessage(`"ok"`)},
+		{Role: "user", Content: json.RawMessage(`[{"type":"text","text":"describe"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]`)},
+	}
+	query, attachments, err := openAIUserInput(messages)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query != "describe" {
+		t.Fatalf("query = %q", query)
+	}
+	if len(attachments) != 1 || attachments[0].Type != "image" || attachments[0].URL != "https://example.com/a.png" {
+		t.Fatalf("unexpected attachments: %#v", attachments)
+	}
+
+	if _, _, err := openAIUserInput([]OpenAIChatMessage{{Role: "system", Content: json.RawMessage(`"x"`)}}); err == nil {
+		t.Fatal("expected error without user message")
+	}
+	if _, _, err := openAIUserInput([]OpenAIChatMessage{{Role: "user", Content: json.RawMessage(`[{"type":"input_audio"}]`)}}); err == nil {
+		t.Fatal("expected error for unsupported part")
+	}
+}
+
+func TestOpenAIAssistantText(t *testing.T) {
+	messages := []conversation.ModelMessage{
+		{Role: "user", Content: conversation.NewTextContent("hi")},
+		{Role: "assistant", Content: conversation.NewTextContent("Looking it up.")},
+		{Role: "tool", Content: conversation.NewTextContent("result")},
+		{Role: "assistant", Content: conversation.NewTextContent("Done.")},
+	}
+	if got := openAIAssistantText(messages); got != "Looking it up.\n\nDone." {
+		t.Fatalf("unexpected text: %q", got)
+	}
+}
+
+func TestOpenAIStreamCompletion(t *testing.T) {
+	h := &OpenAIHandler{
+		logger: slog.Default(),
+		runner: &fakeOpenAIRunner{chunks: []string{
+			`{"type":"agent_start"}`,
+			`{"type":"reasoning_delta","delta":"thinking"}`,
+			`{"type":"text_delta","delta":"Hel"}`,
+			`{"type":"tool_call_start","toolName":"web_search"}`,
+			`{"type":"text_delta","delta":"lo"}`,
+			`{"type":"agent_end","messages":[],"usage":{"inputTokens":12,"outputTokens":3}}`,
+		}},
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), rec)
+	if err := h.streamCompletion(c, conversation.ChatRequest{BotID: "bot-1"}, "chatcmpl-1", 1700000000, true); err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+
+	var chunks []OpenAIChatChunk
+	var done bool
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk OpenAIChatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("decode chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if !done {
+		t.Fatal("expected [DONE] terminator")
+	}
+	if len(chunks) != 6 {
+		t.Fatalf("expected 6 chunks, got %d: %s", len(chunks), rec.Body.String())
+	}
+	if chunks[0].Choices[0].Delta.Role != "assistant" || chunks[0].Object != "chat.completion.chunk" || chunks[0].Model != "bot-1" {
+		t.Fatalf("unexpected role chunk: %#v", chunks[0])
+	}
+	if chunks[1].Choices[0].Delta.ReasoningContent != "thinking" {
+		t.Fatalf("unexpected reasoning chunk: %#v", chunks[1])
+	}
+	if chunks[2].Choices[0].Delta.Content+chunks[3].Choices[0].Delta.Content != "Hello" {
+		t.Fatalf("unexpected content chunks: %#v %#v", chunks[2], chunks[3])
+	}
+	if reason := chunks[4].Choices[0].FinishReason; reason == nil || *reason != "stop" {
+		t.Fatalf("unexpected finish chunk: %#v", chunks[4])
+	}
+	usage := chunks[5].Usage
+	if len(chunks[5].Choices) != 0 || usage == nil || usage.PromptTokens != 12 || usage.CompletionTokens != 3 || usage.TotalTokens != 15 {
+		t.Fatalf("unexpected usage chunk: %#v", chunks[5])
+	}
+}
+
+func TestOpenAIStreamCompletion_Error(t *testing.T) {
+	h := &OpenAIHandler{
+		logger: slog.Default(),
+		runner: &fakeOpenAIRunner{chunks: []string{`{"type":"text_delta","delta":"partial"}`}, err: errors.New("gateway down")},
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil), rec)
+	if err := h.streamCompletion(c, conversation.ChatRequest{BotID: "bot-1"}, "chatcmpl-1", 1700000000, false); err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `"message":"gateway down"`) {
+		t.Fatalf("expected error event, got %s", body)
+	}
+	if strings.Contains(body, "[DONE]") {
+		t.Fatalf("failed stream must not be terminated with [DONE]: %s", body)
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	e := echo.New()
+	cases := []struct {
+		err      error
+		status   int
+		errType  string
+		wantCode string
+	}{
+		{err: newOpenAIError(http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "invalid API token"), status: http.StatusUnauthorized, errType: "invalid_request_error", wantCode: "invalid_api_key"},
+		{err: echo.NewHTTPError(http.StatusNotFound, "bot not found"), status: http.StatusNotFound, errType: "invalid_request_error", wantCode: "model_not_found"},
+		{err: echo.NewHTTPError(http.StatusInternalServerError, "boom"), status: http.StatusInternalServerError, errType: "server_error"},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/v1/models", nil), rec)
+		handler := openAIErrors(func(echo.Context) error { return tc.err })
+		if err := handler(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rec.Code != tc.status {
+			t.Fatalf("status = %d, want %d", rec.Code, tc.status)
+		}
+		var resp OpenAIErrorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Error.Type != tc.errType || resp.Error.Code != tc.wantCode || resp.Error.Message == "" {
+			t.Fatalf("unexpected error body: %#v", resp)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/pii"
+)
+
+type PIIHandler struct {
+	service        *pii.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	logger         *slog.Logger
+}
+
+// PIIVaultResponse lists the values a bot redacted.
+type PIIVaultResponse struct {
+	Items []pii.Entry `json:"items"`
+}
+
+func NewPIIHandler(log *slog.Logger, service *pii.Service, botService *bots.Service, accountService *accounts.Service) *PIIHandler {
+	return &PIIHandler{
+		service:        service,
+		botService:     botService,
+		accountService: accountService,
+		logger:         log.With(slog.String("handler", "pii")),
+	}
+}
+
+func (h *PIIHandler) Register(e *echo.Echo) {
+	group := e.Group("/bots/:bot_id/pii")
+	group.GET("/policy", h.GetPolicy)
+	group.PUT("/policy", h.UpdatePolicy)
+	group.GET("/vault", h.ListVault)
+}
+
+// GetPolicy godoc
+// @Summary Get PII redaction policy
+// @Description Get the detectors, terms and patterns used to redact personal data before it is sent to model and embedding providers.
+// @Tags pii
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} pii.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/pii/policy [get]
+func (h *PIIHandler) GetPolicy(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	policy, err := h.service.GetPolicy(c.Request().Context(), botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update PII redaction policy
+// @Description Turn redaction on or off and replace the detectors, terms and patterns of a bot. Values already in the vault are kept.
+// @Tags pii
+// @Param bot_id path string true "Bot ID"
+// @Param payload body pii.Policy true "PII policy"
+// @Success 200 {object} pii.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/pii/policy [put]
+func (h *PIIHandler) UpdatePolicy(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	var req pii.Policy
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	policy, err := h.service.UpdatePolicy(c.Request().Context(), botID, req)
+	if err != nil {
+		if errors.Is(err, pii.ErrInvalidPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// ListVault godoc
+// @Summary List PII vault
+// @Description List the values a bot redacted and the placeholders that stand for them.
+// @Tags pii
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} PIIVaultResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/pii/vault [get]
+func (h *PIIHandler) ListVault(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	items, err := h.service.ListEntries(c.Request().Context(), botID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, PIIVaultResponse{Items: items})
+}
+
+func (h *PIIHandler) requireBot(c echo.Context) (string, error) {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return "", err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	if _, err := h.authorizeBotAccess(c.Request().Context(), channelIdentityID, botID); err != nil {
+		return "", err
+	}
+	return botID, nil
+}
+
+func (h *PIIHandler) authorizeBotAccess(ctx context.Context, channelIdentityID, botID string) (bots.Bot, error) {
+	return AuthorizeBotAccess(ctx, h.botService, h.accountService, channelIdentityID, botID, bots.AccessPolicy{AllowPublicMember: false})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/quota"
+)
+
+type QuotaHandler struct {
+	service        *quota.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	logger         *slog.Logger
+}
+
+// QuotaResetResponse reports how many counters a reset cleared.
+type QuotaResetResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+func NewQuotaHandler(log *slog.Logger, service *quota.Service, botService *bots.Service, accountService *accounts.Service) *QuotaHandler {
+	return &QuotaHandler{
+		service:        service,
+		botService:     botService,
+		accountService: accountService,
+		logger:         log.With(slog.String("handler", "quota")),
+	}
+}
+
+func (h *QuotaHandler) Register(e *echo.Echo) {
+	group := e.Group("/bots/:bot_id/quotas")
+	group.GET("/policy", h.GetPolicy)
+	group.PUT("/policy", h.UpdatePolicy)
+	group.GET("/usage", h.Usage)
+	group.POST("/reset", h.Reset)
+}
+
+// GetPolicy godoc
+// @Summary Get usage quota policy
+// @Description Get the request, token and cost limits of a bot
+// @Tags quotas
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} quota.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/quotas/policy [get]
+func (h *QuotaHandler) GetPolicy(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	policy, err := h.service.GetPolicy(c.Request().Context(), botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update usage quota policy
+// @Description Replace the request, token and cost limits of a bot. Usage earlier in the current period still counts.
+// @Tags quotas
+// @Param bot_id path string true "Bot ID"
+// @Param payload body quota.Policy true "Quota policy"
+// @Success 200 {object} quota.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/quotas/policy [put]
+func (h *QuotaHandler) UpdatePolicy(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	var req quota.Policy
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	policy, err := h.service.UpdatePolicy(c.Request().Context(), botID, req)
+	if err != nil {
+		if errors.Is(err, quota.ErrInvalidPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// Usage godoc
+// @Summary Current quota usage
+// @Description List the counters of a bot for the current day, week and month
+// @Tags quotas
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {array} quota.Counter
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/quotas/usage [get]
+func (h *QuotaHandler) Usage(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	items, err := h.service.Usage(c.Request().Context(), botID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// Reset godoc
+// @Summary Reset quota counters
+// @Description Clear usage counters ahead of schedule, optionally only those of one scope or subject
+// @Tags quotas
+// @Param bot_id path string true "Bot ID"
+// @Param scope query string false "Scope (bot, user, guest)"
+// @Param subject query string false "User ID or guest channel identity ID"
+// @Success 200 {object} QuotaResetResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/quotas/reset [post]
+func (h *QuotaHandler) Reset(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	scope := strings.TrimSpace(c.QueryParam("scope"))
+	switch quota.Scope(scope) {
+	case "", quota.ScopeBot, quota.ScopeUser, quota.ScopeGuest:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "scope must be bot, user or guest")
+	}
+	deleted, err := h.service.Reset(c.Request().Context(), botID, scope, c.QueryParam("subject"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, QuotaResetResponse{Deleted: deleted})
+}
+
+func (h *QuotaHandler) requireBot(c echo.Context) (string, error) {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return "", err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	if _, err := h.authorizeBotAccess(c.Request().Context(), channelIdentityID, botID); err != nil {
+		return "", err
+	}
+	return botID, nil
+}
+
+func (h *QuotaHandler) authorizeBotAccess(ctx context.Context, channelIdentityID, botID string) (bots.Bot, error) {
+	return AuthorizeBotAccess(ctx, h.botService, h.accountService, channelIdentityID, botID, bots.AccessPolicy{AllowPublicMember: false})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/message/retention"
+)
+
+type RetentionHandler struct {
+	service        *retention.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	logger         *slog.Logger
+}
+
+func NewRetentionHandler(log *slog.Logger, service *retention.Service, botService *bots.Service, accountService *accounts.Service) *RetentionHandler {
+	return &RetentionHandler{
+		service:        service,
+		botService:     botService,
+		accountService: accountService,
+		logger:         log.With(slog.String("handler", "retention")),
+	}
+}
+
+func (h *RetentionHandler) Register(e *echo.Echo) {
+	group := e.Group("/bots/:bot_id/retention")
+	group.GET("/policy", h.GetPolicy)
+	group.PUT("/policy", h.UpdatePolicy)
+	group.GET("/preview", h.Preview)
+	group.POST("/purge", h.Purge)
+	group.GET("/runs", h.ListRuns)
+}
+
+// GetPolicy godoc
+// @Summary Get message retention policy
+// @Description Get the age and count limits after which a bot's history is purged
+// @Tags retention
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} retention.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/retention/policy [get]
+func (h *RetentionHandler) GetPolicy(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	policy, err := h.service.GetPolicy(c.Request().Context(), botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update message retention policy
+// @Description Replace the retention policy of a bot. It is applied by the next scheduled purge; use the preview endpoint to see what it would remove.
+// @Tags retention
+// @Param bot_id path string true "Bot ID"
+// @Param payload body retention.Policy true "Retention policy"
+// @Success 200 {object} retention.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/retention/policy [put]
+func (h *RetentionHandler) UpdatePolicy(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	var req retention.Policy
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	policy, err := h.service.UpdatePolicy(c.Request().Context(), botID, req)
+	if err != nil {
+		if errors.Is(err, retention.ErrInvalidPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// Preview godoc
+// @Summary Preview a retention purge
+// @Description Dry run: report how many messages and attachment links the bot's retention policy would remove now, without deleting anything
+// @Tags retention
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} retention.Report
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/retention/preview [get]
+func (h *RetentionHandler) Preview(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	report, err := h.service.Preview(c.Request().Context(), botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// Purge godoc
+// @Summary Purge history now
+// @Description Apply the bot's retention policy immediately instead of waiting for the scheduled purge. With export_before_purge set, every batch of purged messages is archived as bot media, listed under archive_content_hashes.
+// @Tags retention
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} retention.Report
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/retention/purge [post]
+func (h *RetentionHandler) Purge(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	report, err := h.service.Purge(c.Request().Context(), botID, retention.TriggerManual)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// ListRuns godoc
+// @Summary List retention purges
+// @Description List the latest purges that deleted history of a bot, newest first
+// @Tags retention
+// @Param bot_id path string true "Bot ID"
+// @Param limit query int false "Limit" default(50)
+// @Success 200 {array} retention.Run
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/retention/runs [get]
+func (h *RetentionHandler) ListRuns(c echo.Context) error {
+	botID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	var limit int32
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || parsed <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		limit = int32(parsed)
+	}
+	runs, err := h.service.Runs(c.Request().Context(), botID, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, runs)
+}
+
+func (h *RetentionHandler) requireBot(c echo.Context) (string, error) {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return "", err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	if _, err := h.authorizeBotAccess(c.Request().Context(), channelIdentityID, botID); err != nil {
+		return "", err
+	}
+	return botID, nil
+}
+
+func (h *RetentionHandler) authorizeBotAccess(ctx context.Context, channelIdentityID, botID string) (bots.Bot, error) {
+	return AuthorizeBotAccess(ctx, h.botService, h.accountService, channelIdentityID, botID, bots.AccessPolicy{AllowPublicMember: false})
+}
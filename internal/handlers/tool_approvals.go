@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/toolapproval"
+)
+
+type ToolApprovalHandler struct {
+	service        *toolapproval.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	logger         *slog.Logger
+}
+
+// ToolApprovalDecisionRequest is the optional body of Approve and Deny.
+type ToolApprovalDecisionRequest struct {
+	Note string `json:"note,omitempty"`
+}
+
+func NewToolApprovalHandler(log *slog.Logger, service *toolapproval.Service, botService *bots.Service, accountService *accounts.Service) *ToolApprovalHandler {
+	return &ToolApprovalHandler{
+		service:        service,
+		botService:     botService,
+		accountService: accountService,
+		logger:         log.With(slog.String("handler", "tool_approval")),
+	}
+}
+
+func (h *ToolApprovalHandler) Register(e *echo.Echo) {
+	group := e.Group("/bots/:bot_id/tool-approvals")
+	group.GET("", h.List)
+	group.GET("/policy", h.GetPolicy)
+	group.PUT("/policy", h.UpdatePolicy)
+	group.POST("/:id/approve", h.Approve)
+	group.POST("/:id/deny", h.Deny)
+}
+
+// List godoc
+// @Summary List tool approvals
+// @Description List tool calls held for owner approval and their outcome, newest first
+// @Tags tool-approvals
+// @Param bot_id path string true "Bot ID"
+// @Param status query string false "Filter by status (pending, approved, denied, expired)"
+// @Param limit query int false "Max items to return" default(50)
+// @Success 200 {array} toolapproval.Approval
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/tool-approvals [get]
+func (h *ToolApprovalHandler) List(c echo.Context) error {
+	botID, _, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	items, err := h.service.List(c.Request().Context(), botID, c.QueryParam("status"), parseIntOr(c.QueryParam("limit"), 50))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, items)
+}
+
+// GetPolicy godoc
+// @Summary Get tool approval policy
+// @Description Get the rules marking tool calls of a bot that need owner approval
+// @Tags tool-approvals
+// @Param bot_id path string true "Bot ID"
+// @Success 200 {object} toolapproval.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/tool-approvals/policy [get]
+func (h *ToolApprovalHandler) GetPolicy(c echo.Context) error {
+	botID, _, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	policy, err := h.service.GetPolicy(c.Request().Context(), botID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdatePolicy godoc
+// @Summary Update tool approval policy
+// @Description Replace the rules marking tool calls of a bot that need owner approval
+// @Tags tool-approvals
+// @Param bot_id path string true "Bot ID"
+// @Param payload body toolapproval.Policy true "Approval policy"
+// @Success 200 {object} toolapproval.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/tool-approvals/policy [put]
+func (h *ToolApprovalHandler) UpdatePolicy(c echo.Context) error {
+	botID, _, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	var req toolapproval.Policy
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	policy, err := h.service.UpdatePolicy(c.Request().Context(), botID, req)
+	if err != nil {
+		if errors.Is(err, toolapproval.ErrInvalidPolicy) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return echo.NewHTTPError(http.StatusNotFound, "bot not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// Approve godoc
+// @Summary Approve a held tool call
+// @Description Let a tool call waiting for owner approval run
+// @Tags tool-approvals
+// @Param bot_id path string true "Bot ID"
+// @Param id path string true "Approval ID"
+// @Param payload body ToolApprovalDecisionRequest false "Optional note"
+// @Success 200 {object} toolapproval.Approval
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/tool-approvals/{id}/approve [post]
+func (h *ToolApprovalHandler) Approve(c echo.Context) error {
+	return h.decide(c, true)
+}
+
+// Deny godoc
+// @Summary Deny a held tool call
+// @Description Refuse a tool call waiting for owner approval; the note is returned to the model as the reason
+// @Tags tool-approvals
+// @Param bot_id path string true "Bot ID"
+// @Param id path string true "Approval ID"
+// @Param payload body ToolApprovalDecisionRequest false "Optional reason"
+// @Success 200 {object} toolapproval.Approval
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/tool-approvals/{id}/deny [post]
+func (h *ToolApprovalHandler) Deny(c echo.Context) error {
+	return h.decide(c, false)
+}
+
+func (h *ToolApprovalHandler) decide(c echo.Context, approve bool) error {
+	botID, userID, err := h.requireBot(c)
+	if err != nil {
+		return err
+	}
+	approvalID := strings.TrimSpace(c.Param("id"))
+	if approvalID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "approval id is required")
+	}
+	var req ToolApprovalDecisionRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	decided, err := h.service.Decide(c.Request().Context(), botID, approvalID, approve, userID, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, toolapproval.ErrNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		case errors.Is(err, toolapproval.ErrAlreadyDecided):
+			return echo.NewHTTPError(http.StatusConflict, "tool approval already "+decided.Status)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, decided)
+}
+
+// requireBot checks that the caller manages the bot and returns the bot ID
+// and the caller's ID.
+func (h *ToolApprovalHandler) requireBot(c echo.Context) (string, string, error) {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return "", "", err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	if _, err := h.authorizeBotAccess(c.Request().Context(), channelIdentityID, botID); err != nil {
+		return "", "", err
+	}
+	return botID, channelIdentityID, nil
+}
+
+func (h *ToolApprovalHandler) authorizeBotAccess(ctx context.Context, channelIdentityID, botID string) (bots.Bot, error) {
+	return AuthorizeBotAccess(ctx, h.botService, h.accountService, channelIdentityID, botID, bots.AccessPolicy{AllowPublicMember: false})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/channel/route"
+	"github.com/memohai/memoh/internal/tts"
+)
+
+type TTSHandler struct {
+	service        *tts.Service
+	routeService   route.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	logger         *slog.Logger
+}
+
+// VoiceReplyRequest sets or clears (enabled omitted/null) the route override.
+type VoiceReplyRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// VoiceReplyResponse describes the voice reply state of a route.
+type VoiceReplyResponse struct {
+	RouteID  string `json:"route_id"`
+	Override *bool  `json:"override,omitempty"`
+	Enabled  bool   `json:"enabled"`
+}
+
+func NewTTSHandler(log *slog.Logger, service *tts.Service, routeService route.Service, botService *bots.Service, accountService *accounts.Service) *TTSHandler {
+	return &TTSHandler{
+		service:        service,
+		routeService:   routeService,
+		botService:     botService,
+		accountService: accountService,
+		logger:         log.With(slog.String("handler", "tts")),
+	}
+}
+
+func (h *TTSHandler) Register(e *echo.Echo) {
+	group := e.Group("/bots/:bot_id/routes/:route_id/voice-reply")
+	group.GET("", h.GetVoiceReply)
+	group.PUT("", h.SetVoiceReply)
+}
+
+// GetVoiceReply godoc
+// @Summary Get route voice reply state
+// @Description Get whether replies on a route are spoken, and the route-level override if set
+// @Tags tts
+// @Param bot_id path string true "Bot ID"
+// @Param route_id path string true "Route ID"
+// @Success 200 {object} VoiceReplyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/routes/{route_id}/voice-reply [get]
+func (h *TTSHandler) GetVoiceReply(c echo.Context) error {
+	botID, routeID, err := h.authorizeRoute(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, h.voiceReplyState(c.Request().Context(), botID, routeID))
+}
+
+// SetVoiceReply godoc
+// @Summary Set route voice reply override
+// @Description Enable or disable voice replies for a single route; a null value falls back to the bot setting
+// @Tags tts
+// @Param bot_id path string true "Bot ID"
+// @Param route_id path string true "Route ID"
+// @Param payload body VoiceReplyRequest true "Override payload"
+// @Success 200 {object} VoiceReplyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/routes/{route_id}/voice-reply [put]
+func (h *TTSHandler) SetVoiceReply(c echo.Context) error {
+	botID, routeID, err := h.authorizeRoute(c)
+	if err != nil {
+		return err
+	}
+	var req VoiceReplyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := h.service.SetRouteOverride(c.Request().Context(), routeID, req.Enabled); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, h.voiceReplyState(c.Request().Context(), botID, routeID))
+}
+
+func (h *TTSHandler) voiceReplyState(ctx context.Context, botID, routeID string) VoiceReplyResponse {
+	resp := VoiceReplyResponse{
+		RouteID: routeID,
+		Enabled: h.service.VoiceReplyEnabled(ctx, botID, routeID),
+	}
+	if override, ok := h.service.RouteOverride(ctx, routeID); ok {
+		resp.Override = &override
+	}
+	return resp
+}
+
+func (h *TTSHandler) authorizeRoute(c echo.Context) (string, string, error) {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return "", "", err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	routeID := strings.TrimSpace(c.Param("route_id"))
+	if routeID == "" {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "route id is required")
+	}
+	if h.service == nil || h.routeService == nil {
+		return "", "", echo.NewHTTPError(http.StatusInternalServerError, "tts service not configured")
+	}
+	if _, err := AuthorizeBotAccess(c.Request().Context(), h.botService, h.accountService, channelIdentityID, botID, bots.AccessPolicy{AllowPublicMember: false}); err != nil {
+		return "", "", err
+	}
+	r, err := h.routeService.GetByID(c.Request().Context(), routeID)
+	if err != nil || r.BotID != botID {
+		return "", "", echo.NewHTTPError(http.StatusNotFound, "route not found")
+	}
+	return botID, routeID, nil
+}
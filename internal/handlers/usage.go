@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/memohai/memoh/internal/accounts"
+	"github.com/memohai/memoh/internal/bots"
+	"github.com/memohai/memoh/internal/usage"
+)
+
+// defaultUsageReportWindow is the report range when the request gives no start.
+const defaultUsageReportWindow = 30 * 24 * time.Hour
+
+type UsageHandler struct {
+	service        *usage.Service
+	botService     *bots.Service
+	accountService *accounts.Service
+	logger         *slog.Logger
+}
+
+func NewUsageHandler(log *slog.Logger, service *usage.Service, botService *bots.Service, accountService *accounts.Service) *UsageHandler {
+	return &UsageHandler{
+		service:        service,
+		botService:     botService,
+		accountService: accountService,
+		logger:         log.With(slog.String("handler", "usage")),
+	}
+}
+
+func (h *UsageHandler) Register(e *echo.Echo) {
+	e.GET("/usage/report", h.Report)
+	e.GET("/bots/:bot_id/usage/report", h.BotReport)
+}
+
+// Report godoc
+// @Summary Usage and cost report
+// @Description Aggregate token usage of all bots, or of one with bot_id, and price it with the model price catalog. Admin only. Defaults to the last 30 days grouped by model.
+// @Tags usage
+// @Param bot_id query string false "Bot ID"
+// @Param from query string false "Range start (RFC3339 or unix milliseconds)"
+// @Param to query string false "Range end (RFC3339 or unix milliseconds)"
+// @Param group_by query string false "Comma-separated: bot, user, model, channel, day" default(model)
+// @Param format query string false "json or csv" default(json)
+// @Success 200 {object} usage.Report
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /usage/report [get]
+func (h *UsageHandler) Report(c echo.Context) error {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	isAdmin, err := h.accountService.IsAdmin(c.Request().Context(), channelIdentityID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if !isAdmin {
+		return echo.NewHTTPError(http.StatusForbidden, "admin role required")
+	}
+	return h.report(c, strings.TrimSpace(c.QueryParam("bot_id")))
+}
+
+// BotReport godoc
+// @Summary Bot usage and cost report
+// @Description Aggregate token usage of a bot and price it with the model price catalog. Defaults to the last 30 days grouped by model.
+// @Tags usage
+// @Param bot_id path string true "Bot ID"
+// @Param from query string false "Range start (RFC3339 or unix milliseconds)"
+// @Param to query string false "Range end (RFC3339 or unix milliseconds)"
+// @Param group_by query string false "Comma-separated: bot, user, model, channel, day" default(model)
+// @Param format query string false "json or csv" default(json)
+// @Success 200 {object} usage.Report
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /bots/{bot_id}/usage/report [get]
+func (h *UsageHandler) BotReport(c echo.Context) error {
+	channelIdentityID, err := RequireChannelIdentityID(c)
+	if err != nil {
+		return err
+	}
+	botID := strings.TrimSpace(c.Param("bot_id"))
+	if botID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "bot id is required")
+	}
+	if _, err := AuthorizeBotAccess(c.Request().Context(), h.botService, h.accountService, channelIdentityID, botID, bots.AccessPolicy{AllowPublicMember: false}); err != nil {
+		return err
+	}
+	return h.report(c, botID)
+}
+
+func (h *UsageHandler) report(c echo.Context, botID string) error {
+	to, hasTo, err := parseSinceParam(c.QueryParam("to"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid to parameter")
+	}
+	if !hasTo {
+		to = time.Now().UTC()
+	}
+	from, hasFrom, err := parseSinceParam(c.QueryParam("from"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid from parameter")
+	}
+	if !hasFrom {
+		from = to.Add(-defaultUsageReportWindow)
+	}
+	groupBy, err := usage.ParseDimensions(c.QueryParam("group_by"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	format := strings.ToLower(strings.TrimSpace(c.QueryParam("format")))
+	if format != "" && format != "json" && format != "csv" {
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be json or csv")
+	}
+
+	report, err := h.service.Report(c.Request().Context(), usage.Query{
+		BotID:   botID,
+		From:    from,
+		To:      to,
+		GroupBy: groupBy,
+	})
+	if err != nil {
+		if errors.Is(err, usage.ErrInvalidQuery) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if format != "csv" {
+		return c.JSON(http.StatusOK, report)
+	}
+	var buf bytes.Buffer
+	if err := usage.WriteCSV(&buf, report); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="usage-%s-%s.csv"`, from.Format("20060102"), to.Format("20060102")))
+	return c.Blob(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
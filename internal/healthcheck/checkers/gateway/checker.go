@@ -0,0 +1,96 @@
+package gatewaychecker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/memohai/memoh/internal/conversation/gateway"
+	"github.com/memohai/memoh/internal/healthcheck"
+)
+
+const (
+	checkTypeAgentGateway = "agent_gateway.endpoint"
+	titleKeyAgentGateway  = "bots.checks.titles.agentGateway"
+)
+
+// StatusReader reports agent gateway endpoint states.
+type StatusReader interface {
+	Statuses() []gateway.EndpointStatus
+}
+
+// Checker evaluates agent gateway endpoint health checks. Every bot shares
+// the gateways, so each bot sees the same checks.
+type Checker struct {
+	logger *slog.Logger
+	reader StatusReader
+}
+
+// NewChecker creates an agent gateway health checker.
+func NewChecker(log *slog.Logger, reader StatusReader) *Checker {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Checker{
+		logger: log.With(slog.String("checker", "healthcheck_agent_gateway")),
+		reader: reader,
+	}
+}
+
+// ListChecks reports one check per gateway endpoint.
+func (c *Checker) ListChecks(ctx context.Context, botID string) []healthcheck.CheckResult {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return []healthcheck.CheckResult{}
+	}
+	if strings.TrimSpace(botID) == "" || c.reader == nil {
+		return []healthcheck.CheckResult{}
+	}
+
+	statuses := c.reader.Statuses()
+	healthy := 0
+	for _, status := range statuses {
+		if status.Healthy {
+			healthy++
+		}
+	}
+	checks := make([]healthcheck.CheckResult, 0, len(statuses))
+	for idx, status := range statuses {
+		item := healthcheck.CheckResult{
+			ID:       fmt.Sprintf("%s.%d", checkTypeAgentGateway, idx+1),
+			Type:     checkTypeAgentGateway,
+			TitleKey: titleKeyAgentGateway,
+			Subtitle: status.URL,
+			Metadata: map[string]any{
+				"url":      status.URL,
+				"healthy":  status.Healthy,
+				"inflight": status.Inflight,
+			},
+		}
+		if !status.LastProbeAt.IsZero() {
+			item.Metadata["last_probe_at"] = status.LastProbeAt.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		switch {
+		case !status.Healthy:
+			item.Status = healthcheck.StatusError
+			item.Summary = "Agent gateway is unreachable."
+			if healthy > 0 {
+				// Requests still go to the other endpoints.
+				item.Status = healthcheck.StatusWarn
+				item.Summary = fmt.Sprintf("Agent gateway is unreachable; %d of %d endpoints serve requests.", healthy, len(statuses))
+			}
+			item.Detail = strings.TrimSpace(status.LastError)
+		case !status.Probed:
+			item.Status = healthcheck.StatusUnknown
+			item.Summary = "Agent gateway has not been probed yet."
+		default:
+			item.Status = healthcheck.StatusOK
+			item.Summary = "Agent gateway is healthy."
+		}
+		checks = append(checks, item)
+	}
+	return checks
+}
@@ -0,0 +1,62 @@
+package gatewaychecker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/memohai/memoh/internal/conversation/gateway"
+	"github.com/memohai/memoh/internal/healthcheck"
+)
+
+type fakeStatusReader struct {
+	items []gateway.EndpointStatus
+}
+
+func (f *fakeStatusReader) Statuses() []gateway.EndpointStatus {
+	return f.items
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestCheckerListChecks(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	checker := NewChecker(newTestLogger(), &fakeStatusReader{items: []gateway.EndpointStatus{
+		{URL: "http://gw-1:8081", Healthy: true, Probed: true, LastProbeAt: now},
+		{URL: "http://gw-2:8081", Healthy: false, Probed: true, LastProbeAt: now, LastError: "connection refused"},
+		{URL: "http://gw-3:8081", Healthy: true},
+	}})
+
+	items := checker.ListChecks(context.Background(), "bot-1")
+	if len(items) != 3 {
+		t.Fatalf("expected 3 checks, got %d", len(items))
+	}
+	if items[0].Status != healthcheck.StatusOK || items[0].Subtitle != "http://gw-1:8081" {
+		t.Fatalf("unexpected first check %+v", items[0])
+	}
+	if items[1].Status != healthcheck.StatusWarn || items[1].Detail != "connection refused" {
+		t.Fatalf("expected down endpoint to warn while others serve, got %+v", items[1])
+	}
+	if items[2].Status != healthcheck.StatusUnknown {
+		t.Fatalf("expected unprobed endpoint unknown, got %+v", items[2])
+	}
+}
+
+func TestCheckerAllEndpointsDown(t *testing.T) {
+	t.Parallel()
+
+	checker := NewChecker(newTestLogger(), &fakeStatusReader{items: []gateway.EndpointStatus{
+		{URL: "http://gw-1:8081", Probed: true, LastError: "timeout"},
+	}})
+
+	items := checker.ListChecks(context.Background(), "bot-1")
+	if len(items) != 1 || items[0].Status != healthcheck.StatusError {
+		t.Fatalf("expected error check, got %+v", items)
+	}
+}
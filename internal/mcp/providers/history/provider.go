@@ -0,0 +1,198 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	mcpgw "github.com/memohai/memoh/internal/mcp"
+	mem "github.com/memohai/memoh/internal/memory"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+const (
+	toolSearchHistory  = "search_history"
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// OwnerReader resolves the user who owns a bot.
+type OwnerReader interface {
+	BotOwnerUserID(ctx context.Context, botID string) (string, error)
+}
+
+type Executor struct {
+	searcher   messagepkg.Searcher
+	routes     mcpgw.RouteReader
+	identities mcpgw.IdentityReader
+	owners     OwnerReader
+	logger     *slog.Logger
+}
+
+func NewExecutor(log *slog.Logger, searcher messagepkg.Searcher, routes mcpgw.RouteReader, identities mcpgw.IdentityReader, owners OwnerReader) *Executor {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Executor{
+		searcher:   searcher,
+		routes:     routes,
+		identities: identities,
+		owners:     owners,
+		logger:     log.With(slog.String("provider", "history_tool")),
+	}
+}
+
+func (e *Executor) ListTools(ctx context.Context, session mcpgw.ToolSessionContext) ([]mcpgw.ToolDescriptor, error) {
+	if e.searcher == nil {
+		return []mcpgw.ToolDescriptor{}, nil
+	}
+	return []mcpgw.ToolDescriptor{
+		{
+			Name:        toolSearchHistory,
+			Description: "Search the full history of this conversation, including messages older than the current context window. In a direct chat with the bot owner it searches every channel. Use it to recall what someone said earlier, e.g. what a person said about a topic last month.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{
+						"type":        "string",
+						"description": "Words or phrase to search for. Supports quoted phrases, OR and -exclusions.",
+					},
+					"sender": map[string]any{
+						"type":        "string",
+						"description": "Only messages from this sender: a display name (partial match) or a channel identity ID",
+					},
+					"platform": map[string]any{
+						"type":        "string",
+						"description": "Only messages from this channel platform, e.g. telegram",
+					},
+					"role": map[string]any{
+						"type":        "string",
+						"enum":        []string{"user", "assistant"},
+						"description": "Only messages from users or only the bot's own replies",
+					},
+					"start_time": map[string]any{
+						"type":        "string",
+						"description": "ISO 8601 start time filter (e.g. 2025-01-01T00:00:00Z)",
+					},
+					"end_time": map[string]any{
+						"type":        "string",
+						"description": "ISO 8601 end time filter",
+					},
+					"limit": map[string]any{
+						"type":        "integer",
+						"description": "Maximum number of results (default 20, max 100)",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+	}, nil
+}
+
+func (e *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContext, toolName string, arguments map[string]any) (map[string]any, error) {
+	if toolName != toolSearchHistory {
+		return nil, mcpgw.ErrToolNotFound
+	}
+	if e.searcher == nil {
+		return mcpgw.BuildToolErrorResult("history search not available"), nil
+	}
+
+	query := mcpgw.StringArg(arguments, "query")
+	if query == "" {
+		return mcpgw.BuildToolErrorResult("query is required"), nil
+	}
+	botID := strings.TrimSpace(session.BotID)
+	if botID == "" {
+		return mcpgw.BuildToolErrorResult("bot_id is required"), nil
+	}
+
+	limit := defaultSearchLimit
+	if value, ok, err := mcpgw.IntArg(arguments, "limit"); err != nil {
+		return mcpgw.BuildToolErrorResult(err.Error()), nil
+	} else if ok {
+		limit = value
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	req := messagepkg.SearchQuery{
+		Query:    query,
+		Sender:   mcpgw.StringArg(arguments, "sender"),
+		Platform: mcpgw.StringArg(arguments, "platform"),
+		Role:     mcpgw.StringArg(arguments, "role"),
+		Limit:    int32(limit),
+	}
+	if startStr := mcpgw.StringArg(arguments, "start_time"); startStr != "" {
+		t, err := time.Parse(time.RFC3339, startStr)
+		if err != nil {
+			return mcpgw.BuildToolErrorResult(fmt.Sprintf("invalid start_time: %v", err)), nil
+		}
+		req.StartTime = &t
+	}
+	if endStr := mcpgw.StringArg(arguments, "end_time"); endStr != "" {
+		t, err := time.Parse(time.RFC3339, endStr)
+		if err != nil {
+			return mcpgw.BuildToolErrorResult(fmt.Sprintf("invalid end_time: %v", err)), nil
+		}
+		req.EndTime = &t
+	}
+
+	scope, ok := mcpgw.ResolveSessionScope(ctx, e.logger, e.routes, e.identities, session)
+	if !ok {
+		return mcpgw.BuildToolErrorResult("conversation not found"), nil
+	}
+	if !e.ownerDirectChat(ctx, botID, scope) {
+		req.RouteScoped = true
+		req.RouteID = scope.RouteID
+	}
+
+	hits, err := e.searcher.Search(ctx, botID, req)
+	if err != nil {
+		e.logger.Warn("history search failed", slog.String("bot_id", botID), slog.Any("error", err))
+		return mcpgw.BuildToolErrorResult("history search failed"), nil
+	}
+
+	results := make([]map[string]any, 0, len(hits))
+	for _, hit := range hits {
+		entry := map[string]any{
+			"id":         hit.ID,
+			"role":       hit.Role,
+			"text":       hit.Text,
+			"created_at": hit.CreatedAt.Format(time.RFC3339),
+		}
+		if hit.SenderDisplayName != "" {
+			entry["sender"] = hit.SenderDisplayName
+		}
+		if hit.Platform != "" {
+			entry["platform"] = hit.Platform
+		}
+		results = append(results, entry)
+	}
+
+	return mcpgw.BuildToolSuccessResult(map[string]any{
+		"query":   query,
+		"total":   len(results),
+		"results": results,
+	}), nil
+}
+
+// ownerDirectChat reports whether the session is the owner talking to the bot
+// one to one. Only then may the search reach beyond the current conversation;
+// anywhere else other people's chats would leak into it.
+func (e *Executor) ownerDirectChat(ctx context.Context, botID string, scope mcpgw.SessionScope) bool {
+	if e.owners == nil || scope.UserID == "" || mem.IsGroupConversation(scope.ConversationType) {
+		return false
+	}
+	ownerID, err := e.owners.BotOwnerUserID(ctx, botID)
+	if err != nil {
+		e.logger.Warn("resolve bot owner failed", slog.String("bot_id", botID), slog.Any("error", err))
+		return false
+	}
+	return ownerID != "" && ownerID == scope.UserID
+}
@@ -0,0 +1,180 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/memohai/memoh/internal/channel/identities"
+	"github.com/memohai/memoh/internal/channel/route"
+	mcpgw "github.com/memohai/memoh/internal/mcp"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+type fakeSearcher struct {
+	results []messagepkg.SearchResult
+	err     error
+	got     messagepkg.SearchQuery
+}
+
+func (f *fakeSearcher) Search(ctx context.Context, botID string, query messagepkg.SearchQuery) ([]messagepkg.SearchResult, error) {
+	f.got = query
+	return f.results, f.err
+}
+
+// routeSearcher holds messages per route ("" is the routeless history) and
+// applies the route filter the way the SQL query does.
+type routeSearcher struct {
+	byRoute map[string][]messagepkg.SearchResult
+}
+
+func (f *routeSearcher) Search(ctx context.Context, botID string, query messagepkg.SearchQuery) ([]messagepkg.SearchResult, error) {
+	var out []messagepkg.SearchResult
+	for routeID, hits := range f.byRoute {
+		if query.RouteScoped && routeID != query.RouteID {
+			continue
+		}
+		out = append(out, hits...)
+	}
+	return out, nil
+}
+
+type fakeRoutes map[string]route.Route
+
+func (f fakeRoutes) GetByID(ctx context.Context, routeID string) (route.Route, error) {
+	rt, ok := f[routeID]
+	if !ok {
+		return route.Route{}, errors.New("route not found")
+	}
+	return rt, nil
+}
+
+type fakeIdentities map[string]string
+
+func (f fakeIdentities) GetByID(ctx context.Context, channelIdentityID string) (identities.ChannelIdentity, error) {
+	return identities.ChannelIdentity{ID: channelIdentityID, UserID: f[channelIdentityID]}, nil
+}
+
+type fakeOwners string
+
+func (f fakeOwners) BotOwnerUserID(ctx context.Context, botID string) (string, error) {
+	return string(f), nil
+}
+
+func TestExecutor_ListTools_NilSearcher(t *testing.T) {
+	exec := NewExecutor(nil, nil, nil, nil, nil)
+	tools, err := exec.ListTools(context.Background(), mcpgw.ToolSessionContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 0 {
+		t.Errorf("expected 0 tools without searcher, got %d", len(tools))
+	}
+}
+
+func TestExecutor_CallTool_PassesFilters(t *testing.T) {
+	searcher := &fakeSearcher{results: []messagepkg.SearchResult{{
+		Message: messagepkg.Message{
+			ID:                "m1",
+			Role:              "user",
+			SenderDisplayName: "Bob",
+			Platform:          "telegram",
+			CreatedAt:         time.Date(2025, 5, 2, 9, 0, 0, 0, time.UTC),
+		},
+		Text: "the trip is in June",
+	}}}
+	exec := NewExecutor(nil, searcher, nil, nil, nil)
+	result, err := exec.CallTool(context.Background(), mcpgw.ToolSessionContext{BotID: "bot1"}, toolSearchHistory, map[string]any{
+		"query":      "trip",
+		"sender":     "Bob",
+		"start_time": "2025-05-01T00:00:00Z",
+		"limit":      500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); isErr {
+		t.Fatalf("unexpected error result %v", result)
+	}
+	if searcher.got.Query != "trip" || searcher.got.Sender != "Bob" || searcher.got.Limit != maxSearchLimit {
+		t.Errorf("unexpected query %+v", searcher.got)
+	}
+	if searcher.got.StartTime == nil || searcher.got.EndTime != nil {
+		t.Errorf("expected only start_time set, got %+v", searcher.got)
+	}
+	structured, _ := result["structuredContent"].(map[string]any)
+	results, _ := structured["results"].([]map[string]any)
+	if len(results) != 1 || results[0]["text"] != "the trip is in June" || results[0]["sender"] != "Bob" {
+		t.Errorf("unexpected results %v", structured)
+	}
+}
+
+func TestExecutor_CallTool_InvalidTime(t *testing.T) {
+	exec := NewExecutor(nil, &fakeSearcher{}, nil, nil, nil)
+	result, err := exec.CallTool(context.Background(), mcpgw.ToolSessionContext{BotID: "bot1"}, toolSearchHistory, map[string]any{
+		"query":    "trip",
+		"end_time": "last month",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr {
+		t.Error("expected error for invalid end_time")
+	}
+}
+
+func TestExecutor_CallTool_SearchFailure(t *testing.T) {
+	exec := NewExecutor(nil, &fakeSearcher{err: errors.New("db down")}, nil, nil, nil)
+	result, err := exec.CallTool(context.Background(), mcpgw.ToolSessionContext{BotID: "bot1"}, toolSearchHistory, map[string]any{"query": "trip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr {
+		t.Error("expected error result when search fails")
+	}
+}
+
+func TestExecutor_CallTool_ScopesToSessionRoute(t *testing.T) {
+	searcher := &routeSearcher{byRoute: map[string][]messagepkg.SearchResult{
+		"group1": {{Message: messagepkg.Message{ID: "g1", Role: "user"}, Text: "trip plans for the team"}},
+		"dm1":    {{Message: messagepkg.Message{ID: "d1", Role: "user"}, Text: "my private trip"}},
+	}}
+	routes := fakeRoutes{
+		"group1": {ID: "group1", BotID: "bot1", ConversationType: "group"},
+		"dm1":    {ID: "dm1", BotID: "bot1", ConversationType: "private"},
+		"other":  {ID: "other", BotID: "bot2", ConversationType: "private"},
+	}
+	idents := fakeIdentities{"ci-owner": "owner", "ci-guest": "guest"}
+	exec := NewExecutor(nil, searcher, routes, idents, fakeOwners("owner"))
+
+	search := func(session mcpgw.ToolSessionContext) (map[string]any, []string) {
+		t.Helper()
+		session.BotID = "bot1"
+		result, err := exec.CallTool(context.Background(), session, toolSearchHistory, map[string]any{"query": "trip"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		structured, _ := result["structuredContent"].(map[string]any)
+		results, _ := structured["results"].([]map[string]any)
+		ids := make([]string, 0, len(results))
+		for _, r := range results {
+			ids = append(ids, r["id"].(string))
+		}
+		return result, ids
+	}
+
+	// The owner talking in a group still only sees that group.
+	if _, ids := search(mcpgw.ToolSessionContext{RouteID: "group1", ChannelIdentityID: "ci-owner"}); len(ids) != 1 || ids[0] != "g1" {
+		t.Errorf("group session saw %v, want only g1", ids)
+	}
+	if _, ids := search(mcpgw.ToolSessionContext{RouteID: "dm1", ChannelIdentityID: "ci-guest"}); len(ids) != 1 || ids[0] != "d1" {
+		t.Errorf("guest direct chat saw %v, want only d1", ids)
+	}
+	if _, ids := search(mcpgw.ToolSessionContext{RouteID: "dm1", ChannelIdentityID: "ci-owner"}); len(ids) != 2 {
+		t.Errorf("owner direct chat saw %v, want every route", ids)
+	}
+	if result, _ := search(mcpgw.ToolSessionContext{RouteID: "other", ChannelIdentityID: "ci-owner"}); result["isError"] != true {
+		t.Errorf("expected error for a route of another bot, got %v", result)
+	}
+}
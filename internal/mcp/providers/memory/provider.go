@@ -15,7 +15,6 @@ const (
 	toolSearchMemory       = "search_memory"
 	defaultMemoryToolLimit = 8
 	maxMemoryToolLimit     = 50
-	sharedMemoryNamespace  = "bot"
 )
 
 type MemorySearcher interface {
@@ -30,10 +29,12 @@ type Executor struct {
 	searcher     MemorySearcher
 	chatAccessor conversation.Accessor
 	adminChecker AdminChecker
+	routes       mcpgw.RouteReader
+	identities   mcpgw.IdentityReader
 	logger       *slog.Logger
 }
 
-func NewExecutor(log *slog.Logger, searcher MemorySearcher, chatAccessor conversation.Accessor, adminChecker AdminChecker) *Executor {
+func NewExecutor(log *slog.Logger, searcher MemorySearcher, chatAccessor conversation.Accessor, adminChecker AdminChecker, routes mcpgw.RouteReader, identityReader mcpgw.IdentityReader) *Executor {
 	if log == nil {
 		log = slog.Default()
 	}
@@ -41,6 +42,8 @@ func NewExecutor(log *slog.Logger, searcher MemorySearcher, chatAccessor convers
 		searcher:     searcher,
 		chatAccessor: chatAccessor,
 		adminChecker: adminChecker,
+		routes:       routes,
+		identities:   identityReader,
 		logger:       log.With(slog.String("provider", "memory_tool")),
 	}
 }
@@ -106,7 +109,7 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 		limit = maxMemoryToolLimit
 	}
 
-	// When ChatID equals BotID (e.g. tools called without conversation context), search by bot scope only.
+	// When ChatID equals BotID (e.g. tools called without conversation context), skip the conversation check.
 	// Otherwise require the conversation to exist and the caller to be a participant.
 	if chatID != botID {
 		chatObj, err := p.chatAccessor.Get(ctx, chatID)
@@ -127,23 +130,23 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 		}
 	}
 
-	resp, err := p.searcher.Search(ctx, mem.SearchRequest{
-		Query: query,
-		BotID: botID,
-		Limit: limit,
-		Filters: map[string]any{
-			"namespace": sharedMemoryNamespace,
-			"scopeId":   botID,
-			"bot_id":    botID,
-		},
-		NoStats: true,
-	})
-	if err != nil {
-		p.logger.Warn("memory search namespace failed", slog.String("namespace", sharedMemoryNamespace), slog.Any("error", err))
-		return mcpgw.BuildToolErrorResult("memory search failed"), nil
+	// Search the scopes the conversation reads, as the resolver does when
+	// it adds memory to the context.
+	var allResults []mem.MemoryItem
+	for _, scope := range p.readScopes(ctx, session, botID) {
+		resp, err := p.searcher.Search(ctx, mem.SearchRequest{
+			Query:   query,
+			BotID:   botID,
+			Limit:   limit,
+			Filters: scope.Filters(botID),
+			NoStats: true,
+		})
+		if err != nil {
+			p.logger.Warn("memory search namespace failed", slog.String("namespace", scope.Namespace), slog.Any("error", err))
+			return mcpgw.BuildToolErrorResult("memory search failed"), nil
+		}
+		allResults = append(allResults, resp.Results...)
 	}
-	allResults := make([]mem.MemoryItem, 0, len(resp.Results))
-	allResults = append(allResults, resp.Results...)
 
 	allResults = deduplicateMemoryItems(allResults)
 	sort.Slice(allResults, func(i, j int) bool {
@@ -169,6 +172,17 @@ func (p *Executor) CallTool(ctx context.Context, session mcpgw.ToolSessionContex
 	}), nil
 }
 
+// readScopes resolves the memory scopes of a session. A route that cannot
+// be confirmed is treated as a group chat without one, so only bot memory
+// is read.
+func (p *Executor) readScopes(ctx context.Context, session mcpgw.ToolSessionContext, botID string) []mem.Scope {
+	scope, ok := mcpgw.ResolveSessionScope(ctx, p.logger, p.routes, p.identities, session)
+	if !ok {
+		return mem.ReadScopes(botID, mem.NamespaceGroup, "", "")
+	}
+	return mem.ReadScopes(botID, scope.ConversationType, scope.RouteID, scope.UserID)
+}
+
 func (p *Executor) canAccessChat(ctx context.Context, chatID, channelIdentityID string) (bool, error) {
 	if p.adminChecker != nil {
 		isAdmin, err := p.adminChecker.IsAdmin(ctx, channelIdentityID)
@@ -60,7 +60,7 @@ func (f *fakeAdminChecker) IsAdmin(ctx context.Context, channelIdentityID string
 }
 
 func TestExecutor_ListTools_NilDeps(t *testing.T) {
-	exec := NewExecutor(nil, nil, nil, nil)
+	exec := NewExecutor(nil, nil, nil, nil, nil, nil)
 	tools, err := exec.ListTools(context.Background(), mcpgw.ToolSessionContext{})
 	if err != nil {
 		t.Fatal(err)
@@ -73,7 +73,7 @@ func TestExecutor_ListTools_NilDeps(t *testing.T) {
 func TestExecutor_ListTools(t *testing.T) {
 	searcher := &fakeSearcher{}
 	accessor := &fakeChatAccessor{}
-	exec := NewExecutor(nil, searcher, accessor, nil)
+	exec := NewExecutor(nil, searcher, accessor, nil, nil, nil)
 	tools, err := exec.ListTools(context.Background(), mcpgw.ToolSessionContext{})
 	if err != nil {
 		t.Fatal(err)
@@ -89,7 +89,7 @@ func TestExecutor_ListTools(t *testing.T) {
 func TestExecutor_CallTool_NotFound(t *testing.T) {
 	searcher := &fakeSearcher{}
 	accessor := &fakeChatAccessor{}
-	exec := NewExecutor(nil, searcher, accessor, nil)
+	exec := NewExecutor(nil, searcher, accessor, nil, nil, nil)
 	_, err := exec.CallTool(context.Background(), mcpgw.ToolSessionContext{BotID: "bot1"}, "other_tool", nil)
 	if err != mcpgw.ErrToolNotFound {
 		t.Errorf("expected ErrToolNotFound, got %v", err)
@@ -97,7 +97,7 @@ func TestExecutor_CallTool_NotFound(t *testing.T) {
 }
 
 func TestExecutor_CallTool_NilDeps(t *testing.T) {
-	exec := NewExecutor(nil, nil, nil, nil)
+	exec := NewExecutor(nil, nil, nil, nil, nil, nil)
 	result, err := exec.CallTool(context.Background(), mcpgw.ToolSessionContext{BotID: "bot1"}, toolSearchMemory, map[string]any{"query": "x"})
 	if err != nil {
 		t.Fatal(err)
@@ -110,7 +110,7 @@ func TestExecutor_CallTool_NilDeps(t *testing.T) {
 func TestExecutor_CallTool_NoQuery(t *testing.T) {
 	searcher := &fakeSearcher{}
 	accessor := &fakeChatAccessor{}
-	exec := NewExecutor(nil, searcher, accessor, nil)
+	exec := NewExecutor(nil, searcher, accessor, nil, nil, nil)
 	result, err := exec.CallTool(context.Background(), mcpgw.ToolSessionContext{BotID: "bot1"}, toolSearchMemory, map[string]any{})
 	if err != nil {
 		t.Fatal(err)
@@ -123,7 +123,7 @@ func TestExecutor_CallTool_NoQuery(t *testing.T) {
 func TestExecutor_CallTool_NoBotID(t *testing.T) {
 	searcher := &fakeSearcher{}
 	accessor := &fakeChatAccessor{}
-	exec := NewExecutor(nil, searcher, accessor, nil)
+	exec := NewExecutor(nil, searcher, accessor, nil, nil, nil)
 	result, err := exec.CallTool(context.Background(), mcpgw.ToolSessionContext{}, toolSearchMemory, map[string]any{"query": "q"})
 	if err != nil {
 		t.Fatal(err)
@@ -142,7 +142,7 @@ func TestExecutor_CallTool_Success_BotScope(t *testing.T) {
 		},
 	}
 	accessor := &fakeChatAccessor{}
-	exec := NewExecutor(nil, searcher, accessor, nil)
+	exec := NewExecutor(nil, searcher, accessor, nil, nil, nil)
 	ctx := context.Background()
 	session := mcpgw.ToolSessionContext{BotID: "bot1", ChatID: "bot1"}
 	result, err := exec.CallTool(ctx, session, toolSearchMemory, map[string]any{"query": "test"})
@@ -167,7 +167,7 @@ func TestExecutor_CallTool_Success_BotScope(t *testing.T) {
 func TestExecutor_CallTool_ChatNotFound(t *testing.T) {
 	searcher := &fakeSearcher{}
 	accessor := &fakeChatAccessor{getErr: errors.New("not found")}
-	exec := NewExecutor(nil, searcher, accessor, nil)
+	exec := NewExecutor(nil, searcher, accessor, nil, nil, nil)
 	session := mcpgw.ToolSessionContext{BotID: "bot1", ChatID: "chat-other"}
 	result, err := exec.CallTool(context.Background(), session, toolSearchMemory, map[string]any{"query": "q"})
 	if err != nil {
@@ -183,7 +183,7 @@ func TestExecutor_CallTool_BotMismatch(t *testing.T) {
 		chat: conversation.Conversation{BotID: "other-bot", ID: "c1"},
 	}
 	searcher := &fakeSearcher{}
-	exec := NewExecutor(nil, searcher, accessor, nil)
+	exec := NewExecutor(nil, searcher, accessor, nil, nil, nil)
 	session := mcpgw.ToolSessionContext{BotID: "bot1", ChatID: "c1"}
 	result, err := exec.CallTool(context.Background(), session, toolSearchMemory, map[string]any{"query": "q"})
 	if err != nil {
@@ -200,7 +200,7 @@ func TestExecutor_CallTool_NotParticipant(t *testing.T) {
 		participant: false,
 	}
 	searcher := &fakeSearcher{}
-	exec := NewExecutor(nil, searcher, accessor, &fakeAdminChecker{admin: false})
+	exec := NewExecutor(nil, searcher, accessor, &fakeAdminChecker{admin: false}, nil, nil)
 	session := mcpgw.ToolSessionContext{BotID: "bot1", ChatID: "c1", ChannelIdentityID: "user1"}
 	result, err := exec.CallTool(context.Background(), session, toolSearchMemory, map[string]any{"query": "q"})
 	if err != nil {
@@ -220,7 +220,7 @@ func TestExecutor_CallTool_AdminBypass(t *testing.T) {
 		participant: false,
 	}
 	admin := &fakeAdminChecker{admin: true}
-	exec := NewExecutor(nil, searcher, accessor, admin)
+	exec := NewExecutor(nil, searcher, accessor, admin, nil, nil)
 	session := mcpgw.ToolSessionContext{BotID: "bot1", ChatID: "c1", ChannelIdentityID: "admin1"}
 	result, err := exec.CallTool(context.Background(), session, toolSearchMemory, map[string]any{"query": "q"})
 	if err != nil {
@@ -241,7 +241,7 @@ func TestExecutor_CallTool_AdminBypass(t *testing.T) {
 func TestExecutor_CallTool_SearchError(t *testing.T) {
 	searcher := &fakeSearcher{err: errors.New("search failed")}
 	accessor := &fakeChatAccessor{}
-	exec := NewExecutor(nil, searcher, accessor, nil)
+	exec := NewExecutor(nil, searcher, accessor, nil, nil, nil)
 	session := mcpgw.ToolSessionContext{BotID: "bot1"}
 	result, err := exec.CallTool(context.Background(), session, toolSearchMemory, map[string]any{"query": "q"})
 	if err != nil {
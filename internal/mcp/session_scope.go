@@ -0,0 +1,71 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/memohai/memoh/internal/channel/identities"
+	"github.com/memohai/memoh/internal/channel/route"
+)
+
+// RouteReader resolves the channel route of a tool session.
+type RouteReader interface {
+	GetByID(ctx context.Context, routeID string) (route.Route, error)
+}
+
+// IdentityReader resolves the user a channel identity is linked to.
+type IdentityReader interface {
+	GetByID(ctx context.Context, channelIdentityID string) (identities.ChannelIdentity, error)
+}
+
+// SessionScope is the conversation a tool session runs in, as confirmed by
+// the stored route rather than by what the gateway sent.
+type SessionScope struct {
+	// RouteID is empty for the bot's routeless history, such as web chats.
+	RouteID          string
+	ConversationType string
+	// UserID is the linked user of the caller, or its channel identity
+	// when it is not linked.
+	UserID string
+}
+
+// ResolveSessionScope looks up the route of a session. It reports false
+// when the session names a route that is missing or belongs to another
+// bot, in which case callers must not assume any conversation.
+func ResolveSessionScope(ctx context.Context, log *slog.Logger, routes RouteReader, identityReader IdentityReader, session ToolSessionContext) (SessionScope, bool) {
+	botID := strings.TrimSpace(session.BotID)
+	scope := SessionScope{UserID: sessionUserID(ctx, identityReader, session.ChannelIdentityID)}
+	routeID := strings.TrimSpace(session.RouteID)
+	if routeID == "" {
+		return scope, true
+	}
+	if routes == nil {
+		return scope, false
+	}
+	rt, err := routes.GetByID(ctx, routeID)
+	if err != nil {
+		if log != nil {
+			log.Warn("resolve session route failed", slog.String("route_id", routeID), slog.Any("error", err))
+		}
+		return scope, false
+	}
+	if strings.TrimSpace(rt.BotID) != botID {
+		return scope, false
+	}
+	scope.RouteID = rt.ID
+	scope.ConversationType = rt.ConversationType
+	return scope, true
+}
+
+func sessionUserID(ctx context.Context, identityReader IdentityReader, channelIdentityID string) string {
+	channelIdentityID = strings.TrimSpace(channelIdentityID)
+	if channelIdentityID == "" || identityReader == nil {
+		return channelIdentityID
+	}
+	identity, err := identityReader.GetByID(ctx, channelIdentityID)
+	if err != nil || strings.TrimSpace(identity.UserID) == "" {
+		return channelIdentityID
+	}
+	return identity.UserID
+}
@@ -25,6 +25,8 @@ type ToolGatewayService struct {
 	executors []ToolExecutor
 	sources   []ToolSource
 	cacheTTL  time.Duration
+	reviewer  ToolCallReviewer
+	redactor  ToolCallRedactor
 
 	mu    sync.Mutex
 	cache map[string]cachedToolRegistry
@@ -55,6 +57,17 @@ func NewToolGatewayService(log *slog.Logger, executors []ToolExecutor, sources [
 	}
 }
 
+// SetReviewer holds tool calls for review before they run.
+func (s *ToolGatewayService) SetReviewer(reviewer ToolCallReviewer) {
+	s.reviewer = reviewer
+}
+
+// SetRedactor swaps placeholders in tool arguments for the values they stand
+// for, so tools act on real data, and redacts what tools return.
+func (s *ToolGatewayService) SetRedactor(redactor ToolCallRedactor) {
+	s.redactor = redactor
+}
+
 func (s *ToolGatewayService) InitializeResult() map[string]any {
 	return map[string]any{
 		"protocolVersion": "2025-06-18",
@@ -79,6 +92,22 @@ func (s *ToolGatewayService) ListTools(ctx context.Context, session ToolSessionC
 }
 
 func (s *ToolGatewayService) CallTool(ctx context.Context, session ToolSessionContext, payload ToolCallPayload) (map[string]any, error) {
+	result, err := s.callTool(ctx, session, payload)
+	if err != nil || s.redactor == nil {
+		return result, err
+	}
+	redacted, err := s.redactor.Redact(ctx, session.BotID, result)
+	if err != nil {
+		s.logger.Warn("tool result redaction failed", slog.String("tool", payload.Name), slog.Any("error", err))
+		return BuildToolErrorResult("tool result withheld: personal data could not be redacted"), nil
+	}
+	if out, ok := redacted.(map[string]any); ok {
+		return out, nil
+	}
+	return result, nil
+}
+
+func (s *ToolGatewayService) callTool(ctx context.Context, session ToolSessionContext, payload ToolCallPayload) (map[string]any, error) {
 	toolName := strings.TrimSpace(payload.Name)
 	if toolName == "" {
 		return nil, fmt.Errorf("tool name is required")
@@ -105,6 +134,21 @@ func (s *ToolGatewayService) CallTool(ctx context.Context, session ToolSessionCo
 	if arguments == nil {
 		arguments = map[string]any{}
 	}
+	if s.redactor != nil {
+		if rehydrated, ok := s.redactor.Rehydrate(ctx, session.BotID, arguments).(map[string]any); ok {
+			arguments = rehydrated
+		}
+	}
+	if s.reviewer != nil {
+		refusal, err := s.reviewer.ReviewToolCall(ctx, session, toolName, arguments)
+		if err != nil {
+			s.logger.Warn("tool call review failed", slog.String("tool", toolName), slog.Any("error", err))
+			return BuildToolErrorResult("tool call could not be reviewed: " + err.Error()), nil
+		}
+		if refusal != "" {
+			return BuildToolErrorResult(refusal), nil
+		}
+	}
 	result, err := executor.CallTool(ctx, session, toolName, arguments)
 	if err != nil {
 		if errors.Is(err, ErrToolNotFound) {
@@ -2,8 +2,10 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"strings"
 	"testing"
 )
 
@@ -11,6 +13,7 @@ type gatewayTestProvider struct {
 	tools      []ToolDescriptor
 	callResult map[string]map[string]any
 	callErr    map[string]error
+	lastArgs   map[string]any
 }
 
 func (p *gatewayTestProvider) ListTools(ctx context.Context, session ToolSessionContext) ([]ToolDescriptor, error) {
@@ -18,6 +21,7 @@ func (p *gatewayTestProvider) ListTools(ctx context.Context, session ToolSession
 }
 
 func (p *gatewayTestProvider) CallTool(ctx context.Context, session ToolSessionContext, toolName string, arguments map[string]any) (map[string]any, error) {
+	p.lastArgs = arguments
 	if err, ok := p.callErr[toolName]; ok {
 		return nil, err
 	}
@@ -124,3 +128,110 @@ func TestToolGatewayServiceCallToolProviderError(t *testing.T) {
 		t.Fatalf("expected isError=true for provider failure")
 	}
 }
+
+type refusingReviewer struct {
+	reviewed []string
+}
+
+func (r *refusingReviewer) ReviewToolCall(ctx context.Context, session ToolSessionContext, toolName string, arguments map[string]any) (string, error) {
+	r.reviewed = append(r.reviewed, toolName)
+	if toolName == "exec" {
+		return "denied by owner", nil
+	}
+	return "", nil
+}
+
+func TestToolGatewayServiceCallToolRefusedByReviewer(t *testing.T) {
+	provider := &gatewayTestProvider{
+		tools: []ToolDescriptor{
+			{Name: "exec", InputSchema: map[string]any{"type": "object"}},
+			{Name: "echo_tool", InputSchema: map[string]any{"type": "object"}},
+		},
+		callResult: map[string]map[string]any{
+			"exec":      {"content": []map[string]any{{"type": "text", "text": "ran"}}},
+			"echo_tool": {"content": []map[string]any{{"type": "text", "text": "ok"}}},
+		},
+		callErr: map[string]error{},
+	}
+	reviewer := &refusingReviewer{}
+	service := NewToolGatewayService(slog.Default(), []ToolExecutor{provider}, nil)
+	service.SetReviewer(reviewer)
+
+	result, err := service.CallTool(context.Background(), ToolSessionContext{BotID: "bot-1"}, ToolCallPayload{Name: "exec"})
+	if err != nil {
+		t.Fatalf("refusal should be a tool result: %v", err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr {
+		t.Fatalf("expected isError=true for refused call, got %v", result)
+	}
+
+	result, err = service.CallTool(context.Background(), ToolSessionContext{BotID: "bot-1"}, ToolCallPayload{Name: "echo_tool"})
+	if err != nil {
+		t.Fatalf("call tool should not fail: %v", err)
+	}
+	if isErr, _ := result["isError"].(bool); isErr {
+		t.Fatalf("expected approved call to run, got %v", result)
+	}
+	if len(reviewer.reviewed) != 2 {
+		t.Fatalf("expected both calls reviewed, got %v", reviewer.reviewed)
+	}
+}
+
+// swappingRedactor stands {{PHONE_1}} in for one phone number.
+type swappingRedactor struct {
+	fail bool
+}
+
+func (r swappingRedactor) Redact(ctx context.Context, botID string, value any) (any, error) {
+	if r.fail {
+		return nil, errors.New("vault unavailable")
+	}
+	return swapJSON(value, "555-0100", "{{PHONE_1}}"), nil
+}
+
+func (r swappingRedactor) Rehydrate(ctx context.Context, botID string, value any) any {
+	return swapJSON(value, "{{PHONE_1}}", "555-0100")
+}
+
+func swapJSON(value any, from, to string) any {
+	raw, _ := json.Marshal(value)
+	var out map[string]any
+	_ = json.Unmarshal([]byte(strings.ReplaceAll(string(raw), from, to)), &out)
+	return out
+}
+
+func TestToolGatewayServiceCallToolRedaction(t *testing.T) {
+	provider := &gatewayTestProvider{
+		tools: []ToolDescriptor{
+			{Name: "lookup", InputSchema: map[string]any{"type": "object"}},
+		},
+		callResult: map[string]map[string]any{
+			"lookup": {"content": []map[string]any{{"type": "text", "text": "555-0100 belongs to Jane"}}},
+		},
+		callErr: map[string]error{},
+	}
+	service := NewToolGatewayService(slog.Default(), []ToolExecutor{provider}, nil)
+	service.SetRedactor(swappingRedactor{})
+
+	payload := ToolCallPayload{Name: "lookup", Arguments: map[string]any{"number": "{{PHONE_1}}"}}
+	result, err := service.CallTool(context.Background(), ToolSessionContext{BotID: "bot-1"}, payload)
+	if err != nil {
+		t.Fatalf("call tool should not fail: %v", err)
+	}
+	if provider.lastArgs["number"] != "555-0100" {
+		t.Fatalf("expected re-hydrated arguments, got %v", provider.lastArgs)
+	}
+	encoded, _ := json.Marshal(result)
+	if strings.Contains(string(encoded), "555-0100") || !strings.Contains(string(encoded), "{{PHONE_1}} belongs to Jane") {
+		t.Fatalf("expected a redacted result, got %s", encoded)
+	}
+
+	service.SetRedactor(swappingRedactor{fail: true})
+	result, err = service.CallTool(context.Background(), ToolSessionContext{BotID: "bot-1"}, payload)
+	if err != nil {
+		t.Fatalf("redaction failure should be a tool result: %v", err)
+	}
+	if isErr, _ := result["isError"].(bool); !isErr {
+		t.Fatalf("expected the result withheld, got %v", result)
+	}
+}
@@ -16,6 +16,7 @@ type ToolSessionContext struct {
 	SessionToken      string
 	CurrentPlatform   string
 	ReplyTarget       string
+	RouteID           string
 }
 
 // ToolDescriptor is the MCP tools/list item shape used by the gateway.
@@ -38,6 +39,22 @@ type ToolSource interface {
 	CallTool(ctx context.Context, session ToolSessionContext, toolName string, arguments map[string]any) (map[string]any, error)
 }
 
+// ToolCallReviewer holds tool calls that need a human decision before they
+// run.
+type ToolCallReviewer interface {
+	// ReviewToolCall blocks until the call may run. A non-empty refusal is
+	// returned to the model instead of running the tool.
+	ReviewToolCall(ctx context.Context, session ToolSessionContext, toolName string, arguments map[string]any) (refusal string, err error)
+}
+
+// ToolCallRedactor keeps personal data on the server around tool calls:
+// arguments are re-hydrated before a tool runs, and results are redacted
+// before they go back to the model.
+type ToolCallRedactor interface {
+	Redact(ctx context.Context, botID string, value any) (any, error)
+	Rehydrate(ctx context.Context, botID string, value any) any
+}
+
 // ToolCallPayload is the MCP tools/call params payload.
 type ToolCallPayload struct {
 	Name      string         `json:"name"`
@@ -185,6 +185,29 @@ func (c *LLMClient) DetectLanguage(ctx context.Context, text string) (string, er
 	return lang, nil
 }
 
+func (c *LLMClient) Summarize(ctx context.Context, req SummarizeRequest) (SummarizeResponse, error) {
+	if len(req.Messages) == 0 {
+		return SummarizeResponse{}, fmt.Errorf("messages is required")
+	}
+	systemPrompt, userPrompt := getConversationSummaryMessages(req.PreviousSummary, strings.Join(formatMessages(req.Messages), "\n"))
+	content, err := c.callChat(ctx, []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	})
+	if err != nil {
+		return SummarizeResponse{}, err
+	}
+	var parsed SummarizeResponse
+	if err := json.Unmarshal([]byte(removeCodeBlocks(content)), &parsed); err != nil {
+		return SummarizeResponse{}, fmt.Errorf("failed to parse summary response: %w", err)
+	}
+	parsed.Summary = strings.TrimSpace(parsed.Summary)
+	if parsed.Summary == "" {
+		return SummarizeResponse{}, fmt.Errorf("llm returned an empty summary")
+	}
+	return parsed, nil
+}
+
 type chatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
@@ -2,8 +2,10 @@ package memory
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +36,38 @@ func TestLLMClientExtract(t *testing.T) {
 		t.Fatalf("unexpected response: %+v", resp)
 	}
 }
+
+func TestLLMClientSummarize(t *testing.T) {
+	t.Parallel()
+
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		prompt = req.Messages[1].Content
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"choices":[{"message":{"content":"{\"summary\":\" User plans a trip to Lisbon in May. \"}"}}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewLLMClient(nil, server.URL, "test-key", "gpt-4.1-nano-2025-04-14", 0)
+	if err != nil {
+		t.Fatalf("new llm client: %v", err)
+	}
+	resp, err := client.Summarize(context.Background(), SummarizeRequest{
+		PreviousSummary: "User is planning a trip.",
+		Messages:        []Message{{Role: "user", Content: "Lisbon, in May"}},
+	})
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	if resp.Summary != "User plans a trip to Lisbon in May." {
+		t.Fatalf("unexpected summary: %q", resp.Summary)
+	}
+	if !strings.Contains(prompt, "User is planning a trip.") || !strings.Contains(prompt, "user: Lisbon, in May") {
+		t.Fatalf("prompt is missing the previous summary or messages: %q", prompt)
+	}
+}
@@ -0,0 +1,595 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const localStoreFile = "points.json"
+
+// LocalStore is a VectorStore kept in memory and persisted to a file in its
+// directory, for deployments without a Qdrant server. Searches scan every
+// point and each write rewrites the file, which suits a few small bots.
+type LocalStore struct {
+	path             string
+	logger           *slog.Logger
+	sparseVectorName string
+
+	mu               sync.RWMutex
+	usesNamedVectors bool
+	dimensions       map[string]int
+	points           map[string]*localPoint
+}
+
+type localPoint struct {
+	ID      string                 `json:"id"`
+	Vectors map[string][]float32   `json:"vectors,omitempty"`
+	Sparse  map[string]localSparse `json:"sparse,omitempty"`
+	Payload map[string]any         `json:"payload,omitempty"`
+}
+
+type localSparse struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+type localSnapshot struct {
+	NamedVectors bool           `json:"named_vectors"`
+	Dimensions   map[string]int `json:"dimensions,omitempty"`
+	Points       []*localPoint  `json:"points"`
+}
+
+// NewLocalStore opens the store in dir, creating it when missing. vectors
+// names the dense vector of each embedding model with its dimension; without
+// it points keep a single unnamed vector. A store holding points keeps the
+// layout they were written with.
+func NewLocalStore(log *slog.Logger, dir string, vectors map[string]int, sparseVectorName string) (*LocalStore, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("local store directory is required")
+	}
+	if strings.TrimSpace(sparseVectorName) == "" {
+		sparseVectorName = sparseHashVectorName
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	store := &LocalStore{
+		path:             filepath.Join(dir, localStoreFile),
+		logger:           log.With(slog.String("store", "local")),
+		sparseVectorName: strings.TrimSpace(sparseVectorName),
+		usesNamedVectors: len(vectors) > 0,
+		dimensions:       maps.Clone(vectors),
+		points:           map[string]*localPoint{},
+	}
+	if store.dimensions == nil {
+		store.dimensions = map[string]int{}
+	}
+	if err := store.load(vectors); err != nil {
+		return nil, fmt.Errorf("load local store: %w", err)
+	}
+	return store, nil
+}
+
+func (s *LocalStore) load(vectors map[string]int) error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snapshot localSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	for _, point := range snapshot.Points {
+		if point == nil || point.ID == "" {
+			continue
+		}
+		point.Payload = normalizePayload(point.Payload)
+		s.points[point.ID] = point
+	}
+	if len(s.points) == 0 {
+		return nil
+	}
+	s.usesNamedVectors = snapshot.NamedVectors
+	s.dimensions = maps.Clone(snapshot.Dimensions)
+	if s.dimensions == nil {
+		s.dimensions = map[string]int{}
+	}
+	if !s.usesNamedVectors {
+		return nil
+	}
+	for name, dim := range vectors {
+		if existing, ok := s.dimensions[name]; ok && existing != dim {
+			return fmt.Errorf("vector %s has dimension %d, stored points use %d", name, dim, existing)
+		}
+		s.dimensions[name] = dim
+	}
+	return nil
+}
+
+func (s *LocalStore) UsesNamedVectors() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.usesNamedVectors
+}
+
+func (s *LocalStore) SparseVectorName() string {
+	return s.sparseVectorName
+}
+
+func (s *LocalStore) Upsert(ctx context.Context, points []VectorPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dimensions := maps.Clone(s.dimensions)
+	stored := make([]*localPoint, 0, len(points))
+	for _, point := range points {
+		lp := &localPoint{ID: point.ID, Payload: normalizePayload(point.Payload)}
+		if len(point.Vector) > 0 {
+			name := ""
+			if s.usesNamedVectors {
+				name = point.VectorName
+			}
+			if !s.usesNamedVectors || name != "" {
+				if dim, ok := dimensions[name]; ok && dim != len(point.Vector) {
+					return fmt.Errorf("vector %q of point %s has dimension %d, want %d", name, point.ID, len(point.Vector), dim)
+				}
+				dimensions[name] = len(point.Vector)
+				lp.Vectors = map[string][]float32{name: slices.Clone(point.Vector)}
+			}
+		}
+		if len(point.SparseIndices) > 0 && len(point.SparseValues) > 0 {
+			if len(point.SparseIndices) != len(point.SparseValues) {
+				return fmt.Errorf("sparse vector of point %s has %d indices and %d values", point.ID, len(point.SparseIndices), len(point.SparseValues))
+			}
+			sparseName := strings.TrimSpace(point.SparseVectorName)
+			if sparseName == "" {
+				sparseName = s.sparseVectorName
+			}
+			lp.Sparse = map[string]localSparse{sparseName: {
+				Indices: slices.Clone(point.SparseIndices),
+				Values:  slices.Clone(point.SparseValues),
+			}}
+		}
+		if len(lp.Vectors) == 0 && len(lp.Sparse) == 0 {
+			return fmt.Errorf("no vector data provided for point %s", point.ID)
+		}
+		stored = append(stored, lp)
+	}
+	previous := make(map[string]*localPoint, len(stored))
+	for _, lp := range stored {
+		if _, ok := previous[lp.ID]; !ok {
+			previous[lp.ID] = s.points[lp.ID]
+		}
+		s.points[lp.ID] = lp
+	}
+	previousDimensions := s.dimensions
+	s.dimensions = dimensions
+	if err := s.commitLocked(previous); err != nil {
+		s.dimensions = previousDimensions
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) Search(ctx context.Context, vector []float32, limit int, filters map[string]any, vectorName string) ([]VectorPoint, []float64, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.usesNamedVectors {
+		vectorName = ""
+	}
+	return s.rankLocked(limit, filters, false, func(p *localPoint) (float64, bool) {
+		stored, ok := p.Vectors[vectorName]
+		if !ok || len(stored) != len(vector) {
+			return 0, false
+		}
+		return cosineSimilarity(vector, stored), true
+	})
+}
+
+func (s *LocalStore) SearchSparse(ctx context.Context, indices []uint32, values []float32, limit int, filters map[string]any, withSparseVectors bool) ([]VectorPoint, []float64, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if len(indices) == 0 || len(values) == 0 {
+		return nil, nil, nil
+	}
+	query := make(map[uint32]float64, len(indices))
+	for i, index := range indices {
+		if i < len(values) {
+			query[index] += float64(values[i])
+		}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rankLocked(limit, filters, withSparseVectors, func(p *localPoint) (float64, bool) {
+		sparse, ok := p.Sparse[s.sparseVectorName]
+		if !ok {
+			return 0, false
+		}
+		score, overlap := 0.0, false
+		for i, index := range sparse.Indices {
+			if weight, ok := query[index]; ok {
+				score += weight * float64(sparse.Values[i])
+				overlap = true
+			}
+		}
+		return score, overlap
+	})
+}
+
+// rankLocked returns the limit points matching filters with the highest
+// scores. score reports false for points that cannot be scored.
+func (s *LocalStore) rankLocked(limit int, filters map[string]any, withSparseVectors bool, score func(*localPoint) (float64, bool)) ([]VectorPoint, []float64, error) {
+	type scoredPoint struct {
+		point *localPoint
+		score float64
+	}
+	var ranked []scoredPoint
+	for _, p := range s.points {
+		if !matchesFilters(p.Payload, filters) {
+			continue
+		}
+		if value, ok := score(p); ok {
+			ranked = append(ranked, scoredPoint{point: p, score: value})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].point.ID < ranked[j].point.ID
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	points := make([]VectorPoint, 0, len(ranked))
+	scores := make([]float64, 0, len(ranked))
+	for _, r := range ranked {
+		points = append(points, s.outputLocked(r.point, withSparseVectors))
+		scores = append(scores, r.score)
+	}
+	return points, scores, nil
+}
+
+func (s *LocalStore) Get(ctx context.Context, id string) (*VectorPoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.points[id]
+	if !ok {
+		return nil, nil
+	}
+	point := s.outputLocked(p, false)
+	return &point, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, id string) error {
+	return s.DeleteBatch(ctx, []string{id})
+}
+
+func (s *LocalStore) DeleteBatch(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := map[string]*localPoint{}
+	for _, id := range ids {
+		if p, ok := s.points[id]; ok {
+			previous[id] = p
+			delete(s.points, id)
+		}
+	}
+	return s.commitLocked(previous)
+}
+
+func (s *LocalStore) List(ctx context.Context, limit int, filters map[string]any, withSparseVectors bool) ([]VectorPoint, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	points, _, err := s.scroll(limit, filters, "", withSparseVectors)
+	return points, err
+}
+
+func (s *LocalStore) Scroll(ctx context.Context, limit int, filters map[string]any, offset string) ([]VectorPoint, string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return s.scroll(limit, filters, offset, false)
+}
+
+func (s *LocalStore) scroll(limit int, filters map[string]any, offset string, withSparseVectors bool) ([]VectorPoint, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.points))
+	for id, p := range s.points {
+		if id >= offset && matchesFilters(p.Payload, filters) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	next := ""
+	if len(ids) > limit {
+		next = ids[limit]
+		ids = ids[:limit]
+	}
+	points := make([]VectorPoint, 0, len(ids))
+	for _, id := range ids {
+		points = append(points, s.outputLocked(s.points[id], withSparseVectors))
+	}
+	return points, next, nil
+}
+
+func (s *LocalStore) Count(ctx context.Context, filters map[string]any) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var count uint64
+	for _, p := range s.points {
+		if matchesFilters(p.Payload, filters) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *LocalStore) DeleteAll(ctx context.Context, filters map[string]any) error {
+	if len(filters) == 0 {
+		return fmt.Errorf("delete all requires filters")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := map[string]*localPoint{}
+	for id, p := range s.points {
+		if matchesFilters(p.Payload, filters) {
+			previous[id] = p
+			delete(s.points, id)
+		}
+	}
+	return s.commitLocked(previous)
+}
+
+// outputLocked copies a stored point the way Qdrant returns it: the payload,
+// and the sparse vector when asked for.
+func (s *LocalStore) outputLocked(p *localPoint, withSparseVectors bool) VectorPoint {
+	point := VectorPoint{ID: p.ID, Payload: clonePayloadValue(p.Payload).(map[string]any)}
+	if withSparseVectors {
+		if sparse, ok := p.Sparse[s.sparseVectorName]; ok {
+			point.SparseIndices = slices.Clone(sparse.Indices)
+			point.SparseValues = slices.Clone(sparse.Values)
+		}
+	}
+	return point
+}
+
+// commitLocked writes the store to disk. When that fails the points in
+// previous, keyed by ID with nil for points that did not exist, are put back.
+func (s *LocalStore) commitLocked(previous map[string]*localPoint) error {
+	if len(previous) == 0 {
+		return nil
+	}
+	err := s.saveLocked()
+	if err == nil {
+		return nil
+	}
+	for id, p := range previous {
+		if p == nil {
+			delete(s.points, id)
+		} else {
+			s.points[id] = p
+		}
+	}
+	s.logger.Error("save local store failed", slog.Any("error", err))
+	return fmt.Errorf("save local store: %w", err)
+}
+
+func (s *LocalStore) saveLocked() error {
+	snapshot := localSnapshot{
+		NamedVectors: s.usesNamedVectors,
+		Dimensions:   s.dimensions,
+		Points:       make([]*localPoint, 0, len(s.points)),
+	}
+	for _, p := range s.points {
+		snapshot.Points = append(snapshot.Points, p)
+	}
+	sort.Slice(snapshot.Points, func(i, j int) bool {
+		return snapshot.Points[i].ID < snapshot.Points[j].ID
+	})
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), localStoreFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// normalizePayload copies payload through JSON, so values read back have
+// the types Qdrant returns: int64 for whole numbers, float64 otherwise.
+func normalizePayload(payload map[string]any) map[string]any {
+	if len(payload) == 0 {
+		return map[string]any{}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return clonePayloadValue(payload).(map[string]any)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var decoded map[string]any
+	if err := dec.Decode(&decoded); err != nil {
+		return clonePayloadValue(payload).(map[string]any)
+	}
+	return convertNumbers(decoded).(map[string]any)
+}
+
+func convertNumbers(value any) any {
+	switch typed := value.(type) {
+	case json.Number:
+		if n, err := typed.Int64(); err == nil {
+			return n
+		}
+		f, _ := typed.Float64()
+		return f
+	case map[string]any:
+		for key, item := range typed {
+			typed[key] = convertNumbers(item)
+		}
+		return typed
+	case []any:
+		for i, item := range typed {
+			typed[i] = convertNumbers(item)
+		}
+		return typed
+	default:
+		return value
+	}
+}
+
+func clonePayloadValue(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for key, item := range typed {
+			out[key] = clonePayloadValue(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(typed))
+		for i, item := range typed {
+			out[i] = clonePayloadValue(item)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// matchesFilters applies filters the way buildQdrantFilter does. Dotted
+// keys reach into nested objects, and a list matches when any item does.
+func matchesFilters(payload map[string]any, filters map[string]any) bool {
+	for key, condition := range filters {
+		value, ok := payloadField(payload, key)
+		if !ok || !matchesCondition(value, condition) {
+			return false
+		}
+	}
+	return true
+}
+
+func payloadField(payload map[string]any, key string) (any, bool) {
+	if value, ok := payload[key]; ok {
+		return value, true
+	}
+	var current any = payload
+	for _, part := range strings.Split(key, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func matchesCondition(value any, condition any) bool {
+	if items, ok := value.([]any); ok {
+		for _, item := range items {
+			if matchesCondition(item, condition) {
+				return true
+			}
+		}
+		return false
+	}
+	switch typed := condition.(type) {
+	case string:
+		s, ok := value.(string)
+		return ok && s == typed
+	case bool:
+		b, ok := value.(bool)
+		return ok && b == typed
+	case int, int64, float32, float64:
+		want, _ := toFloat(typed)
+		got, ok := toFloat(value)
+		return ok && got == want
+	case map[string]any:
+		got, isNumber := toFloat(value)
+		bounded := false
+		for _, op := range []string{"gte", "gt", "lte", "lt"} {
+			raw, ok := typed[op]
+			if !ok {
+				continue
+			}
+			bound, ok := toFloat(raw)
+			if !ok {
+				continue
+			}
+			bounded = true
+			if !isNumber {
+				return false
+			}
+			switch op {
+			case "gte":
+				ok = got >= bound
+			case "gt":
+				ok = got > bound
+			case "lte":
+				ok = got <= bound
+			case "lt":
+				ok = got < bound
+			}
+			if !ok {
+				return false
+			}
+		}
+		if bounded {
+			return true
+		}
+	}
+	s, ok := value.(string)
+	return ok && s == fmt.Sprint(condition)
+}
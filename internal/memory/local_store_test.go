@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStoreSearchAndPersist(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocalStore(slog.Default(), dir, map[string]int{"text-model": 2}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := []VectorPoint{
+		{ID: "a", Vector: []float32{1, 0}, VectorName: "text-model", SparseIndices: []uint32{1, 2}, SparseValues: []float32{1, 1}, Payload: map[string]any{"bot_id": "bot-1", "data": "likes tea", "metadata": map[string]any{"turn": 3}}},
+		{ID: "b", Vector: []float32{0.6, 0.8}, VectorName: "text-model", SparseIndices: []uint32{2}, SparseValues: []float32{2}, Payload: map[string]any{"bot_id": "bot-1", "data": "likes coffee"}},
+		{ID: "c", Vector: []float32{1, 0}, VectorName: "text-model", SparseIndices: []uint32{1}, SparseValues: []float32{5}, Payload: map[string]any{"bot_id": "bot-2", "data": "other bot"}},
+	}
+	if err := store.Upsert(ctx, points); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Upsert(ctx, []VectorPoint{{ID: "d", Vector: []float32{1, 0, 0}, VectorName: "text-model"}}); err == nil {
+		t.Fatal("expected a dimension mismatch to fail")
+	}
+
+	found, scores, err := store.Search(ctx, []float32{1, 0}, 10, map[string]any{"bot_id": "bot-1"}, "text-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].ID != "a" || found[1].ID != "b" || scores[0] != 1 {
+		t.Fatalf("unexpected dense results %+v %v", found, scores)
+	}
+
+	found, scores, err = store.SearchSparse(ctx, []uint32{2}, []float32{1}, 10, map[string]any{"bot_id": "bot-1"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 || found[0].ID != "b" || scores[0] != 2 || len(found[0].SparseIndices) != 1 {
+		t.Fatalf("unexpected sparse results %+v %v", found, scores)
+	}
+
+	reopened, err := NewLocalStore(slog.Default(), dir, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.UsesNamedVectors() {
+		t.Fatal("expected the stored layout to be kept")
+	}
+	point, err := reopened.Get(ctx, "a")
+	if err != nil || point == nil {
+		t.Fatalf("expected point a after reopening, got %v, %v", point, err)
+	}
+	if turn, _ := point.Payload["metadata"].(map[string]any)["turn"].(int64); turn != 3 {
+		t.Fatalf("expected whole numbers read back as int64, got %#v", point.Payload["metadata"])
+	}
+	if count, _ := reopened.Count(ctx, map[string]any{"metadata.turn": map[string]any{"gte": 3}}); count != 1 {
+		t.Fatalf("expected one point in range, got %d", count)
+	}
+
+	if err := reopened.DeleteAll(ctx, map[string]any{"bot_id": "bot-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.DeleteAll(ctx, nil); err == nil {
+		t.Fatal("expected delete all without filters to fail")
+	}
+	if count, _ := reopened.Count(ctx, nil); count != 1 {
+		t.Fatalf("expected one point left, got %d", count)
+	}
+	if _, err := os.Stat(filepath.Join(dir, localStoreFile)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalStoreScroll(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store, err := NewLocalStore(slog.Default(), t.TempDir(), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"3", "1", "2"} {
+		if err := store.Upsert(ctx, []VectorPoint{{ID: id, Vector: []float32{1}, Payload: map[string]any{"data": id}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var seen []string
+	offset := ""
+	for {
+		points, next, err := store.Scroll(ctx, 2, nil, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range points {
+			seen = append(seen, p.ID)
+		}
+		if next == "" {
+			break
+		}
+		offset = next
+	}
+	if len(seen) != 3 || seen[0] != "1" || seen[2] != "3" {
+		t.Fatalf("expected every point once in ID order, got %v", seen)
+	}
+}
@@ -0,0 +1,41 @@
+package memory
+
+import "context"
+
+// storedPoint is a point with all of its vectors, as copied between stores.
+type storedPoint struct {
+	ID            string
+	Vectors       map[string][]float32
+	SparseIndices []uint32
+	SparseValues  []float32
+	Payload       map[string]any
+}
+
+// CopyQdrantToPostgres copies every point of from into to with all of its
+// vectors, calling progress after each batch, and returns how many points
+// were copied. Copies overwrite points with the same ID, so an interrupted
+// copy can be run again.
+func CopyQdrantToPostgres(ctx context.Context, from *QdrantStore, to *PostgresStore, batchSize int, progress func(copied int)) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	copied := 0
+	offset := ""
+	for {
+		points, next, err := from.scrollStored(ctx, batchSize, offset)
+		if err != nil {
+			return copied, err
+		}
+		if err := to.write(ctx, points); err != nil {
+			return copied, err
+		}
+		copied += len(points)
+		if progress != nil {
+			progress(copied)
+		}
+		if next == "" {
+			return copied, nil
+		}
+		offset = next
+	}
+}
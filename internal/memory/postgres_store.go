@@ -0,0 +1,567 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	pgPointsTable  = "memory_points"
+	pgVectorsTable = "memory_point_vectors"
+	// pgMaxIndexedDimensions is the largest vector pgvector can index with
+	// HNSW. Larger vectors are searched by scanning.
+	pgMaxIndexedDimensions = 2000
+)
+
+// PostgresStore is a VectorStore in Postgres with the pgvector extension.
+// Dense vectors live in their own table, one row per vector name, and
+// keyword search runs on a tsvector of the memory text in place of the
+// sparse BM25 vectors, which are kept only for statistics.
+type PostgresStore struct {
+	pool             *pgxpool.Pool
+	logger           *slog.Logger
+	sparseVectorName string
+	usesNamedVectors bool
+	dimensions       map[string]int
+	// iterativeScan is set for pgvector 0.8 and later, which can keep
+	// scanning an index until enough points pass the filters.
+	iterativeScan bool
+}
+
+// NewPostgresStore opens the store in the tables of migration 0031 and
+// creates the vector index of each dimension when missing. vectors names the
+// dense vector of each embedding model with its dimension; without it points
+// keep a single unnamed vector of dimension. A store holding vectors keeps the
+// layout they were written with.
+func NewPostgresStore(log *slog.Logger, pool *pgxpool.Pool, dimension int, vectors map[string]int, sparseVectorName string) (*PostgresStore, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("postgres pool is required")
+	}
+	if strings.TrimSpace(sparseVectorName) == "" {
+		sparseVectorName = sparseHashVectorName
+	}
+	dimensions := map[string]int{}
+	for name, dim := range vectors {
+		dimensions[name] = dim
+	}
+	if len(vectors) == 0 && dimension > 0 {
+		dimensions[""] = dimension
+	}
+	store := &PostgresStore{
+		pool:             pool,
+		logger:           log.With(slog.String("store", "postgres")),
+		sparseVectorName: strings.TrimSpace(sparseVectorName),
+		usesNamedVectors: len(vectors) > 0,
+		dimensions:       dimensions,
+	}
+	ctx := context.Background()
+	if err := store.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+	var version string
+	if err := pool.QueryRow(ctx, "SELECT extversion FROM pg_extension WHERE extname = 'vector'").Scan(&version); err != nil {
+		return nil, err
+	}
+	store.iterativeScan = versionAtLeast(version, 0, 8)
+	var name string
+	err := pool.QueryRow(ctx, "SELECT name FROM "+pgVectorsTable+" LIMIT 1").Scan(&name)
+	switch {
+	case err == nil:
+		store.usesNamedVectors = name != ""
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, err
+	}
+	return store, nil
+}
+
+// ensureSchema checks that the migration created the tables and indexes the
+// vectors. An HNSW index needs a fixed dimension, so these indexes follow
+// the configured embedding models rather than a migration.
+func (s *PostgresStore) ensureSchema(ctx context.Context) error {
+	var exists bool
+	if err := s.pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", pgVectorsTable).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s table is missing: install the pgvector extension, then rerun migration 0031 (memoh-server migrate force 30, memoh-server migrate up)", pgVectorsTable)
+	}
+	for name, dim := range s.dimensions {
+		if dim <= 0 {
+			continue
+		}
+		if dim > pgMaxIndexedDimensions {
+			s.logger.Info("vector too large to index, searches scan", slog.String("vector", name), slog.Int("dimension", dim))
+			continue
+		}
+		// A partial index per vector name, since an index needs a fixed
+		// dimension. Rows of another dimension make this fail.
+		statement := fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS %s ON %s USING hnsw ((embedding::vector(%d)) vector_cosine_ops) WHERE name = %s",
+			pgVectorIndexName(name, dim), pgVectorsTable, dim, pgQuoteLiteral(name),
+		)
+		if _, err := s.pool.Exec(ctx, statement); err != nil {
+			return fmt.Errorf("index vector %s (dim %d); migration required: %w", name, dim, err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) UsesNamedVectors() bool {
+	return s.usesNamedVectors
+}
+
+func (s *PostgresStore) SparseVectorName() string {
+	return s.sparseVectorName
+}
+
+func (s *PostgresStore) Upsert(ctx context.Context, points []VectorPoint) error {
+	stored := make([]storedPoint, 0, len(points))
+	for _, point := range points {
+		sp := storedPoint{ID: point.ID, Payload: point.Payload}
+		if len(point.Vector) > 0 {
+			name := ""
+			if s.usesNamedVectors {
+				name = point.VectorName
+			}
+			if !s.usesNamedVectors || name != "" {
+				sp.Vectors = map[string][]float32{name: point.Vector}
+			}
+		}
+		if len(point.SparseIndices) > 0 && len(point.SparseValues) > 0 {
+			sp.SparseIndices, sp.SparseValues = point.SparseIndices, point.SparseValues
+		}
+		if len(sp.Vectors) == 0 && len(sp.SparseIndices) == 0 {
+			return fmt.Errorf("no vector data provided for point %s", point.ID)
+		}
+		stored = append(stored, sp)
+	}
+	return s.write(ctx, stored)
+}
+
+// write replaces points with all of their vectors.
+func (s *PostgresStore) write(ctx context.Context, points []storedPoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, point := range points {
+		for name, vector := range point.Vectors {
+			if dim, ok := s.dimensions[name]; ok && dim != len(vector) {
+				return fmt.Errorf("vector %q of point %s has dimension %d, want %d", name, point.ID, len(vector), dim)
+			}
+		}
+		payload, err := json.Marshal(point.Payload)
+		if err != nil {
+			return err
+		}
+		if point.Payload == nil {
+			payload = []byte("{}")
+		}
+		var indices []int64
+		var values []float32
+		if len(point.SparseIndices) > 0 {
+			if len(point.SparseIndices) != len(point.SparseValues) {
+				return fmt.Errorf("sparse vector of point %s has %d indices and %d values", point.ID, len(point.SparseIndices), len(point.SparseValues))
+			}
+			indices = make([]int64, len(point.SparseIndices))
+			for i, index := range point.SparseIndices {
+				indices[i] = int64(index)
+			}
+			values = point.SparseValues
+		}
+		batch.Queue(`INSERT INTO `+pgPointsTable+` (id, payload, sparse_indices, sparse_values)
+VALUES ($1::uuid, $2::jsonb, $3, $4)
+ON CONFLICT (id) DO UPDATE SET payload = EXCLUDED.payload, sparse_indices = EXCLUDED.sparse_indices, sparse_values = EXCLUDED.sparse_values`,
+			point.ID, string(payload), indices, values)
+		batch.Queue(`DELETE FROM `+pgVectorsTable+` WHERE point_id = $1::uuid`, point.ID)
+		for name, vector := range point.Vectors {
+			batch.Queue(`INSERT INTO `+pgVectorsTable+` (point_id, name, embedding) VALUES ($1::uuid, $2, $3::vector)`,
+				point.ID, name, pgVectorLiteral(vector))
+		}
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *PostgresStore) Search(ctx context.Context, vector []float32, limit int, filters map[string]any, vectorName string) ([]VectorPoint, []float64, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if len(vector) == 0 {
+		return nil, nil, nil
+	}
+	if !s.usesNamedVectors {
+		vectorName = ""
+	}
+	dim := len(vector)
+	// The vector name is inlined so the partial index of that name applies.
+	distance := fmt.Sprintf("v.embedding::vector(%d) <=> $1::vector(%d)", dim, dim)
+	where, args := pgFilter(filters, []any{pgVectorLiteral(vector)})
+	query := fmt.Sprintf(`SELECT p.id::text, p.payload, 1 - (%s) AS score
+FROM %s v JOIN %s p ON p.id = v.point_id
+WHERE v.name = %s AND vector_dims(v.embedding) = %d AND %s
+ORDER BY %s
+LIMIT %d`, distance, pgVectorsTable, pgPointsTable, pgQuoteLiteral(vectorName), dim, where, distance, limit)
+
+	if !s.iterativeScan {
+		rows, err := s.pool.Query(ctx, query, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return scanScoredPoints(rows, false)
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	// Without it the filters only apply to the first candidates of the
+	// index, and a bot with few memories may find none.
+	if _, err := tx.Exec(ctx, "SET LOCAL hnsw.iterative_scan = strict_order"); err != nil {
+		return nil, nil, err
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scanScoredPoints(rows, false)
+}
+
+// SearchSparse scans for points sharing indices with the query. The memory
+// service uses SearchKeyword instead; this keeps the store a complete
+// VectorStore.
+func (s *PostgresStore) SearchSparse(ctx context.Context, indices []uint32, values []float32, limit int, filters map[string]any, withSparseVectors bool) ([]VectorPoint, []float64, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if len(indices) == 0 || len(values) == 0 {
+		return nil, nil, nil
+	}
+	queryIndices := make([]int64, len(indices))
+	for i, index := range indices {
+		queryIndices[i] = int64(index)
+	}
+	where, args := pgFilter(filters, []any{queryIndices, values})
+	query := fmt.Sprintf(`SELECT id, payload, score, sparse_indices, sparse_values FROM (
+  SELECT p.id::text AS id, p.payload, p.sparse_indices, p.sparse_values,
+    (SELECT SUM(pv.v * q.v)::float8
+       FROM unnest(p.sparse_indices, p.sparse_values) AS pv(i, v)
+       JOIN unnest($1::bigint[], $2::real[]) AS q(i, v) ON q.i = pv.i) AS score
+  FROM %s p
+  WHERE p.sparse_indices && $1::bigint[] AND %s
+) ranked
+ORDER BY score DESC, id
+LIMIT %d`, pgPointsTable, where, limit)
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	points, scores, err := scanScoredPoints(rows, true)
+	if err != nil || withSparseVectors {
+		return points, scores, err
+	}
+	for i := range points {
+		points[i].SparseIndices, points[i].SparseValues = nil, nil
+	}
+	return points, scores, nil
+}
+
+// SearchKeyword implements KeywordSearcher. A point matches when its text
+// shares any word with query, ranked by ts_rank_cd.
+func (s *PostgresStore) SearchKeyword(ctx context.Context, query string, limit int, filters map[string]any) ([]VectorPoint, []float64, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, nil, nil
+	}
+	where, args := pgFilter(filters, []any{query})
+	sql := fmt.Sprintf(`WITH query AS (SELECT replace(plainto_tsquery('simple', $1)::text, '&', '|')::tsquery AS q)
+SELECT p.id::text, p.payload, ts_rank_cd(p.search_text, query.q)::float8 AS score
+FROM %s p, query
+WHERE p.search_text @@ query.q AND %s
+ORDER BY score DESC, p.id
+LIMIT %d`, pgPointsTable, where, limit)
+	rows, err := s.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return scanScoredPoints(rows, false)
+}
+
+func (s *PostgresStore) Get(ctx context.Context, id string) (*VectorPoint, error) {
+	var raw []byte
+	err := s.pool.QueryRow(ctx, "SELECT payload FROM "+pgPointsTable+" WHERE id = $1::uuid", id).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	payload, err := decodePayload(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &VectorPoint{ID: id, Payload: payload}, nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, id string) error {
+	return s.DeleteBatch(ctx, []string{id})
+}
+
+func (s *PostgresStore) DeleteBatch(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.pool.Exec(ctx, "DELETE FROM "+pgPointsTable+" WHERE id = ANY($1::uuid[])", ids)
+	return err
+}
+
+func (s *PostgresStore) List(ctx context.Context, limit int, filters map[string]any, withSparseVectors bool) ([]VectorPoint, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	points, _, err := s.scroll(ctx, limit, filters, "", withSparseVectors)
+	return points, err
+}
+
+func (s *PostgresStore) Scroll(ctx context.Context, limit int, filters map[string]any, offset string) ([]VectorPoint, string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return s.scroll(ctx, limit, filters, offset, false)
+}
+
+func (s *PostgresStore) scroll(ctx context.Context, limit int, filters map[string]any, offset string, withSparseVectors bool) ([]VectorPoint, string, error) {
+	var args []any
+	start := "TRUE"
+	if offset != "" {
+		args = append(args, offset)
+		start = "p.id >= $1::uuid"
+	}
+	where, args := pgFilter(filters, args)
+	query := fmt.Sprintf(`SELECT p.id::text, p.payload, 0::float8, p.sparse_indices, p.sparse_values
+FROM %s p
+WHERE %s AND %s
+ORDER BY p.id
+LIMIT %d`, pgPointsTable, start, where, limit+1)
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	points, _, err := scanScoredPoints(rows, true)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if len(points) > limit {
+		next = points[limit].ID
+		points = points[:limit]
+	}
+	if !withSparseVectors {
+		for i := range points {
+			points[i].SparseIndices, points[i].SparseValues = nil, nil
+		}
+	}
+	return points, next, nil
+}
+
+func (s *PostgresStore) Count(ctx context.Context, filters map[string]any) (uint64, error) {
+	where, args := pgFilter(filters, nil)
+	var count int64
+	if err := s.pool.QueryRow(ctx, "SELECT count(*) FROM "+pgPointsTable+" p WHERE "+where, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return uint64(count), nil
+}
+
+func (s *PostgresStore) DeleteAll(ctx context.Context, filters map[string]any) error {
+	if len(filters) == 0 {
+		return fmt.Errorf("delete all requires filters")
+	}
+	where, args := pgFilter(filters, nil)
+	_, err := s.pool.Exec(ctx, "DELETE FROM "+pgPointsTable+" p WHERE "+where, args...)
+	return err
+}
+
+// scanScoredPoints reads rows of id, payload and score, followed by the
+// sparse vector when withSparse is set.
+func scanScoredPoints(rows pgx.Rows, withSparse bool) ([]VectorPoint, []float64, error) {
+	defer rows.Close()
+	var points []VectorPoint
+	var scores []float64
+	for rows.Next() {
+		var (
+			id      string
+			raw     []byte
+			score   *float64
+			indices []int64
+			values  []float32
+		)
+		dest := []any{&id, &raw, &score}
+		if withSparse {
+			dest = append(dest, &indices, &values)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+		payload, err := decodePayload(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		point := VectorPoint{ID: id, Payload: payload, SparseValues: values}
+		if len(indices) > 0 {
+			point.SparseIndices = make([]uint32, len(indices))
+			for i, index := range indices {
+				point.SparseIndices[i] = uint32(index)
+			}
+		}
+		points = append(points, point)
+		if score != nil {
+			scores = append(scores, *score)
+		} else {
+			scores = append(scores, 0)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return points, scores, nil
+}
+
+// pgFilter returns a condition on the payload of p applying filters the way
+// buildQdrantFilter does, with its arguments appended to args. Dotted keys
+// reach into nested objects. Without filters the condition is TRUE.
+func pgFilter(filters map[string]any, args []any) (string, []any) {
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	conditions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		path := strings.Split(key, ".")
+		value := filters[key]
+		if bounds, ok := value.(map[string]any); ok {
+			var ranges []string
+			for _, op := range []string{"gte", "gt", "lte", "lt"} {
+				raw, ok := bounds[op]
+				if !ok {
+					continue
+				}
+				bound, ok := toFloat(raw)
+				if !ok {
+					continue
+				}
+				args = append(args, path, bound)
+				field := fmt.Sprintf("$%d::text[]", len(args)-1)
+				ranges = append(ranges, fmt.Sprintf(
+					"(CASE WHEN jsonb_typeof(p.payload #> %s) = 'number' THEN (p.payload #>> %s)::float8 END) %s $%d::float8",
+					field, field, pgRangeOperators[op], len(args),
+				))
+			}
+			if len(ranges) > 0 {
+				conditions = append(conditions, ranges...)
+				continue
+			}
+		}
+		switch value.(type) {
+		case string, bool, int, int64, float32, float64:
+		default:
+			value = fmt.Sprint(value)
+		}
+		var contained any = value
+		for i := len(path) - 1; i >= 0; i-- {
+			contained = map[string]any{path[i]: contained}
+		}
+		encoded, _ := json.Marshal(contained)
+		args = append(args, string(encoded))
+		conditions = append(conditions, fmt.Sprintf("p.payload @> $%d::jsonb", len(args)))
+	}
+	if len(conditions) == 0 {
+		return "TRUE", args
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+var pgRangeOperators = map[string]string{"gte": ">=", "gt": ">", "lte": "<=", "lt": "<"}
+
+// pgVectorLiteral formats vector in the text form of the pgvector type.
+func pgVectorLiteral(vector []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func pgQuoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// pgVectorIndexName derives an index name from a vector name, which may be
+// any model ID.
+func pgVectorIndexName(name string, dim int) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return fmt.Sprintf("%s_%08x_%d_idx", pgVectorsTable, h.Sum32(), dim)
+}
+
+// versionAtLeast reports whether a "major.minor.patch" version is at least
+// major.minor.
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	gotMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
+
+// decodePayload decodes a JSONB payload with whole numbers as int64, as
+// Qdrant returns them.
+func decodePayload(raw []byte) (map[string]any, error) {
+	if len(raw) == 0 {
+		return map[string]any{}, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var payload map[string]any
+	if err := dec.Decode(&payload); err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return map[string]any{}, nil
+	}
+	return convertNumbers(payload).(map[string]any), nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestPgFilter(t *testing.T) {
+	t.Parallel()
+
+	where, args := pgFilter(map[string]any{
+		"bot_id":        "bot-1",
+		"metadata.turn": map[string]any{"gte": 3, "lt": 10.5},
+		"pinned":        true,
+	}, []any{"query"})
+	want := "p.payload @> $2::jsonb" +
+		" AND (CASE WHEN jsonb_typeof(p.payload #> $3::text[]) = 'number' THEN (p.payload #>> $3::text[])::float8 END) >= $4::float8" +
+		" AND (CASE WHEN jsonb_typeof(p.payload #> $5::text[]) = 'number' THEN (p.payload #>> $5::text[])::float8 END) < $6::float8" +
+		" AND p.payload @> $7::jsonb"
+	if where != want {
+		t.Fatalf("unexpected condition\n got: %s\nwant: %s", where, want)
+	}
+	if len(args) != 7 || args[0] != "query" || args[1] != `{"bot_id":"bot-1"}` || args[3] != 3.0 || args[6] != `{"pinned":true}` {
+		t.Fatalf("unexpected args %#v", args)
+	}
+	if path, ok := args[2].([]string); !ok || len(path) != 2 || path[0] != "metadata" || path[1] != "turn" {
+		t.Fatalf("expected the dotted key split into a path, got %#v", args[2])
+	}
+
+	if where, args := pgFilter(nil, nil); where != "TRUE" || len(args) != 0 {
+		t.Fatalf("expected no condition, got %s %v", where, args)
+	}
+}
+
+func TestPgVectorLiteral(t *testing.T) {
+	t.Parallel()
+
+	if got := pgVectorLiteral([]float32{1, -0.5, 0.1}); got != "[1,-0.5,0.1]" {
+		t.Fatalf("unexpected literal %s", got)
+	}
+	if got := pgQuoteLiteral("model's"); got != "'model''s'" {
+		t.Fatalf("unexpected quoted literal %s", got)
+	}
+	if !versionAtLeast("0.8.0", 0, 8) || !versionAtLeast("1.0", 0, 8) || versionAtLeast("0.7.4", 0, 8) {
+		t.Fatal("unexpected version comparison")
+	}
+}
+
+// keywordStore is a local store with a keyword search that records its
+// queries and returns every point of the bot.
+type keywordStore struct {
+	*LocalStore
+	queries []string
+}
+
+func (k *keywordStore) SearchKeyword(ctx context.Context, query string, limit int, filters map[string]any) ([]VectorPoint, []float64, error) {
+	k.queries = append(k.queries, query)
+	points, err := k.List(ctx, limit, filters, false)
+	scores := make([]float64, len(points))
+	return points, scores, err
+}
+
+func TestSearchUsesKeywordSearch(t *testing.T) {
+	t.Parallel()
+
+	local, err := NewLocalStore(slog.Default(), t.TempDir(), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &keywordStore{LocalStore: local}
+	if err := store.Upsert(context.Background(), []VectorPoint{
+		{ID: "a", SparseIndices: []uint32{1}, SparseValues: []float32{1}, Payload: map[string]any{"bot_id": "bot-1", "data": "likes tea"}},
+		{ID: "b", SparseIndices: []uint32{1}, SparseValues: []float32{1}, Payload: map[string]any{"bot_id": "bot-2", "data": "likes coffee"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{store: store, logger: slog.Default()}
+
+	resp, err := s.Search(context.Background(), SearchRequest{Query: "tea", BotID: "bot-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Memory != "likes tea" {
+		t.Fatalf("unexpected results %+v", resp.Results)
+	}
+	if len(store.queries) != 1 || store.queries[0] != "tea" {
+		t.Fatalf("expected the query text searched directly, got %v", store.queries)
+	}
+}
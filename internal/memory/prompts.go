@@ -156,6 +156,28 @@ Before finalizing, verify the value is one of the allowed codes.`
 	return systemPrompt, userPrompt
 }
 
+func getConversationSummaryMessages(previousSummary, parsedMessages string) (string, string) {
+	systemPrompt := fmt.Sprintf(`You are a Conversation Summarizer. Older turns of a long conversation are being removed from the assistant's context window, and your summary replaces them.
+
+Guidelines:
+1. Merge the existing summary (if any) with the new messages into one updated summary.
+2. Keep facts, decisions, open questions, commitments, names, numbers and user preferences. Drop greetings and small talk.
+3. Note who said what when it matters (user vs assistant).
+4. Prefer the newer statement when messages contradict each other or the existing summary.
+5. Write in the dominant language of the conversation. Do not translate.
+6. Keep the summary under 400 words, in concise prose or short bullet points.
+7. Return a JSON object with a single key "summary" whose value is a string.
+8. DO NOT RETURN ANYTHING ELSE OTHER THAN THE JSON FORMAT.
+9. DO NOT ADD ANY ADDITIONAL TEXT OR CODEBLOCK IN THE JSON FIELDS WHICH MAKE IT INVALID SUCH AS "%s" OR "%s".`, "```json", "```")
+
+	summary := strings.TrimSpace(previousSummary)
+	if summary == "" {
+		summary = "(none)"
+	}
+	userPrompt := fmt.Sprintf("Existing summary:\n%s\n\nNew messages to fold in:\n%s", summary, parsedMessages)
+	return systemPrompt, userPrompt
+}
+
 func removeCodeBlocks(text string) string {
 	return strings.ReplaceAll(strings.ReplaceAll(text, "```json", ""), "```", "")
 }
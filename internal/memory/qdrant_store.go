@@ -33,16 +33,6 @@ type QdrantStore struct {
 	usesSparseVectors bool
 }
 
-type qdrantPoint struct {
-	ID               string         `json:"id"`
-	Vector           []float32      `json:"vector"`
-	VectorName       string         `json:"vector_name,omitempty"`
-	SparseIndices    []uint32       `json:"sparse_indices,omitempty"`
-	SparseValues     []float32      `json:"sparse_values,omitempty"`
-	SparseVectorName string         `json:"sparse_vector_name,omitempty"`
-	Payload          map[string]any `json:"payload,omitempty"`
-}
-
 func NewQdrantStore(log *slog.Logger, baseURL, apiKey, collection string, dimension int, sparseVectorName string, timeout time.Duration) (*QdrantStore, error) {
 	host, port, useTLS, err := parseQdrantEndpoint(baseURL)
 	if err != nil {
@@ -89,6 +79,14 @@ func NewQdrantStore(log *slog.Logger, baseURL, apiKey, collection string, dimens
 	return store, nil
 }
 
+func (s *QdrantStore) UsesNamedVectors() bool {
+	return s.usesNamedVectors
+}
+
+func (s *QdrantStore) SparseVectorName() string {
+	return s.sparseVectorName
+}
+
 func (s *QdrantStore) NewSibling(collection string, dimension int) (*QdrantStore, error) {
 	return NewQdrantStore(s.logger, s.baseURL, s.apiKey, collection, dimension, s.sparseVectorName, s.timeout)
 }
@@ -140,7 +138,7 @@ func NewQdrantStoreWithVectors(log *slog.Logger, baseURL, apiKey, collection str
 	return store, nil
 }
 
-func (s *QdrantStore) Upsert(ctx context.Context, points []qdrantPoint) error {
+func (s *QdrantStore) Upsert(ctx context.Context, points []VectorPoint) error {
 	if len(points) == 0 {
 		return nil
 	}
@@ -191,7 +189,7 @@ func (s *QdrantStore) Upsert(ctx context.Context, points []qdrantPoint) error {
 	return err
 }
 
-func (s *QdrantStore) Search(ctx context.Context, vector []float32, limit int, filters map[string]any, vectorName string) ([]qdrantPoint, []float64, error) {
+func (s *QdrantStore) Search(ctx context.Context, vector []float32, limit int, filters map[string]any, vectorName string) ([]VectorPoint, []float64, error) {
 	if limit <= 0 {
 		limit = 10
 	}
@@ -212,10 +210,10 @@ func (s *QdrantStore) Search(ctx context.Context, vector []float32, limit int, f
 		return nil, nil, err
 	}
 
-	points := make([]qdrantPoint, 0, len(results))
+	points := make([]VectorPoint, 0, len(results))
 	scores := make([]float64, 0, len(results))
 	for _, scored := range results {
-		points = append(points, qdrantPoint{
+		points = append(points, VectorPoint{
 			ID:      pointIDToString(scored.GetId()),
 			Payload: valueMapToInterface(scored.GetPayload()),
 		})
@@ -224,7 +222,7 @@ func (s *QdrantStore) Search(ctx context.Context, vector []float32, limit int, f
 	return points, scores, nil
 }
 
-func (s *QdrantStore) SearchSparse(ctx context.Context, indices []uint32, values []float32, limit int, filters map[string]any, withSparseVectors bool) ([]qdrantPoint, []float64, error) {
+func (s *QdrantStore) SearchSparse(ctx context.Context, indices []uint32, values []float32, limit int, filters map[string]any, withSparseVectors bool) ([]VectorPoint, []float64, error) {
 	if limit <= 0 {
 		limit = 10
 	}
@@ -251,10 +249,10 @@ func (s *QdrantStore) SearchSparse(ctx context.Context, indices []uint32, values
 	if err != nil {
 		return nil, nil, err
 	}
-	points := make([]qdrantPoint, 0, len(results))
+	points := make([]VectorPoint, 0, len(results))
 	scores := make([]float64, 0, len(results))
 	for _, scored := range results {
-		p := qdrantPoint{
+		p := VectorPoint{
 			ID:      pointIDToString(scored.GetId()),
 			Payload: valueMapToInterface(scored.GetPayload()),
 		}
@@ -267,49 +265,7 @@ func (s *QdrantStore) SearchSparse(ctx context.Context, indices []uint32, values
 	return points, scores, nil
 }
 
-func (s *QdrantStore) SearchBySources(ctx context.Context, vector []float32, limit int, filters map[string]any, sources []string, vectorName string) (map[string][]qdrantPoint, map[string][]float64, error) {
-	pointsBySource := make(map[string][]qdrantPoint, len(sources))
-	scoresBySource := make(map[string][]float64, len(sources))
-	if len(sources) == 0 {
-		return pointsBySource, scoresBySource, nil
-	}
-	for _, source := range sources {
-		merged := cloneFilters(filters)
-		if source != "" {
-			merged["source"] = source
-		}
-		points, scores, err := s.Search(ctx, vector, limit, merged, vectorName)
-		if err != nil {
-			return nil, nil, err
-		}
-		pointsBySource[source] = points
-		scoresBySource[source] = scores
-	}
-	return pointsBySource, scoresBySource, nil
-}
-
-func (s *QdrantStore) SearchSparseBySources(ctx context.Context, indices []uint32, values []float32, limit int, filters map[string]any, sources []string, withSparseVectors bool) (map[string][]qdrantPoint, map[string][]float64, error) {
-	pointsBySource := make(map[string][]qdrantPoint, len(sources))
-	scoresBySource := make(map[string][]float64, len(sources))
-	if len(sources) == 0 {
-		return pointsBySource, scoresBySource, nil
-	}
-	for _, source := range sources {
-		merged := cloneFilters(filters)
-		if source != "" {
-			merged["source"] = source
-		}
-		points, scores, err := s.SearchSparse(ctx, indices, values, limit, merged, withSparseVectors)
-		if err != nil {
-			return nil, nil, err
-		}
-		pointsBySource[source] = points
-		scoresBySource[source] = scores
-	}
-	return pointsBySource, scoresBySource, nil
-}
-
-func (s *QdrantStore) Get(ctx context.Context, id string) (*qdrantPoint, error) {
+func (s *QdrantStore) Get(ctx context.Context, id string) (*VectorPoint, error) {
 	result, err := s.client.Get(ctx, &qdrant.GetPoints{
 		CollectionName: s.collection,
 		Ids:            []*qdrant.PointId{qdrant.NewIDUUID(id)},
@@ -322,7 +278,7 @@ func (s *QdrantStore) Get(ctx context.Context, id string) (*qdrantPoint, error)
 		return nil, nil
 	}
 	point := result[0]
-	return &qdrantPoint{
+	return &VectorPoint{
 		ID:      pointIDToString(point.GetId()),
 		Payload: valueMapToInterface(point.GetPayload()),
 	}, nil
@@ -353,7 +309,7 @@ func (s *QdrantStore) DeleteBatch(ctx context.Context, ids []string) error {
 	return err
 }
 
-func (s *QdrantStore) List(ctx context.Context, limit int, filters map[string]any, withSparseVectors bool) ([]qdrantPoint, error) {
+func (s *QdrantStore) List(ctx context.Context, limit int, filters map[string]any, withSparseVectors bool) ([]VectorPoint, error) {
 	if limit <= 0 {
 		limit = 100
 	}
@@ -372,9 +328,9 @@ func (s *QdrantStore) List(ctx context.Context, limit int, filters map[string]an
 		return nil, err
 	}
 
-	result := make([]qdrantPoint, 0, len(points))
+	result := make([]VectorPoint, 0, len(points))
 	for _, point := range points {
-		p := qdrantPoint{
+		p := VectorPoint{
 			ID:      pointIDToString(point.GetId()),
 			Payload: valueMapToInterface(point.GetPayload()),
 		}
@@ -386,29 +342,83 @@ func (s *QdrantStore) List(ctx context.Context, limit int, filters map[string]an
 	return result, nil
 }
 
-func (s *QdrantStore) Scroll(ctx context.Context, limit int, filters map[string]any, offset *qdrant.PointId) ([]qdrantPoint, *qdrant.PointId, error) {
+func (s *QdrantStore) Scroll(ctx context.Context, limit int, filters map[string]any, offset string) ([]VectorPoint, string, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 	filter := buildQdrantFilter(filters)
+	var start *qdrant.PointId
+	if offset != "" {
+		start = qdrant.NewIDUUID(offset)
+	}
 	points, nextOffset, err := s.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
 		CollectionName: s.collection,
 		Limit:          qdrant.PtrOf(uint32(limit)),
 		Filter:         filter,
-		Offset:         offset,
+		Offset:         start,
 		WithPayload:    qdrant.NewWithPayload(true),
 	})
 	if err != nil {
-		return nil, nil, err
+		return nil, "", err
 	}
-	result := make([]qdrantPoint, 0, len(points))
+	result := make([]VectorPoint, 0, len(points))
 	for _, point := range points {
-		result = append(result, qdrantPoint{
+		result = append(result, VectorPoint{
 			ID:      pointIDToString(point.GetId()),
 			Payload: valueMapToInterface(point.GetPayload()),
 		})
 	}
-	return result, nextOffset, nil
+	return result, pointIDToString(nextOffset), nil
+}
+
+// scrollStored pages through points like Scroll, with all of their vectors.
+func (s *QdrantStore) scrollStored(ctx context.Context, limit int, offset string) ([]storedPoint, string, error) {
+	var start *qdrant.PointId
+	if offset != "" {
+		start = qdrant.NewIDUUID(offset)
+	}
+	points, nextOffset, err := s.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+		CollectionName: s.collection,
+		Limit:          qdrant.PtrOf(uint32(limit)),
+		Offset:         start,
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(true),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	result := make([]storedPoint, 0, len(points))
+	for _, point := range points {
+		stored := storedPoint{
+			ID:      pointIDToString(point.GetId()),
+			Vectors: map[string][]float32{},
+			Payload: valueMapToInterface(point.GetPayload()),
+		}
+		vectors := point.GetVectors()
+		if single := vectors.GetVector(); single != nil {
+			stored.Vectors[""] = denseFromVectorOutput(single)
+		}
+		for name, vecOut := range vectors.GetVectors().GetVectors() {
+			if vecOut.GetSparse() != nil || len(vecOut.GetIndices().GetData()) > 0 {
+				if name == s.sparseVectorName {
+					stored.SparseIndices, stored.SparseValues = extractSparseFromVectorOutput(vecOut)
+				}
+				continue
+			}
+			if dense := denseFromVectorOutput(vecOut); len(dense) > 0 {
+				stored.Vectors[name] = dense
+			}
+		}
+		result = append(result, stored)
+	}
+	return result, pointIDToString(nextOffset), nil
+}
+
+func denseFromVectorOutput(vecOut *qdrant.VectorOutput) []float32 {
+	if dense := vecOut.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+	return vecOut.GetData()
 }
 
 // extractSparseVector extracts sparse indices and values from a VectorsOutput.
@@ -671,17 +681,6 @@ func buildQdrantFilter(filters map[string]any) *qdrant.Filter {
 	}
 }
 
-func cloneFilters(filters map[string]any) map[string]any {
-	if len(filters) == 0 {
-		return map[string]any{}
-	}
-	clone := make(map[string]any, len(filters))
-	for key, value := range filters {
-		clone[key] = value
-	}
-	return clone
-}
-
 func buildQdrantCondition(key string, value any) *qdrant.Condition {
 	switch typed := value.(type) {
 	case string:
@@ -0,0 +1,55 @@
+package memory
+
+import "strings"
+
+// Memory namespaces. Bot-wide memory is scoped by bot id, user memory by
+// linked user id (or channel identity id for unlinked senders) and group
+// memory by channel route id.
+const (
+	NamespaceBot   = "bot"
+	NamespaceUser  = "user"
+	NamespaceGroup = "group"
+)
+
+// Scope is one layer of bot memory.
+type Scope struct {
+	Namespace string
+	ScopeID   string
+}
+
+// ReadScopes returns the scopes a conversation reads, most specific first:
+// the group route in group chats, the sender in direct chats, then the bot.
+// User memory is private: it is only read in direct conversations, so one
+// member's facts never surface in a group chat. The first scope is the one
+// the conversation is remembered in.
+func ReadScopes(botID, conversationType, routeID, userScopeID string) []Scope {
+	scopes := make([]Scope, 0, 2)
+	if IsGroupConversation(conversationType) {
+		if routeID = strings.TrimSpace(routeID); routeID != "" {
+			scopes = append(scopes, Scope{Namespace: NamespaceGroup, ScopeID: routeID})
+		}
+	} else if userScopeID = strings.TrimSpace(userScopeID); userScopeID != "" {
+		scopes = append(scopes, Scope{Namespace: NamespaceUser, ScopeID: userScopeID})
+	}
+	return append(scopes, Scope{Namespace: NamespaceBot, ScopeID: strings.TrimSpace(botID)})
+}
+
+// Filters selects the memories of the scope within a bot.
+func (s Scope) Filters(botID string) map[string]any {
+	return map[string]any{
+		"namespace": s.Namespace,
+		"scopeId":   s.ScopeID,
+		"bot_id":    botID,
+	}
+}
+
+// IsGroupConversation reports whether a channel conversation type has more
+// than one human participant. An empty type is a direct conversation.
+func IsGroupConversation(conversationType string) bool {
+	switch strings.ToLower(strings.TrimSpace(conversationType)) {
+	case "", "p2p", "private", "direct":
+		return false
+	default:
+		return true
+	}
+}
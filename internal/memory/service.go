@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/qdrant/go-client/qdrant"
 
 	"github.com/memohai/memoh/internal/embeddings"
 )
@@ -20,15 +19,16 @@ import (
 type Service struct {
 	llm                      LLM
 	embedder                 embeddings.Embedder
-	store                    *QdrantStore
+	store                    VectorStore
 	resolver                 *embeddings.Resolver
 	bm25                     *BM25Indexer
+	redactor                 Redactor
 	logger                   *slog.Logger
 	defaultTextModelID       string
 	defaultMultimodalModelID string
 }
 
-func NewService(log *slog.Logger, llm LLM, embedder embeddings.Embedder, store *QdrantStore, resolver *embeddings.Resolver, bm25 *BM25Indexer, defaultTextModelID, defaultMultimodalModelID string) *Service {
+func NewService(log *slog.Logger, llm LLM, embedder embeddings.Embedder, store VectorStore, resolver *embeddings.Resolver, bm25 *BM25Indexer, defaultTextModelID, defaultMultimodalModelID string) *Service {
 	return &Service{
 		llm:                      llm,
 		embedder:                 embedder,
@@ -41,6 +41,29 @@ func NewService(log *slog.Logger, llm LLM, embedder embeddings.Embedder, store *
 	}
 }
 
+// SetRedactor enables PII redaction of the text the service extracts,
+// embeds, indexes and searches with. Stored memories keep the placeholders.
+func (s *Service) SetRedactor(redactor Redactor) {
+	s.redactor = redactor
+}
+
+// redactTexts redacts texts for botID. Without a redactor or a bot they are
+// returned as they are.
+func (s *Service) redactTexts(ctx context.Context, botID string, texts []string) ([]string, error) {
+	if s.redactor == nil || strings.TrimSpace(botID) == "" {
+		return texts, nil
+	}
+	redacted, err := s.redactor.Redact(ctx, botID, texts)
+	if err != nil {
+		return nil, fmt.Errorf("redact personal data: %w", err)
+	}
+	out, ok := redacted.([]string)
+	if !ok || len(out) != len(texts) {
+		return nil, fmt.Errorf("redact personal data: unexpected result %T", redacted)
+	}
+	return out, nil
+}
+
 func (s *Service) Add(ctx context.Context, req AddRequest) (SearchResponse, error) {
 	if req.Message == "" && len(req.Messages) == 0 {
 		return SearchResponse{}, fmt.Errorf("message or messages is required")
@@ -51,7 +74,12 @@ func (s *Service) Add(ctx context.Context, req AddRequest) (SearchResponse, erro
 
 	messages := normalizeMessages(req)
 	filters := buildFilters(req)
-	ctx = WithBotID(ctx, resolveBotID(req.BotID, filters))
+	botID := resolveBotID(req.BotID, filters)
+	ctx = WithBotID(ctx, botID)
+	messages, err := s.redactMessages(ctx, botID, messages)
+	if err != nil {
+		return SearchResponse{}, err
+	}
 
 	embeddingEnabled := req.EmbeddingEnabled != nil && *req.EmbeddingEnabled
 	if req.Infer != nil && !*req.Infer {
@@ -140,10 +168,16 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 		return SearchResponse{}, fmt.Errorf("query is required")
 	}
 	if s.store == nil {
-		return SearchResponse{}, fmt.Errorf("qdrant store not configured")
+		return SearchResponse{}, fmt.Errorf("vector store not configured")
 	}
 	filters := buildSearchFilters(req)
-	ctx = WithBotID(ctx, resolveBotID(req.BotID, filters))
+	botID := resolveBotID(req.BotID, filters)
+	ctx = WithBotID(ctx, botID)
+	query, err := s.redactTexts(ctx, botID, []string{req.Query})
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	req.Query = query[0]
 	modality := ""
 	if raw, ok := filters["modality"].(string); ok {
 		modality = strings.ToLower(strings.TrimSpace(raw))
@@ -181,7 +215,7 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 			}
 			return SearchResponse{Results: results}, nil
 		}
-		pointsBySource, scoresBySource, err := s.store.SearchBySources(ctx, result.Embedding, req.Limit, filters, req.Sources, vectorName)
+		pointsBySource, scoresBySource, err := searchBySources(ctx, s.store, result.Embedding, req.Limit, filters, req.Sources, vectorName)
 		if err != nil {
 			return SearchResponse{}, err
 		}
@@ -213,7 +247,7 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 			}
 			return SearchResponse{Results: results}, nil
 		}
-		pointsBySource, scoresBySource, err := s.store.SearchBySources(ctx, vector, req.Limit, filters, req.Sources, vectorName)
+		pointsBySource, scoresBySource, err := searchBySources(ctx, s.store, vector, req.Limit, filters, req.Sources, vectorName)
 		if err != nil {
 			return SearchResponse{}, err
 		}
@@ -221,6 +255,9 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 		return SearchResponse{Results: results}, nil
 	}
 
+	if keyword, ok := s.store.(KeywordSearcher); ok {
+		return s.searchKeyword(ctx, keyword, req, filters)
+	}
 	if s.bm25 == nil {
 		return SearchResponse{}, fmt.Errorf("bm25 indexer not configured")
 	}
@@ -252,14 +289,14 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 		}
 		return SearchResponse{Results: results}, nil
 	}
-	pointsBySource, scoresBySource, err := s.store.SearchSparseBySources(ctx, indices, values, req.Limit, filters, req.Sources, wantStats)
+	pointsBySource, scoresBySource, err := searchSparseBySources(ctx, s.store, indices, values, req.Limit, filters, req.Sources, wantStats)
 	if err != nil {
 		return SearchResponse{}, err
 	}
 	// Build sparse vector lookup before fusion (fusion discards raw points).
-	var sparseByID map[string]qdrantPoint
+	var sparseByID map[string]VectorPoint
 	if wantStats {
-		sparseByID = make(map[string]qdrantPoint)
+		sparseByID = make(map[string]VectorPoint)
 		for _, pts := range pointsBySource {
 			for _, p := range pts {
 				if len(p.SparseIndices) > 0 {
@@ -279,6 +316,33 @@ func (s *Service) Search(ctx context.Context, req SearchRequest) (SearchResponse
 	return SearchResponse{Results: results}, nil
 }
 
+// searchKeyword searches stores with their own keyword search. Results
+// carry no sparse vector statistics.
+func (s *Service) searchKeyword(ctx context.Context, keyword KeywordSearcher, req SearchRequest, filters map[string]any) (SearchResponse, error) {
+	if len(req.Sources) == 0 {
+		points, scores, err := keyword.SearchKeyword(ctx, req.Query, req.Limit, filters)
+		if err != nil {
+			return SearchResponse{}, err
+		}
+		results := make([]MemoryItem, 0, len(points))
+		for idx, point := range points {
+			item := payloadToMemoryItem(point.ID, point.Payload)
+			if idx < len(scores) {
+				item.Score = scores[idx]
+			}
+			results = append(results, item)
+		}
+		return SearchResponse{Results: results}, nil
+	}
+	pointsBySource, scoresBySource, err := bySources(filters, req.Sources, func(filters map[string]any) ([]VectorPoint, []float64, error) {
+		return keyword.SearchKeyword(ctx, req.Query, req.Limit, filters)
+	})
+	if err != nil {
+		return SearchResponse{}, err
+	}
+	return SearchResponse{Results: fuseByRankFusion(pointsBySource, scoresBySource)}, nil
+}
+
 func (s *Service) EmbedUpsert(ctx context.Context, req EmbedUpsertRequest) (EmbedUpsertResponse, error) {
 	if s.resolver == nil {
 		return EmbedUpsertResponse{}, fmt.Errorf("embeddings resolver not configured")
@@ -292,6 +356,13 @@ func (s *Service) EmbedUpsert(ctx context.Context, req EmbedUpsertRequest) (Embe
 	req.Input.Text = strings.TrimSpace(req.Input.Text)
 	req.Input.ImageURL = strings.TrimSpace(req.Input.ImageURL)
 	req.Input.VideoURL = strings.TrimSpace(req.Input.VideoURL)
+	if req.Input.Text != "" {
+		text, err := s.redactTexts(ctx, resolveBotID(req.BotID, buildEmbedFilters(req)), []string{req.Input.Text})
+		if err != nil {
+			return EmbedUpsertResponse{}, err
+		}
+		req.Input.Text = text[0]
+	}
 
 	result, err := s.resolver.Embed(ctx, embeddings.Request{
 		Type:     req.Type,
@@ -308,11 +379,11 @@ func (s *Service) EmbedUpsert(ctx context.Context, req EmbedUpsertRequest) (Embe
 	}
 
 	if s.store == nil {
-		return EmbedUpsertResponse{}, fmt.Errorf("qdrant store not configured")
+		return EmbedUpsertResponse{}, fmt.Errorf("vector store not configured")
 	}
 
 	vectorName := ""
-	if s.store.usesNamedVectors {
+	if s.store.UsesNamedVectors() {
 		vectorName = result.Model
 	}
 
@@ -322,7 +393,7 @@ func (s *Service) EmbedUpsert(ctx context.Context, req EmbedUpsertRequest) (Embe
 	if metadata, ok := payload["metadata"].(map[string]any); ok && result.Model != "" {
 		metadata["model_id"] = result.Model
 	}
-	if err := s.store.Upsert(ctx, []qdrantPoint{{
+	if err := s.store.Upsert(ctx, []VectorPoint{{
 		ID:         id,
 		Vector:     result.Embedding,
 		VectorName: vectorName,
@@ -348,7 +419,7 @@ func (s *Service) Update(ctx context.Context, req UpdateRequest) (MemoryItem, er
 		return MemoryItem{}, fmt.Errorf("memory is required")
 	}
 	if s.store == nil {
-		return MemoryItem{}, fmt.Errorf("qdrant store not configured")
+		return MemoryItem{}, fmt.Errorf("vector store not configured")
 	}
 	if s.bm25 == nil {
 		return MemoryItem{}, fmt.Errorf("bm25 indexer not configured")
@@ -361,7 +432,13 @@ func (s *Service) Update(ctx context.Context, req UpdateRequest) (MemoryItem, er
 	if existing == nil {
 		return MemoryItem{}, fmt.Errorf("memory not found")
 	}
-	ctx = WithBotID(ctx, resolveBotID("", existing.Payload))
+	botID := resolveBotID("", existing.Payload)
+	ctx = WithBotID(ctx, botID)
+	memoryText, err := s.redactTexts(ctx, botID, []string{req.Memory})
+	if err != nil {
+		return MemoryItem{}, err
+	}
+	req.Memory = memoryText[0]
 
 	payload := existing.Payload
 	oldText := fmt.Sprint(payload["data"])
@@ -398,11 +475,11 @@ func (s *Service) Update(ctx context.Context, req UpdateRequest) (MemoryItem, er
 	payload["lang"] = newLang
 
 	embeddingEnabled := req.EmbeddingEnabled != nil && *req.EmbeddingEnabled
-	point := qdrantPoint{
+	point := VectorPoint{
 		ID:               req.MemoryID,
 		SparseIndices:    sparseIndices,
 		SparseValues:     sparseValues,
-		SparseVectorName: s.store.sparseVectorName,
+		SparseVectorName: s.store.SparseVectorName(),
 		Payload:          payload,
 	}
 	if embeddingEnabled {
@@ -416,7 +493,7 @@ func (s *Service) Update(ctx context.Context, req UpdateRequest) (MemoryItem, er
 		point.Vector = vector
 		point.VectorName = s.vectorNameForText()
 	}
-	if err := s.store.Upsert(ctx, []qdrantPoint{point}); err != nil {
+	if err := s.store.Upsert(ctx, []VectorPoint{point}); err != nil {
 		return MemoryItem{}, err
 	}
 	return payloadToMemoryItem(req.MemoryID, payload), nil
@@ -528,7 +605,7 @@ func (s *Service) Compact(ctx context.Context, filters map[string]any, ratio flo
 		return CompactResult{}, fmt.Errorf("llm not configured")
 	}
 	if s.store == nil {
-		return CompactResult{}, fmt.Errorf("qdrant store not configured")
+		return CompactResult{}, fmt.Errorf("vector store not configured")
 	}
 	if ratio <= 0 || ratio > 1 {
 		ratio = 0.5
@@ -635,7 +712,7 @@ const (
 
 func (s *Service) Usage(ctx context.Context, filters map[string]any) (UsageResponse, error) {
 	if s.store == nil {
-		return UsageResponse{}, fmt.Errorf("qdrant store not configured")
+		return UsageResponse{}, fmt.Errorf("vector store not configured")
 	}
 	points, err := s.store.List(ctx, 0, filters, false)
 	if err != nil {
@@ -664,7 +741,7 @@ func (s *Service) WarmupBM25(ctx context.Context, batchSize int) error {
 	if s.bm25 == nil || s.store == nil {
 		return nil
 	}
-	var offset *qdrant.PointId
+	offset := ""
 	for {
 		points, next, err := s.store.Scroll(ctx, batchSize, nil, offset)
 		if err != nil {
@@ -689,7 +766,7 @@ func (s *Service) WarmupBM25(ctx context.Context, batchSize int) error {
 			}
 			s.bm25.AddDocument(lang, termFreq, docLen)
 		}
-		if next == nil {
+		if next == "" {
 			break
 		}
 		offset = next
@@ -713,8 +790,20 @@ func (s *Service) addRawMessages(ctx context.Context, messages []Message, filter
 }
 
 func (s *Service) collectCandidates(ctx context.Context, facts []string, filters map[string]any) ([]CandidateMemory, error) {
+	if s.store == nil {
+		return nil, nil
+	}
 	unique := map[string]CandidateMemory{}
+	keyword, hasKeywordSearch := s.store.(KeywordSearcher)
 	for _, fact := range facts {
+		if hasKeywordSearch {
+			points, _, err := keyword.SearchKeyword(ctx, fact, 5, filters)
+			if err != nil {
+				return nil, err
+			}
+			addCandidates(unique, points)
+			continue
+		}
 		if s.bm25 == nil {
 			return nil, fmt.Errorf("bm25 indexer not configured")
 		}
@@ -731,14 +820,7 @@ func (s *Service) collectCandidates(ctx context.Context, facts []string, filters
 		if err != nil {
 			return nil, err
 		}
-		for _, point := range points {
-			item := payloadToMemoryItem(point.ID, point.Payload)
-			unique[item.ID] = CandidateMemory{
-				ID:       item.ID,
-				Memory:   item.Memory,
-				Metadata: item.Metadata,
-			}
-		}
+		addCandidates(unique, points)
 	}
 
 	candidates := make([]CandidateMemory, 0, len(unique))
@@ -748,9 +830,20 @@ func (s *Service) collectCandidates(ctx context.Context, facts []string, filters
 	return candidates, nil
 }
 
+func addCandidates(unique map[string]CandidateMemory, points []VectorPoint) {
+	for _, point := range points {
+		item := payloadToMemoryItem(point.ID, point.Payload)
+		unique[item.ID] = CandidateMemory{
+			ID:       item.ID,
+			Memory:   item.Memory,
+			Metadata: item.Metadata,
+		}
+	}
+}
+
 func (s *Service) applyAdd(ctx context.Context, text string, filters map[string]any, metadata map[string]any, embeddingEnabled bool) (MemoryItem, error) {
 	if s.store == nil {
-		return MemoryItem{}, fmt.Errorf("qdrant store not configured")
+		return MemoryItem{}, fmt.Errorf("vector store not configured")
 	}
 	if s.bm25 == nil {
 		return MemoryItem{}, fmt.Errorf("bm25 indexer not configured")
@@ -767,11 +860,11 @@ func (s *Service) applyAdd(ctx context.Context, text string, filters map[string]
 	id := uuid.NewString()
 	payload := buildPayload(text, filters, metadata, "")
 	payload["lang"] = lang
-	point := qdrantPoint{
+	point := VectorPoint{
 		ID:               id,
 		SparseIndices:    sparseIndices,
 		SparseValues:     sparseValues,
-		SparseVectorName: s.store.sparseVectorName,
+		SparseVectorName: s.store.SparseVectorName(),
 		Payload:          payload,
 	}
 	if embeddingEnabled {
@@ -785,7 +878,7 @@ func (s *Service) applyAdd(ctx context.Context, text string, filters map[string]
 		point.Vector = vector
 		point.VectorName = s.vectorNameForText()
 	}
-	if err := s.store.Upsert(ctx, []qdrantPoint{point}); err != nil {
+	if err := s.store.Upsert(ctx, []VectorPoint{point}); err != nil {
 		return MemoryItem{}, err
 	}
 	return payloadToMemoryItem(id, payload), nil
@@ -795,7 +888,7 @@ func (s *Service) applyAdd(ctx context.Context, text string, filters map[string]
 // Like applyAdd but preserves the given ID instead of generating a new UUID.
 func (s *Service) RebuildAdd(ctx context.Context, id, text string, filters map[string]any) (MemoryItem, error) {
 	if s.store == nil {
-		return MemoryItem{}, fmt.Errorf("qdrant store not configured")
+		return MemoryItem{}, fmt.Errorf("vector store not configured")
 	}
 	if s.bm25 == nil {
 		return MemoryItem{}, fmt.Errorf("bm25 indexer not configured")
@@ -814,14 +907,14 @@ func (s *Service) RebuildAdd(ctx context.Context, id, text string, filters map[s
 	sparseIndices, sparseValues := s.bm25.AddDocument(lang, termFreq, docLen)
 	payload := buildPayload(text, filters, nil, "")
 	payload["lang"] = lang
-	point := qdrantPoint{
+	point := VectorPoint{
 		ID:               id,
 		SparseIndices:    sparseIndices,
 		SparseValues:     sparseValues,
-		SparseVectorName: s.store.sparseVectorName,
+		SparseVectorName: s.store.SparseVectorName(),
 		Payload:          payload,
 	}
-	if err := s.store.Upsert(ctx, []qdrantPoint{point}); err != nil {
+	if err := s.store.Upsert(ctx, []VectorPoint{point}); err != nil {
 		return MemoryItem{}, err
 	}
 	return payloadToMemoryItem(id, payload), nil
@@ -876,11 +969,11 @@ func (s *Service) applyUpdate(ctx context.Context, id, text string, filters map[
 	if filters != nil {
 		applyFiltersToPayload(payload, filters)
 	}
-	point := qdrantPoint{
+	point := VectorPoint{
 		ID:               id,
 		SparseIndices:    sparseIndices,
 		SparseValues:     sparseValues,
-		SparseVectorName: s.store.sparseVectorName,
+		SparseVectorName: s.store.SparseVectorName(),
 		Payload:          payload,
 	}
 	if embeddingEnabled {
@@ -894,7 +987,7 @@ func (s *Service) applyUpdate(ctx context.Context, id, text string, filters map[
 		point.Vector = vector
 		point.VectorName = s.vectorNameForText()
 	}
-	if err := s.store.Upsert(ctx, []qdrantPoint{point}); err != nil {
+	if err := s.store.Upsert(ctx, []VectorPoint{point}); err != nil {
 		return MemoryItem{}, err
 	}
 	return payloadToMemoryItem(id, payload), nil
@@ -937,6 +1030,26 @@ func (s *Service) applyDelete(ctx context.Context, id string) (MemoryItem, error
 	return item, nil
 }
 
+func (s *Service) redactMessages(ctx context.Context, botID string, messages []Message) ([]Message, error) {
+	if s.redactor == nil {
+		return messages, nil
+	}
+	texts := make([]string, len(messages))
+	for i, m := range messages {
+		texts[i] = m.Content
+	}
+	texts, err := s.redactTexts(ctx, botID, texts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		m.Content = texts[i]
+		out[i] = m
+	}
+	return out, nil
+}
+
 func normalizeMessages(req AddRequest) []Message {
 	if len(req.Messages) > 0 {
 		return req.Messages
@@ -1095,14 +1208,14 @@ func buildEmbeddingPayload(req EmbedUpsertRequest, filters map[string]any) map[s
 }
 
 func (s *Service) vectorNameForText() string {
-	if s.store == nil || !s.store.usesNamedVectors {
+	if s.store == nil || !s.store.UsesNamedVectors() {
 		return ""
 	}
 	return strings.TrimSpace(s.defaultTextModelID)
 }
 
 func (s *Service) vectorNameForMultimodal() string {
-	if s.store == nil || !s.store.usesNamedVectors {
+	if s.store == nil || !s.store.UsesNamedVectors() {
 		return ""
 	}
 	return strings.TrimSpace(s.defaultMultimodalModelID)
@@ -1248,7 +1361,7 @@ const (
 	rrfK = 60.0
 )
 
-func fuseByRankFusion(pointsBySource map[string][]qdrantPoint, _ map[string][]float64) []MemoryItem {
+func fuseByRankFusion(pointsBySource map[string][]VectorPoint, _ map[string][]float64) []MemoryItem {
 	candidates := map[string]*rerankCandidate{}
 	rrfScores := map[string]float64{}
 
@@ -13,6 +13,7 @@ type MockLLM struct {
 	DecideFunc         func(ctx context.Context, req DecideRequest) (DecideResponse, error)
 	CompactFunc        func(ctx context.Context, req CompactRequest) (CompactResponse, error)
 	DetectLanguageFunc func(ctx context.Context, text string) (string, error)
+	SummarizeFunc      func(ctx context.Context, req SummarizeRequest) (SummarizeResponse, error)
 }
 
 func (m *MockLLM) Extract(ctx context.Context, req ExtractRequest) (ExtractResponse, error) {
@@ -30,6 +31,12 @@ func (m *MockLLM) Compact(ctx context.Context, req CompactRequest) (CompactRespo
 func (m *MockLLM) DetectLanguage(ctx context.Context, text string) (string, error) {
 	return m.DetectLanguageFunc(ctx, text)
 }
+func (m *MockLLM) Summarize(ctx context.Context, req SummarizeRequest) (SummarizeResponse, error) {
+	if m.SummarizeFunc != nil {
+		return m.SummarizeFunc(ctx, req)
+	}
+	return SummarizeResponse{}, fmt.Errorf("summarize not mocked")
+}
 
 func TestService_Add_FullFlow(t *testing.T) {
 	ctx := context.Background()
@@ -87,8 +94,8 @@ func TestService_Add_FullFlow(t *testing.T) {
 			t.Error("Expected LLM.Decide to be called")
 		}
 
-		if err == nil || !reflectContains(err.Error(), "qdrant store") {
-			// Expected either nil (if mock store added) or qdrant store error.
+		if err == nil || !reflectContains(err.Error(), "vector store") {
+			// Expected either nil (if mock store added) or vector store error.
 		}
 	})
 }
@@ -98,11 +105,11 @@ func reflectContains(s, substr string) bool {
 }
 
 func TestRankFusion_Logic(t *testing.T) {
-	p1 := qdrantPoint{ID: "1", Payload: map[string]any{"data": "result 1"}}
-	p2 := qdrantPoint{ID: "2", Payload: map[string]any{"data": "result 2"}}
+	p1 := VectorPoint{ID: "1", Payload: map[string]any{"data": "result 1"}}
+	p2 := VectorPoint{ID: "2", Payload: map[string]any{"data": "result 2"}}
 
 	// Source A: 1 first, 2 second; Source B: 2 first, 1 second.
-	pointsBySource := map[string][]qdrantPoint{
+	pointsBySource := map[string][]VectorPoint{
 		"source_a": {p1, p2},
 		"source_b": {p2, p1},
 	}
@@ -8,6 +8,13 @@ type LLM interface {
 	Decide(ctx context.Context, req DecideRequest) (DecideResponse, error)
 	Compact(ctx context.Context, req CompactRequest) (CompactResponse, error)
 	DetectLanguage(ctx context.Context, text string) (string, error)
+	Summarize(ctx context.Context, req SummarizeRequest) (SummarizeResponse, error)
+}
+
+// Redactor replaces personal data with placeholders before text reaches the
+// memory model, the embedder or Qdrant. pii.Service implements it.
+type Redactor interface {
+	Redact(ctx context.Context, botID string, value any) (any, error)
 }
 
 type Message struct {
@@ -168,6 +175,17 @@ type CompactResponse struct {
 	Facts []string `json:"facts"`
 }
 
+// SummarizeRequest folds Messages into PreviousSummary, producing a rolling
+// summary of conversation history that no longer fits the context window.
+type SummarizeRequest struct {
+	PreviousSummary string    `json:"previous_summary,omitempty"`
+	Messages        []Message `json:"messages"`
+}
+
+type SummarizeResponse struct {
+	Summary string `json:"summary"`
+}
+
 type CompactResult struct {
 	BeforeCount int          `json:"before_count"`
 	AfterCount  int          `json:"after_count"`
@@ -0,0 +1,94 @@
+package memory
+
+import "context"
+
+// VectorStore keeps memory points and searches them by their dense and
+// sparse vectors. Filters match payload fields: a value matches the same
+// value, and a map of "gte", "gt", "lte" and "lt" bounds matches a range.
+type VectorStore interface {
+	Upsert(ctx context.Context, points []VectorPoint) error
+	// Search ranks points by cosine similarity to vector. vectorName picks
+	// the named vector to compare when the store uses named vectors.
+	Search(ctx context.Context, vector []float32, limit int, filters map[string]any, vectorName string) ([]VectorPoint, []float64, error)
+	// SearchSparse ranks points by the dot product of their sparse vector
+	// with the query, as built by the BM25 indexer.
+	SearchSparse(ctx context.Context, indices []uint32, values []float32, limit int, filters map[string]any, withSparseVectors bool) ([]VectorPoint, []float64, error)
+	// Get returns nil without an error when the point does not exist.
+	Get(ctx context.Context, id string) (*VectorPoint, error)
+	Delete(ctx context.Context, id string) error
+	DeleteBatch(ctx context.Context, ids []string) error
+	List(ctx context.Context, limit int, filters map[string]any, withSparseVectors bool) ([]VectorPoint, error)
+	// Scroll pages through points in ID order, starting at the point with ID
+	// offset, or at the first point when offset is empty. The returned offset
+	// starts the next page and is empty after the last one.
+	Scroll(ctx context.Context, limit int, filters map[string]any, offset string) ([]VectorPoint, string, error)
+	Count(ctx context.Context, filters map[string]any) (uint64, error)
+	// DeleteAll deletes the points matching filters, which must not be empty.
+	DeleteAll(ctx context.Context, filters map[string]any) error
+	// UsesNamedVectors reports whether points keep one dense vector per
+	// embedding model, named by the model ID.
+	UsesNamedVectors() bool
+	SparseVectorName() string
+}
+
+// KeywordSearcher is implemented by stores that search the text of
+// memories themselves. The memory service uses it in place of the sparse
+// vectors built by the BM25 indexer.
+type KeywordSearcher interface {
+	SearchKeyword(ctx context.Context, query string, limit int, filters map[string]any) ([]VectorPoint, []float64, error)
+}
+
+// VectorPoint is a memory with its vectors. Search results carry the
+// payload only; sparse vectors are filled in when asked for.
+type VectorPoint struct {
+	ID               string         `json:"id"`
+	Vector           []float32      `json:"vector"`
+	VectorName       string         `json:"vector_name,omitempty"`
+	SparseIndices    []uint32       `json:"sparse_indices,omitempty"`
+	SparseValues     []float32      `json:"sparse_values,omitempty"`
+	SparseVectorName string         `json:"sparse_vector_name,omitempty"`
+	Payload          map[string]any `json:"payload,omitempty"`
+}
+
+// searchBySources runs Search once per source.
+func searchBySources(ctx context.Context, store VectorStore, vector []float32, limit int, filters map[string]any, sources []string, vectorName string) (map[string][]VectorPoint, map[string][]float64, error) {
+	return bySources(filters, sources, func(filters map[string]any) ([]VectorPoint, []float64, error) {
+		return store.Search(ctx, vector, limit, filters, vectorName)
+	})
+}
+
+// searchSparseBySources runs SearchSparse once per source.
+func searchSparseBySources(ctx context.Context, store VectorStore, indices []uint32, values []float32, limit int, filters map[string]any, sources []string, withSparseVectors bool) (map[string][]VectorPoint, map[string][]float64, error) {
+	return bySources(filters, sources, func(filters map[string]any) ([]VectorPoint, []float64, error) {
+		return store.SearchSparse(ctx, indices, values, limit, filters, withSparseVectors)
+	})
+}
+
+func bySources(filters map[string]any, sources []string, search func(map[string]any) ([]VectorPoint, []float64, error)) (map[string][]VectorPoint, map[string][]float64, error) {
+	pointsBySource := make(map[string][]VectorPoint, len(sources))
+	scoresBySource := make(map[string][]float64, len(sources))
+	for _, source := range sources {
+		merged := cloneFilters(filters)
+		if source != "" {
+			merged["source"] = source
+		}
+		points, scores, err := search(merged)
+		if err != nil {
+			return nil, nil, err
+		}
+		pointsBySource[source] = points
+		scoresBySource[source] = scores
+	}
+	return pointsBySource, scoresBySource, nil
+}
+
+func cloneFilters(filters map[string]any) map[string]any {
+	if len(filters) == 0 {
+		return map[string]any{}
+	}
+	clone := make(map[string]any, len(filters))
+	for key, value := range filters {
+		clone[key] = value
+	}
+	return clone
+}
@@ -0,0 +1,413 @@
+// Package archive exports bot history to portable JSON and Markdown and
+// imports JSON archives back into a bot.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/memohai/memoh/internal/channel/route"
+	"github.com/memohai/memoh/internal/media"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+// Version is the archive format version written by Export.
+const Version = 1
+
+// ErrUnsupportedVersion is returned when importing an archive written by a
+// newer format.
+var ErrUnsupportedVersion = errors.New("unsupported archive version")
+
+// ErrForeignRoute is returned when a route map points at a route that does
+// not belong to the bot being imported into.
+var ErrForeignRoute = errors.New("route does not belong to the bot")
+
+// Archive is a bot's active history in export form. Message content keeps the
+// stored model message JSON, so tool calls and results survive a round trip.
+type Archive struct {
+	Version    int                  `json:"version"`
+	BotID      string               `json:"bot_id"`
+	RouteID    string               `json:"route_id,omitempty"`
+	ExportedAt time.Time            `json:"exported_at"`
+	Messages   []messagepkg.Message `json:"messages"`
+	// Media holds the bundled attachment bytes when requested.
+	Media []Media `json:"media,omitempty"`
+}
+
+// Media is one bundled attachment, keyed by its content hash.
+type Media struct {
+	ContentHash string `json:"content_hash"`
+	Mime        string `json:"mime,omitempty"`
+	Data        []byte `json:"data"`
+}
+
+// ExportOptions selects what Export writes.
+type ExportOptions struct {
+	BotID string
+	// RouteID limits the export to one channel route.
+	RouteID string
+	// MessageIDs exports exactly these messages, superseded ones included,
+	// in place of the active history.
+	MessageIDs []string
+	// IncludeMedia bundles attachment bytes into the archive.
+	IncludeMedia bool
+}
+
+// ImportOptions controls how archived messages are recreated.
+type ImportOptions struct {
+	BotID string
+	// SenderMap maps archived channel identity and user IDs to IDs on this
+	// instance. An empty value drops the sender. Unmapped senders are kept
+	// when they already have messages in the target bot and dropped
+	// otherwise, whatever bot the archive claims to come from.
+	SenderMap map[string]string
+	// RouteMap maps archived route IDs to routes of the target bot. Unmapped
+	// routes are kept when they are routes of the target bot and dropped
+	// otherwise.
+	RouteMap map[string]string
+}
+
+// ImportResult reports what Import created. Skipped counts messages that
+// were already in the bot, such as those of an earlier import.
+type ImportResult struct {
+	Messages int `json:"messages"`
+	Skipped  int `json:"skipped"`
+	Media    int `json:"media"`
+}
+
+type messageStore interface {
+	messagepkg.Writer
+	ListSince(ctx context.Context, botID string, since time.Time) ([]messagepkg.Message, error)
+	ListByIDs(ctx context.Context, botID string, ids []string) ([]messagepkg.Message, error)
+	ListSourceIDs(ctx context.Context, botID string) (map[string]string, error)
+	ListSenderIDs(ctx context.Context, botID string) (map[string]bool, error)
+}
+
+type routeLister interface {
+	List(ctx context.Context, botID string) ([]route.Route, error)
+}
+
+type mediaStore interface {
+	Open(ctx context.Context, botID, contentHash string) (io.ReadCloser, media.Asset, error)
+	Ingest(ctx context.Context, input media.IngestInput) (media.Asset, error)
+}
+
+// Service exports and imports bot history.
+type Service struct {
+	messages messageStore
+	// inTx runs an import in one transaction; without it the import writes
+	// through messages directly.
+	inTx func(ctx context.Context, fn func(messageStore) error) error
+	// routes lists the routes imported messages may keep; without it they
+	// are all dropped.
+	routes routeLister
+	media  mediaStore
+	logger *slog.Logger
+}
+
+// NewService creates an archive service.
+func NewService(log *slog.Logger, messages messagepkg.Service, mediaService *media.Service) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	s := &Service{
+		messages: messages,
+		logger:   log.With(slog.String("service", "archive")),
+	}
+	if db, ok := messages.(*messagepkg.DBService); ok {
+		s.inTx = func(ctx context.Context, fn func(messageStore) error) error {
+			return db.InTx(ctx, func(tx *messagepkg.DBService) error { return fn(tx) })
+		}
+	}
+	if mediaService != nil {
+		s.media = mediaService
+	}
+	return s
+}
+
+// SetRouteLister lets imports keep routes of the target bot.
+func (s *Service) SetRouteLister(routes routeLister) {
+	s.routes = routes
+}
+
+// Export returns the active history of a bot, or the messages selected by
+// ID, oldest first.
+func (s *Service) Export(ctx context.Context, opts ExportOptions) (Archive, error) {
+	botID := strings.TrimSpace(opts.BotID)
+	if botID == "" {
+		return Archive{}, fmt.Errorf("bot id is required")
+	}
+	var all []messagepkg.Message
+	var err error
+	if len(opts.MessageIDs) > 0 {
+		all, err = s.messages.ListByIDs(ctx, botID, opts.MessageIDs)
+	} else {
+		all, err = s.messages.ListSince(ctx, botID, time.Time{})
+	}
+	if err != nil {
+		return Archive{}, fmt.Errorf("list messages: %w", err)
+	}
+	routeID := strings.TrimSpace(opts.RouteID)
+	messages := make([]messagepkg.Message, 0, len(all))
+	for _, msg := range all {
+		if routeID != "" && msg.RouteID != routeID {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	archive := Archive{
+		Version:    Version,
+		BotID:      botID,
+		RouteID:    routeID,
+		ExportedAt: time.Now().UTC(),
+		Messages:   messages,
+	}
+	if opts.IncludeMedia {
+		archive.Media = s.bundleMedia(ctx, botID, messages)
+	}
+	return archive, nil
+}
+
+func (s *Service) bundleMedia(ctx context.Context, botID string, messages []messagepkg.Message) []Media {
+	if s.media == nil {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	var bundled []Media
+	for _, msg := range messages {
+		for _, asset := range msg.Assets {
+			hash := strings.TrimSpace(asset.ContentHash)
+			if hash == "" {
+				continue
+			}
+			if _, ok := seen[hash]; ok {
+				continue
+			}
+			seen[hash] = struct{}{}
+			item, err := s.readMedia(ctx, botID, hash)
+			if err != nil {
+				s.logger.Warn("export media skipped",
+					slog.String("bot_id", botID),
+					slog.String("content_hash", hash),
+					slog.Any("error", err),
+				)
+				continue
+			}
+			bundled = append(bundled, item)
+		}
+	}
+	return bundled
+}
+
+func (s *Service) readMedia(ctx context.Context, botID, hash string) (Media, error) {
+	reader, asset, err := s.media.Open(ctx, botID, hash)
+	if err != nil {
+		return Media{}, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	data, err := media.ReadAllWithLimit(reader, media.MaxAssetBytes)
+	if err != nil {
+		return Media{}, err
+	}
+	return Media{ContentHash: hash, Mime: asset.Mime, Data: data}, nil
+}
+
+// Import recreates the archived messages in a bot with their original
+// timestamps. Bundled media is stored first so attachment links resolve.
+// The messages are written in one transaction and those already in the bot
+// are skipped, so a failed or repeated import can simply be retried.
+func (s *Service) Import(ctx context.Context, archive Archive, opts ImportOptions) (ImportResult, error) {
+	botID := strings.TrimSpace(opts.BotID)
+	if botID == "" {
+		return ImportResult{}, fmt.Errorf("bot id is required")
+	}
+	if archive.Version > Version {
+		return ImportResult{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, archive.Version)
+	}
+
+	botRoutes, err := s.botRoutes(ctx, botID)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	for archived, mapped := range opts.RouteMap {
+		if mapped = strings.TrimSpace(mapped); mapped != "" && !botRoutes[mapped] {
+			return ImportResult{}, fmt.Errorf("%w: %s maps to %s", ErrForeignRoute, archived, mapped)
+		}
+	}
+
+	var result ImportResult
+	if len(archive.Media) > 0 && s.media != nil {
+		for _, item := range archive.Media {
+			asset, err := s.media.Ingest(ctx, media.IngestInput{
+				BotID:  botID,
+				Mime:   item.Mime,
+				Reader: bytes.NewReader(item.Data),
+			})
+			if err != nil {
+				return result, fmt.Errorf("import media %s: %w", item.ContentHash, err)
+			}
+			if asset.ContentHash != item.ContentHash {
+				s.logger.Warn("imported media hash mismatch",
+					slog.String("expected", item.ContentHash),
+					slog.String("got", asset.ContentHash),
+				)
+			}
+			result.Media++
+		}
+	}
+
+	messages := append([]messagepkg.Message(nil), archive.Messages...)
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	// The archive's bot ID is not trusted: IDs are only kept when this bot
+	// already knows them.
+	err = s.transact(ctx, func(store messageStore) error {
+		// Archived IDs already in the bot, from an earlier import or from
+		// the bot the archive was exported from, are skipped so retries do
+		// not duplicate history.
+		imported, err := store.ListSourceIDs(ctx, botID)
+		if err != nil {
+			return fmt.Errorf("list existing messages: %w", err)
+		}
+		senders, err := store.ListSenderIDs(ctx, botID)
+		if err != nil {
+			return fmt.Errorf("list existing senders: %w", err)
+		}
+		prevID := ""
+		for _, msg := range messages {
+			if existing, ok := imported[msg.ID]; ok {
+				prevID = existing
+				result.Skipped++
+				continue
+			}
+			// Keep the archived thread: a message follows its archived
+			// parent, or the message before it when the parent was not
+			// exported. The first one starts a new thread rather than
+			// continuing the bot's current branch.
+			parentID := prevID
+			if mapped, ok := imported[strings.TrimSpace(msg.ParentID)]; ok {
+				parentID = mapped
+			}
+			input := messagepkg.PersistInput{
+				BotID:                   botID,
+				RouteID:                 mapID(msg.RouteID, opts.RouteMap, botRoutes),
+				SenderChannelIdentityID: mapID(msg.SenderChannelIdentityID, opts.SenderMap, senders),
+				SenderUserID:            mapID(msg.SenderUserID, opts.SenderMap, senders),
+				Platform:                msg.Platform,
+				ExternalMessageID:       msg.ExternalMessageID,
+				SourceReplyToMessageID:  msg.SourceReplyToMessageID,
+				Role:                    msg.Role,
+				Content:                 msg.Content,
+				Metadata:                importMetadata(msg),
+				Usage:                   msg.Usage,
+				Assets:                  assetRefs(msg.Assets),
+				ParentID:                parentID,
+				NoParent:                parentID == "",
+				CreatedAt:               msg.CreatedAt,
+			}
+			created, err := store.Persist(ctx, input)
+			if err != nil {
+				return fmt.Errorf("import message %s: %w", msg.ID, err)
+			}
+			if id := strings.TrimSpace(msg.ID); id != "" {
+				imported[id] = created.ID
+			}
+			prevID = created.ID
+			result.Messages++
+		}
+		return nil
+	})
+	if err != nil {
+		return ImportResult{Media: result.Media}, err
+	}
+	return result, nil
+}
+
+// botRoutes returns the route IDs of a bot.
+func (s *Service) botRoutes(ctx context.Context, botID string) (map[string]bool, error) {
+	ids := map[string]bool{}
+	if s.routes == nil {
+		return ids, nil
+	}
+	routes, err := s.routes.List(ctx, botID)
+	if err != nil {
+		return nil, fmt.Errorf("list bot routes: %w", err)
+	}
+	for _, rt := range routes {
+		ids[rt.ID] = true
+	}
+	return ids, nil
+}
+
+func (s *Service) transact(ctx context.Context, fn func(messageStore) error) error {
+	if s.inTx == nil {
+		return fn(s.messages)
+	}
+	return s.inTx(ctx, fn)
+}
+
+// Filename names a downloaded export; ext is the file extension without dot.
+func Filename(botID, ext string, at time.Time) string {
+	return fmt.Sprintf("memoh-%s-%s.%s", botID, at.UTC().Format("20060102-150405"), ext)
+}
+
+// mapID maps an archived sender or route ID. Unmapped IDs are only kept
+// when they are known to the target bot.
+func mapID(id string, mapping map[string]string, known map[string]bool) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ""
+	}
+	if mapped, ok := mapping[id]; ok {
+		return strings.TrimSpace(mapped)
+	}
+	if known[id] {
+		return id
+	}
+	return ""
+}
+
+// importMetadata keeps the original metadata and records where the message
+// came from, including the sender name for senders that were dropped.
+func importMetadata(msg messagepkg.Message) map[string]any {
+	meta := make(map[string]any, len(msg.Metadata)+2)
+	for k, v := range msg.Metadata {
+		meta[k] = v
+	}
+	meta["imported_from"] = map[string]any{
+		"bot_id":     msg.BotID,
+		"message_id": msg.ID,
+	}
+	if name := strings.TrimSpace(msg.SenderDisplayName); name != "" {
+		meta["imported_sender_display_name"] = name
+	}
+	return meta
+}
+
+func assetRefs(assets []messagepkg.MessageAsset) []messagepkg.AssetRef {
+	if len(assets) == 0 {
+		return nil
+	}
+	refs := make([]messagepkg.AssetRef, 0, len(assets))
+	for _, asset := range assets {
+		refs = append(refs, messagepkg.AssetRef{
+			ContentHash: asset.ContentHash,
+			Role:        asset.Role,
+			Ordinal:     asset.Ordinal,
+			Mime:        asset.Mime,
+			SizeBytes:   asset.SizeBytes,
+			StorageKey:  asset.StorageKey,
+		})
+	}
+	return refs
+}
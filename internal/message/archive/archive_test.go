@@ -0,0 +1,364 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memohai/memoh/internal/channel/route"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/flow"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+type fakeMessageStore struct {
+	listed    []messagepkg.Message
+	persisted []messagepkg.PersistInput
+	senders   map[string]bool
+}
+
+func (f *fakeMessageStore) ListSenderIDs(ctx context.Context, botID string) (map[string]bool, error) {
+	return f.senders, nil
+}
+
+type fakeRoutes []route.Route
+
+func (f fakeRoutes) List(ctx context.Context, botID string) ([]route.Route, error) {
+	var out []route.Route
+	for _, rt := range f {
+		if rt.BotID == botID {
+			out = append(out, rt)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeMessageStore) Persist(ctx context.Context, input messagepkg.PersistInput) (messagepkg.Message, error) {
+	f.persisted = append(f.persisted, input)
+	msg := messagepkg.Message{ID: fmt.Sprintf("new-%d", len(f.persisted)), Metadata: input.Metadata}
+	f.listed = append(f.listed, msg)
+	return msg, nil
+}
+
+// ListSourceIDs mirrors the query in keying imported messages by the ID they
+// were imported from.
+func (f *fakeMessageStore) ListSourceIDs(ctx context.Context, botID string) (map[string]string, error) {
+	ids := map[string]string{}
+	for _, msg := range f.listed {
+		source := msg.ID
+		if from, ok := msg.Metadata["imported_from"].(map[string]any); ok {
+			source, _ = from["message_id"].(string)
+		}
+		ids[source] = msg.ID
+	}
+	return ids, nil
+}
+
+// ListSince mirrors the query in leaving out superseded messages.
+func (f *fakeMessageStore) ListSince(ctx context.Context, botID string, since time.Time) ([]messagepkg.Message, error) {
+	var active []messagepkg.Message
+	for _, msg := range f.listed {
+		if !msg.Superseded {
+			active = append(active, msg)
+		}
+	}
+	return active, nil
+}
+
+func (f *fakeMessageStore) ListByIDs(ctx context.Context, botID string, ids []string) ([]messagepkg.Message, error) {
+	var found []messagepkg.Message
+	for _, msg := range f.listed {
+		if slices.Contains(ids, msg.ID) {
+			found = append(found, msg)
+		}
+	}
+	return found, nil
+}
+
+func textContent(t *testing.T, role, text string) json.RawMessage {
+	t.Helper()
+	content, err := json.Marshal(conversation.ModelMessage{Role: role, Content: conversation.NewTextContent(text)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestExport_FiltersRoute(t *testing.T) {
+	store := &fakeMessageStore{listed: []messagepkg.Message{
+		{ID: "m1", RouteID: "r1"},
+		{ID: "m2", RouteID: "r2"},
+		{ID: "m3", RouteID: "r1"},
+	}}
+	svc := &Service{messages: store, logger: slog.Default()}
+
+	exported, err := svc.Export(context.Background(), ExportOptions{BotID: "bot-1", RouteID: "r1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported.Version != Version || len(exported.Messages) != 2 || exported.Messages[1].ID != "m3" {
+		t.Fatalf("unexpected archive %+v", exported)
+	}
+}
+
+func TestExport_FiltersMessageIDs(t *testing.T) {
+	store := &fakeMessageStore{listed: []messagepkg.Message{
+		{ID: "m1", Superseded: true},
+		{ID: "m2"},
+		{ID: "m3"},
+	}}
+	svc := &Service{messages: store, logger: slog.Default()}
+
+	exported, err := svc.Export(context.Background(), ExportOptions{BotID: "bot-1", MessageIDs: []string{"m3", "m1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Messages) != 2 || exported.Messages[0].ID != "m1" || exported.Messages[1].ID != "m3" {
+		t.Fatalf("expected the selected messages, superseded ones included, got %+v", exported)
+	}
+
+	exported, err = svc.Export(context.Background(), ExportOptions{BotID: "bot-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Messages) != 2 || exported.Messages[0].ID != "m2" {
+		t.Fatalf("expected the active history only, got %+v", exported)
+	}
+}
+
+func TestImport_MapsIDsAndKeepsTimestamps(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	store := &fakeMessageStore{}
+	svc := &Service{messages: store, logger: slog.Default()}
+	archive := Archive{
+		Version: Version,
+		BotID:   "old-bot",
+		Messages: []messagepkg.Message{
+			{ID: "a1", BotID: "old-bot", Role: "assistant", RouteID: "r1", CreatedAt: t0.Add(time.Minute)},
+			{ID: "u1", BotID: "old-bot", Role: "user", RouteID: "r1", SenderChannelIdentityID: "ci-old", SenderUserID: "user-old", SenderDisplayName: "Ann", CreatedAt: t0},
+		},
+	}
+
+	result, err := svc.Import(context.Background(), archive, ImportOptions{
+		BotID:     "new-bot",
+		SenderMap: map[string]string{"ci-old": "ci-new", "user-old": ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Messages != 2 || len(store.persisted) != 2 {
+		t.Fatalf("expected 2 messages imported, got %+v", result)
+	}
+	user, reply := store.persisted[0], store.persisted[1]
+	if user.Role != "user" || !user.CreatedAt.Equal(t0) || user.ParentID != "" || !user.NoParent {
+		t.Fatalf("expected user message first with original time, got %+v", user)
+	}
+	if user.BotID != "new-bot" || user.SenderChannelIdentityID != "ci-new" || user.SenderUserID != "" {
+		t.Fatalf("unexpected sender mapping %+v", user)
+	}
+	if user.RouteID != "" {
+		t.Fatalf("expected foreign route dropped, got %q", user.RouteID)
+	}
+	if user.Metadata["imported_sender_display_name"] != "Ann" {
+		t.Fatalf("expected sender name kept, got %v", user.Metadata)
+	}
+	if reply.ParentID != "new-1" {
+		t.Fatalf("expected reply chained to imported user message, got %q", reply.ParentID)
+	}
+}
+
+func TestImport_KeepsArchivedThreadAndDropsUnmappedSenders(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	store := &fakeMessageStore{}
+	svc := &Service{messages: store, logger: slog.Default()}
+	archive := Archive{
+		Version: Version,
+		BotID:   "old-bot",
+		Messages: []messagepkg.Message{
+			{ID: "u1", BotID: "old-bot", Role: "user", SenderChannelIdentityID: "ci-old", CreatedAt: t0},
+			{ID: "a1", BotID: "old-bot", Role: "assistant", ParentID: "u1", CreatedAt: t0.Add(time.Minute)},
+			{ID: "a2", BotID: "old-bot", Role: "assistant", ParentID: "u1", CreatedAt: t0.Add(2 * time.Minute)},
+		},
+	}
+
+	if _, err := svc.Import(context.Background(), archive, ImportOptions{BotID: "new-bot"}); err != nil {
+		t.Fatal(err)
+	}
+	if store.persisted[0].SenderChannelIdentityID != "" {
+		t.Fatalf("expected unmapped foreign sender dropped, got %q", store.persisted[0].SenderChannelIdentityID)
+	}
+	if store.persisted[1].ParentID != "new-1" || store.persisted[2].ParentID != "new-1" {
+		t.Fatalf("expected both replies under the imported user message, got %q and %q",
+			store.persisted[1].ParentID, store.persisted[2].ParentID)
+	}
+
+	sameBot := &fakeMessageStore{senders: map[string]bool{"ci-old": true}}
+	svc = &Service{messages: sameBot, logger: slog.Default()}
+	if _, err := svc.Import(context.Background(), archive, ImportOptions{BotID: "new-bot"}); err != nil {
+		t.Fatal(err)
+	}
+	if sameBot.persisted[0].SenderChannelIdentityID != "ci-old" {
+		t.Fatalf("expected a sender known to the bot kept, got %q", sameBot.persisted[0].SenderChannelIdentityID)
+	}
+}
+
+func TestImport_ForgedBotIDKeepsOnlyKnownIDs(t *testing.T) {
+	store := &fakeMessageStore{senders: map[string]bool{"ci-member": true}}
+	svc := &Service{messages: store, logger: slog.Default()}
+	svc.SetRouteLister(fakeRoutes{
+		{ID: "route-own", BotID: "bot-1"},
+		{ID: "route-other", BotID: "bot-2"},
+	})
+	// The archive claims to come from the target bot, but names a stranger
+	// and another bot's route.
+	archive := Archive{Version: Version, BotID: "bot-1", Messages: []messagepkg.Message{
+		{ID: "u1", Role: "user", RouteID: "route-other", SenderChannelIdentityID: "ci-stranger", SenderUserID: "user-stranger"},
+		{ID: "u2", Role: "user", RouteID: "route-own", SenderChannelIdentityID: "ci-member"},
+	}}
+
+	if _, err := svc.Import(context.Background(), archive, ImportOptions{BotID: "bot-1"}); err != nil {
+		t.Fatal(err)
+	}
+	forged, genuine := store.persisted[0], store.persisted[1]
+	if forged.RouteID != "" || forged.SenderChannelIdentityID != "" || forged.SenderUserID != "" {
+		t.Fatalf("expected unknown route and sender dropped, got %+v", forged)
+	}
+	if genuine.RouteID != "route-own" || genuine.SenderChannelIdentityID != "ci-member" {
+		t.Fatalf("expected the bot's own route and member kept, got %+v", genuine)
+	}
+
+	_, err := svc.Import(context.Background(), archive, ImportOptions{
+		BotID:    "bot-1",
+		RouteMap: map[string]string{"route-own": "route-other"},
+	})
+	if !errors.Is(err, ErrForeignRoute) {
+		t.Fatalf("expected ErrForeignRoute for a map into another bot, got %v", err)
+	}
+}
+
+func TestImport_RetrySkipsImportedMessages(t *testing.T) {
+	t0 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	store := &fakeMessageStore{}
+	svc := &Service{messages: store, logger: slog.Default()}
+	archive := Archive{
+		Version: Version,
+		BotID:   "old-bot",
+		Messages: []messagepkg.Message{
+			{ID: "u1", BotID: "old-bot", Role: "user", CreatedAt: t0},
+			{ID: "a1", BotID: "old-bot", Role: "assistant", ParentID: "u1", CreatedAt: t0.Add(time.Minute)},
+		},
+	}
+	if _, err := svc.Import(context.Background(), Archive{Version: Version, BotID: "old-bot", Messages: archive.Messages[:1]}, ImportOptions{BotID: "new-bot"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := svc.Import(context.Background(), archive, ImportOptions{BotID: "new-bot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Messages != 1 || result.Skipped != 1 || len(store.persisted) != 2 {
+		t.Fatalf("expected only the missing message imported, got %+v with %d writes", result, len(store.persisted))
+	}
+	if store.persisted[1].ParentID != "new-1" {
+		t.Fatalf("expected the reply under the earlier import, got %q", store.persisted[1].ParentID)
+	}
+}
+
+func TestImport_RollsBackOnFailure(t *testing.T) {
+	store := &fakeMessageStore{}
+	var rolledBack bool
+	svc := &Service{
+		messages: store,
+		logger:   slog.Default(),
+		inTx: func(ctx context.Context, fn func(messageStore) error) error {
+			scratch := &fakeMessageStore{}
+			if err := fn(&failingStore{fakeMessageStore: scratch, failAt: 2}); err != nil {
+				rolledBack = true
+				return err
+			}
+			store.persisted = append(store.persisted, scratch.persisted...)
+			return nil
+		},
+	}
+	archive := Archive{Version: Version, BotID: "old-bot", Messages: []messagepkg.Message{
+		{ID: "u1", Role: "user"},
+		{ID: "a1", Role: "assistant"},
+	}}
+
+	if _, err := svc.Import(context.Background(), archive, ImportOptions{BotID: "new-bot"}); err == nil {
+		t.Fatal("expected import error")
+	}
+	if !rolledBack || len(store.persisted) != 0 {
+		t.Fatalf("expected nothing kept after a failed import, got %d messages", len(store.persisted))
+	}
+}
+
+type failingStore struct {
+	*fakeMessageStore
+	failAt int
+}
+
+func (f *failingStore) Persist(ctx context.Context, input messagepkg.PersistInput) (messagepkg.Message, error) {
+	if len(f.persisted)+1 == f.failAt {
+		return messagepkg.Message{}, errors.New("insert failed")
+	}
+	return f.fakeMessageStore.Persist(ctx, input)
+}
+
+func TestImport_RejectsNewerVersion(t *testing.T) {
+	svc := &Service{messages: &fakeMessageStore{}, logger: slog.Default()}
+	_, err := svc.Import(context.Background(), Archive{Version: Version + 1}, ImportOptions{BotID: "bot-1"})
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	toolCall, err := json.Marshal(conversation.ModelMessage{
+		Role:    "assistant",
+		Content: json.RawMessage(`[{"type":"text","text":"Checking."},{"type":"tool-call","toolCallId":"c1","toolName":"web_search","input":{"query":"go"}}]`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := Archive{
+		BotID:      "bot-1",
+		ExportedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Messages: []messagepkg.Message{
+			{
+				Role:              "user",
+				SenderDisplayName: "Ann",
+				Platform:          "telegram",
+				Content:           textContent(t, "user", flow.FormatUserHeader("", "ci-1", "Ann", "telegram", "private", "", nil, "what is ```go```?")),
+			},
+			{Role: "assistant", Content: toolCall},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, archive); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"## User — Ann (telegram)",
+		"what is ```go```?",
+		"Checking.",
+		"**Tool call** `web_search`",
+		`"query": "go"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in markdown:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "display-name") {
+		t.Fatalf("expected user header stripped:\n%s", out)
+	}
+}
@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/flow"
+	messagepkg "github.com/memohai/memoh/internal/message"
+)
+
+const markdownTimeLayout = "2006-01-02 15:04:05 MST"
+
+// WriteMarkdown renders an archive as a human-readable transcript. It is
+// meant for reading; only JSON archives can be imported.
+func WriteMarkdown(w io.Writer, archive Archive) error {
+	var b strings.Builder
+	b.WriteString("# Conversation history\n\n")
+	fmt.Fprintf(&b, "- Bot: `%s`\n", archive.BotID)
+	if archive.RouteID != "" {
+		fmt.Fprintf(&b, "- Route: `%s`\n", archive.RouteID)
+	}
+	fmt.Fprintf(&b, "- Exported: %s\n", archive.ExportedAt.UTC().Format(markdownTimeLayout))
+	fmt.Fprintf(&b, "- Messages: %d\n", len(archive.Messages))
+	for _, msg := range archive.Messages {
+		b.WriteString("\n---\n\n")
+		writeMarkdownMessage(&b, msg)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownMessage(b *strings.Builder, msg messagepkg.Message) {
+	heading := roleTitle(msg.Role)
+	if name := strings.TrimSpace(msg.SenderDisplayName); name != "" && msg.Role == "user" {
+		heading += " — " + name
+	}
+	if msg.Platform != "" {
+		heading += " (" + msg.Platform + ")"
+	}
+	fmt.Fprintf(b, "## %s · %s\n", heading, msg.CreatedAt.UTC().Format(markdownTimeLayout))
+
+	var mm conversation.ModelMessage
+	if err := json.Unmarshal(msg.Content, &mm); err != nil {
+		writeMarkdownText(b, string(msg.Content))
+	} else {
+		writeMarkdownContent(b, msg.Role, mm)
+	}
+
+	if len(msg.Assets) > 0 {
+		b.WriteString("\nAttachments:\n")
+		for _, asset := range msg.Assets {
+			if asset.Mime != "" {
+				fmt.Fprintf(b, "- `%s` (%s)\n", asset.ContentHash, asset.Mime)
+			} else {
+				fmt.Fprintf(b, "- `%s`\n", asset.ContentHash)
+			}
+		}
+	}
+	if usage, ok := conversation.ParseUsage(msg.Usage, nil); ok {
+		fmt.Fprintf(b, "\n_Usage: %d input / %d output tokens_\n", usage.InputTokens, usage.OutputTokens)
+	}
+}
+
+func writeMarkdownContent(b *strings.Builder, role string, mm conversation.ModelMessage) {
+	var text string
+	if err := json.Unmarshal(mm.Content, &text); err == nil {
+		if role == "user" {
+			_, text = flow.SplitUserHeader(text)
+		}
+		writeMarkdownText(b, text)
+	} else {
+		var parts []map[string]json.RawMessage
+		if err := json.Unmarshal(mm.Content, &parts); err == nil {
+			for _, part := range parts {
+				writeMarkdownPart(b, role, part)
+			}
+		}
+	}
+	for _, call := range mm.ToolCalls {
+		writeToolBlock(b, "Tool call", call.Function.Name, prettyJSON(json.RawMessage(call.Function.Arguments)))
+	}
+}
+
+func writeMarkdownPart(b *strings.Builder, role string, part map[string]json.RawMessage) {
+	switch stringField(part, "type") {
+	case "text":
+		text := stringField(part, "text")
+		if role == "user" {
+			_, text = flow.SplitUserHeader(text)
+		}
+		writeMarkdownText(b, text)
+	case "tool-call":
+		writeToolBlock(b, "Tool call", stringField(part, "toolName"), prettyJSON(part["input"]))
+	case "tool-result":
+		output := part["output"]
+		var wrapped struct {
+			Value json.RawMessage `json:"value"`
+		}
+		if json.Unmarshal(output, &wrapped) == nil && len(wrapped.Value) > 0 {
+			output = wrapped.Value
+		}
+		writeToolBlock(b, "Tool result", stringField(part, "toolName"), prettyJSON(output))
+	}
+}
+
+func writeMarkdownText(b *strings.Builder, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	b.WriteString("\n")
+	b.WriteString(text)
+	b.WriteString("\n")
+}
+
+func writeToolBlock(b *strings.Builder, label, name, body string) {
+	if name != "" {
+		fmt.Fprintf(b, "\n**%s** `%s`\n", label, name)
+	} else {
+		fmt.Fprintf(b, "\n**%s**\n", label)
+	}
+	if body == "" {
+		return
+	}
+	fence := codeFence(body)
+	fmt.Fprintf(b, "\n%s\n%s\n%s\n", fence, body, fence)
+}
+
+// codeFence returns a backtick fence longer than any run inside body.
+func codeFence(body string) string {
+	longest, run := 0, 0
+	for _, r := range body {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func prettyJSON(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return string(raw)
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return string(raw)
+	}
+	return string(out)
+}
+
+func stringField(part map[string]json.RawMessage, key string) string {
+	var s string
+	_ = json.Unmarshal(part[key], &s)
+	return s
+}
+
+func roleTitle(role string) string {
+	if role == "" {
+		return "Message"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
@@ -0,0 +1,289 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	dbpkg "github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+// maxBranchDepth bounds the parent walk in Activate.
+const maxBranchDepth = 10000
+
+// Get returns one message of a bot, superseded or not.
+func (s *DBService) Get(ctx context.Context, botID, messageID string) (Message, error) {
+	pgBotID, pgMessageID, err := parseBranchIDs(botID, messageID)
+	if err != nil {
+		return Message{}, err
+	}
+	msg, err := s.get(ctx, pgBotID, pgMessageID)
+	if err != nil {
+		return Message{}, err
+	}
+	msgs := []Message{msg}
+	s.enrichAssets(ctx, msgs)
+	return msgs[0], nil
+}
+
+func (s *DBService) get(ctx context.Context, botID, messageID pgtype.UUID) (Message, error) {
+	row, err := s.queries.GetMessage(ctx, sqlc.GetMessageParams{ID: messageID, BotID: botID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Message{}, ErrMessageNotFound
+		}
+		return Message{}, err
+	}
+	return toMessageFromGetRow(row), nil
+}
+
+// LatestUserMessage returns the newest user message on the active branch of
+// a route; an empty routeID selects the bot's routeless history.
+func (s *DBService) LatestUserMessage(ctx context.Context, botID, routeID string) (Message, error) {
+	pgBotID, err := dbpkg.ParseUUID(botID)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid bot id: %w", err)
+	}
+	pgRouteID, err := parseOptionalUUID(routeID)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid route id: %w", err)
+	}
+	id, err := s.queries.GetLatestActiveUserMessageID(ctx, sqlc.GetLatestActiveUserMessageIDParams{
+		BotID:   pgBotID,
+		RouteID: pgRouteID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Message{}, ErrNothingToRegenerate
+		}
+		return Message{}, err
+	}
+	return s.get(ctx, pgBotID, id)
+}
+
+// SupersedeAfter retires the active messages that follow messageID.
+func (s *DBService) SupersedeAfter(ctx context.Context, botID, messageID string) (string, error) {
+	pgBotID, pgMessageID, err := parseBranchIDs(botID, messageID)
+	if err != nil {
+		return "", err
+	}
+	msg, err := s.activeMessage(ctx, pgBotID, pgMessageID)
+	if err != nil {
+		return "", err
+	}
+	return s.supersedeAfter(ctx, pgBotID, msg.RouteID, pgtype.Timestamptz{Time: msg.CreatedAt, Valid: true})
+}
+
+// SupersedeFrom retires messageID and the active messages that follow it.
+func (s *DBService) SupersedeFrom(ctx context.Context, botID, messageID string) error {
+	pgBotID, pgMessageID, err := parseBranchIDs(botID, messageID)
+	if err != nil {
+		return err
+	}
+	msg, err := s.activeMessage(ctx, pgBotID, pgMessageID)
+	if err != nil {
+		return err
+	}
+	// The active branch of a route is a single chain, so everything after
+	// the parent is the message itself and its descendants.
+	cutoff := pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true}
+	if msg.ParentID != "" {
+		parentID, err := dbpkg.ParseUUID(msg.ParentID)
+		if err != nil {
+			return err
+		}
+		parent, err := s.get(ctx, pgBotID, parentID)
+		if err != nil {
+			return fmt.Errorf("load parent message: %w", err)
+		}
+		cutoff = pgtype.Timestamptz{Time: parent.CreatedAt, Valid: true}
+	}
+	_, err = s.supersedeAfter(ctx, pgBotID, msg.RouteID, cutoff)
+	return err
+}
+
+// Alternatives lists messageID and its siblings.
+func (s *DBService) Alternatives(ctx context.Context, botID, messageID string) ([]Message, error) {
+	pgBotID, pgMessageID, err := parseBranchIDs(botID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := s.get(ctx, pgBotID, pgMessageID)
+	if err != nil {
+		return nil, err
+	}
+	parentID, err := parseOptionalUUID(msg.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListMessageAlternatives(ctx, sqlc.ListMessageAlternativesParams{
+		BotID:    pgBotID,
+		ParentID: parentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]Message, 0, len(rows))
+	for _, row := range rows {
+		msgs = append(msgs, toMessageFromAlternativeRow(row))
+	}
+	s.enrichAssets(ctx, msgs)
+	return msgs, nil
+}
+
+// Activate switches the active branch to the one through messageID: the
+// messages of its route after its nearest active ancestor are retired, and
+// messageID, its superseded ancestors and the continuation retired together
+// with it are restored. The switch happens in one transaction.
+func (s *DBService) Activate(ctx context.Context, botID, messageID string) error {
+	pgBotID, pgMessageID, err := parseBranchIDs(botID, messageID)
+	if err != nil {
+		return err
+	}
+	return s.InTx(ctx, func(tx *DBService) error {
+		return tx.activate(ctx, pgBotID, pgMessageID)
+	})
+}
+
+func (s *DBService) activate(ctx context.Context, pgBotID, pgMessageID pgtype.UUID) error {
+	target, err := s.get(ctx, pgBotID, pgMessageID)
+	if err != nil {
+		return err
+	}
+	if !target.Superseded {
+		return nil
+	}
+
+	cutoff := pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true}
+	var path []pgtype.UUID
+	current := target
+	for depth := 0; current.ParentID != ""; depth++ {
+		if depth >= maxBranchDepth {
+			return fmt.Errorf("message %s: branch too deep", pgMessageID.String())
+		}
+		parentID, err := dbpkg.ParseUUID(current.ParentID)
+		if err != nil {
+			return err
+		}
+		parent, err := s.get(ctx, pgBotID, parentID)
+		if err != nil {
+			return fmt.Errorf("load parent message: %w", err)
+		}
+		if !parent.Superseded {
+			cutoff = pgtype.Timestamptz{Time: parent.CreatedAt, Valid: true}
+			break
+		}
+		path = append(path, parentID)
+		current = parent
+	}
+
+	if _, err := s.supersedeAfter(ctx, pgBotID, target.RouteID, cutoff); err != nil {
+		return err
+	}
+	if len(path) > 0 {
+		if err := s.queries.RestoreMessages(ctx, sqlc.RestoreMessagesParams{BotID: pgBotID, Ids: path}); err != nil {
+			return fmt.Errorf("restore ancestors: %w", err)
+		}
+	}
+	if _, err := s.queries.RestoreMessageBranch(ctx, sqlc.RestoreMessageBranchParams{ID: pgMessageID, BotID: pgBotID}); err != nil {
+		return fmt.Errorf("restore branch: %w", err)
+	}
+	return nil
+}
+
+func (s *DBService) activeMessage(ctx context.Context, botID, messageID pgtype.UUID) (Message, error) {
+	msg, err := s.get(ctx, botID, messageID)
+	if err != nil {
+		return Message{}, err
+	}
+	if msg.Superseded {
+		return Message{}, fmt.Errorf("%w: message is not on the active branch", ErrMessageNotFound)
+	}
+	return msg, nil
+}
+
+// supersedeAfter retires the active messages of a route created after
+// cutoff and returns the earliest of them.
+func (s *DBService) supersedeAfter(ctx context.Context, botID pgtype.UUID, routeID string, cutoff pgtype.Timestamptz) (string, error) {
+	pgRouteID, err := parseOptionalUUID(routeID)
+	if err != nil {
+		return "", fmt.Errorf("invalid route id: %w", err)
+	}
+	rows, err := s.queries.SupersedeActiveMessagesAfter(ctx, sqlc.SupersedeActiveMessagesAfterParams{
+		BotID:     botID,
+		RouteID:   pgRouteID,
+		CreatedAt: cutoff,
+	})
+	if err != nil {
+		return "", fmt.Errorf("supersede messages: %w", err)
+	}
+	var first sqlc.SupersedeActiveMessagesAfterRow
+	for _, row := range rows {
+		if !first.ID.Valid || row.CreatedAt.Time.Before(first.CreatedAt.Time) {
+			first = row
+		}
+	}
+	return first.ID.String(), nil
+}
+
+func parseBranchIDs(botID, messageID string) (pgtype.UUID, pgtype.UUID, error) {
+	pgBotID, err := dbpkg.ParseUUID(botID)
+	if err != nil {
+		return pgtype.UUID{}, pgtype.UUID{}, fmt.Errorf("invalid bot id: %w", err)
+	}
+	pgMessageID, err := dbpkg.ParseUUID(messageID)
+	if err != nil {
+		return pgtype.UUID{}, pgtype.UUID{}, fmt.Errorf("invalid message id: %w", err)
+	}
+	return pgBotID, pgMessageID, nil
+}
+
+func toMessageFromGetRow(row sqlc.GetMessageRow) Message {
+	msg := toMessageFields(
+		row.ID,
+		row.BotID,
+		row.RouteID,
+		row.SenderChannelIdentityID,
+		row.SenderUserID,
+		row.SenderDisplayName,
+		row.SenderAvatarUrl,
+		row.Platform,
+		row.ExternalMessageID,
+		row.SourceReplyToMessageID,
+		row.Role,
+		row.Content,
+		row.Metadata,
+		row.Usage,
+		row.ParentID,
+		row.CreatedAt,
+	)
+	msg.Superseded = row.SupersededAt.Valid
+	return msg
+}
+
+func toMessageFromAlternativeRow(row sqlc.ListMessageAlternativesRow) Message {
+	msg := toMessageFields(
+		row.ID,
+		row.BotID,
+		row.RouteID,
+		row.SenderChannelIdentityID,
+		row.SenderUserID,
+		row.SenderDisplayName,
+		row.SenderAvatarUrl,
+		row.Platform,
+		row.ExternalMessageID,
+		row.SourceReplyToMessageID,
+		row.Role,
+		row.Content,
+		row.Metadata,
+		row.Usage,
+		row.ParentID,
+		row.CreatedAt,
+	)
+	msg.Superseded = row.SupersededAt.Valid
+	return msg
+}
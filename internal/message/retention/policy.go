@@ -0,0 +1,62 @@
+package retention
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidPolicy is returned for policies that cannot be applied.
+var ErrInvalidPolicy = errors.New("invalid retention policy")
+
+// Policy decides which history messages of a bot are purged. A message is
+// purged when it is older than MaxAgeDays or outside the newest MaxMessages.
+type Policy struct {
+	// MaxAgeDays purges messages older than this many days. Zero keeps
+	// messages regardless of age.
+	MaxAgeDays int `json:"max_age_days,omitempty"`
+	// MaxMessages keeps only the newest messages, superseded alternatives
+	// included. Zero sets no cap.
+	MaxMessages int `json:"max_messages,omitempty"`
+	// KeepStarred never purges starred messages.
+	KeepStarred bool `json:"keep_starred,omitempty"`
+	// ExportBeforePurge stores a JSON archive of the purged messages in the
+	// bot's media before deleting them. Nothing is deleted when the export
+	// fails.
+	ExportBeforePurge bool `json:"export_before_purge,omitempty"`
+}
+
+// Validate rejects negative limits.
+func (p Policy) Validate() error {
+	if p.MaxAgeDays < 0 {
+		return fmt.Errorf("%w: max_age_days must not be negative", ErrInvalidPolicy)
+	}
+	if p.MaxMessages < 0 {
+		return fmt.Errorf("%w: max_messages must not be negative", ErrInvalidPolicy)
+	}
+	return nil
+}
+
+// Active reports whether the policy purges anything.
+func (p Policy) Active() bool {
+	return p.MaxAgeDays > 0 || p.MaxMessages > 0
+}
+
+// Cutoff returns the creation time before which messages are too old, and
+// false when the policy has no age limit.
+func (p Policy) Cutoff(now time.Time) (time.Time, bool) {
+	if p.MaxAgeDays <= 0 {
+		return time.Time{}, false
+	}
+	return now.UTC().AddDate(0, 0, -p.MaxAgeDays), true
+}
+
+func parsePolicy(raw []byte) Policy {
+	var p Policy
+	if len(raw) == 0 {
+		return p
+	}
+	_ = json.Unmarshal(raw, &p)
+	return p
+}
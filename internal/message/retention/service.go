@@ -0,0 +1,385 @@
+// Package retention purges bot history according to per-bot retention
+// policies.
+package retention
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/media"
+	"github.com/memohai/memoh/internal/message/archive"
+)
+
+const (
+	// purgeInterval is how often scheduled purges run.
+	purgeInterval = time.Hour
+	// purgeBatchSize caps the messages removed per statement and written to
+	// one export archive, keeping each archive well under the media size
+	// limit however large the backlog is.
+	purgeBatchSize  = 500
+	defaultRunLimit = 50
+)
+
+// ErrExportUnavailable is returned when a policy asks for an export before
+// purging but no media storage is configured to keep it.
+var ErrExportUnavailable = errors.New("retention export requires media storage")
+
+// Trigger records what started a purge.
+type Trigger string
+
+const (
+	TriggerSchedule Trigger = "schedule"
+	TriggerManual   Trigger = "manual"
+)
+
+// Report describes the messages a purge removed, or would remove on a dry
+// run.
+type Report struct {
+	BotID  string `json:"bot_id"`
+	DryRun bool   `json:"dry_run"`
+	Policy Policy `json:"policy"`
+	// Cutoff is the creation time before which messages are too old.
+	Cutoff   *time.Time `json:"cutoff,omitempty"`
+	Messages int64      `json:"messages"`
+	// Assets counts attachment links of the messages. The media files
+	// themselves are kept.
+	Assets int64      `json:"assets"`
+	Oldest *time.Time `json:"oldest,omitempty"`
+	Newest *time.Time `json:"newest,omitempty"`
+	// ArchiveContentHashes are the media content hashes of the exports
+	// taken before deleting, one per batch of purged messages.
+	ArchiveContentHashes []string `json:"archive_content_hashes,omitempty"`
+}
+
+// Run is a past purge that deleted history.
+type Run struct {
+	ID                 string    `json:"id"`
+	BotID              string    `json:"bot_id"`
+	Trigger            Trigger   `json:"trigger"`
+	DeletedMessages    int64     `json:"deleted_messages"`
+	DeletedAssets      int64     `json:"deleted_assets"`
+	ArchiveContentHash string    `json:"archive_content_hash,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type store interface {
+	GetRetentionPolicy(ctx context.Context, id pgtype.UUID) (sqlc.GetRetentionPolicyRow, error)
+	UpdateRetentionPolicy(ctx context.Context, arg sqlc.UpdateRetentionPolicyParams) ([]byte, error)
+	ListRetentionPolicies(ctx context.Context) ([]sqlc.ListRetentionPoliciesRow, error)
+	ListRetentionCandidates(ctx context.Context, arg sqlc.ListRetentionCandidatesParams) ([]sqlc.ListRetentionCandidatesRow, error)
+	DeleteMessagesByIDs(ctx context.Context, arg sqlc.DeleteMessagesByIDsParams) (int64, error)
+	CreatePurgeRun(ctx context.Context, arg sqlc.CreatePurgeRunParams) (sqlc.MessagePurgeRun, error)
+	ListPurgeRuns(ctx context.Context, arg sqlc.ListPurgeRunsParams) ([]sqlc.MessagePurgeRun, error)
+}
+
+type exporter interface {
+	Export(ctx context.Context, opts archive.ExportOptions) (archive.Archive, error)
+}
+
+type mediaStore interface {
+	Ingest(ctx context.Context, input media.IngestInput) (media.Asset, error)
+}
+
+// Service applies retention policies to bot history, on a schedule and on
+// demand.
+type Service struct {
+	queries  store
+	archives exporter
+	media    mediaStore
+	logger   *slog.Logger
+	now      func() time.Time
+	// purgeMu keeps scheduled and manual purges from racing.
+	purgeMu sync.Mutex
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries, archiveService *archive.Service, mediaService *media.Service) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	s := &Service{
+		queries: queries,
+		logger:  log.With(slog.String("service", "retention")),
+		now:     time.Now,
+	}
+	if archiveService != nil {
+		s.archives = archiveService
+	}
+	if mediaService != nil {
+		s.media = mediaService
+	}
+	return s
+}
+
+// GetPolicy returns the retention policy of a bot.
+func (s *Service) GetPolicy(ctx context.Context, botID string) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	row, err := s.queries.GetRetentionPolicy(ctx, pgBotID)
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(row.RetentionPolicy), nil
+}
+
+// UpdatePolicy replaces the retention policy of a bot. It takes effect at the
+// next scheduled purge.
+func (s *Service) UpdatePolicy(ctx context.Context, botID string, policy Policy) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return Policy{}, err
+	}
+	stored, err := s.queries.UpdateRetentionPolicy(ctx, sqlc.UpdateRetentionPolicyParams{
+		RetentionPolicy: raw,
+		ID:              pgBotID,
+	})
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(stored), nil
+}
+
+// Preview reports what purging a bot now would remove without deleting
+// anything.
+func (s *Service) Preview(ctx context.Context, botID string) (Report, error) {
+	pgBotID, policy, err := s.loadPolicy(ctx, botID)
+	if err != nil {
+		return Report{}, err
+	}
+	report, _, err := s.plan(ctx, pgBotID, botID, policy)
+	if err != nil {
+		return Report{}, err
+	}
+	report.DryRun = true
+	return report, nil
+}
+
+// Purge deletes the messages of a bot its retention policy no longer keeps,
+// exporting them first when the policy asks for it.
+func (s *Service) Purge(ctx context.Context, botID string, trigger Trigger) (Report, error) {
+	pgBotID, policy, err := s.loadPolicy(ctx, botID)
+	if err != nil {
+		return Report{}, err
+	}
+	return s.purge(ctx, pgBotID, botID, policy, trigger)
+}
+
+// Runs lists the latest purges of a bot, newest first.
+func (s *Service) Runs(ctx context.Context, botID string, limit int32) ([]Run, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultRunLimit
+	}
+	rows, err := s.queries.ListPurgeRuns(ctx, sqlc.ListPurgeRunsParams{BotID: pgBotID, MaxCount: limit})
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(rows))
+	for _, row := range rows {
+		runs = append(runs, toRun(row))
+	}
+	return runs, nil
+}
+
+// Run purges every bot with a retention policy until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		s.purgeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) purgeAll(ctx context.Context) {
+	rows, err := s.queries.ListRetentionPolicies(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("list retention policies failed", slog.Any("error", err))
+		}
+		return
+	}
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			return
+		}
+		policy := parsePolicy(row.RetentionPolicy)
+		if !policy.Active() {
+			continue
+		}
+		botID := row.ID.String()
+		if _, err := s.purge(ctx, row.ID, botID, policy, TriggerSchedule); err != nil && ctx.Err() == nil {
+			s.logger.Warn("scheduled purge failed", slog.String("bot_id", botID), slog.Any("error", err))
+		}
+	}
+}
+
+func (s *Service) purge(ctx context.Context, pgBotID pgtype.UUID, botID string, policy Policy, trigger Trigger) (Report, error) {
+	s.purgeMu.Lock()
+	defer s.purgeMu.Unlock()
+
+	report, rows, err := s.plan(ctx, pgBotID, botID, policy)
+	if err != nil || len(rows) == 0 {
+		return report, err
+	}
+
+	// Each batch is exported, deleted and recorded as its own run, so a
+	// failure part way keeps what was already purged accounted for and the
+	// next run picks up the rest.
+	report.Messages, report.Assets = 0, 0
+	for start := 0; start < len(rows); start += purgeBatchSize {
+		batch := rows[start:min(start+purgeBatchSize, len(rows))]
+		ids := make([]pgtype.UUID, 0, len(batch))
+		var assets int64
+		for _, row := range batch {
+			ids = append(ids, row.ID)
+			assets += row.AssetCount
+		}
+		var hash string
+		if policy.ExportBeforePurge {
+			if hash, err = s.export(ctx, botID, ids); err != nil {
+				return Report{}, fmt.Errorf("export before purge: %w", err)
+			}
+			report.ArchiveContentHashes = append(report.ArchiveContentHashes, hash)
+		}
+		deleted, err := s.queries.DeleteMessagesByIDs(ctx, sqlc.DeleteMessagesByIDsParams{
+			BotID: pgBotID,
+			Ids:   ids,
+		})
+		if err != nil {
+			return Report{}, fmt.Errorf("delete messages: %w", err)
+		}
+		report.Messages += deleted
+		report.Assets += assets
+		if _, err := s.queries.CreatePurgeRun(ctx, sqlc.CreatePurgeRunParams{
+			BotID:              pgBotID,
+			Trigger:            string(trigger),
+			DeletedMessages:    deleted,
+			DeletedAssets:      assets,
+			ArchiveContentHash: hash,
+		}); err != nil {
+			s.logger.Warn("record purge run failed", slog.String("bot_id", botID), slog.Any("error", err))
+		}
+	}
+
+	s.logger.Info("purged bot history",
+		slog.String("bot_id", botID),
+		slog.String("trigger", string(trigger)),
+		slog.Int64("messages", report.Messages),
+		slog.Int64("assets", report.Assets),
+	)
+	return report, nil
+}
+
+// plan lists the messages policy removes, oldest first.
+func (s *Service) plan(ctx context.Context, pgBotID pgtype.UUID, botID string, policy Policy) (Report, []sqlc.ListRetentionCandidatesRow, error) {
+	report := Report{BotID: botID, Policy: policy}
+	if !policy.Active() {
+		return report, nil, nil
+	}
+	params := sqlc.ListRetentionCandidatesParams{
+		BotID:       pgBotID,
+		KeepStarred: policy.KeepStarred,
+	}
+	if cutoff, ok := policy.Cutoff(s.now()); ok {
+		params.Before = pgtype.Timestamptz{Time: cutoff, Valid: true}
+		report.Cutoff = &cutoff
+	}
+	if policy.MaxMessages > 0 {
+		params.KeepLatest = pgtype.Int8{Int64: int64(policy.MaxMessages), Valid: true}
+	}
+	rows, err := s.queries.ListRetentionCandidates(ctx, params)
+	if err != nil {
+		return Report{}, nil, fmt.Errorf("list retention candidates: %w", err)
+	}
+	for _, row := range rows {
+		report.Assets += row.AssetCount
+	}
+	report.Messages = int64(len(rows))
+	if len(rows) > 0 {
+		oldest := rows[0].CreatedAt.Time.UTC()
+		newest := rows[len(rows)-1].CreatedAt.Time.UTC()
+		report.Oldest = &oldest
+		report.Newest = &newest
+	}
+	return report, rows, nil
+}
+
+// export stores an archive of the given messages in the bot's media and
+// returns its content hash.
+func (s *Service) export(ctx context.Context, botID string, ids []pgtype.UUID) (string, error) {
+	if s.media == nil || s.archives == nil {
+		return "", ErrExportUnavailable
+	}
+	messageIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		messageIDs = append(messageIDs, id.String())
+	}
+	exported, err := s.archives.Export(ctx, archive.ExportOptions{BotID: botID, MessageIDs: messageIDs})
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(exported)
+	if err != nil {
+		return "", err
+	}
+	asset, err := s.media.Ingest(ctx, media.IngestInput{
+		BotID:       botID,
+		Mime:        "application/json",
+		Reader:      bytes.NewReader(data),
+		OriginalExt: ".json",
+	})
+	if err != nil {
+		return "", err
+	}
+	return asset.ContentHash, nil
+}
+
+func (s *Service) loadPolicy(ctx context.Context, botID string) (pgtype.UUID, Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return pgtype.UUID{}, Policy{}, err
+	}
+	row, err := s.queries.GetRetentionPolicy(ctx, pgBotID)
+	if err != nil {
+		return pgtype.UUID{}, Policy{}, err
+	}
+	return pgBotID, parsePolicy(row.RetentionPolicy), nil
+}
+
+func toRun(row sqlc.MessagePurgeRun) Run {
+	return Run{
+		ID:                 row.ID.String(),
+		BotID:              row.BotID.String(),
+		Trigger:            Trigger(row.Trigger),
+		DeletedMessages:    row.DeletedMessages,
+		DeletedAssets:      row.DeletedAssets,
+		ArchiveContentHash: row.ArchiveContentHash,
+		CreatedAt:          db.TimeFromPg(row.CreatedAt),
+	}
+}
@@ -0,0 +1,297 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/media"
+	messagepkg "github.com/memohai/memoh/internal/message"
+	"github.com/memohai/memoh/internal/message/archive"
+)
+
+const testBotID = "00000000-0000-0000-0000-0000000000b1"
+
+type fakeStore struct {
+	policy     Policy
+	candidates []sqlc.ListRetentionCandidatesRow
+	listed     []sqlc.ListRetentionCandidatesParams
+	deleted    [][]pgtype.UUID
+	runs       []sqlc.CreatePurgeRunParams
+}
+
+func (f *fakeStore) GetRetentionPolicy(ctx context.Context, id pgtype.UUID) (sqlc.GetRetentionPolicyRow, error) {
+	raw, _ := json.Marshal(f.policy)
+	return sqlc.GetRetentionPolicyRow{ID: id, RetentionPolicy: raw}, nil
+}
+
+func (f *fakeStore) UpdateRetentionPolicy(ctx context.Context, arg sqlc.UpdateRetentionPolicyParams) ([]byte, error) {
+	return arg.RetentionPolicy, nil
+}
+
+func (f *fakeStore) ListRetentionPolicies(ctx context.Context) ([]sqlc.ListRetentionPoliciesRow, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) ListRetentionCandidates(ctx context.Context, arg sqlc.ListRetentionCandidatesParams) ([]sqlc.ListRetentionCandidatesRow, error) {
+	f.listed = append(f.listed, arg)
+	return f.candidates, nil
+}
+
+func (f *fakeStore) DeleteMessagesByIDs(ctx context.Context, arg sqlc.DeleteMessagesByIDsParams) (int64, error) {
+	f.deleted = append(f.deleted, arg.Ids)
+	return int64(len(arg.Ids)), nil
+}
+
+func (f *fakeStore) CreatePurgeRun(ctx context.Context, arg sqlc.CreatePurgeRunParams) (sqlc.MessagePurgeRun, error) {
+	f.runs = append(f.runs, arg)
+	return sqlc.MessagePurgeRun{}, nil
+}
+
+func (f *fakeStore) ListPurgeRuns(ctx context.Context, arg sqlc.ListPurgeRunsParams) ([]sqlc.MessagePurgeRun, error) {
+	return nil, nil
+}
+
+type fakeExporter struct {
+	opts archive.ExportOptions
+}
+
+func (f *fakeExporter) Export(ctx context.Context, opts archive.ExportOptions) (archive.Archive, error) {
+	f.opts = opts
+	messages := make([]messagepkg.Message, 0, len(opts.MessageIDs))
+	for _, id := range opts.MessageIDs {
+		messages = append(messages, messagepkg.Message{ID: id})
+	}
+	return archive.Archive{Version: archive.Version, BotID: opts.BotID, Messages: messages}, nil
+}
+
+type fakeMedia struct {
+	ingested []media.IngestInput
+	data     [][]byte
+}
+
+func (f *fakeMedia) Ingest(ctx context.Context, input media.IngestInput) (media.Asset, error) {
+	data, err := io.ReadAll(input.Reader)
+	if err != nil {
+		return media.Asset{}, err
+	}
+	f.ingested = append(f.ingested, input)
+	f.data = append(f.data, data)
+	return media.Asset{ContentHash: "archive-hash"}, nil
+}
+
+// historyStore serves the messages read by the archive service. Like the
+// queries, ListSince leaves out superseded messages and ListByIDs does not.
+type historyStore struct {
+	messagepkg.Service
+	messages []messagepkg.Message
+}
+
+func (h *historyStore) ListSince(ctx context.Context, botID string, since time.Time) ([]messagepkg.Message, error) {
+	var active []messagepkg.Message
+	for _, msg := range h.messages {
+		if !msg.Superseded {
+			active = append(active, msg)
+		}
+	}
+	return active, nil
+}
+
+func (h *historyStore) ListByIDs(ctx context.Context, botID string, ids []string) ([]messagepkg.Message, error) {
+	var found []messagepkg.Message
+	for _, msg := range h.messages {
+		if slices.Contains(ids, msg.ID) {
+			found = append(found, msg)
+		}
+	}
+	return found, nil
+}
+
+func candidates(n int, start time.Time) []sqlc.ListRetentionCandidatesRow {
+	rows := make([]sqlc.ListRetentionCandidatesRow, n)
+	for i := range rows {
+		var id pgtype.UUID
+		id.Bytes[15] = byte(i)
+		id.Bytes[14] = byte(i >> 8)
+		id.Valid = true
+		rows[i] = sqlc.ListRetentionCandidatesRow{
+			ID:         id,
+			CreatedAt:  pgtype.Timestamptz{Time: start.Add(time.Duration(i) * time.Minute), Valid: true},
+			AssetCount: int64(i % 2),
+		}
+	}
+	return rows
+}
+
+func newTestService(store *fakeStore, now time.Time) *Service {
+	return &Service{
+		queries: store,
+		logger:  slog.Default(),
+		now:     func() time.Time { return now },
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := (Policy{MaxAgeDays: 30, MaxMessages: 1000}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (Policy{MaxAgeDays: -1}).Validate(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected ErrInvalidPolicy, got %v", err)
+	}
+	if err := (Policy{MaxMessages: -5}).Validate(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected ErrInvalidPolicy, got %v", err)
+	}
+}
+
+func TestPreviewReportsWithoutDeleting(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{
+		policy:     Policy{MaxAgeDays: 30, MaxMessages: 100, KeepStarred: true},
+		candidates: candidates(4, now.AddDate(0, -2, 0)),
+	}
+	svc := newTestService(store, now)
+
+	report, err := svc.Preview(context.Background(), testBotID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Messages != 4 || report.Assets != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Cutoff == nil || !report.Cutoff.Equal(now.AddDate(0, 0, -30)) {
+		t.Fatalf("unexpected cutoff %v", report.Cutoff)
+	}
+	if report.Oldest == nil || report.Newest == nil || !report.Newest.After(*report.Oldest) {
+		t.Fatalf("unexpected range %v..%v", report.Oldest, report.Newest)
+	}
+	params := store.listed[0]
+	if !params.Before.Valid || params.KeepLatest.Int64 != 100 || !params.KeepStarred {
+		t.Fatalf("unexpected candidate params %+v", params)
+	}
+	if len(store.deleted) != 0 || len(store.runs) != 0 {
+		t.Fatal("preview must not delete or record a run")
+	}
+}
+
+func TestPurgeWithoutAgeLimitLeavesCutoffOpen(t *testing.T) {
+	store := &fakeStore{policy: Policy{MaxMessages: 10}}
+	svc := newTestService(store, time.Now())
+
+	if _, err := svc.Purge(context.Background(), testBotID, TriggerManual); err != nil {
+		t.Fatal(err)
+	}
+	if store.listed[0].Before.Valid {
+		t.Fatalf("expected no cutoff, got %+v", store.listed[0].Before)
+	}
+	if len(store.runs) != 0 {
+		t.Fatal("a purge that removes nothing must not record a run")
+	}
+}
+
+func TestPurgeInactivePolicyDoesNothing(t *testing.T) {
+	store := &fakeStore{candidates: candidates(3, time.Now())}
+	svc := newTestService(store, time.Now())
+
+	report, err := svc.Purge(context.Background(), testBotID, TriggerManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Messages != 0 || len(store.listed) != 0 || len(store.deleted) != 0 {
+		t.Fatalf("expected no purge, got %+v", report)
+	}
+}
+
+func TestPurgeExportsThenDeletesInBatches(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	store := &fakeStore{
+		policy:     Policy{MaxAgeDays: 7, ExportBeforePurge: true},
+		candidates: candidates(purgeBatchSize+20, now.AddDate(0, -1, 0)),
+	}
+	exporter := &fakeExporter{}
+	mediaStore := &fakeMedia{}
+	svc := newTestService(store, now)
+	svc.archives = exporter
+	svc.media = mediaStore
+
+	report, err := svc.Purge(context.Background(), testBotID, TriggerSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mediaStore.ingested) != 2 || mediaStore.ingested[0].Mime != "application/json" {
+		t.Fatalf("expected one archive per batch, got %+v", mediaStore.ingested)
+	}
+	var first archive.Archive
+	if err := json.Unmarshal(mediaStore.data[0], &first); err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Messages) != purgeBatchSize || len(exporter.opts.MessageIDs) != 20 {
+		t.Fatalf("unexpected archive sizes %d and %d", len(first.Messages), len(exporter.opts.MessageIDs))
+	}
+	if len(store.deleted) != 2 || len(store.deleted[0]) != purgeBatchSize || len(store.deleted[1]) != 20 {
+		t.Fatalf("unexpected delete batches %d", len(store.deleted))
+	}
+	if report.Messages != purgeBatchSize+20 || len(report.ArchiveContentHashes) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(store.runs) != 2 {
+		t.Fatalf("expected a recorded run per batch, got %d", len(store.runs))
+	}
+	run := store.runs[1]
+	if run.Trigger != string(TriggerSchedule) || run.DeletedMessages != 20 || run.DeletedAssets != 10 || run.ArchiveContentHash != "archive-hash" {
+		t.Fatalf("unexpected run %+v", run)
+	}
+}
+
+func TestPurgeKeepsMessagesWhenExportUnavailable(t *testing.T) {
+	store := &fakeStore{
+		policy:     Policy{MaxAgeDays: 7, ExportBeforePurge: true},
+		candidates: candidates(3, time.Now().AddDate(0, -1, 0)),
+	}
+	svc := newTestService(store, time.Now())
+
+	if _, err := svc.Purge(context.Background(), testBotID, TriggerManual); !errors.Is(err, ErrExportUnavailable) {
+		t.Fatalf("expected ErrExportUnavailable, got %v", err)
+	}
+	if len(store.deleted) != 0 || len(store.runs) != 0 {
+		t.Fatal("nothing may be deleted when the export fails")
+	}
+}
+
+func TestPurgeExportsSupersededCandidates(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	rows := candidates(3, now.AddDate(0, -1, 0))
+	history := &historyStore{}
+	for i, row := range rows {
+		history.messages = append(history.messages, messagepkg.Message{ID: row.ID.String(), Superseded: i == 1})
+	}
+	store := &fakeStore{
+		policy:     Policy{MaxAgeDays: 7, ExportBeforePurge: true},
+		candidates: rows,
+	}
+	mediaStore := &fakeMedia{}
+	svc := newTestService(store, now)
+	svc.archives = archive.NewService(slog.Default(), history, nil)
+	svc.media = mediaStore
+
+	if _, err := svc.Purge(context.Background(), testBotID, TriggerManual); err != nil {
+		t.Fatal(err)
+	}
+	if len(mediaStore.data) != 1 {
+		t.Fatalf("expected one archive, got %d", len(mediaStore.data))
+	}
+	var exported archive.Archive
+	if err := json.Unmarshal(mediaStore.data[0], &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Messages) != 3 || !exported.Messages[1].Superseded || exported.Messages[1].ID != rows[1].ID.String() {
+		t.Fatalf("expected every purged message archived, the superseded one included, got %+v", exported.Messages)
+	}
+}
@@ -0,0 +1,103 @@
+package message
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	dbpkg "github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Search runs a full-text search over the active history of a bot.
+func (s *DBService) Search(ctx context.Context, botID string, query SearchQuery) ([]SearchResult, error) {
+	text := strings.TrimSpace(query.Query)
+	if text == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	pgBotID, err := dbpkg.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	params := sqlc.SearchMessagesParams{
+		BotID:    pgBotID,
+		Query:    text,
+		Pattern:  escapeLike(text),
+		Platform: toPgText(query.Platform),
+		Role:     toPgText(query.Role),
+		MaxCount: limit,
+	}
+	if sender := strings.TrimSpace(query.Sender); sender != "" {
+		if senderID, err := dbpkg.ParseUUID(sender); err == nil {
+			params.SenderID = senderID
+		} else {
+			params.SenderName = toPgText(escapeLike(sender))
+		}
+	}
+	if query.RouteScoped {
+		routeID, err := parseOptionalUUID(query.RouteID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route id: %w", err)
+		}
+		params.RouteScoped, params.RouteID = true, routeID
+	}
+	if query.StartTime != nil {
+		params.StartTime = pgtype.Timestamptz{Time: *query.StartTime, Valid: true}
+	}
+	if query.EndTime != nil {
+		params.EndTime = pgtype.Timestamptz{Time: *query.EndTime, Valid: true}
+	}
+	rows, err := s.queries.SearchMessages(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]Message, 0, len(rows))
+	for _, row := range rows {
+		msgs = append(msgs, toMessageFields(
+			row.ID,
+			row.BotID,
+			row.RouteID,
+			row.SenderChannelIdentityID,
+			row.SenderUserID,
+			row.SenderDisplayName,
+			row.SenderAvatarUrl,
+			row.Platform,
+			row.ExternalMessageID,
+			row.SourceReplyToMessageID,
+			row.Role,
+			row.Content,
+			row.Metadata,
+			row.Usage,
+			row.ParentID,
+			row.CreatedAt,
+		))
+	}
+	s.enrichAssets(ctx, msgs)
+	results := make([]SearchResult, 0, len(rows))
+	for i, row := range rows {
+		results = append(results, SearchResult{Message: msgs[i], Text: row.SearchText.String})
+	}
+	return results, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match itself literally in a LIKE pattern with a
+// backslash escape.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,17 @@
+package message
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	cases := map[string]string{
+		"plain":      "plain",
+		"100%":       `100\%`,
+		"snake_case": `snake\_case`,
+		`C:\tmp`:     `C:\\tmp`,
+	}
+	for in, want := range cases {
+		if got := escapeLike(in); got != want {
+			t.Errorf("escapeLike(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
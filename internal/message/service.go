@@ -8,7 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	dbpkg "github.com/memohai/memoh/internal/db"
 	"github.com/memohai/memoh/internal/db/sqlc"
@@ -17,13 +19,14 @@ import (
 
 // DBService persists and reads bot history messages.
 type DBService struct {
+	pool      *pgxpool.Pool
 	queries   *sqlc.Queries
 	logger    *slog.Logger
 	publisher event.Publisher
 }
 
 // NewService creates a message service.
-func NewService(log *slog.Logger, queries *sqlc.Queries, publishers ...event.Publisher) *DBService {
+func NewService(log *slog.Logger, pool *pgxpool.Pool, queries *sqlc.Queries, publishers ...event.Publisher) *DBService {
 	if log == nil {
 		log = slog.Default()
 	}
@@ -32,12 +35,48 @@ func NewService(log *slog.Logger, queries *sqlc.Queries, publishers ...event.Pub
 		publisher = publishers[0]
 	}
 	return &DBService{
+		pool:      pool,
 		queries:   queries,
 		logger:    log.With(slog.String("service", "message")),
 		publisher: publisher,
 	}
 }
 
+// InTx runs fn with a service whose queries share one transaction, which is
+// committed when fn returns nil. Message events are held back until then.
+func (s *DBService) InTx(ctx context.Context, fn func(tx *DBService) error) error {
+	if s.pool == nil {
+		return fmt.Errorf("message service has no database pool")
+	}
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin message tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	held := &heldEvents{}
+	if err := fn(&DBService{queries: s.queries.WithTx(tx), logger: s.logger, publisher: held}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit message tx: %w", err)
+	}
+	if s.publisher != nil {
+		for _, e := range held.events {
+			s.publisher.Publish(e)
+		}
+	}
+	return nil
+}
+
+// heldEvents collects the events published inside a transaction.
+type heldEvents struct {
+	events []event.Event
+}
+
+func (h *heldEvents) Publish(e event.Event) {
+	h.events = append(h.events, e)
+}
+
 // Persist writes a single message to bot_history_messages.
 func (s *DBService) Persist(ctx context.Context, input PersistInput) (Message, error) {
 	pgBotID, err := dbpkg.ParseUUID(input.BotID)
@@ -57,6 +96,10 @@ func (s *DBService) Persist(ctx context.Context, input PersistInput) (Message, e
 	if err != nil {
 		return Message{}, fmt.Errorf("invalid sender user id: %w", err)
 	}
+	pgParentID, err := parseOptionalUUID(input.ParentID)
+	if err != nil {
+		return Message{}, fmt.Errorf("invalid parent id: %w", err)
+	}
 
 	metaBytes, err := json.Marshal(nonNilMap(input.Metadata))
 	if err != nil {
@@ -80,6 +123,9 @@ func (s *DBService) Persist(ctx context.Context, input PersistInput) (Message, e
 		Content:                 content,
 		Metadata:                metaBytes,
 		Usage:                   input.Usage,
+		ParentID:                pgParentID,
+		NoParent:                input.NoParent,
+		CreatedAt:               toPgTimestamptz(input.CreatedAt),
 	})
 	if err != nil {
 		return Message{}, err
@@ -167,6 +213,91 @@ func (s *DBService) ListSince(ctx context.Context, botID string, since time.Time
 	return msgs, nil
 }
 
+// ListByIDs returns the given messages of a bot, superseded ones included.
+func (s *DBService) ListByIDs(ctx context.Context, botID string, ids []string) ([]Message, error) {
+	pgBotID, err := dbpkg.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	pgIDs := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
+		pgID, err := dbpkg.ParseUUID(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message id %q: %w", id, err)
+		}
+		pgIDs = append(pgIDs, pgID)
+	}
+	if len(pgIDs) == 0 {
+		return []Message{}, nil
+	}
+	rows, err := s.queries.ListMessagesByIDs(ctx, sqlc.ListMessagesByIDsParams{BotID: pgBotID, Ids: pgIDs})
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]Message, 0, len(rows))
+	for _, row := range rows {
+		msg := toMessageFields(
+			row.ID,
+			row.BotID,
+			row.RouteID,
+			row.SenderChannelIdentityID,
+			row.SenderUserID,
+			row.SenderDisplayName,
+			row.SenderAvatarUrl,
+			row.Platform,
+			row.ExternalMessageID,
+			row.SourceReplyToMessageID,
+			row.Role,
+			row.Content,
+			row.Metadata,
+			row.Usage,
+			row.ParentID,
+			row.CreatedAt,
+		)
+		msg.Superseded = row.SupersededAt.Valid
+		msgs = append(msgs, msg)
+	}
+	s.enrichAssets(ctx, msgs)
+	return msgs, nil
+}
+
+// ListSourceIDs maps the archived ID of every message of a bot to the
+// message: imported messages by the message they were imported from, the
+// others by their own ID.
+func (s *DBService) ListSourceIDs(ctx context.Context, botID string) (map[string]string, error) {
+	pgBotID, err := dbpkg.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListMessageSourceIDs(ctx, pgBotID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string, len(rows))
+	for _, row := range rows {
+		ids[row.SourceID] = row.ID.String()
+	}
+	return ids, nil
+}
+
+// ListSenderIDs returns the channel identity and user IDs that have
+// messages in a bot.
+func (s *DBService) ListSenderIDs(ctx context.Context, botID string) (map[string]bool, error) {
+	pgBotID, err := dbpkg.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListMessageSenderIDs(ctx, pgBotID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(rows))
+	for _, id := range rows {
+		ids[id] = true
+	}
+	return ids, nil
+}
+
 // ListActiveSince returns bot messages since a given time, excluding passive_sync messages.
 func (s *DBService) ListActiveSince(ctx context.Context, botID string, since time.Time) ([]Message, error) {
 	pgBotID, err := dbpkg.ParseUUID(botID)
@@ -228,7 +359,107 @@ func (s *DBService) DeleteByBot(ctx context.Context, botID string) error {
 	if err != nil {
 		return err
 	}
-	return s.queries.DeleteMessagesByBot(ctx, pgBotID)
+	if err := s.queries.DeleteMessagesByBot(ctx, pgBotID); err != nil {
+		return err
+	}
+	// Summaries describe the deleted history and must not outlive it.
+	return s.queries.DeleteHistorySummariesByBot(ctx, pgBotID)
+}
+
+// SetReaction records or replaces a user's reaction on an assistant message.
+func (s *DBService) SetReaction(ctx context.Context, botID, messageID, userID, reaction string) error {
+	if reaction != ReactionUp && reaction != ReactionDown {
+		return fmt.Errorf("invalid reaction %q", reaction)
+	}
+	pgBotID, pgMessageID, pgUserID, err := parseReactionIDs(botID, messageID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := s.queries.UpsertMessageReaction(ctx, sqlc.UpsertMessageReactionParams{
+		UserID:    pgUserID,
+		Reaction:  reaction,
+		MessageID: pgMessageID,
+		BotID:     pgBotID,
+	})
+	if err != nil {
+		return fmt.Errorf("set reaction: %w", err)
+	}
+	if affected == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// ClearReaction removes a user's reaction from a message.
+func (s *DBService) ClearReaction(ctx context.Context, botID, messageID, userID string) error {
+	pgBotID, pgMessageID, pgUserID, err := parseReactionIDs(botID, messageID, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.queries.DeleteMessageReaction(ctx, sqlc.DeleteMessageReactionParams{
+		MessageID: pgMessageID,
+		BotID:     pgBotID,
+		UserID:    pgUserID,
+	}); err != nil {
+		return fmt.Errorf("clear reaction: %w", err)
+	}
+	return nil
+}
+
+// Star marks a message as starred by a user.
+func (s *DBService) Star(ctx context.Context, botID, messageID, userID string) error {
+	pgBotID, pgMessageID, pgUserID, err := parseReactionIDs(botID, messageID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := s.queries.StarMessage(ctx, sqlc.StarMessageParams{
+		StarredBy: pgUserID,
+		MessageID: pgMessageID,
+		BotID:     pgBotID,
+	})
+	if err != nil {
+		return fmt.Errorf("star message: %w", err)
+	}
+	if affected == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// Unstar removes the star from a message. Unstarring a message that has no
+// star is not an error.
+func (s *DBService) Unstar(ctx context.Context, botID, messageID string) error {
+	pgBotID, err := dbpkg.ParseUUID(botID)
+	if err != nil {
+		return fmt.Errorf("invalid bot id: %w", err)
+	}
+	pgMessageID, err := dbpkg.ParseUUID(messageID)
+	if err != nil {
+		return fmt.Errorf("invalid message id: %w", err)
+	}
+	if _, err := s.queries.UnstarMessage(ctx, sqlc.UnstarMessageParams{
+		MessageID: pgMessageID,
+		BotID:     pgBotID,
+	}); err != nil {
+		return fmt.Errorf("unstar message: %w", err)
+	}
+	return nil
+}
+
+func parseReactionIDs(botID, messageID, userID string) (pgtype.UUID, pgtype.UUID, pgtype.UUID, error) {
+	pgBotID, err := dbpkg.ParseUUID(botID)
+	if err != nil {
+		return pgtype.UUID{}, pgtype.UUID{}, pgtype.UUID{}, fmt.Errorf("invalid bot id: %w", err)
+	}
+	pgMessageID, err := dbpkg.ParseUUID(messageID)
+	if err != nil {
+		return pgtype.UUID{}, pgtype.UUID{}, pgtype.UUID{}, fmt.Errorf("invalid message id: %w", err)
+	}
+	pgUserID, err := dbpkg.ParseUUID(userID)
+	if err != nil {
+		return pgtype.UUID{}, pgtype.UUID{}, pgtype.UUID{}, fmt.Errorf("invalid user id: %w", err)
+	}
+	return pgBotID, pgMessageID, pgUserID, nil
 }
 
 func toMessageFromCreate(row sqlc.CreateMessageRow) Message {
@@ -247,6 +478,7 @@ func toMessageFromCreate(row sqlc.CreateMessageRow) Message {
 		row.Content,
 		row.Metadata,
 		row.Usage,
+		row.ParentID,
 		row.CreatedAt,
 	)
 }
@@ -267,6 +499,7 @@ func toMessageFromListRow(row sqlc.ListMessagesRow) Message {
 		row.Content,
 		row.Metadata,
 		row.Usage,
+		row.ParentID,
 		row.CreatedAt,
 	)
 }
@@ -287,6 +520,7 @@ func toMessageFromSinceRow(row sqlc.ListMessagesSinceRow) Message {
 		row.Content,
 		row.Metadata,
 		row.Usage,
+		row.ParentID,
 		row.CreatedAt,
 	)
 }
@@ -307,6 +541,7 @@ func toMessageFromActiveSinceRow(row sqlc.ListActiveMessagesSinceRow) Message {
 		row.Content,
 		row.Metadata,
 		row.Usage,
+		row.ParentID,
 		row.CreatedAt,
 	)
 }
@@ -327,6 +562,7 @@ func toMessageFromLatestRow(row sqlc.ListMessagesLatestRow) Message {
 		row.Content,
 		row.Metadata,
 		row.Usage,
+		row.ParentID,
 		row.CreatedAt,
 	)
 }
@@ -346,6 +582,7 @@ func toMessageFields(
 	content []byte,
 	metadata []byte,
 	usage []byte,
+	parentID pgtype.UUID,
 	createdAt pgtype.Timestamptz,
 ) Message {
 	return Message{
@@ -363,6 +600,7 @@ func toMessageFields(
 		Content:                 json.RawMessage(content),
 		Metadata:                parseJSONMap(metadata),
 		Usage:                   json.RawMessage(usage),
+		ParentID:                parentID.String(),
 		CreatedAt:               createdAt.Time,
 	}
 }
@@ -415,6 +653,7 @@ func toMessageFromBeforeRow(row sqlc.ListMessagesBeforeRow) Message {
 		row.Content,
 		row.Metadata,
 		row.Usage,
+		row.ParentID,
 		row.CreatedAt,
 	)
 }
@@ -459,6 +698,13 @@ func coalesce(values ...string) string {
 	return ""
 }
 
+func toPgTimestamptz(t time.Time) pgtype.Timestamptz {
+	if t.IsZero() {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
 func toPgInt8(v int64) pgtype.Int8 {
 	if v == 0 {
 		return pgtype.Int8{}
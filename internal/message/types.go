@@ -3,9 +3,28 @@ package message
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 )
 
+// Reactions users can leave on assistant messages.
+const (
+	ReactionUp   = "up"
+	ReactionDown = "down"
+)
+
+// ErrMessageNotFound is returned when a reaction targets a message that is not
+// an assistant message of the bot.
+var ErrMessageNotFound = errors.New("message not found")
+
+// ErrNotUserMessage is returned when editing a message that was not sent by
+// a user.
+var ErrNotUserMessage = errors.New("only user messages can be edited")
+
+// ErrNothingToRegenerate is returned when a conversation has no user message
+// on its active branch.
+var ErrNothingToRegenerate = errors.New("no user message to regenerate")
+
 // MessageAsset carries media asset metadata attached to a message.
 // ContentHash is the content-addressed identifier for the media file.
 type MessageAsset struct {
@@ -34,6 +53,8 @@ type Message struct {
 	Metadata                map[string]any  `json:"metadata,omitempty"`
 	Usage                   json.RawMessage `json:"usage,omitempty"`
 	Assets                  []MessageAsset  `json:"assets,omitempty"`
+	ParentID                string          `json:"parent_id,omitempty"`
+	Superseded              bool            `json:"superseded,omitempty"`
 	CreatedAt               time.Time       `json:"created_at"`
 }
 
@@ -62,6 +83,12 @@ type PersistInput struct {
 	Metadata                map[string]any
 	Usage                   json.RawMessage
 	Assets                  []AssetRef
+	// ParentID defaults to the latest message on the active branch.
+	ParentID string
+	// NoParent starts a new thread when ParentID is empty.
+	NoParent bool
+	// CreatedAt defaults to now; imports set it to the original time.
+	CreatedAt time.Time
 }
 
 // Writer defines write behavior needed by the inbound router.
@@ -74,8 +101,79 @@ type Service interface {
 	Writer
 	List(ctx context.Context, botID string) ([]Message, error)
 	ListSince(ctx context.Context, botID string, since time.Time) ([]Message, error)
+	// ListByIDs returns the given messages of a bot, superseded ones
+	// included, oldest first. Unknown IDs are skipped.
+	ListByIDs(ctx context.Context, botID string, ids []string) ([]Message, error)
+	// ListSourceIDs maps the archived ID of every message of a bot to the
+	// message, so imports can skip what is already there.
+	ListSourceIDs(ctx context.Context, botID string) (map[string]string, error)
+	// ListSenderIDs returns the channel identity and user IDs that have
+	// messages in a bot.
+	ListSenderIDs(ctx context.Context, botID string) (map[string]bool, error)
 	ListActiveSince(ctx context.Context, botID string, since time.Time) ([]Message, error)
 	ListLatest(ctx context.Context, botID string, limit int32) ([]Message, error)
 	ListBefore(ctx context.Context, botID string, before time.Time, limit int32) ([]Message, error)
 	DeleteByBot(ctx context.Context, botID string) error
 }
+
+// Reactor records user reactions on assistant messages. Reactions feed the
+// model variant report.
+type Reactor interface {
+	SetReaction(ctx context.Context, botID, messageID, userID, reaction string) error
+	ClearReaction(ctx context.Context, botID, messageID, userID string) error
+}
+
+// Starrer marks messages a retention policy with keep_starred never purges.
+type Starrer interface {
+	Star(ctx context.Context, botID, messageID, userID string) error
+	Unstar(ctx context.Context, botID, messageID string) error
+}
+
+// Brancher rewrites which branch of a conversation is active. Superseded
+// messages are kept, so regenerated and edited turns stay browsable as
+// alternatives of the message they replaced.
+type Brancher interface {
+	Get(ctx context.Context, botID, messageID string) (Message, error)
+	// LatestUserMessage returns the newest active user message of a route.
+	LatestUserMessage(ctx context.Context, botID, routeID string) (Message, error)
+	// SupersedeAfter retires every active message after messageID and returns
+	// the first retired one, or "" when there was none.
+	SupersedeAfter(ctx context.Context, botID, messageID string) (string, error)
+	// SupersedeFrom retires messageID and every active message after it.
+	SupersedeFrom(ctx context.Context, botID, messageID string) error
+	// Alternatives lists the messages sharing messageID's parent, including
+	// messageID itself, oldest first.
+	Alternatives(ctx context.Context, botID, messageID string) ([]Message, error)
+	// Activate makes the branch through messageID the active one.
+	Activate(ctx context.Context, botID, messageID string) error
+}
+
+// SearchQuery filters a history search. Empty fields match everything.
+type SearchQuery struct {
+	Query    string
+	Platform string
+	Role     string
+	// Sender matches a channel identity or user ID, or else part of the
+	// sender's display name.
+	Sender    string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     int32
+	// RouteScoped limits the search to RouteID, or to the routeless history
+	// when RouteID is empty.
+	RouteScoped bool
+	RouteID     string
+}
+
+// SearchResult is a matched message with its plain text, the user header
+// stripped.
+type SearchResult struct {
+	Message
+	Text string `json:"text"`
+}
+
+// Searcher finds messages on the active branch by their text, best match
+// first.
+type Searcher interface {
+	Search(ctx context.Context, botID string, query SearchQuery) ([]SearchResult, error)
+}
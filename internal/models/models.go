@@ -132,9 +132,9 @@ func (s *Service) List(ctx context.Context) ([]GetResponse, error) {
 	return convertToGetResponseList(dbModels), nil
 }
 
-// ListByType returns models filtered by type (chat or embedding)
+// ListByType returns models filtered by type (chat, embedding or speech)
 func (s *Service) ListByType(ctx context.Context, modelType ModelType) ([]GetResponse, error) {
-	if modelType != ModelTypeChat && modelType != ModelTypeEmbedding {
+	if !isValidModelType(modelType) {
 		return nil, fmt.Errorf("invalid model type: %s", modelType)
 	}
 
@@ -178,7 +178,7 @@ func (s *Service) ListByProviderID(ctx context.Context, providerID string) ([]Ge
 
 // ListByProviderIDAndType returns models filtered by provider ID and type.
 func (s *Service) ListByProviderIDAndType(ctx context.Context, providerID string, modelType ModelType) ([]GetResponse, error) {
-	if modelType != ModelTypeChat && modelType != ModelTypeEmbedding {
+	if !isValidModelType(modelType) {
 		return nil, fmt.Errorf("invalid model type: %s", modelType)
 	}
 	if strings.TrimSpace(providerID) == "" {
@@ -348,7 +348,7 @@ func (s *Service) Count(ctx context.Context) (int64, error) {
 
 // CountByType returns the number of models of a specific type
 func (s *Service) CountByType(ctx context.Context, modelType ModelType) (int64, error) {
-	if modelType != ModelTypeChat && modelType != ModelTypeEmbedding {
+	if !isValidModelType(modelType) {
 		return 0, fmt.Errorf("invalid model type: %s", modelType)
 	}
 
@@ -423,6 +423,15 @@ func normalizeModalities(modalities []string, fallback []string) []string {
 	return modalities
 }
 
+func isValidModelType(modelType ModelType) bool {
+	switch modelType {
+	case ModelTypeChat, ModelTypeEmbedding, ModelTypeSpeech:
+		return true
+	default:
+		return false
+	}
+}
+
 func isValidClientType(clientType ClientType) bool {
 	switch clientType {
 	case ClientTypeOpenAIResponses,
@@ -203,6 +203,15 @@ func TestModel_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid speech model",
+			model: models.Model{
+				ModelID:       "tts-1",
+				LlmProviderID: "11111111-1111-1111-1111-111111111111",
+				Type:          models.ModelTypeSpeech,
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid input modality",
 			model: models.Model{
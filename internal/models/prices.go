@@ -0,0 +1,187 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+const defaultCurrency = "USD"
+
+var ErrPriceNotFound = errors.New("model price not found")
+
+// Price is what a model costs per million tokens. Cached input and reasoning
+// prices are optional and fall back to the input and output price.
+type Price struct {
+	ModelUUID             string    `json:"model_uuid"`
+	ModelID               string    `json:"model_id,omitempty"`
+	Name                  string    `json:"name,omitempty"`
+	Currency              string    `json:"currency"`
+	InputPerMillion       float64   `json:"input_per_million"`
+	OutputPerMillion      float64   `json:"output_per_million"`
+	CachedInputPerMillion *float64  `json:"cached_input_per_million,omitempty"`
+	ReasoningPerMillion   *float64  `json:"reasoning_per_million,omitempty"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+type PriceRequest struct {
+	Currency              string   `json:"currency"`
+	InputPerMillion       float64  `json:"input_per_million"`
+	OutputPerMillion      float64  `json:"output_per_million"`
+	CachedInputPerMillion *float64 `json:"cached_input_per_million,omitempty"`
+	ReasoningPerMillion   *float64 `json:"reasoning_per_million,omitempty"`
+}
+
+func (r *PriceRequest) validate() error {
+	for _, v := range []*float64{&r.InputPerMillion, &r.OutputPerMillion, r.CachedInputPerMillion, r.ReasoningPerMillion} {
+		if v != nil && *v < 0 {
+			return errors.New("prices must not be negative")
+		}
+	}
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	if r.Currency == "" {
+		r.Currency = defaultCurrency
+	}
+	if len(r.Currency) != 3 {
+		return errors.New("currency must be a 3-letter code")
+	}
+	return nil
+}
+
+// Cost returns the price of a round's usage. Input tokens include cached
+// input and output tokens include reasoning, as reported by the gateway.
+func (p Price) Cost(inputTokens, outputTokens, cachedInputTokens, reasoningTokens int64) float64 {
+	cachedPrice := p.InputPerMillion
+	if p.CachedInputPerMillion != nil {
+		cachedPrice = *p.CachedInputPerMillion
+	}
+	reasoningPrice := p.OutputPerMillion
+	if p.ReasoningPerMillion != nil {
+		reasoningPrice = *p.ReasoningPerMillion
+	}
+	cachedInputTokens = min(cachedInputTokens, inputTokens)
+	reasoningTokens = min(reasoningTokens, outputTokens)
+	cost := float64(inputTokens-cachedInputTokens)*p.InputPerMillion +
+		float64(cachedInputTokens)*cachedPrice +
+		float64(outputTokens-reasoningTokens)*p.OutputPerMillion +
+		float64(reasoningTokens)*reasoningPrice
+	return cost / 1_000_000
+}
+
+// ListPrices returns the prices of all priced models.
+func (s *Service) ListPrices(ctx context.Context) ([]Price, error) {
+	rows, err := s.queries.ListModelPrices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model prices: %w", err)
+	}
+	prices := make([]Price, 0, len(rows))
+	for _, row := range rows {
+		prices = append(prices, Price{
+			ModelUUID:             row.ModelUuid.String(),
+			ModelID:               row.ModelID,
+			Name:                  db.TextToString(row.ModelName),
+			Currency:              row.Currency,
+			InputPerMillion:       row.InputPerMillion,
+			OutputPerMillion:      row.OutputPerMillion,
+			CachedInputPerMillion: float8Ptr(row.CachedInputPerMillion),
+			ReasoningPerMillion:   float8Ptr(row.ReasoningPerMillion),
+			UpdatedAt:             db.TimeFromPg(row.UpdatedAt),
+		})
+	}
+	return prices, nil
+}
+
+// GetPrice returns the price of a model.
+func (s *Service) GetPrice(ctx context.Context, id string) (Price, error) {
+	pgID, err := db.ParseUUID(id)
+	if err != nil {
+		return Price{}, fmt.Errorf("invalid ID: %w", err)
+	}
+	row, err := s.queries.GetModelPrice(ctx, pgID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Price{}, ErrPriceNotFound
+		}
+		return Price{}, fmt.Errorf("failed to get model price: %w", err)
+	}
+	return convertToPrice(row), nil
+}
+
+// SetPrice creates or replaces the price of a model.
+func (s *Service) SetPrice(ctx context.Context, id string, req PriceRequest) (Price, error) {
+	if err := req.validate(); err != nil {
+		return Price{}, fmt.Errorf("validation failed: %w", err)
+	}
+	pgID, err := db.ParseUUID(id)
+	if err != nil {
+		return Price{}, fmt.Errorf("invalid ID: %w", err)
+	}
+	if _, err := s.queries.GetModelByID(ctx, pgID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Price{}, errors.New("model not found")
+		}
+		return Price{}, fmt.Errorf("failed to get model: %w", err)
+	}
+	row, err := s.queries.UpsertModelPrice(ctx, sqlc.UpsertModelPriceParams{
+		ModelUuid:             pgID,
+		Currency:              req.Currency,
+		InputPerMillion:       req.InputPerMillion,
+		OutputPerMillion:      req.OutputPerMillion,
+		CachedInputPerMillion: toFloat8(req.CachedInputPerMillion),
+		ReasoningPerMillion:   toFloat8(req.ReasoningPerMillion),
+	})
+	if err != nil {
+		return Price{}, fmt.Errorf("failed to set model price: %w", err)
+	}
+	return convertToPrice(row), nil
+}
+
+// DeletePrice removes the price of a model.
+func (s *Service) DeletePrice(ctx context.Context, id string) error {
+	pgID, err := db.ParseUUID(id)
+	if err != nil {
+		return fmt.Errorf("invalid ID: %w", err)
+	}
+	n, err := s.queries.DeleteModelPrice(ctx, pgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete model price: %w", err)
+	}
+	if n == 0 {
+		return ErrPriceNotFound
+	}
+	return nil
+}
+
+func convertToPrice(row sqlc.ModelPrice) Price {
+	return Price{
+		ModelUUID:             row.ModelUuid.String(),
+		Currency:              row.Currency,
+		InputPerMillion:       row.InputPerMillion,
+		OutputPerMillion:      row.OutputPerMillion,
+		CachedInputPerMillion: float8Ptr(row.CachedInputPerMillion),
+		ReasoningPerMillion:   float8Ptr(row.ReasoningPerMillion),
+		UpdatedAt:             db.TimeFromPg(row.UpdatedAt),
+	}
+}
+
+func float8Ptr(v pgtype.Float8) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func toFloat8(v *float64) pgtype.Float8 {
+	if v == nil {
+		return pgtype.Float8{}
+	}
+	return pgtype.Float8{Float64: *v, Valid: true}
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceCost(t *testing.T) {
+	cached := 0.5
+	p := Price{InputPerMillion: 2, OutputPerMillion: 8, CachedInputPerMillion: &cached}
+
+	// 1M input with 400k cached, 500k output with no reasoning price set.
+	got := p.Cost(1_000_000, 500_000, 400_000, 100_000)
+	want := 0.6*2 + 0.4*0.5 + 0.5*8
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("expected cost %v, got %v", want, got)
+	}
+}
+
+func TestPriceRequestValidate(t *testing.T) {
+	req := PriceRequest{Currency: " eur ", InputPerMillion: 1}
+	if err := req.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Currency != "EUR" {
+		t.Fatalf("expected normalized currency EUR, got %q", req.Currency)
+	}
+	if err := (&PriceRequest{}).validate(); err != nil {
+		t.Fatalf("empty currency should default: %v", err)
+	}
+	neg := -1.0
+	if err := (&PriceRequest{CachedInputPerMillion: &neg}).validate(); err == nil {
+		t.Fatal("expected negative price to be rejected")
+	}
+	if err := (&PriceRequest{Currency: "dollars"}).validate(); err == nil {
+		t.Fatal("expected invalid currency to be rejected")
+	}
+}
@@ -0,0 +1,280 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+var ErrVariantNotFound = errors.New("model variant not found")
+
+// Variant routes a share of a chat model's traffic to another model.
+// VariantID references the model that answers (UUID or model_id). A variant
+// pointing at the base model itself keeps a control group. Weights are
+// relative; a weight of zero disables the variant.
+type Variant struct {
+	ID        string         `json:"id"`
+	ModelID   string         `json:"model_id"`
+	VariantID string         `json:"variant_id"`
+	Weight    int            `json:"weight"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type VariantRequest struct {
+	VariantID string         `json:"variant_id"`
+	Weight    int            `json:"weight"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// VariantStats aggregates the replies one variant produced.
+type VariantStats struct {
+	VariantID     string  `json:"variant_id"`
+	Model         string  `json:"model,omitempty"`
+	Weight        int     `json:"weight"`
+	Replies       int64   `json:"replies"`
+	Conversations int64   `json:"conversations"`
+	AvgLatencyMs  float64 `json:"avg_latency_ms"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	UpReactions   int64   `json:"up_reactions"`
+	DownReactions int64   `json:"down_reactions"`
+}
+
+type VariantReport struct {
+	ModelID  string         `json:"model_id"`
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Variants []VariantStats `json:"variants"`
+}
+
+func (r *VariantRequest) validate() error {
+	if strings.TrimSpace(r.VariantID) == "" {
+		return errors.New("variant_id is required")
+	}
+	if r.Weight < 0 {
+		return errors.New("weight must not be negative")
+	}
+	return nil
+}
+
+// ListVariants returns the variants configured for a model.
+func (s *Service) ListVariants(ctx context.Context, modelID string) ([]Variant, error) {
+	pgModelID, err := db.ParseUUID(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID: %w", err)
+	}
+	rows, err := s.queries.ListModelVariantsByModelUUID(ctx, pgModelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model variants: %w", err)
+	}
+	variants := make([]Variant, 0, len(rows))
+	for _, row := range rows {
+		variants = append(variants, convertToVariant(row))
+	}
+	return variants, nil
+}
+
+// CreateVariant adds a variant to a model.
+func (s *Service) CreateVariant(ctx context.Context, modelID string, req VariantRequest) (Variant, error) {
+	if err := req.validate(); err != nil {
+		return Variant{}, fmt.Errorf("validation failed: %w", err)
+	}
+	pgModelID, err := db.ParseUUID(modelID)
+	if err != nil {
+		return Variant{}, fmt.Errorf("invalid ID: %w", err)
+	}
+	metadata, err := marshalVariantMetadata(req.Metadata)
+	if err != nil {
+		return Variant{}, err
+	}
+	row, err := s.queries.CreateModelVariant(ctx, sqlc.CreateModelVariantParams{
+		ModelUuid: pgModelID,
+		VariantID: strings.TrimSpace(req.VariantID),
+		Weight:    int32(req.Weight),
+		Metadata:  metadata,
+	})
+	if err != nil {
+		return Variant{}, fmt.Errorf("failed to create model variant: %w", err)
+	}
+	return convertToVariant(row), nil
+}
+
+// UpdateVariant replaces a variant's target, weight and metadata.
+func (s *Service) UpdateVariant(ctx context.Context, modelID, id string, req VariantRequest) (Variant, error) {
+	if err := req.validate(); err != nil {
+		return Variant{}, fmt.Errorf("validation failed: %w", err)
+	}
+	pgModelID, err := db.ParseUUID(modelID)
+	if err != nil {
+		return Variant{}, fmt.Errorf("invalid ID: %w", err)
+	}
+	pgID, err := db.ParseUUID(id)
+	if err != nil {
+		return Variant{}, fmt.Errorf("invalid variant ID: %w", err)
+	}
+	metadata, err := marshalVariantMetadata(req.Metadata)
+	if err != nil {
+		return Variant{}, err
+	}
+	row, err := s.queries.UpdateModelVariant(ctx, sqlc.UpdateModelVariantParams{
+		VariantID: strings.TrimSpace(req.VariantID),
+		Weight:    int32(req.Weight),
+		Metadata:  metadata,
+		ID:        pgID,
+		ModelUuid: pgModelID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Variant{}, ErrVariantNotFound
+		}
+		return Variant{}, fmt.Errorf("failed to update model variant: %w", err)
+	}
+	return convertToVariant(row), nil
+}
+
+// DeleteVariant removes a variant. Replies it produced stay in the report.
+func (s *Service) DeleteVariant(ctx context.Context, modelID, id string) error {
+	pgModelID, err := db.ParseUUID(modelID)
+	if err != nil {
+		return fmt.Errorf("invalid ID: %w", err)
+	}
+	pgID, err := db.ParseUUID(id)
+	if err != nil {
+		return fmt.Errorf("invalid variant ID: %w", err)
+	}
+	deleted, err := s.queries.DeleteModelVariant(ctx, sqlc.DeleteModelVariantParams{ID: pgID, ModelUuid: pgModelID})
+	if err != nil {
+		return fmt.Errorf("failed to delete model variant: %w", err)
+	}
+	if deleted == 0 {
+		return ErrVariantNotFound
+	}
+	return nil
+}
+
+// VariantReport compares the variants of a model over [from, to) by reply
+// latency, token usage and user reactions. Configured variants without
+// replies are included with zero counts.
+func (s *Service) VariantReport(ctx context.Context, modelID string, from, to time.Time) (VariantReport, error) {
+	if !to.After(from) {
+		return VariantReport{}, errors.New("report range end must be after its start")
+	}
+	pgModelID, err := db.ParseUUID(modelID)
+	if err != nil {
+		return VariantReport{}, fmt.Errorf("invalid ID: %w", err)
+	}
+	variants, err := s.ListVariants(ctx, modelID)
+	if err != nil {
+		return VariantReport{}, err
+	}
+	rows, err := s.queries.GetModelVariantReport(ctx, sqlc.GetModelVariantReportParams{
+		ModelUuid: pgModelID.String(),
+		Since:     pgtype.Timestamptz{Time: from, Valid: true},
+		Until:     pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return VariantReport{}, fmt.Errorf("failed to build variant report: %w", err)
+	}
+
+	configured := make(map[string]Variant, len(variants))
+	for _, v := range variants {
+		configured[v.ID] = v
+	}
+	report := VariantReport{ModelID: pgModelID.String(), From: from, To: to, Variants: make([]VariantStats, 0, len(rows)+len(variants))}
+	seen := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		stats := VariantStats{
+			VariantID:     row.VariantID,
+			Replies:       row.Replies,
+			Conversations: row.Conversations,
+			AvgLatencyMs:  row.AvgLatencyMs,
+			InputTokens:   row.InputTokens,
+			OutputTokens:  row.OutputTokens,
+			UpReactions:   row.UpReactions,
+			DownReactions: row.DownReactions,
+		}
+		if v, ok := configured[row.VariantID]; ok {
+			stats.Model = v.VariantID
+			stats.Weight = v.Weight
+		}
+		seen[row.VariantID] = struct{}{}
+		report.Variants = append(report.Variants, stats)
+	}
+	for _, v := range variants {
+		if _, ok := seen[v.ID]; ok {
+			continue
+		}
+		report.Variants = append(report.Variants, VariantStats{VariantID: v.ID, Model: v.VariantID, Weight: v.Weight})
+	}
+	return report, nil
+}
+
+// PickVariant chooses a variant by weight. The choice depends only on key and
+// the variant list, so a conversation keeps its variant until the weights
+// change. It reports false when no variant has a positive weight.
+func PickVariant(variants []Variant, key string) (Variant, bool) {
+	var total uint64
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += uint64(v.Weight)
+		}
+	}
+	if total == 0 {
+		return Variant{}, false
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	point := h.Sum64() % total
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if point < uint64(v.Weight) {
+			return v, true
+		}
+		point -= uint64(v.Weight)
+	}
+	return Variant{}, false
+}
+
+func marshalVariantMetadata(metadata map[string]any) ([]byte, error) {
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("invalid variant metadata: %w", err)
+	}
+	return data, nil
+}
+
+func convertToVariant(row sqlc.ModelVariant) Variant {
+	v := Variant{
+		ID:        row.ID.String(),
+		ModelID:   row.ModelUuid.String(),
+		VariantID: row.VariantID,
+		Weight:    int(row.Weight),
+	}
+	if len(row.Metadata) > 0 {
+		_ = json.Unmarshal(row.Metadata, &v.Metadata)
+	}
+	if row.CreatedAt.Valid {
+		v.CreatedAt = row.CreatedAt.Time
+	}
+	if row.UpdatedAt.Valid {
+		v.UpdatedAt = row.UpdatedAt.Time
+	}
+	return v
+}
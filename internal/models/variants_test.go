@@ -0,0 +1,47 @@
+package models_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/memohai/memoh/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPickVariant_IsSticky(t *testing.T) {
+	variants := []models.Variant{
+		{ID: "control", Weight: 50},
+		{ID: "candidate", Weight: 50},
+	}
+	first, ok := models.PickVariant(variants, "route-1:model")
+	require.True(t, ok)
+	for i := 0; i < 10; i++ {
+		again, _ := models.PickVariant(variants, "route-1:model")
+		assert.Equal(t, first.ID, again.ID)
+	}
+}
+
+func TestPickVariant_FollowsWeights(t *testing.T) {
+	variants := []models.Variant{
+		{ID: "control", Weight: 90},
+		{ID: "candidate", Weight: 10},
+		{ID: "disabled", Weight: 0},
+	}
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		v, ok := models.PickVariant(variants, fmt.Sprintf("route-%d", i))
+		require.True(t, ok)
+		counts[v.ID]++
+	}
+	assert.Zero(t, counts["disabled"])
+	assert.InDelta(t, 9000, counts["control"], 300)
+	assert.InDelta(t, 1000, counts["candidate"], 300)
+}
+
+func TestPickVariant_NoPositiveWeight(t *testing.T) {
+	_, ok := models.PickVariant([]models.Variant{{ID: "off", Weight: 0}}, "route-1")
+	assert.False(t, ok)
+	_, ok = models.PickVariant(nil, "route-1")
+	assert.False(t, ok)
+}
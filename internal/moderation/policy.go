@@ -0,0 +1,212 @@
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidPolicy is returned for policies that cannot be applied.
+var ErrInvalidPolicy = errors.New("invalid moderation policy")
+
+// Stage is the side of a chat round a rule screens.
+type Stage string
+
+const (
+	// StageInput screens queries before they reach the model.
+	StageInput Stage = "input"
+	// StageOutput screens assistant text before it is stored and delivered.
+	StageOutput Stage = "output"
+)
+
+// Action is what happens to text a rule matches.
+type Action string
+
+const (
+	// ActionBlock refuses a query, or withholds an assistant message.
+	ActionBlock Action = "block"
+	// ActionRedact replaces the matched text and lets the rest through.
+	ActionRedact Action = "redact"
+	// ActionWarn lets the text through and only records the match.
+	ActionWarn Action = "warn"
+)
+
+// severity orders actions so the strictest match of a text wins.
+var severity = map[Action]int{
+	ActionWarn:   1,
+	ActionRedact: 2,
+	ActionBlock:  3,
+}
+
+const redactedText = "[redacted]"
+
+// Policy lists the moderation rules of a bot.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+	// Endpoint screens text with an OpenAI-compatible moderation API in
+	// addition to the rules.
+	Endpoint *Endpoint `json:"endpoint,omitempty"`
+	// BlockMessage replaces the reply sent when a query is blocked.
+	BlockMessage string `json:"block_message,omitempty"`
+	// WithheldMessage replaces the text of a blocked assistant message.
+	WithheldMessage string `json:"withheld_message,omitempty"`
+	// NotifyPlatform is the channel the owner is told on. Empty uses the
+	// channel of the message that matched.
+	NotifyPlatform string `json:"notify_platform,omitempty"`
+}
+
+// Rule matches text by keyword or regular expression.
+type Rule struct {
+	Name string `json:"name"`
+	// Stages lists the sides the rule screens. Empty screens both.
+	Stages []Stage `json:"stages,omitempty"`
+	// Keywords match anywhere in the text, ignoring case.
+	Keywords []string `json:"keywords,omitempty"`
+	// Pattern is a regular expression in RE2 syntax.
+	Pattern string `json:"pattern,omitempty"`
+	Action  Action `json:"action"`
+	// NotifyOwner tells the owner each time the rule matches.
+	NotifyOwner bool `json:"notify_owner,omitempty"`
+}
+
+// Endpoint is an OpenAI-compatible moderation API. Text is sent to
+// BaseURL + "/moderations".
+type Endpoint struct {
+	BaseURL string `json:"base_url"`
+	// APIKey is never returned once set; updates without one keep it.
+	APIKey    string `json:"api_key,omitempty"`
+	HasAPIKey bool   `json:"has_api_key"`
+	Model     string `json:"model,omitempty"`
+	// Stages lists the sides the endpoint screens. Empty screens both.
+	Stages []Stage `json:"stages,omitempty"`
+	// Categories limits the verdict to these flagged categories. Empty acts
+	// on any flagged result.
+	Categories []string `json:"categories,omitempty"`
+	// Action is block or warn. The endpoint does not say which part of the
+	// text it flagged, so it cannot redact.
+	Action      Action `json:"action"`
+	NotifyOwner bool   `json:"notify_owner,omitempty"`
+}
+
+// Validate checks names, stages, actions and patterns.
+func (p Policy) Validate() error {
+	names := map[string]bool{}
+	for i, rule := range p.Rules {
+		if strings.TrimSpace(rule.Name) == "" {
+			return fmt.Errorf("%w: rule %d: name is required", ErrInvalidPolicy, i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("%w: rule %d: duplicate name %q", ErrInvalidPolicy, i, rule.Name)
+		}
+		names[rule.Name] = true
+		if err := validateStages(rule.Stages); err != nil {
+			return fmt.Errorf("%w: rule %q: %v", ErrInvalidPolicy, rule.Name, err)
+		}
+		if _, ok := severity[rule.Action]; !ok {
+			return fmt.Errorf("%w: rule %q: unknown action %q", ErrInvalidPolicy, rule.Name, rule.Action)
+		}
+		if len(rule.Keywords) == 0 && rule.Pattern == "" {
+			return fmt.Errorf("%w: rule %q: keywords or pattern is required", ErrInvalidPolicy, rule.Name)
+		}
+		if _, err := rule.compile(); err != nil {
+			return fmt.Errorf("%w: rule %q: %v", ErrInvalidPolicy, rule.Name, err)
+		}
+	}
+	if e := p.Endpoint; e != nil {
+		if !strings.HasPrefix(e.BaseURL, "http://") && !strings.HasPrefix(e.BaseURL, "https://") {
+			return fmt.Errorf("%w: endpoint base_url must be an http(s) URL", ErrInvalidPolicy)
+		}
+		if err := validateStages(e.Stages); err != nil {
+			return fmt.Errorf("%w: endpoint: %v", ErrInvalidPolicy, err)
+		}
+		if e.Action != ActionBlock && e.Action != ActionWarn {
+			return fmt.Errorf("%w: endpoint action must be block or warn", ErrInvalidPolicy)
+		}
+	}
+	return nil
+}
+
+func validateStages(stages []Stage) error {
+	for _, stage := range stages {
+		if stage != StageInput && stage != StageOutput {
+			return fmt.Errorf("unknown stage %q", stage)
+		}
+	}
+	return nil
+}
+
+func appliesTo(stages []Stage, stage Stage) bool {
+	if len(stages) == 0 {
+		return true
+	}
+	for _, s := range stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// compile joins the keywords and pattern of a rule into one expression.
+func (r Rule) compile() (*regexp.Regexp, error) {
+	alternatives := make([]string, 0, len(r.Keywords)+1)
+	for _, keyword := range r.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			alternatives = append(alternatives, "(?i:"+regexp.QuoteMeta(keyword)+")")
+		}
+	}
+	if r.Pattern != "" {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, "(?:"+r.Pattern+")")
+	}
+	if len(alternatives) == 0 {
+		return nil, errors.New("no keywords")
+	}
+	return regexp.Compile(strings.Join(alternatives, "|"))
+}
+
+// normalize trims the policy and carries a stored endpoint key over to an
+// update that does not set one.
+func (p *Policy) normalize(stored Policy) {
+	p.BlockMessage = strings.TrimSpace(p.BlockMessage)
+	p.WithheldMessage = strings.TrimSpace(p.WithheldMessage)
+	p.NotifyPlatform = strings.TrimSpace(p.NotifyPlatform)
+	for i := range p.Rules {
+		p.Rules[i].Name = strings.TrimSpace(p.Rules[i].Name)
+	}
+	if e := p.Endpoint; e != nil {
+		e.BaseURL = strings.TrimRight(strings.TrimSpace(e.BaseURL), "/")
+		e.APIKey = strings.TrimSpace(e.APIKey)
+		if e.APIKey == "" && stored.Endpoint != nil {
+			e.APIKey = stored.Endpoint.APIKey
+		}
+		e.HasAPIKey = false
+	}
+}
+
+// public returns the policy without the endpoint key.
+func (p Policy) public() Policy {
+	if p.Endpoint != nil {
+		e := *p.Endpoint
+		e.HasAPIKey = e.APIKey != ""
+		e.APIKey = ""
+		p.Endpoint = &e
+	}
+	if p.Rules == nil {
+		p.Rules = []Rule{}
+	}
+	return p
+}
+
+func parsePolicy(raw []byte) Policy {
+	var p Policy
+	if len(raw) == 0 {
+		return p
+	}
+	_ = json.Unmarshal(raw, &p)
+	return p
+}
@@ -0,0 +1,548 @@
+// Package moderation screens chat input and output against per-bot content
+// rules.
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/conversation/flow"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+const (
+	endpointTimeout = 10 * time.Second
+	endpointRule    = "endpoint"
+
+	defaultBlockMessage    = "Your message was not sent to the assistant because it breaks this bot's content policy."
+	defaultWithheldMessage = "[This reply was withheld by the bot's content policy.]"
+)
+
+// ChannelSender delivers moderation notices to the owner. channel.Manager
+// implements it.
+type ChannelSender interface {
+	Send(ctx context.Context, botID string, channelType channel.ChannelType, req channel.SendRequest) error
+}
+
+type store interface {
+	GetModerationPolicy(ctx context.Context, id pgtype.UUID) (sqlc.GetModerationPolicyRow, error)
+	UpdateModerationPolicy(ctx context.Context, arg sqlc.UpdateModerationPolicyParams) ([]byte, error)
+}
+
+// Service applies the moderation policy of a bot to chat rounds. It
+// implements flow.Moderator.
+type Service struct {
+	queries    store
+	sender     ChannelSender
+	httpClient *http.Client
+	logger     *slog.Logger
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Service{
+		queries:    queries,
+		httpClient: &http.Client{Timeout: endpointTimeout},
+		logger:     log.With(slog.String("service", "moderation")),
+	}
+}
+
+// SetChannelSender enables telling the owner about rules with notify_owner.
+func (s *Service) SetChannelSender(sender ChannelSender) {
+	s.sender = sender
+}
+
+// GetPolicy returns the moderation policy of a bot, without the endpoint key.
+func (s *Service) GetPolicy(ctx context.Context, botID string) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	row, err := s.queries.GetModerationPolicy(ctx, pgBotID)
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(row.ModerationPolicy).public(), nil
+}
+
+// UpdatePolicy replaces the moderation policy of a bot.
+func (s *Service) UpdatePolicy(ctx context.Context, botID string, policy Policy) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	row, err := s.queries.GetModerationPolicy(ctx, pgBotID)
+	if err != nil {
+		return Policy{}, err
+	}
+	policy.normalize(parsePolicy(row.ModerationPolicy))
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return Policy{}, err
+	}
+	stored, err := s.queries.UpdateModerationPolicy(ctx, sqlc.UpdateModerationPolicyParams{
+		ModerationPolicy: raw,
+		ID:               pgBotID,
+	})
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(stored).public(), nil
+}
+
+// ModerateInput screens a query. A policy that cannot be loaded refuses
+// the query with an error; an endpoint that cannot be reached lets it
+// through.
+func (s *Service) ModerateInput(ctx context.Context, req conversation.ChatRequest, text string) (flow.ModeratedInput, error) {
+	result := flow.ModeratedInput{Text: text}
+	policy, owner, ok, err := s.loadPolicy(ctx, req.BotID)
+	if err != nil || !ok {
+		return result, err
+	}
+	v := s.screen(ctx, req.BotID, policy, StageInput, text)
+	if v.Action == "" {
+		return result, nil
+	}
+	result.Metadata = v.Metadata()
+	switch v.Action {
+	case ActionBlock:
+		result.Blocked = true
+		result.Text = ""
+		result.Message = firstNonEmpty(policy.BlockMessage, defaultBlockMessage)
+	case ActionRedact:
+		result.Text, _ = v.redact(text)
+	}
+	s.logger.Info("query matched moderation rules",
+		slog.String("bot_id", req.BotID),
+		slog.String("action", string(v.Action)),
+		slog.Any("rules", v.Rules),
+	)
+	s.notifyOwner(ctx, policy, owner, req, []Verdict{v})
+	return result, nil
+}
+
+// ModerateOutput screens the text of the assistant messages of a round.
+// Tool calls and results pass unchanged. A policy that cannot be loaded
+// lets the round through.
+func (s *Service) ModerateOutput(ctx context.Context, req conversation.ChatRequest, messages []conversation.ModelMessage) flow.ModeratedOutput {
+	policy, owner, ok, err := s.loadPolicy(ctx, req.BotID)
+	if err != nil {
+		s.logger.Warn("load moderation policy failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+		return flow.ModeratedOutput{}
+	}
+	if !ok {
+		return flow.ModeratedOutput{}
+	}
+	var out flow.ModeratedOutput
+	var verdicts []Verdict
+	for i, msg := range messages {
+		if msg.Role != "assistant" {
+			continue
+		}
+		text := msg.TextContent()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		v := s.screen(ctx, req.BotID, policy, StageOutput, text)
+		if v.Action == "" {
+			continue
+		}
+		verdicts = append(verdicts, v)
+		if out.Metadata == nil {
+			out.Metadata = map[int]map[string]any{}
+		}
+		out.Metadata[i] = v.Metadata()
+		var content json.RawMessage
+		switch v.Action {
+		case ActionBlock:
+			content = withheldContent(msg.Content, firstNonEmpty(policy.WithheldMessage, defaultWithheldMessage))
+		case ActionRedact:
+			content = redactedContent(msg.Content, v.redact)
+		default:
+			continue
+		}
+		if out.Messages == nil {
+			out.Messages = slices.Clone(messages)
+		}
+		out.Messages[i].Content = content
+	}
+	if len(verdicts) > 0 {
+		s.logger.Info("reply matched moderation rules",
+			slog.String("bot_id", req.BotID),
+			slog.Int("messages", len(verdicts)),
+		)
+		s.notifyOwner(ctx, policy, owner, req, verdicts)
+	}
+	return out
+}
+
+// ScreensOutput reports whether a rule or the endpoint of the bot's policy
+// screens assistant output.
+func (s *Service) ScreensOutput(ctx context.Context, botID string) bool {
+	policy, _, ok, err := s.loadPolicy(ctx, botID)
+	if err != nil || !ok {
+		return false
+	}
+	for _, rule := range policy.Rules {
+		if appliesTo(rule.Stages, StageOutput) {
+			return true
+		}
+	}
+	return policy.Endpoint != nil && appliesTo(policy.Endpoint.Stages, StageOutput)
+}
+
+// ModerateStreamText screens a streamed text segment and returns the text
+// to deliver: the withheld message when a rule blocks it, the text redacted
+// or unchanged otherwise. The owner is told once the reply is stored, by
+// ModerateOutput, so segments send no notice.
+func (s *Service) ModerateStreamText(ctx context.Context, req conversation.ChatRequest, text string) string {
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+	policy, _, ok, err := s.loadPolicy(ctx, req.BotID)
+	if err != nil {
+		s.logger.Warn("load moderation policy failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+		return text
+	}
+	if !ok {
+		return text
+	}
+	v := s.screen(ctx, req.BotID, policy, StageOutput, text)
+	switch v.Action {
+	case ActionBlock:
+		return firstNonEmpty(policy.WithheldMessage, defaultWithheldMessage)
+	case ActionRedact:
+		text, _ = v.redact(text)
+	}
+	return text
+}
+
+// Verdict is what the rules of a policy found in one text.
+type Verdict struct {
+	Stage Stage `json:"stage"`
+	// Action is the strictest action of the matching rules.
+	Action     Action   `json:"action"`
+	Rules      []string `json:"rules"`
+	Categories []string `json:"categories,omitempty"`
+	Redactions int      `json:"redactions,omitempty"`
+
+	notify bool
+	// redact replaces what the redacting rules matched and counts the
+	// replacements.
+	redact func(string) (string, int)
+}
+
+// Metadata is the verdict as stored in message metadata.
+func (v Verdict) Metadata() map[string]any {
+	meta := map[string]any{
+		"stage":  string(v.Stage),
+		"action": string(v.Action),
+		"rules":  v.Rules,
+	}
+	if len(v.Categories) > 0 {
+		meta["categories"] = v.Categories
+	}
+	if v.Redactions > 0 {
+		meta["redactions"] = v.Redactions
+	}
+	return meta
+}
+
+func (v *Verdict) match(name string, action Action, notify bool) {
+	v.Rules = append(v.Rules, name)
+	if severity[action] > severity[v.Action] {
+		v.Action = action
+	}
+	v.notify = v.notify || notify
+}
+
+// screen runs the rules and the endpoint of policy that apply to stage
+// over text. The endpoint is skipped once a rule blocks.
+func (s *Service) screen(ctx context.Context, botID string, policy Policy, stage Stage, text string) Verdict {
+	v := Verdict{Stage: stage}
+	var redactions []func(string) (string, int)
+	for _, rule := range policy.Rules {
+		if !appliesTo(rule.Stages, stage) {
+			continue
+		}
+		re, err := rule.compile()
+		if err != nil {
+			continue
+		}
+		if !re.MatchString(text) {
+			continue
+		}
+		v.match(rule.Name, rule.Action, rule.NotifyOwner)
+		if rule.Action == ActionRedact {
+			redactions = append(redactions, func(in string) (string, int) {
+				n := len(re.FindAllStringIndex(in, -1))
+				return re.ReplaceAllLiteralString(in, redactedText), n
+			})
+		}
+	}
+	if e := policy.Endpoint; e != nil && v.Action != ActionBlock && appliesTo(e.Stages, stage) {
+		categories, flagged, err := s.callEndpoint(ctx, *e, text)
+		if err != nil {
+			s.logger.Warn("moderation endpoint failed, text let through",
+				slog.String("bot_id", botID),
+				slog.String("stage", string(stage)),
+				slog.Any("error", err),
+			)
+		} else if flagged {
+			v.match(endpointRule, e.Action, e.NotifyOwner)
+			v.Categories = categories
+		}
+	}
+	v.redact = func(in string) (string, int) {
+		total := 0
+		for _, redact := range redactions {
+			var n int
+			in, n = redact(in)
+			total += n
+		}
+		return in, total
+	}
+	if v.Action == ActionRedact {
+		_, v.Redactions = v.redact(text)
+	}
+	return v
+}
+
+type endpointResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// callEndpoint returns the flagged categories of text and whether the
+// endpoint result counts under e.Categories.
+func (s *Service) callEndpoint(ctx context.Context, e Endpoint, text string) ([]string, bool, error) {
+	body := map[string]any{"input": text}
+	if e.Model != "" {
+		body["model"] = e.Model
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, false, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.BaseURL+"/moderations", bytes.NewReader(payload))
+	if err != nil {
+		return nil, false, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, false, fmt.Errorf("moderation endpoint status %d: %s", resp.StatusCode, strings.TrimSpace(string(errBody)))
+	}
+	var parsed endpointResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, false, fmt.Errorf("decode moderation response: %w", err)
+	}
+	var categories []string
+	flagged := false
+	for _, result := range parsed.Results {
+		if !result.Flagged {
+			continue
+		}
+		flagged = true
+		for name, hit := range result.Categories {
+			if hit && !slices.Contains(categories, name) {
+				categories = append(categories, name)
+			}
+		}
+	}
+	sort.Strings(categories)
+	if !flagged || len(e.Categories) == 0 {
+		return categories, flagged, nil
+	}
+	for _, name := range categories {
+		if slices.Contains(e.Categories, name) {
+			return categories, true, nil
+		}
+	}
+	return categories, false, nil
+}
+
+// withheldContent replaces the text of message content, a string or an
+// array of parts, with replacement. Other parts, such as tool calls, are
+// kept.
+func withheldContent(content json.RawMessage, replacement string) json.RawMessage {
+	parts, ok := contentParts(content)
+	if !ok {
+		return conversation.NewTextContent(replacement)
+	}
+	out := make([]map[string]any, 0, len(parts))
+	replaced := false
+	for _, part := range parts {
+		if part["type"] == "text" {
+			if replaced {
+				continue
+			}
+			replaced = true
+			part["text"] = replacement
+		}
+		out = append(out, part)
+	}
+	return marshalParts(out, replacement)
+}
+
+// redactedContent applies redact to the text of message content. Other
+// parts are kept.
+func redactedContent(content json.RawMessage, redact func(string) (string, int)) json.RawMessage {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		text, _ = redact(text)
+		return conversation.NewTextContent(text)
+	}
+	parts, ok := contentParts(content)
+	if !ok {
+		return content
+	}
+	for _, part := range parts {
+		if value, isText := part["text"].(string); isText && part["type"] == "text" {
+			part["text"], _ = redact(value)
+		}
+	}
+	return marshalParts(parts, "")
+}
+
+// contentParts parses content made of parts as generic maps, so fields
+// ContentPart does not know, such as tool call IDs, survive a rewrite.
+func contentParts(content json.RawMessage) ([]map[string]any, bool) {
+	var parts []map[string]any
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return nil, false
+	}
+	return parts, true
+}
+
+func marshalParts(parts []map[string]any, fallback string) json.RawMessage {
+	data, err := json.Marshal(parts)
+	if err != nil {
+		return conversation.NewTextContent(fallback)
+	}
+	return data
+}
+
+// loadPolicy returns the policy and owner of a bot, and whether it screens
+// anything.
+func (s *Service) loadPolicy(ctx context.Context, botID string) (Policy, string, bool, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, "", false, err
+	}
+	row, err := s.queries.GetModerationPolicy(ctx, pgBotID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Policy{}, "", false, nil
+		}
+		return Policy{}, "", false, fmt.Errorf("load moderation policy: %w", err)
+	}
+	policy := parsePolicy(row.ModerationPolicy)
+	return policy, row.OwnerUserID.String(), len(policy.Rules) > 0 || policy.Endpoint != nil, nil
+}
+
+// notifyOwner tells the owner which rules matched. The matched text is
+// left out of the notice.
+func (s *Service) notifyOwner(ctx context.Context, policy Policy, ownerUserID string, req conversation.ChatRequest, verdicts []Verdict) {
+	var rules []string
+	action := Action("")
+	stage := StageInput
+	for _, v := range verdicts {
+		if !v.notify {
+			continue
+		}
+		for _, name := range v.Rules {
+			if !slices.Contains(rules, name) {
+				rules = append(rules, name)
+			}
+		}
+		if severity[v.Action] > severity[action] {
+			action = v.Action
+		}
+		stage = v.Stage
+	}
+	if len(rules) == 0 {
+		return
+	}
+	platform := policy.NotifyPlatform
+	if platform == "" {
+		platform = strings.TrimSpace(req.CurrentChannel)
+	}
+	if s.sender == nil || platform == "" || ownerUserID == "" {
+		s.logger.Warn("moderation notice not sent to owner",
+			slog.String("bot_id", req.BotID),
+			slog.String("platform", platform),
+		)
+		return
+	}
+	text := formatNotice(stage, action, rules, req)
+	if err := s.sender.Send(ctx, req.BotID, channel.ChannelType(platform), channel.SendRequest{
+		ChannelIdentityID: ownerUserID,
+		Message:           channel.Message{Text: text},
+	}); err != nil {
+		s.logger.Warn("send moderation notice failed",
+			slog.String("bot_id", req.BotID),
+			slog.String("platform", platform),
+			slog.Any("error", err),
+		)
+	}
+}
+
+var actionPastTense = map[Action]string{
+	ActionBlock:  "blocked",
+	ActionRedact: "redacted",
+	ActionWarn:   "flagged",
+}
+
+func formatNotice(stage Stage, action Action, rules []string, req conversation.ChatRequest) string {
+	what := "A reply of this bot"
+	if stage == StageInput {
+		who := firstNonEmpty(strings.TrimSpace(req.DisplayName), strings.TrimSpace(req.SourceChannelIdentityID), strings.TrimSpace(req.UserID), "unknown sender")
+		what = "A message from " + who
+	}
+	where := ""
+	if req.CurrentChannel != "" {
+		where = " on " + req.CurrentChannel
+	}
+	return fmt.Sprintf("Moderation alert: %s%s was %s by: %s.", what, where, actionPastTense[action], strings.Join(rules, ", "))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,270 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+const (
+	testBotID   = "00000000-0000-0000-0000-0000000000b1"
+	testOwnerID = "00000000-0000-0000-0000-0000000000a1"
+)
+
+type fakeStore struct {
+	policy []byte
+}
+
+func (f *fakeStore) GetModerationPolicy(ctx context.Context, id pgtype.UUID) (sqlc.GetModerationPolicyRow, error) {
+	owner, _ := db.ParseUUID(testOwnerID)
+	return sqlc.GetModerationPolicyRow{ID: id, OwnerUserID: owner, ModerationPolicy: f.policy}, nil
+}
+
+func (f *fakeStore) UpdateModerationPolicy(ctx context.Context, arg sqlc.UpdateModerationPolicyParams) ([]byte, error) {
+	f.policy = arg.ModerationPolicy
+	return f.policy, nil
+}
+
+type fakeSender struct {
+	sent []channel.SendRequest
+}
+
+func (f *fakeSender) Send(ctx context.Context, botID string, channelType channel.ChannelType, req channel.SendRequest) error {
+	f.sent = append(f.sent, req)
+	return nil
+}
+
+func newTestService(t *testing.T, policy Policy) (*Service, *fakeSender) {
+	t.Helper()
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := &fakeSender{}
+	svc := &Service{queries: &fakeStore{policy: raw}, httpClient: http.DefaultClient, logger: slog.Default()}
+	svc.SetChannelSender(sender)
+	return svc, sender
+}
+
+func testRequest() conversation.ChatRequest {
+	return conversation.ChatRequest{BotID: testBotID, DisplayName: "Guest", CurrentChannel: "telegram"}
+}
+
+func TestValidateRejectsBadPolicies(t *testing.T) {
+	cases := map[string]Policy{
+		"missing name":     {Rules: []Rule{{Keywords: []string{"x"}, Action: ActionBlock}}},
+		"unknown action":   {Rules: []Rule{{Name: "a", Keywords: []string{"x"}, Action: "delete"}}},
+		"bad pattern":      {Rules: []Rule{{Name: "a", Pattern: "(", Action: ActionWarn}}},
+		"nothing to match": {Rules: []Rule{{Name: "a", Action: ActionWarn}}},
+		"unknown stage":    {Rules: []Rule{{Name: "a", Keywords: []string{"x"}, Stages: []Stage{"both"}, Action: ActionWarn}}},
+		"endpoint redact":  {Endpoint: &Endpoint{BaseURL: "https://api.example.com/v1", Action: ActionRedact}},
+	}
+	for name, policy := range cases {
+		if err := policy.Validate(); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("%s: expected ErrInvalidPolicy, got %v", name, err)
+		}
+	}
+}
+
+func TestModerateInputBlocksAndNotifiesOwner(t *testing.T) {
+	svc, sender := newTestService(t, Policy{
+		Rules: []Rule{
+			{Name: "spam", Keywords: []string{"Buy Now"}, Action: ActionWarn},
+			{Name: "threats", Keywords: []string{"hurt you"}, Action: ActionBlock, NotifyOwner: true},
+		},
+		BlockMessage: "Please keep it civil.",
+	})
+
+	result, err := svc.ModerateInput(context.Background(), testRequest(), "buy now or I will HURT YOU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Blocked || result.Message != "Please keep it civil." || result.Text != "" {
+		t.Fatalf("expected a block, got %+v", result)
+	}
+	if result.Metadata["action"] != "block" || strings.Join(result.Metadata["rules"].([]string), ",") != "spam,threats" {
+		t.Fatalf("unexpected verdict %+v", result.Metadata)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected one owner notice, got %d", len(sender.sent))
+	}
+	notice := sender.sent[0]
+	if notice.ChannelIdentityID != testOwnerID || strings.Contains(notice.Message.Text, "HURT") || !strings.Contains(notice.Message.Text, "Guest") {
+		t.Fatalf("unexpected notice %+v", notice)
+	}
+}
+
+func TestModerateInputRedactsMatches(t *testing.T) {
+	svc, sender := newTestService(t, Policy{Rules: []Rule{
+		{Name: "phone", Pattern: `\d{3}-\d{4}`, Stages: []Stage{StageInput}, Action: ActionRedact},
+		{Name: "replies", Keywords: []string{"ok"}, Stages: []Stage{StageOutput}, Action: ActionBlock},
+	}})
+
+	result, err := svc.ModerateInput(context.Background(), testRequest(), "ok, call 555-0100 or 555-0199")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Blocked || result.Text != "ok, call [redacted] or [redacted]" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.Metadata["redactions"] != 2 {
+		t.Fatalf("expected two redactions, got %+v", result.Metadata)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatal("no rule asked for an owner notice")
+	}
+
+	clean, err := svc.ModerateInput(context.Background(), testRequest(), "hello")
+	if err != nil || clean.Text != "hello" || clean.Metadata != nil {
+		t.Fatalf("expected clean text to pass untouched, got %+v %v", clean, err)
+	}
+}
+
+func TestModerateOutputKeepsToolCalls(t *testing.T) {
+	svc, _ := newTestService(t, Policy{
+		Rules:           []Rule{{Name: "secrets", Keywords: []string{"password"}, Stages: []Stage{StageOutput}, Action: ActionBlock}},
+		WithheldMessage: "[withheld]",
+	})
+	parts := json.RawMessage(`[{"type":"text","text":"The password is hunter2."},{"type":"tool-call","toolCallId":"c1","toolName":"send","input":{}},{"type":"text","text":"Done."}]`)
+	messages := []conversation.ModelMessage{
+		{Role: "user", Content: conversation.NewTextContent("what is the password?")},
+		{Role: "assistant", Content: parts},
+		{Role: "tool", Content: json.RawMessage(`[{"type":"tool-result","toolCallId":"c1","output":"password sent"}]`)},
+		{Role: "assistant", Content: conversation.NewTextContent("All good.")},
+	}
+
+	out := svc.ModerateOutput(context.Background(), testRequest(), messages)
+	if len(out.Metadata) != 1 || out.Metadata[1]["stage"] != "output" {
+		t.Fatalf("expected only the first reply flagged, got %+v", out.Metadata)
+	}
+	if out.Messages == nil {
+		t.Fatal("expected rewritten messages")
+	}
+	var rewritten []map[string]any
+	if err := json.Unmarshal(out.Messages[1].Content, &rewritten); err != nil {
+		t.Fatal(err)
+	}
+	if len(rewritten) != 2 || rewritten[0]["text"] != "[withheld]" || rewritten[1]["toolCallId"] != "c1" {
+		t.Fatalf("unexpected rewritten content %s", out.Messages[1].Content)
+	}
+	if string(out.Messages[2].Content) != string(messages[2].Content) || out.Messages[3].TextContent() != "All good." {
+		t.Fatal("tool results and clean replies must pass unchanged")
+	}
+	if string(messages[1].Content) != string(parts) {
+		t.Fatal("the input messages must not be modified")
+	}
+}
+
+func TestEndpointVerdicts(t *testing.T) {
+	var gotAuth, gotModel string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/moderations" {
+			http.NotFound(w, r)
+			return
+		}
+		gotAuth = r.Header.Get("Authorization")
+		var body struct {
+			Model string `json:"model"`
+			Input string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotModel = body.Model
+		w.WriteHeader(status)
+		flagged := strings.Contains(body.Input, "flag me")
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": []map[string]any{{
+			"flagged":    flagged,
+			"categories": map[string]bool{"harassment": flagged, "violence": false},
+		}}})
+	}))
+	defer server.Close()
+
+	svc, _ := newTestService(t, Policy{Endpoint: &Endpoint{
+		BaseURL: server.URL + "/v1",
+		APIKey:  "sk-test",
+		Model:   "omni-moderation-latest",
+		Action:  ActionBlock,
+	}})
+	result, err := svc.ModerateInput(context.Background(), testRequest(), "please flag me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Blocked || gotAuth != "Bearer sk-test" || gotModel != "omni-moderation-latest" {
+		t.Fatalf("unexpected endpoint call: blocked=%v auth=%q model=%q", result.Blocked, gotAuth, gotModel)
+	}
+	if categories := result.Metadata["categories"].([]string); len(categories) != 1 || categories[0] != "harassment" {
+		t.Fatalf("unexpected categories %+v", result.Metadata)
+	}
+
+	// Only the listed categories count.
+	svc, _ = newTestService(t, Policy{Endpoint: &Endpoint{BaseURL: server.URL + "/v1", Action: ActionBlock, Categories: []string{"violence"}}})
+	if result, _ := svc.ModerateInput(context.Background(), testRequest(), "please flag me"); result.Blocked || result.Metadata != nil {
+		t.Fatalf("expected other categories ignored, got %+v", result)
+	}
+
+	// An unavailable endpoint lets text through.
+	status = http.StatusInternalServerError
+	svc, _ = newTestService(t, Policy{Endpoint: &Endpoint{BaseURL: server.URL + "/v1", Action: ActionBlock}})
+	if result, err := svc.ModerateInput(context.Background(), testRequest(), "please flag me"); err != nil || result.Blocked {
+		t.Fatalf("expected the text let through, got %+v %v", result, err)
+	}
+}
+
+func TestUpdatePolicyKeepsEndpointKey(t *testing.T) {
+	svc, _ := newTestService(t, Policy{Endpoint: &Endpoint{BaseURL: "https://api.example.com/v1", APIKey: "sk-stored", Action: ActionWarn}})
+
+	updated, err := svc.UpdatePolicy(context.Background(), testBotID, Policy{Endpoint: &Endpoint{BaseURL: "https://api.example.com/v1/", Action: ActionBlock}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Endpoint.APIKey != "" || !updated.Endpoint.HasAPIKey || updated.Endpoint.BaseURL != "https://api.example.com/v1" {
+		t.Fatalf("unexpected returned endpoint %+v", updated.Endpoint)
+	}
+	stored := parsePolicy(svc.queries.(*fakeStore).policy)
+	if stored.Endpoint.APIKey != "sk-stored" || stored.Endpoint.Action != ActionBlock {
+		t.Fatalf("expected the stored key kept, got %+v", stored.Endpoint)
+	}
+	if _, err := svc.UpdatePolicy(context.Background(), testBotID, Policy{Rules: []Rule{{Name: "x", Action: ActionWarn}}}); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected ErrInvalidPolicy, got %v", err)
+	}
+}
+
+func TestModerateStreamText(t *testing.T) {
+	svc, sender := newTestService(t, Policy{
+		Rules: []Rule{
+			{Name: "secret", Keywords: []string{"launch code"}, Stages: []Stage{StageOutput}, Action: ActionBlock, NotifyOwner: true},
+			{Name: "phone", Pattern: `\d{3}-\d{4}`, Action: ActionRedact},
+		},
+		WithheldMessage: "Withheld.",
+	})
+	ctx := context.Background()
+	if !svc.ScreensOutput(ctx, testBotID) {
+		t.Fatal("expected the policy to screen output")
+	}
+	if got := svc.ModerateStreamText(ctx, testRequest(), "the launch code is 1234"); got != "Withheld." {
+		t.Fatalf("expected the segment withheld, got %q", got)
+	}
+	if got := svc.ModerateStreamText(ctx, testRequest(), "call 555-0100"); got != "call [redacted]" {
+		t.Fatalf("expected the segment redacted, got %q", got)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatal("segments must not notify the owner")
+	}
+
+	inputOnly, _ := newTestService(t, Policy{Rules: []Rule{{Name: "a", Keywords: []string{"x"}, Stages: []Stage{StageInput}, Action: ActionBlock}}})
+	if inputOnly.ScreensOutput(ctx, testBotID) {
+		t.Fatal("expected an input-only policy to leave streams alone")
+	}
+}
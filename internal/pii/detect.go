@@ -0,0 +1,220 @@
+package pii
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// matcher finds values of one kind. valid, when set, rejects matches that
+// only look like the kind.
+type matcher struct {
+	kind  string
+	re    *regexp.Regexp
+	valid func(text string, start, end int) bool
+}
+
+var detectors = map[string]matcher{
+	KindEmail: {
+		kind: KindEmail,
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	KindIBAN: {
+		kind:  KindIBAN,
+		re:    regexp.MustCompile(`[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?`),
+		valid: isolatedAnd(validIBAN),
+	},
+	KindCard: {
+		kind:  KindCard,
+		re:    regexp.MustCompile(`\d(?:[ -]?\d){12,18}`),
+		valid: isolatedAnd(validLuhn),
+	},
+	KindSSN: {
+		kind:  KindSSN,
+		re:    regexp.MustCompile(`\d{3}-\d{2}-\d{4}`),
+		valid: isolatedAnd(nil),
+	},
+	KindIP: {
+		kind:  KindIP,
+		re:    regexp.MustCompile(`(?:\d{1,3}\.){3}\d{1,3}`),
+		valid: isolatedAnd(validIPv4),
+	},
+	KindPhone: {
+		kind:  KindPhone,
+		re:    regexp.MustCompile(`\+?\(?\d[\d ().-]{5,}\d`),
+		valid: isolatedAnd(validPhone),
+	},
+}
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{\{([A-Z][A-Z0-9_]*)_([0-9]+)\}\}`)
+	partialPlaceholder = regexp.MustCompile(`^\{(?:\{[A-Z0-9_]*\}?)?$`)
+
+	datePattern      = regexp.MustCompile(`\d{4}[-./]\d{1,2}[-./]\d{1,2}|\d{1,2}[-./]\d{1,2}[-./]\d{4}`)
+	yearRangePattern = regexp.MustCompile(`^(?:19|20)\d{2} ?- ?(?:19|20)\d{2}$`)
+	decimalPattern   = regexp.MustCompile(`^\d+\.\d+$`)
+)
+
+// maxPlaceholderLen bounds how much streamed text SplitPartial holds back.
+const maxPlaceholderLen = 48
+
+// placeholder returns the placeholder of the ordinal-th value of kind.
+func placeholder(kind string, ordinal int32) string {
+	return "{{" + strings.ToUpper(kind) + "_" + strconv.Itoa(int(ordinal)) + "}}"
+}
+
+// SplitPartial splits text before a trailing placeholder that may have been
+// cut off, so streamed text is re-hydrated once the placeholder is whole.
+func SplitPartial(text string) (complete, partial string) {
+	i := strings.LastIndex(text, "{")
+	if i < 0 {
+		return text, ""
+	}
+	// Step back onto the first brace of "{{".
+	if i > 0 && text[i-1] == '{' {
+		i--
+	}
+	tail := text[i:]
+	if len(tail) > maxPlaceholderLen || !partialPlaceholder.MatchString(tail) {
+		return text, ""
+	}
+	return text[:i], tail
+}
+
+// entity is a value found in text, by byte offsets.
+type entity struct {
+	start, end int
+	kind       string
+}
+
+// findEntities returns the values in text, in order. Matchers claim text in
+// turn; a match overlapping one already claimed, or a placeholder, is
+// skipped.
+func findEntities(text string, matchers []matcher) []entity {
+	var taken []entity
+	for _, loc := range placeholderPattern.FindAllStringIndex(text, -1) {
+		taken = append(taken, entity{start: loc[0], end: loc[1]})
+	}
+	var found []entity
+	for _, m := range matchers {
+		for _, loc := range m.re.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] || overlaps(taken, loc[0], loc[1]) {
+				continue
+			}
+			if m.valid != nil && !m.valid(text, loc[0], loc[1]) {
+				continue
+			}
+			e := entity{start: loc[0], end: loc[1], kind: m.kind}
+			taken = append(taken, e)
+			found = append(found, e)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+	return found
+}
+
+func overlaps(taken []entity, start, end int) bool {
+	for _, e := range taken {
+		if start < e.end && e.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// isolatedAnd accepts a match that is not part of a longer word or number,
+// such as a UUID, and that check accepts.
+func isolatedAnd(check func(string) bool) func(text string, start, end int) bool {
+	return func(text string, start, end int) bool {
+		if start > 0 {
+			r, _ := utf8.DecodeLastRuneInString(text[:start])
+			if joins(r) {
+				return false
+			}
+		}
+		if end < len(text) {
+			r, _ := utf8.DecodeRuneInString(text[end:])
+			if joins(r) {
+				return false
+			}
+		}
+		return check == nil || check(text[start:end])
+	}
+}
+
+func joins(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+}
+
+func digitsOf(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func validPhone(s string) bool {
+	n := len(digitsOf(s))
+	if n < 7 || n > 15 {
+		return false
+	}
+	return !datePattern.MatchString(s) && !yearRangePattern.MatchString(s) && !decimalPattern.MatchString(s)
+}
+
+func validLuhn(s string) bool {
+	digits := digitsOf(s)
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func validIBAN(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	rearranged := s[4:] + s[:4]
+	remainder := 0
+	for _, r := range rearranged {
+		var value int
+		switch {
+		case r >= '0' && r <= '9':
+			value = int(r - '0')
+			remainder = (remainder*10 + value) % 97
+			continue
+		case r >= 'A' && r <= 'Z':
+			value = int(r-'A') + 10
+		default:
+			return false
+		}
+		remainder = (remainder*100 + value) % 97
+	}
+	return remainder == 1
+}
+
+func validIPv4(s string) bool {
+	for _, octet := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(octet)
+		if err != nil || n > 255 {
+			return false
+		}
+	}
+	return true
+}
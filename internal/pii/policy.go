@@ -0,0 +1,141 @@
+package pii
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidPolicy is returned for policies that cannot be applied.
+var ErrInvalidPolicy = errors.New("invalid pii policy")
+
+// Built-in detector kinds. A placeholder names the kind of value it stands
+// for, such as {{PHONE_1}}.
+const (
+	KindEmail = "email"
+	KindPhone = "phone"
+	KindCard  = "card"
+	KindIBAN  = "iban"
+	KindSSN   = "ssn"
+	KindIP    = "ip"
+	// KindTerm is the kind of terms listed without one.
+	KindTerm = "term"
+)
+
+// detectorOrder is the order built-in detectors claim text in, most
+// specific first, so a card number is not taken for a phone number.
+var detectorOrder = []string{KindEmail, KindIBAN, KindCard, KindSSN, KindIP, KindPhone}
+
+var kindPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// Policy is the redaction policy of a bot.
+type Policy struct {
+	Enabled bool `json:"enabled"`
+	// Detectors lists the built-in detectors to run. Empty runs all of them.
+	Detectors []string `json:"detectors,omitempty"`
+	// Terms are redacted wherever they appear, ignoring case: a home
+	// address, the name of a child.
+	Terms []Term `json:"terms,omitempty"`
+	// Patterns catch what the detectors miss, such as local ID formats.
+	Patterns []Pattern `json:"patterns,omitempty"`
+}
+
+// Term is a literal value to redact.
+type Term struct {
+	// Kind names the placeholder. Empty uses "term".
+	Kind  string `json:"kind,omitempty"`
+	Value string `json:"value"`
+}
+
+// Pattern is a regular expression in RE2 syntax whose matches are redacted.
+type Pattern struct {
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+}
+
+// Validate checks detectors, kinds and patterns.
+func (p Policy) Validate() error {
+	for _, detector := range p.Detectors {
+		if !slices.Contains(detectorOrder, detector) {
+			return fmt.Errorf("%w: unknown detector %q", ErrInvalidPolicy, detector)
+		}
+	}
+	for i, term := range p.Terms {
+		if !kindPattern.MatchString(term.Kind) {
+			return fmt.Errorf("%w: term %d: invalid kind %q", ErrInvalidPolicy, i, term.Kind)
+		}
+		if len([]rune(term.Value)) < 3 {
+			return fmt.Errorf("%w: term %d: value must be at least 3 characters", ErrInvalidPolicy, i)
+		}
+	}
+	for i, pattern := range p.Patterns {
+		if !kindPattern.MatchString(pattern.Kind) {
+			return fmt.Errorf("%w: pattern %d: invalid kind %q", ErrInvalidPolicy, i, pattern.Kind)
+		}
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return fmt.Errorf("%w: pattern %d: %v", ErrInvalidPolicy, i, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("%w: pattern %d: matches empty text", ErrInvalidPolicy, i)
+		}
+	}
+	return nil
+}
+
+// normalize trims the policy and fills in default term kinds.
+func (p *Policy) normalize() {
+	for i, detector := range p.Detectors {
+		p.Detectors[i] = strings.ToLower(strings.TrimSpace(detector))
+	}
+	p.Detectors = slices.Compact(p.Detectors)
+	for i := range p.Terms {
+		p.Terms[i].Kind = strings.ToLower(strings.TrimSpace(p.Terms[i].Kind))
+		if p.Terms[i].Kind == "" {
+			p.Terms[i].Kind = KindTerm
+		}
+		p.Terms[i].Value = strings.TrimSpace(p.Terms[i].Value)
+	}
+	for i := range p.Patterns {
+		p.Patterns[i].Kind = strings.ToLower(strings.TrimSpace(p.Patterns[i].Kind))
+	}
+}
+
+// matchers compiles the policy into matchers in the order they claim text:
+// terms, then patterns, then the built-in detectors.
+func (p Policy) matchers() ([]matcher, error) {
+	var out []matcher
+	for _, term := range p.Terms {
+		re, err := regexp.Compile("(?i)" + regexp.QuoteMeta(term.Value))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, matcher{kind: term.Kind, re: re})
+	}
+	for _, pattern := range p.Patterns {
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, matcher{kind: pattern.Kind, re: re})
+	}
+	for _, kind := range detectorOrder {
+		if len(p.Detectors) == 0 || slices.Contains(p.Detectors, kind) {
+			out = append(out, detectors[kind])
+		}
+	}
+	return out, nil
+}
+
+func parsePolicy(raw []byte) Policy {
+	var p Policy
+	if len(raw) == 0 {
+		return p
+	}
+	_ = json.Unmarshal(raw, &p)
+	p.normalize()
+	return p
+}
@@ -0,0 +1,400 @@
+// Package pii keeps personal data on the server. Values found in text bound
+// for model and embedding providers are replaced with placeholders kept in a
+// per-bot vault, and placeholders coming back are swapped for the values.
+package pii
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+// vaultReloadInterval limits how often a placeholder missing from the cached
+// vault sends it back to the database. Models sometimes make placeholders up.
+const vaultReloadInterval = 5 * time.Second
+
+// skippedKeys hold identifiers and binary data rather than text, and are
+// left alone in structured values.
+var skippedKeys = map[string]bool{
+	"type":             true,
+	"toolCallId":       true,
+	"toolName":         true,
+	"mediaType":        true,
+	"mimeType":         true,
+	"image":            true,
+	"data":             true,
+	"url":              true,
+	"providerOptions":  true,
+	"providerMetadata": true,
+}
+
+type store interface {
+	GetPIIPolicy(ctx context.Context, id pgtype.UUID) ([]byte, error)
+	UpdatePIIPolicy(ctx context.Context, arg sqlc.UpdatePIIPolicyParams) ([]byte, error)
+	ListPIIVaultEntries(ctx context.Context, botID pgtype.UUID) ([]sqlc.PiiVaultEntry, error)
+	CreatePIIVaultEntry(ctx context.Context, arg sqlc.CreatePIIVaultEntryParams) (sqlc.PiiVaultEntry, error)
+}
+
+// Entry is a value in the vault of a bot.
+type Entry struct {
+	Placeholder string    `json:"placeholder"`
+	Kind        string    `json:"kind"`
+	Value       string    `json:"value"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Service redacts and re-hydrates text with the vault of each bot. It
+// implements flow.Redactor.
+type Service struct {
+	queries store
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	vaults map[string]*vault
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Service{
+		queries: queries,
+		logger:  log.With(slog.String("service", "pii")),
+		vaults:  map[string]*vault{},
+	}
+}
+
+// GetPolicy returns the redaction policy of a bot.
+func (s *Service) GetPolicy(ctx context.Context, botID string) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	raw, err := s.queries.GetPIIPolicy(ctx, pgBotID)
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(raw), nil
+}
+
+// UpdatePolicy replaces the redaction policy of a bot. Values already in
+// the vault stay there, so stored placeholders keep re-hydrating.
+func (s *Service) UpdatePolicy(ctx context.Context, botID string, policy Policy) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	policy.normalize()
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return Policy{}, err
+	}
+	stored, err := s.queries.UpdatePIIPolicy(ctx, sqlc.UpdatePIIPolicyParams{
+		PiiPolicy: raw,
+		ID:        pgBotID,
+	})
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(stored), nil
+}
+
+// ListEntries returns the vault of a bot.
+func (s *Service) ListEntries(ctx context.Context, botID string) ([]Entry, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListPIIVaultEntries(ctx, pgBotID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, Entry{
+			Placeholder: placeholder(row.Kind, row.Ordinal),
+			Kind:        row.Kind,
+			Value:       row.Value,
+			CreatedAt:   row.CreatedAt.Time,
+		})
+	}
+	return entries, nil
+}
+
+// Redact replaces personal data in the strings of value with placeholders.
+// value is a string, a []string or a decoded or raw JSON value; the result
+// has the same type. Bots without redaction enabled get value back as it
+// is. An error means the text must not leave the server.
+func (s *Service) Redact(ctx context.Context, botID string, value any) (any, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := s.queries.GetPIIPolicy(ctx, pgBotID)
+	if err != nil {
+		return nil, err
+	}
+	policy := parsePolicy(raw)
+	if !policy.Enabled {
+		return value, nil
+	}
+	matchers, err := policy.matchers()
+	if err != nil {
+		return nil, err
+	}
+	v := s.vault(botID, pgBotID)
+	out, _, err := mapStrings(value, func(text string) (string, error) {
+		return v.redact(ctx, text, matchers)
+	})
+	return out, err
+}
+
+// Rehydrate swaps the placeholders in the strings of value back for the
+// values they stand for. Placeholders missing from the vault are kept.
+func (s *Service) Rehydrate(ctx context.Context, botID string, value any) any {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return value
+	}
+	v := s.vault(botID, pgBotID)
+	out, _, _ := mapStrings(value, func(text string) (string, error) {
+		return v.rehydrate(ctx, text, s.logger), nil
+	})
+	return out
+}
+
+// SplitPartial implements flow.Redactor.
+func (s *Service) SplitPartial(text string) (string, string) {
+	return SplitPartial(text)
+}
+
+func (s *Service) vault(botID string, pgBotID pgtype.UUID) *vault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.vaults[botID]
+	if !ok {
+		v = &vault{botID: pgBotID, queries: s.queries}
+		s.vaults[botID] = v
+	}
+	return v
+}
+
+// vault caches the entries of one bot. Entries never change once created,
+// so the cache only has to catch up with entries added elsewhere.
+type vault struct {
+	botID   pgtype.UUID
+	queries store
+
+	mu            sync.Mutex
+	loadedAt      time.Time
+	byValue       map[string]string
+	byPlaceholder map[string]string
+}
+
+func (v *vault) loadLocked(ctx context.Context) error {
+	rows, err := v.queries.ListPIIVaultEntries(ctx, v.botID)
+	if err != nil {
+		return err
+	}
+	v.byValue = make(map[string]string, len(rows))
+	v.byPlaceholder = make(map[string]string, len(rows))
+	for _, row := range rows {
+		v.addLocked(row)
+	}
+	v.loadedAt = time.Now()
+	return nil
+}
+
+func (v *vault) addLocked(row sqlc.PiiVaultEntry) {
+	ph := placeholder(row.Kind, row.Ordinal)
+	v.byValue[row.Value] = ph
+	v.byPlaceholder[ph] = row.Value
+}
+
+func (v *vault) redact(ctx context.Context, text string, matchers []matcher) (string, error) {
+	entities := findEntities(text, matchers)
+	if len(entities) == 0 {
+		return text, nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, e := range entities {
+		ph, err := v.placeholderFor(ctx, e.kind, text[e.start:e.end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(text[last:e.start])
+		b.WriteString(ph)
+		last = e.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
+
+func (v *vault) placeholderFor(ctx context.Context, kind, value string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.loadedAt.IsZero() {
+		if err := v.loadLocked(ctx); err != nil {
+			return "", err
+		}
+	}
+	if ph, ok := v.byValue[value]; ok {
+		return ph, nil
+	}
+	arg := sqlc.CreatePIIVaultEntryParams{BotID: v.botID, Kind: kind, Value: value}
+	row, err := v.queries.CreatePIIVaultEntry(ctx, arg)
+	if db.IsUniqueViolation(err) {
+		// Another server took the ordinal first.
+		row, err = v.queries.CreatePIIVaultEntry(ctx, arg)
+	}
+	if err != nil {
+		return "", err
+	}
+	v.addLocked(row)
+	return placeholder(row.Kind, row.Ordinal), nil
+}
+
+func (v *vault) rehydrate(ctx context.Context, text string, logger *slog.Logger) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.loadedAt.IsZero() {
+		if err := v.loadLocked(ctx); err != nil {
+			logger.Warn("load pii vault failed", slog.Any("error", err))
+			return text
+		}
+	}
+	missing := false
+	replace := func(ph string) string {
+		if value, ok := v.byPlaceholder[ph]; ok {
+			return value
+		}
+		missing = true
+		return ph
+	}
+	out := placeholderPattern.ReplaceAllStringFunc(text, replace)
+	if missing && time.Since(v.loadedAt) > vaultReloadInterval {
+		if err := v.loadLocked(ctx); err != nil {
+			logger.Warn("reload pii vault failed", slog.Any("error", err))
+			return out
+		}
+		out = placeholderPattern.ReplaceAllStringFunc(text, replace)
+	}
+	return out
+}
+
+// mapStrings returns value with fn applied to each of its strings, and
+// whether any of them changed. Unchanged values are returned as they are;
+// changed typed values come back decoded into maps and slices.
+func mapStrings(value any, fn func(string) (string, error)) (any, bool, error) {
+	switch v := value.(type) {
+	case string:
+		out, err := fn(v)
+		if err != nil {
+			return nil, false, err
+		}
+		return out, out != v, nil
+	case []string:
+		out := make([]string, len(v))
+		changed := false
+		for i, item := range v {
+			mapped, err := fn(item)
+			if err != nil {
+				return nil, false, err
+			}
+			out[i] = mapped
+			changed = changed || mapped != item
+		}
+		if !changed {
+			return v, false, nil
+		}
+		return out, true, nil
+	case json.RawMessage:
+		if len(v) == 0 {
+			return v, false, nil
+		}
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.UseNumber()
+		var decoded any
+		if err := dec.Decode(&decoded); err != nil {
+			return v, false, nil
+		}
+		mapped, changed, err := mapStrings(decoded, fn)
+		if err != nil || !changed {
+			return v, false, err
+		}
+		encoded, err := json.Marshal(mapped)
+		if err != nil {
+			return nil, false, err
+		}
+		return json.RawMessage(encoded), true, nil
+	case []any:
+		out := make([]any, len(v))
+		changed := false
+		for i, item := range v {
+			mapped, itemChanged, err := mapStrings(item, fn)
+			if err != nil {
+				return nil, false, err
+			}
+			out[i] = mapped
+			changed = changed || itemChanged
+		}
+		if !changed {
+			return v, false, nil
+		}
+		return out, true, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		changed := false
+		for key, item := range v {
+			if skippedKeys[key] {
+				out[key] = item
+				continue
+			}
+			mapped, itemChanged, err := mapStrings(item, fn)
+			if err != nil {
+				return nil, false, err
+			}
+			out[key] = mapped
+			changed = changed || itemChanged
+		}
+		if !changed {
+			return v, false, nil
+		}
+		return out, true, nil
+	case nil, bool, float64, int, int64, json.Number:
+		return value, false, nil
+	default:
+		// Typed values, such as []map[string]any, go through JSON.
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return value, false, nil
+		}
+		mapped, changed, err := mapStrings(json.RawMessage(raw), fn)
+		if err != nil || !changed {
+			return value, false, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(mapped.(json.RawMessage)))
+		dec.UseNumber()
+		var decoded any
+		if err := dec.Decode(&decoded); err != nil {
+			return nil, false, err
+		}
+		return decoded, true, nil
+	}
+}
@@ -0,0 +1,204 @@
+package pii
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db/sqlc"
+)
+
+const testBotID = "00000000-0000-0000-0000-0000000000b1"
+
+type fakeStore struct {
+	policy  []byte
+	entries []sqlc.PiiVaultEntry
+	creates int
+}
+
+func (f *fakeStore) GetPIIPolicy(ctx context.Context, id pgtype.UUID) ([]byte, error) {
+	return f.policy, nil
+}
+
+func (f *fakeStore) UpdatePIIPolicy(ctx context.Context, arg sqlc.UpdatePIIPolicyParams) ([]byte, error) {
+	f.policy = arg.PiiPolicy
+	return f.policy, nil
+}
+
+func (f *fakeStore) ListPIIVaultEntries(ctx context.Context, botID pgtype.UUID) ([]sqlc.PiiVaultEntry, error) {
+	return append([]sqlc.PiiVaultEntry(nil), f.entries...), nil
+}
+
+func (f *fakeStore) CreatePIIVaultEntry(ctx context.Context, arg sqlc.CreatePIIVaultEntryParams) (sqlc.PiiVaultEntry, error) {
+	f.creates++
+	var ordinal int32
+	for _, e := range f.entries {
+		if e.Value == arg.Value {
+			return e, nil
+		}
+		if e.Kind == arg.Kind && e.Ordinal > ordinal {
+			ordinal = e.Ordinal
+		}
+	}
+	entry := sqlc.PiiVaultEntry{BotID: arg.BotID, Kind: arg.Kind, Ordinal: ordinal + 1, Value: arg.Value}
+	f.entries = append(f.entries, entry)
+	return entry, nil
+}
+
+func newTestService(t *testing.T, policy Policy) (*Service, *fakeStore) {
+	t.Helper()
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeStore{policy: raw}
+	return &Service{queries: store, logger: slog.Default(), vaults: map[string]*vault{}}, store
+}
+
+func redactString(t *testing.T, svc *Service, text string) string {
+	t.Helper()
+	out, err := svc.Redact(context.Background(), testBotID, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.(string)
+}
+
+func TestRedactAndRehydrate(t *testing.T) {
+	svc, store := newTestService(t, Policy{Enabled: true})
+
+	text := "Mail jane.doe@example.com or call +1 (555) 010-0199. Card 4111 1111 1111 1111, server 10.0.0.12."
+	redacted := redactString(t, svc, text)
+	want := "Mail {{EMAIL_1}} or call {{PHONE_1}}. Card {{CARD_1}}, server {{IP_1}}."
+	if redacted != want {
+		t.Fatalf("unexpected redaction\n got: %s\nwant: %s", redacted, want)
+	}
+	if again := redactString(t, svc, "jane.doe@example.com again"); again != "{{EMAIL_1}} again" {
+		t.Fatalf("expected the same placeholder for the same value, got %q", again)
+	}
+	if store.creates != 4 {
+		t.Fatalf("expected four vault entries created, got %d", store.creates)
+	}
+	if back := svc.Rehydrate(context.Background(), testBotID, redacted); back != text {
+		t.Fatalf("unexpected re-hydration %q", back)
+	}
+	if kept := svc.Rehydrate(context.Background(), testBotID, "{{PHONE_7}}"); kept != "{{PHONE_7}}" {
+		t.Fatalf("unknown placeholders must be kept, got %q", kept)
+	}
+}
+
+func TestRedactLeavesLookalikesAlone(t *testing.T) {
+	svc, _ := newTestService(t, Policy{Enabled: true})
+	texts := []string{
+		"message-id: 3f1c2a9e-1234-5678-9abc-0123456789ab",
+		"due 2026-10-19 12:30",
+		"between 2019-2021",
+		"pi is 3.14159265",
+		"version 1.2.3",
+		"card 4111 1111 1111 1112",
+	}
+	for _, text := range texts {
+		if out := redactString(t, svc, text); out != text {
+			t.Errorf("expected %q unchanged, got %q", text, out)
+		}
+	}
+}
+
+func TestTermsAndPatternsComeFirst(t *testing.T) {
+	svc, _ := newTestService(t, Policy{
+		Enabled:   true,
+		Detectors: []string{KindPhone},
+		Terms:     []Term{{Kind: "address", Value: "12 Elm Street"}, {Value: "Tommy"}},
+		Patterns:  []Pattern{{Kind: "member_id", Pattern: `M-\d{7}`}},
+	})
+	out := redactString(t, svc, "Tommy lives at 12 elm street, member M-5550100, phone 555-0100, mail a@b.co")
+	want := "{{TERM_1}} lives at {{ADDRESS_1}}, member {{MEMBER_ID_1}}, phone {{PHONE_1}}, mail a@b.co"
+	if out != want {
+		t.Fatalf("unexpected redaction\n got: %s\nwant: %s", out, want)
+	}
+}
+
+func TestRedactStructuredValues(t *testing.T) {
+	svc, _ := newTestService(t, Policy{Enabled: true})
+	content := json.RawMessage(`[{"type":"text","text":"call 555-0100"},{"type":"tool-call","toolCallId":"555-0100-55","toolName":"send","input":{"to":"555-0100","count":12345678901234567890}}]`)
+
+	out, err := svc.Redact(context.Background(), testBotID, []any{content, map[string]any{"note": "555-0100"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := out.([]any)
+	raw := string(values[0].(json.RawMessage))
+	if strings.Contains(raw, `"555-0100"`) || !strings.Contains(raw, `"toolCallId":"555-0100-55"`) || !strings.Contains(raw, "12345678901234567890") {
+		t.Fatalf("unexpected redacted content %s", raw)
+	}
+	if values[1].(map[string]any)["note"] != "{{PHONE_1}}" {
+		t.Fatalf("unexpected redacted map %+v", values[1])
+	}
+
+	typed := map[string]any{"content": []map[string]any{{"type": "text", "text": "555-0100"}}}
+	out, err = svc.Redact(context.Background(), testBotID, typed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := json.Marshal(out)
+	if string(encoded) != `{"content":[{"text":"{{PHONE_1}}","type":"text"}]}` {
+		t.Fatalf("unexpected typed result %s", encoded)
+	}
+}
+
+func TestRedactDisabledPolicy(t *testing.T) {
+	svc, store := newTestService(t, Policy{})
+	content := json.RawMessage(`{"text":"call 555-0100"}`)
+	out, err := svc.Redact(context.Background(), testBotID, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out.(json.RawMessage)) != string(content) || store.creates != 0 {
+		t.Fatalf("expected a disabled policy to change nothing, got %s", out)
+	}
+}
+
+func TestSplitPartial(t *testing.T) {
+	cases := map[string][2]string{
+		"call {{PHO":       {"call ", "{{PHO"},
+		"call {":           {"call ", "{"},
+		"call {{PHONE_1}":  {"call ", "{{PHONE_1}"},
+		"call {{PHONE_1}}": {"call {{PHONE_1}}", ""},
+		"a {b} c":          {"a {b} c", ""},
+		"set {x":           {"set {x", ""},
+	}
+	for text, want := range cases {
+		complete, partial := SplitPartial(text)
+		if complete != want[0] || partial != want[1] {
+			t.Errorf("SplitPartial(%q) = %q, %q; want %q, %q", text, complete, partial, want[0], want[1])
+		}
+	}
+}
+
+func TestUpdatePolicyValidates(t *testing.T) {
+	svc, _ := newTestService(t, Policy{})
+	updated, err := svc.UpdatePolicy(context.Background(), testBotID, Policy{Enabled: true, Terms: []Term{{Value: " Elm Street "}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Terms[0].Kind != KindTerm || updated.Terms[0].Value != "Elm Street" {
+		t.Fatalf("unexpected normalized term %+v", updated.Terms[0])
+	}
+	cases := map[string]Policy{
+		"unknown detector": {Detectors: []string{"passport"}},
+		"short term":       {Terms: []Term{{Value: "ab"}}},
+		"bad kind":         {Patterns: []Pattern{{Kind: "Member ID", Pattern: `\d+`}}},
+		"bad pattern":      {Patterns: []Pattern{{Kind: "id", Pattern: "("}}},
+		"empty match":      {Patterns: []Pattern{{Kind: "id", Pattern: `\d*`}}},
+	}
+	for name, policy := range cases {
+		if _, err := svc.UpdatePolicy(context.Background(), testBotID, policy); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("%s: expected ErrInvalidPolicy, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrInvalidPolicy is returned for policies that cannot be applied.
+var ErrInvalidPolicy = errors.New("invalid usage quota policy")
+
+// Scope is who a limit applies to.
+type Scope string
+
+const (
+	// ScopeBot limits the bot as a whole.
+	ScopeBot Scope = "bot"
+	// ScopeUser limits each member of the bot separately. The owner is exempt.
+	ScopeUser Scope = "user"
+	// ScopeGuest limits each guest separately: senders who are neither the
+	// owner nor a member, counted per channel identity.
+	ScopeGuest Scope = "guest"
+)
+
+// Metric is what a limit counts.
+type Metric string
+
+const (
+	MetricRequests Metric = "requests"
+	MetricTokens   Metric = "tokens"
+	// MetricCost counts in the currency of the model price catalog. Cost
+	// limits are refused while models are priced in several currencies,
+	// since their costs cannot be added up.
+	MetricCost Metric = "cost"
+)
+
+// Period is how often a limit's counter starts over. Periods follow UTC
+// calendar days, ISO weeks and months.
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+)
+
+var defaultNotifyAt = []int{80, 100}
+
+// Policy lists the usage quotas of a bot.
+type Policy struct {
+	Limits []Limit `json:"limits"`
+	// NotifyAt lists the percentages of a limit at which the owner is told.
+	// Empty uses 80 and 100.
+	NotifyAt []int `json:"notify_at,omitempty"`
+	// NotifyPlatform is the channel the owner is told on. Empty uses the
+	// channel of the request that crossed the threshold.
+	NotifyPlatform string `json:"notify_platform,omitempty"`
+	// Message replaces the reply sent when a request is refused.
+	Message string `json:"message,omitempty"`
+}
+
+// Limit caps one metric of a scope over a period.
+type Limit struct {
+	Scope  Scope   `json:"scope"`
+	Metric Metric  `json:"metric"`
+	Period Period  `json:"period"`
+	Max    float64 `json:"max"`
+}
+
+// Validate checks scopes, metrics, periods and thresholds. A scope may have
+// one limit per metric and period.
+func (p Policy) Validate() error {
+	seen := map[Limit]bool{}
+	for i, limit := range p.Limits {
+		switch limit.Scope {
+		case ScopeBot, ScopeUser, ScopeGuest:
+		default:
+			return fmt.Errorf("%w: limit %d: unknown scope %q", ErrInvalidPolicy, i, limit.Scope)
+		}
+		switch limit.Metric {
+		case MetricRequests, MetricTokens, MetricCost:
+		default:
+			return fmt.Errorf("%w: limit %d: unknown metric %q", ErrInvalidPolicy, i, limit.Metric)
+		}
+		switch limit.Period {
+		case PeriodDay, PeriodWeek, PeriodMonth:
+		default:
+			return fmt.Errorf("%w: limit %d: unknown period %q", ErrInvalidPolicy, i, limit.Period)
+		}
+		if limit.Max < 0 {
+			return fmt.Errorf("%w: limit %d: max must not be negative", ErrInvalidPolicy, i)
+		}
+		key := Limit{Scope: limit.Scope, Metric: limit.Metric, Period: limit.Period}
+		if seen[key] {
+			return fmt.Errorf("%w: limit %d: duplicate %s %s limit per %s", ErrInvalidPolicy, i, limit.Scope, limit.Metric, limit.Period)
+		}
+		seen[key] = true
+	}
+	for _, pct := range p.NotifyAt {
+		if pct <= 0 || pct > 100 {
+			return fmt.Errorf("%w: notify_at values must be between 1 and 100", ErrInvalidPolicy)
+		}
+	}
+	return nil
+}
+
+// hasCostLimit reports whether any limit counts cost.
+func (p Policy) hasCostLimit() bool {
+	for _, limit := range p.Limits {
+		if limit.Metric == MetricCost {
+			return true
+		}
+	}
+	return false
+}
+
+// thresholds returns the notification percentages in ascending order.
+func (p Policy) thresholds() []int {
+	if len(p.NotifyAt) == 0 {
+		return defaultNotifyAt
+	}
+	out := append([]int(nil), p.NotifyAt...)
+	sort.Ints(out)
+	return out
+}
+
+// Start returns the start of the period containing t.
+func (p Period) Start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PeriodWeek:
+		offset := (int(day.Weekday()) + 6) % 7 // Monday starts the week.
+		return day.AddDate(0, 0, -offset)
+	case PeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// End returns when the period starting at start is over.
+func (p Period) End(start time.Time) time.Time {
+	switch p {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func parsePolicy(raw []byte) Policy {
+	var p Policy
+	if len(raw) == 0 {
+		return p
+	}
+	_ = json.Unmarshal(raw, &p)
+	return p
+}
@@ -0,0 +1,572 @@
+// Package quota enforces per-bot usage quotas on chat rounds.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/models"
+)
+
+// pruneInterval is how often counters of past periods are deleted.
+const pruneInterval = time.Hour
+
+// ErrExceeded matches every *ExceededError.
+var ErrExceeded = errors.New("usage quota exceeded")
+
+// ExceededError refuses a request over a limit. Its message is meant for the
+// sender.
+type ExceededError struct {
+	Limit   Limit
+	Used    float64
+	ResetAt time.Time
+	Message string
+}
+
+func (e *ExceededError) Error() string {
+	return e.Message
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrExceeded
+}
+
+// Counter is the usage of one scope subject in the current period.
+type Counter struct {
+	Scope       Scope     `json:"scope"`
+	Subject     string    `json:"subject,omitempty"`
+	Period      Period    `json:"period"`
+	PeriodStart time.Time `json:"period_start"`
+	ResetAt     time.Time `json:"reset_at"`
+	Requests    int64     `json:"requests"`
+	Tokens      int64     `json:"tokens"`
+	Cost        float64   `json:"cost"`
+}
+
+// ChannelSender delivers threshold notices to the owner. channel.Manager
+// implements it.
+type ChannelSender interface {
+	Send(ctx context.Context, botID string, channelType channel.ChannelType, req channel.SendRequest) error
+}
+
+type store interface {
+	GetQuotaPolicy(ctx context.Context, id pgtype.UUID) (sqlc.GetQuotaPolicyRow, error)
+	UpdateQuotaPolicy(ctx context.Context, arg sqlc.UpdateQuotaPolicyParams) ([]byte, error)
+	GetQuotaCounter(ctx context.Context, arg sqlc.GetQuotaCounterParams) (sqlc.UsageQuotaCounter, error)
+	AddQuotaUsage(ctx context.Context, arg sqlc.AddQuotaUsageParams) (sqlc.UsageQuotaCounter, error)
+	MarkQuotaNotified(ctx context.Context, arg sqlc.MarkQuotaNotifiedParams) (int64, error)
+	ListQuotaCounters(ctx context.Context, arg sqlc.ListQuotaCountersParams) ([]sqlc.UsageQuotaCounter, error)
+	DeleteQuotaCounters(ctx context.Context, arg sqlc.DeleteQuotaCountersParams) (int64, error)
+	DeleteExpiredQuotaCounters(ctx context.Context, before pgtype.Timestamptz) (int64, error)
+	GetBotMember(ctx context.Context, arg sqlc.GetBotMemberParams) (sqlc.BotMember, error)
+}
+
+type priceLister interface {
+	ListPrices(ctx context.Context) ([]models.Price, error)
+}
+
+// Service checks chat rounds against the quota policy of their bot and
+// meters the usage they consume. It implements flow.QuotaEnforcer.
+type Service struct {
+	queries store
+	prices  priceLister
+	sender  ChannelSender
+	logger  *slog.Logger
+	now     func() time.Time
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries, modelsService *models.Service) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Service{
+		queries: queries,
+		prices:  modelsService,
+		logger:  log.With(slog.String("service", "quota")),
+		now:     time.Now,
+	}
+}
+
+// SetChannelSender enables telling the owner when usage crosses a
+// notification threshold.
+func (s *Service) SetChannelSender(sender ChannelSender) {
+	s.sender = sender
+}
+
+// GetPolicy returns the quota policy of a bot.
+func (s *Service) GetPolicy(ctx context.Context, botID string) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	row, err := s.queries.GetQuotaPolicy(ctx, pgBotID)
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(row.QuotaPolicy), nil
+}
+
+// UpdatePolicy replaces the quota policy of a bot. Counters are kept, so
+// usage earlier in the period still counts against the new limits.
+func (s *Service) UpdatePolicy(ctx context.Context, botID string, policy Policy) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	policy.NotifyPlatform = strings.TrimSpace(policy.NotifyPlatform)
+	policy.Message = strings.TrimSpace(policy.Message)
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+	if policy.hasCostLimit() {
+		prices, err := s.listPrices(ctx)
+		if err != nil {
+			return Policy{}, err
+		}
+		if currencies := priceCurrencies(prices); len(currencies) > 1 {
+			return Policy{}, fmt.Errorf("%w: cost limits need one currency, but models are priced in %s", ErrInvalidPolicy, strings.Join(currencies, ", "))
+		}
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return Policy{}, err
+	}
+	stored, err := s.queries.UpdateQuotaPolicy(ctx, sqlc.UpdateQuotaPolicyParams{
+		QuotaPolicy: raw,
+		ID:          pgBotID,
+	})
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(stored), nil
+}
+
+// CheckQuota returns an *ExceededError when a limit that applies to the
+// sender of req is used up.
+func (s *Service) CheckQuota(ctx context.Context, req conversation.ChatRequest) error {
+	policy, owner, ok, err := s.loadPolicy(ctx, req.BotID)
+	if err != nil || !ok {
+		return err
+	}
+	pgBotID, _ := db.ParseUUID(req.BotID)
+	who, err := s.requester(ctx, pgBotID, owner, req)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	for _, limit := range policy.Limits {
+		subject, applies := who.subjectFor(limit.Scope)
+		if !applies {
+			continue
+		}
+		start := limit.Period.Start(now)
+		counter, err := s.queries.GetQuotaCounter(ctx, sqlc.GetQuotaCounterParams{
+			BotID:       pgBotID,
+			Scope:       string(limit.Scope),
+			Subject:     subject,
+			Period:      string(limit.Period),
+			PeriodStart: pgtype.Timestamptz{Time: start, Valid: true},
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return fmt.Errorf("load quota counter: %w", err)
+		}
+		if used := metricValue(counter, limit.Metric); used >= limit.Max {
+			s.logger.Info("request refused by usage quota",
+				slog.String("bot_id", req.BotID),
+				slog.String("scope", string(limit.Scope)),
+				slog.String("subject", subject),
+				slog.String("metric", string(limit.Metric)),
+				slog.String("period", string(limit.Period)),
+			)
+			resetAt := limit.Period.End(start)
+			return &ExceededError{
+				Limit:   limit,
+				Used:    used,
+				ResetAt: resetAt,
+				Message: refusal(policy, limit, resetAt),
+			}
+		}
+	}
+	return nil
+}
+
+// RecordUsage adds a finished round to every counter the bot's policy keeps
+// for its sender, and tells the owner about thresholds it crossed.
+// modelUUID is the models table ID of the model that answered.
+func (s *Service) RecordUsage(ctx context.Context, req conversation.ChatRequest, modelUUID string, usage conversation.Usage) {
+	policy, owner, ok, err := s.loadPolicy(ctx, req.BotID)
+	if err != nil {
+		s.logger.Warn("record quota usage failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+		return
+	}
+	if !ok {
+		return
+	}
+	pgBotID, _ := db.ParseUUID(req.BotID)
+	who, err := s.requester(ctx, pgBotID, owner, req)
+	if err != nil {
+		s.logger.Warn("record quota usage failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+		return
+	}
+	cost := s.cost(ctx, policy, modelUUID, usage)
+	now := s.now()
+
+	type counterKey struct {
+		scope   Scope
+		subject string
+		period  Period
+	}
+	counters := map[counterKey]sqlc.UsageQuotaCounter{}
+	for _, limit := range policy.Limits {
+		subject, applies := who.subjectFor(limit.Scope)
+		if !applies {
+			continue
+		}
+		key := counterKey{scope: limit.Scope, subject: subject, period: limit.Period}
+		counter, done := counters[key]
+		if !done {
+			counter, err = s.queries.AddQuotaUsage(ctx, sqlc.AddQuotaUsageParams{
+				BotID:       pgBotID,
+				Scope:       string(limit.Scope),
+				Subject:     subject,
+				Period:      string(limit.Period),
+				PeriodStart: pgtype.Timestamptz{Time: limit.Period.Start(now), Valid: true},
+				Requests:    1,
+				Tokens:      int64(usage.TotalTokens()),
+				Cost:        cost,
+			})
+			if err != nil {
+				s.logger.Warn("add quota usage failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+				continue
+			}
+			counters[key] = counter
+		}
+		s.notifyThreshold(ctx, policy, owner, req, limit, counter)
+	}
+}
+
+// Usage returns the counters of the current periods of a bot.
+func (s *Service) Usage(ctx context.Context, botID string) ([]Counter, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	since := PeriodMonth.Start(now)
+	if week := PeriodWeek.Start(now); week.Before(since) {
+		since = week
+	}
+	rows, err := s.queries.ListQuotaCounters(ctx, sqlc.ListQuotaCountersParams{
+		BotID: pgBotID,
+		Since: pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Counter, 0, len(rows))
+	for _, row := range rows {
+		period := Period(row.Period)
+		start := db.TimeFromPg(row.PeriodStart)
+		if !start.Equal(period.Start(now)) {
+			continue
+		}
+		items = append(items, Counter{
+			Scope:       Scope(row.Scope),
+			Subject:     row.Subject,
+			Period:      period,
+			PeriodStart: start,
+			ResetAt:     period.End(start),
+			Requests:    row.Requests,
+			Tokens:      row.Tokens,
+			Cost:        row.Cost,
+		})
+	}
+	return items, nil
+}
+
+// Reset clears the counters of a bot ahead of schedule. Empty scope or
+// subject clear every scope or subject.
+func (s *Service) Reset(ctx context.Context, botID, scope, subject string) (int64, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return 0, err
+	}
+	var pgScope, pgSubject pgtype.Text
+	if scope = strings.TrimSpace(scope); scope != "" {
+		pgScope = pgtype.Text{String: scope, Valid: true}
+	}
+	if subject = strings.TrimSpace(subject); subject != "" {
+		pgSubject = pgtype.Text{String: subject, Valid: true}
+	}
+	return s.queries.DeleteQuotaCounters(ctx, sqlc.DeleteQuotaCountersParams{
+		BotID:   pgBotID,
+		Scope:   pgScope,
+		Subject: pgSubject,
+	})
+}
+
+// Run deletes counters of past periods until ctx ends. Counters start over
+// on their own at each period boundary; pruning only bounds the table. The
+// previous month is kept for reference.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		s.prune(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) prune(ctx context.Context) {
+	before := PeriodMonth.Start(s.now()).AddDate(0, -1, 0)
+	n, err := s.queries.DeleteExpiredQuotaCounters(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Warn("prune quota counters failed", slog.Any("error", err))
+		}
+		return
+	}
+	if n > 0 {
+		s.logger.Info("pruned quota counters", slog.Int64("deleted", n))
+	}
+}
+
+// loadPolicy returns the policy and owner of a bot, and whether it has any
+// limits.
+func (s *Service) loadPolicy(ctx context.Context, botID string) (Policy, string, bool, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, "", false, err
+	}
+	row, err := s.queries.GetQuotaPolicy(ctx, pgBotID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Policy{}, "", false, nil
+		}
+		return Policy{}, "", false, fmt.Errorf("load quota policy: %w", err)
+	}
+	policy := parsePolicy(row.QuotaPolicy)
+	return policy, row.OwnerUserID.String(), len(policy.Limits) > 0, nil
+}
+
+// requester is who a request is counted against besides the bot itself.
+// An empty scope is the owner, who is only bound by bot-wide limits.
+type requester struct {
+	scope   Scope
+	subject string
+}
+
+func (r requester) subjectFor(scope Scope) (string, bool) {
+	if scope == ScopeBot {
+		return "", true
+	}
+	if r.scope != scope {
+		return "", false
+	}
+	return r.subject, true
+}
+
+func (s *Service) requester(ctx context.Context, botID pgtype.UUID, ownerUserID string, req conversation.ChatRequest) (requester, error) {
+	userID := strings.TrimSpace(req.UserID)
+	if userID != "" && userID == ownerUserID {
+		return requester{}, nil
+	}
+	if userID != "" {
+		if pgUserID, err := db.ParseUUID(userID); err == nil {
+			_, err := s.queries.GetBotMember(ctx, sqlc.GetBotMemberParams{BotID: botID, UserID: pgUserID})
+			if err == nil {
+				return requester{scope: ScopeUser, subject: userID}, nil
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return requester{}, fmt.Errorf("check bot membership: %w", err)
+			}
+		}
+	}
+	subject := strings.TrimSpace(req.SourceChannelIdentityID)
+	if subject == "" {
+		subject = userID
+	}
+	return requester{scope: ScopeGuest, subject: subject}, nil
+}
+
+// cost prices a round when the policy has a cost limit. Models without a
+// price cost nothing.
+func (s *Service) cost(ctx context.Context, policy Policy, modelUUID string, usage conversation.Usage) float64 {
+	if !policy.hasCostLimit() || modelUUID == "" {
+		return 0
+	}
+	prices, err := s.listPrices(ctx)
+	if err != nil {
+		s.logger.Warn("load model prices failed", slog.Any("error", err))
+		return 0
+	}
+	// Prices added in another currency after the policy was saved cannot
+	// be added to the counters.
+	if currencies := priceCurrencies(prices); len(currencies) > 1 {
+		s.logger.Warn("cost not counted: models are priced in several currencies", slog.Any("currencies", currencies))
+		return 0
+	}
+	for _, price := range prices {
+		if price.ModelUUID == modelUUID {
+			return price.Cost(int64(usage.InputTokens), int64(usage.OutputTokens), int64(usage.CachedInputTokens), int64(usage.ReasoningTokens))
+		}
+	}
+	return 0
+}
+
+func (s *Service) listPrices(ctx context.Context) ([]models.Price, error) {
+	if s.prices == nil {
+		return nil, nil
+	}
+	return s.prices.ListPrices(ctx)
+}
+
+// priceCurrencies returns the distinct currencies of prices, sorted.
+func priceCurrencies(prices []models.Price) []string {
+	var out []string
+	for _, price := range prices {
+		if !slices.Contains(out, price.Currency) {
+			out = append(out, price.Currency)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// notifyThreshold tells the owner once per period about the highest
+// threshold the counter has reached for limit.
+func (s *Service) notifyThreshold(ctx context.Context, policy Policy, ownerUserID string, req conversation.ChatRequest, limit Limit, counter sqlc.UsageQuotaCounter) {
+	used := metricValue(counter, limit.Metric)
+	percent := 100.0
+	if limit.Max > 0 {
+		percent = used / limit.Max * 100
+	}
+	reached := 0
+	for _, threshold := range policy.thresholds() {
+		if percent >= float64(threshold) {
+			reached = threshold
+		}
+	}
+	if reached == 0 {
+		return
+	}
+	marked, err := s.queries.MarkQuotaNotified(ctx, sqlc.MarkQuotaNotifiedParams{
+		Metric:      string(limit.Metric),
+		Percent:     int32(reached),
+		BotID:       counter.BotID,
+		Scope:       counter.Scope,
+		Subject:     counter.Subject,
+		Period:      counter.Period,
+		PeriodStart: counter.PeriodStart,
+	})
+	if err != nil {
+		s.logger.Warn("mark quota notice failed", slog.String("bot_id", req.BotID), slog.Any("error", err))
+		return
+	}
+	if marked == 0 {
+		return
+	}
+	platform := policy.NotifyPlatform
+	if platform == "" {
+		platform = strings.TrimSpace(req.CurrentChannel)
+	}
+	if s.sender == nil || platform == "" || ownerUserID == "" {
+		s.logger.Warn("usage quota notice not sent to owner",
+			slog.String("bot_id", req.BotID),
+			slog.String("platform", platform),
+		)
+		return
+	}
+	text := formatNotice(limit, counter.Subject, used, reached, limit.Period.End(db.TimeFromPg(counter.PeriodStart)))
+	if err := s.sender.Send(ctx, req.BotID, channel.ChannelType(platform), channel.SendRequest{
+		ChannelIdentityID: ownerUserID,
+		Message:           channel.Message{Text: text},
+	}); err != nil {
+		s.logger.Warn("send usage quota notice failed",
+			slog.String("bot_id", req.BotID),
+			slog.String("platform", platform),
+			slog.Any("error", err),
+		)
+	}
+}
+
+func metricValue(counter sqlc.UsageQuotaCounter, metric Metric) float64 {
+	switch metric {
+	case MetricRequests:
+		return float64(counter.Requests)
+	case MetricTokens:
+		return float64(counter.Tokens)
+	case MetricCost:
+		return counter.Cost
+	}
+	return 0
+}
+
+var periodAdjectives = map[Period]string{
+	PeriodDay:   "daily",
+	PeriodWeek:  "weekly",
+	PeriodMonth: "monthly",
+}
+
+var metricNouns = map[Metric]string{
+	MetricRequests: "request",
+	MetricTokens:   "token",
+	MetricCost:     "spending",
+}
+
+// refusal is the reply to a request over limit.
+func refusal(policy Policy, limit Limit, resetAt time.Time) string {
+	if policy.Message != "" {
+		return policy.Message
+	}
+	when := resetAt.UTC().Format("2006-01-02 15:04")
+	if limit.Scope == ScopeBot {
+		return fmt.Sprintf("This bot has reached its %s usage limit. Please try again after %s UTC.", periodAdjectives[limit.Period], when)
+	}
+	return fmt.Sprintf("You have reached your %s usage limit for this bot. Please try again after %s UTC.", periodAdjectives[limit.Period], when)
+}
+
+func formatNotice(limit Limit, subject string, used float64, percent int, resetAt time.Time) string {
+	who, their := "this bot", "its"
+	switch limit.Scope {
+	case ScopeUser:
+		who, their = "member "+subject, "their"
+	case ScopeGuest:
+		who, their = "guest "+subject, "their"
+	}
+	what := fmt.Sprintf("%s %s limit (%s of %s)", periodAdjectives[limit.Period], metricNouns[limit.Metric],
+		formatAmount(limit.Metric, used), formatAmount(limit.Metric, limit.Max))
+	when := resetAt.UTC().Format("2006-01-02 15:04")
+	if percent >= 100 {
+		return fmt.Sprintf("Usage alert: %s has reached %s %s. Further requests are refused until %s UTC.", who, their, what, when)
+	}
+	return fmt.Sprintf("Usage alert: %s has used %d%% of %s %s. The counter resets at %s UTC.", who, percent, their, what, when)
+}
+
+func formatAmount(metric Metric, v float64) string {
+	if metric == MetricCost {
+		return fmt.Sprintf("%.2f", v)
+	}
+	return fmt.Sprintf("%.0f", math.Floor(v))
+}
@@ -0,0 +1,312 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/conversation"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/models"
+)
+
+const (
+	testBotID   = "11111111-1111-1111-1111-111111111111"
+	testOwnerID = "22222222-2222-2222-2222-222222222222"
+	testMember  = "33333333-3333-3333-3333-333333333333"
+)
+
+type counterKey struct {
+	scope, subject, period string
+	start                  time.Time
+}
+
+type fakeStore struct {
+	policy   []byte
+	members  map[string]bool
+	counters map[counterKey]*sqlc.UsageQuotaCounter
+	notified map[counterKey]map[string]int32
+}
+
+func newFakeStore(policy Policy) *fakeStore {
+	raw, _ := json.Marshal(policy)
+	return &fakeStore{
+		policy:   raw,
+		members:  map[string]bool{},
+		counters: map[counterKey]*sqlc.UsageQuotaCounter{},
+		notified: map[counterKey]map[string]int32{},
+	}
+}
+
+func (f *fakeStore) GetQuotaPolicy(_ context.Context, id pgtype.UUID) (sqlc.GetQuotaPolicyRow, error) {
+	owner, _ := db.ParseUUID(testOwnerID)
+	return sqlc.GetQuotaPolicyRow{ID: id, OwnerUserID: owner, QuotaPolicy: f.policy}, nil
+}
+
+func (f *fakeStore) UpdateQuotaPolicy(_ context.Context, arg sqlc.UpdateQuotaPolicyParams) ([]byte, error) {
+	f.policy = arg.QuotaPolicy
+	return arg.QuotaPolicy, nil
+}
+
+func (f *fakeStore) GetQuotaCounter(_ context.Context, arg sqlc.GetQuotaCounterParams) (sqlc.UsageQuotaCounter, error) {
+	c, ok := f.counters[counterKey{arg.Scope, arg.Subject, arg.Period, arg.PeriodStart.Time}]
+	if !ok {
+		return sqlc.UsageQuotaCounter{}, pgx.ErrNoRows
+	}
+	return *c, nil
+}
+
+func (f *fakeStore) AddQuotaUsage(_ context.Context, arg sqlc.AddQuotaUsageParams) (sqlc.UsageQuotaCounter, error) {
+	key := counterKey{arg.Scope, arg.Subject, arg.Period, arg.PeriodStart.Time}
+	c, ok := f.counters[key]
+	if !ok {
+		c = &sqlc.UsageQuotaCounter{BotID: arg.BotID, Scope: arg.Scope, Subject: arg.Subject, Period: arg.Period, PeriodStart: arg.PeriodStart}
+		f.counters[key] = c
+	}
+	c.Requests += arg.Requests
+	c.Tokens += arg.Tokens
+	c.Cost += arg.Cost
+	return *c, nil
+}
+
+func (f *fakeStore) MarkQuotaNotified(_ context.Context, arg sqlc.MarkQuotaNotifiedParams) (int64, error) {
+	key := counterKey{arg.Scope, arg.Subject, arg.Period, arg.PeriodStart.Time}
+	if f.notified[key] == nil {
+		f.notified[key] = map[string]int32{}
+	}
+	if f.notified[key][arg.Metric] >= arg.Percent {
+		return 0, nil
+	}
+	f.notified[key][arg.Metric] = arg.Percent
+	return 1, nil
+}
+
+func (f *fakeStore) ListQuotaCounters(_ context.Context, _ sqlc.ListQuotaCountersParams) ([]sqlc.UsageQuotaCounter, error) {
+	var out []sqlc.UsageQuotaCounter
+	for _, c := range f.counters {
+		out = append(out, *c)
+	}
+	return out, nil
+}
+
+func (f *fakeStore) DeleteQuotaCounters(_ context.Context, _ sqlc.DeleteQuotaCountersParams) (int64, error) {
+	n := int64(len(f.counters))
+	f.counters = map[counterKey]*sqlc.UsageQuotaCounter{}
+	return n, nil
+}
+
+func (f *fakeStore) DeleteExpiredQuotaCounters(_ context.Context, _ pgtype.Timestamptz) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeStore) GetBotMember(_ context.Context, arg sqlc.GetBotMemberParams) (sqlc.BotMember, error) {
+	if f.members[arg.UserID.String()] {
+		return sqlc.BotMember{BotID: arg.BotID, UserID: arg.UserID}, nil
+	}
+	return sqlc.BotMember{}, pgx.ErrNoRows
+}
+
+type fakePrices []models.Price
+
+func (f fakePrices) ListPrices(context.Context) ([]models.Price, error) {
+	return f, nil
+}
+
+type fakeSender struct {
+	sent []channel.SendRequest
+}
+
+func (f *fakeSender) Send(_ context.Context, _ string, _ channel.ChannelType, req channel.SendRequest) error {
+	f.sent = append(f.sent, req)
+	return nil
+}
+
+func newTestService(store *fakeStore, now time.Time) (*Service, *fakeSender) {
+	sender := &fakeSender{}
+	return &Service{
+		queries: store,
+		prices:  fakePrices{{ModelUUID: "gpt-test-uuid", ModelID: "gpt-test", Currency: "USD", InputPerMillion: 1, OutputPerMillion: 2}},
+		sender:  sender,
+		logger:  slog.Default(),
+		now:     func() time.Time { return now },
+	}, sender
+}
+
+func TestPeriodBoundaries(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC) // a Sunday
+	cases := []struct {
+		period     Period
+		start, end time.Time
+	}{
+		{PeriodDay, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{PeriodWeek, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+		{PeriodMonth, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		start := tc.period.Start(now)
+		if !start.Equal(tc.start) {
+			t.Fatalf("%s start: expected %s, got %s", tc.period, tc.start, start)
+		}
+		if end := tc.period.End(start); !end.Equal(tc.end) {
+			t.Fatalf("%s end: expected %s, got %s", tc.period, tc.end, end)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	valid := Policy{Limits: []Limit{
+		{Scope: ScopeGuest, Metric: MetricRequests, Period: PeriodDay, Max: 20},
+		{Scope: ScopeGuest, Metric: MetricTokens, Period: PeriodDay, Max: 50000},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	invalid := []Policy{
+		{Limits: []Limit{{Scope: "team", Metric: MetricRequests, Period: PeriodDay, Max: 1}}},
+		{Limits: []Limit{{Scope: ScopeBot, Metric: "calls", Period: PeriodDay, Max: 1}}},
+		{Limits: []Limit{{Scope: ScopeBot, Metric: MetricCost, Period: "year", Max: 1}}},
+		{Limits: []Limit{{Scope: ScopeBot, Metric: MetricCost, Period: PeriodDay, Max: -1}}},
+		{Limits: []Limit{
+			{Scope: ScopeUser, Metric: MetricTokens, Period: PeriodWeek, Max: 1},
+			{Scope: ScopeUser, Metric: MetricTokens, Period: PeriodWeek, Max: 2},
+		}},
+		{NotifyAt: []int{150}},
+	}
+	for i, p := range invalid {
+		if err := p.Validate(); !errors.Is(err, ErrInvalidPolicy) {
+			t.Fatalf("policy %d: expected ErrInvalidPolicy, got %v", i, err)
+		}
+	}
+}
+
+func TestCheckQuotaRefusesGuestOverLimit(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := newFakeStore(Policy{Limits: []Limit{{Scope: ScopeGuest, Metric: MetricRequests, Period: PeriodDay, Max: 2}}})
+	svc, _ := newTestService(store, now)
+	ctx := context.Background()
+	guest := conversation.ChatRequest{BotID: testBotID, SourceChannelIdentityID: "guest-1", CurrentChannel: "telegram"}
+
+	for i := 0; i < 2; i++ {
+		if err := svc.CheckQuota(ctx, guest); err != nil {
+			t.Fatalf("request %d: unexpected refusal: %v", i, err)
+		}
+		svc.RecordUsage(ctx, guest, "gpt-test-uuid", conversation.Usage{InputTokens: 10, OutputTokens: 5})
+	}
+	err := svc.CheckQuota(ctx, guest)
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || !errors.Is(err, ErrExceeded) {
+		t.Fatalf("expected quota refusal, got %v", err)
+	}
+	if !exceeded.ResetAt.Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected reset time: %s", exceeded.ResetAt)
+	}
+	if !strings.Contains(exceeded.Message, "daily usage limit") {
+		t.Fatalf("unexpected refusal message: %q", exceeded.Message)
+	}
+
+	// Other guests, members and the owner are not bound by this guest's counter.
+	if err := svc.CheckQuota(ctx, conversation.ChatRequest{BotID: testBotID, SourceChannelIdentityID: "guest-2"}); err != nil {
+		t.Fatalf("other guest refused: %v", err)
+	}
+	if err := svc.CheckQuota(ctx, conversation.ChatRequest{BotID: testBotID, UserID: testOwnerID, SourceChannelIdentityID: "guest-1"}); err != nil {
+		t.Fatalf("owner refused: %v", err)
+	}
+
+	// A new day starts the counter over.
+	svc.now = func() time.Time { return now.Add(24 * time.Hour) }
+	if err := svc.CheckQuota(ctx, guest); err != nil {
+		t.Fatalf("expected counter to reset the next day, got %v", err)
+	}
+}
+
+func TestRecordUsageCountsMembersAndNotifiesOwnerOnce(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := newFakeStore(Policy{
+		Limits: []Limit{
+			{Scope: ScopeBot, Metric: MetricTokens, Period: PeriodMonth, Max: 1000},
+			{Scope: ScopeBot, Metric: MetricCost, Period: PeriodMonth, Max: 1},
+			{Scope: ScopeUser, Metric: MetricRequests, Period: PeriodDay, Max: 100},
+		},
+	})
+	store.members[testMember] = true
+	svc, sender := newTestService(store, now)
+	ctx := context.Background()
+	member := conversation.ChatRequest{BotID: testBotID, UserID: testMember, CurrentChannel: "telegram"}
+
+	svc.RecordUsage(ctx, member, "gpt-test-uuid", conversation.Usage{InputTokens: 500, OutputTokens: 350})
+	svc.RecordUsage(ctx, member, "gpt-test-uuid", conversation.Usage{InputTokens: 10, OutputTokens: 10})
+
+	monthStart := PeriodMonth.Start(now)
+	bot := store.counters[counterKey{"bot", "", "month", monthStart}]
+	if bot == nil || bot.Requests != 2 || bot.Tokens != 870 {
+		t.Fatalf("unexpected bot counter: %+v", bot)
+	}
+	wantCost := (510*1 + 360*2) / 1e6
+	if diff := bot.Cost - wantCost; diff > 1e-12 || diff < -1e-12 {
+		t.Fatalf("expected cost %v, got %v", wantCost, bot.Cost)
+	}
+	user := store.counters[counterKey{"user", testMember, "day", PeriodDay.Start(now)}]
+	if user == nil || user.Requests != 2 {
+		t.Fatalf("unexpected member counter: %+v", user)
+	}
+	if _, ok := store.counters[counterKey{"guest", testMember, "day", PeriodDay.Start(now)}]; ok {
+		t.Fatal("member must not be counted as a guest")
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected one threshold notice, got %d", len(sender.sent))
+	}
+	notice := sender.sent[0]
+	if notice.ChannelIdentityID != testOwnerID || !strings.Contains(notice.Message.Text, "80% of its monthly token limit") {
+		t.Fatalf("unexpected notice: %+v", notice)
+	}
+}
+
+func TestRecordUsagePricesCachedAndReasoningTokens(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := newFakeStore(Policy{Limits: []Limit{{Scope: ScopeBot, Metric: MetricCost, Period: PeriodDay, Max: 1}}})
+	svc, _ := newTestService(store, now)
+	cached, reasoning := 0.1, 4.0
+	svc.prices = fakePrices{{ModelUUID: "gpt-test-uuid", ModelID: "gpt-test", Currency: "USD", InputPerMillion: 1, OutputPerMillion: 2, CachedInputPerMillion: &cached, ReasoningPerMillion: &reasoning}}
+
+	svc.RecordUsage(context.Background(), conversation.ChatRequest{BotID: testBotID, UserID: testOwnerID}, "gpt-test-uuid", conversation.Usage{
+		InputTokens:       1000,
+		OutputTokens:      100,
+		CachedInputTokens: 800,
+		ReasoningTokens:   50,
+	})
+
+	bot := store.counters[counterKey{"bot", "", "day", PeriodDay.Start(now)}]
+	wantCost := (200*1 + 800*0.1 + 50*2 + 50*4) / 1e6
+	if bot == nil || math.Abs(bot.Cost-wantCost) > 1e-12 {
+		t.Fatalf("expected cost %v, got %+v", wantCost, bot)
+	}
+}
+
+func TestUpdatePolicyRefusesCostLimitsAcrossCurrencies(t *testing.T) {
+	store := newFakeStore(Policy{})
+	svc, _ := newTestService(store, time.Now())
+	svc.prices = fakePrices{
+		{ModelUUID: "gpt-test-uuid", ModelID: "gpt-test", Currency: "USD", InputPerMillion: 1, OutputPerMillion: 2},
+		{ModelID: "glm-test", Currency: "CNY", InputPerMillion: 4, OutputPerMillion: 16},
+	}
+	ctx := context.Background()
+
+	costLimit := Policy{Limits: []Limit{{Scope: ScopeBot, Metric: MetricCost, Period: PeriodMonth, Max: 10}}}
+	if _, err := svc.UpdatePolicy(ctx, testBotID, costLimit); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected ErrInvalidPolicy, got %v", err)
+	}
+	tokenLimit := Policy{Limits: []Limit{{Scope: ScopeBot, Metric: MetricTokens, Period: PeriodMonth, Max: 10}}}
+	if _, err := svc.UpdatePolicy(ctx, testBotID, tokenLimit); err != nil {
+		t.Fatalf("token limits do not depend on prices: %v", err)
+	}
+}
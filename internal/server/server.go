@@ -81,5 +81,9 @@ func shouldSkipJWT(path string) bool {
 	if strings.HasPrefix(path, "/channels/feishu/webhook/") {
 		return true
 	}
+	// The OpenAI-compatible API authenticates with API tokens in its handler.
+	if strings.HasPrefix(path, "/v1/") {
+		return true
+	}
 	return false
 }
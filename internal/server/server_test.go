@@ -21,3 +21,24 @@ func TestShouldSkipJWT_FeishuWebhookPaths(t *testing.T) {
 		}
 	}
 }
+
+func TestShouldSkipJWT_OpenAIPaths(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{path: "/v1/chat/completions", want: true},
+		{path: "/v1/models", want: true},
+		{path: "/v1", want: false},
+		{path: "/bots/v1/models", want: false},
+	}
+
+	for _, tc := range cases {
+		got := shouldSkipJWT(tc.path)
+		if got != tc.want {
+			t.Fatalf("path=%q want=%v got=%v", tc.path, tc.want, got)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/memohai/memoh/internal/db"
 	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/models"
 )
 
 type Service struct {
@@ -84,6 +85,19 @@ func (s *Service) UpsertBot(ctx context.Context, botID string, req UpsertRequest
 	if req.ReasoningEffort != nil && isValidReasoningEffort(*req.ReasoningEffort) {
 		current.ReasoningEffort = *req.ReasoningEffort
 	}
+	// TTS columns are not part of the bot row, read them from the settings view.
+	if existing, err := s.queries.GetSettingsByBotID(ctx, pgID); err == nil {
+		current.TTSEnabled = existing.TtsEnabled
+		current.TTSVoice = strings.TrimSpace(existing.TtsVoice)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return Settings{}, err
+	}
+	if req.TTSEnabled != nil {
+		current.TTSEnabled = *req.TTSEnabled
+	}
+	if req.TTSVoice != nil {
+		current.TTSVoice = strings.TrimSpace(*req.TTSVoice)
+	}
 
 	chatModelUUID := pgtype.UUID{}
 	if value := strings.TrimSpace(req.ChatModelID); value != "" {
@@ -117,6 +131,30 @@ func (s *Service) UpsertBot(ctx context.Context, botID string, req UpsertRequest
 		}
 		searchProviderUUID = providerID
 	}
+	ttsModelUUID := pgtype.UUID{}
+	if value := strings.TrimSpace(req.TTSModelID); value != "" {
+		modelID, err := s.resolveModelUUIDOfType(ctx, value, models.ModelTypeSpeech)
+		if err != nil {
+			return Settings{}, err
+		}
+		ttsModelUUID = modelID
+	}
+	var fallbackModelUUIDs []pgtype.UUID
+	if req.FallbackModelIDs != nil {
+		fallbackModelUUIDs = make([]pgtype.UUID, 0, len(req.FallbackModelIDs))
+		seen := map[pgtype.UUID]struct{}{}
+		for _, ref := range req.FallbackModelIDs {
+			modelID, err := s.resolveModelUUID(ctx, ref)
+			if err != nil {
+				return Settings{}, err
+			}
+			if _, ok := seen[modelID]; ok {
+				continue
+			}
+			seen[modelID] = struct{}{}
+			fallbackModelUUIDs = append(fallbackModelUUIDs, modelID)
+		}
+	}
 
 	updated, err := s.queries.UpsertBotSettings(ctx, sqlc.UpsertBotSettingsParams{
 		ID:                 pgID,
@@ -127,10 +165,14 @@ func (s *Service) UpsertBot(ctx context.Context, botID string, req UpsertRequest
 		AllowGuest:         current.AllowGuest,
 		ReasoningEnabled:   current.ReasoningEnabled,
 		ReasoningEffort:    current.ReasoningEffort,
+		TtsEnabled:         current.TTSEnabled,
+		TtsVoice:           current.TTSVoice,
+		FallbackModelIds:   fallbackModelUUIDs,
 		ChatModelID:        chatModelUUID,
 		MemoryModelID:      memoryModelUUID,
 		EmbeddingModelID:   embeddingModelUUID,
 		SearchProviderID:   searchProviderUUID,
+		TtsModelID:         ttsModelUUID,
 	})
 	if err != nil {
 		return Settings{}, err
@@ -195,10 +237,14 @@ func normalizeBotSettingsReadRow(row sqlc.GetSettingsByBotIDRow) Settings {
 		row.AllowGuest,
 		row.ReasoningEnabled,
 		row.ReasoningEffort,
+		row.TtsEnabled,
+		row.TtsVoice,
+		row.FallbackModelIds,
 		row.ChatModelID,
 		row.MemoryModelID,
 		row.EmbeddingModelID,
 		row.SearchProviderID,
+		row.TtsModelID,
 	)
 }
 
@@ -211,10 +257,14 @@ func normalizeBotSettingsWriteRow(row sqlc.UpsertBotSettingsRow) Settings {
 		row.AllowGuest,
 		row.ReasoningEnabled,
 		row.ReasoningEffort,
+		row.TtsEnabled,
+		row.TtsVoice,
+		row.FallbackModelIds,
 		row.ChatModelID,
 		row.MemoryModelID,
 		row.EmbeddingModelID,
 		row.SearchProviderID,
+		row.TtsModelID,
 	)
 }
 
@@ -226,12 +276,19 @@ func normalizeBotSettingsFields(
 	allowGuest bool,
 	reasoningEnabled bool,
 	reasoningEffort string,
+	ttsEnabled bool,
+	ttsVoice string,
+	fallbackModelIDs []pgtype.UUID,
 	chatModelID pgtype.UUID,
 	memoryModelID pgtype.UUID,
 	embeddingModelID pgtype.UUID,
 	searchProviderID pgtype.UUID,
+	ttsModelID pgtype.UUID,
 ) Settings {
 	settings := normalizeBotSetting(maxContextLoadTime, maxContextTokens, maxInboxItems, language, allowGuest, reasoningEnabled, reasoningEffort)
+	settings.TTSEnabled = ttsEnabled
+	settings.TTSVoice = strings.TrimSpace(ttsVoice)
+	settings.FallbackModelIDs = uuidStrings(fallbackModelIDs)
 	if chatModelID.Valid {
 		settings.ChatModelID = uuid.UUID(chatModelID.Bytes).String()
 	}
@@ -244,9 +301,22 @@ func normalizeBotSettingsFields(
 	if searchProviderID.Valid {
 		settings.SearchProviderID = uuid.UUID(searchProviderID.Bytes).String()
 	}
+	if ttsModelID.Valid {
+		settings.TTSModelID = uuid.UUID(ttsModelID.Bytes).String()
+	}
 	return settings
 }
 
+func uuidStrings(ids []pgtype.UUID) []string {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id.Valid {
+			out = append(out, uuid.UUID(id.Bytes).String())
+		}
+	}
+	return out
+}
+
 func (s *Service) resolveModelUUID(ctx context.Context, modelID string) (pgtype.UUID, error) {
 	modelID = strings.TrimSpace(modelID)
 	if modelID == "" {
@@ -274,3 +344,20 @@ func (s *Service) resolveModelUUID(ctx context.Context, modelID string) (pgtype.
 	}
 	return rows[0].ID, nil
 }
+
+// resolveModelUUIDOfType resolves modelID like resolveModelUUID and rejects
+// models of any type other than want.
+func (s *Service) resolveModelUUIDOfType(ctx context.Context, modelID string, want models.ModelType) (pgtype.UUID, error) {
+	id, err := s.resolveModelUUID(ctx, modelID)
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+	row, err := s.queries.GetModelByID(ctx, id)
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+	if models.ModelType(row.Type) != want {
+		return pgtype.UUID{}, fmt.Errorf("%w: model %s is not a %s model", ErrInvalidModelRef, row.ModelID, want)
+	}
+	return id, nil
+}
@@ -8,29 +8,37 @@ const (
 )
 
 type Settings struct {
-	ChatModelID        string `json:"chat_model_id"`
-	MemoryModelID      string `json:"memory_model_id"`
-	EmbeddingModelID   string `json:"embedding_model_id"`
-	SearchProviderID   string `json:"search_provider_id"`
-	MaxContextLoadTime int    `json:"max_context_load_time"`
-	MaxContextTokens   int    `json:"max_context_tokens"`
-	MaxInboxItems      int    `json:"max_inbox_items"`
-	Language           string `json:"language"`
-	AllowGuest         bool   `json:"allow_guest"`
-	ReasoningEnabled   bool   `json:"reasoning_enabled"`
-	ReasoningEffort    string `json:"reasoning_effort"`
+	ChatModelID        string   `json:"chat_model_id"`
+	MemoryModelID      string   `json:"memory_model_id"`
+	EmbeddingModelID   string   `json:"embedding_model_id"`
+	SearchProviderID   string   `json:"search_provider_id"`
+	MaxContextLoadTime int      `json:"max_context_load_time"`
+	MaxContextTokens   int      `json:"max_context_tokens"`
+	MaxInboxItems      int      `json:"max_inbox_items"`
+	Language           string   `json:"language"`
+	AllowGuest         bool     `json:"allow_guest"`
+	ReasoningEnabled   bool     `json:"reasoning_enabled"`
+	ReasoningEffort    string   `json:"reasoning_effort"`
+	TTSEnabled         bool     `json:"tts_enabled"`
+	TTSModelID         string   `json:"tts_model_id"`
+	TTSVoice           string   `json:"tts_voice"`
+	FallbackModelIDs   []string `json:"fallback_model_ids"`
 }
 
 type UpsertRequest struct {
-	ChatModelID        string  `json:"chat_model_id,omitempty"`
-	MemoryModelID      string  `json:"memory_model_id,omitempty"`
-	EmbeddingModelID   string  `json:"embedding_model_id,omitempty"`
-	SearchProviderID   string  `json:"search_provider_id,omitempty"`
-	MaxContextLoadTime *int    `json:"max_context_load_time,omitempty"`
-	MaxContextTokens   *int    `json:"max_context_tokens,omitempty"`
-	MaxInboxItems      *int    `json:"max_inbox_items,omitempty"`
-	Language           string  `json:"language,omitempty"`
-	AllowGuest         *bool   `json:"allow_guest,omitempty"`
-	ReasoningEnabled   *bool   `json:"reasoning_enabled,omitempty"`
-	ReasoningEffort    *string `json:"reasoning_effort,omitempty"`
+	ChatModelID        string   `json:"chat_model_id,omitempty"`
+	MemoryModelID      string   `json:"memory_model_id,omitempty"`
+	EmbeddingModelID   string   `json:"embedding_model_id,omitempty"`
+	SearchProviderID   string   `json:"search_provider_id,omitempty"`
+	MaxContextLoadTime *int     `json:"max_context_load_time,omitempty"`
+	MaxContextTokens   *int     `json:"max_context_tokens,omitempty"`
+	MaxInboxItems      *int     `json:"max_inbox_items,omitempty"`
+	Language           string   `json:"language,omitempty"`
+	AllowGuest         *bool    `json:"allow_guest,omitempty"`
+	ReasoningEnabled   *bool    `json:"reasoning_enabled,omitempty"`
+	ReasoningEffort    *string  `json:"reasoning_effort,omitempty"`
+	TTSEnabled         *bool    `json:"tts_enabled,omitempty"`
+	TTSModelID         string   `json:"tts_model_id,omitempty"`
+	TTSVoice           *string  `json:"tts_voice,omitempty"`
+	FallbackModelIDs   []string `json:"fallback_model_ids,omitempty"`
 }
@@ -0,0 +1,96 @@
+package tokenizer
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Approximation estimates token counts from character classes for providers
+// whose tokenizers are not available locally. The rates are tuned to err on
+// the high side so budgets built on them do not overflow.
+type Approximation struct {
+	// LatinCharsPerToken is the average length of a token within runs of
+	// ASCII letters and digits.
+	LatinCharsPerToken float64
+	// OtherCharsPerToken applies to runs of non-ASCII letters outside CJK
+	// scripts (Cyrillic, Greek, Arabic, accented Latin, ...).
+	OtherCharsPerToken float64
+	// CJKTokensPerChar is the cost of one Han, Kana or Hangul character.
+	CJKTokensPerChar float64
+	// PunctCharsPerToken applies to runs of punctuation and symbols, which
+	// dominate JSON and code.
+	PunctCharsPerToken float64
+}
+
+// Approximations for the client types without a local tokenizer. OpenAI's
+// applies when no encoding file is loaded.
+var (
+	OpenAIApproximation    = Approximation{LatinCharsPerToken: 4, OtherCharsPerToken: 2, CJKTokensPerChar: 1.2, PunctCharsPerToken: 1.5}
+	AnthropicApproximation = Approximation{LatinCharsPerToken: 3.5, OtherCharsPerToken: 2, CJKTokensPerChar: 1.4, PunctCharsPerToken: 1.5}
+	GoogleApproximation    = Approximation{LatinCharsPerToken: 4, OtherCharsPerToken: 2.5, CJKTokensPerChar: 1, PunctCharsPerToken: 1.5}
+)
+
+type charClass int
+
+const (
+	classSpace charClass = iota
+	classLatin
+	classOther
+	classCJK
+	classPunct
+	classNewline
+)
+
+// Count returns the estimated number of tokens in text.
+func (a Approximation) Count(text string) int {
+	var total float64
+	runClass := classSpace
+	runLen := 0
+	flush := func() {
+		if runLen == 0 {
+			return
+		}
+		switch runClass {
+		case classLatin:
+			total += math.Ceil(float64(runLen) / a.LatinCharsPerToken)
+		case classOther:
+			total += math.Ceil(float64(runLen) / a.OtherCharsPerToken)
+		case classCJK:
+			total += math.Ceil(float64(runLen) * a.CJKTokensPerChar)
+		case classPunct:
+			total += math.Ceil(float64(runLen) / a.PunctCharsPerToken)
+		case classNewline:
+			total++
+		}
+		runLen = 0
+	}
+	for _, r := range text {
+		class := classify(r)
+		if class != runClass {
+			flush()
+			runClass = class
+		}
+		runLen++
+	}
+	flush()
+	return int(total)
+}
+
+func classify(r rune) charClass {
+	switch {
+	case r == '\n' || r == '\r':
+		return classNewline
+	case unicode.IsSpace(r):
+		// Spaces merge into the following word in every tokenizer we model.
+		return classSpace
+	case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+		return classLatin
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return classCJK
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+		return classOther
+	default:
+		return classPunct
+	}
+}
@@ -0,0 +1,163 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Pre-tokenization patterns of the OpenAI encodings. Both end in
+// `\s+(?!\S)|\s+` upstream; RE2 has no lookahead, so the alternative is
+// emulated in BPE.split.
+const (
+	PatternCL100K = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`
+	PatternO200K  = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`
+)
+
+// BPE is a byte-level BPE tokenizer compatible with tiktoken encodings.
+type BPE struct {
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// NewBPE builds a tokenizer from merge ranks and a pre-tokenization pattern.
+// Every single byte must have a rank.
+func NewBPE(ranks map[string]int, pattern string) (*BPE, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("compile pattern: %w", err)
+	}
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("ranks miss byte 0x%02x", b)
+		}
+	}
+	return &BPE{ranks: ranks, pattern: re}, nil
+}
+
+// LoadTiktoken reads a .tiktoken rank file: one base64 token and its rank
+// per line.
+func LoadTiktoken(r io.Reader, pattern string) (*BPE, error) {
+	ranks := make(map[string]int, 200000)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected token and rank", line)
+		}
+		raw, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(rank))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ranks[string(raw)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewBPE(ranks, pattern)
+}
+
+// LoadTiktokenFile reads a .tiktoken rank file from disk.
+func LoadTiktokenFile(path, pattern string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return LoadTiktoken(f, pattern)
+}
+
+// Count returns the number of tokens text encodes to.
+func (b *BPE) Count(text string) int {
+	total := 0
+	for _, piece := range b.split(text) {
+		if _, ok := b.ranks[piece]; ok {
+			total++
+			continue
+		}
+		total += b.mergeCount([]byte(piece))
+	}
+	return total
+}
+
+// split applies the pre-tokenization pattern. A whitespace run matched by the
+// trailing `\s+` gives its last character back when a non-space follows, as
+// `\s+(?!\S)` does in the original patterns.
+func (b *BPE) split(text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		loc := b.pattern.FindStringIndex(text)
+		if loc == nil {
+			pieces = append(pieces, text)
+			break
+		}
+		if loc[0] > 0 {
+			pieces = append(pieces, text[:loc[0]])
+		}
+		end := loc[1]
+		if end == loc[0] {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+		}
+		piece := text[loc[0]:end]
+		if end < len(text) && isBareSpaceRun(piece) {
+			if _, size := utf8.DecodeLastRuneInString(piece); size < len(piece) {
+				end -= size
+				piece = piece[:len(piece)-size]
+			}
+		}
+		pieces = append(pieces, piece)
+		text = text[end:]
+	}
+	return pieces
+}
+
+// isBareSpaceRun reports whether s is whitespace not ending in a line break,
+// which only the trailing `\s+` alternative produces.
+func isBareSpaceRun(s string) bool {
+	if s == "" || strings.HasSuffix(s, "\n") || strings.HasSuffix(s, "\r") {
+		return false
+	}
+	// RE2's \s class.
+	return strings.Trim(s, " \t\n\f\r") == ""
+}
+
+// mergeCount runs byte pair merges over piece, always merging the adjacent
+// pair with the lowest rank, and returns the resulting number of tokens.
+func (b *BPE) mergeCount(piece []byte) int {
+	// bounds[i]:bounds[i+1] is the i-th part of piece.
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(bounds); i++ {
+			rank, ok := b.ranks[string(piece[bounds[i]:bounds[i+2]])]
+			if ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return len(bounds) - 1
+}
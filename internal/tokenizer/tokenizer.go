@@ -0,0 +1,134 @@
+// Package tokenizer counts tokens the way the model providers do, so context
+// budgets hold for non-Latin scripts, images and tool payloads.
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/memohai/memoh/internal/models"
+)
+
+// Tokenizer counts the tokens a text costs a model.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// Encoding names of the tiktoken rank files.
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+)
+
+var encodingPatterns = map[string]string{
+	EncodingCL100K: PatternCL100K,
+	EncodingO200K:  PatternO200K,
+}
+
+// Registry picks the tokenizer for a client type and model. OpenAI models use
+// a BPE encoding once its rank file is loaded; everything else, and OpenAI
+// without rank files, uses the provider's Approximation.
+type Registry struct {
+	mu        sync.RWMutex
+	encodings map[string]Tokenizer
+	clients   map[models.ClientType]Tokenizer
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		encodings: map[string]Tokenizer{},
+		clients: map[models.ClientType]Tokenizer{
+			models.ClientTypeOpenAIResponses:    OpenAIApproximation,
+			models.ClientTypeOpenAICompletions:  OpenAIApproximation,
+			models.ClientTypeAnthropicMessages:  AnthropicApproximation,
+			models.ClientTypeGoogleGenerativeAI: GoogleApproximation,
+		},
+	}
+}
+
+// SetEncoding registers the tokenizer for a tiktoken encoding name.
+func (r *Registry) SetEncoding(name string, tok Tokenizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.encodings[name] = tok
+}
+
+// SetClient replaces the tokenizer used for a client type.
+func (r *Registry) SetClient(clientType models.ClientType, tok Tokenizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[clientType] = tok
+}
+
+// LoadDir loads the known encodings (<name>.tiktoken) found in dir. Missing
+// files are skipped. It returns the names it loaded.
+func (r *Registry) LoadDir(dir string) ([]string, error) {
+	var loaded []string
+	for name, pattern := range encodingPatterns {
+		path := filepath.Join(dir, name+".tiktoken")
+		bpe, err := LoadTiktokenFile(path, pattern)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return loaded, fmt.Errorf("load %s: %w", path, err)
+		}
+		r.SetEncoding(name, bpe)
+		loaded = append(loaded, name)
+	}
+	return loaded, nil
+}
+
+// For returns the tokenizer for a model. It never returns nil.
+func (r *Registry) For(clientType models.ClientType, modelID string) Tokenizer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if isOpenAI(clientType) {
+		if tok, ok := r.encodings[EncodingForModel(modelID)]; ok {
+			return tok
+		}
+	}
+	if tok, ok := r.clients[clientType]; ok {
+		return tok
+	}
+	return OpenAIApproximation
+}
+
+// EncodingForModel returns the tiktoken encoding an OpenAI model uses.
+func EncodingForModel(modelID string) string {
+	id := strings.ToLower(strings.TrimSpace(modelID))
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+	for _, prefix := range []string{"gpt-3.5", "gpt-35", "gpt-4-", "text-embedding-"} {
+		if strings.HasPrefix(id, prefix) {
+			return EncodingCL100K
+		}
+	}
+	if id == "gpt-4" {
+		return EncodingCL100K
+	}
+	return EncodingO200K
+}
+
+// ImageTokens estimates what one image input costs a client type: a
+// high-detail 1024x1024 tile set for OpenAI, the ~1.15 megapixel cap for
+// Anthropic and Gemini's fixed per-image charge.
+func ImageTokens(clientType models.ClientType) int {
+	switch clientType {
+	case models.ClientTypeAnthropicMessages:
+		return 1600
+	case models.ClientTypeGoogleGenerativeAI:
+		return 258
+	default:
+		return 765
+	}
+}
+
+func isOpenAI(clientType models.ClientType) bool {
+	return clientType == models.ClientTypeOpenAIResponses || clientType == models.ClientTypeOpenAICompletions
+}
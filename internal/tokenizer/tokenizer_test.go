@@ -0,0 +1,186 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/models"
+)
+
+// fixtureRanks is a tiny encoding: every byte, then a few merges.
+func fixtureRanks(merges ...string) map[string]int {
+	ranks := make(map[string]int, 256+len(merges))
+	for b := 0; b < 256; b++ {
+		ranks[string([]byte{byte(b)})] = b
+	}
+	for i, m := range merges {
+		ranks[m] = 256 + i
+	}
+	return ranks
+}
+
+func TestBPESplitMixedText(t *testing.T) {
+	bpe, err := NewBPE(fixtureRanks(), PatternCL100K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := bpe.split("Hello  world\n\n  你好123456 it's {\"x\":1}")
+	want := []string{"Hello", " ", " world", "\n\n", " ", " 你好", "123", "456", " it", "'s", " {\"", "x", "\":", "1", "}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("split mismatch\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestBPESplitTrailingSpaces(t *testing.T) {
+	bpe, err := NewBPE(fixtureRanks(), PatternO200K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := bpe.split("a   1  ")
+	want := []string{"a", "  ", " ", "1", "  "}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("split mismatch\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestBPEMergesByRank(t *testing.T) {
+	bpe, err := NewBPE(fixtureRanks("he", "ll", "hell", " w", "\xe4\xbd", "你"), PatternCL100K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]int{
+		"hello":  2, // he + ll -> hell, o
+		"hel":    2, // he, l
+		" world": 5, // " w", o, r, l, d
+		"你":      1,
+		"你好":     4, // 你 + three bytes of 好
+	}
+	for text, want := range cases {
+		if got := bpe.Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestLoadTiktokenAndRegistry(t *testing.T) {
+	var b strings.Builder
+	for token, rank := range fixtureRanks("he", "ll", "hell") {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, EncodingO200K+".tiktoken"), []byte(b.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := NewRegistry()
+	if got := reg.For(models.ClientTypeOpenAIResponses, "gpt-4o"); got != OpenAIApproximation {
+		t.Fatalf("expected approximation before loading, got %T", got)
+	}
+	loaded, err := reg.LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, []string{EncodingO200K}) {
+		t.Fatalf("unexpected loaded encodings: %v", loaded)
+	}
+	tok := reg.For(models.ClientTypeOpenAICompletions, "openai/gpt-4.1-mini")
+	if _, ok := tok.(*BPE); !ok {
+		t.Fatalf("expected BPE for o200k model, got %T", tok)
+	}
+	if got := tok.Count("hello"); got != 2 {
+		t.Fatalf("Count(hello) = %d, want 2", got)
+	}
+	// cl100k is not loaded, so gpt-4 keeps the approximation.
+	if got := reg.For(models.ClientTypeOpenAICompletions, "gpt-4"); got != OpenAIApproximation {
+		t.Fatalf("expected approximation for cl100k model, got %T", got)
+	}
+	if got := reg.For(models.ClientTypeAnthropicMessages, "claude-sonnet"); got != AnthropicApproximation {
+		t.Fatalf("expected Anthropic approximation, got %T", got)
+	}
+	if got := reg.For("unknown", "x"); got == nil {
+		t.Fatal("For must not return nil")
+	}
+}
+
+func TestLoadTiktokenRejectsIncompleteRanks(t *testing.T) {
+	if _, err := LoadTiktoken(strings.NewReader("aGU= 256\n"), PatternCL100K); err == nil {
+		t.Fatal("expected an error for ranks without byte tokens")
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	cases := map[string]string{
+		"gpt-4":         EncodingCL100K,
+		"gpt-4-turbo":   EncodingCL100K,
+		"gpt-3.5-turbo": EncodingCL100K,
+		"gpt-4o-mini":   EncodingO200K,
+		"gpt-4.1":       EncodingO200K,
+		"o3-mini":       EncodingO200K,
+		"openai/gpt-5":  EncodingO200K,
+	}
+	for model, want := range cases {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %s, want %s", model, got, want)
+		}
+	}
+}
+
+// Mixed-language fixtures with approximate cl100k token counts. The
+// approximations only need to land in a safe band around them; the byte-length
+// heuristic the resolver used before undercounts everything but English.
+var mixedFixtures = []struct {
+	name   string
+	text   string
+	cl100k int
+}{
+	{"english", "The quick brown fox jumps over the lazy dog.", 10},
+	{"chinese", "我们今天下午三点在会议室讨论项目的进度和下一步计划。", 30},
+	{"japanese", "明日の天気は晴れのち曇りでしょう。", 18},
+	{"russian", "Привет, как у тебя дела сегодня?", 13},
+	{"mixed", "请把 report.pdf 发给 Alice，然后 reply 说 OK。", 20},
+	{"json", `{"name":"search","arguments":{"query":"天气","limit":5}}`, 20},
+}
+
+func TestApproximationsOnMixedLanguages(t *testing.T) {
+	approximations := map[string]Approximation{
+		"openai":    OpenAIApproximation,
+		"anthropic": AnthropicApproximation,
+		"google":    GoogleApproximation,
+	}
+	for _, f := range mixedFixtures {
+		for name, a := range approximations {
+			got := a.Count(f.text)
+			// Within 40% below and 2x above the reference count.
+			if float64(got) < float64(f.cl100k)*0.6 || got > f.cl100k*2 {
+				t.Errorf("%s/%s: Count = %d, reference %d", name, f.name, got, f.cl100k)
+			}
+		}
+		if f.name != "english" && len(f.text)/4 >= OpenAIApproximation.Count(f.text) {
+			t.Errorf("%s: expected approximation above len/4 (%d)", f.name, len(f.text)/4)
+		}
+	}
+}
+
+func TestApproximationCJKCostsMoreThanBytes(t *testing.T) {
+	text := strings.Repeat("汉字", 50)
+	if got := AnthropicApproximation.Count(text); got < 100 {
+		t.Fatalf("expected at least one token per Han character, got %d", got)
+	}
+	if got := OpenAIApproximation.Count(""); got != 0 {
+		t.Fatalf("empty text should cost nothing, got %d", got)
+	}
+}
+
+func TestImageTokens(t *testing.T) {
+	if ImageTokens(models.ClientTypeGoogleGenerativeAI) >= ImageTokens(models.ClientTypeOpenAIResponses) {
+		t.Fatal("expected Gemini images to be cheaper than OpenAI high detail")
+	}
+	if ImageTokens("") <= 0 {
+		t.Fatal("expected a positive default image cost")
+	}
+}
@@ -0,0 +1,132 @@
+package toolapproval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = 5 * time.Minute
+	maxTimeout     = 24 * time.Hour
+)
+
+// ErrInvalidPolicy is returned for policies that cannot be applied.
+var ErrInvalidPolicy = errors.New("invalid tool approval policy")
+
+// Policy lists the tool calls of a bot that wait for the owner's approval.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+	// TimeoutSeconds is how long a call waits for a decision before it is
+	// refused. Zero uses the default of five minutes.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// NotifyPlatform is the channel the owner is asked on. Empty asks on the
+	// channel the call came from.
+	NotifyPlatform string `json:"notify_platform,omitempty"`
+}
+
+// Rule marks calls of a tool as needing approval. Tool accepts glob
+// patterns such as "send_*". With Pattern set, only calls whose Argument
+// (or, without Argument, whose whole argument object as JSON) matches the
+// regular expression need approval.
+type Rule struct {
+	Tool     string `json:"tool"`
+	Argument string `json:"argument,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+}
+
+// String describes the rule for the audit trail.
+func (r Rule) String() string {
+	switch {
+	case r.Pattern == "":
+		return r.Tool
+	case r.Argument == "":
+		return fmt.Sprintf("%s ~ /%s/", r.Tool, r.Pattern)
+	default:
+		return fmt.Sprintf("%s %s ~ /%s/", r.Tool, r.Argument, r.Pattern)
+	}
+}
+
+// Timeout returns how long a call waits for a decision.
+func (p Policy) Timeout() time.Duration {
+	if p.TimeoutSeconds <= 0 {
+		return defaultTimeout
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+// Validate checks tool globs, patterns and the timeout.
+func (p Policy) Validate() error {
+	if p.TimeoutSeconds < 0 || time.Duration(p.TimeoutSeconds)*time.Second > maxTimeout {
+		return fmt.Errorf("%w: timeout_seconds must be between 0 and %d", ErrInvalidPolicy, int(maxTimeout/time.Second))
+	}
+	for i, rule := range p.Rules {
+		if strings.TrimSpace(rule.Tool) == "" {
+			return fmt.Errorf("%w: rule %d: tool is required", ErrInvalidPolicy, i)
+		}
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return fmt.Errorf("%w: rule %d: bad tool pattern %q", ErrInvalidPolicy, i, rule.Tool)
+		}
+		if rule.Argument != "" && rule.Pattern == "" {
+			return fmt.Errorf("%w: rule %d: argument needs a pattern", ErrInvalidPolicy, i)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("%w: rule %d: %v", ErrInvalidPolicy, i, err)
+		}
+	}
+	return nil
+}
+
+// Match returns the first rule that holds the call, if any.
+func (p Policy) Match(toolName string, arguments map[string]any) (Rule, bool) {
+	for _, rule := range p.Rules {
+		if ok, _ := path.Match(rule.Tool, toolName); !ok {
+			continue
+		}
+		if rule.Pattern == "" {
+			return rule, true
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			continue
+		}
+		var subject string
+		if rule.Argument == "" {
+			subject = argumentText(arguments)
+		} else {
+			value, ok := arguments[rule.Argument]
+			if !ok {
+				continue
+			}
+			subject = argumentText(value)
+		}
+		if re.MatchString(subject) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+func argumentText(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func parsePolicy(raw []byte) Policy {
+	var p Policy
+	if len(raw) == 0 {
+		return p
+	}
+	_ = json.Unmarshal(raw, &p)
+	return p
+}
@@ -0,0 +1,496 @@
+package toolapproval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	mcpgw "github.com/memohai/memoh/internal/mcp"
+)
+
+// Approval statuses.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+	StatusExpired  = "expired"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+	refLength        = 8
+	// pollInterval bounds how late a waiting call sees a decision made by
+	// another server instance.
+	pollInterval = 3 * time.Second
+)
+
+var (
+	ErrNotFound       = errors.New("tool approval not found")
+	ErrAlreadyDecided = errors.New("tool approval already decided")
+	ErrAmbiguous      = errors.New("more than one pending tool approval matches")
+)
+
+// Approval is one held tool call and its outcome.
+type Approval struct {
+	ID          string         `json:"id"`
+	BotID       string         `json:"bot_id"`
+	ToolName    string         `json:"tool_name"`
+	Arguments   map[string]any `json:"arguments"`
+	Rule        string         `json:"rule,omitempty"`
+	Platform    string         `json:"platform,omitempty"`
+	RequestedBy string         `json:"requested_by_channel_identity_id,omitempty"`
+	Status      string         `json:"status"`
+	DecidedBy   string         `json:"decided_by_user_id,omitempty"`
+	Note        string         `json:"decision_note,omitempty"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+	DecidedAt   time.Time      `json:"decided_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Ref is the short ID used in /approve and /deny commands.
+func (a Approval) Ref() string {
+	if len(a.ID) <= refLength {
+		return a.ID
+	}
+	return a.ID[:refLength]
+}
+
+// ChannelSender delivers approval requests to the owner. channel.Manager
+// implements it.
+type ChannelSender interface {
+	Send(ctx context.Context, botID string, channelType channel.ChannelType, req channel.SendRequest) error
+}
+
+type store interface {
+	GetToolApprovalPolicy(ctx context.Context, id pgtype.UUID) (sqlc.GetToolApprovalPolicyRow, error)
+	UpdateToolApprovalPolicy(ctx context.Context, arg sqlc.UpdateToolApprovalPolicyParams) ([]byte, error)
+	CreateToolApproval(ctx context.Context, arg sqlc.CreateToolApprovalParams) (sqlc.ToolApproval, error)
+	GetToolApproval(ctx context.Context, arg sqlc.GetToolApprovalParams) (sqlc.ToolApproval, error)
+	ListToolApprovals(ctx context.Context, arg sqlc.ListToolApprovalsParams) ([]sqlc.ToolApproval, error)
+	DecideToolApproval(ctx context.Context, arg sqlc.DecideToolApprovalParams) (sqlc.ToolApproval, error)
+	ExpireToolApprovals(ctx context.Context) error
+}
+
+// Service holds tool calls that a bot's policy marks as sensitive until the
+// bot owner approves or denies them. It implements mcp.ToolCallReviewer.
+type Service struct {
+	queries  store
+	sender   ChannelSender
+	registry *channel.Registry
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	waiters map[string]chan Approval
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Service{
+		queries: queries,
+		logger:  log.With(slog.String("service", "tool_approval")),
+		waiters: map[string]chan Approval{},
+	}
+}
+
+// SetChannelSender enables asking the owner on a channel. Approve and deny
+// buttons are attached where the registry reports button support.
+func (s *Service) SetChannelSender(sender ChannelSender, registry *channel.Registry) {
+	s.sender = sender
+	s.registry = registry
+}
+
+// GetPolicy returns the approval policy of a bot.
+func (s *Service) GetPolicy(ctx context.Context, botID string) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	row, err := s.queries.GetToolApprovalPolicy(ctx, pgBotID)
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(row.ToolApprovalPolicy), nil
+}
+
+// UpdatePolicy replaces the approval policy of a bot.
+func (s *Service) UpdatePolicy(ctx context.Context, botID string, policy Policy) (Policy, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Policy{}, err
+	}
+	policy.NotifyPlatform = strings.TrimSpace(policy.NotifyPlatform)
+	rules := make([]Rule, 0, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		rule.Tool = strings.TrimSpace(rule.Tool)
+		rule.Argument = strings.TrimSpace(rule.Argument)
+		rules = append(rules, rule)
+	}
+	policy.Rules = rules
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return Policy{}, err
+	}
+	stored, err := s.queries.UpdateToolApprovalPolicy(ctx, sqlc.UpdateToolApprovalPolicyParams{
+		ToolApprovalPolicy: raw,
+		ID:                 pgBotID,
+	})
+	if err != nil {
+		return Policy{}, err
+	}
+	return parsePolicy(stored), nil
+}
+
+// ReviewToolCall holds a call matched by the bot's policy until the owner
+// decides or the policy timeout passes. Unmatched calls return at once.
+func (s *Service) ReviewToolCall(ctx context.Context, session mcpgw.ToolSessionContext, toolName string, arguments map[string]any) (string, error) {
+	pgBotID, err := db.ParseUUID(session.BotID)
+	if err != nil {
+		return "", err
+	}
+	row, err := s.queries.GetToolApprovalPolicy(ctx, pgBotID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("load tool approval policy: %w", err)
+	}
+	policy := parsePolicy(row.ToolApprovalPolicy)
+	rule, ok := policy.Match(toolName, arguments)
+	if !ok {
+		return "", nil
+	}
+
+	approval, err := s.create(ctx, pgBotID, session, toolName, arguments, rule, policy.Timeout())
+	if err != nil {
+		return "", err
+	}
+	waiter := s.register(approval.ID)
+	defer s.unregister(approval.ID)
+
+	s.logger.Info("tool call held for approval",
+		slog.String("bot_id", approval.BotID),
+		slog.String("approval_id", approval.ID),
+		slog.String("tool", toolName),
+		slog.String("rule", approval.Rule),
+	)
+	s.notify(ctx, policy, uuidString(row.OwnerUserID), session, approval)
+
+	decided, err := s.wait(ctx, approval, waiter)
+	if err != nil {
+		return "", err
+	}
+	return refusal(decided), nil
+}
+
+// Decide approves or denies a pending call on behalf of userID.
+func (s *Service) Decide(ctx context.Context, botID, approvalID string, approve bool, userID, note string) (Approval, error) {
+	status := StatusDenied
+	if approve {
+		status = StatusApproved
+	}
+	decided, err := s.decide(ctx, botID, approvalID, status, userID, note)
+	if err != nil {
+		return Approval{}, err
+	}
+	s.signal(decided)
+	s.logger.Info("tool call decided",
+		slog.String("bot_id", decided.BotID),
+		slog.String("approval_id", decided.ID),
+		slog.String("status", decided.Status),
+	)
+	return decided, nil
+}
+
+// FindPending resolves the short ref of a pending approval. An empty ref
+// matches the only pending approval of the bot.
+func (s *Service) FindPending(ctx context.Context, botID, ref string) (Approval, error) {
+	pending, err := s.List(ctx, botID, StatusPending, maxListLimit)
+	if err != nil {
+		return Approval{}, err
+	}
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	var matched []Approval
+	for _, item := range pending {
+		if strings.HasPrefix(item.ID, ref) {
+			matched = append(matched, item)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return Approval{}, ErrNotFound
+	case 1:
+		return matched[0], nil
+	default:
+		return Approval{}, ErrAmbiguous
+	}
+}
+
+// List returns the audit trail of a bot, newest first, optionally narrowed
+// to one status.
+func (s *Service) List(ctx context.Context, botID, status string, limit int) ([]Approval, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.queries.ExpireToolApprovals(ctx); err != nil {
+		s.logger.Warn("expire tool approvals failed", slog.Any("error", err))
+	}
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	var pgStatus pgtype.Text
+	if status = strings.TrimSpace(status); status != "" {
+		pgStatus = pgtype.Text{String: status, Valid: true}
+	}
+	rows, err := s.queries.ListToolApprovals(ctx, sqlc.ListToolApprovalsParams{
+		BotID:    pgBotID,
+		Status:   pgStatus,
+		MaxCount: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Approval, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, toApproval(row))
+	}
+	return items, nil
+}
+
+func (s *Service) create(ctx context.Context, botID pgtype.UUID, session mcpgw.ToolSessionContext, toolName string, arguments map[string]any, rule Rule, timeout time.Duration) (Approval, error) {
+	args, err := json.Marshal(arguments)
+	if err != nil {
+		return Approval{}, err
+	}
+	requestedBy, _ := db.ParseUUID(session.ChannelIdentityID)
+	row, err := s.queries.CreateToolApproval(ctx, sqlc.CreateToolApprovalParams{
+		BotID:                        botID,
+		ToolName:                     toolName,
+		Arguments:                    args,
+		Rule:                         rule.String(),
+		Platform:                     strings.TrimSpace(session.CurrentPlatform),
+		RequestedByChannelIdentityID: requestedBy,
+		ExpiresAt:                    pgtype.Timestamptz{Time: time.Now().Add(timeout), Valid: true},
+	})
+	if err != nil {
+		return Approval{}, fmt.Errorf("create tool approval: %w", err)
+	}
+	return toApproval(row), nil
+}
+
+func (s *Service) decide(ctx context.Context, botID, approvalID, status, userID, note string) (Approval, error) {
+	pgBotID, err := db.ParseUUID(botID)
+	if err != nil {
+		return Approval{}, err
+	}
+	pgID, err := db.ParseUUID(approvalID)
+	if err != nil {
+		return Approval{}, ErrNotFound
+	}
+	decidedBy, _ := db.ParseUUID(userID)
+	row, err := s.queries.DecideToolApproval(ctx, sqlc.DecideToolApprovalParams{
+		Status:          status,
+		DecidedByUserID: decidedBy,
+		DecisionNote:    strings.TrimSpace(note),
+		ID:              pgID,
+		BotID:           pgBotID,
+	})
+	if err == nil {
+		return toApproval(row), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Approval{}, err
+	}
+	current, err := s.queries.GetToolApproval(ctx, sqlc.GetToolApprovalParams{ID: pgID, BotID: pgBotID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Approval{}, ErrNotFound
+		}
+		return Approval{}, err
+	}
+	return toApproval(current), ErrAlreadyDecided
+}
+
+// wait blocks until approval is decided, expires, or ctx ends. An abandoned
+// call is recorded as expired.
+func (s *Service) wait(ctx context.Context, approval Approval, waiter <-chan Approval) (Approval, error) {
+	timer := time.NewTimer(time.Until(approval.ExpiresAt))
+	defer timer.Stop()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case decided := <-waiter:
+			return decided, nil
+		case <-ticker.C:
+			pgID, _ := db.ParseUUID(approval.ID)
+			pgBotID, _ := db.ParseUUID(approval.BotID)
+			current, err := s.queries.GetToolApproval(ctx, sqlc.GetToolApprovalParams{ID: pgID, BotID: pgBotID})
+			if err == nil && current.Status != StatusPending {
+				return toApproval(current), nil
+			}
+		case <-timer.C:
+			return s.expire(context.WithoutCancel(ctx), approval, "")
+		case <-ctx.Done():
+			_, _ = s.expire(context.WithoutCancel(ctx), approval, "tool call abandoned")
+			return Approval{}, ctx.Err()
+		}
+	}
+}
+
+func (s *Service) expire(ctx context.Context, approval Approval, note string) (Approval, error) {
+	expired, err := s.decide(ctx, approval.BotID, approval.ID, StatusExpired, "", note)
+	if errors.Is(err, ErrAlreadyDecided) {
+		return expired, nil
+	}
+	return expired, err
+}
+
+func (s *Service) register(id string) chan Approval {
+	ch := make(chan Approval, 1)
+	s.mu.Lock()
+	s.waiters[id] = ch
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *Service) unregister(id string) {
+	s.mu.Lock()
+	delete(s.waiters, id)
+	s.mu.Unlock()
+}
+
+func (s *Service) signal(approval Approval) {
+	s.mu.Lock()
+	ch, ok := s.waiters[approval.ID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case ch <- approval:
+	default:
+	}
+}
+
+// notify asks the owner on the policy's channel, or the channel the call
+// came from. A failed notice only logs: the call can still be decided over
+// HTTP until it expires.
+func (s *Service) notify(ctx context.Context, policy Policy, ownerUserID string, session mcpgw.ToolSessionContext, approval Approval) {
+	platform := policy.NotifyPlatform
+	if platform == "" {
+		platform = strings.TrimSpace(session.CurrentPlatform)
+	}
+	if s.sender == nil || platform == "" || ownerUserID == "" {
+		s.logger.Warn("tool approval not sent to owner",
+			slog.String("approval_id", approval.ID),
+			slog.String("platform", platform),
+		)
+		return
+	}
+	channelType := channel.ChannelType(platform)
+	msg := channel.Message{Text: formatRequest(approval)}
+	if s.registry != nil {
+		if caps, ok := s.registry.GetCapabilities(channelType); ok && caps.Buttons {
+			msg.Actions = []channel.Action{
+				{Type: "button", Label: "Approve", Value: "/approve " + approval.Ref()},
+				{Type: "button", Label: "Deny", Value: "/deny " + approval.Ref()},
+			}
+		}
+	}
+	if err := s.sender.Send(ctx, approval.BotID, channelType, channel.SendRequest{
+		ChannelIdentityID: ownerUserID,
+		Message:           msg,
+	}); err != nil {
+		s.logger.Warn("send tool approval request failed",
+			slog.String("approval_id", approval.ID),
+			slog.String("platform", platform),
+			slog.Any("error", err),
+		)
+	}
+}
+
+const maxArgumentPreview = 1000
+
+func formatRequest(approval Approval) string {
+	args, err := json.MarshalIndent(approval.Arguments, "", "  ")
+	if err != nil {
+		args = []byte("{}")
+	}
+	preview := string(args)
+	if len(preview) > maxArgumentPreview {
+		preview = preview[:maxArgumentPreview] + "\n…"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Approval needed: the bot wants to call %s.\n\n", approval.ToolName)
+	fmt.Fprintf(&b, "Arguments:\n%s\n\n", preview)
+	fmt.Fprintf(&b, "Rule: %s\n", approval.Rule)
+	fmt.Fprintf(&b, "Reply /approve %s or /deny %s [reason] before %s UTC.",
+		approval.Ref(), approval.Ref(), approval.ExpiresAt.UTC().Format("2006-01-02 15:04"))
+	return b.String()
+}
+
+// refusal is the tool result text for a call that may not run.
+func refusal(approval Approval) string {
+	switch approval.Status {
+	case StatusApproved:
+		return ""
+	case StatusDenied:
+		if approval.Note != "" {
+			return "The bot owner denied this tool call: " + approval.Note
+		}
+		return "The bot owner denied this tool call."
+	default:
+		return "The bot owner did not approve this tool call in time, so it was not run."
+	}
+}
+
+func toApproval(row sqlc.ToolApproval) Approval {
+	var args map[string]any
+	if len(row.Arguments) > 0 {
+		_ = json.Unmarshal(row.Arguments, &args)
+	}
+	return Approval{
+		ID:          uuidString(row.ID),
+		BotID:       uuidString(row.BotID),
+		ToolName:    row.ToolName,
+		Arguments:   args,
+		Rule:        row.Rule,
+		Platform:    row.Platform,
+		RequestedBy: uuidString(row.RequestedByChannelIdentityID),
+		Status:      row.Status,
+		DecidedBy:   uuidString(row.DecidedByUserID),
+		Note:        row.DecisionNote,
+		ExpiresAt:   db.TimeFromPg(row.ExpiresAt),
+		DecidedAt:   db.TimeFromPg(row.DecidedAt),
+		CreatedAt:   db.TimeFromPg(row.CreatedAt),
+	}
+}
+
+func uuidString(id pgtype.UUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return uuid.UUID(id.Bytes).String()
+}
@@ -0,0 +1,243 @@
+package toolapproval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/channel"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	mcpgw "github.com/memohai/memoh/internal/mcp"
+)
+
+type fakeStore struct {
+	mu        sync.Mutex
+	owner     pgtype.UUID
+	policy    []byte
+	approvals []sqlc.ToolApproval
+	created   chan sqlc.ToolApproval
+}
+
+func (f *fakeStore) GetToolApprovalPolicy(ctx context.Context, id pgtype.UUID) (sqlc.GetToolApprovalPolicyRow, error) {
+	return sqlc.GetToolApprovalPolicyRow{ID: id, OwnerUserID: f.owner, ToolApprovalPolicy: f.policy}, nil
+}
+
+func (f *fakeStore) UpdateToolApprovalPolicy(ctx context.Context, arg sqlc.UpdateToolApprovalPolicyParams) ([]byte, error) {
+	f.policy = arg.ToolApprovalPolicy
+	return f.policy, nil
+}
+
+func (f *fakeStore) CreateToolApproval(ctx context.Context, arg sqlc.CreateToolApprovalParams) (sqlc.ToolApproval, error) {
+	f.mu.Lock()
+	row := sqlc.ToolApproval{
+		ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		BotID:     arg.BotID,
+		ToolName:  arg.ToolName,
+		Arguments: arg.Arguments,
+		Rule:      arg.Rule,
+		Platform:  arg.Platform,
+		Status:    StatusPending,
+		ExpiresAt: arg.ExpiresAt,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	f.approvals = append(f.approvals, row)
+	f.mu.Unlock()
+	if f.created != nil {
+		f.created <- row
+	}
+	return row, nil
+}
+
+func (f *fakeStore) GetToolApproval(ctx context.Context, arg sqlc.GetToolApprovalParams) (sqlc.ToolApproval, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, row := range f.approvals {
+		if row.ID == arg.ID {
+			return row, nil
+		}
+	}
+	return sqlc.ToolApproval{}, pgx.ErrNoRows
+}
+
+func (f *fakeStore) ListToolApprovals(ctx context.Context, arg sqlc.ListToolApprovalsParams) ([]sqlc.ToolApproval, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []sqlc.ToolApproval
+	for _, row := range f.approvals {
+		if !arg.Status.Valid || row.Status == arg.Status.String {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) DecideToolApproval(ctx context.Context, arg sqlc.DecideToolApprovalParams) (sqlc.ToolApproval, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, row := range f.approvals {
+		if row.ID == arg.ID && row.Status == StatusPending {
+			row.Status = arg.Status
+			row.DecidedByUserID = arg.DecidedByUserID
+			row.DecisionNote = arg.DecisionNote
+			row.DecidedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			f.approvals[i] = row
+			return row, nil
+		}
+	}
+	return sqlc.ToolApproval{}, pgx.ErrNoRows
+}
+
+func (f *fakeStore) ExpireToolApprovals(ctx context.Context) error {
+	return nil
+}
+
+type recordingSender struct {
+	sent []channel.SendRequest
+}
+
+func (r *recordingSender) Send(ctx context.Context, botID string, channelType channel.ChannelType, req channel.SendRequest) error {
+	r.sent = append(r.sent, req)
+	return nil
+}
+
+const (
+	testBotID   = "00000000-0000-0000-0000-000000000001"
+	testOwnerID = "00000000-0000-0000-0000-000000000002"
+)
+
+func newTestService(t *testing.T, policy Policy) (*Service, *fakeStore, *recordingSender) {
+	t.Helper()
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fakeStore{
+		owner:   pgtype.UUID{Bytes: uuid.MustParse(testOwnerID), Valid: true},
+		policy:  raw,
+		created: make(chan sqlc.ToolApproval, 1),
+	}
+	sender := &recordingSender{}
+	svc := &Service{
+		queries: store,
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		waiters: map[string]chan Approval{},
+	}
+	svc.SetChannelSender(sender, nil)
+	return svc, store, sender
+}
+
+func TestPolicyMatch(t *testing.T) {
+	policy := Policy{Rules: []Rule{
+		{Tool: "exec", Argument: "command", Pattern: `^\s*(rm|sudo)\b`},
+		{Tool: "send_*"},
+	}}
+	cases := []struct {
+		tool string
+		args map[string]any
+		want bool
+	}{
+		{"exec", map[string]any{"command": "rm -rf /tmp/x"}, true},
+		{"exec", map[string]any{"command": "ls"}, false},
+		{"exec", map[string]any{}, false},
+		{"send_message", map[string]any{"text": "hi"}, true},
+		{"web_search", map[string]any{"query": "rm"}, false},
+	}
+	for _, c := range cases {
+		if _, got := policy.Match(c.tool, c.args); got != c.want {
+			t.Errorf("Match(%s, %v) = %v, want %v", c.tool, c.args, got, c.want)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := (Policy{Rules: []Rule{{Tool: "exec", Argument: "command", Pattern: "("}}}).Validate(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected bad pattern rejected, got %v", err)
+	}
+	if err := (Policy{Rules: []Rule{{Tool: "exec", Argument: "command"}}}).Validate(); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected argument without pattern rejected, got %v", err)
+	}
+	if err := (Policy{TimeoutSeconds: 60, Rules: []Rule{{Tool: "exec"}}}).Validate(); err != nil {
+		t.Fatalf("expected valid policy, got %v", err)
+	}
+}
+
+func TestReviewToolCall_UnmatchedRunsAtOnce(t *testing.T) {
+	svc, store, sender := newTestService(t, Policy{Rules: []Rule{{Tool: "exec"}}})
+	refusal, err := svc.ReviewToolCall(context.Background(), mcpgw.ToolSessionContext{BotID: testBotID}, "web_search", nil)
+	if err != nil || refusal != "" {
+		t.Fatalf("expected unmatched call allowed, got %q %v", refusal, err)
+	}
+	if len(store.approvals) != 0 || len(sender.sent) != 0 {
+		t.Fatal("expected no approval for unmatched call")
+	}
+}
+
+func TestReviewToolCall_WaitsForDecision(t *testing.T) {
+	for _, approve := range []bool{true, false} {
+		svc, store, sender := newTestService(t, Policy{Rules: []Rule{{Tool: "exec"}}})
+		session := mcpgw.ToolSessionContext{BotID: testBotID, CurrentPlatform: "telegram"}
+
+		done := make(chan string, 1)
+		go func() {
+			refusal, err := svc.ReviewToolCall(context.Background(), session, "exec", map[string]any{"command": "rm -rf /"})
+			if err != nil {
+				refusal = "error: " + err.Error()
+			}
+			done <- refusal
+		}()
+		row := <-store.created
+		pending, err := svc.FindPending(context.Background(), testBotID, uuid.UUID(row.ID.Bytes).String()[:8])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.Decide(context.Background(), testBotID, pending.ID, approve, testOwnerID, "not now"); err != nil {
+			t.Fatal(err)
+		}
+
+		var refusal string
+		select {
+		case refusal = <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for the held call")
+		}
+		if approve && refusal != "" {
+			t.Fatalf("expected approved call to run, got %q", refusal)
+		}
+		if !approve && !strings.Contains(refusal, "not now") {
+			t.Fatalf("expected denial with reason, got %q", refusal)
+		}
+		if len(sender.sent) != 1 || sender.sent[0].ChannelIdentityID != testOwnerID {
+			t.Fatalf("expected owner notified once, got %+v", sender.sent)
+		}
+		if !strings.Contains(sender.sent[0].Message.Text, "/approve "+pending.Ref()) {
+			t.Fatalf("expected approve command in notice, got %q", sender.sent[0].Message.Text)
+		}
+		if _, err := svc.Decide(context.Background(), testBotID, pending.ID, true, testOwnerID, ""); !errors.Is(err, ErrAlreadyDecided) {
+			t.Fatalf("expected second decision rejected, got %v", err)
+		}
+	}
+}
+
+func TestReviewToolCall_ExpiresAfterTimeout(t *testing.T) {
+	svc, store, _ := newTestService(t, Policy{TimeoutSeconds: 1, Rules: []Rule{{Tool: "exec"}}})
+	refusal, err := svc.ReviewToolCall(context.Background(), mcpgw.ToolSessionContext{BotID: testBotID}, "exec", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(refusal, "in time") {
+		t.Fatalf("expected timeout refusal, got %q", refusal)
+	}
+	if store.approvals[0].Status != StatusExpired {
+		t.Fatalf("expected approval recorded as expired, got %q", store.approvals[0].Status)
+	}
+}
@@ -0,0 +1,229 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/memohai/memoh/internal/channel/route"
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/media"
+	"github.com/memohai/memoh/internal/models"
+	"github.com/memohai/memoh/internal/settings"
+)
+
+// RouteVoiceReplyKey is the route metadata key overriding the bot-level
+// voice reply setting for a single conversation route.
+const RouteVoiceReplyKey = "voice_reply"
+
+var ErrNotConfigured = errors.New("text-to-speech is not configured for this bot")
+
+type settingsReader interface {
+	GetBot(ctx context.Context, botID string) (settings.Settings, error)
+}
+
+type routeStore interface {
+	GetByID(ctx context.Context, routeID string) (route.Route, error)
+	UpdateMetadata(ctx context.Context, routeID string, metadata map[string]any) error
+}
+
+type assetIngestor interface {
+	Ingest(ctx context.Context, input media.IngestInput) (media.Asset, error)
+}
+
+// Service synthesizes assistant replies into voice notes.
+type Service struct {
+	queries  *sqlc.Queries
+	settings settingsReader
+	routes   routeStore
+	media    assetIngestor
+	logger   *slog.Logger
+	timeout  time.Duration
+
+	resolveSpeaker func(ctx context.Context, modelID string) (Speaker, error)
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries, settingsService *settings.Service, routeService route.Service, mediaService *media.Service) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	s := &Service{
+		queries: queries,
+		routes:  routeService,
+		logger:  log.With(slog.String("service", "tts")),
+		timeout: 60 * time.Second,
+	}
+	// Avoid storing typed-nil pointers in the interfaces.
+	if settingsService != nil {
+		s.settings = settingsService
+	}
+	if mediaService != nil {
+		s.media = mediaService
+	}
+	s.resolveSpeaker = s.resolveOpenAISpeaker
+	return s
+}
+
+// VoiceReplyEnabled reports whether replies on the given route should be
+// spoken. A route-level override wins over the bot setting.
+func (s *Service) VoiceReplyEnabled(ctx context.Context, botID, routeID string) bool {
+	if s == nil || s.settings == nil {
+		return false
+	}
+	botSettings, err := s.settings.GetBot(ctx, botID)
+	if err != nil {
+		return false
+	}
+	if strings.TrimSpace(botSettings.TTSModelID) == "" {
+		return false
+	}
+	if override, ok := s.routeOverride(ctx, routeID); ok {
+		return override
+	}
+	return botSettings.TTSEnabled
+}
+
+// RouteOverride returns the route-level voice reply override, if any.
+func (s *Service) RouteOverride(ctx context.Context, routeID string) (bool, bool) {
+	if s == nil {
+		return false, false
+	}
+	return s.routeOverride(ctx, routeID)
+}
+
+// SetRouteOverride stores or clears (enabled == nil) the route-level override.
+func (s *Service) SetRouteOverride(ctx context.Context, routeID string, enabled *bool) error {
+	if s == nil || s.routes == nil {
+		return fmt.Errorf("route service not configured")
+	}
+	current, err := s.routes.GetByID(ctx, routeID)
+	if err != nil {
+		return err
+	}
+	metadata := make(map[string]any, len(current.Metadata)+1)
+	for k, v := range current.Metadata {
+		metadata[k] = v
+	}
+	if enabled == nil {
+		delete(metadata, RouteVoiceReplyKey)
+	} else {
+		metadata[RouteVoiceReplyKey] = *enabled
+	}
+	return s.routes.UpdateMetadata(ctx, routeID, metadata)
+}
+
+func (s *Service) routeOverride(ctx context.Context, routeID string) (bool, bool) {
+	routeID = strings.TrimSpace(routeID)
+	if routeID == "" || s.routes == nil {
+		return false, false
+	}
+	r, err := s.routes.GetByID(ctx, routeID)
+	if err != nil {
+		return false, false
+	}
+	raw, ok := r.Metadata[RouteVoiceReplyKey]
+	if !ok {
+		return false, false
+	}
+	switch value := raw.(type) {
+	case bool:
+		return value, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "1", "true", "yes", "on":
+			return true, true
+		case "0", "false", "no", "off":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// Synthesize speaks text with the bot's speech model and stores the audio
+// as a media asset owned by the bot.
+func (s *Service) Synthesize(ctx context.Context, botID, text string) (media.Asset, error) {
+	if s == nil || s.settings == nil || s.media == nil {
+		return media.Asset{}, fmt.Errorf("tts service not configured")
+	}
+	input := SpeechText(text)
+	if input == "" {
+		return media.Asset{}, fmt.Errorf("nothing to speak")
+	}
+	botSettings, err := s.settings.GetBot(ctx, botID)
+	if err != nil {
+		return media.Asset{}, err
+	}
+	modelID := strings.TrimSpace(botSettings.TTSModelID)
+	if modelID == "" {
+		return media.Asset{}, ErrNotConfigured
+	}
+	speaker, err := s.resolveSpeaker(ctx, modelID)
+	if err != nil {
+		return media.Asset{}, err
+	}
+	audio, err := speaker.Speak(ctx, input, botSettings.TTSVoice)
+	if err != nil {
+		return media.Asset{}, fmt.Errorf("synthesize speech: %w", err)
+	}
+	asset, err := s.media.Ingest(ctx, media.IngestInput{
+		BotID:       botID,
+		Mime:        audio.Mime,
+		Reader:      bytes.NewReader(audio.Data),
+		MaxBytes:    maxSpeechBytes,
+		OriginalExt: audio.Ext,
+	})
+	if err != nil {
+		return media.Asset{}, fmt.Errorf("ingest speech: %w", err)
+	}
+	return asset, nil
+}
+
+func (s *Service) resolveOpenAISpeaker(ctx context.Context, modelID string) (Speaker, error) {
+	if s.queries == nil {
+		return nil, fmt.Errorf("tts queries not configured")
+	}
+	pgID, err := db.ParseUUID(modelID)
+	if err != nil {
+		return nil, err
+	}
+	dbModel, err := s.queries.GetModelByID(ctx, pgID)
+	if err != nil {
+		return nil, fmt.Errorf("load speech model: %w", err)
+	}
+	if models.ModelType(dbModel.Type) != models.ModelTypeSpeech {
+		return nil, fmt.Errorf("model %s is not a speech model", dbModel.ModelID)
+	}
+	provider, err := s.queries.GetLlmProviderByID(ctx, dbModel.LlmProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("load speech provider: %w", err)
+	}
+	return NewOpenAISpeaker(s.logger, provider.ApiKey, provider.BaseUrl, dbModel.ModelID, s.timeout)
+}
+
+var (
+	codeFencePattern  = regexp.MustCompile("(?s)```.*?```")
+	markdownLinkRegex = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	markdownMarkRegex = regexp.MustCompile("(?m)^[ \\t]{0,3}(#{1,6}[ \\t]+|>[ \\t]?|[-*+][ \\t]+)|[*_`~]+")
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// SpeechText converts a markdown reply into plain text suitable for speech.
+// Code blocks are dropped and the result is capped at the provider limit.
+func SpeechText(text string) string {
+	text = codeFencePattern.ReplaceAllString(text, "")
+	text = markdownLinkRegex.ReplaceAllString(text, "$1")
+	text = markdownMarkRegex.ReplaceAllString(text, "")
+	text = blankLinesPattern.ReplaceAllString(text, "\n\n")
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	if len(runes) > maxSpeechInputRunes {
+		text = strings.TrimSpace(string(runes[:maxSpeechInputRunes]))
+	}
+	return text
+}
@@ -0,0 +1,199 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/memohai/memoh/internal/channel/route"
+	"github.com/memohai/memoh/internal/media"
+	"github.com/memohai/memoh/internal/settings"
+)
+
+type fakeSettings struct {
+	settings settings.Settings
+	err      error
+}
+
+func (f *fakeSettings) GetBot(context.Context, string) (settings.Settings, error) {
+	return f.settings, f.err
+}
+
+type fakeRoutes struct {
+	routes  map[string]route.Route
+	updated map[string]any
+}
+
+func (f *fakeRoutes) GetByID(_ context.Context, routeID string) (route.Route, error) {
+	r, ok := f.routes[routeID]
+	if !ok {
+		return route.Route{}, errors.New("not found")
+	}
+	return r, nil
+}
+
+func (f *fakeRoutes) UpdateMetadata(_ context.Context, _ string, metadata map[string]any) error {
+	f.updated = metadata
+	return nil
+}
+
+type fakeIngestor struct {
+	input media.IngestInput
+	data  []byte
+}
+
+func (f *fakeIngestor) Ingest(_ context.Context, input media.IngestInput) (media.Asset, error) {
+	f.input = input
+	data, err := io.ReadAll(input.Reader)
+	if err != nil {
+		return media.Asset{}, err
+	}
+	f.data = data
+	return media.Asset{ContentHash: "hash", BotID: input.BotID, Mime: input.Mime, SizeBytes: int64(len(data)), StorageKey: "bot/hash.ogg"}, nil
+}
+
+type fakeSpeaker struct {
+	input string
+	voice string
+}
+
+func (f *fakeSpeaker) Speak(_ context.Context, input, voice string) (Audio, error) {
+	f.input = input
+	f.voice = voice
+	return Audio{Data: []byte("OggS"), Mime: "audio/ogg", Ext: ".ogg"}, nil
+}
+
+func TestVoiceReplyEnabled(t *testing.T) {
+	routes := &fakeRoutes{routes: map[string]route.Route{
+		"plain": {ID: "plain"},
+		"on":    {ID: "on", Metadata: map[string]any{RouteVoiceReplyKey: true}},
+		"off":   {ID: "off", Metadata: map[string]any{RouteVoiceReplyKey: "false"}},
+	}}
+	tests := []struct {
+		name     string
+		settings settings.Settings
+		routeID  string
+		want     bool
+	}{
+		{name: "bot enabled", settings: settings.Settings{TTSEnabled: true, TTSModelID: "m"}, routeID: "plain", want: true},
+		{name: "bot disabled", settings: settings.Settings{TTSModelID: "m"}, routeID: "plain", want: false},
+		{name: "route enables", settings: settings.Settings{TTSModelID: "m"}, routeID: "on", want: true},
+		{name: "route disables", settings: settings.Settings{TTSEnabled: true, TTSModelID: "m"}, routeID: "off", want: false},
+		{name: "no speech model", settings: settings.Settings{TTSEnabled: true}, routeID: "on", want: false},
+		{name: "unknown route", settings: settings.Settings{TTSEnabled: true, TTSModelID: "m"}, routeID: "missing", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &Service{settings: &fakeSettings{settings: tt.settings}, routes: routes}
+			if got := svc.VoiceReplyEnabled(context.Background(), "bot-1", tt.routeID); got != tt.want {
+				t.Fatalf("VoiceReplyEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRouteOverride(t *testing.T) {
+	routes := &fakeRoutes{routes: map[string]route.Route{
+		"r1": {ID: "r1", Metadata: map[string]any{"sender_id": "u1", RouteVoiceReplyKey: true}},
+	}}
+	svc := &Service{routes: routes}
+	disabled := false
+	if err := svc.SetRouteOverride(context.Background(), "r1", &disabled); err != nil {
+		t.Fatalf("SetRouteOverride() error = %v", err)
+	}
+	if routes.updated[RouteVoiceReplyKey] != false || routes.updated["sender_id"] != "u1" {
+		t.Fatalf("unexpected metadata: %#v", routes.updated)
+	}
+	if err := svc.SetRouteOverride(context.Background(), "r1", nil); err != nil {
+		t.Fatalf("SetRouteOverride(nil) error = %v", err)
+	}
+	if _, ok := routes.updated[RouteVoiceReplyKey]; ok {
+		t.Fatalf("expected override to be cleared: %#v", routes.updated)
+	}
+}
+
+func TestSynthesizeIngestsAudio(t *testing.T) {
+	speaker := &fakeSpeaker{}
+	ingestor := &fakeIngestor{}
+	svc := &Service{
+		settings: &fakeSettings{settings: settings.Settings{TTSModelID: "model-1", TTSVoice: "nova"}},
+		media:    ingestor,
+		resolveSpeaker: func(_ context.Context, modelID string) (Speaker, error) {
+			if modelID != "model-1" {
+				t.Fatalf("unexpected model id %q", modelID)
+			}
+			return speaker, nil
+		},
+	}
+	asset, err := svc.Synthesize(context.Background(), "bot-1", "**Hello** [world](https://example.com)")
+	if err != nil {
+		t.Fatalf("Synthesize() error = %v", err)
+	}
+	if speaker.input != "Hello world" || speaker.voice != "nova" {
+		t.Fatalf("unexpected speaker call: %q / %q", speaker.input, speaker.voice)
+	}
+	if ingestor.input.BotID != "bot-1" || ingestor.input.Mime != "audio/ogg" || string(ingestor.data) != "OggS" {
+		t.Fatalf("unexpected ingest input: %#v", ingestor.input)
+	}
+	if asset.ContentHash != "hash" {
+		t.Fatalf("unexpected asset: %#v", asset)
+	}
+}
+
+func TestSynthesizeRequiresModel(t *testing.T) {
+	svc := &Service{settings: &fakeSettings{}, media: &fakeIngestor{}}
+	if _, err := svc.Synthesize(context.Background(), "bot-1", "hi"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("expected ErrNotConfigured, got %v", err)
+	}
+}
+
+func TestSpeechText(t *testing.T) {
+	in := "# Title\n\nSome *bold* text.\n\n```go\nfmt.Println(1)\n```\n\n- item one\n> quoted"
+	got := SpeechText(in)
+	want := "Title\n\nSome bold text.\n\nitem one\nquoted"
+	if got != want {
+		t.Fatalf("SpeechText() = %q, want %q", got, want)
+	}
+	long := strings.Repeat("语", maxSpeechInputRunes+10)
+	if n := len([]rune(SpeechText(long))); n != maxSpeechInputRunes {
+		t.Fatalf("expected truncation to %d runes, got %d", maxSpeechInputRunes, n)
+	}
+}
+
+func TestOpenAISpeakerSpeak(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected auth header %q", r.Header.Get("Authorization"))
+		}
+		var body openAISpeechRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if body.Model != "tts-1" || body.Voice != DefaultVoice || body.ResponseFormat != "opus" || body.Input != "hi" {
+			t.Errorf("unexpected body: %#v", body)
+		}
+		w.Header().Set("Content-Type", "audio/ogg")
+		_, _ = w.Write([]byte("OggS-data"))
+	}))
+	defer srv.Close()
+
+	speaker, err := NewOpenAISpeaker(nil, "key", srv.URL+"/v1/", "tts-1", 0)
+	if err != nil {
+		t.Fatalf("NewOpenAISpeaker() error = %v", err)
+	}
+	audio, err := speaker.Speak(context.Background(), " hi ", "")
+	if err != nil {
+		t.Fatalf("Speak() error = %v", err)
+	}
+	if string(audio.Data) != "OggS-data" || audio.Mime != "audio/ogg" {
+		t.Fatalf("unexpected audio: %#v", audio)
+	}
+}
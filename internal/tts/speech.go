@@ -0,0 +1,126 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultVoice is used when the bot has no voice configured.
+	DefaultVoice = "alloy"
+	// maxSpeechInputRunes mirrors the OpenAI /audio/speech input limit.
+	maxSpeechInputRunes = 4096
+	// maxSpeechBytes bounds the audio payload read from the provider.
+	maxSpeechBytes = 25 * 1024 * 1024
+)
+
+// Speaker turns text into encoded audio.
+type Speaker interface {
+	Speak(ctx context.Context, input, voice string) (Audio, error)
+}
+
+// Audio is a synthesized speech payload.
+type Audio struct {
+	Data []byte
+	Mime string
+	Ext  string
+}
+
+// OpenAISpeaker calls an OpenAI-compatible /audio/speech endpoint.
+type OpenAISpeaker struct {
+	apiKey  string
+	baseURL string
+	model   string
+	logger  *slog.Logger
+	http    *http.Client
+}
+
+type openAISpeechRequest struct {
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format"`
+}
+
+func NewOpenAISpeaker(log *slog.Logger, apiKey, baseURL, model string, timeout time.Duration) (*OpenAISpeaker, error) {
+	if strings.TrimSpace(baseURL) == "" {
+		return nil, fmt.Errorf("openai speaker: base url is required")
+	}
+	if strings.TrimSpace(apiKey) == "" {
+		return nil, fmt.Errorf("openai speaker: api key is required")
+	}
+	if strings.TrimSpace(model) == "" {
+		return nil, fmt.Errorf("openai speaker: model is required")
+	}
+	if log == nil {
+		log = slog.Default()
+	}
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &OpenAISpeaker{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		logger:  log.With(slog.String("speaker", "openai")),
+		http: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// Speak requests Opus-in-Ogg audio, which every voice-note platform accepts.
+func (s *OpenAISpeaker) Speak(ctx context.Context, input, voice string) (Audio, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return Audio{}, fmt.Errorf("speech input is required")
+	}
+	voice = strings.TrimSpace(voice)
+	if voice == "" {
+		voice = DefaultVoice
+	}
+	body, err := json.Marshal(openAISpeechRequest{
+		Model:          s.model,
+		Input:          input,
+		Voice:          voice,
+		ResponseFormat: "opus",
+	})
+	if err != nil {
+		return Audio{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/audio/speech", bytes.NewReader(body))
+	if err != nil {
+		return Audio{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return Audio{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return Audio{}, fmt.Errorf("speech error: %s", strings.TrimSpace(string(b)))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSpeechBytes+1))
+	if err != nil {
+		return Audio{}, err
+	}
+	if len(data) == 0 {
+		return Audio{}, fmt.Errorf("speech response is empty")
+	}
+	if len(data) > maxSpeechBytes {
+		return Audio{}, fmt.Errorf("speech response exceeds %d bytes", maxSpeechBytes)
+	}
+	return Audio{Data: data, Mime: "audio/ogg", Ext: ".ogg"}, nil
+}
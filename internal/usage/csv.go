@@ -0,0 +1,57 @@
+package usage
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes the report rows with one column per grouped dimension
+// followed by the token counts and cost.
+func WriteCSV(w io.Writer, report Report) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, 0, len(report.GroupBy)+7)
+	for _, d := range report.GroupBy {
+		header = append(header, string(d))
+	}
+	header = append(header, "messages", "input_tokens", "output_tokens", "cached_input_tokens", "reasoning_tokens", "cost", "currency")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		record := make([]string, 0, len(header))
+		for _, d := range report.GroupBy {
+			record = append(record, row.value(d))
+		}
+		record = append(record,
+			strconv.FormatInt(row.Messages, 10),
+			strconv.FormatInt(row.InputTokens, 10),
+			strconv.FormatInt(row.OutputTokens, 10),
+			strconv.FormatInt(row.CachedInputTokens, 10),
+			strconv.FormatInt(row.ReasoningTokens, 10),
+			strconv.FormatFloat(row.Cost, 'f', 6, 64),
+			row.Currency,
+		)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r Row) value(d Dimension) string {
+	switch d {
+	case DimensionDay:
+		return r.Day
+	case DimensionBot:
+		return r.BotID
+	case DimensionUser:
+		return r.UserID
+	case DimensionModel:
+		return r.Model
+	case DimensionChannel:
+		return r.Channel
+	}
+	return ""
+}
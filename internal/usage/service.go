@@ -0,0 +1,255 @@
+// Package usage turns the token usage stored on history messages into cost
+// reports.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db"
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/models"
+)
+
+// Dimension is a field a report can be grouped by.
+type Dimension string
+
+const (
+	DimensionBot     Dimension = "bot"
+	DimensionUser    Dimension = "user"
+	DimensionModel   Dimension = "model"
+	DimensionChannel Dimension = "channel"
+	DimensionDay     Dimension = "day"
+)
+
+var allDimensions = []Dimension{DimensionDay, DimensionBot, DimensionUser, DimensionModel, DimensionChannel}
+
+var ErrInvalidQuery = errors.New("invalid usage report query")
+
+// ParseDimensions reads a comma-separated group_by value. Empty groups by
+// model.
+func ParseDimensions(raw string) ([]Dimension, error) {
+	var out []Dimension
+	seen := map[Dimension]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		d := Dimension(strings.ToLower(strings.TrimSpace(part)))
+		if d == "" || seen[d] {
+			continue
+		}
+		valid := false
+		for _, known := range allDimensions {
+			if d == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("%w: unknown group_by %q", ErrInvalidQuery, d)
+		}
+		seen[d] = true
+		out = append(out, d)
+	}
+	if len(out) == 0 {
+		out = []Dimension{DimensionModel}
+	}
+	return out, nil
+}
+
+type Query struct {
+	// BotID limits the report to one bot; empty covers all bots.
+	BotID   string
+	From    time.Time
+	To      time.Time
+	GroupBy []Dimension
+}
+
+// Row is the usage and cost of one group. Fields of dimensions the report
+// is not grouped by are empty. Usage of models without a price is reported
+// in rows with an empty currency and zero cost.
+type Row struct {
+	Day               string  `json:"day,omitempty"`
+	BotID             string  `json:"bot_id,omitempty"`
+	UserID            string  `json:"user_id,omitempty"`
+	Model             string  `json:"model,omitempty"`
+	Channel           string  `json:"channel,omitempty"`
+	Messages          int64   `json:"messages"`
+	InputTokens       int64   `json:"input_tokens"`
+	OutputTokens      int64   `json:"output_tokens"`
+	CachedInputTokens int64   `json:"cached_input_tokens"`
+	ReasoningTokens   int64   `json:"reasoning_tokens"`
+	Cost              float64 `json:"cost"`
+	Currency          string  `json:"currency,omitempty"`
+}
+
+type Report struct {
+	BotID   string      `json:"bot_id,omitempty"`
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
+	GroupBy []Dimension `json:"group_by"`
+	Rows    []Row       `json:"rows"`
+	// TotalCost sums the cost per currency.
+	TotalCost map[string]float64 `json:"total_cost"`
+	// UnpricedTokens counts tokens of models without a price.
+	UnpricedTokens int64 `json:"unpriced_tokens"`
+}
+
+type store interface {
+	ListUsageBuckets(ctx context.Context, arg sqlc.ListUsageBucketsParams) ([]sqlc.ListUsageBucketsRow, error)
+}
+
+type priceLister interface {
+	ListPrices(ctx context.Context) ([]models.Price, error)
+}
+
+type Service struct {
+	queries store
+	prices  priceLister
+	logger  *slog.Logger
+}
+
+func NewService(log *slog.Logger, queries *sqlc.Queries, modelsService *models.Service) *Service {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &Service{
+		queries: queries,
+		prices:  modelsService,
+		logger:  log.With(slog.String("service", "usage")),
+	}
+}
+
+// Report aggregates usage over [From, To) and prices it per model.
+func (s *Service) Report(ctx context.Context, q Query) (Report, error) {
+	if !q.To.After(q.From) {
+		return Report{}, fmt.Errorf("%w: report range end must be after its start", ErrInvalidQuery)
+	}
+	if len(q.GroupBy) == 0 {
+		q.GroupBy = []Dimension{DimensionModel}
+	}
+	var pgBotID pgtype.UUID
+	if strings.TrimSpace(q.BotID) != "" {
+		id, err := db.ParseUUID(q.BotID)
+		if err != nil {
+			return Report{}, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+		pgBotID = id
+	}
+	buckets, err := s.queries.ListUsageBuckets(ctx, sqlc.ListUsageBucketsParams{
+		Since: pgtype.Timestamptz{Time: q.From, Valid: true},
+		Until: pgtype.Timestamptz{Time: q.To, Valid: true},
+		BotID: pgBotID,
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("list usage: %w", err)
+	}
+	prices, err := s.prices.ListPrices(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	return aggregate(q, buckets, newPriceIndex(prices)), nil
+}
+
+// priceIndex finds the price of the model that answered a reply.
+type priceIndex struct {
+	byUUID map[string]models.Price
+	// byModelID serves replies stored before their metadata carried the
+	// model_uuid. When several providers serve the same model_id, the first
+	// listed price wins.
+	byModelID map[string]models.Price
+}
+
+func newPriceIndex(prices []models.Price) priceIndex {
+	idx := priceIndex{
+		byUUID:    make(map[string]models.Price, len(prices)),
+		byModelID: make(map[string]models.Price, len(prices)),
+	}
+	for _, p := range prices {
+		idx.byUUID[p.ModelUUID] = p
+		if _, ok := idx.byModelID[p.ModelID]; !ok {
+			idx.byModelID[p.ModelID] = p
+		}
+	}
+	return idx
+}
+
+func (idx priceIndex) lookup(b sqlc.ListUsageBucketsRow) (models.Price, bool) {
+	if b.ModelUuid != "" {
+		p, ok := idx.byUUID[b.ModelUuid]
+		return p, ok
+	}
+	p, ok := idx.byModelID[b.ModelID]
+	return p, ok
+}
+
+func aggregate(q Query, buckets []sqlc.ListUsageBucketsRow, prices priceIndex) Report {
+	report := Report{
+		BotID:     strings.TrimSpace(q.BotID),
+		From:      q.From,
+		To:        q.To,
+		GroupBy:   q.GroupBy,
+		Rows:      []Row{},
+		TotalCost: map[string]float64{},
+	}
+	groups := map[Row]*Row{}
+	var order []Row
+	for _, b := range buckets {
+		price, priced := prices.lookup(b)
+		var key Row
+		for _, d := range q.GroupBy {
+			switch d {
+			case DimensionDay:
+				key.Day = b.Day
+			case DimensionBot:
+				key.BotID = b.BotID.String()
+			case DimensionUser:
+				key.UserID = b.UserID
+			case DimensionModel:
+				key.Model = b.ModelID
+			case DimensionChannel:
+				key.Channel = b.Channel
+			}
+		}
+		if priced {
+			key.Currency = price.Currency
+		}
+		row, ok := groups[key]
+		if !ok {
+			row = new(Row)
+			*row = key
+			groups[key] = row
+			order = append(order, key)
+		}
+		row.Messages += b.Messages
+		row.InputTokens += b.InputTokens
+		row.OutputTokens += b.OutputTokens
+		row.CachedInputTokens += b.CachedInputTokens
+		row.ReasoningTokens += b.ReasoningTokens
+		if priced {
+			cost := price.Cost(b.InputTokens, b.OutputTokens, b.CachedInputTokens, b.ReasoningTokens)
+			row.Cost += cost
+			report.TotalCost[price.Currency] += cost
+		} else {
+			report.UnpricedTokens += b.InputTokens + b.OutputTokens
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		for _, pair := range [][2]string{{a.Day, b.Day}, {a.BotID, b.BotID}, {a.UserID, b.UserID}, {a.Model, b.Model}, {a.Channel, b.Channel}, {a.Currency, b.Currency}} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+		return false
+	})
+	for _, key := range order {
+		report.Rows = append(report.Rows, *groups[key])
+	}
+	return report
+}
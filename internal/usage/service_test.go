@@ -0,0 +1,140 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/memohai/memoh/internal/db/sqlc"
+	"github.com/memohai/memoh/internal/models"
+)
+
+type fakeStore struct {
+	rows []sqlc.ListUsageBucketsRow
+}
+
+func (f *fakeStore) ListUsageBuckets(ctx context.Context, arg sqlc.ListUsageBucketsParams) ([]sqlc.ListUsageBucketsRow, error) {
+	return f.rows, nil
+}
+
+type fakePrices []models.Price
+
+func (f fakePrices) ListPrices(ctx context.Context) ([]models.Price, error) {
+	return f, nil
+}
+
+func TestReport_GroupsAndPrices(t *testing.T) {
+	bot := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	cached := 0.5
+	svc := &Service{
+		queries: &fakeStore{rows: []sqlc.ListUsageBucketsRow{
+			{Day: "2025-03-01", BotID: bot, UserID: "u1", ModelID: "gpt-4o", Channel: "telegram", Messages: 2, InputTokens: 1_000_000, CachedInputTokens: 500_000, OutputTokens: 100_000},
+			{Day: "2025-03-02", BotID: bot, UserID: "u2", ModelID: "gpt-4o", Channel: "feishu", Messages: 1, InputTokens: 1_000_000},
+			{Day: "2025-03-02", BotID: bot, UserID: "u1", ModelID: "local-llama", Channel: "telegram", Messages: 1, InputTokens: 300, OutputTokens: 200},
+		}},
+		prices: fakePrices{{ModelID: "gpt-4o", Currency: "USD", InputPerMillion: 2, OutputPerMillion: 10, CachedInputPerMillion: &cached}},
+	}
+
+	report, err := svc.Report(context.Background(), Query{
+		From:    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+		GroupBy: []Dimension{DimensionModel},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rows) != 2 {
+		t.Fatalf("expected one row per model, got %+v", report.Rows)
+	}
+	gpt := report.Rows[0]
+	// 0.5M uncached * 2 + 0.5M cached * 0.5 + 0.1M output * 10 + 1M * 2
+	if gpt.Model != "gpt-4o" || gpt.Messages != 3 || math.Abs(gpt.Cost-4.25) > 1e-9 || gpt.Currency != "USD" {
+		t.Fatalf("unexpected priced row %+v", gpt)
+	}
+	if llama := report.Rows[1]; llama.Currency != "" || llama.Cost != 0 {
+		t.Fatalf("expected unpriced row without cost, got %+v", llama)
+	}
+	if report.UnpricedTokens != 500 || math.Abs(report.TotalCost["USD"]-4.25) > 1e-9 {
+		t.Fatalf("unexpected totals %+v %d", report.TotalCost, report.UnpricedTokens)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "model,messages,input_tokens,output_tokens,cached_input_tokens,reasoning_tokens,cost,currency" {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+	if lines[1] != "gpt-4o,3,2000000,100000,500000,0,4.250000,USD" {
+		t.Fatalf("unexpected csv row %q", lines[1])
+	}
+}
+
+func TestReport_GroupsByDayAndUser(t *testing.T) {
+	bot := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	svc := &Service{
+		queries: &fakeStore{rows: []sqlc.ListUsageBucketsRow{
+			{Day: "2025-03-01", BotID: bot, UserID: "u1", ModelID: "m", Channel: "telegram", Messages: 1, InputTokens: 10},
+			{Day: "2025-03-01", BotID: bot, UserID: "u1", ModelID: "m", Channel: "feishu", Messages: 1, InputTokens: 5},
+			{Day: "2025-03-01", BotID: bot, UserID: "u2", ModelID: "m", Channel: "telegram", Messages: 1, InputTokens: 1},
+		}},
+		prices: fakePrices{},
+	}
+	report, err := svc.Report(context.Background(), Query{
+		From:    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+		GroupBy: []Dimension{DimensionDay, DimensionUser},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Rows) != 2 || report.Rows[0].UserID != "u1" || report.Rows[0].InputTokens != 15 || report.Rows[0].Channel != "" {
+		t.Fatalf("unexpected rows %+v", report.Rows)
+	}
+}
+
+func TestParseDimensions(t *testing.T) {
+	got, err := ParseDimensions("bot, Day,bot")
+	if err != nil || len(got) != 2 || got[0] != DimensionBot || got[1] != DimensionDay {
+		t.Fatalf("unexpected dimensions %v %v", got, err)
+	}
+	if _, err := ParseDimensions("provider"); err == nil {
+		t.Fatal("expected unknown dimension rejected")
+	}
+}
+
+func TestReport_PricesByModelUUID(t *testing.T) {
+	bot := pgtype.UUID{Bytes: uuid.New(), Valid: true}
+	svc := &Service{
+		queries: &fakeStore{rows: []sqlc.ListUsageBucketsRow{
+			{Day: "2025-03-01", BotID: bot, ModelID: "gpt-4o", ModelUuid: "azure-gpt", Messages: 1, InputTokens: 1_000_000},
+			{Day: "2025-03-01", BotID: bot, ModelID: "gpt-4o", ModelUuid: "openai-gpt", Messages: 1, InputTokens: 1_000_000},
+			{Day: "2025-03-01", BotID: bot, ModelID: "gpt-4o", Messages: 1, InputTokens: 1_000_000},
+		}},
+		prices: fakePrices{
+			{ModelUUID: "openai-gpt", ModelID: "gpt-4o", Currency: "USD", InputPerMillion: 2},
+			{ModelUUID: "azure-gpt", ModelID: "gpt-4o", Currency: "EUR", InputPerMillion: 3},
+		},
+	}
+
+	report, err := svc.Report(context.Background(), Query{
+		From:    time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+		GroupBy: []Dimension{DimensionModel},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replies stored without a model_uuid fall back to the first price of
+	// their model_id.
+	if math.Abs(report.TotalCost["EUR"]-3) > 1e-9 || math.Abs(report.TotalCost["USD"]-4) > 1e-9 {
+		t.Fatalf("expected each provider's own price, got %+v", report.TotalCost)
+	}
+}
@@ -52,6 +52,11 @@ const buildProviderOptions = (config: ModelConfig): Record<string, Record<string
   }
 }
 
+const buildSamplingOptions = (config: ModelConfig): { temperature?: number; topP?: number } => ({
+  ...(config.temperature !== undefined && { temperature: config.temperature }),
+  ...(config.topP !== undefined && { topP: config.topP }),
+})
+
 const buildStepUsages = (
   steps: { usage: LanguageModelUsage; response: { messages: unknown[] } }[],
 ): (LanguageModelUsage | null)[] => {
@@ -92,12 +97,14 @@ export const createAgent = (
     },
     auth,
     inbox = [],
+    instructions = '',
   }: AgentParams,
   fetch: AuthFetcher,
 ) => {
   const model = createModel(modelConfig)
   // eslint-disable-next-line @typescript-eslint/no-explicit-any
   const providerOptions = buildProviderOptions(modelConfig) as any
+  const samplingOptions = buildSamplingOptions(modelConfig)
   const enabledSkills: AgentSkill[] = []
   const fs = createFS({ fetch, botId: identity.botId })
 
@@ -139,6 +146,7 @@ export const createAgent = (
       soulContent,
       toolsContent,
       inbox,
+      instructions,
     })
   }
 
@@ -203,6 +211,7 @@ export const createAgent = (
       messages,
       system: systemPrompt,
       ...(providerOptions && { providerOptions }),
+      ...samplingOptions,
       stopWhen: stepCountIs(Infinity),
       prepareStep: () => {
         return {
@@ -261,6 +270,7 @@ export const createAgent = (
       messages,
       system: generateSubagentSystemPrompt(),
       ...(providerOptions && { providerOptions }),
+      ...samplingOptions,
       stopWhen: stepCountIs(Infinity),
       prepareStep: () => {
         return {
@@ -305,6 +315,7 @@ export const createAgent = (
       messages,
       system: await generateSystemPrompt(),
       ...(providerOptions && { providerOptions }),
+      ...samplingOptions,
       stopWhen: stepCountIs(Infinity),
       onFinish: async () => {
         await close()
@@ -368,6 +379,7 @@ export const createAgent = (
         messages,
         system: systemPrompt,
         ...(providerOptions && { providerOptions }),
+        ...samplingOptions,
         stopWhen: stepCountIs(Infinity),
         prepareStep: () => {
           return {
@@ -389,9 +401,8 @@ export const createAgent = (
       }
       for await (const chunk of fullStream) {
         if (chunk.type === 'error') {
-          throw new Error(
-            resolveStreamErrorMessage((chunk as { error?: unknown }).error),
-          )
+          const cause = (chunk as { error?: unknown }).error
+          throw new Error(resolveStreamErrorMessage(cause), { cause })
         }
         switch (chunk.type) {
           case 'reasoning-start':
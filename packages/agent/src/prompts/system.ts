@@ -16,6 +16,7 @@ export interface SystemParams {
   toolsContent?: string
   attachments?: string[]
   inbox?: InboxItem[]
+  instructions?: string
 }
 
 export const skillPrompt = (skill: AgentSkill) => {
@@ -42,6 +43,17 @@ Use ${quote('search_inbox')} to find older messages by keyword.
 `.trim()
 }
 
+const formatInstructions = (instructions: string): string => {
+  if (!instructions.trim()) return ''
+  return `
+## Conversation Instructions
+
+The owner of this conversation set these instructions. Follow them unless they conflict with the rules above.
+
+${instructions.trim()}
+`.trim()
+}
+
 export const system = ({
   date,
   language,
@@ -54,6 +66,7 @@ export const system = ({
   soulContent,
   toolsContent,
   inbox = [],
+  instructions = '',
 }: SystemParams) => {
   // ── Static section (stable prefix for LLM prompt caching) ──────────
   const staticHeaders = {
@@ -92,6 +105,7 @@ ${quote('/data')} is your HOME — you can read and write files there freely.
 
 ## Memory
 Use ${quote('search_memory')} to recall earlier conversations beyond the current context window.
+Use ${quote('search_history')} to find the exact words someone said, filtered by sender, channel or date.
 
 ## How to Respond
 
@@ -189,6 +203,8 @@ ${enabledSkills.map(skill => skillPrompt(skill)).join('\n\n---\n\n')}
 
 ${formatInbox(inbox)}
 
+${formatInstructions(instructions)}
+
 <context>
 ${stringify(dynamicHeaders)}
 </context>
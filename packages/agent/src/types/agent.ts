@@ -10,6 +10,7 @@ export interface IdentityContext {
   displayName: string
   currentPlatform?: string
   conversationType?: string
+  routeId?: string
   sessionToken?: string
 }
 
@@ -49,6 +50,8 @@ export interface AgentParams {
   auth: AgentAuthContext
   skills?: AgentSkill[]
   inbox?: InboxItem[]
+  /** Extra per-conversation instructions appended to the system prompt. */
+  instructions?: string
 }
 
 export interface AgentInput {
@@ -27,6 +27,8 @@ export interface ModelConfig {
   clientType: ClientType
   input: ModelInput[]
   reasoning?: ReasoningConfig
+  temperature?: number
+  topP?: number
 }
 
 export const hasInputModality = (config: ModelConfig, modality: ModelInput): boolean =>
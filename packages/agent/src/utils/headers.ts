@@ -13,5 +13,8 @@ export const buildIdentityHeaders = (identity: IdentityContext, auth: AgentAuthC
   if (identity.currentPlatform) {
     headers['X-Memoh-Current-Platform'] = identity.currentPlatform
   }
+  if (identity.routeId) {
+    headers['X-Memoh-Route-Id'] = identity.routeId
+  }
   return headers
 }
\ No newline at end of file